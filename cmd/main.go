@@ -22,10 +22,18 @@ import (
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run executes the command and returns the exit code.
+// Separate from main, so that the deferred functions run before exiting.
+func run() int {
 	rootCommand := switcher.NewCommandStartSwitcher()
+	defer switcher.StopStores()
 
 	if err := rootCommand.Execute(); err != nil {
 		fmt.Print(err)
-		os.Exit(1)
+		return switcher.ExitCode(err)
 	}
+	return 0
 }
@@ -0,0 +1,93 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/annotate"
+)
+
+var (
+	removeAnnotations bool
+	listAnnotations   bool
+
+	annotateCmd = &cobra.Command{
+		Use:   "annotate <context> [KEY=VALUE ...]",
+		Short: "Attach user-defined annotations to a context",
+		Long: `Attach user-defined annotations such as the owner, usage instructions or deprecation notes to a context.
+Annotations are stored in ~/.kube/switch-annotations.yaml keyed by the context name, so that they survive the re-creation of the cluster.
+The annotations are added to the tags of the context with the prefix "user." and shown in the preview of the search.
+
+Use --remove with the keys of the annotations to remove, or without keys to remove all annotations of the context.
+Use --list to show all annotations of the context.`,
+		Example: `  switch annotate dev/cluster owner=team-a "note=do not use for load tests"
+  switch annotate dev/cluster --list
+  switch annotate dev/cluster --remove note`,
+		Args: cobra.MinimumNArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return completeContexts(toComplete)
+			}
+			if removeAnnotations {
+				a, _ := annotations.Load()
+				return a.Keys(args[0]), cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contextName, err := resolveContextName(args[0])
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case listAnnotations && removeAnnotations:
+				return fmt.Errorf("the flags --list and --remove cannot be used together")
+			case listAnnotations:
+				if len(args) > 1 {
+					return fmt.Errorf("--list does not accept annotations")
+				}
+				return annotate.ListAnnotations(contextName)
+			case removeAnnotations:
+				return annotate.RemoveAnnotations(contextName, args[1:])
+			case len(args) == 1:
+				return fmt.Errorf("please provide at least one annotation in the form KEY=VALUE")
+			default:
+				return annotate.Annotate(contextName, args[1:])
+			}
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	annotateCmd.Flags().BoolVar(
+		&removeAnnotations,
+		"remove",
+		false,
+		"remove the annotations with the given keys from the context. Removes all annotations of the context if no keys are given.")
+	annotateCmd.Flags().BoolVar(
+		&listAnnotations,
+		"list",
+		false,
+		"list all annotations of the context.")
+
+	setFlagsForContextCommands(annotateCmd)
+
+	rootCommand.AddCommand(annotateCmd)
+}
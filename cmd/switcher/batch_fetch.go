@@ -0,0 +1,68 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	batchfetch "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/batch-fetch"
+)
+
+var (
+	batchFetchOptions = batchfetch.Options{}
+
+	batchFetchCmd = &cobra.Command{
+		Use:   "batch-fetch [context-list-file]",
+		Short: "Pre-fetches the kubeconfigs of many contexts in parallel",
+		Long: `Retrieves the kubeconfigs of the contexts listed in the file (one context per line) or matching the regex given with --from-selector in parallel.
+The kubeconfigs are written to the cache of the stores (see "cache" in the store configuration), so that switching to the contexts later does not have to wait for the store.
+Contexts of stores without a persistent cache (cache kind "disk" or "filesystem") are not fetched and reported as failed, as their kubeconfigs would be discarded.
+Eg: switch batch-fetch contexts.txt --concurrency 20 --error-output errors.txt`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				batchFetchOptions.ContextsFile = args[0]
+			}
+
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return batchfetch.BatchFetch(batchFetchOptions, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(batchFetchCmd)
+	batchFetchCmd.Flags().StringVar(
+		&batchFetchOptions.Selector,
+		"from-selector",
+		"",
+		"fetch the kubeconfigs of all contexts whose name matches the regex instead of the contexts listed in a file")
+	batchFetchCmd.Flags().IntVar(
+		&batchFetchOptions.Concurrency,
+		"concurrency",
+		10,
+		"maximum number of kubeconfigs fetched in parallel")
+	batchFetchCmd.Flags().StringVar(
+		&batchFetchOptions.ErrorOutput,
+		"error-output",
+		"",
+		"write the errors of the contexts that could not be fetched to this file")
+
+	rootCommand.AddCommand(batchFetchCmd)
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/bootstrap"
+)
+
+var (
+	bootstrapShell  string
+	bootstrapRCFile string
+
+	bootstrapCmd = &cobra.Command{
+		Use:   "bootstrap",
+		Short: "Set up the shell integration",
+		Long: `Add the shell function and the completion script to the rc file of the shell (e.g. ~/.zshrc).
+The shell is detected from the $SHELL environment variable unless set with --shell.
+Does nothing if the shell integration is already installed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return bootstrap.Bootstrap(bootstrapShell, bootstrapRCFile, switcherExecutable(), kubeswitchio.IsDryRun())
+		},
+		SilenceUsage: true,
+	}
+)
+
+// switcherExecutable returns the name of the running switcher binary, which is expected to be on the PATH
+func switcherExecutable() string {
+	executable, err := os.Executable()
+	if err != nil {
+		return "switcher"
+	}
+	return strings.TrimSuffix(filepath.Base(executable), ".exe")
+}
+
+func init() {
+	bootstrapCmd.Flags().StringVar(
+		&bootstrapShell,
+		"shell",
+		"",
+		"the shell to set up: \"bash\", \"zsh\", \"fish\" or \"powershell\". Detected from $SHELL if not set.")
+	bootstrapCmd.Flags().StringVar(
+		&bootstrapRCFile,
+		"rc-file",
+		"",
+		"the file to add the shell integration to. Defaults to the rc file of the shell (e.g. ~/.zshrc or $PROFILE).")
+
+	_ = bootstrapCmd.RegisterFlagCompletionFunc("shell", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return bootstrap.Shells, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	rootCommand.AddCommand(bootstrapCmd)
+}
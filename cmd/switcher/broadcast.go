@@ -0,0 +1,90 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"fmt"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/broadcast"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+var (
+	maxConcurrent       int
+	saveBroadcastOutput bool
+	filterResource      string
+	broadcastShowEmpty  bool
+	broadcastAggregate  bool
+
+	broadcastCmd = &cobra.Command{
+		Use:                   "broadcast selector -- COMMAND [args...]",
+		DisableFlagsInUseLine: true,
+		Short:                 "Execute a command in parallel against all contexts matching the regex selector",
+		Long:                  `Execute a command in parallel against all contexts whose name matches the regex selector. The output is grouped and prefixed by context name. Eg: switch broadcast "prod-.*" -- kubectl get pods -n kube-system`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// split additional args from the command and populate args after "--"
+			cmdArgs := util.SplitAdditionalArgs(&args)
+			if len(cmdArgs) == 0 || len(args) == 0 || len(args[0]) == 0 {
+				return fmt.Errorf("please provide a selector and the command to execute on each cluster")
+			}
+
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			var filter *broadcast.ResourceFilter
+			if len(filterResource) > 0 {
+				filter, err = broadcast.ParseResourceFilter(filterResource)
+				if err != nil {
+					return err
+				}
+			}
+
+			return broadcast.Broadcast(args[0], cmdArgs, maxConcurrent, saveBroadcastOutput, filter, broadcastShowEmpty, broadcastAggregate, stores, config, stateDirectory, noIndex)
+		},
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(broadcastCmd)
+	broadcastCmd.Flags().IntVar(
+		&maxConcurrent,
+		"max-concurrent",
+		10,
+		"maximum number of contexts the command is executed against in parallel")
+	broadcastCmd.Flags().BoolVar(
+		&saveBroadcastOutput,
+		"save-output",
+		false,
+		"save the output to ./kubeswitch-broadcast-<timestamp>/ with one file per context")
+	broadcastCmd.Flags().StringVar(
+		&filterResource,
+		"filter-resource",
+		"",
+		"only show resources in the JSON output of the command for which the JSONPath expression yields the value. Format: <jsonpath>=<value>, e.g. \"{.status.phase}=Pending\". Requires the command to output JSON (\"-o json\").")
+	broadcastCmd.Flags().BoolVar(
+		&broadcastShowEmpty,
+		"show-empty",
+		false,
+		"show contexts without resources matching --filter-resource")
+	broadcastCmd.Flags().BoolVar(
+		&broadcastAggregate,
+		"aggregate",
+		false,
+		"display the resources matching --filter-resource of all contexts in a single table with a CLUSTER column")
+
+	rootCommand.AddCommand(broadcastCmd)
+}
@@ -14,6 +14,41 @@
 package switcher
 
 import (
+	"github.com/spf13/cobra"
+
+	_ "github.com/danielfoehrkn/kubeswitch/pkg/cache/disk"
 	_ "github.com/danielfoehrkn/kubeswitch/pkg/cache/file"
 	_ "github.com/danielfoehrkn/kubeswitch/pkg/cache/memory"
+	cachestats "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/cache-stats"
 )
+
+var (
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect the kubeconfig caches of the stores",
+	}
+
+	cacheStatsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "Shows the size of the disk caches",
+		Long:  `Shows the number and size on disk of the kubeconfigs cached by the stores with a disk cache, together with the size before compression (see "compressCache" in the SwitchConfig).`,
+		Args:  cobra.NoArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, _, err := initialize()
+			if err != nil {
+				return err
+			}
+			return cachestats.ShowStats(stores)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	rootCommand.AddCommand(cacheCmd)
+}
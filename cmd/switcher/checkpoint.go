@@ -0,0 +1,86 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/checkpoint"
+)
+
+var (
+	checkpointName  string
+	checkpointSince time.Duration
+
+	checkpointCmd = &cobra.Command{
+		Use:   "checkpoint",
+		Short: "Save and compare the list of discovered contexts",
+		Long:  `Save the list of discovered contexts as a checkpoint and compare the currently discovered contexts against it to detect added or removed clusters.`,
+	}
+
+	checkpointSaveCmd = &cobra.Command{
+		Use:   "save",
+		Short: "Save the currently discovered contexts as a checkpoint",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+			return checkpoint.Save(checkpointName, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+
+	checkpointDiffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Show contexts added or removed since a checkpoint",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+			return checkpoint.Diff(checkpointName, checkpointSince, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(checkpointSaveCmd)
+	checkpointSaveCmd.Flags().StringVar(
+		&checkpointName,
+		"name",
+		"",
+		"name of the checkpoint. Defaults to the current time.")
+
+	setFlagsForContextCommands(checkpointDiffCmd)
+	checkpointDiffCmd.Flags().StringVar(
+		&checkpointName,
+		"name",
+		"",
+		"name of the checkpoint to compare with. Defaults to the most recent checkpoint.")
+	checkpointDiffCmd.Flags().DurationVar(
+		&checkpointSince,
+		"since",
+		0,
+		"compare with the most recent checkpoint older than the given duration. Ignored if --name is set.")
+
+	checkpointCmd.AddCommand(checkpointSaveCmd)
+	checkpointCmd.AddCommand(checkpointDiffCmd)
+	rootCommand.AddCommand(checkpointCmd)
+}
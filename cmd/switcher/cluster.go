@@ -0,0 +1,104 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	clusterprovision "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/cluster-provision"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/wait"
+)
+
+var (
+	clusterProvisionOptions   clusterprovision.Options
+	printDefaultClusterConfig bool
+
+	clusterCmd = &cobra.Command{
+		Use:   "cluster",
+		Short: "Manage the lifecycle of clusters",
+		Long:  `Manage the lifecycle of clusters with the cloud providers of the configured kubeconfig stores.`,
+	}
+
+	clusterProvisionCmd = &cobra.Command{
+		Use:   "provision --provider gke|eks|aks --name <name>",
+		Short: "Create a new cluster with a cloud provider",
+		Long: `Create a new cluster with the cloud provider of a configured GKE, EKS or Azure store and wait until its API server is ready.
+The cluster is created with a minimal default config, or the provider-specific YAML config given with --config.
+Print the documented default config with --print-default-config.
+Once ready, the cluster is added to the search index of the store.`,
+		Example: `  switch cluster provision --provider gke --print-default-config > gke.yaml
+  switch cluster provision --provider gke --name my-cluster --config gke.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if printDefaultClusterConfig {
+				return clusterprovision.PrintDefaultConfig(clusterProvisionOptions.Provider)
+			}
+
+			if len(clusterProvisionOptions.Name) == 0 {
+				return fmt.Errorf("please specify the name of the cluster with --name")
+			}
+
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return clusterprovision.Provision(clusterProvisionOptions, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(clusterProvisionCmd)
+	clusterProvisionCmd.Flags().StringVar(
+		&clusterProvisionOptions.Provider,
+		"provider",
+		"",
+		"the cloud provider to create the cluster with: \"gke\", \"eks\" or \"aks\"")
+	clusterProvisionCmd.Flags().StringVar(
+		&clusterProvisionOptions.Name,
+		"name",
+		"",
+		"the name of the new cluster")
+	clusterProvisionCmd.Flags().StringVar(
+		&clusterProvisionOptions.StoreID,
+		"store-id",
+		"",
+		"the ID of the store to create the cluster with. Defaults to the first configured store of the provider.")
+	clusterProvisionCmd.Flags().StringVar(
+		&clusterProvisionOptions.ConfigPath,
+		"config",
+		"",
+		"path to the provider-specific cluster config. Uses the default config if not set.")
+	clusterProvisionCmd.Flags().BoolVar(
+		&printDefaultClusterConfig,
+		"print-default-config",
+		false,
+		"print the documented default cluster config of the provider and exit")
+	clusterProvisionCmd.Flags().DurationVar(
+		&clusterProvisionOptions.Timeout,
+		"timeout",
+		wait.DefaultTimeout,
+		"the maximum duration to wait for the API server of the created cluster")
+	_ = clusterProvisionCmd.MarkFlagRequired("provider")
+	_ = clusterProvisionCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return clusterprovision.Providers(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	clusterCmd.AddCommand(clusterProvisionCmd)
+	rootCommand.AddCommand(clusterCmd)
+}
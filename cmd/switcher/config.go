@@ -0,0 +1,151 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	addstore "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/add-store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var (
+	addStoreKind string
+
+	exportRedactCredentials bool
+	exportOutput            string
+	exportKeepPaths         bool
+
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Modify the switch configuration file",
+		Long:  `Modify the switch configuration file.`,
+	}
+
+	configExcludeCmd = &cobra.Command{
+		Use:   "exclude <context>",
+		Short: "Exclude a context from the search results",
+		Long:  `Adds the context name or wildcard pattern to the excludeContexts of the switch configuration file. Excluded contexts are not shown in the search results.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := switchconfig.AddExcludedContext(util.ExpandEnv(configPath), args[0]); err != nil {
+				return fmt.Errorf("failed to exclude context: %w", err)
+			}
+			fmt.Printf("excluded context %q\n", args[0])
+			return nil
+		},
+	}
+
+	configIncludeCmd = &cobra.Command{
+		Use:   "include <context>",
+		Short: "Include a previously excluded context in the search results",
+		Long:  `Removes the context name or wildcard pattern from the excludeContexts of the switch configuration file. If the context is still excluded by a wildcard pattern, it is re-included with an override "!<context>".`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := switchconfig.RemoveExcludedContext(util.ExpandEnv(configPath), args[0]); err != nil {
+				return fmt.Errorf("failed to include context: %w", err)
+			}
+			fmt.Printf("included context %q\n", args[0])
+			return nil
+		},
+	}
+
+	configAddStoreCmd = &cobra.Command{
+		Use:   "add-store",
+		Short: "Interactively add a kubeconfig store",
+		Long:  `Interactively prompts for the configuration of a kubeconfig store and appends the store to the switch configuration file. The credentials of the store are verified before saving.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return addstore.AddStore(types.StoreKind(addStoreKind), util.ExpandEnv(configPath), kubeswitchio.IsDryRun(), func(kubeconfigStore types.KubeconfigStore) (store.KubeconfigStore, error) {
+				return newStore(kubeconfigStore, kubeconfigName)
+			})
+		},
+		SilenceUsage: true,
+	}
+
+	configExportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export a sanitized switch configuration file",
+		Long:  `Prints a copy of the switch configuration file that can be shared with teammates. Credentials are replaced with "<REDACTED>" and local file paths with their basename. The structure of the configuration such as the kubeconfig stores, filters and templates is kept.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, err := switchconfig.ExportConfig(util.ExpandEnv(configPath), switchconfig.ExportOptions{
+				RedactCredentials: exportRedactCredentials,
+				KeepPaths:         exportKeepPaths,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to export config: %w", err)
+			}
+
+			if len(exportOutput) == 0 {
+				fmt.Print(string(output))
+				return nil
+			}
+			if err := kubeswitchio.WriteFile(util.ExpandEnv(exportOutput), output, 0644); err != nil {
+				return fmt.Errorf("failed to write exported config: %w", err)
+			}
+			fmt.Printf("exported config to %q\n", exportOutput)
+			return nil
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	configCmd.PersistentFlags().StringVar(
+		&configPath,
+		"config-path",
+		os.ExpandEnv("$HOME/.kube/switch-config.yaml"),
+		"path on the local filesystem to the configuration file.")
+
+	configAddStoreCmd.Flags().StringVar(
+		&addStoreKind,
+		"kind",
+		"",
+		fmt.Sprintf("kind of the kubeconfig store. One of %q", addstore.SupportedKinds()))
+	_ = configAddStoreCmd.MarkFlagRequired("kind")
+	_ = configAddStoreCmd.RegisterFlagCompletionFunc("kind", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return addstore.SupportedKinds(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	configExportCmd.Flags().BoolVar(
+		&exportRedactCredentials,
+		"redact-credentials",
+		true,
+		"replace API keys, tokens, passwords and HTTP header values with \"<REDACTED>\". Values referencing environment variables are kept.")
+	configExportCmd.Flags().StringVarP(
+		&exportOutput,
+		"output",
+		"o",
+		"",
+		"path to the file to write the exported configuration to. Defaults to stdout.")
+	configExportCmd.Flags().BoolVar(
+		&exportKeepPaths,
+		"keep-paths",
+		false,
+		"keep the local file paths instead of replacing them with their basename, e.g. for teammates on the same system")
+
+	configCmd.AddCommand(configExcludeCmd)
+	configCmd.AddCommand(configIncludeCmd)
+	configCmd.AddCommand(configAddStoreCmd)
+	configCmd.AddCommand(configExportCmd)
+	rootCommand.AddCommand(configCmd)
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/connect"
+)
+
+var (
+	proxyPort     int
+	printProxyURL bool
+
+	connectCmd = &cobra.Command{
+		Use:   "connect [context]",
+		Short: "Start a kubectl proxy for a context",
+		Long:  `Start a kubectl proxy for the given context. If no context is given, the context can be selected interactively.`,
+		Args:  cobra.MaximumNArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			var desiredContext string
+			if len(args) == 1 {
+				desiredContext = args[0]
+			}
+			return connect.Connect(desiredContext, proxyPort, printProxyURL, stores, config, stateDirectory, noIndex, showPreview)
+		},
+		SilenceUsage: true,
+	}
+
+	connectListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the running proxies",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return connect.List(stateDirectory)
+		},
+	}
+
+	connectStopCmd = &cobra.Command{
+		Use:   "stop <id>",
+		Short: "Stop a running proxy",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return connect.Stop(args[0], stateDirectory)
+		},
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(connectCmd)
+	connectCmd.Flags().IntVar(
+		&proxyPort,
+		"port",
+		0,
+		"local port of the proxy. Selects a free port if not set.")
+	connectCmd.Flags().BoolVar(
+		&printProxyURL,
+		"url",
+		false,
+		"start the proxy in the background and only print the proxy URL")
+
+	connectListCmd.Flags().StringVar(
+		&stateDirectory,
+		"state-directory",
+		os.ExpandEnv("$HOME/.kube/switch-state"),
+		"path to the local directory used for storing internal state.")
+	connectStopCmd.Flags().StringVar(
+		&stateDirectory,
+		"state-directory",
+		os.ExpandEnv("$HOME/.kube/switch-state"),
+		"path to the local directory used for storing internal state.")
+
+	connectCmd.AddCommand(connectListCmd)
+	connectCmd.AddCommand(connectStopCmd)
+	rootCommand.AddCommand(connectCmd)
+}
@@ -16,7 +16,10 @@ package switcher
 import (
 	"fmt"
 	"os"
+	"time"
 
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/state"
 	delete_context "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/delete-context"
 	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/history"
 	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/hooks"
@@ -24,10 +27,14 @@ import (
 	set_context "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/set-context"
 	unset_context "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/unset-context"
 	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+// defaultCompletionTimeout is the default maximum duration of the search for the context names offered by the shell completion
+const defaultCompletionTimeout = 3 * time.Second
+
 var (
 	previousContextCmd = &cobra.Command{
 		Use:     "set-previous-context",
@@ -113,8 +120,7 @@ var (
 			if len(args) != 0 {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
-			lc, _ := listContexts(toComplete)
-			return lc, cobra.ShellCompDirectiveNoFileComp
+			return completeContexts(toComplete)
 		},
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			log := logrus.New().WithField("hook", "")
@@ -127,8 +133,15 @@ var (
 			}
 
 			kubeconfigPath, contextName, err := set_context.SetContext(args[0], stores, config, stateDirectory, noIndex, true)
+			if err != nil {
+				return err
+			}
+
+			if err := setNamespaceForContext(kubeconfigPath); err != nil {
+				return err
+			}
 			reportNewContext(kubeconfigPath, contextName)
-			return err
+			return nil
 		},
 		SilenceUsage: true,
 	}
@@ -142,8 +155,7 @@ var (
 			if len(args) != 0 {
 				return nil, cobra.ShellCompDirectiveNoFileComp
 			}
-			lc, _ := listContexts(toComplete)
-			return lc, cobra.ShellCompDirectiveNoFileComp
+			return completeContexts(toComplete)
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctxName, err := resolveContextName(args[0])
@@ -197,23 +209,48 @@ func init() {
 	rootCommand.AddCommand(lastContextCmd)
 
 	setFlagsForContextCommands(setContextCmd)
+	setNamespaceFlags(setContextCmd)
 	setFlagsForContextCommands(listContextsCmd)
 	// need to add flags as the namespace history allows switching to any {context: namespace} combination
 	setFlagsForContextCommands(previousContextCmd)
 	setFlagsForContextCommands(lastContextCmd)
 }
 
-func listContexts(prefix string) ([]string, error) {
+// completeContexts returns the context names starting with the prefix as shell completion candidates.
+// The kind of the store is added as description, which is shown by shells supporting descriptions (e.g. zsh and fish).
+// No candidates are returned if the search does not finish within the completion timeout.
+func completeContexts(prefix string) ([]string, cobra.ShellCompDirective) {
 	stores, config, err := initialize()
 	if err != nil {
-		return nil, err
+		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
-	lc, err := list_contexts.ListContexts("*", stores, config, stateDirectory, noIndex)
-	if err != nil {
-		return nil, err
+	timeout := defaultCompletionTimeout
+	if config.CompletionTimeout != nil {
+		timeout = *config.CompletionTimeout
+	}
+
+	result := make(chan []list_contexts.Context, 1)
+	go func() {
+		contexts, err := list_contexts.ListContextsWithStoreKind(fmt.Sprintf("%s*", prefix), stores, config, stateDirectory, noIndex)
+		if err != nil {
+			contexts = nil
+		}
+		result <- contexts
+	}()
+
+	var contexts []list_contexts.Context
+	select {
+	case contexts = <-result:
+	case <-time.After(timeout):
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	candidates := make([]string, 0, len(contexts))
+	for _, context := range contexts {
+		candidates = append(candidates, fmt.Sprintf("%s\t%s", context.Name, context.StoreKind))
 	}
-	return lc, nil
+	return candidates, cobra.ShellCompDirectiveNoFileComp
 }
 
 func resolveContextName(contextName string) (string, error) {
@@ -262,8 +299,61 @@ func reportNewContext(kubeconfigPath *string, contextName *string) {
 		return
 	}
 
+	rememberPreviousContext(*contextName)
+
+	// the kubeconfig was not written, hence the calling script must not switch to it
+	if kubeswitchio.IsDryRun() {
+		return
+	}
+
 	// print kubeconfig path and context name to std.out
 	// captured by calling script setting KUBECONFIG environment variable
 	// prefixed with "__ " to distinguish kubeconfig path output from other responses (e.g., errors, list of context, ...)
 	fmt.Printf("__ %s,%s", *kubeconfigPath, *contextName)
+
+	runAutoGC()
+}
+
+// rememberPreviousContext persists the context that is active before switching to the given context
+// so that it can be switched back to with --last
+func rememberPreviousContext(newContext string) {
+	kubeconfig, err := kubeconfigutil.LoadCurrentKubeconfig()
+	if err != nil {
+		// e.g. no kubeconfig set yet
+		logrus.Debugf("failed to remember the previous context: %v", err)
+		return
+	}
+
+	previousContext := kubeconfig.GetKubeswitchContext()
+	if len(previousContext) == 0 {
+		previousContext = kubeconfig.GetCurrentContext()
+	}
+
+	if err := state.RememberPreviousContext(stateDirectory, previousContext, newContext); err != nil {
+		logrus.Warnf("failed to remember the previous context: %v", err)
+	}
+}
+
+// switchToLastContext switches to the context that was active before the last switch
+func switchToLastContext() error {
+	lastContext, err := state.ReadLastContext(stateDirectory)
+	if err != nil {
+		return err
+	}
+
+	stores, config, err := initialize()
+	if err != nil {
+		return err
+	}
+
+	kubeconfigPath, contextName, err := set_context.SetContext(lastContext, stores, config, stateDirectory, noIndex, true)
+	if err != nil {
+		return fmt.Errorf("failed to switch to the previous context %q. The context may no longer be available: %w", lastContext, err)
+	}
+
+	if err := setNamespaceForContext(kubeconfigPath); err != nil {
+		return err
+	}
+	reportNewContext(kubeconfigPath, contextName)
+	return nil
 }
@@ -0,0 +1,68 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	contextcopy "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/context-copy"
+)
+
+var (
+	contextCopyOptions contextcopy.Options
+
+	contextCopyCmd = &cobra.Command{
+		Use:   "copy <source> <destination>",
+		Short: "Duplicate a context under a new name",
+		Long:  `Duplicate a context under a new name, optionally with a different namespace or user. The copy is written to the first kubeconfig directory of a filesystem store.`,
+		Args:  cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return contextcopy.CopyContext(args[0], args[1], contextCopyOptions, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(contextCopyCmd)
+	contextCopyCmd.Flags().StringVarP(
+		&contextCopyOptions.Namespace,
+		"namespace",
+		"n",
+		"",
+		"namespace of the copied context. Defaults to the namespace of the source context.")
+	contextCopyCmd.Flags().StringVar(
+		&contextCopyOptions.User,
+		"user",
+		"",
+		"user of the copied context. Must exist in the kubeconfig of the source context.")
+	contextCopyCmd.Flags().BoolVar(
+		&contextCopyOptions.Overwrite,
+		"overwrite",
+		false,
+		"overwrite the destination context if it already exists.")
+
+	contextCmd.AddCommand(contextCopyCmd)
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	contextinfo "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/context-info"
+)
+
+var (
+	contextInfoOutput string
+
+	contextCmd = &cobra.Command{
+		Use:   "context",
+		Short: "Inspect and manage contexts",
+		Long:  `Inspect and manage contexts from all kubeconfig stores.`,
+	}
+
+	contextInfoCmd = &cobra.Command{
+		Use:   "info <context-name>",
+		Short: "Display detailed metadata about a context",
+		Long:  `Display detailed metadata about a context without switching to it, such as the API server URL, certificate expiry, namespace and store.`,
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return contextinfo.ShowContextInfo(args[0], contextInfoOutput, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(contextInfoCmd)
+	contextInfoCmd.Flags().StringVarP(
+		&contextInfoOutput,
+		"output",
+		"o",
+		contextinfo.OutputTable,
+		"output format. One of: json|yaml|table")
+
+	contextCmd.AddCommand(contextInfoCmd)
+	rootCommand.AddCommand(contextCmd)
+}
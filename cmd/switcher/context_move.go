@@ -0,0 +1,64 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	contextmove "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/context-move"
+)
+
+var (
+	moveToStore    string
+	noDeleteSource bool
+
+	contextMoveCmd = &cobra.Command{
+		Use:   "move <context> --to-store <store-id>",
+		Short: "Move a context to another store",
+		Long:  `Move a context to another store, e.g. after migrating the cluster to another provider. The kubeconfig is written to the target store, verified and then removed from the source store. Stores that cannot be written to, such as cloud provider stores, are substituted by the first filesystem store with a kubeconfig directory.`,
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return contextmove.MoveContext(args[0], moveToStore, !noDeleteSource, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(contextMoveCmd)
+	contextMoveCmd.Flags().StringVar(
+		&moveToStore,
+		"to-store",
+		"",
+		"ID of the store to move the context to, e.g. \"filesystem.default\"")
+	contextMoveCmd.Flags().BoolVar(
+		&noDeleteSource,
+		"no-delete-source",
+		false,
+		"keep the context in the source store")
+	_ = contextMoveCmd.MarkFlagRequired("to-store")
+
+	contextCmd.AddCommand(contextMoveCmd)
+}
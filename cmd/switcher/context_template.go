@@ -0,0 +1,78 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	contexttemplate "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/context-template"
+)
+
+var (
+	contextTemplateOptions contexttemplate.Options
+	contextTemplateOutput  string
+	contextTemplatePlugin  string
+
+	contextTemplateCmd = &cobra.Command{
+		Use:   "template --cluster-name <name> --server <url>",
+		Short: "Generate a kubeconfig template for a new cluster",
+		Long:  `Generate a minimal kubeconfig for a new cluster with a single context, cluster and user. The user authenticates with an exec plugin, either pre-populated for EKS, GKE or Azure via --exec-plugin or a placeholder to be replaced.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contextTemplateOptions.ExecPlugin = contexttemplate.ExecPlugin(contextTemplatePlugin)
+			return contexttemplate.WriteTemplate(contextTemplateOptions, contextTemplateOutput)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	contextTemplateCmd.Flags().StringVar(
+		&contextTemplateOptions.ClusterName,
+		"cluster-name",
+		"",
+		"name of the context, cluster and user")
+	contextTemplateCmd.Flags().StringVar(
+		&contextTemplateOptions.Server,
+		"server",
+		"",
+		"HTTPS URL of the API server")
+	contextTemplateCmd.Flags().StringVar(
+		&contextTemplateOptions.CAFile,
+		"ca-file",
+		"",
+		"path to the PEM encoded certificate authority of the API server. The certificate is inlined in the kubeconfig.")
+	contextTemplateCmd.Flags().StringVar(
+		&contextTemplatePlugin,
+		"exec-plugin",
+		"",
+		"credential plugin to pre-populate: \"eks\", \"gke\" or \"azure\". Uses a placeholder command if not set.")
+	contextTemplateCmd.Flags().StringVarP(
+		&contextTemplateOutput,
+		"output",
+		"o",
+		"-",
+		"file to write the kubeconfig to. Writes to STDOUT if set to \"-\".")
+	_ = contextTemplateCmd.MarkFlagRequired("cluster-name")
+	_ = contextTemplateCmd.MarkFlagRequired("server")
+	_ = contextTemplateCmd.RegisterFlagCompletionFunc("exec-plugin", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		plugins := make([]string, 0, len(contexttemplate.ValidExecPlugins))
+		for _, plugin := range contexttemplate.ValidExecPlugins {
+			plugins = append(plugins, string(plugin))
+		}
+		return plugins, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	contextCmd.AddCommand(contextTemplateCmd)
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/convert"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+)
+
+var (
+	convertInput  string
+	convertOutput string
+	convertTo     string
+
+	convertCmd = &cobra.Command{
+		Use:   "convert",
+		Short: "Convert a kubeconfig file between YAML and JSON",
+		Long:  `Convert an existing kubeconfig file from YAML to JSON or vice versa. The conversion is lossless, converting back yields a semantically identical kubeconfig.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return convert.Convert(convertInput, convertOutput, convertTo)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	convertCmd.Flags().StringVar(
+		&convertInput,
+		"input",
+		"",
+		"the kubeconfig file to convert.")
+	convertCmd.Flags().StringVarP(
+		&convertOutput,
+		"output",
+		"o",
+		"-",
+		"file to write the converted kubeconfig to. Writes to STDOUT if set to \"-\".")
+	convertCmd.Flags().StringVar(
+		&convertTo,
+		"to",
+		string(kubeconfigutil.FormatJSON),
+		"the format to convert the kubeconfig to: \"yaml\" or \"json\".")
+	_ = convertCmd.MarkFlagRequired("input")
+
+	_ = convertCmd.RegisterFlagCompletionFunc("to", completeKubeconfigFormat)
+
+	rootCommand.AddCommand(convertCmd)
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	copytoclipboard "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/copy-to-clipboard"
+)
+
+var (
+	copyFormat string
+
+	copyCmd = &cobra.Command{
+		Use:   "copy <context-name>",
+		Short: "Copy a context name or its kubeconfig to the clipboard",
+		Long: `Copy the name of a context to the system clipboard to share it with others.
+Use --format kubeconfig to copy the standalone kubeconfig of the context instead. The kubeconfig may contain credentials, only share it if this is acceptable (e.g. for short-lived tokens).
+Prints to STDOUT if no clipboard is available.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return copytoclipboard.CopyContext(args[0], copyFormat, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(copyCmd)
+	copyCmd.Flags().StringVar(
+		&copyFormat,
+		"format",
+		copytoclipboard.FormatName,
+		"content copied to the clipboard. One of: name|kubeconfig")
+	_ = copyCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{copytoclipboard.FormatName, copytoclipboard.FormatKubeconfig}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	rootCommand.AddCommand(copyCmd)
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	credentialplugin "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/credential-plugin"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+)
+
+var (
+	credentialPluginCmd = &cobra.Command{
+		Use:   "credential-plugin <context-name>",
+		Short: "Print the token of a context as ExecCredential for kubectl",
+		Long: `Print the bearer token of the user of a context as client.authentication.k8s.io/v1 ExecCredential.
+Meant to be used as exec credential plugin in a kubeconfig, so that kubectl obtains a fresh token via kubeswitch whenever the token expired.
+Set "credentialPlugin: true" in the switch configuration to use it for the kubeconfigs of switched contexts.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return credentialplugin.PrintExecCredential(args[0], stores, config, stateDirectory, noIndex, credentialCache)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(credentialPluginCmd)
+	rootCommand.AddCommand(credentialPluginCmd)
+}
+
+// setCredentialPlugin configures this binary as the exec plugin of the users of switched contexts.
+// The switch configuration is passed explicitly, as kubectl invokes the plugin from arbitrary directories.
+func setCredentialPlugin() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	switchConfigPath, err := filepath.Abs(util.ExpandEnv(configPath))
+	if err != nil {
+		return err
+	}
+
+	kubeconfigutil.SetCredentialPlugin(executable, []string{"credential-plugin", "--config-path", switchConfigPath})
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+	"github.com/spf13/cobra"
+)
+
+const credentialCacheDirectoryName = "creds"
+
+var (
+	credentialsStoreID string
+
+	credentialsCmd = &cobra.Command{
+		Use:   "credentials",
+		Short: "Manage the cached credentials of cloud provider stores",
+		Long:  `Manage the credentials of cloud provider stores (EKS, GKE, Azure) cached encrypted in the state directory.`,
+	}
+
+	credentialsClearCmd = &cobra.Command{
+		Use:   "clear",
+		Short: "Removes cached cloud provider credentials",
+		Long:  `Removes the cached credentials of all stores or only of the store given with --store (e.g. "eks.default"). The credentials are obtained again from the cloud provider on the next search.`,
+		Args:  cobra.NoArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			removed, err := credentials.Clear(filepath.Join(stateDirectory, credentialCacheDirectoryName), credentialsStoreID)
+			if err != nil {
+				return fmt.Errorf("failed to clear cached credentials: %w", err)
+			}
+			fmt.Printf("Removed %d cached credential(s)\n", removed)
+			return nil
+		},
+	}
+)
+
+func init() {
+	credentialsClearCmd.Flags().StringVar(
+		&credentialsStoreID,
+		"store",
+		"",
+		"only remove the credentials of the store with the given ID in the format <kind>.<id> (e.g. \"eks.default\")")
+	credentialsClearCmd.Flags().StringVar(
+		&stateDirectory,
+		"state-directory",
+		os.ExpandEnv("$HOME/.kube/switch-state"),
+		"path to the local directory used for storing internal state.")
+
+	credentialsCmd.AddCommand(credentialsClearCmd)
+	rootCommand.AddCommand(credentialsCmd)
+}
+
+// newCredentialCache creates the cache for cloud provider credentials in the state directory.
+// Returns nil if the configured SSH key cannot be used to encrypt the credentials.
+func newCredentialCache(config *types.CredentialCacheConfig) (*credentials.CredentialCache, error) {
+	var (
+		maxAge     time.Duration
+		sshKeyPath string
+	)
+	if config != nil {
+		if config.MaxAge != nil {
+			maxAge = *config.MaxAge
+		}
+		if config.SSHKeyPath != nil {
+			sshKeyPath = util.ExpandEnv(*config.SSHKeyPath)
+		}
+	}
+	return credentials.NewCredentialCache(filepath.Join(stateDirectory, credentialCacheDirectoryName), maxAge, sshKeyPath)
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/debug"
+)
+
+var (
+	debugVerbose         bool
+	debugCaptureDuration time.Duration
+
+	debugCmd = &cobra.Command{
+		Use:   "debug",
+		Short: "Troubleshoot the kubeconfig stores",
+	}
+
+	debugStoreCmd = &cobra.Command{
+		Use:   "store <store-id>",
+		Short: "Run diagnostics against a kubeconfig store",
+		Long: `Print the effective configuration of a kubeconfig store (with secrets redacted) and run each step of the search against the store:
+verify the kubeconfig paths, probe the backing store, search with a timeout of 10s and fetch and validate the kubeconfig of the first search result.
+Each step is shown as PASS or FAIL together with the elapsed time.
+With --verbose, the raw HTTP requests and responses of the HTTP, Vault, Rancher, WebDAV, Gardener, CAPI and EKS stores are logged to stderr with credentials redacted.`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoreIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the API clients have to be instrumented before they are created
+			if debugVerbose {
+				debug.EnableHTTPDebugLogs()
+			}
+
+			stores, _, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return debug.DebugStore(args[0], stores)
+		},
+		SilenceUsage: true,
+	}
+
+	debugAPICallsCmd = &cobra.Command{
+		Use:   "api-calls <store-id>",
+		Short: "Capture the raw API calls of a kubeconfig store",
+		Long: `Search a kubeconfig store and fetch the kubeconfig of the first search result while streaming the raw HTTP requests and responses of the API client of the store to stderr.
+Tokens, passwords, certificate data and kubeconfigs are redacted. The capture stops once finished or after --duration.
+Covers the HTTP, Vault, Rancher, WebDAV, Gardener, CAPI and EKS stores as well as stores using API clients with the default HTTP transport.`,
+		Example:           `  switch debug api-calls vault.default --duration 10s`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeStoreIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the API clients have to be instrumented before they are created
+			debug.EnableAPICallLogs()
+
+			stores, _, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return debug.CaptureAPICalls(args[0], debugCaptureDuration, stores)
+		},
+		SilenceUsage: true,
+	}
+)
+
+// completeStoreIDs completes the IDs of the configured stores
+func completeStoreIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	stores, _, err := initialize()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	ids := make([]string, 0, len(stores))
+	for _, s := range stores {
+		ids = append(ids, s.GetID())
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	setFlagsForContextCommands(debugStoreCmd)
+	debugStoreCmd.Flags().BoolVar(
+		&debugVerbose,
+		"verbose",
+		false,
+		"show the raw HTTP requests and responses of the API client of the store")
+
+	setFlagsForContextCommands(debugAPICallsCmd)
+	debugAPICallsCmd.Flags().DurationVar(
+		&debugCaptureDuration,
+		"duration",
+		debug.DefaultCaptureDuration,
+		"the maximum duration of the capture")
+
+	debugCmd.AddCommand(debugStoreCmd)
+	debugCmd.AddCommand(debugAPICallsCmd)
+	rootCommand.AddCommand(debugCmd)
+}
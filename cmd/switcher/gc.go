@@ -0,0 +1,147 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/gc"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var (
+	// autoGC is set from the switch configuration and runs "switch gc" in the background after each successful switch
+	autoGC bool
+	// gcStoreIDs are the IDs of the configured stores passed by the automatic garbage collection,
+	// so that the stores do not have to be created again
+	gcStoreIDs []string
+
+	gcCmd = &cobra.Command{
+		Use:   "gc",
+		Short: "Removes stale files from the state directory",
+		Long: `Removes stale files from the state directory:
+  - files of proxies and monitors whose process is no longer running
+  - cached credentials older than the maximum age of the credential cache
+  - index files of stores that are no longer configured
+  - the oldest checkpoints beyond "maxBackups" of the switch configuration
+Use --dry-run to only list the files that would be removed.`,
+		Args: cobra.NoArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			storeIDs, config, err := getStoreIDsForGC()
+			if err != nil {
+				return err
+			}
+
+			var credentialMaxAge time.Duration
+			if config.CredentialCache != nil && config.CredentialCache.MaxAge != nil {
+				credentialMaxAge = *config.CredentialCache.MaxAge
+			}
+
+			collector := &gc.GarbageCollector{
+				StateDir:                 stateDirectory,
+				StoreIDs:                 storeIDs,
+				CredentialCacheDirectory: filepath.Join(stateDirectory, credentialCacheDirectoryName),
+				CredentialMaxAge:         credentialMaxAge,
+				MaxBackups:               config.MaxBackups,
+			}
+			return collector.Run()
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(gcCmd)
+	gcCmd.Flags().StringSliceVar(
+		&gcStoreIDs,
+		"store-ids",
+		nil,
+		"the IDs of the configured stores. Set by the automatic garbage collection.")
+	_ = gcCmd.Flags().MarkHidden("store-ids")
+	rootCommand.AddCommand(gcCmd)
+}
+
+// getStoreIDsForGC returns the IDs of the configured stores and the switch configuration.
+// If the IDs are passed via --store-ids, only the switch configuration is read instead of creating the stores.
+func getStoreIDsForGC() (sets.Set[string], *types.Config, error) {
+	if len(gcStoreIDs) > 0 {
+		config, err := switchconfig.LoadConfigFromFile(util.ExpandEnv(configPath))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read switch config file: %v", err)
+		}
+		if config == nil {
+			config = &types.Config{}
+		}
+		return sets.New[string](gcStoreIDs...), config, nil
+	}
+
+	stores, config, err := initialize()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	storeIDs := sets.New[string]()
+	for _, s := range stores {
+		storeIDs.Insert(s.GetID())
+	}
+	return storeIDs, config, nil
+}
+
+// runAutoGC starts "switch gc" in the background if enabled in the switch configuration.
+// The IDs of the stores created for the switch are passed, so that "switch gc" does not create the stores again.
+// Failures are only logged, as the switch already succeeded.
+func runAutoGC() {
+	if !autoGC || kubeswitchio.IsDryRun() || len(initializedStores) == 0 {
+		return
+	}
+
+	storeIDs := sets.New[string]()
+	for _, s := range initializedStores {
+		storeIDs.Insert(s.GetID())
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		logrus.Debugf("failed to run the garbage collection: %v", err)
+		return
+	}
+
+	switchConfigPath, err := filepath.Abs(util.ExpandEnv(configPath))
+	if err != nil {
+		logrus.Debugf("failed to run the garbage collection: %v", err)
+		return
+	}
+
+	command := exec.Command(executable, "gc", "--config-path", switchConfigPath, "--state-directory", stateDirectory, "--store-ids", strings.Join(sets.List(storeIDs), ","))
+	if err := command.Start(); err != nil {
+		logrus.Debugf("failed to run the garbage collection: %v", err)
+		return
+	}
+	_ = command.Process.Release()
+}
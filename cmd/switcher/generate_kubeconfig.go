@@ -0,0 +1,84 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	generatekubeconfig "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/generate-kubeconfig"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+)
+
+var (
+	generateKubeconfigOutput string
+	includeExecPlugin        bool
+	generateKubeconfigTTL    time.Duration
+	outputContextName        string
+
+	generateKubeconfigCmd = &cobra.Command{
+		Use:   "generate-kubeconfig <context>",
+		Short: "Write a standalone kubeconfig for the given context",
+		Long:  `Write a standalone kubeconfig that only contains the given context with its cluster and user. Useful for CI pipelines or tools requiring a kubeconfig file.`,
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			format, err := kubeconfigutil.ParseFormat(kubeconfigFormat)
+			if err != nil {
+				return err
+			}
+
+			return generatekubeconfig.GenerateKubeconfig(args[0], generateKubeconfigOutput, outputContextName, format, includeExecPlugin, generateKubeconfigTTL, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(generateKubeconfigCmd)
+	generateKubeconfigCmd.Flags().StringVarP(
+		&generateKubeconfigOutput,
+		"output",
+		"o",
+		"-",
+		"file to write the kubeconfig to. Writes to STDOUT if set to \"-\".")
+	generateKubeconfigCmd.Flags().BoolVar(
+		&includeExecPlugin,
+		"include-exec-plugin",
+		true,
+		"invoke exec plugins and inline the returned credentials. If false, exec plugins are left as-is.")
+	generateKubeconfigCmd.Flags().DurationVar(
+		&generateKubeconfigTTL,
+		"ttl",
+		0,
+		"minimum validity of inlined credentials. Logs a warning if the provider issues credentials that expire earlier.")
+	generateKubeconfigCmd.Flags().StringVar(
+		&outputContextName,
+		"output-context-name",
+		"",
+		"name of the context in the generated kubeconfig. The cluster and user are named \"<name>-cluster\" and \"<name>-user\". The original context name is kept in the \"kubeswitch.io/original-context\" extension.")
+
+	rootCommand.AddCommand(generateKubeconfigCmd)
+}
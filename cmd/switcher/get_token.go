@@ -0,0 +1,61 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	gettoken "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/get-token"
+)
+
+var (
+	getTokenFormat string
+
+	getTokenCmd = &cobra.Command{
+		Use:   "get-token <context-name>",
+		Short: "Print the bearer token of a context",
+		Long: `Print the bearer token of the user of a context as JSON ({"token": "...", "expiry": "..."}) or as raw string.
+The exec plugin of the user is invoked if the kubeconfig does not contain a static token.
+Exec plugins are run by the client-go exec authenticator and may prompt on the terminal.
+Tokens of exec plugins are cached in the credential cache until they expire. The expiry is read from the "exp" claim of JWTs,
+other tokens are not cached.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return gettoken.PrintToken(args[0], getTokenFormat, stores, config, stateDirectory, noIndex, credentialCache)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(getTokenCmd)
+	getTokenCmd.Flags().StringVar(
+		&getTokenFormat,
+		"format",
+		gettoken.FormatJSON,
+		"output format. One of: json|raw")
+
+	rootCommand.AddCommand(getTokenCmd)
+}
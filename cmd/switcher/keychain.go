@@ -0,0 +1,65 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/keychain"
+)
+
+var (
+	keychainToken string
+
+	keychainCmd = &cobra.Command{
+		Use:   "keychain",
+		Short: "Manage tokens stored in the macOS Keychain",
+		Long:  `Manage user tokens stored in the macOS Keychain instead of in plaintext kubeconfig files. Requires "keychainBackend: true" in the configuration of the filesystem store.`,
+	}
+
+	keychainAddCmd = &cobra.Command{
+		Use:   "add <context>",
+		Short: "Store the token of the user of a context in the macOS Keychain",
+		Long:  `Store the token of the user of a context in the macOS Keychain and replace the token in the kubeconfig file with a reference to the Keychain item.`,
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return keychain.AddToken(args[0], keychainToken, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(keychainAddCmd)
+	keychainAddCmd.Flags().StringVar(
+		&keychainToken,
+		"token",
+		"",
+		"the bearer token to store in the Keychain")
+	_ = keychainAddCmd.MarkFlagRequired("token")
+
+	keychainCmd.AddCommand(keychainAddCmd)
+	rootCommand.AddCommand(keychainCmd)
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/merge"
+)
+
+var (
+	mergeOutput            string
+	mergeConflict          string
+	mergeSetCurrentContext string
+
+	mergeCmd = &cobra.Command{
+		Use:   "merge <file> <file>...",
+		Short: "Merge multiple kubeconfig files into one",
+		Long:  `Merge multiple kubeconfig files into a single kubeconfig. Clusters, contexts and users are deduplicated by name.`,
+		Args:  cobra.MinimumNArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveDefault
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return merge.Merge(args, mergeOutput, merge.ConflictStrategy(mergeConflict), mergeSetCurrentContext)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	mergeCmd.Flags().StringVarP(
+		&mergeOutput,
+		"output",
+		"o",
+		"-",
+		"file to write the merged kubeconfig to. Writes to STDOUT if set to \"-\".")
+	mergeCmd.Flags().StringVar(
+		&mergeConflict,
+		"conflict",
+		string(merge.ConflictStrict),
+		"how to handle differing entries with the same name: \"strict\" fails, \"overwrite\" uses the entry of the last file, \"skip\" uses the entry of the first file.")
+	mergeCmd.Flags().StringVar(
+		&mergeSetCurrentContext,
+		"set-current-context",
+		"",
+		"the current-context of the merged kubeconfig. Defaults to the current-context of the first file setting one.")
+
+	_ = mergeCmd.RegisterFlagCompletionFunc("conflict", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return merge.ValidConflictStrategies.List(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	rootCommand.AddCommand(mergeCmd)
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/config/validation"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/monitor"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var (
+	monitorForeground bool
+
+	monitorCmd = &cobra.Command{
+		Use:   "monitor",
+		Short: "Alert when stores or contexts become unreachable",
+		Long: `Periodically probe the kubeconfig stores and check the connectivity to the API servers of the watched contexts.
+Once a store or context failed the configured number of consecutive checks, an alert is sent to the Alertmanager.
+The alert is resolved once the store or context is reachable again.
+The monitor is configured in the "monitor" section of the switch configuration.
+Changes to the kubeconfig stores and watched contexts in the switch configuration are applied to the running monitor.`,
+	}
+
+	monitorStartCmd = &cobra.Command{
+		Use:   "start",
+		Short: "Start the monitor in the background",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			if monitorForeground {
+				return monitor.Run(stores, config, stateDirectory, noIndex, &monitor.Reload{
+					ConfigPath: util.ExpandEnv(configPath),
+					Prepare: func(config *types.Config) error {
+						if errList := validation.ValidateConfig(config); len(errList) > 0 {
+							return fmt.Errorf("the switch configuration file contains errors: %s", errList.ToAggregate().Error())
+						}
+						// keep the store configured by flags and environment variables
+						if storeFromFlags := getStoreFromFlagAndEnv(config); storeFromFlags != nil {
+							config.KubeconfigStores = append(config.KubeconfigStores, *storeFromFlags)
+						}
+						return nil
+					},
+					NewStore: func(kubeconfigStore types.KubeconfigStore) (store.KubeconfigStore, error) {
+						return newConfiguredStore(kubeconfigStore, kubeconfigName)
+					},
+				})
+			}
+
+			if config.Monitor == nil {
+				return fmt.Errorf("the monitor is not configured. Please configure \"monitor.alertmanagerURL\" in the switch configuration")
+			}
+
+			executable, err := os.Executable()
+			if err != nil {
+				return err
+			}
+
+			switchConfigPath, err := filepath.Abs(util.ExpandEnv(configPath))
+			if err != nil {
+				return err
+			}
+
+			return monitor.Start(executable, []string{"monitor", "start", "--foreground", "--config-path", switchConfigPath, "--state-directory", stateDirectory}, stateDirectory)
+		},
+		SilenceUsage: true,
+	}
+
+	monitorStopCmd = &cobra.Command{
+		Use:   "stop",
+		Short: "Stop the monitor",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return monitor.Stop(stateDirectory)
+		},
+		SilenceUsage: true,
+	}
+
+	monitorStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of the monitor and the result of the last check",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return monitor.Status(stateDirectory)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(monitorStartCmd)
+	monitorStartCmd.Flags().BoolVar(
+		&monitorForeground,
+		"foreground",
+		false,
+		"run the monitor in the foreground instead of starting it in the background")
+
+	monitorStopCmd.Flags().StringVar(
+		&stateDirectory,
+		"state-directory",
+		os.ExpandEnv("$HOME/.kube/switch-state"),
+		"path to the local directory used for storing internal state.")
+	monitorStatusCmd.Flags().StringVar(
+		&stateDirectory,
+		"state-directory",
+		os.ExpandEnv("$HOME/.kube/switch-state"),
+		"path to the local directory used for storing internal state.")
+
+	monitorCmd.AddCommand(monitorStartCmd)
+	monitorCmd.AddCommand(monitorStopCmd)
+	monitorCmd.AddCommand(monitorStatusCmd)
+	rootCommand.AddCommand(monitorCmd)
+}
@@ -14,11 +14,20 @@
 package switcher
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/ns"
 	"github.com/spf13/cobra"
 )
 
 var (
+	// namespace to set on the selected context
+	targetNamespace            string
+	namespaceCompletionTimeout time.Duration
+	// createNamespace creates the target namespace if it does not exist
+	createNamespace bool
+
 	checkExistence   bool = true
 	namespaceCommand      = &cobra.Command{
 		Use:     "namespace",
@@ -56,6 +65,48 @@ var (
 	}
 )
 
+// setNamespaceFlags adds the --namespace flag with completion of the namespaces of the current cluster
+func setNamespaceFlags(command *cobra.Command) {
+	command.Flags().StringVarP(
+		&targetNamespace,
+		"namespace",
+		"n",
+		"",
+		"namespace to set for the selected context")
+	command.Flags().DurationVar(
+		&namespaceCompletionTimeout,
+		"namespace-completion-timeout",
+		2*time.Second,
+		"timeout of the API call listing the namespaces of the current cluster for the completion of the --namespace flag")
+	command.Flags().BoolVar(
+		&createNamespace,
+		"create-namespace",
+		false,
+		"create the namespace given via --namespace if it does not exist. Only a warning is logged if the namespace cannot be created.")
+
+	_ = command.RegisterFlagCompletionFunc("namespace", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// do not show errors during completion (e.g., cluster not reachable or missing RBAC permissions)
+		namespaces, err := ns.ListClusterNamespaces(getKubeconfigPathFromFlag(), namespaceCompletionTimeout)
+		if err != nil {
+			return []string{}, cobra.ShellCompDirectiveNoFileComp
+		}
+		return namespaces, cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
+// setNamespaceForContext sets the namespace given via the --namespace flag on the kubeconfig of the selected context
+// and creates the namespace if --create-namespace is set
+func setNamespaceForContext(kubeconfigPath *string) error {
+	if createNamespace && len(targetNamespace) == 0 {
+		return fmt.Errorf("--create-namespace can only be used together with --namespace")
+	}
+	if len(targetNamespace) == 0 || kubeconfigPath == nil {
+		return nil
+	}
+
+	return ns.SetNamespaceForContext(targetNamespace, *kubeconfigPath, createNamespace)
+}
+
 func init() {
 	setCommonFlags(namespaceCommand)
 	namespaceCommand.Flags().BoolVar(&checkExistence, "check-existence", true, "Check if the namespace exists before switching to it (default true)")
@@ -0,0 +1,57 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/packages"
+)
+
+var (
+	packageCmd = &cobra.Command{
+		Use:   "package",
+		Short: "Inspect the packages (logical cluster groups) of the contexts",
+		Long:  `Packages group contexts into logical cluster groups, e.g. the rings of a multi-cluster deployment. Packages are configured per store with "packages" in the SwitchConfig or assigned by the store. Use "switch --package <name>" to only show the contexts of a package.`,
+	}
+
+	packageListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the packages and their contexts",
+		Args:  cobra.NoArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			result, err := packages.ListPackages(stores, config, stateDirectory, noIndex)
+			if err != nil {
+				return err
+			}
+			packages.PrintPackages(result)
+			return nil
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(packageListCmd)
+	packageCmd.AddCommand(packageListCmd)
+	rootCommand.AddCommand(packageCmd)
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/plugin"
+	pluginsubcommand "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/plugin"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var (
+	pluginDirectory    string
+	pluginRegistryPath string
+
+	pluginCmd = &cobra.Command{
+		Use:   "plugin",
+		Short: "Install and manage store plugins",
+		Long: `Install and manage kubeconfig store plugins. Plugin binaries are named "kubeswitch-store-<name>"
+and are installed to the plugin directory, which is added to the PATH of kubeswitch.
+Plugins are installed by name from the plugin registry or from the latest release of a GitHub repository.`,
+	}
+
+	pluginInstallCmd = &cobra.Command{
+		Use:   "install <plugin-name|github-repository-url>",
+		Short: "Install a plugin from the registry or a GitHub release",
+		Long: `Install the latest version of a plugin. The plugin is either looked up by name in the plugin registry
+or downloaded from the latest release of a GitHub repository named "kubeswitch-store-<name>",
+e.g. https://github.com/<owner>/kubeswitch-store-<name>. The release must contain the binary
+"kubeswitch-store-<name>_<os>_<arch>" and a checksums.txt file.
+The binary is verified against its SHA256 checksum before it is installed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pluginsubcommand.Install(args[0], newPluginManager())
+		},
+		SilenceUsage: true,
+	}
+
+	pluginListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the installed plugins with their versions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pluginsubcommand.List(newPluginManager())
+		},
+		SilenceUsage: true,
+	}
+
+	pluginUpdateCmd = &cobra.Command{
+		Use:               "update <plugin-name>",
+		Short:             "Update an installed plugin to the latest version",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeInstalledPlugins,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pluginsubcommand.Update(args[0], newPluginManager())
+		},
+		SilenceUsage: true,
+	}
+
+	pluginRemoveCmd = &cobra.Command{
+		Use:               "remove <plugin-name>",
+		Short:             "Uninstall a plugin",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeInstalledPlugins,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pluginsubcommand.Remove(args[0], newPluginManager())
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	for _, command := range []*cobra.Command{pluginInstallCmd, pluginListCmd, pluginUpdateCmd, pluginRemoveCmd} {
+		command.Flags().StringVar(
+			&pluginDirectory,
+			"plugin-directory",
+			os.ExpandEnv(plugin.DefaultDirectory),
+			"path to the local directory plugins are installed to.")
+		command.Flags().StringVar(
+			&pluginRegistryPath,
+			"plugin-registry",
+			os.ExpandEnv(plugin.DefaultRegistryPath),
+			"path to the plugin registry listing the plugins that can be installed by name.")
+		pluginCmd.AddCommand(command)
+	}
+
+	rootCommand.AddCommand(pluginCmd)
+}
+
+func newPluginManager() *plugin.Manager {
+	return plugin.NewManager(util.ExpandEnv(pluginDirectory), util.ExpandEnv(pluginRegistryPath))
+}
+
+// completeInstalledPlugins completes the names of the installed plugins
+func completeInstalledPlugins(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	installed, err := newPluginManager().List()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	names := make([]string, 0, len(installed))
+	for _, p := range installed {
+		names = append(names, p.Name)
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
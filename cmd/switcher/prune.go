@@ -0,0 +1,70 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/prune"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var (
+	pruneKubeconfigPath string
+	pruneAge            time.Duration
+
+	pruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Remove contexts from a kubeconfig file that are no longer found in any store",
+		Long: `Remove the contexts from a kubeconfig file, by default the merged kubeconfig ~/.kube/config, that are no longer found in any kubeconfig store.
+All stores are searched. If any store cannot be searched, no context is removed.
+The current context of the kubeconfig is never removed.
+The time each context was last found in a store is recorded in the state directory. Use --age to only remove contexts that have not been found in any store for the given duration.
+Use --dry-run to show the contexts that would be removed.`,
+		Example: `  switch prune --dry-run
+  switch prune --age 168h`,
+		Args: cobra.NoArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return prune.NewContextPruner(util.ExpandEnv(pruneKubeconfigPath), stateDirectory, pruneAge).Prune(stores, config)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(pruneCmd)
+	pruneCmd.Flags().StringVar(
+		&pruneKubeconfigPath,
+		"kubeconfig",
+		os.ExpandEnv("$HOME/.kube/config"),
+		"the kubeconfig file to remove the contexts from")
+	pruneCmd.Flags().DurationVar(
+		&pruneAge,
+		"age",
+		0,
+		"only remove contexts that have not been found in any store for at least this duration")
+
+	rootCommand.AddCommand(pruneCmd)
+}
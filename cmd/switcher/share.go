@@ -0,0 +1,90 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/share"
+)
+
+var (
+	shareTTL time.Duration
+
+	shareCmd = &cobra.Command{
+		Use:   "share <context-name>",
+		Short: "Print a signed URL to share a context",
+		Long: `Print a signed URL of the form kubeswitch://share/<payload>/<signature> referencing a context.
+The URL only contains the ID of the store, the name of the context and the expiry, but no credentials.
+It is signed with the "shareSecret" of the switch configuration and can be opened with "switch open" until it expires.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return share.Share(args[0], shareTTL, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+
+	openCmd = &cobra.Command{
+		Use:   "open <url>",
+		Short: "Switch to a context shared with \"switch share\"",
+		Long: `Verify the signature and expiry of a URL created with "switch share" and switch to the referenced context.
+The context is retrieved from the store with the ID contained in the URL using your own credentials.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			kubeconfigPath, contextName, err := share.Open(args[0], stores, config, stateDirectory, noIndex)
+			if err != nil {
+				return err
+			}
+
+			if err := setNamespaceForContext(kubeconfigPath); err != nil {
+				return err
+			}
+			reportNewContext(kubeconfigPath, contextName)
+			return nil
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(shareCmd)
+	shareCmd.Flags().DurationVar(
+		&shareTTL,
+		"ttl",
+		time.Hour,
+		"duration after which the shared URL expires")
+
+	setFlagsForContextCommands(openCmd)
+
+	rootCommand.AddCommand(shareCmd)
+	rootCommand.AddCommand(openCmd)
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"fmt"
+
+	"filippo.io/age"
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/snapshot"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var (
+	snapshotOutput     string
+	snapshotEncryptKey string
+	snapshotFrom       string
+	snapshotIdentity   string
+
+	snapshotCmd = &cobra.Command{
+		Use:   "snapshot",
+		Short: "Export the kubeconfigs of all contexts to an archive",
+		Long: `Export the kubeconfigs of all contexts of all stores to a tar.gz archive together with a manifest.json listing the store IDs, context names, tags and timestamps.
+The credentials (tokens, passwords, client certificates and keys) can be encrypted for an age recipient or SSH public key with --encrypt-key.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var recipient age.Recipient
+			if len(snapshotEncryptKey) > 0 {
+				var err error
+				if recipient, err = snapshot.ParseRecipient(snapshotEncryptKey); err != nil {
+					return fmt.Errorf("invalid recipient %q: %w", snapshotEncryptKey, err)
+				}
+			}
+
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return snapshot.CreateSnapshot(util.ExpandEnv(snapshotOutput), recipient, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+
+	snapshotRestoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Restore the kubeconfigs of a snapshot",
+		Long:  `Write the kubeconfigs of a snapshot to the kubeconfig directory of the first filesystem store. Every kubeconfig is written to its own directory.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if storageBackend != string(types.StoreKindFilesystem) {
+				return fmt.Errorf("snapshots can only be restored to the filesystem store")
+			}
+
+			var identities []age.Identity
+			if len(snapshotIdentity) > 0 {
+				var err error
+				if identities, err = snapshot.ParseIdentityFile(util.ExpandEnv(snapshotIdentity)); err != nil {
+					return err
+				}
+			}
+
+			stores, _, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return snapshot.RestoreSnapshot(util.ExpandEnv(snapshotFrom), identities, stores)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(snapshotCmd)
+	snapshotCmd.Flags().StringVarP(
+		&snapshotOutput,
+		"output",
+		"o",
+		"snapshot.tar.gz",
+		"path of the snapshot archive")
+	snapshotCmd.Flags().StringVar(
+		&snapshotEncryptKey,
+		"encrypt-key",
+		"",
+		"age recipient (age1...) or SSH public key to encrypt the credentials of the kubeconfigs with")
+
+	setFlagsForContextCommands(snapshotRestoreCmd)
+	snapshotRestoreCmd.Flags().StringVar(
+		&snapshotFrom,
+		"from",
+		"",
+		"path of the snapshot archive to restore")
+	snapshotRestoreCmd.Flags().StringVar(
+		&snapshotIdentity,
+		"identity",
+		"",
+		"age identity file or SSH private key to decrypt the credentials of an encrypted snapshot")
+	_ = snapshotRestoreCmd.MarkFlagRequired("from")
+
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	rootCommand.AddCommand(snapshotCmd)
+}
@@ -15,16 +15,21 @@
 package switcher
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/bombsimon/logrusr/v4"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/audit"
 
 	"github.com/danielfoehrkn/kubeswitch/pkg/cache"
+	diskcache "github.com/danielfoehrkn/kubeswitch/pkg/cache/disk"
 	"github.com/danielfoehrkn/kubeswitch/pkg/util"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -32,7 +37,18 @@ import (
 
 	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
 	"github.com/danielfoehrkn/kubeswitch/pkg/config/validation"
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/oidc"
+	"github.com/danielfoehrkn/kubeswitch/pkg/plugin"
 	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/autoselect"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/composite"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/namespaces"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/script"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/transform"
+	tokenexpiry "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/token-expiry"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
 	"github.com/danielfoehrkn/kubeswitch/types"
 )
 
@@ -45,12 +61,18 @@ const (
 
 var (
 	// root command
-	kubeconfigPath string
-	kubeconfigName string
-	showPreview    bool
-	deleteContext  bool
-	unsetContext   bool
-	currentContext bool
+	kubeconfigPath      string
+	kubeconfigName      string
+	showPreview         bool
+	noConnectivityCheck bool
+	deleteContext       bool
+	unsetContext        bool
+	currentContext      bool
+	lastContext         bool
+	filterPattern       string
+	filterMode          string
+	packageName         string
+	ignoreDirMapping    bool
 
 	// vault store
 	storageBackend          string
@@ -66,21 +88,42 @@ var (
 	version   string
 	buildDate string
 
-	showDebugLogs bool
-	noIndex       bool
+	showDebugLogs        bool
+	noIndex              bool
+	ignoreStoreErrors    bool
+	kubeconfigOutputPath string
+	kubeconfigFormat     string
+	maxKubeconfigSize    int
+
+	// dryRunWriter records the file writes instead of writing them if --dry-run is set
+	dryRun       bool
+	outputDiff   bool
+	dryRunWriter *kubeswitchio.DryRunWriter
+
+	// credentialCache caches the credentials of cloud provider stores. Nil if disabled.
+	credentialCache *credentials.CredentialCache
+
+	// initializedStores are the stores created by initialize(), stopped by StopStores when the command exits
+	initializedStores []store.KubeconfigStore
 
 	rootCommand = &cobra.Command{
 		Use:     "switcher",
 		Short:   "Launch the switch binary",
 		Long:    `The kubectx for operators.`,
 		Version: version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return setDryRun()
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			printDryRunSummary(cmd)
+		},
 		Args: func(cmd *cobra.Command, args []string) error {
 			switch {
 			case deleteContext:
 				if err := cobra.ExactArgs(1)(cmd, args); err != nil {
 					return err
 				}
-			case unsetContext || currentContext:
+			case unsetContext || currentContext || lastContext || len(filterPattern) > 0:
 				if err := cobra.NoArgs(cmd, args); err != nil {
 					return err
 				}
@@ -95,6 +138,8 @@ var (
 				return unsetContextCmd.RunE(cmd, args)
 			case currentContext:
 				return currentContextCmd.RunE(cmd, args)
+			case lastContext:
+				return switchToLastContext()
 			}
 
 			if len(args) > 0 {
@@ -108,6 +153,14 @@ var (
 				}
 			}
 
+			var filter *util.ContextFilter
+			if len(filterPattern) > 0 {
+				var err error
+				if filter, err = util.NewContextFilter(filterPattern, util.FilterMode(filterMode)); err != nil {
+					return err
+				}
+			}
+
 			stores, config, err := initialize()
 			if err != nil {
 				return err
@@ -118,9 +171,29 @@ var (
 				showPreview = false
 			}
 
-			kubeconfigPath, contextName, err := pkg.Switcher(stores, config, stateDirectory, noIndex, showPreview)
+			// command line flag overwrites the config file setting
+			if noConnectivityCheck {
+				config.PreflightConnectivityCheck = ptr.To(false)
+			}
+
+			if !ignoreDirMapping {
+				if stores, err = selectStoreForWorkingDirectory(stores, config); err != nil {
+					return err
+				}
+			}
+
+			kubeconfigPath, contextName, err := pkg.Switcher(stores, config, stateDirectory, noIndex, showPreview, filter, packageName)
+			if err != nil {
+				return err
+			}
+
+			sendTelemetry(stores, config)
+
+			if err := setNamespaceForContext(kubeconfigPath); err != nil {
+				return err
+			}
 			reportNewContext(kubeconfigPath, contextName)
-			return err
+			return nil
 		},
 		SilenceUsage: true,
 	}
@@ -128,15 +201,80 @@ var (
 
 func init() {
 	setFlagsForContextCommands(rootCommand)
+	setNamespaceFlags(rootCommand)
 	rootCommand.Flags().BoolVarP(&deleteContext, "d", "d", false, "delete desired context. Context name is required")
 	rootCommand.Flags().BoolVarP(&unsetContext, "unset", "u", false, "unset current context")
 	rootCommand.Flags().BoolVarP(&currentContext, "current", "c", false, "show current context")
+	rootCommand.Flags().BoolVar(&lastContext, "last", false, "switch back to the context that was active before the last switch")
+	rootCommand.Flags().BoolVar(&noConnectivityCheck, "no-connectivity-check", false, "skip the connectivity check of the API server of the selected context")
+	rootCommand.Flags().StringVar(&filterPattern, "filter", "", "only show the contexts matching the pattern. Switches directly if exactly one context matches")
+	rootCommand.Flags().StringVar(&filterMode, "filter-mode", string(util.FilterModeFuzzy), "how the --filter pattern is matched: \"fuzzy\" like the interactive search or \"regex\"")
+	_ = rootCommand.RegisterFlagCompletionFunc("filter-mode", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{string(util.FilterModeFuzzy), string(util.FilterModeRegex)}, cobra.ShellCompDirectiveNoFileComp
+	})
+	rootCommand.Flags().StringVar(&packageName, "package", "", "only show the contexts of the package (logical cluster group)")
+	rootCommand.Flags().BoolVar(&ignoreDirMapping, "ignore-dir-mapping", false, "search all stores instead of the store mapped to the current working directory (see \"directoryStoreMapping\" in the switch configuration file)")
+	rootCommand.PersistentFlags().BoolVar(
+		&dryRun,
+		"dry-run",
+		false,
+		"do not write any files, such as the kubeconfig, the index, the history or the audit log. Instead, print what would have been written.")
+	rootCommand.PersistentFlags().BoolVar(
+		&outputDiff,
+		"output-diff",
+		false,
+		"with --dry-run, print a unified diff of the current kubeconfig and the kubeconfig that would have been written.")
+	rootCommand.PersistentFlags().IntVar(
+		&maxKubeconfigSize,
+		"max-kubeconfig-size",
+		0,
+		"the maximum size in bytes of a kubeconfig returned by a store. Overrides the maxKubeconfigSize of all stores. Defaults to 1 MiB.")
+}
+
+// setDryRun replaces all file writes with a writer only recording the writes if --dry-run is set
+func setDryRun() error {
+	if outputDiff && !dryRun {
+		return fmt.Errorf("--output-diff can only be used together with --dry-run")
+	}
+	if !dryRun {
+		return nil
+	}
+
+	dryRunWriter = kubeswitchio.NewDryRunWriter(outputDiff, currentKubeconfigPath())
+	kubeswitchio.SetWriter(dryRunWriter)
+	return nil
+}
+
+// printDryRunSummary prints the files that would have been written if --dry-run is set
+func printDryRunSummary(cmd *cobra.Command) {
+	if dryRunWriter == nil || cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd {
+		return
+	}
+	dryRunWriter.PrintSummary(os.Stdout)
+}
+
+// currentKubeconfigPath returns the path of the kubeconfig currently used by kubectl
+func currentKubeconfigPath() string {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 {
+		return paths[0]
+	}
+	return os.ExpandEnv(defaultKubeconfigPath)
 }
 
 func NewCommandStartSwitcher() *cobra.Command {
 	return rootCommand
 }
 
+// ExitCode returns the exit code for the error returned by the command.
+// Exits with code 2 if credentials expire within the duration given by --alert-before of the token-expiry command.
+func ExitCode(err error) int {
+	var expiresSoon *tokenexpiry.ErrExpiresSoon
+	if errors.As(err, &expiresSoon) {
+		return 2
+	}
+	return 1
+}
+
 func setCommonFlags(command *cobra.Command) {
 	command.Flags().BoolVar(
 		&showDebugLogs,
@@ -158,6 +296,27 @@ func setCommonFlags(command *cobra.Command) {
 		"state-directory",
 		os.ExpandEnv("$HOME/.kube/switch-state"),
 		"path to the local directory used for storing internal state.")
+	command.Flags().BoolVar(
+		&ignoreStoreErrors,
+		"ignore-store-errors",
+		false,
+		"suppress errors of kubeconfig stores during the search, such as exceeded search timeouts.")
+	command.Flags().StringVar(
+		&kubeconfigOutputPath,
+		"kubeconfig-output",
+		"",
+		"path the kubeconfig of the selected context is written to instead of a temporary file. Supports the template {{ .ContextName }} to write one file per context.")
+	command.Flags().StringVar(
+		&kubeconfigFormat,
+		"kubeconfig-format",
+		string(kubeconfigutil.FormatYAML),
+		"format the kubeconfig of the selected context is written in: \"yaml\" or \"json\".")
+	_ = command.RegisterFlagCompletionFunc("kubeconfig-format", completeKubeconfigFormat)
+}
+
+// completeKubeconfigFormat completes the supported kubeconfig formats
+func completeKubeconfigFormat(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return []string{string(kubeconfigutil.FormatYAML), string(kubeconfigutil.FormatJSON)}, cobra.ShellCompDirectiveNoFileComp
 }
 
 func initialize() ([]store.KubeconfigStore, *types.Config, error) {
@@ -178,6 +337,55 @@ func initialize() ([]store.KubeconfigStore, *types.Config, error) {
 		config = &types.Config{}
 	}
 
+	if maxKubeconfigSize < 0 {
+		return nil, nil, fmt.Errorf("--max-kubeconfig-size must not be negative")
+	}
+
+	// command line flag overwrites the config file setting
+	if ignoreStoreErrors {
+		config.IgnoreStoreErrors = ptr.To(true)
+	}
+
+	if len(kubeconfigOutputPath) > 0 {
+		config.KubeconfigOutputPath = &kubeconfigOutputPath
+	}
+
+	if config.KubeconfigOutputPath != nil {
+		if err := kubeconfigutil.SetOutputPath(util.ExpandEnv(*config.KubeconfigOutputPath)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := kubeconfigutil.SetOutputFormat(kubeconfigFormat); err != nil {
+		return nil, nil, err
+	}
+
+	if config.CredentialPlugin != nil && *config.CredentialPlugin {
+		if err := setCredentialPlugin(); err != nil {
+			return nil, nil, fmt.Errorf("failed to configure the credential plugin: %w", err)
+		}
+	}
+
+	// installed store plugins are found like plugins installed on the PATH
+	if err := plugin.AddToPath(os.ExpandEnv(plugin.DefaultDirectory)); err != nil {
+		logrus.Debugf("failed to add the plugin directory to the PATH: %v", err)
+	}
+
+	autoGC = config.AutoGC
+	diskcache.SetCompress(config.CompressCache)
+
+	if config.AuditLogPath != nil {
+		audit.SetPath(util.ExpandEnv(*config.AuditLogPath))
+	}
+
+	// the credential cache is opt-in, as it reads the SSH private key of the user
+	if config.CredentialCache != nil && config.CredentialCache.Enabled != nil && *config.CredentialCache.Enabled {
+		credentialCache, err = newCredentialCache(config.CredentialCache)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	if kubeconfigName == defaultKubeconfigName {
 		if config.KubeconfigName != nil && *config.KubeconfigName != "" {
 			kubeconfigName = *config.KubeconfigName
@@ -198,140 +406,20 @@ func initialize() ([]store.KubeconfigStore, *types.Config, error) {
 		digitalOceanStoreAddedViaConfig bool
 	)
 	for _, kubeconfigStoreFromConfig := range config.KubeconfigStores {
-		var s store.KubeconfigStore
-
 		if kubeconfigStoreFromConfig.KubeconfigName != nil && *kubeconfigStoreFromConfig.KubeconfigName != "" {
 			kubeconfigName = *kubeconfigStoreFromConfig.KubeconfigName
 		}
 
-		switch kubeconfigStoreFromConfig.Kind {
-		case types.StoreKindFilesystem:
-			filesystemStore, err := store.NewFilesystemStore(kubeconfigName, kubeconfigStoreFromConfig)
-			if err != nil {
-				if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
-					continue
-				}
-				return nil, nil, err
-			}
-			s = filesystemStore
-
-		case types.StoreKindVault:
-			vaultStore, err := store.NewVaultStore(vaultAPIAddressFromFlag,
-				vaultTokenFileName,
-				kubeconfigName,
-				kubeconfigStoreFromConfig)
-			if err != nil {
-				if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
-					continue
-				}
-				return nil, nil, err
-			}
-			s = vaultStore
-
-		case types.StoreKindGardener:
-			gardenerStore, err := store.NewGardenerStore(kubeconfigStoreFromConfig, stateDirectory)
-			if err != nil {
-				return nil, nil, fmt.Errorf("unable to create Gardener store: %w", err)
-			}
-			s = gardenerStore
-
-		case types.StoreKindGKE:
-			gkeStore, err := store.NewGKEStore(kubeconfigStoreFromConfig, stateDirectory)
-			if err != nil {
-				return nil, nil, fmt.Errorf("unable to create GKE store: %w", err)
-			}
-			s = gkeStore
-
-		case types.StoreKindAzure:
-			azureStore, err := store.NewAzureStore(kubeconfigStoreFromConfig, stateDirectory)
-			if err != nil {
-				if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
-					continue
-				}
-				return nil, nil, fmt.Errorf("unable to create Azure store: %w", err)
-			}
-			s = azureStore
-		case types.StoreKindEKS:
-			eksStore, err := store.NewEKSStore(kubeconfigStoreFromConfig, stateDirectory)
-			if err != nil {
-				if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
-					continue
-				}
-				return nil, nil, err
-			}
-			s = eksStore
-		case types.StoreKindRancher:
-			rancherStore, err := store.NewRancherStore(kubeconfigStoreFromConfig)
-			if err != nil {
-				if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
-					continue
-				}
-				return nil, nil, err
-			}
-			s = rancherStore
-		case types.StoreKindOVH:
-			ovhStore, err := store.NewOVHStore(kubeconfigStoreFromConfig)
-			if err != nil {
-				if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
-					continue
-				}
-				return nil, nil, err
-			}
-			s = ovhStore
-		case types.StoreKindScaleway:
-			scalewayStore, err := store.NewScalewayStore(kubeconfigStoreFromConfig)
-			if err != nil {
-				if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
-					continue
-				}
-				return nil, nil, err
-			}
-			s = scalewayStore
-		case types.StoreKindDigitalOcean:
-			doStore, err := store.NewDigitalOceanStore(kubeconfigStoreFromConfig)
-			if err != nil {
-				if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
-					continue
-				}
-				return nil, nil, err
-			}
-			s = doStore
-			digitalOceanStoreAddedViaConfig = true
-		case types.StoreKindAkamai:
-			akamaiStore, err := store.NewAkamaiStore(kubeconfigStoreFromConfig)
-			if err != nil {
-				if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
-					continue
-				}
-				return nil, nil, err
-			}
-			s = akamaiStore
-		case types.StoreKindCapi:
-			capiStore, err := store.NewCapiStore(kubeconfigStoreFromConfig, stateDirectory)
-			if err != nil {
-				if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
-					continue
-				}
-				return nil, nil, err
+		s, err := newConfiguredStore(kubeconfigStoreFromConfig, kubeconfigName)
+		if err != nil {
+			if errors.Is(err, errOptionalStore) {
+				continue
 			}
-			s = capiStore
-		default:
-			return nil, nil, fmt.Errorf("unknown store %q", kubeconfigStoreFromConfig.Kind)
-		}
-
-		if showDebugLogs {
-			s.GetLogger().Logger.SetLevel(logrus.DebugLevel)
+			return nil, nil, err
 		}
 
-		// Add cache to the store
-		// defaults to in-memory cache -> prevents duplicate reads of the same kubeconfig
-		if cacheCfg := kubeconfigStoreFromConfig.Cache; cacheCfg == nil {
-			s, err = cache.New("memory", s, nil)
-		} else {
-			s, err = cache.New(cacheCfg.Kind, s, cacheCfg)
-		}
-		if err != nil {
-			return nil, nil, err
+		if kubeconfigStoreFromConfig.Kind == types.StoreKindDigitalOcean {
+			digitalOceanStoreAddedViaConfig = true
 		}
 		stores = append(stores, s)
 	}
@@ -360,9 +448,277 @@ func initialize() ([]store.KubeconfigStore, *types.Config, error) {
 	log := logrusr.New(logrus.New())
 	logf.SetLogger(log)
 
+	initializedStores = append(initializedStores, stores...)
 	return stores, config, nil
 }
 
+// StopStores stops all stores created by the command to release their resources
+func StopStores() {
+	if err := store.StopAll(context.Background(), initializedStores); err != nil {
+		logrus.Debugf("failed to stop stores: %v", err)
+	}
+	initializedStores = nil
+}
+
+// errOptionalStore is returned by newConfiguredStore if an optional store cannot be created
+var errOptionalStore = errors.New("optional store cannot be created")
+
+// newConfiguredStore creates the kubeconfig store for the given store configuration
+// including its cache, the OIDC token refresh and the transformers
+func newConfiguredStore(kubeconfigStoreFromConfig types.KubeconfigStore, kubeconfigName string) (store.KubeconfigStore, error) {
+	var (
+		s   store.KubeconfigStore
+		err error
+	)
+	if kubeconfigStoreFromConfig.LazyInit {
+		s = newLazyStore(kubeconfigStoreFromConfig, kubeconfigName)
+	} else {
+		s, err = newStore(kubeconfigStoreFromConfig, kubeconfigName)
+		if err != nil {
+			if kubeconfigStoreFromConfig.Required != nil && !*kubeconfigStoreFromConfig.Required {
+				return nil, fmt.Errorf("%w: %v", errOptionalStore, err)
+			}
+			return nil, err
+		}
+	}
+
+	store.SetLogLevel(s)
+
+	// reject oversized kubeconfigs before they are cached and parsed
+	maxSize := kubeconfigStoreFromConfig.MaxKubeconfigSize
+	if maxKubeconfigSize > 0 {
+		maxSize = maxKubeconfigSize
+	}
+	s = store.NewSizeLimitedStore(s, maxSize)
+
+	// Add cache to the store
+	// defaults to in-memory cache -> prevents duplicate reads of the same kubeconfig
+	if cacheCfg := kubeconfigStoreFromConfig.Cache; cacheCfg == nil {
+		s, err = cache.New("memory", s, nil)
+	} else {
+		s, err = cache.New(cacheCfg.Kind, s, cacheCfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// refresh the OIDC tokens after reading from the cache, as the cached kubeconfig might contain an expired token
+	if kubeconfigStoreFromConfig.OIDCRefresh {
+		s = oidc.NewRefreshingStore(s)
+	}
+
+	// expand the namespaces after refreshing the OIDC tokens, so that the namespaces are listed with valid credentials
+	if kubeconfigStoreFromConfig.ExpandNamespaces {
+		timeout := namespaces.DefaultTimeout
+		if kubeconfigStoreFromConfig.NamespaceListTimeout != nil {
+			timeout = *kubeconfigStoreFromConfig.NamespaceListTimeout
+		}
+		s = namespaces.NewStore(s, timeout, kubeconfigStoreFromConfig.NamespaceFilter)
+	}
+
+	// transform the kubeconfigs last, so that the transformers also apply to cached and refreshed kubeconfigs
+	if len(kubeconfigStoreFromConfig.Transformers) > 0 {
+		transformer, err := transform.New(kubeconfigStoreFromConfig.Transformers)
+		if err != nil {
+			return nil, err
+		}
+		s = transform.NewStore(s, transformer)
+	}
+	return s, nil
+}
+
+// newLazyStore returns a store that creates the kubeconfig store for the given store configuration on first use
+func newLazyStore(kubeconfigStoreFromConfig types.KubeconfigStore, kubeconfigName string) store.KubeconfigStore {
+	logger := logrus.WithField("store", kubeconfigStoreFromConfig.Kind)
+	return store.NewLazyStore(logger, kubeconfigStoreFromConfig, func() (store.KubeconfigStore, error) {
+		s, err := newStore(kubeconfigStoreFromConfig, kubeconfigName)
+		if err == nil {
+			store.SetLogLevel(s)
+		}
+		return s, err
+	})
+}
+
+// newStore creates the kubeconfig store for the given store configuration
+func newStore(kubeconfigStoreFromConfig types.KubeconfigStore, kubeconfigName string) (store.KubeconfigStore, error) {
+	switch kubeconfigStoreFromConfig.Kind {
+	case types.StoreKindFilesystem:
+		filesystemStore, err := store.NewFilesystemStore(kubeconfigName, kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return filesystemStore, nil
+
+	case types.StoreKindVault:
+		vaultStore, err := store.NewVaultStore(vaultAPIAddressFromFlag,
+			vaultTokenFileName,
+			kubeconfigName,
+			kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return vaultStore, nil
+
+	case types.StoreKindGardener:
+		gardenerStore, err := store.NewGardenerStore(kubeconfigStoreFromConfig, stateDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create Gardener store: %w", err)
+		}
+		return gardenerStore, nil
+
+	case types.StoreKindGKE:
+		gkeStore, err := store.NewGKEStore(kubeconfigStoreFromConfig, stateDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create GKE store: %w", err)
+		}
+		gkeStore.CredentialCache = credentialCache
+		return gkeStore, nil
+
+	case types.StoreKindAzure:
+		azureStore, err := store.NewAzureStore(kubeconfigStoreFromConfig, stateDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create Azure store: %w", err)
+		}
+		azureStore.CredentialCache = credentialCache
+		return azureStore, nil
+	case types.StoreKindEKS:
+		eksStore, err := store.NewEKSStore(kubeconfigStoreFromConfig, stateDirectory)
+		if err != nil {
+			return nil, err
+		}
+		eksStore.CredentialCache = credentialCache
+		return eksStore, nil
+	case types.StoreKindRancher:
+		rancherStore, err := store.NewRancherStore(kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return rancherStore, nil
+	case types.StoreKindOVH:
+		ovhStore, err := store.NewOVHStore(kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return ovhStore, nil
+	case types.StoreKindScaleway:
+		scalewayStore, err := store.NewScalewayStore(kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return scalewayStore, nil
+	case types.StoreKindDigitalOcean:
+		doStore, err := store.NewDigitalOceanStore(kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return doStore, nil
+	case types.StoreKindAkamai:
+		akamaiStore, err := store.NewAkamaiStore(kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return akamaiStore, nil
+	case types.StoreKindCapi:
+		capiStore, err := store.NewCapiStore(kubeconfigStoreFromConfig, stateDirectory)
+		if err != nil {
+			return nil, err
+		}
+		return capiStore, nil
+	case types.StoreKindWebDAV:
+		webDAVStore, err := store.NewWebDAVStore(kubeconfigName, kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return webDAVStore, nil
+	case types.StoreKindConsul:
+		consulStore, err := store.NewConsulStore(kubeconfigName, kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return consulStore, nil
+	case types.StoreKindEtcd:
+		etcdStore, err := store.NewEtcdStore(kubeconfigName, kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return etcdStore, nil
+	case types.StoreKindFirestore:
+		firestoreStore, err := store.NewFirestoreStore(kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return firestoreStore, nil
+	case types.StoreKindHTTP:
+		httpStore, err := store.NewHTTPStore(kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return httpStore, nil
+	case types.StoreKindScript:
+		scriptStore, err := script.NewScriptStore(kubeconfigStoreFromConfig)
+		if err != nil {
+			return nil, err
+		}
+		return scriptStore, nil
+	case types.StoreKindComposite:
+		return composite.NewCompositeStore(kubeconfigStoreFromConfig, func(childStoreFromConfig types.KubeconfigStore) (store.KubeconfigStore, error) {
+			childStore, err := newStore(childStoreFromConfig, kubeconfigName)
+			if err == nil {
+				store.SetLogLevel(childStore)
+			}
+			return childStore, err
+		})
+	case types.StoreKindRoundRobin:
+		return composite.NewRoundRobinStore(kubeconfigStoreFromConfig, func(replicaStoreFromConfig types.KubeconfigStore) (store.KubeconfigStore, error) {
+			replicaStore, err := newStore(replicaStoreFromConfig, kubeconfigName)
+			if err == nil {
+				store.SetLogLevel(replicaStore)
+			}
+			return replicaStore, err
+		})
+	case types.StoreKindFailover:
+		return composite.NewFailoverStore(kubeconfigStoreFromConfig, func(replicaStoreFromConfig types.KubeconfigStore) (store.KubeconfigStore, error) {
+			replicaStore, err := newStore(replicaStoreFromConfig, kubeconfigName)
+			if err == nil {
+				store.SetLogLevel(replicaStore)
+			}
+			return replicaStore, err
+		})
+	case types.StoreKindFallback:
+		return store.NewFallbackStore(kubeconfigStoreFromConfig, func(childStoreFromConfig types.KubeconfigStore) (store.KubeconfigStore, error) {
+			childStore, err := newStore(childStoreFromConfig, kubeconfigName)
+			if err == nil {
+				store.SetLogLevel(childStore)
+			}
+			return childStore, err
+		})
+	default:
+		return nil, fmt.Errorf("unknown store %q", kubeconfigStoreFromConfig.Kind)
+	}
+}
+
+// selectStoreForWorkingDirectory restricts the search to the store mapped to the current working directory.
+// Returns all stores if the working directory is not mapped.
+// Only applied to the selection dialog of the root command, the subcommands search all stores.
+func selectStoreForWorkingDirectory(stores []store.KubeconfigStore, config *types.Config) ([]store.KubeconfigStore, error) {
+	if len(config.DirectoryStoreMapping) == 0 {
+		return stores, nil
+	}
+
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the current working directory: %w", err)
+	}
+
+	mapping := autoselect.Select(config.DirectoryStoreMapping, workingDirectory)
+	if mapping == nil {
+		return stores, nil
+	}
+
+	logrus.Debugf("Only searching store %q mapped to directory %q", mapping.Store, mapping.Directory)
+	return autoselect.FilterStores(stores, *mapping)
+}
+
 // getStoreFromFlagAndEnv translates the kubeconfig flag --kubeconfig-path & environment variable KUBECONFIG into a
 // dedicated store in addition to the stores configured in the switch-config.yaml.
 // This way, it is "just another store" -> does not need special handling
@@ -382,7 +738,7 @@ func getStoreFromFlagAndEnv(config *types.Config) *types.KubeconfigStore {
 	for _, path := range pathsFromEnv {
 		if !isDuplicatePath(config.KubeconfigStores, path) && !strings.HasSuffix(path, ".tmp") && path != "" {
 			// the KUBECONFIG env sets a unique, non kubeswitch set, env variable to a kubeconfig.
-			paths = append(paths, util.ExpandEnv(path))
+			paths = append(paths, util.ResolveKubeconfigPath(path))
 			logrus.Debugf("Adding kubeconfig path from KUBECONFIG env %s", kubeconfigPathFromEnv)
 		}
 	}
@@ -411,15 +767,15 @@ func getKubeconfigPathFromFlag() string {
 	kubeconfigPath = strings.ReplaceAll(kubeconfigPath, "~", "$HOME")
 	if kubeconfigPath == defaultKubeconfigPath {
 		// do not return, if the kubeconfig under the default kubeconfig path does not exist
-		if _, err := os.Stat(os.ExpandEnv(defaultKubeconfigPath)); err != nil {
+		if _, err := os.Stat(util.ResolveKubeconfigPath(defaultKubeconfigPath)); err != nil {
 			return ""
 		}
 		// the kubeconfig under the default path exists -> return it.
-		return os.ExpandEnv(defaultKubeconfigPath)
+		return util.ResolveKubeconfigPath(defaultKubeconfigPath)
 	}
 
 	// the flag sets a non-default kubeconfig path
-	return os.ExpandEnv(kubeconfigPath)
+	return util.ResolveKubeconfigPath(kubeconfigPath)
 }
 
 // isDuplicatePath searches through all kubeconfig stores in the switch-config.yaml and checks if the
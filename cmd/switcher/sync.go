@@ -0,0 +1,70 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	syncstore "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/sync-store"
+)
+
+var (
+	syncOptions = syncstore.Options{}
+
+	syncCmd = &cobra.Command{
+		Use:   "sync --from-store <store-id> --to-store <filesystem-store-id>",
+		Short: "Synchronize the contexts of a store to a filesystem store",
+		Long: `Writes every context of the source store as standalone kubeconfig file <context-name>.yaml to the target filesystem store, e.g. for offline use.
+The files are written to a directory named after the source store in the first kubeconfig directory of the target store. Only changed files are written.
+Eg: switch sync --from-store eks.default --to-store filesystem.offline --delete-stale`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return syncstore.Sync(syncOptions, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(syncCmd)
+	syncCmd.Flags().StringVar(
+		&syncOptions.FromStore,
+		"from-store",
+		"",
+		"ID of the store to synchronize the contexts from, e.g. \"eks.default\"")
+	syncCmd.Flags().StringVar(
+		&syncOptions.ToStore,
+		"to-store",
+		"",
+		"ID of the filesystem store to write the contexts to, e.g. \"filesystem.default\"")
+	syncCmd.Flags().IntVar(
+		&syncOptions.Concurrency,
+		"concurrency",
+		5,
+		"maximum number of kubeconfigs fetched in parallel")
+	syncCmd.Flags().BoolVar(
+		&syncOptions.DeleteStale,
+		"delete-stale",
+		false,
+		"remove the files of contexts which no longer exist in the source store")
+	_ = syncCmd.MarkFlagRequired("from-store")
+	_ = syncCmd.MarkFlagRequired("to-store")
+
+	rootCommand.AddCommand(syncCmd)
+}
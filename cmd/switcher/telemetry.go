@@ -0,0 +1,212 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/telemetry"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var (
+	telemetryCmd = &cobra.Command{
+		Use:   "telemetry",
+		Short: "Manage the opt-in anonymous usage statistics",
+		Long: `Manage the opt-in anonymous usage statistics that help the maintainers to prioritize the development.
+If enabled, each switch sends the kubeswitch version, the OS and CPU architecture, the kinds of the configured stores,
+the range of the number of contexts and percentiles of the search durations to the "telemetryEndpoint" of the switch configuration file.
+No context names, store IDs, paths, hostnames or usernames are sent. Use "switch telemetry status" to see the sent data.`,
+	}
+
+	telemetryEnableCmd = &cobra.Command{
+		Use:   "enable",
+		Short: "Opt in to send anonymous usage statistics",
+		Args:  cobra.NoArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setTelemetryEnabled(true)
+		},
+		SilenceUsage: true,
+	}
+
+	telemetryDisableCmd = &cobra.Command{
+		Use:   "disable",
+		Short: "Stop sending anonymous usage statistics",
+		Args:  cobra.NoArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setTelemetryEnabled(false)
+		},
+		SilenceUsage: true,
+	}
+
+	telemetryStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Shows if anonymous usage statistics are sent and an example of the sent data",
+		Args:  cobra.NoArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			settings, err := telemetry.LoadSettings(os.ExpandEnv(telemetry.DefaultSettingsPath))
+			if err != nil {
+				return err
+			}
+
+			config, err := switchconfig.LoadConfigFromFile(util.ExpandEnv(configPath))
+			if err != nil {
+				return fmt.Errorf("failed to read switch config file: %v", err)
+			}
+
+			var endpoint string
+			if config != nil {
+				endpoint = config.TelemetryEndpoint
+			}
+
+			switch {
+			case !settings.Enabled:
+				fmt.Println("Telemetry is disabled.")
+			case len(endpoint) == 0:
+				fmt.Println("Telemetry is enabled, but no data is sent because \"telemetryEndpoint\" is not set in the switch configuration file.")
+			default:
+				fmt.Printf("Telemetry is enabled. Sending usage statistics to %s\n", endpoint)
+			}
+
+			example, err := json.MarshalIndent(telemetry.NewEvent(version, []types.StoreKind{types.StoreKindFilesystem}, 42, []time.Duration{120 * time.Millisecond}), "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\nExample of the data sent after each switch:\n%s\n", example)
+			return nil
+		},
+		SilenceUsage: true,
+	}
+
+	telemetrySendCmd = &cobra.Command{
+		Use:    "send event",
+		Short:  "Sends the usage statistics of a switch. Started in the background after each switch.",
+		Args:   cobra.ExactArgs(1),
+		Hidden: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var event telemetry.Event
+			if err := json.Unmarshal([]byte(args[0]), &event); err != nil {
+				return fmt.Errorf("invalid telemetry event: %w", err)
+			}
+
+			config, err := switchconfig.LoadConfigFromFile(util.ExpandEnv(configPath))
+			if err != nil {
+				return fmt.Errorf("failed to read switch config file: %v", err)
+			}
+			if config == nil || len(config.TelemetryEndpoint) == 0 {
+				return nil
+			}
+			return telemetry.Send(config.TelemetryEndpoint, event)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(telemetryStatusCmd)
+	setFlagsForContextCommands(telemetrySendCmd)
+	telemetryCmd.AddCommand(telemetrySendCmd)
+	telemetryCmd.AddCommand(telemetryEnableCmd)
+	telemetryCmd.AddCommand(telemetryDisableCmd)
+	telemetryCmd.AddCommand(telemetryStatusCmd)
+	rootCommand.AddCommand(telemetryCmd)
+}
+
+// setTelemetryEnabled opts in or out of sending anonymous usage statistics
+func setTelemetryEnabled(enabled bool) error {
+	if err := telemetry.SaveSettings(os.ExpandEnv(telemetry.DefaultSettingsPath), telemetry.Settings{Enabled: enabled}); err != nil {
+		return err
+	}
+
+	if enabled {
+		fmt.Println("Telemetry enabled. Thank you! Use \"switch telemetry status\" to see the sent data.")
+		return nil
+	}
+	fmt.Println("Telemetry disabled.")
+	return nil
+}
+
+// sendTelemetry starts "switch telemetry send" in the background to send the usage statistics of the search if the user opted in,
+// so that the switch does not wait for the telemetry endpoint.
+// Failures are only logged, as the switch already succeeded.
+func sendTelemetry(stores []store.KubeconfigStore, config *types.Config) {
+	if len(config.TelemetryEndpoint) == 0 || kubeswitchio.IsDryRun() {
+		return
+	}
+
+	settings, err := telemetry.LoadSettings(os.ExpandEnv(telemetry.DefaultSettingsPath))
+	if err != nil {
+		logrus.Debugf("not sending telemetry: %v", err)
+		return
+	}
+	if !settings.Enabled {
+		return
+	}
+
+	storeKinds := make([]types.StoreKind, 0, len(stores))
+	for _, s := range stores {
+		storeKinds = append(storeKinds, s.GetKind())
+	}
+
+	var searchDurations []time.Duration
+	for _, duration := range pkg.GetSearchDurations() {
+		searchDurations = append(searchDurations, duration)
+	}
+	event, err := json.Marshal(telemetry.NewEvent(version, storeKinds, pkg.GetContextCount(), searchDurations))
+	if err != nil {
+		logrus.Debugf("not sending telemetry: %v", err)
+		return
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		logrus.Debugf("not sending telemetry: %v", err)
+		return
+	}
+
+	switchConfigPath, err := filepath.Abs(util.ExpandEnv(configPath))
+	if err != nil {
+		logrus.Debugf("not sending telemetry: %v", err)
+		return
+	}
+
+	command := exec.Command(executable, "telemetry", "send", "--config-path", switchConfigPath, string(event))
+	if err := command.Start(); err != nil {
+		logrus.Debugf("not sending telemetry: %v", err)
+		return
+	}
+	_ = command.Process.Release()
+}
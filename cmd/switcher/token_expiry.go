@@ -0,0 +1,81 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	tokenexpiry "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/token-expiry"
+)
+
+var (
+	tokenExpiryOutput      string
+	tokenExpiryAlertBefore time.Duration
+
+	tokenExpiryCmd = &cobra.Command{
+		Use:   "token-expiry [context-name]",
+		Short: "Show when the credentials of a context expire",
+		Long: `Show the type (JWT, token or cert) and expiry of the credentials of the user of a context. Defaults to the current context.
+Client certificates, static tokens, OIDC id tokens and tokens of exec plugins are checked. The exec plugin is invoked to get the current token.
+With --alert-before, exits with code 2 if any credential expires within the given duration, e.g. as pre-flight check in CI.`,
+		Example: `  switch token-expiry dev/my-cluster
+  switch token-expiry --output json
+  switch token-expiry dev/my-cluster --alert-before 1h`,
+		Args: cobra.MaximumNArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contextName := "."
+			if len(args) > 0 {
+				contextName = args[0]
+			}
+
+			contextName, err := resolveContextName(contextName)
+			if err != nil {
+				return err
+			}
+
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return tokenexpiry.ShowTokenExpiry(contextName, tokenExpiryOutput, tokenExpiryAlertBefore, stores, config, stateDirectory, noIndex, credentialCache)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(tokenExpiryCmd)
+	tokenExpiryCmd.Flags().StringVarP(
+		&tokenExpiryOutput,
+		"output",
+		"o",
+		tokenexpiry.OutputText,
+		"output format. One of: text|json")
+	tokenExpiryCmd.Flags().DurationVar(
+		&tokenExpiryAlertBefore,
+		"alert-before",
+		0,
+		"exit with code 2 if any credential expires within this duration")
+
+	rootCommand.AddCommand(tokenExpiryCmd)
+}
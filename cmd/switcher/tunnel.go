@@ -0,0 +1,72 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/tunnel"
+)
+
+var (
+	tunnelCmd = &cobra.Command{
+		Use:   "tunnel",
+		Short: "Route the API servers of clusters through tunnels",
+		Long:  `Commands to expose clusters with private API servers through tunnels.`,
+	}
+
+	tunnelCloudflareCmd = &cobra.Command{
+		Use:   "cloudflare",
+		Short: "Cloudflare Tunnel specific commands",
+		Long:  `Commands that can only be used if a Cloudflare Tunnel is configured for a CAPI store.`,
+	}
+
+	tunnelCloudflareStartCmd = &cobra.Command{
+		Use:   "start <context>",
+		Short: "Route the API server of a cluster through the Cloudflare Tunnel",
+		Long: `Route the API server of the cluster of the given context through the Cloudflare Tunnel configured for its CAPI store.
+Adds an ingress rule for the public hostname <namespace>-<cluster>.cfargotunnel.com to the tunnel.
+Afterwards, the kubeconfig of the context uses the public hostname as API server.`,
+		Example: `  switch tunnel cloudflare start capi/my-cluster-admin@my-cluster`,
+		Args:    cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completeContexts(toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			contextName, err := resolveContextName(args[0])
+			if err != nil {
+				return err
+			}
+
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return tunnel.StartCloudflareTunnel(contextName, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(tunnelCloudflareStartCmd)
+
+	tunnelCloudflareCmd.AddCommand(tunnelCloudflareStartCmd)
+	tunnelCmd.AddCommand(tunnelCloudflareCmd)
+	rootCommand.AddCommand(tunnelCmd)
+}
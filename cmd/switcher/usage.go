@@ -0,0 +1,88 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/usage"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var (
+	usageOptions usage.Options
+
+	usageCmd = &cobra.Command{
+		Use:   "usage",
+		Short: "Show how often each context is switched to",
+		Long: `Show the number of switches, the last access and the store kind per context as recorded in the audit log, ranked by the number of switches.
+The audit log is disabled by default and enabled by setting "auditLogPath" in the switch configuration file.
+It can be shared by a team to group the statistics by OS username with --by-user.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := switchconfig.LoadConfigFromFile(util.ExpandEnv(configPath))
+			if err != nil {
+				return fmt.Errorf("failed to read switch config file: %v", err)
+			}
+
+			if config == nil || config.AuditLogPath == nil || len(*config.AuditLogPath) == 0 {
+				return fmt.Errorf("the audit log is disabled. Please set \"auditLogPath\" in the switch config file to record switches")
+			}
+
+			return usage.ShowUsage(util.ExpandEnv(*config.AuditLogPath), usageOptions)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	usageCmd.Flags().StringVar(
+		&configPath,
+		"config-path",
+		os.ExpandEnv("$HOME/.kube/switch-config.yaml"),
+		"path on the local filesystem to the configuration file.")
+	usageCmd.Flags().DurationVar(
+		&usageOptions.Since,
+		"since",
+		time.Duration(0),
+		"only consider switches within the given duration, e.g. 720h for the last 30 days")
+	usageCmd.Flags().IntVar(
+		&usageOptions.Top,
+		"top",
+		0,
+		"only show the given number of most used contexts")
+	usageCmd.Flags().StringVarP(
+		&usageOptions.Output,
+		"output",
+		"o",
+		usage.OutputTable,
+		"output format. One of: table|json|csv")
+	usageCmd.Flags().BoolVar(
+		&usageOptions.ByUser,
+		"by-user",
+		false,
+		"group the statistics by OS username")
+	usageCmd.Flags().BoolVar(
+		&usageOptions.Heatmap,
+		"heatmap",
+		false,
+		"show a calendar of the daily switches instead of the statistics")
+
+	rootCommand.AddCommand(usageCmd)
+}
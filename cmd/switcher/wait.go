@@ -0,0 +1,88 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/wait"
+)
+
+var (
+	waitContext        string
+	waitReady          bool
+	waitTimeout        time.Duration
+	waitReadyThreshold int
+
+	waitCmd = &cobra.Command{
+		Use:   "wait",
+		Short: "Wait for the cluster of a context to become ready",
+		Long: `Wait for the API server of the cluster of a context to become ready, e.g. after provisioning the cluster.
+The API server is polled (GET /api) every 5 seconds until it responded to --ready-threshold consecutive health checks or the timeout expires.
+Exits with code 1 if the cluster did not become ready in time.`,
+		Example: `  switch wait --context dev/my-cluster --ready --timeout 10m`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !waitReady {
+				return fmt.Errorf("please specify the condition to wait for. Supported conditions: --ready")
+			}
+
+			contextName, err := resolveContextName(waitContext)
+			if err != nil {
+				return err
+			}
+
+			stores, config, err := initialize()
+			if err != nil {
+				return err
+			}
+
+			return wait.WaitForReady(contextName, waitTimeout, waitReadyThreshold, stores, config, stateDirectory, noIndex)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	setFlagsForContextCommands(waitCmd)
+	waitCmd.Flags().StringVar(
+		&waitContext,
+		"context",
+		"",
+		"the name of the context to wait for")
+	waitCmd.Flags().BoolVar(
+		&waitReady,
+		"ready",
+		false,
+		"wait for the API server of the cluster to respond")
+	waitCmd.Flags().DurationVar(
+		&waitTimeout,
+		"timeout",
+		wait.DefaultTimeout,
+		"the maximum duration to wait for the cluster")
+	waitCmd.Flags().IntVar(
+		&waitReadyThreshold,
+		"ready-threshold",
+		wait.DefaultReadyThreshold,
+		"the number of consecutive successful health checks required to declare the cluster ready")
+	_ = waitCmd.MarkFlagRequired("context")
+	_ = waitCmd.RegisterFlagCompletionFunc("context", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeContexts(toComplete)
+	})
+
+	rootCommand.AddCommand(waitCmd)
+}
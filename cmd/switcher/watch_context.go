@@ -0,0 +1,57 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package switcher
+
+import (
+	"github.com/spf13/cobra"
+
+	watchcontext "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/watch-context"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var (
+	watchBell    bool
+	watchCommand string
+
+	watchContextCmd = &cobra.Command{
+		Use:   "watch-context",
+		Short: "Watch the kubeconfig for context changes",
+		Long:  `Watch the kubeconfig for changes of the current-context, e.g. made by other terminals or processes, and print a notification on each change.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return watchcontext.WatchContext(util.ResolveKubeconfigPath(kubeconfigPath), watchBell, watchCommand)
+		},
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	watchContextCmd.Flags().StringVar(
+		&kubeconfigPath,
+		"kubeconfig-path",
+		defaultKubeconfigPath,
+		"path to the kubeconfig file to watch")
+	watchContextCmd.Flags().BoolVar(
+		&watchBell,
+		"bell",
+		false,
+		"ring the terminal bell when the context changes")
+	watchContextCmd.Flags().StringVar(
+		&watchCommand,
+		"exec",
+		"",
+		"command to run when the context changes. The environment variables KUBESWITCH_OLD_CONTEXT and KUBESWITCH_NEW_CONTEXT are set.")
+
+	rootCommand.AddCommand(watchContextCmd)
+}
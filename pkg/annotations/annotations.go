@@ -0,0 +1,169 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// DefaultPath is the default path of the file containing the user-defined annotations
+	DefaultPath = "$HOME/.kube/switch-annotations.yaml"
+	// TagPrefix is the prefix of the search result tags containing the user-defined annotations
+	TagPrefix = "user."
+)
+
+// path is the path of the annotations file
+var path = DefaultPath
+
+// Annotations maps context names to the user-defined annotations of the context.
+// Annotations are keyed by context name, so that they survive the re-creation of a cluster.
+type Annotations map[string]map[string]string
+
+// SetPath configures the path of the annotations file
+func SetPath(annotationsPath string) {
+	path = annotationsPath
+}
+
+// GetPath returns the path of the annotations file
+func GetPath() string {
+	return os.ExpandEnv(path)
+}
+
+// Load reads the annotations from the annotations file.
+// Returns empty annotations if the file does not exist.
+func Load() (Annotations, error) {
+	annotationsPath := GetPath()
+	bytes, err := os.ReadFile(annotationsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Annotations{}, nil
+		}
+		return nil, fmt.Errorf("failed to read annotations file %q: %w", annotationsPath, err)
+	}
+
+	annotations := Annotations{}
+	if err := yaml.Unmarshal(bytes, &annotations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal annotations file %q: %w", annotationsPath, err)
+	}
+	return annotations, nil
+}
+
+// Save writes the annotations to the annotations file
+func (a Annotations) Save() error {
+	output, err := yaml.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	annotationsPath := GetPath()
+	if err := kubeswitchio.MkdirAll(filepath.Dir(annotationsPath), 0755); err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(annotationsPath, output, 0644)
+}
+
+// Set sets the annotation with the given key for the context
+func (a Annotations) Set(contextName, key, value string) error {
+	if len(key) == 0 || strings.Contains(key, "=") {
+		return fmt.Errorf("invalid annotation key %q", key)
+	}
+
+	if a[contextName] == nil {
+		a[contextName] = make(map[string]string)
+	}
+	a[contextName][key] = value
+	return nil
+}
+
+// Remove removes the annotations with the given keys from the context.
+// Removes all annotations of the context if no keys are given.
+// Returns the keys of the removed annotations.
+func (a Annotations) Remove(contextName string, keys ...string) []string {
+	if len(keys) == 0 {
+		keys = a.Keys(contextName)
+	}
+
+	var removed []string
+	for _, key := range keys {
+		if _, ok := a[contextName][key]; ok {
+			delete(a[contextName], key)
+			removed = append(removed, key)
+		}
+	}
+
+	if len(a[contextName]) == 0 {
+		delete(a, contextName)
+	}
+	return removed
+}
+
+// Keys returns the sorted keys of the annotations of the context
+func (a Annotations) Keys(contextName string) []string {
+	keys := make([]string, 0, len(a[contextName]))
+	for key := range a[contextName] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AddTags adds the annotations of the context to the given tags with the TagPrefix.
+// Returns the given tags if the context does not have annotations.
+func (a Annotations) AddTags(contextName string, tags map[string]string) map[string]string {
+	if len(a[contextName]) == 0 {
+		return tags
+	}
+
+	result := make(map[string]string, len(tags)+len(a[contextName]))
+	for key, value := range tags {
+		result[key] = value
+	}
+	for key, value := range a[contextName] {
+		result[TagPrefix+key] = value
+	}
+	return result
+}
+
+// SplitTags splits the tags into the tags of the store and the user-defined annotations without the TagPrefix.
+// Only the given annotations of the context added with AddTags are separated,
+// tags of the store starting with the TagPrefix remain tags of the store.
+func SplitTags(tags map[string]string, contextAnnotations map[string]string) (map[string]string, map[string]string) {
+	var storeTags, annotations map[string]string
+	for key, value := range tags {
+		if annotationKey, ok := strings.CutPrefix(key, TagPrefix); ok {
+			if _, isAnnotation := contextAnnotations[annotationKey]; isAnnotation {
+				if annotations == nil {
+					annotations = make(map[string]string)
+				}
+				annotations[annotationKey] = value
+				continue
+			}
+		}
+
+		if storeTags == nil {
+			storeTags = make(map[string]string)
+		}
+		storeTags[key] = value
+	}
+	return storeTags, annotations
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotations_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+)
+
+var _ = Describe("Annotations", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-annotations")
+		Expect(err).ToNot(HaveOccurred())
+		annotations.SetPath(filepath.Join(tempDir, "kube", "switch-annotations.yaml"))
+	})
+
+	AfterEach(func() {
+		annotations.SetPath(annotations.DefaultPath)
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("returns empty annotations if the file does not exist", func() {
+		a, err := annotations.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(a).To(BeEmpty())
+	})
+
+	It("saves and loads the annotations", func() {
+		a := annotations.Annotations{}
+		Expect(a.Set("dev/cluster", "owner", "team-a")).To(Succeed())
+		Expect(a.Set("dev/cluster", "note", "do not use on fridays")).To(Succeed())
+		Expect(a.Save()).To(Succeed())
+
+		loaded, err := annotations.Load()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loaded).To(Equal(annotations.Annotations{
+			"dev/cluster": {"owner": "team-a", "note": "do not use on fridays"},
+		}))
+		Expect(loaded.Keys("dev/cluster")).To(Equal([]string{"note", "owner"}))
+	})
+
+	It("rejects invalid keys", func() {
+		a := annotations.Annotations{}
+		Expect(a.Set("dev/cluster", "", "value")).ToNot(Succeed())
+		Expect(a.Set("dev/cluster", "a=b", "value")).ToNot(Succeed())
+	})
+
+	It("removes single and all annotations of a context", func() {
+		a := annotations.Annotations{
+			"dev/cluster": {"owner": "team-a", "note": "deprecated"},
+			"prod":        {"owner": "team-b"},
+		}
+
+		Expect(a.Remove("dev/cluster", "note", "unknown")).To(Equal([]string{"note"}))
+		Expect(a["dev/cluster"]).To(Equal(map[string]string{"owner": "team-a"}))
+
+		Expect(a.Remove("dev/cluster")).To(Equal([]string{"owner"}))
+		Expect(a).ToNot(HaveKey("dev/cluster"))
+		Expect(a).To(HaveKey("prod"))
+	})
+
+	It("adds the annotations to the tags and splits them again", func() {
+		a := annotations.Annotations{"dev/cluster": {"owner": "team-a"}}
+		tags := map[string]string{"region": "eu"}
+
+		result := a.AddTags("dev/cluster", tags)
+		Expect(result).To(Equal(map[string]string{"region": "eu", "user.owner": "team-a"}))
+		Expect(tags).To(Equal(map[string]string{"region": "eu"}))
+		Expect(a.AddTags("other", tags)).To(Equal(tags))
+
+		storeTags, userAnnotations := annotations.SplitTags(result, a["dev/cluster"])
+		Expect(storeTags).To(Equal(map[string]string{"region": "eu"}))
+		Expect(userAnnotations).To(Equal(map[string]string{"owner": "team-a"}))
+	})
+
+	It("keeps tags of the store starting with the tag prefix", func() {
+		a := annotations.Annotations{"dev/cluster": {"owner": "team-a"}}
+		tags := map[string]string{"region": "eu", "user.team": "platform"}
+
+		storeTags, userAnnotations := annotations.SplitTags(a.AddTags("dev/cluster", tags), a["dev/cluster"])
+		Expect(storeTags).To(Equal(tags))
+		Expect(userAnnotations).To(Equal(map[string]string{"owner": "team-a"}))
+
+		storeTags, userAnnotations = annotations.SplitTags(tags, nil)
+		Expect(storeTags).To(Equal(tags))
+		Expect(userAnnotations).To(BeEmpty())
+	})
+})
@@ -0,0 +1,119 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// path is the path of the audit log. Switches are not recorded if the path is empty.
+// The audit log is opt-in, as it records the OS username of every switch.
+var path string
+
+// Entry is a switch to a context recorded in the audit log
+type Entry struct {
+	// Time is the time of the switch
+	Time time.Time `json:"time"`
+	// User is the OS username of the user switching the context
+	User string `json:"user"`
+	// Context is the name of the context as shown in the search results
+	Context string `json:"context"`
+	// StoreID is the ID of the store of the context
+	StoreID string `json:"storeID"`
+	// StoreKind is the kind of the store of the context
+	StoreKind types.StoreKind `json:"storeKind"`
+}
+
+// SetPath configures the path of the audit log.
+// If the path is empty, switches are not recorded. The audit log is disabled by default.
+func SetPath(auditLogPath string) {
+	path = auditLogPath
+}
+
+// GetPath returns the path of the audit log
+func GetPath() string {
+	return os.ExpandEnv(path)
+}
+
+// Record appends a switch to the given context to the audit log
+func Record(contextName, storeID string, storeKind types.StoreKind) error {
+	if len(path) == 0 {
+		return nil
+	}
+
+	entry, err := json.Marshal(Entry{
+		Time:      time.Now().UTC(),
+		User:      currentUser(),
+		Context:   contextName,
+		StoreID:   storeID,
+		StoreKind: storeKind,
+	})
+	if err != nil {
+		return err
+	}
+
+	auditLogPath := GetPath()
+	if err := kubeswitchio.MkdirAll(filepath.Dir(auditLogPath), 0755); err != nil {
+		return err
+	}
+
+	// the audit log may be shared with other users, e.g. via a shared filesystem.
+	// A single write per entry, so that concurrent appends are not interleaved.
+	return kubeswitchio.AppendFile(auditLogPath, append(entry, '\n'), 0644)
+}
+
+// Read returns all entries of the audit log at the given path.
+// Malformed lines are skipped.
+func Read(auditLogPath string) ([]Entry, error) {
+	file, err := os.Open(auditLogPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no audit log entries yet - please run `switch` first")
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// currentUser returns the OS username of the current user
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	if username, ok := os.LookupEnv("USER"); ok {
+		return username
+	}
+	return os.Getenv("USERNAME")
+}
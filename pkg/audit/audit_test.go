@@ -0,0 +1,121 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/audit"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("Audit log", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-audit")
+		Expect(err).ToNot(HaveOccurred())
+		audit.SetPath(filepath.Join(tempDir, "audit", "audit.log"))
+	})
+
+	AfterEach(func() {
+		audit.SetPath("")
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should record and read switches", func() {
+		Expect(audit.Record("a", "filesystem.default", types.StoreKindFilesystem)).To(Succeed())
+		Expect(audit.Record("b", "vault.default", types.StoreKindVault)).To(Succeed())
+
+		// malformed lines are skipped
+		f, err := os.OpenFile(audit.GetPath(), os.O_APPEND|os.O_WRONLY, 0644)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.WriteString("malformed\n")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		entries, err := audit.Read(audit.GetPath())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Context).To(Equal("a"))
+		Expect(entries[0].StoreKind).To(Equal(types.StoreKindFilesystem))
+		Expect(entries[0].User).ToNot(BeEmpty())
+		Expect(entries[1].StoreID).To(Equal("vault.default"))
+	})
+
+	It("should not record switches if disabled", func() {
+		audit.SetPath("")
+		Expect(audit.Record("a", "filesystem.default", types.StoreKindFilesystem)).To(Succeed())
+		_, err := os.Stat(filepath.Join(tempDir, "audit"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should be disabled by default", func() {
+		audit.SetPath("")
+		Expect(audit.GetPath()).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Stats", func() {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	entries := []audit.Entry{
+		{Time: now.Add(-72 * time.Hour), User: "alice", Context: "a", StoreKind: types.StoreKindVault},
+		{Time: now.Add(-2 * time.Hour), User: "alice", Context: "a", StoreKind: types.StoreKindFilesystem},
+		{Time: now.Add(-time.Hour), User: "bob", Context: "a", StoreKind: types.StoreKindFilesystem},
+		{Time: now.Add(-3 * time.Hour), User: "bob", Context: "b", StoreKind: types.StoreKindEKS},
+		{Time: now.Add(-30 * time.Minute), User: "bob", Context: "c", StoreKind: types.StoreKindGKE},
+	}
+
+	It("should rank the contexts by the number of switches", func() {
+		stats := audit.ComputeStats(entries, false)
+		Expect(stats).To(Equal([]audit.Stats{
+			{Context: "a", Switches: 3, LastAccessed: now.Add(-time.Hour), StoreKind: types.StoreKindFilesystem},
+			{Context: "c", Switches: 1, LastAccessed: now.Add(-30 * time.Minute), StoreKind: types.StoreKindGKE},
+			{Context: "b", Switches: 1, LastAccessed: now.Add(-3 * time.Hour), StoreKind: types.StoreKindEKS},
+		}))
+	})
+
+	It("should group the statistics by user", func() {
+		stats := audit.ComputeStats(audit.Filter(entries, now.Add(-24*time.Hour)), true)
+		Expect(stats).To(Equal([]audit.Stats{
+			{Context: "c", User: "bob", Switches: 1, LastAccessed: now.Add(-30 * time.Minute), StoreKind: types.StoreKindGKE},
+			{Context: "a", User: "bob", Switches: 1, LastAccessed: now.Add(-time.Hour), StoreKind: types.StoreKindFilesystem},
+			{Context: "a", User: "alice", Switches: 1, LastAccessed: now.Add(-2 * time.Hour), StoreKind: types.StoreKindFilesystem},
+			{Context: "b", User: "bob", Switches: 1, LastAccessed: now.Add(-3 * time.Hour), StoreKind: types.StoreKindEKS},
+		}))
+	})
+
+	It("should render a heatmap of the daily switches", func() {
+		lines := strings.Split(audit.Heatmap(entries, now), "\n")
+		// month labels, the days of the week, an empty line and the legend
+		Expect(lines[0]).To(Equal("    Oct"))
+		Expect(lines[1]).To(Equal("Mo  ░"))
+		Expect(lines[4]).To(Equal("Th  █"))
+		Expect(lines[5]).To(Equal("Fr   "))
+		Expect(lines[9]).To(ContainSubstring("max. 4 switches per day"))
+
+		// the calendar starts at the week of the first entry
+		lines = strings.Split(audit.Heatmap(append(entries, audit.Entry{Time: now.AddDate(0, 0, -14)}), now), "\n")
+		Expect(lines[0]).To(Equal("     Oct"))
+		Expect(lines[4]).To(Equal("Th  ░·█"))
+	})
+})
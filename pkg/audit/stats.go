@@ -0,0 +1,197 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const (
+	// maxHeatmapWeeks limits the heatmap to roughly one year
+	maxHeatmapWeeks = 53
+	// day is the duration of a day in the heatmap
+	day = 24 * time.Hour
+)
+
+// heatmapLevels are the characters of the heatmap from no usage to the highest usage
+var heatmapLevels = []string{"·", "░", "▒", "▓", "█"}
+
+// Stats are the statistics of the switches to a context
+type Stats struct {
+	// Context is the name of the context
+	Context string `json:"context"`
+	// User is the OS username. Only set if the statistics are grouped by user.
+	User string `json:"user,omitempty"`
+	// Switches is the number of switches to the context
+	Switches int `json:"switches"`
+	// LastAccessed is the time of the last switch to the context
+	LastAccessed time.Time `json:"lastAccessed"`
+	// StoreKind is the kind of the store of the context at the last switch
+	StoreKind types.StoreKind `json:"storeKind"`
+}
+
+// Filter returns the entries recorded at or after the given time
+func Filter(entries []Entry, since time.Time) []Entry {
+	var filtered []Entry
+	for _, entry := range entries {
+		if !entry.Time.Before(since) {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// ComputeStats counts the switches per context, optionally grouped by user.
+// The statistics are ranked by the number of switches, the most recently accessed context first for the same number of switches.
+func ComputeStats(entries []Entry, byUser bool) []Stats {
+	type key struct {
+		context string
+		user    string
+	}
+
+	statsByKey := make(map[key]*Stats)
+	for _, entry := range entries {
+		k := key{context: entry.Context}
+		if byUser {
+			k.user = entry.User
+		}
+
+		stats, ok := statsByKey[k]
+		if !ok {
+			stats = &Stats{Context: k.context, User: k.user}
+			statsByKey[k] = stats
+		}
+
+		stats.Switches++
+		if !entry.Time.Before(stats.LastAccessed) {
+			stats.LastAccessed = entry.Time
+			stats.StoreKind = entry.StoreKind
+		}
+	}
+
+	result := make([]Stats, 0, len(statsByKey))
+	for _, stats := range statsByKey {
+		result = append(result, *stats)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Switches != result[j].Switches {
+			return result[i].Switches > result[j].Switches
+		}
+		if !result[i].LastAccessed.Equal(result[j].LastAccessed) {
+			return result[i].LastAccessed.After(result[j].LastAccessed)
+		}
+		if result[i].Context != result[j].Context {
+			return result[i].Context < result[j].Context
+		}
+		return result[i].User < result[j].User
+	})
+	return result
+}
+
+// Heatmap returns an ASCII calendar of the switches per day.
+// The rows are the days of the week, the columns are the weeks from the first entry (at most one year ago) until now.
+func Heatmap(entries []Entry, now time.Time) string {
+	now = now.UTC()
+	today := truncateToDay(now)
+	lastWeek := startOfWeek(today)
+
+	// starts at the week of the first entry, but shows at least the current week
+	firstWeek := lastWeek
+	switchesPerDay := make(map[time.Time]int)
+	for _, entry := range entries {
+		entryDay := truncateToDay(entry.Time.UTC())
+		if entryDay.After(today) {
+			continue
+		}
+		switchesPerDay[entryDay]++
+		if week := startOfWeek(entryDay); week.Before(firstWeek) {
+			firstWeek = week
+		}
+	}
+
+	weeks := int(lastWeek.Sub(firstWeek)/(7*day)) + 1
+	if weeks > maxHeatmapWeeks {
+		weeks = maxHeatmapWeeks
+		firstWeek = lastWeek.AddDate(0, 0, -7*(maxHeatmapWeeks-1))
+	}
+
+	maxSwitches := 0
+	for d, switches := range switchesPerDay {
+		if !d.Before(firstWeek) && switches > maxSwitches {
+			maxSwitches = switches
+		}
+	}
+
+	var b strings.Builder
+
+	// month labels above the first week of each month
+	// the partial month of the first week is only labeled if the label does not hide the next month
+	labels := []byte(strings.Repeat(" ", weeks+2))
+	for week := 1; week < weeks; week++ {
+		start := firstWeek.AddDate(0, 0, 7*week)
+		if start.Month() != start.AddDate(0, 0, -7).Month() {
+			copy(labels[week:], start.Format("Jan"))
+		}
+	}
+	if strings.TrimSpace(string(labels[:3])) == "" {
+		copy(labels, firstWeek.Format("Jan"))
+	}
+	b.WriteString("    ")
+	b.WriteString(strings.TrimRight(string(labels), " "))
+	b.WriteString("\n")
+
+	for weekday := 0; weekday < 7; weekday++ {
+		b.WriteString(firstWeek.AddDate(0, 0, weekday).Format("Mon")[:2])
+		b.WriteString("  ")
+		for week := 0; week < weeks; week++ {
+			d := firstWeek.AddDate(0, 0, 7*week+weekday)
+			if d.After(today) {
+				b.WriteString(" ")
+				continue
+			}
+			b.WriteString(heatmapLevel(switchesPerDay[d], maxSwitches))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString(fmt.Sprintf("\nLess %s More (max. %d switches per day)\n", strings.Join(heatmapLevels, " "), maxSwitches))
+	return b.String()
+}
+
+// heatmapLevel returns the heatmap character for the number of switches relative to the maximum number of switches per day
+func heatmapLevel(switches, maxSwitches int) string {
+	if switches == 0 || maxSwitches == 0 {
+		return heatmapLevels[0]
+	}
+	// levels 1 to len(heatmapLevels)-1 for at least one switch
+	level := 1 + (switches-1)*(len(heatmapLevels)-1)/maxSwitches
+	return heatmapLevels[level]
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// startOfWeek returns the Monday of the week of the given day
+func startOfWeek(d time.Time) time.Time {
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package disk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/cache"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storecache "github.com/danielfoehrkn/kubeswitch/pkg/store/cache"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const cacheKey = "disk"
+
+// defaultTTL is the default duration after which cached kubeconfigs expire
+const defaultTTL = time.Hour
+
+// compress is set from the switch configuration and gzip compresses the cached kubeconfigs of all disk caches
+var compress bool
+
+// SetCompress configures if disk caches created afterwards compress the cached kubeconfigs
+func SetCompress(enabled bool) {
+	compress = enabled
+}
+
+func init() {
+	cache.Register(cacheKey, New)
+}
+
+type diskCacheCfg struct {
+	// Path is the directory to store the kubeconfigs in.
+	// Defaults to ~/.kube/switch-state/kubeconfig-cache
+	Path string `yaml:"path"`
+	// TTL is the duration after which cached kubeconfigs expire.
+	// Defaults to 1h
+	TTL *time.Duration `yaml:"ttl"`
+}
+
+// New returns a store persisting the kubeconfigs of the upstream store across invocations.
+// The kubeconfigs of each store are written to a separate subdirectory named by the store ID.
+func New(upstream store.KubeconfigStore, ccfg *types.Cache) (store.KubeconfigStore, error) {
+	var cfg diskCacheCfg
+	if ccfg != nil && ccfg.Config != nil {
+		buf, err := yaml.Marshal(ccfg.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal cache config: %w", err)
+		}
+		if err := yaml.Unmarshal(buf, &cfg); err != nil {
+			return nil, fmt.Errorf("cache config is invalid: %w", err)
+		}
+	}
+
+	path := cfg.Path
+	if len(path) == 0 {
+		path = filepath.Join("$HOME", ".kube", "switch-state", storecache.DefaultDiskCacheDirectory)
+	}
+	if strings.HasPrefix(path, "~/") {
+		homedir, _ := os.UserHomeDir()
+		path = filepath.Join(homedir, path[2:])
+	}
+	path = util.ExpandEnv(path)
+
+	ttl := defaultTTL
+	if cfg.TTL != nil {
+		ttl = *cfg.TTL
+	}
+
+	directory := filepath.Join(path, upstream.GetID())
+	if compress {
+		return storecache.NewCachingStore(upstream, storecache.NewCompressedDiskCache(directory), ttl), nil
+	}
+	return storecache.NewCachingStore(upstream, storecache.NewDiskCache(directory), ttl), nil
+}
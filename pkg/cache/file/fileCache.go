@@ -15,6 +15,7 @@
 package file
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"os"
@@ -22,6 +23,7 @@ import (
 	"strings"
 
 	"github.com/danielfoehrkn/kubeswitch/pkg/cache"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
 	"github.com/danielfoehrkn/kubeswitch/pkg/store"
 	"github.com/danielfoehrkn/kubeswitch/pkg/util"
 	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
@@ -56,7 +58,7 @@ func New(upstream store.KubeconfigStore, ccfg *types.Cache) (store.KubeconfigSto
 		path = filepath.Join(homedir, path[2:])
 	}
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		if err := kubeswitchio.MkdirAll(path, os.ModePerm); err != nil {
 			return nil, fmt.Errorf("path: %s was not able to be created", path)
 		}
 	}
@@ -113,7 +115,7 @@ func (c *fileCache) suffix() string {
 // GetKubeconfigForPath returns the kubeconfig for the given path.
 // First, it checks if the kubeconfig is already available in cache.
 // If not, it is loaded from the upstream store and stored in cache
-func (c *fileCache) GetKubeconfigForPath(path string, tags map[string]string) ([]byte, error) {
+func (c *fileCache) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
 	c.logger.Debugf("Looking for '%s'", path)
 
 	// check if kubeconfig is already available in the cache
@@ -128,7 +130,7 @@ func (c *fileCache) GetKubeconfigForPath(path string, tags map[string]string) ([
 	}
 	c.logger.Debugf("kubeconfig not found in cache '%s'", path)
 	// kubeconfig not found in cache, load from upstream store
-	kubeconfig, err := c.upstream.GetKubeconfigForPath(path, tags)
+	kubeconfig, err := c.upstream.GetKubeconfigForPath(ctx, path, tags)
 	if err != nil { // if the upstream returns an error, the result is not cached
 		return kubeconfig, err
 	}
@@ -143,6 +145,11 @@ func (c *fileCache) GetKubeconfigForPath(path string, tags map[string]string) ([
 	return kubeconfig, err
 }
 
+// PersistsKubeconfigs returns true, as the kubeconfigs are cached in the configured directory
+func (c *fileCache) PersistsKubeconfigs() bool {
+	return true
+}
+
 // Flush cache by deleting all files in the cache directory
 func (c *fileCache) Flush() (int, error) {
 	path := util.ExpandEnv(c.cfg.Path)
@@ -155,7 +162,7 @@ func (c *fileCache) Flush() (int, error) {
 		if !strings.HasSuffix(f.Name(), c.suffix()) {
 			continue
 		}
-		err := os.Remove(filepath.Join(path, f.Name()))
+		err := kubeswitchio.Remove(filepath.Join(path, f.Name()))
 		if err != nil {
 			return deleted, fmt.Errorf("failed to delete file '%s': %w", f.Name(), err)
 		}
@@ -178,17 +185,25 @@ func (c *fileCache) GetContextPrefix(path string) string {
 	return c.upstream.GetContextPrefix(path)
 }
 
-func (c *fileCache) VerifyKubeconfigPaths() error {
-	return c.upstream.VerifyKubeconfigPaths()
+func (c *fileCache) VerifyKubeconfigPaths(ctx context.Context) error {
+	return c.upstream.VerifyKubeconfigPaths(ctx)
+}
+
+func (c *fileCache) Probe(ctx context.Context) error {
+	return c.upstream.Probe(ctx)
 }
 
-func (c *fileCache) StartSearch(channel chan store.SearchResult) {
-	c.upstream.StartSearch(channel)
+func (c *fileCache) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	c.upstream.StartSearch(ctx, channel)
 }
 
 func (c *fileCache) GetLogger() *logrus.Entry {
 	return c.upstream.GetLogger()
 }
+
+func (c *fileCache) Stop(ctx context.Context) error {
+	return c.upstream.Stop(ctx)
+}
 func (c *fileCache) GetStoreConfig() types.KubeconfigStore {
 	return c.upstream.GetStoreConfig()
 }
@@ -202,3 +217,7 @@ func (c *fileCache) GetSearchPreview(path string, optionalTags map[string]string
 
 	return previewer.GetSearchPreview(path, optionalTags)
 }
+
+func (c *fileCache) Unwrap() store.KubeconfigStore {
+	return c.upstream
+}
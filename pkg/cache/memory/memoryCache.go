@@ -15,79 +15,42 @@
 package memory
 
 import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
 	"github.com/danielfoehrkn/kubeswitch/pkg/cache"
 	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storecache "github.com/danielfoehrkn/kubeswitch/pkg/store/cache"
 	"github.com/danielfoehrkn/kubeswitch/types"
-	"github.com/sirupsen/logrus"
 )
 
 func init() {
 	cache.Register("memory", New)
 }
 
-func New(upstream store.KubeconfigStore, _ *types.Cache) (store.KubeconfigStore, error) {
-	return &memoryCache{
-		upstream: upstream,
-		cache:    make(map[string][]byte),
-	}, nil
-}
-
-type memoryCache struct {
-	upstream store.KubeconfigStore
-	cache    map[string][]byte
+type memoryCacheCfg struct {
+	// TTL is the duration after which cached kubeconfigs expire.
+	// Defaults to caching for the lifetime of the process
+	TTL *time.Duration `yaml:"ttl"`
 }
 
-// GetKubeconfigForPath implements the store.KubeconfigStore interface.
-// It is a wrapper around a KubeConfigCache.
-// It intercepts calls to GetKubeconfigForPath and caches the result in memory.
-func (c *memoryCache) GetKubeconfigForPath(path string, tags map[string]string) ([]byte, error) {
-	if val, ok := c.cache[path]; ok {
-		c.GetLogger().Debugf("GetKubeconfigForPath: %s found in cache", path)
-		return val, nil
-	}
-	c.GetLogger().Debugf("GetKubeconfigForPath: %s not cached", path)
-	kube, err := c.upstream.GetKubeconfigForPath(path, tags)
-	if err != nil {
-		return kube, err
+func New(upstream store.KubeconfigStore, ccfg *types.Cache) (store.KubeconfigStore, error) {
+	var cfg memoryCacheCfg
+	if ccfg != nil && ccfg.Config != nil {
+		buf, err := yaml.Marshal(ccfg.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal cache config: %w", err)
+		}
+		if err := yaml.Unmarshal(buf, &cfg); err != nil {
+			return nil, fmt.Errorf("cache config is invalid: %w", err)
+		}
 	}
-	c.cache[path] = kube
-	return kube, nil
-}
 
-func (c *memoryCache) GetID() string {
-	return c.upstream.GetID()
-}
-
-func (c *memoryCache) GetKind() types.StoreKind {
-	return c.upstream.GetKind()
-}
-
-func (c *memoryCache) GetContextPrefix(path string) string {
-	return c.upstream.GetContextPrefix(path)
-}
-
-func (c *memoryCache) VerifyKubeconfigPaths() error {
-	return c.upstream.VerifyKubeconfigPaths()
-}
-
-func (c *memoryCache) StartSearch(channel chan store.SearchResult) {
-	c.upstream.StartSearch(channel)
-}
-
-func (c *memoryCache) GetLogger() *logrus.Entry {
-	return c.upstream.GetLogger()
-}
-
-func (c *memoryCache) GetStoreConfig() types.KubeconfigStore {
-	return c.upstream.GetStoreConfig()
-}
-
-func (c *memoryCache) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
-	previewer, ok := c.upstream.(store.Previewer)
-	if !ok {
-		// if the wrapped store is not a previewer, simply return an empty string, hence causing no visual distortion
-		return "", nil
+	var ttl time.Duration
+	if cfg.TTL != nil {
+		ttl = *cfg.TTL
 	}
-
-	return previewer.GetSearchPreview(path, optionalTags)
+	return storecache.NewCachingStore(upstream, storecache.NewMemoryCache(), ttl), nil
 }
@@ -21,6 +21,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/danielfoehrkn/kubeswitch/pkg/config/migration"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
 	"github.com/danielfoehrkn/kubeswitch/types"
 )
 
@@ -61,38 +62,24 @@ func LoadConfigFromFile(filepath string) (*types.Config, error) {
 
 func MigrateConfig(old types.ConfigOld, filename string) (*types.Config, error) {
 	// first, copy the old configuration
-	file, err := os.Create(fmt.Sprintf("%s.old", filename))
-	if err != nil {
-		return nil, fmt.Errorf("failed to migrate SwitchConfig file: %w", err)
-	}
-	defer file.Close()
-
 	output, err := yaml.Marshal(old)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = file.Write(output)
-	if err != nil {
-		return nil, err
+	if err := kubeswitchio.WriteFile(fmt.Sprintf("%s.old", filename), output, 0644); err != nil {
+		return nil, fmt.Errorf("failed to migrate SwitchConfig file: %w", err)
 	}
 
 	// then overwrite the configuration with the new format
 	new := migration.ConvertConfiguration(old)
-	fileNew, err := os.Create(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to migrate SwitchConfig file: %w", err)
-	}
-	defer fileNew.Close()
-
 	output, err = yaml.Marshal(new)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = fileNew.Write(output)
-	if err != nil {
-		return nil, err
+	if err := kubeswitchio.WriteFile(filename, output, 0644); err != nil {
+		return nil, fmt.Errorf("failed to migrate SwitchConfig file: %w", err)
 	}
 
 	return &new, nil
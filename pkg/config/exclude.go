@@ -0,0 +1,123 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+const excludeContextsKey = "excludeContexts"
+
+// AddExcludedContext adds the given context name or pattern to the excludeContexts of the config file.
+// Creates the config file if it does not exist.
+func AddExcludedContext(filepath, context string) error {
+	return modifyExcludedContexts(filepath, func(excludeContexts []string) []string {
+		if slices.Contains(excludeContexts, context) {
+			return excludeContexts
+		}
+		return append(excludeContexts, context)
+	})
+}
+
+// RemoveExcludedContext removes the given context name or pattern from the excludeContexts of the config file.
+// If the context is still excluded by a wildcard pattern, an override "!<context>" is added.
+func RemoveExcludedContext(filepath, context string) error {
+	return modifyExcludedContexts(filepath, func(excludeContexts []string) []string {
+		excludeContexts = slices.DeleteFunc(excludeContexts, func(pattern string) bool {
+			return pattern == context
+		})
+		if util.IsContextExcluded(context, excludeContexts) {
+			excludeContexts = append(excludeContexts, fmt.Sprintf("!%s", context))
+		}
+		return excludeContexts
+	})
+}
+
+// modifyExcludedContexts modifies the excludeContexts of the config file
+func modifyExcludedContexts(filepath string, modify func([]string) []string) error {
+	return modifyConfigDocument(filepath, func(root *yaml.Node) error {
+		var excludeContextsNode *yaml.Node
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == excludeContextsKey {
+				excludeContextsNode = root.Content[i+1]
+				break
+			}
+		}
+
+		if excludeContextsNode == nil {
+			excludeContextsNode = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: excludeContextsKey}, excludeContextsNode)
+		}
+
+		var excludeContexts []string
+		if err := excludeContextsNode.Decode(&excludeContexts); err != nil {
+			return fmt.Errorf("field %q in config with path '%s' is invalid: %v", excludeContextsKey, filepath, err)
+		}
+
+		return excludeContextsNode.Encode(modify(excludeContexts))
+	})
+}
+
+// modifyConfigDocument modifies the config file directly on the yaml document
+// to preserve comments and the order of the existing fields.
+// Creates the config file if it does not exist.
+func modifyConfigDocument(filepath string, modify func(root *yaml.Node) error) error {
+	content, err := os.ReadFile(filepath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if len(content) == 0 {
+		content = []byte("kind: SwitchConfig\nversion: v1alpha1\n")
+	}
+
+	document := yaml.Node{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return fmt.Errorf("could not unmarshal config with path '%s': %v", filepath, err)
+	}
+
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("config with path '%s' is not a yaml mapping", filepath)
+	}
+
+	if err := modify(document.Content[0]); err != nil {
+		return err
+	}
+
+	output, err := encodeYAML(&document)
+	if err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(filepath, output, 0644)
+}
+
+// encodeYAML encodes the node with the indentation used in the switch config file
+func encodeYAML(node *yaml.Node) ([]byte, error) {
+	output := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&output)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
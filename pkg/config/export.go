@@ -0,0 +1,187 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// RedactedValue replaces the values of credential fields in the exported config and in the debug output
+const RedactedValue = "<REDACTED>"
+
+// secretKeyFragments identify the fields of the config that contain secrets (compared in lower case)
+var secretKeyFragments = []string{"token", "password", "secret", "apikey", "privatekey", "accesskey", "clientkey", "applicationkey", "consumerkey", "credentials"}
+
+// secretMapKeys identify the fields of the config mapping arbitrary names to secrets, e.g. HTTP headers (compared in lower case)
+var secretMapKeys = []string{"headers"}
+
+// pathKeySuffixes identify the fields of the config that reference the local filesystem (compared in lower case)
+var pathKeySuffixes = []string{"path", "file", "dir", "directory"}
+
+var (
+	// envReference matches values that only reference an environment variable, e.g. "${VAULT_TOKEN}"
+	envReference = regexp.MustCompile(`^\$(\{\w+\}|\w+)$`)
+	// localPath matches absolute paths and paths relative to the home directory or an environment variable
+	localPath = regexp.MustCompile(`^(/|~|\$|[A-Za-z]:[\\/])`)
+)
+
+// ExportOptions configure how the config is sanitized by ExportConfig
+type ExportOptions struct {
+	// RedactCredentials replaces the values of credential fields with RedactedValue.
+	// Values that only reference an environment variable are kept.
+	RedactCredentials bool
+	// KeepPaths keeps the local filesystem paths instead of replacing them with their basename
+	KeepPaths bool
+}
+
+// ExportConfig reads the config file and returns a sanitized copy that can be shared with others.
+// The yaml document is modified directly to preserve comments and the order of the fields.
+func ExportConfig(filepath string, options ExportOptions) ([]byte, error) {
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("config with path '%s' does not exist", filepath)
+		}
+		return nil, err
+	}
+
+	document := yaml.Node{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("could not unmarshal config with path '%s': %v", filepath, err)
+	}
+
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config with path '%s' is not a yaml mapping", filepath)
+	}
+
+	sanitize(document.Content[0], options)
+
+	return encodeYAML(&document)
+}
+
+// sanitize redacts the credentials and shortens the local paths of the given yaml node and its children
+func sanitize(node *yaml.Node, options ExportOptions) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			switch {
+			case value.Kind == yaml.ScalarNode && options.RedactCredentials && IsSecretKey(key):
+				redactScalar(value)
+			case value.Kind == yaml.MappingNode && options.RedactCredentials && IsSecretMapKey(key):
+				// the names are kept, e.g. the names of the headers
+				for j := 1; j < len(value.Content); j += 2 {
+					if value.Content[j].Kind == yaml.ScalarNode {
+						redactScalar(value.Content[j])
+					}
+				}
+			case value.Kind == yaml.ScalarNode && !options.KeepPaths && isPathKey(key):
+				shortenPath(value)
+			case value.Kind == yaml.SequenceNode && !options.KeepPaths && key == "paths" && isFilesystemStore(node):
+				// only the paths of the filesystem store are local, other stores use remote paths
+				for _, path := range value.Content {
+					if path.Kind == yaml.ScalarNode {
+						shortenPath(path)
+					}
+				}
+			default:
+				sanitize(value, options)
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			sanitize(item, options)
+		}
+	}
+}
+
+// redactScalar replaces the value of the scalar node with RedactedValue
+func redactScalar(node *yaml.Node) {
+	if len(node.Value) == 0 || node.Tag == "!!null" || envReference.MatchString(node.Value) {
+		return
+	}
+	node.Value = RedactedValue
+	node.Tag = "!!str"
+	node.Style = 0
+}
+
+// shortenPath replaces the value of the scalar node with its basename if it is a local path
+func shortenPath(node *yaml.Node) {
+	if !localPath.MatchString(node.Value) {
+		return
+	}
+	// support paths written on windows independent of the current OS
+	base := filepath.Base(strings.ReplaceAll(node.Value, `\`, "/"))
+	if base == "/" || base == "." || base == "~" {
+		return
+	}
+	node.Value = base
+}
+
+// isFilesystemStore checks if the mapping node is a kubeconfig store of kind filesystem
+func isFilesystemStore(node *yaml.Node) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "kind" {
+			return node.Content[i+1].Value == string(types.StoreKindFilesystem)
+		}
+	}
+	return false
+}
+
+// isPathKey checks if the field with the given key references the local filesystem
+func isPathKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, suffix := range pathKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSecretKey checks if the field with the given key contains a secret
+// fields referencing files containing secrets (e.g. "tokenFile") are not secret
+func IsSecretKey(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	if strings.HasSuffix(key, "file") || strings.HasSuffix(key, "path") {
+		return false
+	}
+	for _, fragment := range secretKeyFragments {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSecretMapKey checks if every value of the mapping field with the given key may contain a secret,
+// e.g. the Authorization header in the HTTP headers
+func IsSecretMapKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, secretMapKey := range secretMapKeys {
+		if key == secretMapKey {
+			return true
+		}
+	}
+	return false
+}
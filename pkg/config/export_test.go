@@ -0,0 +1,156 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const exportTestConfig = `kind: SwitchConfig
+version: v1alpha1
+# shared kubeconfig stores
+kubeconfigStores:
+  - kind: filesystem
+    paths:
+      - /home/jane/.kube/configs/
+      - ~/work/kubeconfig.yaml
+    excludeContexts:
+      - prod-*
+    transformers:
+      - type: setNamespace
+        namespace: default
+  - kind: vault
+    id: team
+    paths:
+      - secret/k8s
+    config:
+      vaultAPIAddress: https://vault.example.com
+      token: s.abcdef
+  - kind: http
+    config:
+      endpoints:
+        - url: https://kubeconfigs.example.com
+          bearerTokenFile: /home/jane/.secrets/token
+          password: ${HTTP_PASSWORD}
+          headers:
+            Authorization: Bearer abcdef
+            X-Api-Token: ${HTTP_API_TOKEN}
+  - kind: ovh
+    config:
+      application_key: app-key
+      application_secret: app-secret
+      consumer_key: consumer-key
+hooks:
+  - name: sync
+    type: Executable
+    path: /home/jane/bin/sync-hook
+`
+
+var _ = Describe("ExportConfig", func() {
+	var (
+		tempDir    string
+		configPath string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "config-export")
+		Expect(err).ToNot(HaveOccurred())
+		configPath = filepath.Join(tempDir, "switch-config.yaml")
+		Expect(os.WriteFile(configPath, []byte(exportTestConfig), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should redact credentials and shorten local paths", func() {
+		output, err := switchconfig.ExportConfig(configPath, switchconfig.ExportOptions{RedactCredentials: true})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(string(output)).To(ContainSubstring("# shared kubeconfig stores"))
+		Expect(string(output)).To(ContainSubstring("- configs\n"))
+		Expect(string(output)).To(ContainSubstring("- kubeconfig.yaml\n"))
+		Expect(string(output)).To(ContainSubstring("- secret/k8s\n"))
+		Expect(string(output)).To(ContainSubstring("bearerTokenFile: token\n"))
+		Expect(string(output)).To(ContainSubstring("path: sync-hook\n"))
+		Expect(string(output)).To(ContainSubstring("token: <REDACTED>\n"))
+		Expect(string(output)).To(ContainSubstring("password: ${HTTP_PASSWORD}\n"))
+		Expect(string(output)).To(ContainSubstring("vaultAPIAddress: https://vault.example.com\n"))
+		Expect(string(output)).ToNot(ContainSubstring("s.abcdef"))
+		Expect(string(output)).ToNot(ContainSubstring("app-key"))
+		Expect(string(output)).ToNot(ContainSubstring("app-secret"))
+		Expect(string(output)).ToNot(ContainSubstring("consumer-key"))
+		Expect(string(output)).ToNot(ContainSubstring("jane"))
+		Expect(string(output)).To(ContainSubstring("Authorization: <REDACTED>\n"))
+		Expect(string(output)).To(ContainSubstring("X-Api-Token: ${HTTP_API_TOKEN}\n"))
+		Expect(string(output)).ToNot(ContainSubstring("Bearer abcdef"))
+
+		config := &types.Config{}
+		Expect(yaml.Unmarshal(output, config)).To(Succeed())
+		Expect(config.KubeconfigStores).To(HaveLen(4))
+		Expect(config.KubeconfigStores[0].ExcludeContexts).To(ConsistOf("prod-*"))
+		Expect(config.KubeconfigStores[0].Transformers).To(ConsistOf(types.Transformer{Type: types.TransformerTypeSetNamespace, Namespace: "default"}))
+	})
+
+	It("should keep the paths", func() {
+		output, err := switchconfig.ExportConfig(configPath, switchconfig.ExportOptions{RedactCredentials: true, KeepPaths: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(output)).To(ContainSubstring("- /home/jane/.kube/configs/\n"))
+		Expect(string(output)).To(ContainSubstring("bearerTokenFile: /home/jane/.secrets/token\n"))
+		Expect(string(output)).To(ContainSubstring("token: <REDACTED>\n"))
+	})
+
+	It("should keep the credentials", func() {
+		output, err := switchconfig.ExportConfig(configPath, switchconfig.ExportOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(output)).To(ContainSubstring("token: s.abcdef\n"))
+		Expect(string(output)).To(ContainSubstring("Authorization: Bearer abcdef\n"))
+		Expect(string(output)).ToNot(ContainSubstring("<REDACTED>"))
+	})
+
+	It("should fail if the config does not exist", func() {
+		_, err := switchconfig.ExportConfig(filepath.Join(tempDir, "missing.yaml"), switchconfig.ExportOptions{})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("IsSecretKey", func() {
+	It("should detect secret fields", func() {
+		Expect(switchconfig.IsSecretKey("rancherToken")).To(BeTrue())
+		Expect(switchconfig.IsSecretKey("secret_key")).To(BeTrue())
+		Expect(switchconfig.IsSecretKey("consumer_key")).To(BeTrue())
+		Expect(switchconfig.IsSecretKey("bearerTokenFile")).To(BeFalse())
+		Expect(switchconfig.IsSecretKey("apiKeyFilePath")).To(BeFalse())
+		Expect(switchconfig.IsSecretKey("vaultAPIAddress")).To(BeFalse())
+	})
+})
+
+var _ = Describe("IsSecretMapKey", func() {
+	It("should detect fields mapping names to secrets", func() {
+		Expect(switchconfig.IsSecretMapKey("headers")).To(BeTrue())
+		Expect(switchconfig.IsSecretMapKey("Headers")).To(BeTrue())
+		Expect(switchconfig.IsSecretMapKey("tags")).To(BeFalse())
+		Expect(switchconfig.IsSecretMapKey("labels")).To(BeFalse())
+	})
+})
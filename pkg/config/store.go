@@ -0,0 +1,66 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+const kubeconfigStoresKey = "kubeconfigStores"
+
+// AddKubeconfigStore appends the given kubeconfig store to the kubeconfigStores of the config file.
+// Creates the config file if it does not exist.
+func AddKubeconfigStore(filepath string, kubeconfigStore *yaml.Node) error {
+	return modifyConfigDocument(filepath, func(root *yaml.Node) error {
+		var kubeconfigStoresNode *yaml.Node
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			if root.Content[i].Value == kubeconfigStoresKey {
+				kubeconfigStoresNode = root.Content[i+1]
+				break
+			}
+		}
+
+		if kubeconfigStoresNode == nil {
+			kubeconfigStoresNode = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: kubeconfigStoresKey}, kubeconfigStoresNode)
+		}
+
+		// an empty field "kubeconfigStores:" is parsed as null
+		if kubeconfigStoresNode.Kind == yaml.ScalarNode && kubeconfigStoresNode.Tag == "!!null" {
+			*kubeconfigStoresNode = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		}
+
+		if kubeconfigStoresNode.Kind != yaml.SequenceNode {
+			return fmt.Errorf("field %q in config with path '%s' is not a list", kubeconfigStoresKey, filepath)
+		}
+
+		kubeconfigStoresNode.Content = append(kubeconfigStoresNode.Content, kubeconfigStore)
+		return nil
+	})
+}
+
+// EncodeKubeconfigStore returns the given kubeconfig store as list entry of the kubeconfigStores in the config file
+func EncodeKubeconfigStore(kubeconfigStore *yaml.Node) ([]byte, error) {
+	return encodeYAML(&yaml.Node{
+		Kind: yaml.MappingNode,
+		Tag:  "!!map",
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: kubeconfigStoresKey},
+			{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{kubeconfigStore}},
+		},
+	})
+}
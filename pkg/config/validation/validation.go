@@ -16,10 +16,13 @@ package validation
 
 import (
 	"fmt"
+	"net/url"
+	"text/template"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
+	"github.com/danielfoehrkn/kubeswitch/pkg/rbac"
 	gardenerstore "github.com/danielfoehrkn/kubeswitch/pkg/store/gardener"
 	gkestore "github.com/danielfoehrkn/kubeswitch/pkg/store/gke"
 	"github.com/danielfoehrkn/kubeswitch/types"
@@ -42,6 +45,14 @@ func ValidateConfig(config *types.Config) field.ErrorList {
 		errors = append(errors, field.Invalid(field.NewPath("version"), config.Version, fmt.Sprintf("Config version %q is unknown. Valid versions are %q", config.Version, types.ValidConfigVersions)))
 	}
 
+	if config.SortOrder != nil && !types.ValidSortOrders.Has(string(*config.SortOrder)) {
+		errors = append(errors, field.Invalid(field.NewPath("sortOrder"), *config.SortOrder, fmt.Sprintf("sort order %q is unknown. Valid sort orders are %q", *config.SortOrder, types.ValidSortOrders)))
+	}
+
+	if config.CompletionTimeout != nil && *config.CompletionTimeout <= 0 {
+		errors = append(errors, field.Invalid(field.NewPath("completionTimeout"), config.CompletionTimeout.String(), "the completion timeout must be positive"))
+	}
+
 	for i, kubeconfigStore := range config.KubeconfigStores {
 		id := kubeconfigStore.ID
 		if kubeconfigStore.ID == nil {
@@ -60,6 +71,72 @@ func ValidateConfig(config *types.Config) field.ErrorList {
 			errors = append(errors, field.Invalid(indexFieldPath.Child("kind"), kubeconfigStore.Kind, fmt.Sprintf("kind %q of kubeconfig store is unknown. Valid kinds are %q", kubeconfigStore.Kind, types.ValidStoreKinds)))
 		}
 
+		if len(kubeconfigStore.LogLevel) > 0 && !types.ValidStoreLogLevels.Has(kubeconfigStore.LogLevel) {
+			errors = append(errors, field.Invalid(indexFieldPath.Child("logLevel"), kubeconfigStore.LogLevel, fmt.Sprintf("log level %q of kubeconfig store is unknown. Valid log levels are %q", kubeconfigStore.LogLevel, types.ValidStoreLogLevels)))
+		}
+
+		for j, check := range kubeconfigStore.RBACCheckResources {
+			if _, err := rbac.ParseCheck(check); err != nil {
+				errors = append(errors, field.Invalid(indexFieldPath.Child("rbacCheckResources").Index(j), check, err.Error()))
+			}
+		}
+
+		for j, transformer := range kubeconfigStore.Transformers {
+			transformerPath := indexFieldPath.Child("transformers").Index(j)
+			switch transformer.Type {
+			case types.TransformerTypeServerRewrite:
+				if len(transformer.From) == 0 || len(transformer.To) == 0 {
+					errors = append(errors, field.Required(transformerPath, "from and to have to be provided for a serverRewrite transformer"))
+				}
+			case types.TransformerTypeInjectProxyURL:
+				if _, err := url.Parse(transformer.URL); err != nil || len(transformer.URL) == 0 {
+					errors = append(errors, field.Invalid(transformerPath.Child("url"), transformer.URL, "a valid proxy URL has to be provided for an injectProxyURL transformer"))
+				}
+			case types.TransformerTypeSetNamespace:
+				if len(transformer.Namespace) == 0 {
+					errors = append(errors, field.Required(transformerPath.Child("namespace"), "the namespace has to be provided for a setNamespace transformer"))
+				}
+			case types.TransformerTypeAddLabel:
+				if len(transformer.Key) == 0 {
+					errors = append(errors, field.Required(transformerPath.Child("key"), "the key has to be provided for an addLabel transformer"))
+				}
+			case types.TransformerTypeStripToken:
+			default:
+				errors = append(errors, field.Invalid(transformerPath.Child("type"), transformer.Type, fmt.Sprintf("Unknown transformer type. Valid transformer types are %q", types.ValidTransformerTypes)))
+			}
+		}
+
+		if kubeconfigStore.WindowSize != nil && *kubeconfigStore.WindowSize <= 0 {
+			errors = append(errors, field.Invalid(indexFieldPath.Child("windowSize"), *kubeconfigStore.WindowSize, "the window size of a paginated kubeconfig store must be positive"))
+		}
+
+		if kubeconfigStore.MaxKubeconfigSize < 0 {
+			errors = append(errors, field.Invalid(indexFieldPath.Child("maxKubeconfigSize"), kubeconfigStore.MaxKubeconfigSize, "the maximum kubeconfig size must not be negative"))
+		}
+
+		if len(kubeconfigStore.GroupSortOrder) > 0 {
+			if !types.ValidGroupSortOrders.Has(string(kubeconfigStore.GroupSortOrder)) {
+				errors = append(errors, field.Invalid(indexFieldPath.Child("groupSortOrder"), kubeconfigStore.GroupSortOrder, fmt.Sprintf("group sort order %q is unknown. Valid group sort orders are %q", kubeconfigStore.GroupSortOrder, types.ValidGroupSortOrders)))
+			} else if len(kubeconfigStore.GroupByTag) == 0 {
+				errors = append(errors, field.Required(indexFieldPath.Child("groupByTag"), "the tag to group by has to be provided if a group sort order is set"))
+			}
+		}
+
+		packageNames := sets.New[string]()
+		for j, p := range kubeconfigStore.Packages {
+			packagePath := indexFieldPath.Child("packages").Index(j)
+			if len(p.Name) == 0 {
+				errors = append(errors, field.Required(packagePath.Child("name"), "the name of the package has to be provided"))
+			} else if packageNames.Has(p.Name) {
+				errors = append(errors, field.Duplicate(packagePath.Child("name"), p.Name))
+			}
+			packageNames.Insert(p.Name)
+
+			if len(p.Contexts) == 0 {
+				errors = append(errors, field.Required(packagePath.Child("contexts"), "at least one context name pattern has to be provided for the package"))
+			}
+		}
+
 		if len(kubeconfigStore.Paths) == 0 &&
 			(kubeconfigStore.Kind == types.StoreKindFilesystem ||
 				kubeconfigStore.Kind == types.StoreKindVault) {
@@ -91,6 +168,61 @@ func ValidateConfig(config *types.Config) field.ErrorList {
 		storeKinds.Insert(fmt.Sprintf("%s:%s", kubeconfigStore.Kind, *id))
 	}
 
+	for i, enricher := range config.Enrichers {
+		enricherPath := field.NewPath("enrichers").Index(i)
+		switch enricher.Type {
+		case types.EnricherTypeHTTP:
+			if len(enricher.URL) == 0 {
+				errors = append(errors, field.Required(enricherPath.Child("url"), "the URL has to be provided for an http enricher"))
+			}
+		case types.EnricherTypeCommand:
+			if len(enricher.Command) == 0 {
+				errors = append(errors, field.Required(enricherPath.Child("command"), "the command has to be provided for a command enricher"))
+			}
+		default:
+			errors = append(errors, field.Invalid(enricherPath.Child("type"), enricher.Type, fmt.Sprintf("Unknown enricher type. Valid enricher types are %q", types.ValidEnricherTypes)))
+		}
+	}
+
+	if config.KubeconfigOutputPath != nil {
+		if _, err := template.New("kubeconfigOutputPath").Parse(*config.KubeconfigOutputPath); err != nil {
+			errors = append(errors, field.Invalid(field.NewPath("kubeconfigOutputPath"), *config.KubeconfigOutputPath, fmt.Sprintf("the kubeconfig output path is not a valid template: %v", err)))
+		}
+	}
+
+	if config.CredentialCache != nil {
+		if config.CredentialCache.MaxAge != nil && *config.CredentialCache.MaxAge <= 0 {
+			errors = append(errors, field.Invalid(field.NewPath("credentialCache").Child("maxAge"), *config.CredentialCache.MaxAge, "the maximum age of cached credentials must be positive"))
+		}
+		if config.CredentialCache.Enabled != nil && *config.CredentialCache.Enabled && (config.CredentialCache.SSHKeyPath == nil || len(*config.CredentialCache.SSHKeyPath) == 0) {
+			errors = append(errors, field.Required(field.NewPath("credentialCache").Child("sshKeyPath"), "the SSH key to encrypt the cached credentials must be configured if the credential cache is enabled"))
+		}
+	}
+
+	if config.MaxBackups != nil && *config.MaxBackups < 0 {
+		errors = append(errors, field.Invalid(field.NewPath("maxBackups"), *config.MaxBackups, "the maximum number of backups must not be negative"))
+	}
+
+	if len(config.TelemetryEndpoint) > 0 {
+		if endpoint, err := url.Parse(config.TelemetryEndpoint); err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || len(endpoint.Host) == 0 {
+			errors = append(errors, field.Invalid(field.NewPath("telemetryEndpoint"), config.TelemetryEndpoint, "the telemetry endpoint must be a http or https URL"))
+		}
+	}
+
+	for i, mapping := range config.DirectoryStoreMapping {
+		mappingPath := field.NewPath("directoryStoreMapping").Index(i)
+		if len(mapping.Directory) == 0 {
+			errors = append(errors, field.Required(mappingPath.Child("directory"), "the directory has to be provided"))
+		}
+		if len(mapping.Store) == 0 {
+			errors = append(errors, field.Required(mappingPath.Child("store"), "the ID of the store has to be provided"))
+		}
+	}
+
+	if config.Monitor != nil {
+		errors = append(errors, validateMonitor(field.NewPath("monitor"), config.Monitor)...)
+	}
+
 	if len(config.Hooks) > 0 {
 		errors = append(errors, validateHooks(field.NewPath("hooks"), config.Hooks)...)
 	}
@@ -98,6 +230,26 @@ func ValidateConfig(config *types.Config) field.ErrorList {
 	return errors
 }
 
+// validateMonitor validates the monitor configuration
+func validateMonitor(path *field.Path, monitor *types.MonitorConfig) field.ErrorList {
+	var errors = field.ErrorList{}
+
+	if len(monitor.AlertmanagerURL) == 0 {
+		errors = append(errors, field.Required(path.Child("alertmanagerURL"), "the URL of the Alertmanager has to be provided"))
+	} else if u, err := url.Parse(monitor.AlertmanagerURL); err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+		errors = append(errors, field.Invalid(path.Child("alertmanagerURL"), monitor.AlertmanagerURL, "the URL of the Alertmanager must be an absolute URL, e.g. http://localhost:9093"))
+	}
+
+	if monitor.CheckInterval != nil && *monitor.CheckInterval <= 0 {
+		errors = append(errors, field.Invalid(path.Child("checkInterval"), *monitor.CheckInterval, "the check interval must be positive"))
+	}
+
+	if monitor.FailureThreshold != nil && *monitor.FailureThreshold < 1 {
+		errors = append(errors, field.Invalid(path.Child("failureThreshold"), *monitor.FailureThreshold, "the failure threshold must be at least 1"))
+	}
+	return errors
+}
+
 // validateHooks validates hook configuration
 func validateHooks(path *field.Path, hooks []types.Hook) field.ErrorList {
 	var errors = field.ErrorList{}
@@ -93,6 +93,150 @@ var _ = Describe("ValidateConfig", func() {
 		))
 	})
 
+	It("should throw error - invalid log level of the kubeconfig store", func() {
+		config := &types.Config{
+			Version: "v1alpha1",
+			KubeconfigStores: []types.KubeconfigStore{
+				{
+					Kind:     types.StoreKindFilesystem,
+					Paths:    []string{"path/abc"},
+					LogLevel: "verbose",
+				},
+			},
+		}
+		errorList := validation.ValidateConfig(config)
+		Expect(errorList).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeInvalid),
+				"Field": Equal("kubeconfigStores[0].logLevel"),
+			})),
+		))
+	})
+
+	It("should throw error - invalid RBAC check of the kubeconfig store", func() {
+		config := &types.Config{
+			Version: "v1alpha1",
+			KubeconfigStores: []types.KubeconfigStore{
+				{
+					Kind:               types.StoreKindFilesystem,
+					Paths:              []string{"path/abc"},
+					RBACCheckResources: []string{"get pods", "pods"},
+				},
+			},
+		}
+		errorList := validation.ValidateConfig(config)
+		Expect(errorList).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeInvalid),
+				"Field": Equal("kubeconfigStores[0].rbacCheckResources[1]"),
+			})),
+		))
+	})
+
+	It("should throw error - invalid window size of the kubeconfig store", func() {
+		config := &types.Config{
+			Version: "v1alpha1",
+			KubeconfigStores: []types.KubeconfigStore{
+				{
+					Kind:       types.StoreKindFilesystem,
+					Paths:      []string{"path/abc"},
+					Paginated:  true,
+					WindowSize: ptr.To(0),
+				},
+			},
+		}
+		errorList := validation.ValidateConfig(config)
+		Expect(errorList).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeInvalid),
+				"Field": Equal("kubeconfigStores[0].windowSize"),
+			})),
+		))
+	})
+
+	It("should throw error - the maximum kubeconfig size is negative", func() {
+		config := &types.Config{
+			Version: "v1alpha1",
+			KubeconfigStores: []types.KubeconfigStore{
+				{
+					Kind:              types.StoreKindFilesystem,
+					Paths:             []string{"path/abc"},
+					MaxKubeconfigSize: -1,
+				},
+			},
+		}
+		errorList := validation.ValidateConfig(config)
+		Expect(errorList).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeInvalid),
+				"Field": Equal("kubeconfigStores[0].maxKubeconfigSize"),
+			})),
+		))
+	})
+
+	It("should throw error - packages are invalid", func() {
+		config := &types.Config{
+			Version: "v1alpha1",
+			KubeconfigStores: []types.KubeconfigStore{
+				{
+					Kind:  types.StoreKindFilesystem,
+					Paths: []string{"path/abc"},
+					Packages: []types.PackageConfig{
+						{Name: "ring-0", Contexts: []string{"mgmt-*"}},
+						{Name: "ring-0", Contexts: []string{"infra-*"}},
+						{Contexts: []string{"apps-*"}},
+						{Name: "ring-2"},
+					},
+				},
+			},
+		}
+		errorList := validation.ValidateConfig(config)
+		Expect(errorList).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeDuplicate),
+				"Field": Equal("kubeconfigStores[0].packages[1].name"),
+			})),
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeRequired),
+				"Field": Equal("kubeconfigStores[0].packages[2].name"),
+			})),
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeRequired),
+				"Field": Equal("kubeconfigStores[0].packages[3].contexts"),
+			})),
+		))
+	})
+
+	It("should throw error - group sort order is invalid", func() {
+		config := &types.Config{
+			Version: "v1alpha1",
+			KubeconfigStores: []types.KubeconfigStore{
+				{
+					Kind:           types.StoreKindFilesystem,
+					Paths:          []string{"path/abc"},
+					GroupByTag:     "env",
+					GroupSortOrder: "size",
+				},
+				{
+					Kind:           types.StoreKindFilesystem,
+					Paths:          []string{"path/def"},
+					GroupSortOrder: types.GroupSortOrderCountDesc,
+				},
+			},
+		}
+		errorList := validation.ValidateConfig(config)
+		Expect(errorList).To(ConsistOf(
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeInvalid),
+				"Field": Equal("kubeconfigStores[0].groupSortOrder"),
+			})),
+			PointTo(MatchFields(IgnoreExtras, Fields{
+				"Type":  Equal(field.ErrorTypeRequired),
+				"Field": Equal("kubeconfigStores[1].groupByTag"),
+			})),
+		))
+	})
+
 	It("should throw error - no paths are configured for the kubeconfig store", func() {
 		config := &types.Config{
 			Version: "v1alpha1",
@@ -370,6 +514,59 @@ var _ = Describe("ValidateConfig", func() {
 				})),
 			))
 		})
+
+		It("should successfully validate multiple landscapes", func() {
+			config := &types.Config{
+				Version: "v1alpha1",
+				KubeconfigStores: []types.KubeconfigStore{
+					{
+						Kind: types.StoreKindGardener,
+						Config: types.StoreConfigGardener{
+							Landscapes: []types.LandscapeConfig{
+								{Name: "dev", GardenletKubeconfigPath: "dev"},
+								{Name: "live", GardenletKubeconfigPath: "live", Namespace: "garden-my-project"},
+							},
+						},
+					},
+				},
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(BeEmpty())
+		})
+
+		It("should throw error - invalid landscapes", func() {
+			config := &types.Config{
+				Version: "v1alpha1",
+				KubeconfigStores: []types.KubeconfigStore{
+					{
+						Kind: types.StoreKindGardener,
+						Config: types.StoreConfigGardener{
+							Landscapes: []types.LandscapeConfig{
+								{Name: "dev", GardenletKubeconfigPath: "dev"},
+								{Name: "dev", Namespace: "my-project"},
+							},
+						},
+					},
+				},
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeRequired),
+					"Field": Equal("kubeconfigStores[0].config.landscapes[1].gardenletKubeconfigPath"),
+				})),
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("kubeconfigStores[0].config.landscapes[1].namespace"),
+				})),
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeDuplicate),
+					"Field": Equal("kubeconfigStores[0].config.landscapes[1]"),
+				})),
+			))
+		})
 	})
 
 	Context("Hooks", func() {
@@ -449,4 +646,254 @@ var _ = Describe("ValidateConfig", func() {
 			))
 		})
 	})
+
+	Context("KubeconfigOutputPath", func() {
+		It("should successfully validate a templated kubeconfig output path", func() {
+			config := &types.Config{
+				Version:              "v1alpha1",
+				KubeconfigOutputPath: ptr.To("~/.kube/contexts/{{ .ContextName }}.yaml"),
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(BeEmpty())
+		})
+
+		It("should throw error - the kubeconfig output path is not a valid template", func() {
+			config := &types.Config{
+				Version:              "v1alpha1",
+				KubeconfigOutputPath: ptr.To("~/.kube/contexts/{{ .ContextName .yaml"),
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("kubeconfigOutputPath"),
+				})),
+			))
+		})
+	})
+
+	Context("CredentialCache", func() {
+		It("should throw error - the maximum age is not positive", func() {
+			config := &types.Config{
+				Version: "v1alpha1",
+				CredentialCache: &types.CredentialCacheConfig{
+					MaxAge: ptr.To(-time.Minute),
+				},
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("credentialCache.maxAge"),
+				})),
+			))
+		})
+
+		It("should throw error - the credential cache is enabled without SSH key", func() {
+			config := &types.Config{
+				Version: "v1alpha1",
+				CredentialCache: &types.CredentialCacheConfig{
+					Enabled: ptr.To(true),
+				},
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeRequired),
+					"Field": Equal("credentialCache.sshKeyPath"),
+				})),
+			))
+		})
+
+		It("should not require a SSH key if the credential cache is not enabled", func() {
+			config := &types.Config{
+				Version:         "v1alpha1",
+				CredentialCache: &types.CredentialCacheConfig{},
+			}
+
+			Expect(validation.ValidateConfig(config)).To(BeEmpty())
+		})
+
+		It("should accept an enabled credential cache with SSH key", func() {
+			config := &types.Config{
+				Version: "v1alpha1",
+				CredentialCache: &types.CredentialCacheConfig{
+					Enabled:    ptr.To(true),
+					SSHKeyPath: ptr.To("~/.ssh/kubeswitch"),
+				},
+			}
+
+			Expect(validation.ValidateConfig(config)).To(BeEmpty())
+		})
+	})
+
+	Context("MaxBackups", func() {
+		It("should throw error - the maximum number of backups is negative", func() {
+			config := &types.Config{
+				Version:    "v1alpha1",
+				MaxBackups: ptr.To(-1),
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("maxBackups"),
+				})),
+			))
+		})
+	})
+
+	Context("TelemetryEndpoint", func() {
+		It("should throw error - the telemetry endpoint is not a http URL", func() {
+			for _, endpoint := range []string{"telemetry.example.com", "ftp://telemetry.example.com", "https://"} {
+				config := &types.Config{
+					Version:           "v1alpha1",
+					TelemetryEndpoint: endpoint,
+				}
+
+				errorList := validation.ValidateConfig(config)
+				Expect(errorList).To(ConsistOf(
+					PointTo(MatchFields(IgnoreExtras, Fields{
+						"Type":  Equal(field.ErrorTypeInvalid),
+						"Field": Equal("telemetryEndpoint"),
+					})),
+				))
+			}
+		})
+
+		It("should accept a https telemetry endpoint", func() {
+			config := &types.Config{
+				Version:           "v1alpha1",
+				TelemetryEndpoint: "https://telemetry.example.com/events",
+			}
+			Expect(validation.ValidateConfig(config)).To(BeEmpty())
+		})
+	})
+
+	Context("CompletionTimeout", func() {
+		It("should throw error - the completion timeout is not positive", func() {
+			config := &types.Config{
+				Version:           "v1alpha1",
+				CompletionTimeout: ptr.To(time.Duration(0)),
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("completionTimeout"),
+				})),
+			))
+		})
+	})
+
+	Context("DirectoryStoreMapping", func() {
+		It("should throw error - the directory or store of a mapping is missing", func() {
+			config := &types.Config{
+				Version: "v1alpha1",
+				DirectoryStoreMapping: []types.DirMapping{
+					{Directory: "~/work/project-a", Store: "eks.dev"},
+					{Store: "gke.prod"},
+					{Directory: "~/work/project-c"},
+				},
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeRequired),
+					"Field": Equal("directoryStoreMapping[1].directory"),
+				})),
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeRequired),
+					"Field": Equal("directoryStoreMapping[2].store"),
+				})),
+			))
+		})
+	})
+
+	Context("Transformers", func() {
+		It("should throw error - invalid transformer configuration", func() {
+			config := &types.Config{
+				Version: "v1alpha1",
+				KubeconfigStores: []types.KubeconfigStore{
+					{
+						Kind:  types.StoreKindFilesystem,
+						Paths: []string{"/path"},
+						Transformers: []types.Transformer{
+							{Type: types.TransformerTypeStripToken},
+							{Type: types.TransformerTypeServerRewrite, From: "internal.example.com"},
+							{Type: types.TransformerTypeSetNamespace},
+							{Type: "unknown"},
+						},
+					},
+				},
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeRequired),
+					"Field": Equal("kubeconfigStores[0].transformers[1]"),
+				})),
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeRequired),
+					"Field": Equal("kubeconfigStores[0].transformers[2].namespace"),
+				})),
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("kubeconfigStores[0].transformers[3].type"),
+				})),
+			))
+		})
+	})
+
+	Context("Monitor", func() {
+		It("should successfully validate the monitor configuration", func() {
+			config := &types.Config{
+				Version: "v1alpha1",
+				Monitor: &types.MonitorConfig{
+					AlertmanagerURL:  "http://localhost:9093",
+					CheckInterval:    ptr.To(30 * time.Second),
+					FailureThreshold: ptr.To(2),
+					WatchContexts:    []string{"prod"},
+				},
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(BeEmpty())
+		})
+
+		It("should throw error - invalid monitor configuration", func() {
+			config := &types.Config{
+				Version: "v1alpha1",
+				Monitor: &types.MonitorConfig{
+					AlertmanagerURL:  "localhost:9093",
+					CheckInterval:    ptr.To(time.Duration(0)),
+					FailureThreshold: ptr.To(0),
+				},
+			}
+
+			errorList := validation.ValidateConfig(config)
+			Expect(errorList).To(ConsistOf(
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("monitor.alertmanagerURL"),
+				})),
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("monitor.checkInterval"),
+				})),
+				PointTo(MatchFields(IgnoreExtras, Fields{
+					"Type":  Equal(field.ErrorTypeInvalid),
+					"Field": Equal("monitor.failureThreshold"),
+				})),
+			))
+		})
+	})
 })
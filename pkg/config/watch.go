@@ -0,0 +1,173 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// watchDebounce is the time to wait for further writes to the config file before reloading it
+const watchDebounce = 500 * time.Millisecond
+
+// configWatcher watches a config file until closed
+type configWatcher struct {
+	watcher *fsnotify.Watcher
+
+	lock   sync.Mutex
+	timer  *time.Timer
+	closed bool
+}
+
+// WatchConfig watches the config file with the given path and calls onChange with the reloaded config whenever the file changes.
+// Rapid successive writes only trigger a single reload. Configs that cannot be read are logged and skipped.
+// The returned closer stops watching the file.
+func WatchConfig(path string, onChange func(types.Config)) (io.Closer, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	// watch the parent directory as editors frequently replace the file instead of writing to it
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch config %q: %w", path, err)
+	}
+
+	w := &configWatcher{watcher: watcher}
+
+	reload := func() {
+		config, err := LoadConfigFromFile(path)
+		if err != nil {
+			logrus.Warnf("failed to reload config %q: %v", path, err)
+			return
+		}
+		if config == nil {
+			logrus.Debugf("config %q has been removed", path)
+			return
+		}
+
+		w.lock.Lock()
+		closed := w.closed
+		w.lock.Unlock()
+		if !closed {
+			onChange(*config)
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path || event.Op == fsnotify.Chmod {
+					continue
+				}
+				w.debounce(reload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logrus.Warnf("error watching config %q: %v", path, err)
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+// debounce calls the function once no further change happened within the debounce time
+func (w *configWatcher) debounce(f func()) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(watchDebounce, f)
+}
+
+// Close stops watching the config file
+func (w *configWatcher) Close() error {
+	w.lock.Lock()
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.lock.Unlock()
+
+	return w.watcher.Close()
+}
+
+// StoreChanges are the changes of the kubeconfig stores between two configs.
+// Stores are identified by the ID of the store created for their configuration (see store.IDForConfig), a store whose configuration changed is both removed and added.
+type StoreChanges struct {
+	// Removed contains the IDs of the stores to remove
+	Removed []string
+	// Added contains the configuration of the stores to add
+	Added []types.KubeconfigStore
+}
+
+// IsEmpty returns true if no store changed
+func (c StoreChanges) IsEmpty() bool {
+	return len(c.Removed) == 0 && len(c.Added) == 0
+}
+
+// DiffKubeconfigStores returns the changes from the old to the new kubeconfig stores
+func DiffKubeconfigStores(old, new []types.KubeconfigStore) StoreChanges {
+	oldStores := make(map[string]types.KubeconfigStore, len(old))
+	for _, s := range old {
+		oldStores[store.IDForConfig(s)] = s
+	}
+
+	newStores := make(map[string]types.KubeconfigStore, len(new))
+	for _, s := range new {
+		newStores[store.IDForConfig(s)] = s
+	}
+
+	changes := StoreChanges{}
+	for _, s := range old {
+		id := store.IDForConfig(s)
+		if n, ok := newStores[id]; !ok || !reflect.DeepEqual(s, n) {
+			changes.Removed = append(changes.Removed, id)
+		}
+	}
+	for _, s := range new {
+		if o, ok := oldStores[store.IDForConfig(s)]; !ok || !reflect.DeepEqual(o, s) {
+			changes.Added = append(changes.Added, s)
+		}
+	}
+	return changes
+}
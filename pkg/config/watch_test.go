@@ -0,0 +1,197 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const watchTestConfig = `kind: SwitchConfig
+version: v1alpha1
+kubeconfigStores:
+  - kind: filesystem
+    paths:
+      - %s
+`
+
+var _ = Describe("DiffKubeconfigStores", func() {
+	var (
+		filesystem = types.KubeconfigStore{Kind: types.StoreKindFilesystem, Paths: []string{"~/.kube"}}
+		vault      = types.KubeconfigStore{ID: ptr.To("team"), Kind: types.StoreKindVault, Paths: []string{"secret/k8s"}}
+	)
+
+	It("should not report unchanged stores", func() {
+		changes := switchconfig.DiffKubeconfigStores(
+			[]types.KubeconfigStore{filesystem, vault},
+			[]types.KubeconfigStore{vault, filesystem},
+		)
+		Expect(changes.IsEmpty()).To(BeTrue())
+	})
+
+	It("should report added stores", func() {
+		changes := switchconfig.DiffKubeconfigStores(
+			[]types.KubeconfigStore{filesystem},
+			[]types.KubeconfigStore{filesystem, vault},
+		)
+		Expect(changes.Removed).To(BeEmpty())
+		Expect(changes.Added).To(Equal([]types.KubeconfigStore{vault}))
+	})
+
+	It("should report removed stores", func() {
+		changes := switchconfig.DiffKubeconfigStores(
+			[]types.KubeconfigStore{filesystem, vault},
+			[]types.KubeconfigStore{filesystem},
+		)
+		Expect(changes.Removed).To(Equal([]string{"vault.team"}))
+		Expect(changes.Added).To(BeEmpty())
+	})
+
+	It("should remove and add changed stores", func() {
+		changed := filesystem
+		changed.Paths = []string{"~/work"}
+
+		changes := switchconfig.DiffKubeconfigStores(
+			[]types.KubeconfigStore{filesystem, vault},
+			[]types.KubeconfigStore{changed, vault},
+		)
+		Expect(changes.Removed).To(Equal([]string{"filesystem.default"}))
+		Expect(changes.Added).To(Equal([]types.KubeconfigStore{changed}))
+	})
+
+	It("should remove a Gardener store by the ID of its landscape", func() {
+		gardener := types.KubeconfigStore{
+			Kind:   types.StoreKindGardener,
+			Config: map[string]interface{}{"landscapeName": "dev", "gardenerAPIKubeconfigPath": "/garden/dev"},
+		}
+		changed := gardener
+		changed.Config = map[string]interface{}{"landscapeName": "dev", "gardenerAPIKubeconfigPath": "/garden/dev-new"}
+
+		changes := switchconfig.DiffKubeconfigStores(
+			[]types.KubeconfigStore{gardener},
+			[]types.KubeconfigStore{changed},
+		)
+		Expect(changes.Removed).To(Equal([]string{"gardener.dev"}))
+		Expect(changes.Added).To(Equal([]types.KubeconfigStore{changed}))
+	})
+
+	It("should remove an Akamai store by its default ID", func() {
+		akamai := types.KubeconfigStore{ID: ptr.To("ignored"), Kind: types.StoreKindAkamai}
+
+		changes := switchconfig.DiffKubeconfigStores(
+			[]types.KubeconfigStore{filesystem, akamai},
+			[]types.KubeconfigStore{filesystem},
+		)
+		Expect(changes.Removed).To(Equal([]string{"akamai.default"}))
+	})
+
+	It("should remove a fallback store by the hash of the IDs of its stores", func() {
+		fallback := types.KubeconfigStore{
+			Kind: types.StoreKindFallback,
+			Config: map[string]interface{}{
+				"primary":  map[string]interface{}{"kind": "filesystem", "paths": []interface{}{"~/.kube"}},
+				"fallback": map[string]interface{}{"kind": "vault", "id": "team", "paths": []interface{}{"secret/k8s"}},
+			},
+		}
+
+		changes := switchconfig.DiffKubeconfigStores(
+			[]types.KubeconfigStore{filesystem, fallback},
+			[]types.KubeconfigStore{filesystem},
+		)
+		Expect(changes.Removed).To(HaveLen(1))
+		Expect(changes.Removed[0]).To(HavePrefix("fallback."))
+		Expect(changes.Removed[0]).ToNot(Equal("fallback.default"))
+	})
+})
+
+var _ = Describe("WatchConfig", func() {
+	var (
+		dir     string
+		path    string
+		watcher io.Closer
+
+		lock    sync.Mutex
+		reloads []types.Config
+	)
+
+	getReloads := func() []types.Config {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]types.Config{}, reloads...)
+	}
+
+	writeConfig := func(path, kubeconfigPath string) {
+		Expect(os.WriteFile(path, []byte(fmt.Sprintf(watchTestConfig, kubeconfigPath)), 0600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "kubeswitch-watch")
+		Expect(err).ToNot(HaveOccurred())
+		path = filepath.Join(dir, "switch-config.yaml")
+		writeConfig(path, "/initial")
+
+		reloads = nil
+		watcher, err = switchconfig.WatchConfig(path, func(config types.Config) {
+			lock.Lock()
+			defer lock.Unlock()
+			reloads = append(reloads, config)
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(watcher.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should reload the config once after rapid writes", func() {
+		for i := 0; i < 5; i++ {
+			writeConfig(path, fmt.Sprintf("/write-%d", i))
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		Eventually(getReloads, "3s").Should(HaveLen(1))
+		Consistently(getReloads, "1s").Should(HaveLen(1))
+		Expect(getReloads()[0].KubeconfigStores[0].Paths).To(Equal([]string{"/write-4"}))
+	})
+
+	It("should reload the config after it has been replaced with a rename", func() {
+		replacement := filepath.Join(dir, "switch-config.yaml.tmp")
+		writeConfig(replacement, "/renamed")
+		Expect(os.Rename(replacement, path)).To(Succeed())
+
+		Eventually(getReloads, "3s").Should(HaveLen(1))
+		Expect(getReloads()[0].KubeconfigStores[0].Paths).To(Equal([]string{"/renamed"}))
+	})
+
+	It("should not reload the config after it has been closed", func() {
+		Expect(watcher.Close()).To(Succeed())
+		writeConfig(path, "/closed")
+
+		Consistently(getReloads, "1s").Should(BeEmpty())
+	})
+})
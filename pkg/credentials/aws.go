@@ -0,0 +1,68 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/sirupsen/logrus"
+)
+
+// awsCredentialsProvider returns AWS credentials from the credential cache
+// and only falls back to the wrapped provider if no valid credentials are cached
+type awsCredentialsProvider struct {
+	cache    *CredentialCache
+	storeID  string
+	key      string
+	provider aws.CredentialsProvider
+}
+
+// NewAWSCredentialsProvider wraps the given AWS credentials provider (e.g. resolving STS credentials)
+// to cache the retrieved credentials for the store under the given key.
+// Returns the given provider if the cache is nil.
+func NewAWSCredentialsProvider(cache *CredentialCache, storeID, key string, provider aws.CredentialsProvider) aws.CredentialsProvider {
+	if cache == nil || provider == nil {
+		return provider
+	}
+	return &awsCredentialsProvider{
+		cache:    cache,
+		storeID:  storeID,
+		key:      key,
+		provider: provider,
+	}
+}
+
+func (p *awsCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	var creds aws.Credentials
+	if found, err := p.cache.Get(p.storeID, p.key, &creds); err == nil && found {
+		return creds, nil
+	}
+
+	creds, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		return creds, err
+	}
+
+	var expiry time.Time
+	if creds.CanExpire {
+		expiry = creds.Expires
+	}
+	if err := p.cache.Put(p.storeID, p.key, creds, expiry); err != nil {
+		logrus.Debugf("Failed to cache AWS credentials of store %q: %v", p.storeID, err)
+	}
+	return creds, nil
+}
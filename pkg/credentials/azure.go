@@ -0,0 +1,91 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/sirupsen/logrus"
+)
+
+// azureTokenCredential returns Azure ARM access tokens from the credential cache
+// and only falls back to the wrapped credential if no valid token is cached
+type azureTokenCredential struct {
+	cache      *CredentialCache
+	storeID    string
+	credential azcore.TokenCredential
+}
+
+// NewAzureTokenCredential wraps the given Azure token credential (e.g. the default Azure credential)
+// to cache the obtained access tokens for the store.
+// Returns the given credential if the cache is nil.
+func NewAzureTokenCredential(cache *CredentialCache, storeID string, credential azcore.TokenCredential) azcore.TokenCredential {
+	if cache == nil || credential == nil {
+		return credential
+	}
+	return &azureTokenCredential{
+		cache:      cache,
+		storeID:    storeID,
+		credential: credential,
+	}
+}
+
+func (c *azureTokenCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (*azcore.AccessToken, error) {
+	// tokens are specific to the requested scopes and tenant
+	key := strings.Join(append([]string{options.TenantID}, options.Scopes...), "_")
+
+	token := &azcore.AccessToken{}
+	if found, err := c.cache.Get(c.storeID, key, token); err == nil && found {
+		return token, nil
+	}
+
+	token, err := c.credential.GetToken(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.cache.Put(c.storeID, key, token, token.ExpiresOn); err != nil {
+		logrus.Debugf("Failed to cache Azure access token of store %q: %v", c.storeID, err)
+	}
+	return token, nil
+}
+
+// NewAuthenticationPolicy returns a policy adding the (cached) bearer token to each request
+func (c *azureTokenCredential) NewAuthenticationPolicy(options runtime.AuthenticationOptions) policy.Policy {
+	return &bearerTokenPolicy{
+		credential: c,
+		options:    options.TokenRequest,
+	}
+}
+
+type bearerTokenPolicy struct {
+	credential azcore.TokenCredential
+	options    policy.TokenRequestOptions
+}
+
+func (p *bearerTokenPolicy) Do(req *policy.Request) (*http.Response, error) {
+	token, err := p.credential.GetToken(req.Raw().Context(), p.options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain Azure access token: %w", err)
+	}
+	req.Raw().Header.Set("Authorization", "Bearer "+token.Token)
+	return req.Next()
+}
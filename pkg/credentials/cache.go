@@ -0,0 +1,239 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// DefaultMaxAge is the maximum age of a cached credential if not configured otherwise
+	DefaultMaxAge = 1 * time.Hour
+
+	// FileSuffix is the suffix of the encrypted credential files
+	FileSuffix = ".age"
+)
+
+// CredentialCache persists cloud provider credentials in files encrypted with the SSH key of the user.
+// This avoids obtaining new credentials from the cloud provider on every invocation of kubeswitch.
+// A nil *CredentialCache is valid and caches nothing.
+type CredentialCache struct {
+	// Directory contains one encrypted file per cached credential
+	Directory string
+	// MaxAge is the maximum duration a credential is cached,
+	// regardless of the expiry of the credential itself
+	MaxAge time.Duration
+
+	identity  age.Identity
+	recipient age.Recipient
+	lock      sync.Mutex
+}
+
+// entry is the decrypted content of a credential file
+type entry struct {
+	Data   json.RawMessage `json:"data"`
+	Expiry time.Time       `json:"expiry"`
+}
+
+// NewCredentialCache creates a credential cache in the given directory encrypting the credentials with the given SSH private key.
+// The SSH key has to be configured explicitly, no default key of the user is read.
+// Returns a nil cache without error if the SSH key is protected by a passphrase,
+// as the credential cache is an optimization only.
+func NewCredentialCache(directory string, maxAge time.Duration, sshKeyPath string) (*CredentialCache, error) {
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+
+	if len(sshKeyPath) == 0 {
+		return nil, fmt.Errorf("the SSH key to encrypt the credential cache must be configured")
+	}
+	keyPath := os.ExpandEnv(sshKeyPath)
+
+	pemBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key %q: %w", keyPath, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	if err != nil {
+		var passphraseErr *ssh.PassphraseMissingError
+		if errors.As(err, &passphraseErr) {
+			logrus.Warnf("SSH key %q is protected by a passphrase. Credentials are not cached.", keyPath)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to parse SSH key %q: %w", keyPath, err)
+	}
+
+	identity, err := agessh.ParseIdentity(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported SSH key %q: %w", keyPath, err)
+	}
+
+	recipient, err := agessh.ParseRecipient(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported SSH key %q: %w", keyPath, err)
+	}
+
+	return &CredentialCache{
+		Directory: directory,
+		MaxAge:    maxAge,
+		identity:  identity,
+		recipient: recipient,
+	}, nil
+}
+
+// Get decodes the cached credential for the given store and key into v.
+// Returns false if no valid credential is cached. Expired credentials are removed from the cache.
+func (c *CredentialCache) Get(storeID, key string, v interface{}) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	path := c.path(storeID, key)
+	encrypted, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	reader, err := age.Decrypt(bytes.NewReader(encrypted), c.identity)
+	if err != nil {
+		// e.g. the SSH key changed. Drop the credential so that it is obtained again.
+		_ = kubeswitchio.Remove(path)
+		return false, fmt.Errorf("failed to decrypt cached credential %q: %w", path, err)
+	}
+
+	var e entry
+	if err := json.NewDecoder(reader).Decode(&e); err != nil {
+		_ = kubeswitchio.Remove(path)
+		return false, fmt.Errorf("failed to decode cached credential %q: %w", path, err)
+	}
+
+	if !time.Now().Before(e.Expiry) {
+		_ = kubeswitchio.Remove(path)
+		return false, nil
+	}
+
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return false, fmt.Errorf("failed to decode cached credential %q: %w", path, err)
+	}
+	return true, nil
+}
+
+// Put caches the credential for the given store and key.
+// The credential is cached until the given expiry, but at most for the MaxAge of the cache.
+// A zero expiry means the credential does not expire.
+func (c *CredentialCache) Put(storeID, key string, v interface{}, expiry time.Time) error {
+	if c == nil {
+		return nil
+	}
+
+	maxExpiry := time.Now().Add(c.MaxAge)
+	if expiry.IsZero() || expiry.After(maxExpiry) {
+		expiry = maxExpiry
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(entry{Data: data, Expiry: expiry})
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	writer, err := age.Encrypt(&buf, c.recipient)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, bytes.NewReader(content)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if err := kubeswitchio.MkdirAll(c.Directory, 0700); err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(c.path(storeID, key), buf.Bytes(), 0600)
+}
+
+func (c *CredentialCache) path(storeID, key string) string {
+	return filepath.Join(c.Directory, fileName(storeID, key))
+}
+
+// fileName returns the name of the credential file.
+// Path separators are replaced so that the file is always created in the cache directory.
+func fileName(storeID, key string) string {
+	replacer := strings.NewReplacer("/", "_", "\\", "_")
+	return replacer.Replace(storeID) + "." + replacer.Replace(key) + FileSuffix
+}
+
+// Clear removes the cached credentials of the store with the given ID from the cache directory.
+// Removes all cached credentials if the store ID is empty.
+// Returns the number of removed credentials.
+func Clear(directory, storeID string) (int, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	prefix := ""
+	if len(storeID) > 0 {
+		prefix = fileName(storeID, "")
+		prefix = strings.TrimSuffix(prefix, FileSuffix)
+	}
+
+	removed := 0
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), FileSuffix) || !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		if err := kubeswitchio.Remove(filepath.Join(directory, e.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+)
+
+type token struct {
+	Value string `json:"value"`
+}
+
+var _ = Describe("CredentialCache", func() {
+	var (
+		dir        string
+		sshKeyPath string
+		cache      *credentials.CredentialCache
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "credentials")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		block, err := ssh.MarshalPrivateKey(privateKey, "")
+		Expect(err).ToNot(HaveOccurred())
+
+		sshKeyPath = filepath.Join(dir, "id_ed25519")
+		Expect(os.WriteFile(sshKeyPath, pem.EncodeToMemory(block), 0600)).To(Succeed())
+
+		cache, err = credentials.NewCredentialCache(filepath.Join(dir, "creds"), time.Hour, sshKeyPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cache).ToNot(BeNil())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should return a cached credential", func() {
+		Expect(cache.Put("eks.default", "profile", token{Value: "secret"}, time.Now().Add(time.Minute))).To(Succeed())
+
+		var t token
+		found, err := cache.Get("eks.default", "profile", &t)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(t.Value).To(Equal("secret"))
+	})
+
+	It("should encrypt the cached credential", func() {
+		Expect(cache.Put("eks.default", "profile", token{Value: "secret"}, time.Time{})).To(Succeed())
+
+		content, err := os.ReadFile(filepath.Join(dir, "creds", "eks.default.profile.age"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).ToNot(ContainSubstring("secret"))
+	})
+
+	It("should remove an expired credential", func() {
+		Expect(cache.Put("eks.default", "profile", token{Value: "secret"}, time.Now().Add(-time.Minute))).To(Succeed())
+
+		var t token
+		found, err := cache.Get("eks.default", "profile", &t)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+		Expect(filepath.Join(dir, "creds", "eks.default.profile.age")).ToNot(BeAnExistingFile())
+	})
+
+	It("should not cache a credential longer than the maximum age", func() {
+		cache.MaxAge = time.Nanosecond
+		Expect(cache.Put("eks.default", "profile", token{Value: "secret"}, time.Now().Add(time.Hour))).To(Succeed())
+
+		var t token
+		found, err := cache.Get("eks.default", "profile", &t)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	It("should not find a credential of another store", func() {
+		Expect(cache.Put("eks.default", "profile", token{Value: "secret"}, time.Time{})).To(Succeed())
+
+		var t token
+		found, err := cache.Get("gke.default", "profile", &t)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	It("should cache nothing if the cache is nil", func() {
+		var nilCache *credentials.CredentialCache
+		Expect(nilCache.Put("eks.default", "profile", token{Value: "secret"}, time.Time{})).To(Succeed())
+
+		var t token
+		found, err := nilCache.Get("eks.default", "profile", &t)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	It("should clear the credentials of a single store", func() {
+		Expect(cache.Put("eks.default", "profile", token{Value: "a"}, time.Time{})).To(Succeed())
+		Expect(cache.Put("gke.default", "account", token{Value: "b"}, time.Time{})).To(Succeed())
+
+		removed, err := credentials.Clear(cache.Directory, "eks.default")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(removed).To(Equal(1))
+
+		var t token
+		found, err := cache.Get("gke.default", "account", &t)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+	})
+
+	It("should clear all credentials", func() {
+		Expect(cache.Put("eks.default", "profile", token{Value: "a"}, time.Time{})).To(Succeed())
+		Expect(cache.Put("gke.default", "account", token{Value: "b"}, time.Time{})).To(Succeed())
+
+		removed, err := credentials.Clear(cache.Directory, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(removed).To(Equal(2))
+	})
+
+	It("should not read a default SSH key of the user", func() {
+		home := os.Getenv("HOME")
+		defer os.Setenv("HOME", home)
+		Expect(os.MkdirAll(filepath.Join(dir, ".ssh"), 0700)).To(Succeed())
+		Expect(os.Rename(sshKeyPath, filepath.Join(dir, ".ssh", "id_ed25519"))).To(Succeed())
+		Expect(os.Setenv("HOME", dir)).To(Succeed())
+
+		cache, err := credentials.NewCredentialCache(filepath.Join(dir, "creds"), time.Hour, "")
+		Expect(err).To(MatchError(ContainSubstring("must be configured")))
+		Expect(cache).To(BeNil())
+	})
+
+	It("should not cache credentials if the SSH key is protected by a passphrase", func() {
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		block, err := ssh.MarshalPrivateKeyWithPassphrase(privateKey, "", []byte("passphrase"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(sshKeyPath, pem.EncodeToMemory(block), 0600)).To(Succeed())
+
+		cache, err := credentials.NewCredentialCache(filepath.Join(dir, "creds"), time.Hour, sshKeyPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cache).To(BeNil())
+	})
+})
@@ -0,0 +1,61 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentials
+
+import (
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// oauth2TokenSource returns OAuth2 tokens from the credential cache
+// and only falls back to the wrapped token source if no valid token is cached
+type oauth2TokenSource struct {
+	cache   *CredentialCache
+	storeID string
+	key     string
+	source  oauth2.TokenSource
+}
+
+// NewOAuth2TokenSource wraps the given OAuth2 token source (e.g. the Google application default credentials)
+// to cache the obtained tokens for the store under the given key.
+// Returns the given token source if the cache is nil.
+func NewOAuth2TokenSource(cache *CredentialCache, storeID, key string, source oauth2.TokenSource) oauth2.TokenSource {
+	if cache == nil || source == nil {
+		return source
+	}
+	return oauth2.ReuseTokenSource(nil, &oauth2TokenSource{
+		cache:   cache,
+		storeID: storeID,
+		key:     key,
+		source:  source,
+	})
+}
+
+func (s *oauth2TokenSource) Token() (*oauth2.Token, error) {
+	token := &oauth2.Token{}
+	if found, err := s.cache.Get(s.storeID, s.key, token); err == nil && found && token.Valid() {
+		return token, nil
+	}
+
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.Put(s.storeID, s.key, token, token.Expiry); err != nil {
+		logrus.Debugf("Failed to cache OAuth2 token of store %q: %v", s.storeID, err)
+	}
+	return token, nil
+}
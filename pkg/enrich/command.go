@@ -0,0 +1,65 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrich
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommandEnricher runs a command with the context name as last argument
+// and adds the "key=value" lines printed by the command to the tags
+type CommandEnricher struct {
+	command string
+	args    []string
+}
+
+// NewCommandEnricher creates an enricher running the given command
+func NewCommandEnricher(command string, args []string) *CommandEnricher {
+	return &CommandEnricher{
+		command: command,
+		args:    args,
+	}
+}
+
+func (e *CommandEnricher) Enrich(ctx context.Context, result *SearchResult) error {
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, e.command, append(e.args, result.ContextName)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("command %q failed: %w: %s", e.command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	scanner := bufio.NewScanner(&stdout)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found || len(strings.TrimSpace(key)) == 0 {
+			return fmt.Errorf("command %q printed invalid line %q. Expected format key=value", e.command, line)
+		}
+		result.Tags[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// defaultTimeout is the default maximum duration of an enricher for a single search result
+const defaultTimeout = 5 * time.Second
+
+// SearchResult is a discovered context that is enriched with additional metadata
+type SearchResult struct {
+	// ContextName is the context name as shown in the selection dialog
+	ContextName string
+	// StoreID is the ID of the store that discovered the context
+	StoreID string
+	// Tags contains the metadata of the context.
+	// Initially contains the tags set by the store.
+	Tags map[string]string
+}
+
+// Enricher adds metadata to search results
+type Enricher interface {
+	// Enrich adds metadata to the tags of the given search result
+	Enrich(ctx context.Context, result *SearchResult) error
+}
+
+// chain is an enricher running multiple enrichers in order
+type chain []Enricher
+
+// Chain returns an enricher that runs all given enrichers in order.
+// Later enrichers can overwrite tags of previous enrichers.
+// A failing enricher does not prevent the following enrichers from running.
+func Chain(enrichers ...Enricher) Enricher {
+	return chain(enrichers)
+}
+
+func (c chain) Enrich(ctx context.Context, result *SearchResult) error {
+	var errs error
+	for _, enricher := range c {
+		if err := enricher.Enrich(ctx, result); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+// timeoutEnricher limits the duration of an enricher
+type timeoutEnricher struct {
+	enricher Enricher
+	timeout  time.Duration
+}
+
+// WithTimeout returns an enricher that cancels the given enricher after the timeout
+func WithTimeout(enricher Enricher, timeout time.Duration) Enricher {
+	return &timeoutEnricher{enricher: enricher, timeout: timeout}
+}
+
+func (t *timeoutEnricher) Enrich(ctx context.Context, result *SearchResult) error {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.enricher.Enrich(ctx, result)
+}
+
+// NewEnricher returns an enricher running all configured enrichers in order
+func NewEnricher(configs []types.Enricher) (Enricher, error) {
+	var enrichers []Enricher
+	for _, config := range configs {
+		var (
+			enricher Enricher
+			err      error
+		)
+
+		switch config.Type {
+		case types.EnricherTypeHTTP:
+			enricher, err = NewHTTPEnricher(config.URL, config.Headers)
+		case types.EnricherTypeCommand:
+			enricher = NewCommandEnricher(config.Command, config.Args)
+		default:
+			err = fmt.Errorf("unknown enricher type %q", config.Type)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		timeout := defaultTimeout
+		if config.Timeout != nil {
+			timeout = *config.Timeout
+		}
+		enrichers = append(enrichers, WithTimeout(enricher, timeout))
+	}
+	return Chain(enrichers...), nil
+}
+
+// EnrichAll enriches all search results concurrently with at most maxConcurrent results at a time.
+// Returns the aggregated errors of all search results.
+func EnrichAll(ctx context.Context, enricher Enricher, results []*SearchResult, maxConcurrent int) error {
+	var (
+		errs      error
+		errsLock  sync.Mutex
+		wg        sync.WaitGroup
+		semaphore = make(chan struct{}, maxConcurrent)
+	)
+
+	for _, result := range results {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(result *SearchResult) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			if result.Tags == nil {
+				result.Tags = make(map[string]string)
+			}
+
+			if err := enricher.Enrich(ctx, result); err != nil {
+				errsLock.Lock()
+				errs = multierror.Append(errs, fmt.Errorf("failed to enrich context %q: %w", result.ContextName, err))
+				errsLock.Unlock()
+			}
+		}(result)
+	}
+	wg.Wait()
+	return errs
+}
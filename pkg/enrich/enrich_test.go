@@ -0,0 +1,78 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrich_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/enrich"
+)
+
+var _ = Describe("Enrich", func() {
+	var results []*enrich.SearchResult
+
+	BeforeEach(func() {
+		results = []*enrich.SearchResult{
+			{ContextName: "dev", Tags: map[string]string{"region": "eu"}},
+			{ContextName: "prod"},
+		}
+	})
+
+	It("should add the JSON fields returned by the URL", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"team": "team-%s", "replicas": 3, "owner": null}`, r.URL.Query().Get("context"))
+		}))
+		defer server.Close()
+
+		enricher, err := enrich.NewHTTPEnricher(server.URL+"?context={{ .ContextName }}", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(enrich.EnrichAll(context.Background(), enricher, results, 2)).To(Succeed())
+		Expect(results[0].Tags).To(Equal(map[string]string{"region": "eu", "team": "team-dev", "replicas": "3"}))
+		Expect(results[1].Tags).To(Equal(map[string]string{"team": "team-prod", "replicas": "3"}))
+	})
+
+	It("should add the key=value lines printed by the command", func() {
+		enricher := enrich.NewCommandEnricher("sh", []string{"-c", "echo team=team-$0; echo; echo 'region = us'"})
+
+		Expect(enrich.EnrichAll(context.Background(), enricher, results, 2)).To(Succeed())
+		Expect(results[0].Tags).To(Equal(map[string]string{"region": "us", "team": "team-dev"}))
+		Expect(results[1].Tags).To(Equal(map[string]string{"region": "us", "team": "team-prod"}))
+	})
+
+	It("should run all chained enrichers and return their errors", func() {
+		enricher := enrich.Chain(
+			enrich.NewCommandEnricher("sh", []string{"-c", "echo invalid"}),
+			enrich.NewCommandEnricher("sh", []string{"-c", "echo team=a"}),
+		)
+
+		Expect(enrich.EnrichAll(context.Background(), enricher, results, 1)).ToNot(Succeed())
+		Expect(results[1].Tags).To(HaveKeyWithValue("team", "a"))
+	})
+
+	It("should cancel enrichers exceeding the timeout", func() {
+		enricher := enrich.WithTimeout(enrich.NewCommandEnricher("sleep", nil), 100*time.Millisecond)
+
+		result := &enrich.SearchResult{ContextName: "5", Tags: map[string]string{}}
+		Expect(enricher.Enrich(context.Background(), result)).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,100 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"text/template"
+)
+
+// HTTPEnricher fetches a JSON object from a URL and adds its fields to the tags
+type HTTPEnricher struct {
+	url     *template.Template
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewHTTPEnricher creates an enricher fetching the metadata from the given URL template.
+// The context name is available in the template as {{ .ContextName }}
+func NewHTTPEnricher(urlTemplate string, headers map[string]string) (*HTTPEnricher, error) {
+	tmpl, err := template.New("url").Option("missingkey=error").Parse(urlTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse URL template %q: %w", urlTemplate, err)
+	}
+
+	return &HTTPEnricher{
+		url:     tmpl,
+		headers: headers,
+		client:  http.DefaultClient,
+	}, nil
+}
+
+func (e *HTTPEnricher) Enrich(ctx context.Context, result *SearchResult) error {
+	url := bytes.Buffer{}
+	if err := e.url.Execute(&url, result); err != nil {
+		return fmt.Errorf("failed to render URL: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	for key, value := range e.headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", url.String(), response.StatusCode)
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return fmt.Errorf("response of GET %s is not a JSON object: %w", url.String(), err)
+	}
+
+	for key, value := range fields {
+		switch v := value.(type) {
+		case string:
+			result.Tags[key] = v
+		case nil:
+			continue
+		default:
+			// numbers, booleans and nested objects are stored in their JSON representation
+			encoded, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			result.Tags[key] = string(encoded)
+		}
+	}
+	return nil
+}
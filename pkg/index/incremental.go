@@ -0,0 +1,191 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+)
+
+// IncrementalRefresh returns the current search results of the store based on the existing search results of the index.
+// Stores implementing the store.ChangeDetector interface only return the changes since the last update of the index.
+// For all other stores, the search results are compared using a hash.
+// If nothing changed, the existing search results are returned.
+// The added and removed kubeconfig paths are logged.
+func (i *SearchIndex) IncrementalRefresh(ctx context.Context, kubeconfigStore store.KubeconfigStore, existing []store.SearchResult) ([]store.SearchResult, error) {
+	indexState, err := i.getIndexState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index state: %v", err)
+	}
+
+	if changeDetector, ok := store.Unwrap(kubeconfigStore).(store.ChangeDetector); ok && indexState != nil {
+		existingPaths := make([]string, 0, len(existing))
+		for _, result := range existing {
+			existingPaths = append(existingPaths, result.KubeconfigPath)
+		}
+
+		added, removed, err := changeDetector.SearchChangesSince(ctx, indexState.LastUpdateTime, existingPaths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search for changes since %s: %w", indexState.LastUpdateTime, err)
+		}
+		i.logDelta(added, removed)
+		return applyDelta(existing, added, removed), nil
+	}
+
+	current, err := search(ctx, kubeconfigStore)
+	if err != nil {
+		return nil, err
+	}
+
+	if hashSearchResults(current) == hashSearchResults(existing) {
+		i.log.Debugf("search results of the index did not change")
+		return existing, nil
+	}
+
+	added, removed := diff(existing, current)
+	i.logDelta(added, removed)
+	return current, nil
+}
+
+// search returns all search results of the store
+func search(ctx context.Context, kubeconfigStore store.KubeconfigStore) ([]store.SearchResult, error) {
+	channel := make(chan store.SearchResult)
+	go func() {
+		defer close(channel)
+		kubeconfigStore.StartSearch(ctx, channel)
+	}()
+
+	var (
+		results []store.SearchResult
+		err     error
+	)
+	for result := range channel {
+		if result.Error != nil {
+			// keep reading, so that the search of the store does not block
+			err = result.Error
+			continue
+		}
+		results = append(results, result)
+	}
+	return results, err
+}
+
+// hashSearchResults returns a hash of the kubeconfig paths and tags that does not depend on the order of the search results
+func hashSearchResults(results []store.SearchResult) string {
+	entries := make([]string, 0, len(results))
+	for _, result := range results {
+		tags := make([]string, 0, len(result.Tags))
+		for key, value := range result.Tags {
+			tags = append(tags, fmt.Sprintf("%q=%q", key, value))
+		}
+		sort.Strings(tags)
+		entries = append(entries, fmt.Sprintf("%q%v", result.KubeconfigPath, tags))
+	}
+	sort.Strings(entries)
+
+	hash := sha256.New()
+	for _, entry := range entries {
+		hash.Write([]byte(entry))
+		hash.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// diff returns the search results of current not contained in existing and the kubeconfig paths of existing not contained in current
+func diff(existing, current []store.SearchResult) ([]store.SearchResult, []string) {
+	existingPaths := make(map[string]struct{}, len(existing))
+	for _, result := range existing {
+		existingPaths[result.KubeconfigPath] = struct{}{}
+	}
+
+	currentPaths := make(map[string]struct{}, len(current))
+	var added []store.SearchResult
+	for _, result := range current {
+		currentPaths[result.KubeconfigPath] = struct{}{}
+		if _, ok := existingPaths[result.KubeconfigPath]; !ok {
+			added = append(added, result)
+		}
+	}
+
+	var removed []string
+	for _, result := range existing {
+		if _, ok := currentPaths[result.KubeconfigPath]; !ok {
+			removed = append(removed, result.KubeconfigPath)
+		}
+	}
+	return added, removed
+}
+
+// applyDelta removes and adds the given search results to the existing search results
+func applyDelta(existing, added []store.SearchResult, removed []string) []store.SearchResult {
+	removedPaths := make(map[string]struct{}, len(removed)+len(added))
+	for _, path := range removed {
+		removedPaths[path] = struct{}{}
+	}
+	// added search results replace existing search results with the same path
+	for _, result := range added {
+		removedPaths[result.KubeconfigPath] = struct{}{}
+	}
+
+	var results []store.SearchResult
+	for _, result := range existing {
+		if _, ok := removedPaths[result.KubeconfigPath]; !ok {
+			results = append(results, result)
+		}
+	}
+	return append(results, added...)
+}
+
+// logDelta logs the added and removed kubeconfig paths
+func (i *SearchIndex) logDelta(added []store.SearchResult, removed []string) {
+	for _, result := range added {
+		i.log.Infof("index refresh: added kubeconfig path %q", result.KubeconfigPath)
+	}
+	for _, path := range removed {
+		i.log.Infof("index refresh: removed kubeconfig path %q", path)
+	}
+}
+
+// GetSearchResults returns the search results contained in the index
+// together with the context names of each kubeconfig path
+func (i *SearchIndex) GetSearchResults() ([]store.SearchResult, map[string][]string) {
+	contextToPath, contextToTags := i.GetContent()
+
+	// sort the context names to deterministically select the tags of a kubeconfig path
+	contextNames := make([]string, 0, len(contextToPath))
+	for contextName := range contextToPath {
+		contextNames = append(contextNames, contextName)
+	}
+	sort.Strings(contextNames)
+
+	var results []store.SearchResult
+	pathToContexts := make(map[string][]string)
+	for _, contextName := range contextNames {
+		path := contextToPath[contextName]
+		if _, ok := pathToContexts[path]; !ok {
+			results = append(results, store.SearchResult{
+				KubeconfigPath: path,
+				Tags:           contextToTags[contextName],
+			})
+		}
+		pathToContexts[path] = append(pathToContexts[path], contextName)
+	}
+	return results, pathToContexts
+}
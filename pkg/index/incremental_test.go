@@ -0,0 +1,157 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/index"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// changeDetectingStore returns the configured changes instead of searching the kubeconfig paths
+type changeDetectingStore struct {
+	*store.FilesystemStore
+	added    []store.SearchResult
+	removed  []string
+	since    time.Time
+	existing []string
+}
+
+func (s *changeDetectingStore) SearchChangesSince(_ context.Context, since time.Time, existing []string) ([]store.SearchResult, []string, error) {
+	s.since = since
+	s.existing = existing
+	return s.added, s.removed, nil
+}
+
+var _ = Describe("IncrementalRefresh", func() {
+	var (
+		tempDir         string
+		stateDir        string
+		searchIndex     *index.SearchIndex
+		filesystemStore *store.FilesystemStore
+	)
+
+	paths := func(results []store.SearchResult) []string {
+		var paths []string
+		for _, result := range results {
+			paths = append(paths, result.KubeconfigPath)
+		}
+		return paths
+	}
+
+	addKubeconfig := func(name string) string {
+		dir := filepath.Join(tempDir, name)
+		Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+		path := filepath.Join(dir, "config")
+		Expect(os.WriteFile(path, []byte{}, 0600)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-incremental-refresh")
+		Expect(err).ToNot(HaveOccurred())
+		stateDir = filepath.Join(tempDir, "state")
+
+		filesystemStore, err = store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{filepath.Join(tempDir, "kubeconfigs")},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		searchIndex, err = index.New(logrus.NewEntry(logrus.New()), types.StoreKindFilesystem, stateDir, filesystemStore.GetID())
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should return the existing search results if nothing changed", func() {
+		a := addKubeconfig("kubeconfigs/a")
+		existing := []store.SearchResult{{KubeconfigPath: a}}
+		Expect(filesystemStore.VerifyKubeconfigPaths(context.Background())).To(Succeed())
+
+		results, err := searchIndex.IncrementalRefresh(context.Background(), filesystemStore, existing)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(Equal(existing))
+	})
+
+	It("should detect added and removed kubeconfig paths", func() {
+		a := addKubeconfig("kubeconfigs/a")
+		b := addKubeconfig("kubeconfigs/b")
+		existing := []store.SearchResult{{KubeconfigPath: a}, {KubeconfigPath: filepath.Join(tempDir, "kubeconfigs", "removed", "config")}}
+		Expect(filesystemStore.VerifyKubeconfigPaths(context.Background())).To(Succeed())
+
+		results, err := searchIndex.IncrementalRefresh(context.Background(), filesystemStore, existing)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths(results)).To(ConsistOf(a, b))
+	})
+
+	It("should apply the changes of stores with native change detection", func() {
+		lastUpdateTime := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+		Expect(searchIndex.WriteState(types.IndexState{Kind: types.StoreKindFilesystem, LastUpdateTime: lastUpdateTime})).To(Succeed())
+
+		s := &changeDetectingStore{
+			FilesystemStore: filesystemStore,
+			added:           []store.SearchResult{{KubeconfigPath: "c"}},
+			removed:         []string{"b"},
+		}
+		existing := []store.SearchResult{{KubeconfigPath: "a"}, {KubeconfigPath: "b"}}
+
+		results, err := searchIndex.IncrementalRefresh(context.Background(), s, existing)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths(results)).To(Equal([]string{"a", "c"}))
+		Expect(s.since.Equal(lastUpdateTime)).To(BeTrue())
+		Expect(s.existing).To(Equal([]string{"a", "b"}))
+	})
+
+	It("should return the search results contained in the index", func() {
+		Expect(searchIndex.Write(types.Index{
+			Kind: types.StoreKindFilesystem,
+			ContextToPathMapping: map[string]string{
+				"a/one": "a",
+				"a/two": "a",
+				"b/one": "b",
+			},
+			ContextToTags: map[string]map[string]string{
+				"a/one": {"env": "dev"},
+			},
+		})).To(Succeed())
+
+		// reload the index from the file
+		searchIndex, err := index.New(logrus.NewEntry(logrus.New()), types.StoreKindFilesystem, stateDir, filesystemStore.GetID())
+		Expect(err).ToNot(HaveOccurred())
+
+		results, pathToContexts := searchIndex.GetSearchResults()
+		Expect(results).To(Equal([]store.SearchResult{
+			{KubeconfigPath: "a", Tags: map[string]string{"env": "dev"}},
+			{KubeconfigPath: "b"},
+		}))
+		Expect(pathToContexts).To(Equal(map[string][]string{
+			"a": {"a/one", "a/two"},
+			"b": {"b/one"},
+		}))
+	})
+})
@@ -19,6 +19,7 @@ import (
 	"os"
 	"time"
 
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
 	"github.com/danielfoehrkn/kubeswitch/types"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -44,7 +45,7 @@ type SearchIndex struct {
 // New creates a new SearchIndex
 func New(log *logrus.Entry, storeKind types.StoreKind, stateDirectory string, storeID string) (*SearchIndex, error) {
 	if _, err := os.Stat(stateDirectory); os.IsNotExist(err) {
-		if err := os.Mkdir(stateDirectory, 0755); err != nil {
+		if err := kubeswitchio.MkdirAll(stateDirectory, 0755); err != nil {
 			return nil, err
 		}
 	}
@@ -136,45 +137,23 @@ func (i *SearchIndex) ShouldBeUsed(config *types.Config, storeLocalRefreshIndexA
 }
 
 func (i *SearchIndex) WriteState(toWrite types.IndexState) error {
-	// creates or truncate/clean the existing state file (only state is last execution anyways atm.)
-	file, err := os.Create(i.indexStateFilepath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
 	output, err := yaml.Marshal(toWrite)
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(output)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// creates or truncate/clean the existing state file (only state is last execution anyways atm.)
+	return kubeswitchio.WriteFile(i.indexStateFilepath, output, 0644)
 }
 
 func (i *SearchIndex) Write(toWrite types.Index) error {
-	// creates or truncate/clean the existing file
-	file, err := os.Create(i.indexFilepath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
 	output, err := yaml.Marshal(toWrite)
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(output)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// creates or truncate/clean the existing file
+	return kubeswitchio.WriteFile(i.indexFilepath, output, 0644)
 }
 
 func (i *SearchIndex) Delete() error {
@@ -186,17 +165,31 @@ func (i *SearchIndex) Delete() error {
 		return err
 	}
 
-	if err := os.Remove(i.indexFilepath); err != nil {
+	if err := kubeswitchio.Remove(i.indexFilepath); err != nil {
 		return err
 	}
 
-	if err := os.Remove(i.indexStateFilepath); err != nil {
+	if err := kubeswitchio.Remove(i.indexStateFilepath); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// Invalidate deletes the search index of the store, so that contexts written to the store are found by the next search.
+// Failing to do so is not fatal, the contexts are found once the index is refreshed.
+func Invalidate(log *logrus.Entry, storeKind types.StoreKind, stateDirectory string, storeID string) {
+	searchIndex, err := New(log, storeKind, stateDirectory, storeID)
+	if err != nil {
+		log.Warnf("failed to load the index of store %q: %v", storeID, err)
+		return
+	}
+
+	if err := searchIndex.Delete(); err != nil {
+		log.Warnf("failed to delete the index of store %q: %v", storeID, err)
+	}
+}
+
 // getIndexState loads and unmarshalls an index state file
 func (i *SearchIndex) getIndexState() (*types.IndexState, error) {
 	if _, err := os.Stat(i.indexStateFilepath); err != nil {
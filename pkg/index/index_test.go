@@ -0,0 +1,73 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/index"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("Invalidate", func() {
+	var (
+		tempDir         string
+		stateDir        string
+		filesystemStore *store.FilesystemStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-index-invalidate")
+		Expect(err).ToNot(HaveOccurred())
+		stateDir = filepath.Join(tempDir, "state")
+
+		filesystemStore, err = store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{filepath.Join(tempDir, "kubeconfigs")},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should delete the index of the store", func() {
+		searchIndex, err := index.New(filesystemStore.GetLogger(), types.StoreKindFilesystem, stateDir, filesystemStore.GetID())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(searchIndex.Write(types.Index{
+			Kind:                 types.StoreKindFilesystem,
+			ContextToPathMapping: map[string]string{"a": "a"},
+		})).To(Succeed())
+		Expect(searchIndex.WriteState(types.IndexState{Kind: types.StoreKindFilesystem, LastUpdateTime: time.Now().UTC()})).To(Succeed())
+
+		index.Invalidate(filesystemStore.GetLogger(), filesystemStore.GetKind(), stateDir, filesystemStore.GetID())
+
+		searchIndex, err = index.New(filesystemStore.GetLogger(), types.StoreKindFilesystem, stateDir, filesystemStore.GetID())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(searchIndex.HasContent()).To(BeFalse())
+	})
+
+	It("should not fail without an index", func() {
+		index.Invalidate(filesystemStore.GetLogger(), filesystemStore.GetKind(), stateDir, filesystemStore.GetID())
+	})
+})
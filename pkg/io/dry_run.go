@@ -0,0 +1,168 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+)
+
+// DryRunWriter does not write any files, but records a summary of the writes
+type DryRunWriter struct {
+	// ShowDiff adds a unified diff of the current and the new content of each written file to the summary
+	ShowDiff bool
+	// CurrentKubeconfigPath is the kubeconfig new kubeconfig files are compared with if ShowDiff is set
+	CurrentKubeconfigPath string
+
+	lock    sync.Mutex
+	summary []string
+}
+
+// NewDryRunWriter creates a writer that only records the writes
+func NewDryRunWriter(showDiff bool, currentKubeconfigPath string) *DryRunWriter {
+	return &DryRunWriter{
+		ShowDiff:              showDiff,
+		CurrentKubeconfigPath: currentKubeconfigPath,
+	}
+}
+
+func (w *DryRunWriter) WriteFile(path string, data []byte, _ os.FileMode) error {
+	w.record(fmt.Sprintf("Would write %d bytes to %s", len(data), path), path, data)
+	return nil
+}
+
+func (w *DryRunWriter) AppendFile(path string, data []byte, _ os.FileMode) error {
+	w.record(fmt.Sprintf("Would append %d bytes to %s", len(data), path), "", nil)
+	return nil
+}
+
+func (w *DryRunWriter) CreateTemp(directory, pattern string, data []byte) (string, error) {
+	path := filepath.Join(directory, strings.Replace(pattern, "*", "dry-run", 1))
+	w.record(fmt.Sprintf("Would write %d bytes to %s", len(data), path), path, data)
+	return path, nil
+}
+
+func (w *DryRunWriter) MkdirAll(string, os.FileMode) error {
+	return nil
+}
+
+func (w *DryRunWriter) Remove(path string) error {
+	w.record(fmt.Sprintf("Would remove %s", path), "", nil)
+	return nil
+}
+
+func (w *DryRunWriter) RemoveAll(path string) error {
+	w.record(fmt.Sprintf("Would remove %s and all of its contents", path), "", nil)
+	return nil
+}
+
+func (w *DryRunWriter) Rename(oldPath, newPath string) error {
+	w.record(fmt.Sprintf("Would move %s to %s", oldPath, newPath), "", nil)
+	return nil
+}
+
+func (w *DryRunWriter) Chmod(string, os.FileMode) error {
+	return nil
+}
+
+// Summary returns the recorded writes in the order they were made
+func (w *DryRunWriter) Summary() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return append([]string(nil), w.summary...)
+}
+
+// PrintSummary prints the recorded writes
+func (w *DryRunWriter) PrintSummary(out io.Writer) {
+	summary := w.Summary()
+	if len(summary) == 0 {
+		fmt.Fprintln(out, "Would not write any files")
+		return
+	}
+	for _, entry := range summary {
+		fmt.Fprintln(out, entry)
+	}
+}
+
+// record adds the write to the summary.
+// Written kubeconfigs are summarized with their current context and optionally diffed.
+func (w *DryRunWriter) record(message, path string, data []byte) {
+	kubeconfig := struct {
+		Kind           string `yaml:"kind"`
+		CurrentContext string `yaml:"current-context"`
+	}{}
+	isKubeconfig := yaml.Unmarshal(data, &kubeconfig) == nil && kubeconfig.Kind == "Config"
+	if isKubeconfig && len(kubeconfig.CurrentContext) > 0 {
+		message = fmt.Sprintf("%s, setting current-context to %s", message, kubeconfig.CurrentContext)
+	}
+
+	if w.ShowDiff && len(path) > 0 {
+		if diff := w.diff(path, data, isKubeconfig); len(diff) > 0 {
+			message = fmt.Sprintf("%s\n%s", message, strings.TrimSuffix(diff, "\n"))
+		}
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.summary = append(w.summary, message)
+}
+
+// diff returns a unified diff of the current content of the file at the path and the data.
+// New kubeconfig files are compared with the current kubeconfig.
+func (w *DryRunWriter) diff(path string, data []byte, isKubeconfig bool) string {
+	fromPath := path
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) && isKubeconfig && len(w.CurrentKubeconfigPath) > 0 {
+		fromPath = w.CurrentKubeconfigPath
+		current, err = os.ReadFile(fromPath)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Sprintf("failed to read %s: %v", fromPath, err)
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(current),
+		B:        splitLines(data),
+		FromFile: fromPath,
+		ToFile:   path,
+		Context:  3,
+	})
+	if err != nil {
+		return fmt.Sprintf("failed to compute the diff of %s: %v", path, err)
+	}
+	return diff
+}
+
+// splitLines splits the data into lines keeping the line endings. Empty data has no lines.
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if last := len(lines) - 1; len(lines[last]) == 0 {
+		lines = lines[:last]
+	} else {
+		lines[last] += "\n"
+	}
+	return lines
+}
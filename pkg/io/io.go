@@ -0,0 +1,149 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package io routes the file writes of kubeswitch through a configurable Writer.
+// With --dry-run, the writes are replaced by a DryRunWriter that only records what would have been written.
+package io
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Writer writes files to the local filesystem
+type Writer interface {
+	// WriteFile writes the data to the file at the path, truncating an existing file
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	// AppendFile appends the data to the file at the path, creating the file if it does not exist
+	AppendFile(path string, data []byte, perm os.FileMode) error
+	// CreateTemp writes the data to a new file in the directory and returns the path of the file.
+	// The pattern is used like in os.CreateTemp.
+	CreateTemp(directory, pattern string, data []byte) (string, error)
+	// MkdirAll creates the directory and all of its parents
+	MkdirAll(path string, perm os.FileMode) error
+	// Remove removes the file at the path
+	Remove(path string) error
+	// RemoveAll removes the path and all of its children
+	RemoveAll(path string) error
+	// Rename moves the file at the old path to the new path, replacing an existing file
+	Rename(oldPath, newPath string) error
+	// Chmod changes the mode of the file at the path
+	Chmod(path string, mode os.FileMode) error
+}
+
+// writer is used for all file writes
+var writer Writer = FileWriter{}
+
+// SetWriter configures the Writer used for all file writes
+func SetWriter(w Writer) {
+	writer = w
+}
+
+// IsDryRun returns true if file writes are only recorded instead of written
+func IsDryRun() bool {
+	_, ok := writer.(*DryRunWriter)
+	return ok
+}
+
+// WriteFile writes the data to the file at the path with the configured Writer
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	return writer.WriteFile(path, data, perm)
+}
+
+// AppendFile appends the data to the file at the path with the configured Writer
+func AppendFile(path string, data []byte, perm os.FileMode) error {
+	return writer.AppendFile(path, data, perm)
+}
+
+// CreateTemp writes the data to a new file in the directory with the configured Writer
+func CreateTemp(directory, pattern string, data []byte) (string, error) {
+	return writer.CreateTemp(directory, pattern, data)
+}
+
+// MkdirAll creates the directory and all of its parents with the configured Writer
+func MkdirAll(path string, perm os.FileMode) error {
+	return writer.MkdirAll(path, perm)
+}
+
+// Remove removes the file at the path with the configured Writer
+func Remove(path string) error {
+	return writer.Remove(path)
+}
+
+// RemoveAll removes the path and all of its children with the configured Writer
+func RemoveAll(path string) error {
+	return writer.RemoveAll(path)
+}
+
+// Rename moves the file at the old path to the new path with the configured Writer
+func Rename(oldPath, newPath string) error {
+	return writer.Rename(oldPath, newPath)
+}
+
+// Chmod changes the mode of the file at the path with the configured Writer
+func Chmod(path string, mode os.FileMode) error {
+	return writer.Chmod(path, mode)
+}
+
+// FileWriter writes to the local filesystem
+type FileWriter struct{}
+
+func (FileWriter) WriteFile(path string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+
+func (FileWriter) AppendFile(path string, data []byte, perm os.FileMode) error {
+	// a single write, so that concurrent appends are not interleaved
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(data)
+	return err
+}
+
+func (FileWriter) CreateTemp(directory, pattern string, data []byte) (string, error) {
+	file, err := os.CreateTemp(directory, pattern)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return "", err
+	}
+	return filepath.Clean(file.Name()), nil
+}
+
+func (FileWriter) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (FileWriter) Remove(path string) error {
+	return os.Remove(path)
+}
+
+func (FileWriter) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (FileWriter) Rename(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+func (FileWriter) Chmod(path string, mode os.FileMode) error {
+	return os.Chmod(path, mode)
+}
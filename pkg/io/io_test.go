@@ -0,0 +1,139 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package io_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+`
+
+var _ = Describe("Writer", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-io")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		kubeswitchio.SetWriter(kubeswitchio.FileWriter{})
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	Context("FileWriter", func() {
+		It("writes, appends and creates temporary files", func() {
+			path := filepath.Join(tempDir, "nested", "file")
+			Expect(kubeswitchio.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+			Expect(kubeswitchio.WriteFile(path, []byte("a\n"), 0600)).To(Succeed())
+			Expect(kubeswitchio.AppendFile(path, []byte("b\n"), 0600)).To(Succeed())
+			Expect(os.ReadFile(path)).To(Equal([]byte("a\nb\n")))
+
+			tempPath, err := kubeswitchio.CreateTemp(tempDir, "config.*.tmp", []byte(kubeconfig))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filepath.Dir(tempPath)).To(Equal(filepath.Clean(tempDir)))
+			Expect(os.ReadFile(tempPath)).To(Equal([]byte(kubeconfig)))
+			Expect(kubeswitchio.IsDryRun()).To(BeFalse())
+
+			Expect(kubeswitchio.Chmod(tempPath, 0700)).To(Succeed())
+			renamed := filepath.Join(tempDir, "renamed")
+			Expect(kubeswitchio.Rename(tempPath, renamed)).To(Succeed())
+			Expect(tempPath).ToNot(BeAnExistingFile())
+			Expect(os.ReadFile(renamed)).To(Equal([]byte(kubeconfig)))
+
+			Expect(kubeswitchio.Remove(renamed)).To(Succeed())
+			Expect(renamed).ToNot(BeAnExistingFile())
+
+			Expect(kubeswitchio.RemoveAll(filepath.Dir(path))).To(Succeed())
+			Expect(filepath.Dir(path)).ToNot(BeADirectory())
+		})
+	})
+
+	Context("DryRunWriter", func() {
+		It("does not write any files but records a summary", func() {
+			writer := kubeswitchio.NewDryRunWriter(false, "")
+			kubeswitchio.SetWriter(writer)
+			Expect(kubeswitchio.IsDryRun()).To(BeTrue())
+
+			path := filepath.Join(tempDir, "nested", "file")
+			Expect(kubeswitchio.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+			Expect(kubeswitchio.WriteFile(path, []byte("a\n"), 0600)).To(Succeed())
+			Expect(kubeswitchio.AppendFile(path, []byte("b\n"), 0600)).To(Succeed())
+			tempPath, err := kubeswitchio.CreateTemp(tempDir, "config.*.tmp", []byte(kubeconfig))
+			Expect(err).ToNot(HaveOccurred())
+
+			entries, err := os.ReadDir(tempDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+
+			existing := filepath.Join(tempDir, "existing")
+			Expect(os.WriteFile(existing, nil, 0600)).To(Succeed())
+			Expect(kubeswitchio.Chmod(existing, 0700)).To(Succeed())
+			renamed := filepath.Join(tempDir, "renamed")
+			Expect(kubeswitchio.Rename(existing, renamed)).To(Succeed())
+			Expect(kubeswitchio.Remove(existing)).To(Succeed())
+			Expect(kubeswitchio.RemoveAll(tempDir)).To(Succeed())
+			Expect(existing).To(BeAnExistingFile())
+			Expect(renamed).ToNot(BeAnExistingFile())
+
+			Expect(writer.Summary()).To(Equal([]string{
+				"Would write 2 bytes to " + path,
+				"Would append 2 bytes to " + path,
+				"Would write 49 bytes to " + tempPath + ", setting current-context to dev",
+				"Would move " + existing + " to " + renamed,
+				"Would remove " + existing,
+				"Would remove " + tempDir + " and all of its contents",
+			}))
+
+			out := bytes.Buffer{}
+			writer.PrintSummary(&out)
+			Expect(out.String()).To(HaveSuffix("Would remove " + tempDir + " and all of its contents\n"))
+		})
+
+		It("diffs new kubeconfigs with the current kubeconfig", func() {
+			currentKubeconfig := filepath.Join(tempDir, "config")
+			Expect(os.WriteFile(currentKubeconfig, []byte("apiVersion: v1\nkind: Config\ncurrent-context: prod\n"), 0600)).To(Succeed())
+
+			writer := kubeswitchio.NewDryRunWriter(true, currentKubeconfig)
+			kubeswitchio.SetWriter(writer)
+
+			tempPath, err := kubeswitchio.CreateTemp(tempDir, "config.*.tmp", []byte(kubeconfig))
+			Expect(err).ToNot(HaveOccurred())
+
+			summary := writer.Summary()
+			Expect(summary).To(HaveLen(1))
+			Expect(summary[0]).To(ContainSubstring("--- " + currentKubeconfig))
+			Expect(summary[0]).To(ContainSubstring("+++ " + tempPath))
+			Expect(summary[0]).To(ContainSubstring("-current-context: prod\n+current-context: dev"))
+		})
+
+		It("prints that no files would be written", func() {
+			out := bytes.Buffer{}
+			kubeswitchio.NewDryRunWriter(false, "").PrintSummary(&out)
+			Expect(out.String()).To(Equal("Would not write any files\n"))
+		})
+	})
+})
@@ -0,0 +1,100 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keychain
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// Scheme is the URL scheme of tokens in a kubeconfig that reference a macOS Keychain item
+	Scheme = "keychain://"
+
+	// DefaultService is the service of the Keychain items created by kubeswitch
+	DefaultService = "kubeswitch"
+)
+
+// Reference returns the token referencing the Keychain item with the given service and account
+func Reference(service, account string) string {
+	return fmt.Sprintf("%s%s/%s", Scheme, service, account)
+}
+
+// IsReference returns true if the token references a Keychain item
+func IsReference(token string) bool {
+	return strings.HasPrefix(token, Scheme)
+}
+
+// ParseReference returns the service and account of a token referencing a Keychain item
+// in the form keychain://<service>/<account>
+func ParseReference(token string) (string, string, error) {
+	if !IsReference(token) {
+		return "", "", fmt.Errorf("token does not reference a Keychain item")
+	}
+
+	service, account, found := strings.Cut(strings.TrimPrefix(token, Scheme), "/")
+	if !found || len(service) == 0 || len(account) == 0 {
+		return "", "", fmt.Errorf("invalid Keychain reference %q. Expected format is %s<service>/<account>", token, Scheme)
+	}
+	return service, account, nil
+}
+
+// ResolveTokens replaces the tokens of the users in the kubeconfig that reference a Keychain item
+// with the password of the Keychain item.
+// Returns the unmodified kubeconfig if no token references a Keychain item.
+func ResolveTokens(kubeconfig []byte) ([]byte, error) {
+	if !bytes.Contains(kubeconfig, []byte(Scheme)) {
+		return kubeconfig, nil
+	}
+
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	modified := false
+	for name, authInfo := range config.AuthInfos {
+		if !IsReference(authInfo.Token) {
+			continue
+		}
+
+		service, account, err := ParseReference(authInfo.Token)
+		if err != nil {
+			return nil, fmt.Errorf("user %q: %w", name, err)
+		}
+
+		token, err := findPassword(service, account)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the token of user %q from the Keychain: %w", name, err)
+		}
+
+		authInfo.Token = token
+		modified = true
+	}
+
+	if !modified {
+		return kubeconfig, nil
+	}
+	return clientcmd.Write(*config)
+}
+
+// AddToken stores the token in the Keychain item with the given service and account.
+// An existing item is updated.
+func AddToken(service, account, token string) error {
+	return addPassword(service, account, token)
+}
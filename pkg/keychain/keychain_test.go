@@ -0,0 +1,181 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keychain
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+users:
+- name: keychain-user
+  user:
+    token: keychain://kubeswitch/dev/keychain-user
+- name: plain-user
+  user:
+    token: plain
+contexts:
+- name: dev
+  context:
+    cluster: cluster
+    user: keychain-user
+current-context: dev
+`
+
+var _ = Describe("Keychain", func() {
+	var originalFindPassword func(service, account string) (string, error)
+
+	BeforeEach(func() {
+		originalFindPassword = findPassword
+		findPassword = func(service, account string) (string, error) {
+			if service == "kubeswitch" && account == "dev/keychain-user" {
+				return "secret", nil
+			}
+			return "", fmt.Errorf("not found")
+		}
+	})
+
+	AfterEach(func() {
+		findPassword = originalFindPassword
+	})
+
+	It("should parse a Keychain reference", func() {
+		service, account, err := ParseReference(Reference("kubeswitch", "dev/user"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(service).To(Equal("kubeswitch"))
+		Expect(account).To(Equal("dev/user"))
+	})
+
+	It("should reject an invalid Keychain reference", func() {
+		_, _, err := ParseReference("keychain://kubeswitch")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should replace tokens referencing a Keychain item", func() {
+		resolved, err := ResolveTokens([]byte(kubeconfig))
+		Expect(err).ToNot(HaveOccurred())
+
+		config, err := clientcmd.Load(resolved)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.AuthInfos["keychain-user"].Token).To(Equal("secret"))
+		Expect(config.AuthInfos["plain-user"].Token).To(Equal("plain"))
+	})
+
+	It("should return the kubeconfig unmodified without Keychain references", func() {
+		plain := []byte("apiVersion: v1\nkind: Config\n")
+		resolved, err := ResolveTokens(plain)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resolved).To(Equal(plain))
+	})
+
+	It("should fail if the Keychain item does not exist", func() {
+		findPassword = func(service, account string) (string, error) {
+			return "", fmt.Errorf("not found")
+		}
+
+		_, err := ResolveTokens([]byte(kubeconfig))
+		Expect(err).To(MatchError(ContainSubstring("keychain-user")))
+	})
+})
+
+var _ = Describe("security command", func() {
+	var (
+		dir                     string
+		originalSecurityCommand string
+	)
+
+	// fakeSecurity replaces the security command with a script recording its arguments and stdin
+	fakeSecurity := func(script string) {
+		securityCommand = filepath.Join(dir, "security")
+		Expect(os.WriteFile(securityCommand, []byte("#!/bin/sh\n"+
+			"echo \"$@\" > "+filepath.Join(dir, "args")+"\n"+
+			"cat > "+filepath.Join(dir, "stdin")+"\n"+
+			script), 0700)).To(Succeed())
+	}
+
+	readFile := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		Expect(err).ToNot(HaveOccurred())
+		return string(content)
+	}
+
+	BeforeEach(func() {
+		if runtime.GOOS == "windows" {
+			Skip("the fake security command is a shell script")
+		}
+
+		var err error
+		dir, err = os.MkdirTemp("", "keychain")
+		Expect(err).ToNot(HaveOccurred())
+		originalSecurityCommand = securityCommand
+	})
+
+	AfterEach(func() {
+		securityCommand = originalSecurityCommand
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should pass the token on stdin instead of the arguments", func() {
+		fakeSecurity("")
+
+		Expect(securityAddPassword("kubeswitch", "dev/user", "secret-token")).To(Succeed())
+		Expect(readFile("args")).To(Equal("-i\n"))
+		Expect(readFile("stdin")).To(Equal(`"add-generic-password" "-U" "-s" "kubeswitch" "-a" "dev/user" "-w" "secret-token"` + "\n"))
+	})
+
+	It("should fail if the security command reports an error", func() {
+		fakeSecurity("echo 'The user name or passphrase you entered is not correct.' >&2\n")
+
+		err := securityAddPassword("kubeswitch", "dev/user", "secret-token")
+		Expect(err).To(MatchError(ContainSubstring("passphrase you entered is not correct")))
+		Expect(err).ToNot(MatchError(ContainSubstring("secret-token")))
+	})
+
+	It("should fail if the security command fails", func() {
+		fakeSecurity("exit 1\n")
+
+		Expect(securityAddPassword("kubeswitch", "dev/user", "secret-token")).To(MatchError(ContainSubstring("exit status 1")))
+	})
+
+	It("should find the token", func() {
+		fakeSecurity("printf 'secret-token\\n'\n")
+
+		Expect(securityFindPassword("kubeswitch", "dev/user")).To(Equal("secret-token"))
+		Expect(readFile("args")).To(Equal("find-generic-password -s kubeswitch -a dev/user -w\n"))
+	})
+
+	It("should quote the arguments of the interactive mode", func() {
+		command, err := addGenericPasswordCommand("kube switch", `dev"user`, `a\b c`)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(command).To(Equal(`"add-generic-password" "-U" "-s" "kube switch" "-a" "dev\"user" "-w" "a\\b c"` + "\n"))
+	})
+
+	It("should reject tokens containing line breaks", func() {
+		_, err := addGenericPasswordCommand("kubeswitch", "dev/user", "secret\nlist-keychains")
+		Expect(err).To(MatchError(ContainSubstring("must not contain line breaks")))
+	})
+})
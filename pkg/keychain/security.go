@@ -0,0 +1,81 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keychain
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityCommand is the macOS command line interface to the Keychain
+var securityCommand = "security"
+
+// securityFindPassword returns the password of the generic password Keychain item
+func securityFindPassword(service, account string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(securityCommand, "find-generic-password", "-s", service, "-a", account, "-w")
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find Keychain item with service %q and account %q: %v: %s", service, account, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+// securityAddPassword creates or updates the generic password Keychain item.
+// The command is passed to the interactive mode of the security command on stdin,
+// so that the password is not visible in the arguments of the process.
+func securityAddPassword(service, account, password string) error {
+	command, err := addGenericPasswordCommand(service, account, password)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(securityCommand, "-i")
+	cmd.Stdin = strings.NewReader(command)
+	cmd.Stdout = &bytes.Buffer{}
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to add Keychain item with service %q and account %q: %v: %s", service, account, err, strings.TrimSpace(stderr.String()))
+	}
+	// the interactive mode does not fail if a command fails, but prints the error
+	if stderr.Len() > 0 {
+		return fmt.Errorf("failed to add Keychain item with service %q and account %q: %s", service, account, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// addGenericPasswordCommand returns the command line for the interactive mode of the security command
+// creating or updating the generic password Keychain item
+func addGenericPasswordCommand(service, account, password string) (string, error) {
+	args := []string{"add-generic-password", "-U", "-s", service, "-a", account, "-w", password}
+	for i, arg := range args {
+		if strings.ContainsAny(arg, "\n\r\x00") {
+			return "", fmt.Errorf("the service, account and token must not contain line breaks")
+		}
+		args[i] = quoteSecurityArg(arg)
+	}
+	return strings.Join(args, " ") + "\n", nil
+}
+
+// quoteSecurityArg quotes the argument for the interactive mode of the security command
+func quoteSecurityArg(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
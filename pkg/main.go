@@ -15,20 +15,37 @@
 package pkg
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	historyutil "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/history/util"
 	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/golang-lru/simplelru"
 	"github.com/ktr0731/go-fuzzyfinder"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/audit"
+	"github.com/danielfoehrkn/kubeswitch/pkg/enrich"
 	"github.com/danielfoehrkn/kubeswitch/pkg/index"
+	"github.com/danielfoehrkn/kubeswitch/pkg/prefetch"
+	"github.com/danielfoehrkn/kubeswitch/pkg/rbac"
+	sortutil "github.com/danielfoehrkn/kubeswitch/pkg/sort"
 	"github.com/danielfoehrkn/kubeswitch/pkg/store"
 	aliasutil "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/alias/util"
+	"github.com/danielfoehrkn/kubeswitch/pkg/ui"
 	"github.com/danielfoehrkn/kubeswitch/pkg/util"
 	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
 	"github.com/danielfoehrkn/kubeswitch/types"
@@ -48,6 +65,9 @@ var (
 
 	pathToKubeconfig     = make(map[string]string)
 	pathToKubeconfigLock = sync.RWMutex{}
+	// kubeconfigs of stores whose kubeconfigs are only kept for the last read kubeconfig paths, by store ID.
+	// Guarded by pathToKubeconfigLock.
+	limitedKubeconfigCaches = make(map[string]*simplelru.LRU)
 
 	pathToStoreID   = make(map[string]string)
 	pathToStoreLock = sync.RWMutex{}
@@ -55,57 +75,220 @@ var (
 	aliasToContext     = make(map[string]string)
 	aliasToContextLock = sync.RWMutex{}
 
+	// IDs of stores that exceeded their search timeout and only returned partial results
+	partialStoreIDs     = make(map[string]struct{})
+	partialStoreIDsLock = sync.RWMutex{}
+
+	// metadata tags added by the enrichers per context name
+	contextToEnrichedTags     = make(map[string]map[string]string)
+	contextToEnrichedTagsLock = sync.RWMutex{}
+
+	// user-defined annotations per context name
+	contextToAnnotations     = make(map[string]map[string]string)
+	contextToAnnotationsLock = sync.RWMutex{}
+
+	// package (logical cluster group) per context name
+	contextToPackage     = make(map[string]string)
+	contextToPackageLock = sync.RWMutex{}
+
+	// stores grouping their search results by tag per store ID
+	groupedStores     = make(map[string]types.KubeconfigStore)
+	groupedStoresLock = sync.RWMutex{}
+
+	// context names of grouped stores that have not been moved into their group yet.
+	// Guarded by fuzzySearchReloadLock.
+	ungroupedContextNames []string
+	// requests to move the ungrouped context names into their groups once the initial search results have been sorted and grouped.
+	// nil until then
+	regroupRequests     chan struct{}
+	regroupRequestsLock = sync.Mutex{}
+
+	// permission tables of the RBAC preview per context name
+	// empty if the permissions could not be checked
+	contextToRBACPreview     = make(map[string]string)
+	contextToRBACPreviewLock = sync.RWMutex{}
+
 	hotReloadLock sync.RWMutex
+	// serializes reloads of the fuzzy search with each other and with added search results,
+	// as a reload temporarily hides the last context name
+	fuzzySearchReloadLock sync.Mutex
+	// receives a value whenever the shown fuzzy search checked the context names for changes
+	fuzzySearchPolled = make(chan struct{})
+	// closed once the shown fuzzy search returned, nil if no fuzzy search is shown
+	fuzzySearchDone     chan struct{}
+	fuzzySearchDoneLock sync.Mutex
 
 	// aggregated errors that were suppressed during the search
 	// are logged on exit
-	searchError error
+	searchError     error
+	searchErrorLock = sync.Mutex{}
+
+	// search results of paginated stores that are read while scrolling through the selection dialog
+	paginatedLists []*ui.VirtualList[DiscoveredContext]
+	// index of the search result previously shown in the preview, -1 if no search result matched the query
+	previewIndex     = -1
+	previewIndexLock sync.Mutex
 
 	logger = logrus.New()
 )
 
-func Switcher(stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex, showPreview bool) (*string, *string, error) {
-	c, err := DoSearch(stores, config, stateDir, noIndex)
+// connectivityCheckTimeout is the timeout for the preflight connectivity check of the selected API server
+const connectivityCheckTimeout = 2 * time.Second
+
+// rbacPreviewTimeout is the timeout for checking the permissions of the user for the RBAC preview
+const rbacPreviewTimeout = 2 * time.Second
+
+// maxConcurrentEnrichments is the maximum number of search results that are enriched concurrently
+const maxConcurrentEnrichments = 10
+
+// defaultSortDeadline is the default maximum duration to wait for all stores before sorting the search results
+const defaultSortDeadline = 5 * time.Second
+
+// Switcher shows the selection dialog for the contexts of all stores and returns the path of the kubeconfig of the selected context.
+// If a filter is given, only the matching contexts are shown. If exactly one context matches, it is selected without showing the selection dialog.
+// If a package name is given, only the contexts of the package are shown.
+func Switcher(stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex, showPreview bool, filter *util.ContextFilter, packageName string) (*string, *string, error) {
+	// paginated stores are searched separately, so that their search results can be read page by page
+	var paginatedStores []store.KubeconfigStore
+	searchedStores := slices.DeleteFunc(slices.Clone(stores), func(s store.KubeconfigStore) bool {
+		if s.GetStoreConfig().Paginated {
+			paginatedStores = append(paginatedStores, s)
+			return true
+		}
+		return false
+	})
+
+	for _, s := range stores {
+		if len(s.GetStoreConfig().GroupByTag) > 0 {
+			writeToGroupedStores(s.GetID(), s.GetStoreConfig())
+		}
+	}
+
+	// the search results of stores whose errors are ignored are marked as partial
+	c, err := DoSearch(searchedStores, config, stateDir, noIndex, WithIgnoredStoreErrors())
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// set once a context of the package has been found
+	var packageFound atomic.Bool
+	addSearchResult := func(discoveredContext DiscoveredContext) {
+		if len(packageName) > 0 && discoveredContext.Error == nil {
+			if discoveredContext.PackageName != packageName {
+				return
+			}
+			packageFound.Store(true)
+		}
+		addDiscoveredContext(discoveredContext)
+	}
+
+	for _, paginatedStore := range paginatedStores {
+		windowSize := ui.DefaultWindowSize
+		if paginatedStore.GetStoreConfig().WindowSize != nil {
+			windowSize = *paginatedStore.GetStoreConfig().WindowSize
+		}
+		// only the kubeconfigs of the last read search results are kept in memory
+		limitKubeconfigCache(paginatedStore.GetID(), windowSize)
+
+		// the search of a paginated store pauses until the next page is read when scrolling,
+		// which must not count towards the search timeout
+		pc, err := DoSearch([]store.KubeconfigStore{paginatedStore}, config, stateDir, noIndex, WithIgnoredStoreErrors(), WithPausedSearchTimeout())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		paginatedLists = append(paginatedLists, ui.NewVirtualList(*pc, windowSize, func(page []DiscoveredContext) {
+			// with a filter, only the matching search results of each page are kept
+			if filter != nil {
+				page = filterDiscoveredContexts(page, filter)
+			}
+			for _, discoveredContext := range page {
+				addSearchResult(discoveredContext)
+			}
+			if len(paginatedStore.GetStoreConfig().GroupByTag) > 0 {
+				regroupLateSearchResults()
+			}
+		}))
+	}
+
+	// closed once all stores finished the search
+	searchDone := make(chan struct{})
+
 	// here we asynchronously read from the result channel until the wait group is done (call wg.Done for all stores)
 	go func(channel chan DiscoveredContext) {
+		defer close(searchDone)
 		// read from result channel until
 		for discoveredContext := range channel {
-			if discoveredContext.Error != nil {
-				// aggregate the errors during the search to show after the selection screen
-				logger.Debugf("%v", discoveredContext.Error)
-				searchError = multierror.Append(searchError, discoveredContext.Error)
-				continue
+			addSearchResult(discoveredContext)
+
+			// the search results that are already available are added at once, so that they are grouped together
+			grouped := isOfGroupedStore(discoveredContext)
+		batch:
+			for {
+				select {
+				case discoveredContext, ok := <-channel:
+					if !ok {
+						break batch
+					}
+					addSearchResult(discoveredContext)
+					grouped = grouped || isOfGroupedStore(discoveredContext)
+				default:
+					break batch
+				}
 			}
 
-			if discoveredContext.Store == nil {
-				// this should not happen
-				logger.Debugf("store returned from search is nil. This should not happen")
-				continue
+			if grouped {
+				regroupLateSearchResults()
 			}
-			kubeconfigStore := *discoveredContext.Store
+		}
+	}(*c)
 
-			contextName := discoveredContext.Name
-			if len(discoveredContext.Alias) > 0 {
-				contextName = discoveredContext.Alias
-				writeToAliasToContext(discoveredContext.Alias, discoveredContext.Name)
-			}
+	for _, list := range paginatedLists {
+		if filter != nil {
+			// all search results are required to find the matching contexts.
+			// The pages are still read one after another, only keeping the matching search results.
+			list.LoadAll()
+		} else if showPreview {
+			// read the first page, further pages are read when scrolling
+			list.Scroll(0)
+		} else {
+			// the cursor position is only known to the preview
+			go list.LoadAll()
+		}
+	}
 
-			// write to global map that is polled by the fuzzy search
-			appendToAllKubeconfigContextNames(contextName)
-			// add to global contextToPath map
-			// required to map back from selected context -> path
-			writeToContextToPathMapping(contextName, discoveredContext.Path)
-			// required to map back from kubeconfig path -> tags
-			writeToPathToTagsMapping(discoveredContext.Path, discoveredContext.Tags)
-			// associate (path -> store)
-			// required to map back from selected context -> path -> store -> store.getKubeconfig(path)
-			writeToPathToStoreID(discoveredContext.Path, kubeconfigStore.GetID())
+	// packages not configured for any store might still be assigned by the stores, which is only known after the search.
+	// The search results of paginated stores are only read when scrolling, hence their packages are not checked.
+	if len(packageName) > 0 && !isConfiguredPackage(stores, packageName) {
+		<-searchDone
+		if !packageFound.Load() && len(paginatedLists) == 0 {
+			return nil, nil, fmt.Errorf("unknown package %q: no context belongs to the package", packageName)
 		}
-	}(*c)
+	}
+
+	if sortOrder := getSortOrder(stores, config); sortOrder != types.SortOrderNone || hasGroupedStores() {
+		sortDeadline := defaultSortDeadline
+		if config.SortDeadline != nil {
+			sortDeadline = *config.SortDeadline
+		}
+		if sortOrder == types.SortOrderPriority && config.PriorityDeadline != nil {
+			sortDeadline = *config.PriorityDeadline
+		}
+
+		priorityScores := make(map[string]int, len(stores))
+		for _, s := range stores {
+			priorityScores[s.GetID()] = s.GetStoreConfig().PriorityScore
+		}
+		go sortAfterSearch(searchDone, sortOrder, sortDeadline, priorityScores)
+	}
+
+	if len(config.Enrichers) > 0 {
+		enricher, err := enrich.NewEnricher(config.Enrichers)
+		if err != nil {
+			return nil, nil, err
+		}
+		go enrichAfterSearch(searchDone, enricher)
+	}
 
 	// remember the store for later kubeconfig retrieval
 	var kindToStore = map[string]store.KubeconfigStore{}
@@ -115,9 +298,35 @@ func Switcher(stores []store.KubeconfigStore, config *types.Config, stateDir str
 
 	defer logSearchErrors()
 
-	kubeconfigPath, selectedContext, err := showFuzzySearch(kindToStore, showPreview)
-	if err != nil {
-		return nil, nil, err
+	var kubeconfigPath, selectedContext string
+	if filter != nil {
+		<-searchDone
+		matches := filterContextNames(filter)
+		switch len(matches) {
+		case 0:
+			return nil, nil, fmt.Errorf("no context matches pattern %q", filter.Pattern)
+		case 1:
+			selectedContext = matches[0]
+			kubeconfigPath = readFromContextToPathMapping(selectedContext)
+		}
+	}
+
+	if len(selectedContext) == 0 {
+		picker := pickerConfig{
+			storeIDToStore: kindToStore,
+			showPreview:    showPreview,
+		}
+		if showPreview {
+			// speculatively fetch the store-specific previews around the cursor
+			prefetcher := prefetch.New(getPreviewEntry(kindToStore), prefetch.DefaultCacheSize, prefetch.DefaultWorkers, prefetch.DefaultTTL)
+			defer prefetcher.Stop()
+			picker.previewCacheHook = prefetcher.GetSearchPreview
+		}
+
+		kubeconfigPath, selectedContext, err = showFuzzySearch(picker)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	if len(kubeconfigPath) == 0 {
@@ -134,7 +343,7 @@ func Switcher(stores []store.KubeconfigStore, config *types.Config, stateDir str
 	tags := readFromPathToTagsMapping(kubeconfigPath)
 
 	// use the store to get the kubeconfig for the selected kubeconfig path
-	kubeconfigData, err := store.GetKubeconfigForPath(kubeconfigPath, tags)
+	kubeconfigData, err := store.GetKubeconfigForPath(context.Background(), kubeconfigPath, tags)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -161,6 +370,20 @@ func Switcher(stores []store.KubeconfigStore, config *types.Config, stateDir str
 		return nil, nil, err
 	}
 
+	if err := kubeconfig.UseCredentialPlugin(contextForHistory); err != nil {
+		return nil, nil, err
+	}
+
+	if config.PreflightConnectivityCheck != nil && *config.PreflightConnectivityCheck {
+		switchAnyway, err := checkConnectivity(kubeconfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !switchAnyway {
+			return nil, nil, nil
+		}
+	}
+
 	// write a temporary kubeconfig file and return the path
 	tempKubeconfigPath, err := kubeconfig.WriteKubeconfigFile()
 	if err != nil {
@@ -175,9 +398,488 @@ func Switcher(stores []store.KubeconfigStore, config *types.Config, stateDir str
 		logger.Warnf("failed to append context to history file: %v", err)
 	}
 
+	if err := audit.Record(contextForHistory, store.GetID(), store.GetKind()); err != nil {
+		logger.Warnf("failed to append context to audit log: %v", err)
+	}
+
 	return &tempKubeconfigPath, &selectedContext, nil
 }
 
+// isConfiguredPackage returns true if a package with the given name is configured for one of the stores
+func isConfiguredPackage(stores []store.KubeconfigStore, packageName string) bool {
+	for _, s := range stores {
+		for _, p := range s.GetStoreConfig().Packages {
+			if p.Name == packageName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetContextCount returns the number of contexts found by the search of the selection dialog
+func GetContextCount() int {
+	contextToPathMappingLock.RLock()
+	defer contextToPathMappingLock.RUnlock()
+	return len(contextToPathMapping)
+}
+
+// addDiscoveredContext adds a search result to the selection dialog
+func addDiscoveredContext(discoveredContext DiscoveredContext) {
+	if discoveredContext.Error != nil {
+		// the search results of a store that failed or exceeded its search timeout are incomplete
+		if discoveredContext.Store != nil {
+			writeToPartialStoreIDs((*discoveredContext.Store).GetID())
+			// mark the already shown search results of the store
+			reloadFuzzySearch(func(contextNames []string) []string { return contextNames })
+		}
+
+		var ignored *IgnoredStoreError
+		if errors.As(discoveredContext.Error, &ignored) {
+			logger.Debugf("%v", discoveredContext.Error)
+			return
+		}
+		// aggregate the errors during the search to show after the selection screen
+		logger.Debugf("%v", discoveredContext.Error)
+		appendToSearchError(discoveredContext.Error)
+		return
+	}
+
+	if discoveredContext.Store == nil {
+		// this should not happen
+		logger.Debugf("store returned from search is nil. This should not happen")
+		return
+	}
+	kubeconfigStore := *discoveredContext.Store
+
+	contextName := discoveredContext.Name
+	if len(discoveredContext.Alias) > 0 {
+		contextName = discoveredContext.Alias
+		writeToAliasToContext(discoveredContext.Alias, discoveredContext.Name)
+	}
+
+	// write to global map that is polled by the fuzzy search
+	// wait for ongoing reloads, so that the context name is not appended before the hidden context name
+	fuzzySearchReloadLock.Lock()
+	appendToAllKubeconfigContextNames(contextName)
+	fuzzySearchReloadLock.Unlock()
+	// add to global contextToPath map
+	// required to map back from selected context -> path
+	writeToContextToPathMapping(contextName, discoveredContext.Path)
+	// the user-defined annotations belong to the context, the remaining tags to the kubeconfig path
+	storeTags, userAnnotations := annotations.SplitTags(discoveredContext.Tags, discoveredContext.Annotations)
+	if len(userAnnotations) > 0 {
+		writeToContextToAnnotations(contextName, userAnnotations)
+	}
+	if len(discoveredContext.PackageName) > 0 {
+		writeToContextToPackage(contextName, discoveredContext.PackageName)
+	}
+	// required to map back from kubeconfig path -> tags
+	writeToPathToTagsMapping(discoveredContext.Path, storeTags)
+	// associate (path -> store)
+	// required to map back from selected context -> path -> store -> store.getKubeconfig(path)
+	writeToPathToStoreID(discoveredContext.Path, kubeconfigStore.GetID())
+
+	// remembered after the mappings are written, as they are required to find the group
+	if readFromGroupedStores(kubeconfigStore.GetID()) != nil {
+		fuzzySearchReloadLock.Lock()
+		ungroupedContextNames = append(ungroupedContextNames, contextName)
+		fuzzySearchReloadLock.Unlock()
+	}
+}
+
+// filterDiscoveredContexts returns the discovered contexts whose name shown in the selection dialog matches the filter.
+// Errors are kept.
+func filterDiscoveredContexts(discoveredContexts []DiscoveredContext, filter *util.ContextFilter) []DiscoveredContext {
+	var contextNames []string
+	for _, discoveredContext := range discoveredContexts {
+		if discoveredContext.Error == nil {
+			contextNames = append(contextNames, getDisplayName(discoveredContext))
+		}
+	}
+	matches := sets.New(filter.Filter(contextNames)...)
+
+	return slices.DeleteFunc(discoveredContexts, func(discoveredContext DiscoveredContext) bool {
+		return discoveredContext.Error == nil && !matches.Has(getDisplayName(discoveredContext))
+	})
+}
+
+// getDisplayName returns the name of the discovered context shown in the selection dialog
+func getDisplayName(discoveredContext DiscoveredContext) string {
+	if len(discoveredContext.Alias) > 0 {
+		return discoveredContext.Alias
+	}
+	return discoveredContext.Name
+}
+
+// filterContextNames reduces the context names shown in the selection dialog to the ones matching the filter
+// and returns the matching context names
+func filterContextNames(filter *util.ContextFilter) []string {
+	// wait for ongoing reloads to include the hidden context name
+	fuzzySearchReloadLock.Lock()
+	defer fuzzySearchReloadLock.Unlock()
+	hotReloadLock.Lock()
+	defer hotReloadLock.Unlock()
+	allKubeconfigContextNamesLock.Lock()
+	defer allKubeconfigContextNamesLock.Unlock()
+
+	matches := filter.Filter(slices.Clone(allKubeconfigContextNames))
+	allKubeconfigContextNames = slices.Clone(matches)
+	return matches
+}
+
+// getSortOrder returns the configured sort order.
+// Defaults to sorting by priority if any store has a priority score configured.
+func getSortOrder(stores []store.KubeconfigStore, config *types.Config) types.SortOrder {
+	if config.SortOrder != nil {
+		return *config.SortOrder
+	}
+
+	for _, s := range stores {
+		if s.GetStoreConfig().PriorityScore != 0 {
+			return types.SortOrderPriority
+		}
+	}
+	return types.SortOrderNone
+}
+
+// sortAfterSearch sorts and groups the search results once all stores finished the search or the deadline is exceeded.
+// Results discovered after the deadline are appended in arrival order and moved into their group once they are added.
+func sortAfterSearch(searchDone chan struct{}, order types.SortOrder, deadline time.Duration, priorityScores map[string]int) {
+	select {
+	case <-searchDone:
+	case <-time.After(deadline):
+		logger.Debugf("not all stores finished the search within %s. Sorting partial results.", deadline.String())
+	}
+
+	reloadFuzzySearch(func(contextNames []string) []string {
+		results := make([]sortutil.SearchResult, len(contextNames))
+		for i, contextName := range contextNames {
+			storeID := readFromPathToStoreID(readFromContextToPathMapping(contextName))
+			results[i] = sortutil.SearchResult{
+				ContextName:   contextName,
+				StoreID:       storeID,
+				PriorityScore: priorityScores[storeID],
+			}
+		}
+
+		for i, result := range sortutil.SortResults(results, order) {
+			contextNames[i] = result.ContextName
+		}
+		// all context names are grouped
+		ungroupedContextNames = nil
+		return groupContextNames(contextNames)
+	})
+
+	if hasGroupedStores() {
+		startRegrouping()
+	}
+}
+
+// groupContextNames groups the context names of each store with a tag to group by.
+// The groups of a store are shown at the position of the first context name of the store.
+// Instead of selectable group headers, the group is shown in the label of each context name.
+func groupContextNames(contextNames []string) []string {
+	if !hasGroupedStores() {
+		return contextNames
+	}
+
+	// the tags belong to the kubeconfig path, hence the paths are grouped and replaced by their context names afterwards
+	storeIDToResults := make(map[string][]store.SearchResult)
+	pathToContextNames := make(map[string][]string)
+	for _, contextName := range contextNames {
+		path := readFromContextToPathMapping(contextName)
+		storeID := readFromPathToStoreID(path)
+		if readFromGroupedStores(storeID) == nil {
+			continue
+		}
+		if _, ok := pathToContextNames[path]; !ok {
+			storeIDToResults[storeID] = append(storeIDToResults[storeID], store.SearchResult{
+				KubeconfigPath: path,
+				Tags:           readFromPathToTagsMapping(path),
+			})
+		}
+		pathToContextNames[path] = append(pathToContextNames[path], contextName)
+	}
+
+	grouped := make([]string, 0, len(contextNames))
+	for _, contextName := range contextNames {
+		storeID := readFromPathToStoreID(readFromContextToPathMapping(contextName))
+		kubeconfigStore := readFromGroupedStores(storeID)
+		if kubeconfigStore == nil {
+			grouped = append(grouped, contextName)
+			continue
+		}
+
+		results, ok := storeIDToResults[storeID]
+		if !ok {
+			// the groups of the store have already been added
+			continue
+		}
+		delete(storeIDToResults, storeID)
+
+		for _, group := range sortutil.GroupResults(results, kubeconfigStore.GroupByTag, kubeconfigStore.GroupSortOrder) {
+			for _, result := range group.Results {
+				grouped = append(grouped, pathToContextNames[result.KubeconfigPath]...)
+			}
+		}
+	}
+	return grouped
+}
+
+// groupKey identifies a group of context names of a grouped store
+type groupKey struct {
+	storeID string
+	value   string
+}
+
+// groupLateContextNames moves the context names added after the search results have been grouped into their groups.
+// Only the late context names are grouped, the groups already shown keep their order and position.
+// New groups are inserted according to the group sort order of the store without reordering the shown groups.
+// Must be called while holding the fuzzySearchReloadLock.
+func groupLateContextNames(contextNames []string) []string {
+	if len(ungroupedContextNames) == 0 {
+		return contextNames
+	}
+	late := sets.New(ungroupedContextNames...)
+	ungroupedContextNames = nil
+
+	var (
+		shown = make([]string, 0, len(contextNames))
+		// the shown groups of each store in the order they are shown
+		storeIDToGroups = make(map[string][]sortutil.Group)
+		firstIndex      = make(map[groupKey]int)
+		lastIndex       = make(map[groupKey]int)
+		// the late search results of each store in the order they were added
+		storeIDToLateResults = make(map[string][]store.SearchResult)
+		pathToLateNames      = make(map[string][]string)
+		lateStoreIDs         []string
+	)
+
+	for _, contextName := range contextNames {
+		path := readFromContextToPathMapping(contextName)
+		storeID := readFromPathToStoreID(path)
+		kubeconfigStore := readFromGroupedStores(storeID)
+		if kubeconfigStore == nil {
+			shown = append(shown, contextName)
+			continue
+		}
+
+		if late.Has(contextName) {
+			if _, ok := pathToLateNames[path]; !ok {
+				if _, ok := storeIDToLateResults[storeID]; !ok {
+					lateStoreIDs = append(lateStoreIDs, storeID)
+				}
+				storeIDToLateResults[storeID] = append(storeIDToLateResults[storeID], store.SearchResult{
+					KubeconfigPath: path,
+					Tags:           readFromPathToTagsMapping(path),
+				})
+			}
+			pathToLateNames[path] = append(pathToLateNames[path], contextName)
+			continue
+		}
+
+		key := groupKey{storeID: storeID, value: readFromPathToTagsMapping(path)[kubeconfigStore.GroupByTag]}
+		if _, ok := firstIndex[key]; !ok {
+			firstIndex[key] = len(shown)
+			storeIDToGroups[storeID] = append(storeIDToGroups[storeID], sortutil.Group{Value: key.value})
+		}
+		lastIndex[key] = len(shown)
+		groups := storeIDToGroups[storeID]
+		groups[len(groups)-1].Results = append(groups[len(groups)-1].Results, store.SearchResult{KubeconfigPath: path})
+		shown = append(shown, contextName)
+	}
+
+	// the late context names to insert before the shown context name with the same index
+	insertions := make(map[int][]string)
+	for _, storeID := range lateStoreIDs {
+		kubeconfigStore := readFromGroupedStores(storeID)
+		groups := storeIDToGroups[storeID]
+
+		for _, group := range sortutil.GroupResults(storeIDToLateResults[storeID], kubeconfigStore.GroupByTag, kubeconfigStore.GroupSortOrder) {
+			var names []string
+			for _, result := range group.Results {
+				names = append(names, pathToLateNames[result.KubeconfigPath]...)
+			}
+
+			var index int
+			if last, ok := lastIndex[groupKey{storeID: storeID, value: group.Value}]; ok {
+				// append to the shown group
+				index = last + 1
+			} else if len(groups) == 0 {
+				// the store has no shown context names
+				index = len(shown)
+			} else if position := sortutil.GroupPosition(groups, group, kubeconfigStore.GroupSortOrder); position < len(groups) {
+				index = firstIndex[groupKey{storeID: storeID, value: groups[position].Value}]
+			} else {
+				index = lastIndex[groupKey{storeID: storeID, value: groups[len(groups)-1].Value}] + 1
+			}
+			insertions[index] = append(insertions[index], names...)
+		}
+	}
+
+	grouped := make([]string, 0, len(contextNames))
+	for i, contextName := range shown {
+		grouped = append(grouped, insertions[i]...)
+		grouped = append(grouped, contextName)
+	}
+	return append(grouped, insertions[len(shown)]...)
+}
+
+// getGroupOfContext returns the group of the context name shown in the selection dialog
+// or an empty string if the store of the context does not group its search results
+func getGroupOfContext(contextName string) string {
+	path := readFromContextToPathMapping(contextName)
+	kubeconfigStore := readFromGroupedStores(readFromPathToStoreID(path))
+	if kubeconfigStore == nil {
+		return ""
+	}
+
+	value, ok := readFromPathToTagsMapping(path)[kubeconfigStore.GroupByTag]
+	if !ok || len(value) == 0 {
+		return fmt.Sprintf("%s %s", kubeconfigStore.GroupByTag, sortutil.UntaggedGroupName)
+	}
+	return fmt.Sprintf("%s=%s", kubeconfigStore.GroupByTag, value)
+}
+
+// enrichAfterSearch enriches all search results once all stores finished the search
+// and shows the metadata added by the enrichers in the selection dialog
+func enrichAfterSearch(searchDone chan struct{}, enricher enrich.Enricher) {
+	<-searchDone
+
+	// wait for ongoing reloads to include the hidden context name
+	fuzzySearchReloadLock.Lock()
+	allKubeconfigContextNamesLock.RLock()
+	contextNames := slices.Clone(allKubeconfigContextNames)
+	allKubeconfigContextNamesLock.RUnlock()
+	fuzzySearchReloadLock.Unlock()
+
+	results := make([]*enrich.SearchResult, len(contextNames))
+	for i, contextName := range contextNames {
+		path := readFromContextToPathMapping(contextName)
+		results[i] = &enrich.SearchResult{
+			ContextName: contextName,
+			StoreID:     readFromPathToStoreID(path),
+			Tags:        maps.Clone(readFromPathToTagsMapping(path)),
+		}
+	}
+
+	if err := enrich.EnrichAll(context.Background(), enricher, results, maxConcurrentEnrichments); err != nil {
+		logger.Debugf("%v", err)
+		appendToSearchError(err)
+	}
+
+	for _, result := range results {
+		// only remember the tags added or changed by the enrichers
+		storeTags := readFromPathToTagsMapping(readFromContextToPathMapping(result.ContextName))
+		enrichedTags := make(map[string]string)
+		for key, value := range result.Tags {
+			if storeValue, ok := storeTags[key]; !ok || storeValue != value {
+				enrichedTags[key] = value
+			}
+		}
+		if len(enrichedTags) > 0 {
+			writeToContextToEnrichedTags(result.ContextName, enrichedTags)
+		}
+	}
+
+	// redraw the selection dialog to show the enriched tags
+	reloadFuzzySearch(func(contextNames []string) []string { return contextNames })
+}
+
+// reloadFuzzySearch replaces the context names with the modified context names and makes the fuzzy search display them.
+// The fuzzy search only rebuilds its items when the number of context names changes.
+// Hence, a shown fuzzy search first picks up the context names without the last one before all context names are shown.
+func reloadFuzzySearch(modify func(contextNames []string) []string) {
+	fuzzySearchReloadLock.Lock()
+	defer fuzzySearchReloadLock.Unlock()
+
+	// prevent the fuzzy search from reading while the context names are modified
+	hotReloadLock.Lock()
+	allKubeconfigContextNamesLock.Lock()
+	contextNames := modify(slices.Clone(allKubeconfigContextNames))
+	done := getFuzzySearchDone()
+	hide := done != nil && len(contextNames) > 0
+	if hide {
+		allKubeconfigContextNames = contextNames[:len(contextNames)-1]
+	} else {
+		allKubeconfigContextNames = contextNames
+	}
+	allKubeconfigContextNamesLock.Unlock()
+	hotReloadLock.Unlock()
+
+	if !hide {
+		return
+	}
+
+	select {
+	case <-fuzzySearchPolled:
+	case <-done:
+	}
+
+	hotReloadLock.Lock()
+	defer hotReloadLock.Unlock()
+	allKubeconfigContextNamesLock.Lock()
+	defer allKubeconfigContextNamesLock.Unlock()
+	allKubeconfigContextNames = contextNames
+}
+
+// fuzzySearchLocker is the hot reload lock of the fuzzy search.
+// The fuzzy search holds the lock while checking if the number of context names changed.
+type fuzzySearchLocker struct{}
+
+func (fuzzySearchLocker) Lock() {
+	hotReloadLock.RLock()
+}
+
+// Unlock notifies a waiting reload that the fuzzy search checked the context names.
+// The notification is sent while holding the lock, so that it cannot stem from a check before the reload.
+func (fuzzySearchLocker) Unlock() {
+	select {
+	case fuzzySearchPolled <- struct{}{}:
+	default:
+	}
+	hotReloadLock.RUnlock()
+}
+
+func getFuzzySearchDone() chan struct{} {
+	fuzzySearchDoneLock.Lock()
+	defer fuzzySearchDoneLock.Unlock()
+	return fuzzySearchDone
+}
+
+func setFuzzySearchDone(done chan struct{}) {
+	fuzzySearchDoneLock.Lock()
+	defer fuzzySearchDoneLock.Unlock()
+	fuzzySearchDone = done
+}
+
+// checkConnectivity checks if the API server of the selected context is reachable.
+// If it is not, the user is asked for confirmation to switch anyway.
+func checkConnectivity(kubeconfig *kubeconfigutil.Kubeconfig) (bool, error) {
+	kubeconfigBytes, err := kubeconfig.GetBytes()
+	if err != nil {
+		return false, err
+	}
+
+	err = util.CheckAPIServerConnectivity(kubeconfigBytes, connectivityCheckTimeout)
+	if err == nil {
+		logger.Debugf("connectivity check succeeded for context %q", kubeconfig.GetCurrentContext())
+		return true, nil
+	}
+	logger.Debugf("connectivity check failed for context %q: %v", kubeconfig.GetCurrentContext(), err)
+
+	// STDOUT is read by the calling shell script
+	fmt.Fprint(os.Stderr, "Warning: API server may be unreachable. Switch anyway? [y/N] ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, nil
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
 // writeIndex tries to write the Index file for the kubeconfig store
 // if it fails to do so, it logs a warning, but does not panic
 func writeIndex(store store.KubeconfigStore, searchIndex *index.SearchIndex, ctxToPathMapping map[string]string, ctxToTagsMapping map[string]map[string]string) {
@@ -202,14 +904,47 @@ func writeIndex(store store.KubeconfigStore, searchIndex *index.SearchIndex, ctx
 	}
 }
 
-func showFuzzySearch(storeIDToStore map[string]store.KubeconfigStore, showPreview bool) (string, string, error) {
-	// display selection dialog for all kubeconfig context names
+// PreviewCacheHook returns the store-specific preview of the given entry shown at the given index of the picker,
+// e.g. from a cache
+type PreviewCacheHook func(index int, entry prefetch.Entry) (string, error)
+
+// pickerConfig configures the fuzzy finder to select a context
+type pickerConfig struct {
+	storeIDToStore map[string]store.KubeconfigStore
+	showPreview    bool
+	// previewCacheHook is optional. If not set, the store-specific preview is read from the store directly
+	previewCacheHook PreviewCacheHook
+}
+
+func showFuzzySearch(picker pickerConfig) (string, string, error) {
+	done := make(chan struct{})
+	setFuzzySearchDone(done)
+	defer func() {
+		setFuzzySearchDone(nil)
+		close(done)
+	}()
+
 	idx, err := fuzzyfinder.Find(
 		&allKubeconfigContextNames,
 		func(i int) string {
-			return readFromAllKubeconfigContextNames(i)
+			// the group, the package and the enriched tags are shown and can be searched for
+			contextName := readFromAllKubeconfigContextNames(i)
+			label := contextName
+			if packageName := readFromContextToPackage(contextName); len(packageName) > 0 {
+				label = fmt.Sprintf("%s ▸ %s", packageName, contextName)
+			}
+			if group := getGroupOfContext(contextName); len(group) > 0 {
+				label = fmt.Sprintf("%s ▸ %s", group, label)
+			}
+			if tags := formatEnrichedTags(contextName, ", "); len(tags) > 0 {
+				label = fmt.Sprintf("%s  [%s]", label, tags)
+			}
+			if isPartialStoreID(readFromPathToStoreID(readFromContextToPathMapping(contextName))) {
+				label = fmt.Sprintf("%s  [partial]", label)
+			}
+			return label
 		},
-		getFuzzyFinderOptions(storeIDToStore, showPreview)...,
+		getFuzzyFinderOptions(picker)...,
 	)
 
 	if err != nil {
@@ -224,13 +959,21 @@ func showFuzzySearch(storeIDToStore map[string]store.KubeconfigStore, showPrevie
 }
 
 // getFuzzyFinderOptions returns a list of fuzzy finder options
-func getFuzzyFinderOptions(storeIDToStore map[string]store.KubeconfigStore, showPreview bool) []fuzzyfinder.Option {
-	options := []fuzzyfinder.Option{fuzzyfinder.WithHotReloadLock(hotReloadLock.RLocker())}
+func getFuzzyFinderOptions(picker pickerConfig) []fuzzyfinder.Option {
+	options := []fuzzyfinder.Option{fuzzyfinder.WithHotReloadLock(fuzzySearchLocker{})}
+	storeIDToStore, showPreview := picker.storeIDToStore, picker.showPreview
 
 	if showPreview {
 		log := logrus.New()
 		withPreviewWindow := fuzzyfinder.WithPreviewWindow(func(i, w, h int) string {
-			if !showPreview || i == -1 {
+			if !showPreview {
+				return ""
+			}
+
+			// read the next page of paginated stores when approaching the last search result
+			scrollPaginatedLists(i)
+
+			if i == -1 {
 				return ""
 			}
 
@@ -247,7 +990,18 @@ func getFuzzyFinderOptions(storeIDToStore map[string]store.KubeconfigStore, show
 			var storeSpecificPreview *string
 			previewer, ok := kubeconfigStore.(store.Previewer)
 			if ok {
-				pr, err := previewer.GetSearchPreview(path, tags)
+				var pr string
+				var err error
+				if picker.previewCacheHook != nil {
+					pr, err = picker.previewCacheHook(i, prefetch.Entry{
+						Key:       previewKey(storeID, path),
+						Previewer: previewer,
+						Path:      path,
+						Tags:      tags,
+					})
+				} else {
+					pr, err = previewer.GetSearchPreview(path, tags)
+				}
 				if err != nil {
 					log.Debugf("failed to get preview for store %s: %v", kubeconfigStore.GetID(), err)
 					return ""
@@ -269,6 +1023,28 @@ func getFuzzyFinderOptions(storeIDToStore map[string]store.KubeconfigStore, show
 				preview = fmt.Sprintf("%s \n %s \n \n %s", preview, strings.Join(separators, "-"), *storeSpecificPreview)
 			}
 
+			if kubeconfigStore.GetStoreConfig().RBACPreview {
+				if rbacPreview := getRBACPreview(kubeconfigStore, path, tags, currentContextName); len(rbacPreview) > 0 {
+					preview = fmt.Sprintf("%s \n%s", rbacPreview, preview)
+				}
+			}
+
+			if tags := formatEnrichedTags(currentContextName, " \n "); len(tags) > 0 {
+				preview = fmt.Sprintf("%s \n \n%s", tags, preview)
+			}
+
+			if userAnnotations := formatAnnotations(currentContextName, " \n "); len(userAnnotations) > 0 {
+				preview = fmt.Sprintf("Annotations: \n %s \n \n%s", userAnnotations, preview)
+			}
+
+			if isPartialStoreID(storeID) {
+				preview = fmt.Sprintf("[partial] store %q failed or did not finish the search in time \n \n%s", storeID, preview)
+			}
+
+			if loadingStoreIDs := getLoadingStoreIDs(storeIDToStore); len(loadingStoreIDs) > 0 {
+				preview = fmt.Sprintf("[loading…] initializing stores: %s \n \n%s", strings.Join(loadingStoreIDs, ", "), preview)
+			}
+
 			return preview
 		})
 
@@ -278,14 +1054,148 @@ func getFuzzyFinderOptions(storeIDToStore map[string]store.KubeconfigStore, show
 	return options
 }
 
+// scrollPaginatedLists reads the next page of the paginated stores once few search results remain below
+// the search result with the given index that is shown in the preview.
+// The fuzzy search does not expose how many search results match the query. Without a matching search result (index -1),
+// or if the shown search result did not move by a single position because the query changed the matching search results,
+// the matching search results are treated as exhausted, so that typing a query reads further pages.
+func scrollPaginatedLists(i int) {
+	if len(paginatedLists) == 0 {
+		return
+	}
+
+	previewIndexLock.Lock()
+	previous := previewIndex
+	previewIndex = i
+	previewIndexLock.Unlock()
+
+	remaining := 0
+	if i != -1 && i-previous >= -1 && i-previous <= 1 {
+		hotReloadLock.RLock()
+		remaining = lenAllKubeconfigContextNames() - i - 1
+		hotReloadLock.RUnlock()
+	}
+
+	for _, list := range paginatedLists {
+		list.Scroll(remaining)
+	}
+}
+
+// getPreviewEntry returns a resolver for the entries of the picker with a store-specific preview
+func getPreviewEntry(storeIDToStore map[string]store.KubeconfigStore) prefetch.Resolver {
+	return func(index int) *prefetch.Entry {
+		hotReloadLock.RLock()
+		if index < 0 || index >= lenAllKubeconfigContextNames() {
+			hotReloadLock.RUnlock()
+			return nil
+		}
+		contextName := readFromAllKubeconfigContextNames(index)
+		hotReloadLock.RUnlock()
+
+		path := readFromContextToPathMapping(contextName)
+		storeID := readFromPathToStoreID(path)
+		previewer, ok := storeIDToStore[storeID].(store.Previewer)
+		if !ok {
+			return nil
+		}
+
+		return &prefetch.Entry{
+			Key:       previewKey(storeID, path),
+			Previewer: previewer,
+			Path:      path,
+			Tags:      readFromPathToTagsMapping(path),
+		}
+	}
+}
+
+// previewKey identifies the store-specific preview of a kubeconfig path.
+// Contexts sharing the same kubeconfig share the preview.
+func previewKey(storeID, path string) string {
+	return fmt.Sprintf("%s:%s", storeID, path)
+}
+
+// getRBACPreview returns a table of the permissions of the user of the context in the cluster.
+// Returns an empty string if the permissions cannot be checked, so that the standard preview is shown.
+// The result is cached, as the preview is rendered on every cursor movement.
+func getRBACPreview(kubeconfigStore store.KubeconfigStore, path string, tags map[string]string, contextName string) string {
+	contextToRBACPreviewLock.RLock()
+	preview, ok := contextToRBACPreview[contextName]
+	contextToRBACPreviewLock.RUnlock()
+	if ok {
+		return preview
+	}
+
+	preview, err := checkRBAC(kubeconfigStore, path, tags, contextName)
+	if err != nil {
+		logger.Debugf("failed to check the permissions of context %q: %v", contextName, err)
+	}
+
+	contextToRBACPreviewLock.Lock()
+	contextToRBACPreview[contextName] = preview
+	contextToRBACPreviewLock.Unlock()
+	return preview
+}
+
+func checkRBAC(kubeconfigStore store.KubeconfigStore, path string, tags map[string]string, contextName string) (string, error) {
+	data, err := kubeconfigStore.GetKubeconfigForPath(context.Background(), path, tags)
+	if err != nil {
+		return "", err
+	}
+
+	kubeconfig, err := clientcmd.Load(data)
+	if err != nil {
+		return "", err
+	}
+
+	// the context name in the kubeconfig is the original context name without the store prefix
+	aliasToContextLock.RLock()
+	if original := aliasutil.GetContextForAlias(contextName, aliasToContext); len(original) > 0 {
+		contextName = original
+	}
+	aliasToContextLock.RUnlock()
+	if prefix := kubeconfigStore.GetContextPrefix(path); len(prefix) > 0 {
+		contextName = strings.TrimPrefix(contextName, fmt.Sprintf("%s/", prefix))
+	}
+
+	kubeContext, ok := kubeconfig.Contexts[contextName]
+	if !ok {
+		return "", fmt.Errorf("context %q not found in kubeconfig", contextName)
+	}
+
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*kubeconfig, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return "", err
+	}
+	restConfig.Timeout = rbacPreviewTimeout
+
+	namespace := kubeContext.Namespace
+	if len(namespace) == 0 {
+		namespace = "default"
+	}
+
+	checks := kubeconfigStore.GetStoreConfig().RBACCheckResources
+	if len(checks) == 0 {
+		checks = rbac.DefaultChecks
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rbacPreviewTimeout)
+	defer cancel()
+
+	results, err := rbac.CheckAccess(ctx, restConfig, namespace, checks)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Permissions in namespace %q:\n%s", namespace, rbac.FormatResults(results)), nil
+}
+
 func getSanitizedKubeconfigForKubeconfigPath(kubeconfigStore store.KubeconfigStore, path string, tags map[string]string) (string, error) {
 	// during first run without index, the files are already read in the getContextsForKubeconfigPath and saved in-memory
-	kubeconfig := readFromPathToKubeconfig(path)
+	kubeconfig := readFromPathToKubeconfig(kubeconfigStore.GetID(), path)
 	if len(kubeconfig) > 0 {
 		return kubeconfig, nil
 	}
 
-	data, err := kubeconfigStore.GetKubeconfigForPath(path, tags)
+	data, err := kubeconfigStore.GetKubeconfigForPath(context.Background(), path, tags)
 	if err != nil {
 		return "", fmt.Errorf("could not read kubeconfig with path '%s': %v", path, err)
 	}
@@ -301,7 +1211,7 @@ func getSanitizedKubeconfigForKubeconfigPath(kubeconfigStore store.KubeconfigSto
 	}
 
 	// save kubeconfig content to in-memory map to avoid duplicate read operation in getSanitizedKubeconfigForKubeconfigPath
-	writeToPathToKubeconfig(path, string(kubeconfigData))
+	writeToPathToKubeconfig(kubeconfigStore.GetID(), path, string(kubeconfigData))
 
 	return string(kubeconfigData), nil
 }
@@ -312,6 +1222,12 @@ func readFromAllKubeconfigContextNames(index int) string {
 	return allKubeconfigContextNames[index]
 }
 
+func lenAllKubeconfigContextNames() int {
+	allKubeconfigContextNamesLock.RLock()
+	defer allKubeconfigContextNamesLock.RUnlock()
+	return len(allKubeconfigContextNames)
+}
+
 func appendToAllKubeconfigContextNames(values ...string) {
 	allKubeconfigContextNamesLock.Lock()
 	defer allKubeconfigContextNamesLock.Unlock()
@@ -354,15 +1270,33 @@ func writeToPathToStoreID(key string, value string) {
 	pathToStoreID[key] = value
 }
 
-func readFromPathToKubeconfig(key string) string {
-	pathToKubeconfigLock.RLock()
-	defer pathToKubeconfigLock.RUnlock()
+// limitKubeconfigCache only keeps the kubeconfigs of the given number of last read kubeconfig paths of the store in memory
+func limitKubeconfigCache(storeID string, size int) {
+	pathToKubeconfigLock.Lock()
+	defer pathToKubeconfigLock.Unlock()
+	limitedKubeconfigCaches[storeID], _ = simplelru.NewLRU(max(size, 1), nil)
+}
+
+func readFromPathToKubeconfig(storeID, key string) string {
+	// reading from a limited cache updates the recently used kubeconfigs
+	pathToKubeconfigLock.Lock()
+	defer pathToKubeconfigLock.Unlock()
+	if cache, ok := limitedKubeconfigCaches[storeID]; ok {
+		if value, ok := cache.Get(key); ok {
+			return value.(string)
+		}
+		return ""
+	}
 	return pathToKubeconfig[key]
 }
 
-func writeToPathToKubeconfig(key, value string) {
+func writeToPathToKubeconfig(storeID, key, value string) {
 	pathToKubeconfigLock.Lock()
 	defer pathToKubeconfigLock.Unlock()
+	if cache, ok := limitedKubeconfigCaches[storeID]; ok {
+		cache.Add(key, value)
+		return
+	}
 	pathToKubeconfig[key] = value
 }
 
@@ -372,8 +1306,164 @@ func writeToAliasToContext(key, value string) {
 	aliasToContext[key] = value
 }
 
+func readFromContextToEnrichedTags(key string) map[string]string {
+	contextToEnrichedTagsLock.RLock()
+	defer contextToEnrichedTagsLock.RUnlock()
+	return contextToEnrichedTags[key]
+}
+
+func writeToContextToEnrichedTags(key string, value map[string]string) {
+	contextToEnrichedTagsLock.Lock()
+	defer contextToEnrichedTagsLock.Unlock()
+	contextToEnrichedTags[key] = value
+}
+
+// formatEnrichedTags returns the enriched tags of the context as sorted "key=value" pairs joined by the separator
+func formatEnrichedTags(contextName, separator string) string {
+	tags := readFromContextToEnrichedTags(contextName)
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, separator)
+}
+
+func readFromContextToAnnotations(key string) map[string]string {
+	contextToAnnotationsLock.RLock()
+	defer contextToAnnotationsLock.RUnlock()
+	return contextToAnnotations[key]
+}
+
+func writeToContextToAnnotations(key string, value map[string]string) {
+	contextToAnnotationsLock.Lock()
+	defer contextToAnnotationsLock.Unlock()
+	contextToAnnotations[key] = value
+}
+
+func readFromContextToPackage(key string) string {
+	contextToPackageLock.RLock()
+	defer contextToPackageLock.RUnlock()
+	return contextToPackage[key]
+}
+
+func writeToContextToPackage(key string, value string) {
+	contextToPackageLock.Lock()
+	defer contextToPackageLock.Unlock()
+	contextToPackage[key] = value
+}
+
+func hasGroupedStores() bool {
+	groupedStoresLock.RLock()
+	defer groupedStoresLock.RUnlock()
+	return len(groupedStores) > 0
+}
+
+func readFromGroupedStores(key string) *types.KubeconfigStore {
+	groupedStoresLock.RLock()
+	defer groupedStoresLock.RUnlock()
+	kubeconfigStore, ok := groupedStores[key]
+	if !ok {
+		return nil
+	}
+	return &kubeconfigStore
+}
+
+func writeToGroupedStores(key string, value types.KubeconfigStore) {
+	groupedStoresLock.Lock()
+	defer groupedStoresLock.Unlock()
+	groupedStores[key] = value
+}
+
+// isOfGroupedStore returns true if the discovered context belongs to a store that groups its search results by tag
+func isOfGroupedStore(discoveredContext DiscoveredContext) bool {
+	return discoveredContext.Store != nil && readFromGroupedStores((*discoveredContext.Store).GetID()) != nil
+}
+
+// startRegrouping moves the context names added after the initial search results have been sorted and grouped into their groups.
+// Requests to regroup that arrive while the selection dialog is reloaded are served by a single subsequent reload.
+func startRegrouping() {
+	requests := make(chan struct{}, 1)
+	regroupRequestsLock.Lock()
+	regroupRequests = requests
+	regroupRequestsLock.Unlock()
+
+	go func() {
+		for range requests {
+			fuzzySearchReloadLock.Lock()
+			pending := len(ungroupedContextNames) > 0
+			fuzzySearchReloadLock.Unlock()
+			if pending {
+				reloadFuzzySearch(groupLateContextNames)
+			}
+		}
+	}()
+
+	// context names might have been added since the search results have been grouped
+	regroupLateSearchResults()
+}
+
+// regroupLateSearchResults requests to move the search results added after the initial search results have been sorted and grouped
+// into their groups. Does not block.
+func regroupLateSearchResults() {
+	regroupRequestsLock.Lock()
+	defer regroupRequestsLock.Unlock()
+	if regroupRequests == nil {
+		return
+	}
+	select {
+	case regroupRequests <- struct{}{}:
+	default:
+		// the pending request also moves the search results
+	}
+}
+
+// formatAnnotations returns the user-defined annotations of the context as sorted "key=value" pairs joined by the separator
+func formatAnnotations(contextName, separator string) string {
+	userAnnotations := readFromContextToAnnotations(contextName)
+	pairs := make([]string, 0, len(userAnnotations))
+	for key, value := range userAnnotations {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, separator)
+}
+
+// getLoadingStoreIDs returns the sorted IDs of the lazily initialized stores that are still initializing
+func getLoadingStoreIDs(storeIDToStore map[string]store.KubeconfigStore) []string {
+	var storeIDs []string
+	for storeID, kubeconfigStore := range storeIDToStore {
+		if store.IsLoading(kubeconfigStore) {
+			storeIDs = append(storeIDs, storeID)
+		}
+	}
+	slices.Sort(storeIDs)
+	return storeIDs
+}
+
+func isPartialStoreID(key string) bool {
+	partialStoreIDsLock.RLock()
+	defer partialStoreIDsLock.RUnlock()
+	_, ok := partialStoreIDs[key]
+	return ok
+}
+
+func writeToPartialStoreIDs(key string) {
+	partialStoreIDsLock.Lock()
+	defer partialStoreIDsLock.Unlock()
+	partialStoreIDs[key] = struct{}{}
+}
+
+func appendToSearchError(err error) {
+	searchErrorLock.Lock()
+	defer searchErrorLock.Unlock()
+	searchError = multierror.Append(searchError, err)
+}
+
 // logSearchErrors logs errors that were suppressed during the search
 func logSearchErrors() {
+	searchErrorLock.Lock()
+	defer searchErrorLock.Unlock()
 	if searchError != nil {
 		logger.Warnf("Supressed warnings during the search: %v", searchError.Error())
 	}
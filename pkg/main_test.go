@@ -0,0 +1,390 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/pkg/ui"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// paginatedStore finds the given number of kubeconfigs with one context each
+type paginatedStore struct {
+	count int
+	// sent is the number of search results the store sent
+	sent atomic.Int32
+}
+
+func (s *paginatedStore) GetID() string                               { return "vault.paginated" }
+func (s *paginatedStore) GetKind() types.StoreKind                    { return types.StoreKindVault }
+func (s *paginatedStore) GetContextPrefix(string) string              { return "" }
+func (s *paginatedStore) VerifyKubeconfigPaths(context.Context) error { return nil }
+func (s *paginatedStore) Probe(context.Context) error                 { return nil }
+func (s *paginatedStore) GetLogger() *logrus.Entry                    { return testutil.NewTestLogger() }
+func (s *paginatedStore) Stop(context.Context) error                  { return nil }
+func (s *paginatedStore) GetStoreConfig() types.KubeconfigStore {
+	return types.KubeconfigStore{Kind: types.StoreKindVault, Paginated: true}
+}
+
+func (s *paginatedStore) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	for i := 0; i < s.count; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case channel <- testutil.FakeSearchResult(fmt.Sprintf("cluster-%d", i)):
+			s.sent.Add(1)
+		}
+	}
+}
+
+func (s *paginatedStore) GetKubeconfigForPath(_ context.Context, path string, _ map[string]string) ([]byte, error) {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: %[1]s
+  cluster:
+    server: https://%[1]s.example.com
+contexts:
+- name: %[1]s
+  context:
+    cluster: %[1]s
+    user: %[1]s
+users:
+- name: %[1]s
+`, path)), nil
+}
+
+var _ = Describe("reloadFuzzySearch", func() {
+	appendContextName := func(contextNames []string) []string {
+		return append(contextNames, "c")
+	}
+
+	BeforeEach(func() {
+		allKubeconfigContextNames = []string{"a", "b"}
+	})
+
+	AfterEach(func() {
+		setFuzzySearchDone(nil)
+		allKubeconfigContextNames = nil
+	})
+
+	It("should publish the context names at once if no fuzzy search is shown", func() {
+		reloadFuzzySearch(appendContextName)
+		Expect(allKubeconfigContextNames).To(Equal([]string{"a", "b", "c"}))
+	})
+
+	It("should hide the last context name until the fuzzy search checked the context names", func() {
+		setFuzzySearchDone(make(chan struct{}))
+
+		reloaded := make(chan struct{})
+		go func() {
+			defer close(reloaded)
+			reloadFuzzySearch(appendContextName)
+		}()
+
+		// the fuzzy search has not checked the context names yet, hence the added context name is hidden
+		Expect(readAllKubeconfigContextNames()).To(Equal([]string{"a", "b"}))
+		Expect(reloaded).ToNot(BeClosed())
+
+		// the send only succeeds once the reload published the hidden context names and waits for the check
+		fuzzySearchPolled <- struct{}{}
+		<-reloaded
+		Expect(allKubeconfigContextNames).To(Equal([]string{"a", "b", "c"}))
+	})
+
+	It("should publish all context names once the fuzzy search returned", func() {
+		done := make(chan struct{})
+		setFuzzySearchDone(done)
+
+		reloaded := make(chan struct{})
+		go func() {
+			defer close(reloaded)
+			reloadFuzzySearch(appendContextName)
+		}()
+
+		close(done)
+		<-reloaded
+		Expect(allKubeconfigContextNames).To(Equal([]string{"a", "b", "c"}))
+	})
+})
+
+func readAllKubeconfigContextNames() []string {
+	hotReloadLock.RLock()
+	defer hotReloadLock.RUnlock()
+	allKubeconfigContextNamesLock.RLock()
+	defer allKubeconfigContextNamesLock.RUnlock()
+	return append([]string(nil), allKubeconfigContextNames...)
+}
+
+var _ = Describe("Paginated stores", func() {
+	const windowSize = 5
+
+	var (
+		stateDir string
+		s        *paginatedStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		stateDir, err = os.MkdirTemp("", "kubeswitch-paginated")
+		Expect(err).ToNot(HaveOccurred())
+		annotations.SetPath(filepath.Join(stateDir, "annotations.yaml"))
+
+		s = &paginatedStore{count: 100}
+		limitKubeconfigCache(s.GetID(), windowSize)
+	})
+
+	AfterEach(func() {
+		pathToKubeconfigLock.Lock()
+		delete(limitedKubeconfigCaches, s.GetID())
+		pathToKubeconfigLock.Unlock()
+		annotations.SetPath(annotations.DefaultPath)
+		Expect(os.RemoveAll(stateDir)).To(Succeed())
+	})
+
+	newList := func(onPage func(page []DiscoveredContext)) *ui.VirtualList[DiscoveredContext] {
+		c, err := DoSearch([]store.KubeconfigStore{s}, &types.Config{}, stateDir, true, WithPausedSearchTimeout())
+		Expect(err).ToNot(HaveOccurred())
+		return ui.NewVirtualList(*c, windowSize, onPage)
+	}
+
+	It("should not search further than the next page ahead of the read search results", func() {
+		list := newList(func([]DiscoveredContext) {})
+		Expect(list.NextPage()).To(HaveLen(windowSize))
+
+		// besides the read page, the search and the store can each block on sending a single search result
+		Consistently(s.sent.Load, "200ms").Should(BeNumerically("<=", windowSize+2))
+
+		list.LoadAll()
+		Expect(s.sent.Load()).To(BeEquivalentTo(100))
+	})
+
+	It("should only keep the kubeconfigs of the last window in memory", func() {
+		read := 0
+		list := newList(func(page []DiscoveredContext) {
+			read += len(page)
+		})
+		list.LoadAll()
+		Expect(read).To(Equal(100))
+
+		pathToKubeconfigLock.Lock()
+		defer pathToKubeconfigLock.Unlock()
+		Expect(limitedKubeconfigCaches[s.GetID()].Len()).To(Equal(windowSize))
+		for path := range pathToKubeconfig {
+			Expect(path).ToNot(HavePrefix("cluster-"))
+		}
+	})
+
+	It("should read the kubeconfigs of the last window from memory", func() {
+		newList(func([]DiscoveredContext) {}).LoadAll()
+
+		Expect(readFromPathToKubeconfig(s.GetID(), "cluster-99")).To(ContainSubstring("https://cluster-99.example.com"))
+		Expect(readFromPathToKubeconfig(s.GetID(), "cluster-0")).To(BeEmpty())
+	})
+
+	It("should only keep the search results of a page matching the filter", func() {
+		filter, err := util.NewContextFilter("^cluster-1[0-9]$", util.FilterModeRegex)
+		Expect(err).ToNot(HaveOccurred())
+
+		var kept []string
+		newList(func(page []DiscoveredContext) {
+			for _, discoveredContext := range filterDiscoveredContexts(page, filter) {
+				kept = append(kept, discoveredContext.Name)
+			}
+		}).LoadAll()
+
+		Expect(kept).To(HaveLen(10))
+		Expect(strings.Join(kept, ",")).To(Equal("cluster-10,cluster-11,cluster-12,cluster-13,cluster-14,cluster-15,cluster-16,cluster-17,cluster-18,cluster-19"))
+	})
+})
+
+var _ = Describe("Switcher", func() {
+	var (
+		stateDir string
+		stores   []store.KubeconfigStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		stateDir, err = os.MkdirTemp("", "kubeswitch-switcher")
+		Expect(err).ToNot(HaveOccurred())
+		annotations.SetPath(filepath.Join(stateDir, "annotations.yaml"))
+
+		kubeconfigsDir := filepath.Join(stateDir, "kubeconfigs", "team")
+		Expect(os.MkdirAll(kubeconfigsDir, 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(kubeconfigsDir, "config"), []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: infra-eu-1
+  cluster:
+    server: https://infra-eu-1.example.com
+contexts:
+- name: infra-eu-1
+  context:
+    cluster: infra-eu-1
+    user: admin
+users:
+- name: admin
+`), 0600)).To(Succeed())
+
+		filesystemStore, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:     types.StoreKindFilesystem,
+			Paths:    []string{filepath.Dir(kubeconfigsDir)},
+			Packages: []types.PackageConfig{{Name: "ring-1", Contexts: []string{"infra-*"}}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		stores = []store.KubeconfigStore{filesystemStore}
+	})
+
+	AfterEach(func() {
+		annotations.SetPath(annotations.DefaultPath)
+		Expect(os.RemoveAll(stateDir)).To(Succeed())
+	})
+
+	It("should reject an unknown package", func() {
+		_, _, err := Switcher(stores, &types.Config{}, stateDir, true, false, nil, "ring-9")
+		Expect(err).To(MatchError(`unknown package "ring-9": no context belongs to the package`))
+	})
+
+	It("should know the packages configured for the stores", func() {
+		Expect(isConfiguredPackage(stores, "ring-1")).To(BeTrue())
+		Expect(isConfiguredPackage(stores, "ring-9")).To(BeFalse())
+	})
+})
+
+var _ = Describe("groupLateContextNames", func() {
+	const (
+		groupedStoreID = "http.grouped"
+		otherStoreID   = "http.other"
+	)
+
+	var added []string
+
+	// addContextName adds the context name of the store with the value of the env tag to the search results
+	addContextName := func(contextName, storeID, env string) {
+		path := "path-" + contextName
+		writeToContextToPathMapping(contextName, path)
+		writeToPathToStoreID(path, storeID)
+		if len(env) > 0 {
+			writeToPathToTagsMapping(path, map[string]string{"env": env})
+		}
+		added = append(added, contextName)
+	}
+
+	BeforeEach(func() {
+		added = nil
+		writeToGroupedStores(groupedStoreID, types.KubeconfigStore{GroupByTag: "env", GroupSortOrder: types.GroupSortOrderAsc})
+
+		addContextName("dev-1", groupedStoreID, "dev")
+		addContextName("prod-1", groupedStoreID, "prod")
+		addContextName("local-1", groupedStoreID, "")
+		addContextName("other-1", otherStoreID, "")
+	})
+
+	AfterEach(func() {
+		regroupRequestsLock.Lock()
+		if regroupRequests != nil {
+			close(regroupRequests)
+			regroupRequests = nil
+		}
+		regroupRequestsLock.Unlock()
+
+		groupedStoresLock.Lock()
+		delete(groupedStores, groupedStoreID)
+		groupedStoresLock.Unlock()
+		for _, contextName := range added {
+			path := readFromContextToPathMapping(contextName)
+			contextToPathMappingLock.Lock()
+			delete(contextToPathMapping, contextName)
+			contextToPathMappingLock.Unlock()
+			pathToStoreLock.Lock()
+			delete(pathToStoreID, path)
+			pathToStoreLock.Unlock()
+			pathToTagsMappingLock.Lock()
+			delete(pathToTagsMapping, path)
+			pathToTagsMappingLock.Unlock()
+		}
+		ungroupedContextNames = nil
+		allKubeconfigContextNames = nil
+	})
+
+	It("should move the late context names into their groups without reordering the shown groups", func() {
+		addContextName("prod-2", groupedStoreID, "prod")
+		addContextName("staging-1", groupedStoreID, "staging")
+		addContextName("local-2", groupedStoreID, "")
+		addContextName("alpha-1", groupedStoreID, "alpha")
+		addContextName("other-2", otherStoreID, "")
+		ungroupedContextNames = []string{"prod-2", "staging-1", "local-2", "alpha-1"}
+
+		Expect(groupLateContextNames(slices.Clone(added))).To(Equal([]string{
+			"alpha-1", "dev-1", "prod-1", "prod-2", "staging-1", "local-1", "local-2", "other-1", "other-2",
+		}))
+		Expect(ungroupedContextNames).To(BeEmpty())
+	})
+
+	It("should not modify the context names without late context names", func() {
+		Expect(groupLateContextNames(slices.Clone(added))).To(Equal([]string{"dev-1", "prod-1", "local-1", "other-1"}))
+	})
+
+	It("should group the late context names of a store without shown context names at the bottom", func() {
+		const lateStoreID = "http.late"
+		writeToGroupedStores(lateStoreID, types.KubeconfigStore{GroupByTag: "env", GroupSortOrder: types.GroupSortOrderDesc})
+		defer func() {
+			groupedStoresLock.Lock()
+			delete(groupedStores, lateStoreID)
+			groupedStoresLock.Unlock()
+		}()
+
+		addContextName("late-dev", lateStoreID, "dev")
+		addContextName("late-prod", lateStoreID, "prod")
+		ungroupedContextNames = []string{"late-dev", "late-prod"}
+
+		Expect(groupLateContextNames(slices.Clone(added))).To(Equal([]string{
+			"dev-1", "prod-1", "local-1", "other-1", "late-prod", "late-dev",
+		}))
+	})
+
+	It("should move the late context names into their groups on request", func() {
+		allKubeconfigContextNames = slices.Clone(added)
+		startRegrouping()
+
+		fuzzySearchReloadLock.Lock()
+		addContextName("prod-2", groupedStoreID, "prod")
+		appendToAllKubeconfigContextNames("prod-2")
+		ungroupedContextNames = append(ungroupedContextNames, "prod-2")
+		fuzzySearchReloadLock.Unlock()
+
+		// multiple requests are served by a single reload
+		regroupLateSearchResults()
+		regroupLateSearchResults()
+
+		Eventually(readAllKubeconfigContextNames).Should(Equal([]string{"dev-1", "prod-1", "prod-2", "local-1", "other-1"}))
+	})
+})
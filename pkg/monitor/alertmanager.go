@@ -0,0 +1,71 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/prometheus/alertmanager/api/v2/client"
+	"github.com/prometheus/alertmanager/api/v2/client/alert"
+	"github.com/prometheus/alertmanager/api/v2/models"
+)
+
+// AlertmanagerAlerter sends alerts to the v2 API of an Alertmanager
+type AlertmanagerAlerter struct {
+	client *client.AlertmanagerAPI
+}
+
+// NewAlertmanagerAlerter creates an alerter for the Alertmanager with the given URL, e.g. http://localhost:9093
+func NewAlertmanagerAlerter(alertmanagerURL string) (*AlertmanagerAlerter, error) {
+	u, err := url.Parse(alertmanagerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Alertmanager URL %q: %w", alertmanagerURL, err)
+	}
+	if len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return nil, fmt.Errorf("the Alertmanager URL %q must be an absolute URL", alertmanagerURL)
+	}
+
+	transportConfig := client.DefaultTransportConfig().
+		WithHost(u.Host).
+		WithBasePath(path.Join("/", u.Path, client.DefaultBasePath)).
+		WithSchemes([]string{u.Scheme})
+
+	return &AlertmanagerAlerter{
+		client: client.NewHTTPClientWithConfig(strfmt.Default, transportConfig),
+	}, nil
+}
+
+// Send posts the alerts to the Alertmanager
+func (a *AlertmanagerAlerter) Send(ctx context.Context, alerts []Alert) error {
+	postableAlerts := make(models.PostableAlerts, 0, len(alerts))
+	for _, alert := range alerts {
+		postableAlerts = append(postableAlerts, &models.PostableAlert{
+			Alert: models.Alert{
+				Labels: alert.Labels,
+			},
+			Annotations: alert.Annotations,
+			StartsAt:    strfmt.DateTime(alert.StartsAt),
+			// the Alertmanager treats a zero end time as not set
+			EndsAt: strfmt.DateTime(alert.EndsAt),
+		})
+	}
+
+	_, err := a.client.Alert.PostAlerts(alert.NewPostAlertsParamsWithContext(ctx).WithAlerts(postableAlerts))
+	return err
+}
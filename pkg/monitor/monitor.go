@@ -0,0 +1,255 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// AlertNameContextUnreachable is the name of the alert sent when the API server of a watched context is unreachable
+	AlertNameContextUnreachable = "KubecontextUnreachable"
+	// AlertNameStoreUnreachable is the name of the alert sent when a store cannot be probed
+	AlertNameStoreUnreachable = "KubeconfigStoreUnreachable"
+
+	// DefaultCheckInterval is the default duration between two checks
+	DefaultCheckInterval = time.Minute
+	// DefaultFailureThreshold is the default number of consecutive failed checks after which an alert is sent
+	DefaultFailureThreshold = 3
+
+	// checkTimeout is the maximum duration of a single check
+	checkTimeout = 30 * time.Second
+)
+
+// Target is a store or context checked by the monitor
+type Target struct {
+	// Name identifies the target, e.g. the name of the context or the ID of the store
+	Name string
+	// Labels are the labels of the alert sent for this target.
+	// Must contain the label "alertname".
+	Labels map[string]string
+	// Check returns an error if the target is unreachable
+	Check func(ctx context.Context) error
+}
+
+// Status is the result of the last check of a target
+type Status struct {
+	Name                string            `json:"name"`
+	Labels              map[string]string `json:"labels"`
+	Reachable           bool              `json:"reachable"`
+	ConsecutiveFailures int               `json:"consecutiveFailures"`
+	// Alerting is true if an alert is firing for the target
+	Alerting bool `json:"alerting"`
+	// FailingSince is the time of the first failed check of the current series of failed checks
+	FailingSince *time.Time `json:"failingSince,omitempty"`
+	LastCheck    time.Time  `json:"lastCheck"`
+	LastError    string     `json:"lastError,omitempty"`
+}
+
+// Alert is an alert sent to the Alertmanager
+type Alert struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	StartsAt    time.Time
+	// EndsAt is set for resolved alerts
+	EndsAt time.Time
+}
+
+// Alerter sends alerts
+type Alerter interface {
+	Send(ctx context.Context, alerts []Alert) error
+}
+
+// Monitor periodically checks targets and sends an alert once a target failed FailureThreshold consecutive checks.
+// Firing alerts are sent again on every check to keep them active and are resolved once the target is reachable again.
+type Monitor struct {
+	Logger           *logrus.Entry
+	Targets          []Target
+	Alerter          Alerter
+	CheckInterval    time.Duration
+	FailureThreshold int
+	// OnCheck is called with the status of all targets after every check
+	OnCheck func([]Status)
+
+	// lock guards the targets and their status, as the targets can be replaced while checking
+	lock   sync.Mutex
+	status map[string]*Status
+}
+
+// New creates a new monitor for the given targets
+func New(targets []Target, alerter Alerter, checkInterval time.Duration, failureThreshold int) *Monitor {
+	return &Monitor{
+		Logger:           logrus.New().WithField("component", "monitor"),
+		Targets:          targets,
+		Alerter:          alerter,
+		CheckInterval:    checkInterval,
+		FailureThreshold: failureThreshold,
+		status:           map[string]*Status{},
+	}
+}
+
+// Run checks all targets every check interval until the context is canceled
+func (m *Monitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		m.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check checks all targets concurrently, sends the resulting alerts and returns the status of all targets
+func (m *Monitor) Check(ctx context.Context) []Status {
+	m.lock.Lock()
+	targets := m.Targets
+	m.lock.Unlock()
+
+	errs := make([]error, len(targets))
+
+	wg := sync.WaitGroup{}
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			errs[i] = target.Check(checkCtx)
+		}(i, target)
+	}
+	wg.Wait()
+
+	now := time.Now()
+	var alerts []Alert
+	m.lock.Lock()
+	for i, target := range targets {
+		if alert := m.update(target, errs[i], now); alert != nil {
+			alerts = append(alerts, *alert)
+		}
+	}
+	m.lock.Unlock()
+
+	if len(alerts) > 0 {
+		if err := m.Alerter.Send(ctx, alerts); err != nil {
+			m.Logger.Errorf("failed to send %d alert(s): %v", len(alerts), err)
+		}
+	}
+
+	status := m.GetStatus()
+	if m.OnCheck != nil {
+		m.OnCheck(status)
+	}
+	return status
+}
+
+// SetTargets replaces the targets checked from the next check on.
+// The status of targets that are not checked anymore is removed. Their firing alerts are not sent anymore
+// and are resolved by the Alertmanager once they time out.
+func (m *Monitor) SetTargets(targets []Target) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	names := make(map[string]struct{}, len(targets))
+	for _, target := range targets {
+		names[target.Name] = struct{}{}
+	}
+	for name := range m.status {
+		if _, ok := names[name]; !ok {
+			delete(m.status, name)
+		}
+	}
+	m.Targets = targets
+}
+
+// update updates the status of the target with the result of the check.
+// Returns the alert to send, if any. Must be called with the lock held.
+func (m *Monitor) update(target Target, err error, now time.Time) *Alert {
+	status, ok := m.status[target.Name]
+	if !ok {
+		status = &Status{Name: target.Name, Labels: target.Labels}
+		m.status[target.Name] = status
+	}
+	status.LastCheck = now
+
+	if err == nil {
+		wasAlerting := status.Alerting
+		failingSince := status.FailingSince
+
+		status.Reachable = true
+		status.ConsecutiveFailures = 0
+		status.Alerting = false
+		status.FailingSince = nil
+		status.LastError = ""
+
+		if !wasAlerting {
+			return nil
+		}
+		m.Logger.Infof("%s is reachable again", target.Name)
+		return &Alert{Labels: target.Labels, StartsAt: *failingSince, EndsAt: now}
+	}
+
+	status.Reachable = false
+	status.ConsecutiveFailures++
+	status.LastError = err.Error()
+	if status.FailingSince == nil {
+		status.FailingSince = &now
+	}
+
+	if status.ConsecutiveFailures < m.FailureThreshold {
+		m.Logger.Debugf("check of %s failed (%d/%d): %v", target.Name, status.ConsecutiveFailures, m.FailureThreshold, err)
+		return nil
+	}
+
+	if !status.Alerting {
+		m.Logger.Warnf("%s is unreachable after %d failed checks: %v", target.Name, status.ConsecutiveFailures, err)
+	}
+	status.Alerting = true
+
+	return &Alert{
+		Labels: target.Labels,
+		Annotations: map[string]string{
+			"summary":     fmt.Sprintf("%s is unreachable", target.Name),
+			"description": err.Error(),
+		},
+		StartsAt: *status.FailingSince,
+	}
+}
+
+// GetStatus returns the status of all checked targets ordered by name
+func (m *Monitor) GetStatus() []Status {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	status := make([]Status, 0, len(m.status))
+	for _, s := range m.status {
+		status = append(status, *s)
+	}
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Name < status[j].Name
+	})
+	return status
+}
@@ -0,0 +1,186 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/monitor"
+)
+
+// fakeAlerter records the sent alerts
+type fakeAlerter struct {
+	alerts [][]monitor.Alert
+}
+
+func (f *fakeAlerter) Send(_ context.Context, alerts []monitor.Alert) error {
+	f.alerts = append(f.alerts, alerts)
+	return nil
+}
+
+var _ = Describe("Monitor", func() {
+	var (
+		alerter   *fakeAlerter
+		checkErr  error
+		m         *monitor.Monitor
+		labels    = map[string]string{"alertname": monitor.AlertNameContextUnreachable, "context": "prod", "store": "eks.default"}
+		ctx       = context.Background()
+		onChecked []monitor.Status
+	)
+
+	BeforeEach(func() {
+		alerter = &fakeAlerter{}
+		checkErr = nil
+		onChecked = nil
+
+		m = monitor.New([]monitor.Target{{
+			Name:   "context/prod",
+			Labels: labels,
+			Check: func(context.Context) error {
+				return checkErr
+			},
+		}}, alerter, time.Minute, 2)
+		m.OnCheck = func(status []monitor.Status) {
+			onChecked = status
+		}
+	})
+
+	It("should not alert while the target is reachable", func() {
+		status := m.Check(ctx)
+		Expect(status).To(HaveLen(1))
+		Expect(status[0].Reachable).To(BeTrue())
+		Expect(status[0].Alerting).To(BeFalse())
+		Expect(onChecked).To(Equal(status))
+		Expect(alerter.alerts).To(BeEmpty())
+	})
+
+	It("should alert after the failure threshold and resolve the alert once the target is reachable again", func() {
+		checkErr = errors.New("connection refused")
+
+		status := m.Check(ctx)
+		Expect(status[0].Reachable).To(BeFalse())
+		Expect(status[0].ConsecutiveFailures).To(Equal(1))
+		Expect(status[0].Alerting).To(BeFalse())
+		Expect(status[0].LastError).To(Equal("connection refused"))
+		Expect(alerter.alerts).To(BeEmpty())
+
+		status = m.Check(ctx)
+		Expect(status[0].Alerting).To(BeTrue())
+		Expect(alerter.alerts).To(HaveLen(1))
+		firing := alerter.alerts[0][0]
+		Expect(firing.Labels).To(Equal(labels))
+		Expect(firing.Annotations).To(HaveKeyWithValue("description", "connection refused"))
+		Expect(firing.StartsAt).To(Equal(*status[0].FailingSince))
+		Expect(firing.EndsAt.IsZero()).To(BeTrue())
+
+		// firing alerts are sent again to keep them active
+		m.Check(ctx)
+		Expect(alerter.alerts).To(HaveLen(2))
+		Expect(alerter.alerts[1][0].StartsAt).To(Equal(firing.StartsAt))
+
+		checkErr = nil
+		status = m.Check(ctx)
+		Expect(status[0].Reachable).To(BeTrue())
+		Expect(status[0].Alerting).To(BeFalse())
+		Expect(status[0].FailingSince).To(BeNil())
+		Expect(alerter.alerts).To(HaveLen(3))
+		resolved := alerter.alerts[2][0]
+		Expect(resolved.Labels).To(Equal(labels))
+		Expect(resolved.StartsAt).To(Equal(firing.StartsAt))
+		Expect(resolved.EndsAt.IsZero()).To(BeFalse())
+
+		// resolved alerts are only sent once
+		m.Check(ctx)
+		Expect(alerter.alerts).To(HaveLen(3))
+	})
+
+	It("should reset the consecutive failures after a successful check", func() {
+		checkErr = errors.New("timeout")
+		m.Check(ctx)
+		checkErr = nil
+		m.Check(ctx)
+		checkErr = errors.New("timeout")
+		status := m.Check(ctx)
+		Expect(status[0].ConsecutiveFailures).To(Equal(1))
+		Expect(alerter.alerts).To(BeEmpty())
+	})
+
+	It("should only check and report the replaced targets", func() {
+		checkErr = errors.New("timeout")
+		m.Check(ctx)
+
+		m.SetTargets([]monitor.Target{{
+			Name:   "store/eks.default",
+			Labels: map[string]string{"alertname": monitor.AlertNameStoreUnreachable, "store": "eks.default"},
+			Check: func(context.Context) error {
+				return nil
+			},
+		}})
+
+		status := m.Check(ctx)
+		Expect(status).To(HaveLen(1))
+		Expect(status[0].Name).To(Equal("store/eks.default"))
+		Expect(status[0].Reachable).To(BeTrue())
+	})
+})
+
+var _ = Describe("AlertmanagerAlerter", func() {
+	It("should post the alerts to the Alertmanager API", func() {
+		var received []map[string]interface{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.URL.Path).To(Equal("/alertmanager/api/v2/alerts"))
+			Expect(json.NewDecoder(r.Body).Decode(&received)).To(Succeed())
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		alerter, err := monitor.NewAlertmanagerAlerter(server.URL + "/alertmanager")
+		Expect(err).ToNot(HaveOccurred())
+
+		startsAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		Expect(alerter.Send(context.Background(), []monitor.Alert{
+			{
+				Labels:      map[string]string{"alertname": monitor.AlertNameStoreUnreachable, "store": "eks.default"},
+				Annotations: map[string]string{"summary": "store/eks.default is unreachable"},
+				StartsAt:    startsAt,
+			},
+			{
+				Labels:   map[string]string{"alertname": monitor.AlertNameContextUnreachable, "context": "prod"},
+				StartsAt: startsAt,
+				EndsAt:   startsAt.Add(time.Hour),
+			},
+		})).To(Succeed())
+
+		Expect(received).To(HaveLen(2))
+		Expect(received[0]["labels"]).To(Equal(map[string]interface{}{"alertname": "KubeconfigStoreUnreachable", "store": "eks.default"}))
+		Expect(received[0]["startsAt"]).To(Equal("2024-01-01T12:00:00.000Z"))
+		Expect(received[1]["endsAt"]).To(Equal("2024-01-01T13:00:00.000Z"))
+	})
+
+	It("should reject relative URLs", func() {
+		_, err := monitor.NewAlertmanagerAlerter("localhost:9093")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,220 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// authProviderName is the name of the OIDC auth provider in the kubeconfig
+	authProviderName = "oidc"
+
+	// keys of the OIDC auth provider configuration
+	keyIDToken      = "id-token"
+	keyRefreshToken = "refresh-token"
+	keyIssuer       = "idp-issuer-url"
+	keyClientID     = "client-id"
+	keyClientSecret = "client-secret"
+
+	// expirySkew refreshes tokens shortly before they expire
+	expirySkew = 10 * time.Second
+)
+
+var (
+	httpClient = &http.Client{Timeout: 10 * time.Second}
+
+	// refreshedTokens caches the tokens obtained for a refresh token.
+	// Refresh tokens may only be used once, hence concurrent calls for the same refresh token
+	// must not refresh again but use the already refreshed tokens.
+	refreshedTokens     = make(map[string]tokens)
+	refreshedTokensLock sync.Mutex
+)
+
+type tokens struct {
+	idToken      string
+	refreshToken string
+}
+
+// RefreshOIDCToken refreshes expired id tokens of users with the OIDC auth provider in the given kubeconfig
+// and returns the kubeconfig with the refreshed tokens.
+// The issuer, client ID and client secret override the values configured for the auth provider if not empty.
+// Returns the unmodified kubeconfig if no id token is expired.
+// Safe to call concurrently.
+func RefreshOIDCToken(kubeconfig []byte, issuer, clientID, clientSecret string) ([]byte, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	modified := false
+	for name, authInfo := range config.AuthInfos {
+		if authInfo.AuthProvider == nil || authInfo.AuthProvider.Name != authProviderName {
+			continue
+		}
+		providerConfig := authInfo.AuthProvider.Config
+
+		expired, err := isExpired(providerConfig[keyIDToken])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse id token of user %q: %w", name, err)
+		}
+		if !expired {
+			continue
+		}
+
+		refreshToken := providerConfig[keyRefreshToken]
+		if len(refreshToken) == 0 {
+			return nil, fmt.Errorf("id token of user %q is expired and cannot be refreshed without refresh token", name)
+		}
+
+		refreshed, err := refresh(
+			refreshToken,
+			valueOrDefault(issuer, providerConfig[keyIssuer]),
+			valueOrDefault(clientID, providerConfig[keyClientID]),
+			valueOrDefault(clientSecret, providerConfig[keyClientSecret]),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh id token of user %q: %w", name, err)
+		}
+
+		providerConfig[keyIDToken] = refreshed.idToken
+		providerConfig[keyRefreshToken] = refreshed.refreshToken
+		modified = true
+	}
+
+	if !modified {
+		return kubeconfig, nil
+	}
+	return clientcmd.Write(*config)
+}
+
+// isExpired returns true if the "exp" claim of the JWT is in the past.
+// An empty token is considered expired.
+func isExpired(jwt string) (bool, error) {
+	if len(jwt) == 0 {
+		return true, nil
+	}
+
+	expiry, err := util.JWTExpiry(jwt)
+	if err != nil {
+		return false, err
+	}
+
+	// tokens without expiry do not expire
+	if expiry.IsZero() {
+		return false, nil
+	}
+	return expiry.Before(time.Now().Add(expirySkew)), nil
+}
+
+// refresh obtains new tokens from the token endpoint of the issuer
+func refresh(refreshToken, issuer, clientID, clientSecret string) (tokens, error) {
+	refreshedTokensLock.Lock()
+	defer refreshedTokensLock.Unlock()
+
+	if refreshed, ok := refreshedTokens[refreshToken]; ok {
+		return refreshed, nil
+	}
+
+	if len(issuer) == 0 {
+		return tokens{}, fmt.Errorf("the issuer URL is not configured")
+	}
+
+	tokenEndpoint, err := discoverTokenEndpoint(issuer)
+	if err != nil {
+		return tokens{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {clientID},
+	}
+	if len(clientSecret) > 0 {
+		form.Set("client_secret", clientSecret)
+	}
+
+	response, err := httpClient.PostForm(tokenEndpoint, form)
+	if err != nil {
+		return tokens{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return tokens{}, fmt.Errorf("token endpoint %q returned status %d", tokenEndpoint, response.StatusCode)
+	}
+
+	tokenResponse := struct {
+		IDToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&tokenResponse); err != nil {
+		return tokens{}, fmt.Errorf("failed to parse response of token endpoint %q: %w", tokenEndpoint, err)
+	}
+
+	if len(tokenResponse.IDToken) == 0 {
+		return tokens{}, fmt.Errorf("token endpoint %q did not return an id token", tokenEndpoint)
+	}
+
+	refreshed := tokens{
+		idToken:      tokenResponse.IDToken,
+		refreshToken: valueOrDefault(tokenResponse.RefreshToken, refreshToken),
+	}
+	refreshedTokens[refreshToken] = refreshed
+	return refreshed, nil
+}
+
+// discoverTokenEndpoint reads the token endpoint from the OpenID provider configuration of the issuer
+func discoverTokenEndpoint(issuer string) (string, error) {
+	discoveryURL := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	response, err := httpClient.Get(discoveryURL)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OpenID provider configuration %q returned status %d", discoveryURL, response.StatusCode)
+	}
+
+	providerConfig := struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&providerConfig); err != nil {
+		return "", fmt.Errorf("failed to parse OpenID provider configuration %q: %w", discoveryURL, err)
+	}
+
+	if len(providerConfig.TokenEndpoint) == 0 {
+		return "", fmt.Errorf("OpenID provider configuration %q does not contain a token endpoint", discoveryURL)
+	}
+	return providerConfig.TokenEndpoint, nil
+}
+
+func valueOrDefault(value, defaultValue string) string {
+	if len(value) > 0 {
+		return value
+	}
+	return defaultValue
+}
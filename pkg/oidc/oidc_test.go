@@ -0,0 +1,148 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/oidc"
+)
+
+func newJWT(expiry time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp": %d}`, expiry.Unix())))
+	return fmt.Sprintf("eyJhbGciOiJub25lIn0.%s.signature", payload)
+}
+
+func newKubeconfig(issuer, idToken, refreshToken string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: ctx
+clusters:
+- name: cluster
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: ctx
+  context:
+    cluster: cluster
+    user: user
+users:
+- name: user
+  user:
+    auth-provider:
+      name: oidc
+      config:
+        idp-issuer-url: %s
+        client-id: kubeswitch
+        id-token: %s
+        refresh-token: %s
+`, issuer, idToken, refreshToken))
+}
+
+var _ = Describe("RefreshOIDCToken", func() {
+	var (
+		server        *httptest.Server
+		refreshCalls  atomic.Int32
+		refreshedJWT  string
+		refreshTokens []string
+		lock          sync.Mutex
+	)
+
+	BeforeEach(func() {
+		refreshCalls.Store(0)
+		refreshTokens = nil
+		refreshedJWT = newJWT(time.Now().Add(time.Hour))
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/.well-known/openid-configuration":
+				_ = json.NewEncoder(w).Encode(map[string]string{"token_endpoint": server.URL + "/token"})
+			case "/token":
+				refreshCalls.Add(1)
+				Expect(r.ParseForm()).To(Succeed())
+				lock.Lock()
+				refreshTokens = append(refreshTokens, r.PostForm.Get("refresh_token"))
+				lock.Unlock()
+				Expect(r.PostForm.Get("grant_type")).To(Equal("refresh_token"))
+				Expect(r.PostForm.Get("client_id")).To(Equal("kubeswitch"))
+				_ = json.NewEncoder(w).Encode(map[string]string{"id_token": refreshedJWT, "refresh_token": "new-refresh-token"})
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should not modify a kubeconfig with a valid id token", func() {
+		kubeconfig := newKubeconfig(server.URL, newJWT(time.Now().Add(time.Hour)), "refresh-token")
+
+		result, err := oidc.RefreshOIDCToken(kubeconfig, "", "", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(kubeconfig))
+		Expect(refreshCalls.Load()).To(BeZero())
+	})
+
+	It("should refresh an expired id token", func() {
+		kubeconfig := newKubeconfig(server.URL, newJWT(time.Now().Add(-time.Hour)), "refresh-token-1")
+
+		result, err := oidc.RefreshOIDCToken(kubeconfig, "", "", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		config, err := clientcmd.Load(result)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.AuthInfos["user"].AuthProvider.Config).To(HaveKeyWithValue("id-token", refreshedJWT))
+		Expect(config.AuthInfos["user"].AuthProvider.Config).To(HaveKeyWithValue("refresh-token", "new-refresh-token"))
+		Expect(refreshTokens).To(ConsistOf("refresh-token-1"))
+	})
+
+	It("should use the refresh token only once when called concurrently", func() {
+		kubeconfig := newKubeconfig(server.URL, newJWT(time.Now().Add(-time.Hour)), "refresh-token-2")
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := oidc.RefreshOIDCToken(kubeconfig, "", "", "")
+				Expect(err).ToNot(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+
+		Expect(refreshCalls.Load()).To(Equal(int32(1)))
+	})
+
+	It("should fail if the id token is expired and there is no refresh token", func() {
+		kubeconfig := newKubeconfig(server.URL, newJWT(time.Now().Add(-time.Hour)), "")
+
+		_, err := oidc.RefreshOIDCToken(kubeconfig, "", "", "")
+		Expect(err).To(HaveOccurred())
+	})
+})
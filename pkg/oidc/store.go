@@ -0,0 +1,99 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"context"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// NewRefreshingStore wraps the store to refresh expired OIDC id tokens in the kubeconfigs returned by the store
+func NewRefreshingStore(upstream store.KubeconfigStore) store.KubeconfigStore {
+	return &refreshingStore{
+		upstream: upstream,
+	}
+}
+
+type refreshingStore struct {
+	upstream store.KubeconfigStore
+}
+
+// GetKubeconfigForPath implements the store.KubeconfigStore interface.
+// It intercepts calls to GetKubeconfigForPath and refreshes expired OIDC id tokens.
+func (s *refreshingStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	kubeconfig, err := s.upstream.GetKubeconfigForPath(ctx, path, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	oidcConfig := s.upstream.GetStoreConfig().OIDC
+	if oidcConfig == nil {
+		oidcConfig = &types.OIDCConfig{}
+	}
+	return RefreshOIDCToken(kubeconfig, oidcConfig.Issuer, oidcConfig.ClientID, oidcConfig.ClientSecret)
+}
+
+func (s *refreshingStore) GetID() string {
+	return s.upstream.GetID()
+}
+
+func (s *refreshingStore) GetKind() types.StoreKind {
+	return s.upstream.GetKind()
+}
+
+func (s *refreshingStore) GetContextPrefix(path string) string {
+	return s.upstream.GetContextPrefix(path)
+}
+
+func (s *refreshingStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	return s.upstream.VerifyKubeconfigPaths(ctx)
+}
+
+func (s *refreshingStore) Probe(ctx context.Context) error {
+	return s.upstream.Probe(ctx)
+}
+
+func (s *refreshingStore) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	s.upstream.StartSearch(ctx, channel)
+}
+
+func (s *refreshingStore) GetLogger() *logrus.Entry {
+	return s.upstream.GetLogger()
+}
+
+func (s *refreshingStore) Stop(ctx context.Context) error {
+	return s.upstream.Stop(ctx)
+}
+
+func (s *refreshingStore) GetStoreConfig() types.KubeconfigStore {
+	return s.upstream.GetStoreConfig()
+}
+
+func (s *refreshingStore) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
+	previewer, ok := s.upstream.(store.Previewer)
+	if !ok {
+		// if the wrapped store is not a previewer, simply return an empty string, hence causing no visual distortion
+		return "", nil
+	}
+
+	return previewer.GetSearchPreview(path, optionalTags)
+}
+
+func (s *refreshingStore) Unwrap() store.KubeconfigStore {
+	return s.upstream
+}
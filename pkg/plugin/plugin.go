@@ -0,0 +1,453 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+)
+
+const (
+	// BinaryPrefix is the prefix of the binary name of every store plugin
+	BinaryPrefix = "kubeswitch-store-"
+	// DefaultDirectory is the directory plugins are installed to
+	DefaultDirectory = "$HOME/.kube/switch-plugins"
+	// DefaultRegistryPath is the path of the registry of available plugins
+	DefaultRegistryPath = "$HOME/.kube/switch-plugins.yaml"
+	// DefaultGitHubAPIURL is the URL of the GitHub API used to look up the latest release of a plugin
+	DefaultGitHubAPIURL = "https://api.github.com"
+
+	// installedFile is the file in the plugin directory recording the installed plugins
+	installedFile = "installed.yaml"
+	// maxBinarySize is the maximum size of a downloaded plugin binary
+	maxBinarySize = 256 << 20
+)
+
+var (
+	// ErrNotInstalled is returned if a plugin is not installed
+	ErrNotInstalled = errors.New("plugin is not installed")
+
+	validName     = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	gitHubRepoURL = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+?)/?$`)
+)
+
+// Registry lists the plugins available for installation by name
+type Registry struct {
+	Plugins []RegistryPlugin `yaml:"plugins"`
+}
+
+// RegistryPlugin is the latest version of a plugin in the registry
+type RegistryPlugin struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	// Platforms contains the binary for every supported platform
+	Platforms []Platform `yaml:"platforms"`
+}
+
+// Platform is the plugin binary for an operating system and architecture
+type Platform struct {
+	OS   string `yaml:"os"`
+	Arch string `yaml:"arch"`
+	URL  string `yaml:"url"`
+	// SHA256 is the hex encoded checksum of the binary
+	SHA256 string `yaml:"sha256"`
+}
+
+// Installed is an installed plugin
+type Installed struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	// Source is the name in the registry or the URL of the GitHub repository the plugin was installed from
+	Source      string    `yaml:"source"`
+	SHA256      string    `yaml:"sha256"`
+	InstalledAt time.Time `yaml:"installedAt"`
+}
+
+// release is a version of a plugin that can be downloaded
+type release struct {
+	name    string
+	version string
+	source  string
+	url     string
+	sha256  string
+}
+
+// Manager installs plugins to a directory
+type Manager struct {
+	// Directory is the directory plugins are installed to
+	Directory string
+	// RegistryPath is the path of the registry file. The registry is optional.
+	RegistryPath string
+	GitHubAPIURL string
+	HTTPClient   *http.Client
+	// OS and Arch select the plugin binary. Default to the platform kubeswitch runs on.
+	OS   string
+	Arch string
+}
+
+// NewManager creates a manager for the given plugin directory and registry
+func NewManager(directory, registryPath string) *Manager {
+	return &Manager{
+		Directory:    directory,
+		RegistryPath: registryPath,
+		GitHubAPIURL: DefaultGitHubAPIURL,
+		HTTPClient:   &http.Client{Timeout: 5 * time.Minute},
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+	}
+}
+
+// BinaryName returns the name of the binary of the plugin with the given name
+func BinaryName(name, goos string) string {
+	if goos == "windows" {
+		return BinaryPrefix + name + ".exe"
+	}
+	return BinaryPrefix + name
+}
+
+// Install installs the latest version of the plugin with the given name in the registry
+// or from the latest release of the given GitHub repository (https://github.com/<owner>/kubeswitch-store-<name>).
+// An installed version of the plugin is replaced.
+func (m *Manager) Install(source string) (*Installed, error) {
+	r, err := m.resolve(source)
+	if err != nil {
+		return nil, err
+	}
+	return m.install(r)
+}
+
+// Update installs the latest version of the installed plugin from the source it was installed from.
+// Returns false if the installed version is already the latest version.
+func (m *Manager) Update(name string) (*Installed, bool, error) {
+	installed, err := m.List()
+	if err != nil {
+		return nil, false, err
+	}
+
+	i := slices.IndexFunc(installed, func(p Installed) bool { return p.Name == name })
+	if i < 0 {
+		return nil, false, fmt.Errorf("%w: %q", ErrNotInstalled, name)
+	}
+
+	r, err := m.resolve(installed[i].Source)
+	if err != nil {
+		return nil, false, err
+	}
+	if r.version == installed[i].Version {
+		return &installed[i], false, nil
+	}
+
+	updated, err := m.install(r)
+	return updated, err == nil, err
+}
+
+// Remove uninstalls the plugin with the given name
+func (m *Manager) Remove(name string) error {
+	installed, err := m.List()
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(installed, func(p Installed) bool { return p.Name == name })
+	if i < 0 {
+		return fmt.Errorf("%w: %q", ErrNotInstalled, name)
+	}
+
+	if err := kubeswitchio.Remove(filepath.Join(m.Directory, BinaryName(name, m.OS))); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove plugin %q: %w", name, err)
+	}
+	return m.writeInstalled(slices.Delete(installed, i, i+1))
+}
+
+// List returns the installed plugins ordered by name
+func (m *Manager) List() ([]Installed, error) {
+	data, err := os.ReadFile(filepath.Join(m.Directory, installedFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var installed []Installed
+	if err := yaml.Unmarshal(data, &installed); err != nil {
+		return nil, fmt.Errorf("failed to parse installed plugins: %w", err)
+	}
+	return installed, nil
+}
+
+// AddToPath prepends the plugin directory to the PATH of the current process,
+// so that installed plugins are found like plugins installed on the PATH
+func AddToPath(directory string) error {
+	if _, err := os.Stat(directory); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	path := os.Getenv("PATH")
+	if slices.Contains(filepath.SplitList(path), directory) {
+		return nil
+	}
+	if len(path) == 0 {
+		return os.Setenv("PATH", directory)
+	}
+	return os.Setenv("PATH", directory+string(os.PathListSeparator)+path)
+}
+
+// resolve returns the latest release of the plugin with the given source
+func (m *Manager) resolve(source string) (*release, error) {
+	if match := gitHubRepoURL.FindStringSubmatch(source); match != nil {
+		return m.resolveGitHubRelease(source, match[1], match[2])
+	}
+	if strings.Contains(source, "://") {
+		return nil, fmt.Errorf("unsupported plugin source %q: must be the name of a plugin in the registry or a GitHub repository URL", source)
+	}
+	return m.resolveFromRegistry(source)
+}
+
+func (m *Manager) resolveFromRegistry(name string) (*release, error) {
+	data, err := os.ReadFile(m.RegistryPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("plugin %q not found: the plugin registry %q does not exist", name, m.RegistryPath)
+		}
+		return nil, err
+	}
+
+	registry := Registry{}
+	if err := yaml.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin registry %q: %w", m.RegistryPath, err)
+	}
+
+	i := slices.IndexFunc(registry.Plugins, func(p RegistryPlugin) bool { return p.Name == name })
+	if i < 0 {
+		return nil, fmt.Errorf("plugin %q not found in the plugin registry %q", name, m.RegistryPath)
+	}
+	p := registry.Plugins[i]
+
+	j := slices.IndexFunc(p.Platforms, func(platform Platform) bool { return platform.OS == m.OS && platform.Arch == m.Arch })
+	if j < 0 {
+		return nil, fmt.Errorf("plugin %q is not available for %s/%s", name, m.OS, m.Arch)
+	}
+
+	return &release{
+		name:    p.Name,
+		version: p.Version,
+		source:  name,
+		url:     p.Platforms[j].URL,
+		sha256:  p.Platforms[j].SHA256,
+	}, nil
+}
+
+// gitHubRelease is the subset of a release returned by the GitHub API
+type gitHubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// resolveGitHubRelease returns the latest release of the GitHub repository.
+// The release must contain the binary named kubeswitch-store-<name>_<os>_<arch> and a checksums.txt file
+// with the SHA256 checksums of the binaries in the format of sha256sum.
+func (m *Manager) resolveGitHubRelease(source, owner, repo string) (*release, error) {
+	name := strings.TrimPrefix(repo, BinaryPrefix)
+	if name == repo {
+		return nil, fmt.Errorf("the name of the GitHub repository %q must start with %q", repo, BinaryPrefix)
+	}
+
+	data, err := m.get(fmt.Sprintf("%s/repos/%s/%s/releases/latest", strings.TrimSuffix(m.GitHubAPIURL, "/"), owner, repo), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the latest release of %q: %w", source, err)
+	}
+
+	latest := gitHubRelease{}
+	if err := json.Unmarshal(data, &latest); err != nil {
+		return nil, fmt.Errorf("failed to parse the latest release of %q: %w", source, err)
+	}
+
+	binary := fmt.Sprintf("%s%s_%s_%s", BinaryPrefix, name, m.OS, m.Arch)
+	if m.OS == "windows" {
+		binary += ".exe"
+	}
+
+	var binaryURL, checksumsURL string
+	for _, asset := range latest.Assets {
+		switch {
+		case asset.Name == binary:
+			binaryURL = asset.BrowserDownloadURL
+		case asset.Name == "checksums.txt" || strings.HasSuffix(asset.Name, "_checksums.txt"):
+			checksumsURL = asset.BrowserDownloadURL
+		}
+	}
+	if len(binaryURL) == 0 {
+		return nil, fmt.Errorf("release %s of %q does not contain the binary %q", latest.TagName, source, binary)
+	}
+	if len(checksumsURL) == 0 {
+		return nil, fmt.Errorf("release %s of %q does not contain a checksums.txt file", latest.TagName, source)
+	}
+
+	checksums, err := m.get(checksumsURL, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the checksums of release %s of %q: %w", latest.TagName, source, err)
+	}
+	checksum, err := findChecksum(checksums, binary)
+	if err != nil {
+		return nil, fmt.Errorf("release %s of %q: %w", latest.TagName, source, err)
+	}
+
+	return &release{
+		name:    name,
+		version: latest.TagName,
+		source:  source,
+		url:     binaryURL,
+		sha256:  checksum,
+	}, nil
+}
+
+// findChecksum returns the checksum of the file from a checksum file in the format of sha256sum
+func findChecksum(checksums []byte, file string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		// sha256sum marks files read in binary mode with a leading '*'
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == file {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no checksum found for %q", file)
+}
+
+// install downloads the binary of the release, verifies its checksum and records the installed version
+func (m *Manager) install(r *release) (*Installed, error) {
+	if !validName.MatchString(r.name) {
+		return nil, fmt.Errorf("invalid plugin name %q", r.name)
+	}
+	if len(r.sha256) == 0 {
+		return nil, fmt.Errorf("no SHA256 checksum configured for plugin %q", r.name)
+	}
+
+	data, err := m.get(r.url, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download plugin %q: %w", r.name, err)
+	}
+
+	sum := sha256.Sum256(data)
+	if checksum := hex.EncodeToString(sum[:]); !strings.EqualFold(checksum, r.sha256) {
+		return nil, fmt.Errorf("checksum mismatch for plugin %q: expected %s, got %s", r.name, r.sha256, checksum)
+	}
+
+	if err := kubeswitchio.MkdirAll(m.Directory, 0755); err != nil {
+		return nil, err
+	}
+
+	// write to a temporary file first, so that an installed version is never left partially written
+	tmp, err := kubeswitchio.CreateTemp(m.Directory, ".download-*", data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := kubeswitchio.Chmod(tmp, 0755); err != nil {
+		_ = kubeswitchio.Remove(tmp)
+		return nil, err
+	}
+	if err := kubeswitchio.Rename(tmp, filepath.Join(m.Directory, BinaryName(r.name, m.OS))); err != nil {
+		_ = kubeswitchio.Remove(tmp)
+		return nil, fmt.Errorf("failed to install plugin %q: %w", r.name, err)
+	}
+
+	installed, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+
+	plugin := Installed{
+		Name:        r.name,
+		Version:     r.version,
+		Source:      r.source,
+		SHA256:      strings.ToLower(r.sha256),
+		InstalledAt: time.Now().UTC(),
+	}
+	installed = slices.DeleteFunc(installed, func(p Installed) bool { return p.Name == r.name })
+	installed = append(installed, plugin)
+	if err := m.writeInstalled(installed); err != nil {
+		return nil, err
+	}
+	return &plugin, nil
+}
+
+func (m *Manager) writeInstalled(installed []Installed) error {
+	slices.SortFunc(installed, func(a, b Installed) int { return strings.Compare(a.Name, b.Name) })
+
+	data, err := yaml.Marshal(installed)
+	if err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(filepath.Join(m.Directory, installedFile), data, 0644)
+}
+
+// get downloads the given URL. Requests to the GitHub API are authenticated with GITHUB_TOKEN if set.
+func (m *Manager) get(url string, gitHubAPI bool) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if gitHubAPI {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token := os.Getenv("GITHUB_TOKEN"); len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := m.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, url)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBinarySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBinarySize {
+		return nil, fmt.Errorf("response from %s exceeds the maximum size of %d bytes", url, maxBinarySize)
+	}
+	return data, nil
+}
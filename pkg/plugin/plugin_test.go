@@ -0,0 +1,184 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/plugin"
+)
+
+func checksum(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+var _ = Describe("Manager", func() {
+	var (
+		server     *httptest.Server
+		manager    *plugin.Manager
+		directory  string
+		binaries   map[string]string
+		latestTag  string
+		binaryPath string
+	)
+
+	BeforeEach(func() {
+		binaries = map[string]string{
+			"/download/kubeswitch-store-foo_linux_amd64": "foo v1",
+		}
+		latestTag = "v1.0.0"
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/acme/kubeswitch-store-foo/releases/latest":
+				fmt.Fprintf(w, `{"tag_name": %q, "assets": [
+					{"name": "kubeswitch-store-foo_linux_amd64", "browser_download_url": "%s/download/kubeswitch-store-foo_linux_amd64"},
+					{"name": "checksums.txt", "browser_download_url": "%s/download/checksums.txt"}
+				]}`, latestTag, server.URL, server.URL)
+			case "/download/checksums.txt":
+				fmt.Fprintf(w, "%s  kubeswitch-store-foo_linux_amd64\n", checksum(binaries["/download/kubeswitch-store-foo_linux_amd64"]))
+			default:
+				data, ok := binaries[r.URL.Path]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprint(w, data)
+			}
+		}))
+
+		tmp, err := os.MkdirTemp("", "plugins")
+		Expect(err).ToNot(HaveOccurred())
+		directory = filepath.Join(tmp, "switch-plugins")
+		binaryPath = filepath.Join(directory, "kubeswitch-store-foo")
+
+		manager = plugin.NewManager(directory, filepath.Join(tmp, "switch-plugins.yaml"))
+		manager.GitHubAPIURL = server.URL
+		manager.OS = "linux"
+		manager.Arch = "amd64"
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(filepath.Dir(directory))).To(Succeed())
+	})
+
+	writeRegistry := func(sha string) {
+		Expect(os.WriteFile(manager.RegistryPath, []byte(fmt.Sprintf(`plugins:
+- name: bar
+  version: v0.1.0
+  platforms:
+  - os: linux
+    arch: amd64
+    url: %s/download/bar
+    sha256: %s
+`, server.URL, sha)), 0600)).To(Succeed())
+	}
+
+	It("should install a plugin from the latest GitHub release", func() {
+		installed, err := manager.Install("https://github.com/acme/kubeswitch-store-foo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(installed.Name).To(Equal("foo"))
+		Expect(installed.Version).To(Equal("v1.0.0"))
+
+		data, err := os.ReadFile(binaryPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("foo v1"))
+
+		info, err := os.Stat(binaryPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm() & 0100).ToNot(BeZero())
+
+		list, err := manager.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(list).To(HaveLen(1))
+		Expect(list[0].Source).To(Equal("https://github.com/acme/kubeswitch-store-foo"))
+	})
+
+	It("should install a plugin from the registry", func() {
+		binaries["/download/bar"] = "bar"
+		writeRegistry(checksum("bar"))
+
+		installed, err := manager.Install("bar")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(installed.Version).To(Equal("v0.1.0"))
+		Expect(filepath.Join(directory, "kubeswitch-store-bar")).To(BeAnExistingFile())
+	})
+
+	It("should reject binaries with a wrong checksum", func() {
+		binaries["/download/bar"] = "tampered"
+		writeRegistry(checksum("bar"))
+
+		_, err := manager.Install("bar")
+		Expect(err).To(MatchError(ContainSubstring("checksum mismatch")))
+		Expect(filepath.Join(directory, "kubeswitch-store-bar")).ToNot(BeAnExistingFile())
+	})
+
+	It("should reject unknown plugins and sources", func() {
+		writeRegistry(checksum("bar"))
+
+		_, err := manager.Install("unknown")
+		Expect(err).To(HaveOccurred())
+		_, err = manager.Install("https://example.com/kubeswitch-store-foo")
+		Expect(err).To(HaveOccurred())
+		_, err = manager.Install("https://github.com/acme/foo")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should update a plugin to the latest release", func() {
+		_, err := manager.Install("https://github.com/acme/kubeswitch-store-foo")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, updated, err := manager.Update("foo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated).To(BeFalse())
+
+		latestTag = "v2.0.0"
+		binaries["/download/kubeswitch-store-foo_linux_amd64"] = "foo v2"
+
+		installed, updated, err := manager.Update("foo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(updated).To(BeTrue())
+		Expect(installed.Version).To(Equal("v2.0.0"))
+
+		data, err := os.ReadFile(binaryPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal("foo v2"))
+	})
+
+	It("should remove a plugin", func() {
+		_, err := manager.Install("https://github.com/acme/kubeswitch-store-foo")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(manager.Remove("foo")).To(Succeed())
+		Expect(binaryPath).ToNot(BeAnExistingFile())
+
+		list, err := manager.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(list).To(BeEmpty())
+
+		Expect(manager.Remove("foo")).To(MatchError(plugin.ErrNotInstalled))
+	})
+})
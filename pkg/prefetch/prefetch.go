@@ -0,0 +1,222 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prefetch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+)
+
+const (
+	// DefaultRadius is the number of entries above and below the cursor that are prefetched
+	DefaultRadius = 3
+	// DefaultCacheSize is the maximum number of previews kept in the cache
+	DefaultCacheSize = 20
+	// DefaultWorkers is the number of goroutines fetching previews in the background
+	DefaultWorkers = 4
+	// DefaultTTL is the duration after which an unused preview is evicted from the cache
+	DefaultTTL = 30 * time.Second
+)
+
+// Entry is an entry of the picker whose store-specific preview can be fetched
+type Entry struct {
+	// Key identifies the preview in the cache
+	Key       string
+	Previewer store.Previewer
+	Path      string
+	Tags      map[string]string
+}
+
+// Resolver returns the entry shown at the given index of the picker.
+// Returns nil if there is no entry at the index or it has no store-specific preview.
+type Resolver func(index int) *Entry
+
+// Prefetcher speculatively fetches the store-specific previews of the entries around the cursor of the picker,
+// so that the preview can be shown without waiting for the store once the cursor is moved.
+type Prefetcher struct {
+	resolve Resolver
+	radius  int
+	ttl     time.Duration
+	// now returns the current time, can be replaced in tests
+	now func() time.Time
+
+	lock  sync.Mutex
+	cache *simplelru.LRU
+	// inFlight contains a channel for each preview currently fetched, closed when the fetch is done
+	inFlight map[string]chan struct{}
+
+	jobs chan Entry
+	stop chan struct{}
+	once sync.Once
+}
+
+type cachedPreview struct {
+	preview  string
+	lastUsed time.Time
+}
+
+// New creates a prefetcher with the given cache size, number of workers and time after which unused previews are evicted.
+// The workers are started immediately and run until Stop is called.
+func New(resolve Resolver, cacheSize, workers int, ttl time.Duration) *Prefetcher {
+	// the size is always positive, so no error can occur
+	cache, _ := simplelru.NewLRU(max(cacheSize, 1), nil)
+
+	p := &Prefetcher{
+		resolve:  resolve,
+		radius:   DefaultRadius,
+		ttl:      ttl,
+		now:      time.Now,
+		cache:    cache,
+		inFlight: make(map[string]chan struct{}),
+		jobs:     make(chan Entry, 2*DefaultRadius),
+		stop:     make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Stop stops the workers. Previews already being fetched are still added to the cache.
+func (p *Prefetcher) Stop() {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+}
+
+// GetSearchPreview returns the preview of the given entry shown at the given index of the picker.
+// The preview is served from the cache if it was prefetched, otherwise it is fetched from the store.
+// The entries around the index are prefetched.
+func (p *Prefetcher) GetSearchPreview(index int, entry Entry) (string, error) {
+	p.Observe(index)
+
+	for {
+		p.lock.Lock()
+		if preview, ok := p.getCached(entry.Key); ok {
+			p.lock.Unlock()
+			return preview, nil
+		}
+
+		done, ok := p.inFlight[entry.Key]
+		if !ok {
+			p.inFlight[entry.Key] = make(chan struct{})
+			p.lock.Unlock()
+			return p.fetch(entry)
+		}
+		p.lock.Unlock()
+
+		// wait for the prefetch of the same preview instead of calling the store twice.
+		// If the prefetch failed, the preview is fetched again.
+		<-done
+	}
+}
+
+// Observe notifies the prefetcher about the cursor moving to the given index.
+// The previews of the entries around the index that are neither cached nor already fetched are queued for prefetching.
+func (p *Prefetcher) Observe(index int) {
+	p.lock.Lock()
+	p.evictExpired()
+	p.lock.Unlock()
+
+	// prefetch the closest entries first
+	for distance := 1; distance <= p.radius; distance++ {
+		for _, i := range []int{index + distance, index - distance} {
+			if i < 0 {
+				continue
+			}
+			if entry := p.resolve(i); entry != nil {
+				p.enqueue(*entry)
+			}
+		}
+	}
+}
+
+func (p *Prefetcher) enqueue(entry Entry) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, ok := p.inFlight[entry.Key]; ok || p.cache.Contains(entry.Key) {
+		return
+	}
+
+	select {
+	case p.jobs <- entry:
+		p.inFlight[entry.Key] = make(chan struct{})
+	default:
+		// all workers are busy: skip the entry instead of blocking the picker.
+		// It is prefetched when the cursor moves again.
+	}
+}
+
+func (p *Prefetcher) work() {
+	for {
+		select {
+		case <-p.stop:
+			return
+		case entry := <-p.jobs:
+			_, _ = p.fetch(entry)
+		}
+	}
+}
+
+// fetch fetches the preview of the entry from the store and adds it to the cache.
+// The entry must have been marked as in flight by the caller.
+func (p *Prefetcher) fetch(entry Entry) (string, error) {
+	preview, err := entry.Previewer.GetSearchPreview(entry.Path, entry.Tags)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// errors are not cached, so that the preview is fetched again when requested
+	if err == nil {
+		p.cache.Add(entry.Key, &cachedPreview{preview: preview, lastUsed: p.now()})
+	}
+
+	close(p.inFlight[entry.Key])
+	delete(p.inFlight, entry.Key)
+	return preview, err
+}
+
+// getCached returns the cached preview and marks it as used.
+// Must be called with the lock held.
+func (p *Prefetcher) getCached(key string) (string, bool) {
+	p.evictExpired()
+
+	value, ok := p.cache.Get(key)
+	if !ok {
+		return "", false
+	}
+	cached := value.(*cachedPreview)
+	cached.lastUsed = p.now()
+	return cached.preview, true
+}
+
+// evictExpired removes the previews from the cache that have not been used within the TTL.
+// As the cache is ordered by the last usage, only the oldest entries have to be checked.
+// Must be called with the lock held.
+func (p *Prefetcher) evictExpired() {
+	for {
+		_, value, ok := p.cache.GetOldest()
+		if !ok || p.now().Sub(value.(*cachedPreview).lastUsed) <= p.ttl {
+			return
+		}
+		p.cache.RemoveOldest()
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prefetch
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakePreviewer counts the calls per path
+type fakePreviewer struct {
+	lock  sync.Mutex
+	calls map[string]int
+}
+
+func (f *fakePreviewer) GetSearchPreview(path string, _ map[string]string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.calls[path]++
+	return "preview of " + path, nil
+}
+
+func (f *fakePreviewer) callsFor(path string) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.calls[path]
+}
+
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+var _ = Describe("Prefetcher", func() {
+	var (
+		previewer  *fakePreviewer
+		prefetcher *Prefetcher
+		clock      *fakeClock
+	)
+
+	entry := func(i int) Entry {
+		path := fmt.Sprintf("path-%d", i)
+		return Entry{Key: path, Previewer: previewer, Path: path}
+	}
+
+	BeforeEach(func() {
+		previewer = &fakePreviewer{calls: map[string]int{}}
+		clock = &fakeClock{now: time.Now()}
+		prefetcher = New(func(i int) *Entry {
+			if i >= 10 {
+				return nil
+			}
+			e := entry(i)
+			return &e
+		}, DefaultCacheSize, DefaultWorkers, DefaultTTL)
+		prefetcher.now = clock.Now
+	})
+
+	AfterEach(func() {
+		prefetcher.Stop()
+	})
+
+	It("should prefetch the entries around the cursor", func() {
+		preview, err := prefetcher.GetSearchPreview(5, entry(5))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview).To(Equal("preview of path-5"))
+
+		for _, i := range []int{2, 3, 4, 6, 7, 8} {
+			Eventually(func() int { return previewer.callsFor(fmt.Sprintf("path-%d", i)) }).Should(Equal(1))
+		}
+		Consistently(func() int { return previewer.callsFor("path-1") }, 100*time.Millisecond).Should(BeZero())
+		Expect(previewer.callsFor("path-9")).To(BeZero())
+	})
+
+	It("should serve prefetched previews from the cache", func() {
+		prefetcher.Observe(0)
+		Eventually(func() int { return previewer.callsFor("path-3") }).Should(Equal(1))
+
+		preview, err := prefetcher.GetSearchPreview(3, entry(3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview).To(Equal("preview of path-3"))
+		Expect(previewer.callsFor("path-3")).To(Equal(1))
+	})
+
+	It("should ignore positions without entries", func() {
+		prefetcher.Observe(9)
+		Eventually(func() int { return previewer.callsFor("path-6") }).Should(Equal(1))
+		Consistently(func() int { return previewer.callsFor("path-10") }, 100*time.Millisecond).Should(BeZero())
+	})
+
+	It("should evict previews not used within the TTL", func() {
+		_, err := prefetcher.GetSearchPreview(0, entry(0))
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func() int { return previewer.callsFor("path-3") }).Should(Equal(1))
+
+		clock.Advance(DefaultTTL + time.Second)
+
+		_, err = prefetcher.GetSearchPreview(0, entry(0))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(previewer.callsFor("path-0")).To(Equal(2))
+		Eventually(func() int { return previewer.callsFor("path-3") }).Should(Equal(2))
+	})
+
+	It("should keep at most the configured number of previews", func() {
+		prefetcher.Stop()
+		prefetcher = New(func(int) *Entry { return nil }, 2, 0, DefaultTTL)
+
+		for i := 0; i < 3; i++ {
+			_, err := prefetcher.GetSearchPreview(i, entry(i))
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(prefetcher.cache.Len()).To(Equal(2))
+		Expect(prefetcher.cache.Contains("path-0")).To(BeFalse())
+	})
+})
@@ -0,0 +1,156 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultChecks are the permissions checked if no checks are configured.
+// "* *" checks for full access to all resources (cluster admin).
+var DefaultChecks = []string{
+	"get pods",
+	"list secrets",
+	"create pods/exec",
+	"create deployments.apps",
+	"delete namespaces",
+	"* *",
+}
+
+// Check is a permission to check in the form "<verb> <resource>[.<group>][/<subresource>]",
+// e.g. "create deployments.apps" or "create pods/exec"
+type Check struct {
+	Verb        string
+	Resource    string
+	Group       string
+	Subresource string
+}
+
+func (c Check) String() string {
+	s := fmt.Sprintf("%s %s", c.Verb, c.Resource)
+	if len(c.Group) > 0 {
+		s = fmt.Sprintf("%s.%s", s, c.Group)
+	}
+	if len(c.Subresource) > 0 {
+		s = fmt.Sprintf("%s/%s", s, c.Subresource)
+	}
+	return s
+}
+
+// Result is the result of a permission check
+type Result struct {
+	Check   Check
+	Allowed bool
+}
+
+// ParseCheck parses a permission check in the form "<verb> <resource>[.<group>][/<subresource>]"
+func ParseCheck(check string) (Check, error) {
+	fields := strings.Fields(check)
+	if len(fields) != 2 {
+		return Check{}, fmt.Errorf("invalid permission check %q: expected \"<verb> <resource>[.<group>][/<subresource>]\"", check)
+	}
+
+	c := Check{Verb: fields[0]}
+	resource, subresource, _ := strings.Cut(fields[1], "/")
+	c.Resource, c.Group, _ = strings.Cut(resource, ".")
+	c.Subresource = subresource
+
+	if len(c.Resource) == 0 {
+		return Check{}, fmt.Errorf("invalid permission check %q: the resource must not be empty", check)
+	}
+	return c, nil
+}
+
+// CheckAccess checks the permissions of the user of the given REST config in the namespace
+// using SelfSubjectAccessReviews. Returns an error if any of the checks fails.
+func CheckAccess(ctx context.Context, config *rest.Config, namespace string, checks []string) ([]Result, error) {
+	parsedChecks := make([]Check, 0, len(checks))
+	for _, check := range checks {
+		c, err := ParseCheck(check)
+		if err != nil {
+			return nil, err
+		}
+		parsedChecks = append(parsedChecks, c)
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		results = make([]Result, len(parsedChecks))
+		errs    = make([]error, len(parsedChecks))
+		wg      sync.WaitGroup
+	)
+	for i, check := range parsedChecks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace:   namespace,
+						Verb:        check.Verb,
+						Group:       check.Group,
+						Resource:    check.Resource,
+						Subresource: check.Subresource,
+					},
+				},
+			}
+
+			response, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to check permission %q: %w", check, err)
+				return
+			}
+			results[i] = Result{Check: check, Allowed: response.Status.Allowed}
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// FormatResults renders the results as a compact permission table
+func FormatResults(results []Result) string {
+	builder := strings.Builder{}
+	w := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PERMISSION\tALLOWED")
+	for _, result := range results {
+		allowed := "no"
+		if result.Allowed {
+			allowed = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", result.Check, allowed)
+	}
+	_ = w.Flush()
+	return builder.String()
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/rbac"
+)
+
+// newAuthorizationServer returns a server mocking the SelfSubjectAccessReview API
+// that only allows the given verb
+func newAuthorizationServer(allowedVerb string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		review := &authorizationv1.SelfSubjectAccessReview{}
+		if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		review.Status.Allowed = review.Spec.ResourceAttributes.Verb == allowedVerb
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(review)
+	}))
+}
+
+var _ = Describe("RBAC", func() {
+	Describe("ParseCheck", func() {
+		It("should parse a check of a core resource", func() {
+			Expect(rbac.ParseCheck("get pods")).To(Equal(rbac.Check{Verb: "get", Resource: "pods"}))
+		})
+
+		It("should parse a check with group and subresource", func() {
+			Expect(rbac.ParseCheck("create deployments.apps/scale")).To(Equal(rbac.Check{Verb: "create", Resource: "deployments", Group: "apps", Subresource: "scale"}))
+		})
+
+		It("should fail for an invalid check", func() {
+			_, err := rbac.ParseCheck("get")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CheckAccess", func() {
+		It("should check the permissions using SelfSubjectAccessReviews", func() {
+			server := newAuthorizationServer("get")
+			defer server.Close()
+
+			results, err := rbac.CheckAccess(context.Background(), &rest.Config{Host: server.URL}, "default", []string{"get pods", "delete namespaces"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(Equal([]rbac.Result{
+				{Check: rbac.Check{Verb: "get", Resource: "pods"}, Allowed: true},
+				{Check: rbac.Check{Verb: "delete", Resource: "namespaces"}, Allowed: false},
+			}))
+
+			Expect(rbac.FormatResults(results)).To(Equal("PERMISSION         ALLOWED\nget pods           yes\ndelete namespaces  no\n"))
+		})
+
+		It("should fail if the API is not available", func() {
+			server := httptest.NewServer(http.NotFoundHandler())
+			defer server.Close()
+
+			_, err := rbac.CheckAccess(context.Background(), &rest.Config{Host: server.URL}, "default", []string{"get pods"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -15,19 +15,31 @@
 package pkg
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
 	"github.com/danielfoehrkn/kubeswitch/pkg/index"
 	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
 	aliasstate "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/alias/state"
 	aliasutil "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/alias/util"
 	"github.com/danielfoehrkn/kubeswitch/pkg/util"
 	"github.com/danielfoehrkn/kubeswitch/types"
+	"github.com/hashicorp/go-multierror"
 	"github.com/sirupsen/logrus"
 )
 
+// PackageTag is the tag containing the package assigned by the store, so that the package is written to the index
+const PackageTag = "package"
+
 type DiscoveredContext struct {
 	// Path is the kubeconfig path in the backing store (filesystem / Vault)
 	Path string
@@ -35,18 +47,83 @@ type DiscoveredContext struct {
 	Name string
 	// Alias is a custom alias defined for this context name
 	Alias string
+	// PackageName is the logical cluster group (e.g. "ring-0") the context belongs to.
+	// Empty if the context does not belong to any package.
+	PackageName string
 	// Tags contains the additional metadata that the store wants to associate with a context name.
 	// This metadata is later handed over in the getKubeconfigForPath() function when retrieving the kubeconfig bytes for the path
 	Tags map[string]string
+	// Annotations contains the user-defined annotations of the context.
+	// They are also added to the Tags with the prefix annotations.TagPrefix.
+	Annotations map[string]string
 	// Store is a reference to the backing store that contains the kubeconfig
 	Store *store.KubeconfigStore
 	// Error is an error that occured during the search
 	Error error
 }
 
+// SearchOption configures the search of DoSearch
+type SearchOption func(*searchOptions)
+
+type searchOptions struct {
+	// pauseSearchTimeout pauses the search timeout of the stores while the caller does not read the search results
+	pauseSearchTimeout bool
+	// reportKubeconfigErrors returns kubeconfigs that cannot be retrieved as errors instead of skipping them
+	reportKubeconfigErrors bool
+	// reportIgnoredStoreErrors returns the ignored errors of stores as *IgnoredStoreError instead of dropping them
+	reportIgnoredStoreErrors bool
+}
+
+// IgnoredStoreError is an error of a store that is not required or whose errors are ignored (--ignore-store-errors).
+// The search results of the store are incomplete.
+type IgnoredStoreError struct {
+	StoreID string
+	Err     error
+}
+
+func (e *IgnoredStoreError) Error() string {
+	return fmt.Sprintf("ignored error of store %q: %v", e.StoreID, e.Err)
+}
+
+func (e *IgnoredStoreError) Unwrap() error {
+	return e.Err
+}
+
+// WithPausedSearchTimeout only counts the time the searched stores take to discover the search results towards their
+// search timeout, not the time waiting for the caller to read the search results.
+// Used for stores whose search pauses until the caller reads the next search results.
+func WithPausedSearchTimeout() SearchOption {
+	return func(o *searchOptions) {
+		o.pauseSearchTimeout = true
+	}
+}
+
+// WithKubeconfigErrors returns an error on the result channel for every kubeconfig that cannot be retrieved from its store.
+// By default, such kubeconfigs are skipped, as stores like Vault can list paths that do not contain a kubeconfig.
+// Paths the store reports not to contain a kubeconfig (*storeerrors.ErrKubeconfigNotFound) are always skipped.
+func WithKubeconfigErrors() SearchOption {
+	return func(o *searchOptions) {
+		o.reportKubeconfigErrors = true
+	}
+}
+
+// WithIgnoredStoreErrors returns an *IgnoredStoreError on the result channel for every error of a store that is ignored,
+// so that the search results of the store can be marked as incomplete.
+// By default, ignored errors are dropped.
+func WithIgnoredStoreErrors() SearchOption {
+	return func(o *searchOptions) {
+		o.reportIgnoredStoreErrors = true
+	}
+}
+
 // DoSearch executes a concurrent search over the given kubeconfig stores
 // returns results from all stores on the return channel
-func DoSearch(stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) (*chan DiscoveredContext, error) {
+func DoSearch(stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool, opts ...SearchOption) (*chan DiscoveredContext, error) {
+	options := searchOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	// Silence STDOUT during search to not interfere with the search selection screen
 	// restore after search is over
 	originalSTDOUT := os.Stdout
@@ -67,24 +144,62 @@ func DoSearch(stores []store.KubeconfigStore, config *types.Config, stateDir str
 		contextToAliasMapping = alias.Content.ContextToAliasMapping
 	}
 
+	// user-defined annotations are added to the tags of the discovered contexts
+	userAnnotations, err := annotations.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	ignoreStoreErrors := config.IgnoreStoreErrors != nil && *config.IgnoreStoreErrors
+	sanitizeContextNames := config.SanitizeContextNames != nil && *config.SanitizeContextNames
+
 	resultChannel := make(chan DiscoveredContext)
 	wgResultChannel := sync.WaitGroup{}
 	wgResultChannel.Add(len(stores))
 
 	for _, kubeconfigStore := range stores {
 		logger := kubeconfigStore.GetLogger()
+		searchStart := time.Now()
+
+		// the context is done once the search timeout of the store is exceeded or the search is finished.
+		// Cancelling the context aborts the search of the store.
+		ctx, cancel := context.WithCancel(context.Background())
+		var deadline *searchDeadline
+		if searchTimeout := kubeconfigStore.GetStoreConfig().SearchTimeout; searchTimeout != nil {
+			if options.pauseSearchTimeout {
+				deadline = newSearchDeadline(*searchTimeout, cancel)
+			} else {
+				ctx, cancel = context.WithTimeout(context.Background(), *searchTimeout)
+			}
+		}
 
-		if err := kubeconfigStore.VerifyKubeconfigPaths(); err != nil {
+		if err := kubeconfigStore.VerifyKubeconfigPaths(ctx); err != nil {
+			deadline.stop()
+			cancel()
 			// Required defines if errors when initializing this store should be logged
 			if kubeconfigStore.GetStoreConfig().Required != nil && !*kubeconfigStore.GetStoreConfig().Required {
+				go func(store store.KubeconfigStore) {
+					defer wgResultChannel.Done()
+					if options.reportIgnoredStoreErrors {
+						resultChannel <- DiscoveredContext{
+							Store: &store,
+							Error: &IgnoredStoreError{StoreID: store.GetID(), Err: err},
+						}
+					}
+				}(kubeconfigStore)
 				continue
 			}
 
 			return nil, err
 		}
 
+		// store specific exclusions are evaluated after the global exclusions
+		excludeContexts := append(slices.Clone(config.ExcludeContexts), kubeconfigStore.GetStoreConfig().ExcludeContexts...)
+
 		searchIndex, err := index.New(logger, kubeconfigStore.GetKind(), stateDir, kubeconfigStore.GetID())
 		if err != nil {
+			deadline.stop()
+			cancel()
 			return nil, err
 		}
 
@@ -95,16 +210,21 @@ func DoSearch(stores []store.KubeconfigStore, config *types.Config, stateDir str
 		} else {
 			readFromIndex, err = shouldReadFromIndex(searchIndex, kubeconfigStore, config)
 			if err != nil {
+				deadline.stop()
+				cancel()
 				return nil, err
 			}
 		}
 
 		if readFromIndex {
+			deadline.stop()
+			cancel()
 			logrus.Debugf("Reading from index for store %s with kind %s", kubeconfigStore.GetID(), kubeconfigStore.GetKind())
 
 			go func(store store.KubeconfigStore, index index.SearchIndex) {
 				// reading from this store is finished, decrease wait counter
 				defer wgResultChannel.Done()
+				defer recordSearchDuration(store.GetID(), searchStart)
 
 				// directly set from pre-computed index
 				content, tags := index.GetContent()
@@ -114,7 +234,7 @@ func DoSearch(stores []store.KubeconfigStore, config *types.Config, stateDir str
 						tagsForContextName = tagsForCtx
 					}
 
-					resultChannel <- DiscoveredContext{
+					discoveredContext := DiscoveredContext{
 						Path:  path,
 						Name:  contextName,
 						Tags:  tagsForContextName,
@@ -122,6 +242,15 @@ func DoSearch(stores []store.KubeconfigStore, config *types.Config, stateDir str
 						Store: &store,
 						Error: nil,
 					}
+					if isContextExcluded(discoveredContext, store.GetContextPrefix(path), excludeContexts) || !matchesStoreTags(discoveredContext) {
+						continue
+					}
+					assignPackage(&discoveredContext, store.GetContextPrefix(path))
+					if sanitizeContextNames {
+						sanitizeContextName(&discoveredContext, store.GetContextPrefix(path))
+					}
+					annotateContext(&discoveredContext, userAnnotations)
+					resultChannel <- discoveredContext
 				}
 			}(kubeconfigStore, *searchIndex)
 
@@ -130,12 +259,38 @@ func DoSearch(stores []store.KubeconfigStore, config *types.Config, stateDir str
 
 		// otherwise, we need to query the backing store for the kubeconfig files
 		c := make(chan store.SearchResult)
-		go func(store store.KubeconfigStore, channel chan store.SearchResult) {
-			// only close when directory search is over, otherwise send on closed resultChannel
-			defer close(channel)
-			store.GetLogger().Debugf("Starting search for store: %s", store.GetKind())
-			store.StartSearch(channel)
-		}(kubeconfigStore, c)
+
+		// the context names of unchanged kubeconfig paths are taken from the index when refreshing the index incrementally
+		var indexedPathToContexts map[string][]string
+		incrementalRefresh := !noIndex && kubeconfigStore.GetStoreConfig().IncrementalIndexRefresh && searchIndex.HasKind(kubeconfigStore.GetKind())
+		if incrementalRefresh {
+			var indexedResults []store.SearchResult
+			indexedResults, indexedPathToContexts = searchIndex.GetSearchResults()
+
+			go func(kubeconfigStore store.KubeconfigStore, channel chan store.SearchResult, searchIndex *index.SearchIndex) {
+				defer close(channel)
+				kubeconfigStore.GetLogger().Debugf("Starting incremental index refresh for store: %s", kubeconfigStore.GetKind())
+				results, err := searchIndex.IncrementalRefresh(ctx, kubeconfigStore, indexedResults)
+				if err != nil {
+					channel <- store.SearchResult{Error: err}
+					return
+				}
+				for _, result := range results {
+					select {
+					case <-ctx.Done():
+						return
+					case channel <- result:
+					}
+				}
+			}(kubeconfigStore, c, searchIndex)
+		} else {
+			go func(store store.KubeconfigStore, channel chan store.SearchResult) {
+				// only close when directory search is over, otherwise send on closed resultChannel
+				defer close(channel)
+				store.GetLogger().Debugf("Starting search for store: %s", store.GetKind())
+				store.StartSearch(ctx, channel)
+			}(kubeconfigStore, c)
+		}
 
 		go func(store store.KubeconfigStore, storeSearchChannel chan store.SearchResult, index index.SearchIndex) {
 			// remember the context to kubeconfig path mapping for this store
@@ -146,64 +301,168 @@ func DoSearch(stores []store.KubeconfigStore, config *types.Config, stateDir str
 			// also written to the index file
 			localContextToTagsMapping := make(map[string]map[string]string)
 
-			for channelResult := range storeSearchChannel {
-				if channelResult.Error != nil {
-					// Required defines if errors when initializing this store should be logged
-					if store.GetStoreConfig().Required != nil && !*store.GetStoreConfig().Required {
+			// aborts the search of the store once the search is over
+			defer cancel()
+			defer deadline.stop()
+
+			// the search timeout is paused while waiting for the caller to read the search result
+			send := func(discoveredContext DiscoveredContext) {
+				deadline.pause()
+				resultChannel <- discoveredContext
+				deadline.resume()
+			}
+
+			sendDiscoveredContext := func(discoveredContext DiscoveredContext) {
+				prefix := store.GetContextPrefix(discoveredContext.Path)
+				if isContextExcluded(discoveredContext, prefix, excludeContexts) || !matchesStoreTags(discoveredContext) {
+					return
+				}
+				assignPackage(&discoveredContext, prefix)
+				if sanitizeContextNames {
+					sanitizeContextName(&discoveredContext, prefix)
+				}
+				annotateContext(&discoveredContext, userAnnotations)
+				send(discoveredContext)
+			}
+
+			timedOut := false
+		search:
+			for {
+				select {
+				case <-ctx.Done():
+					timedOut = true
+					break search
+				case channelResult, ok := <-storeSearchChannel:
+					if !ok {
+						// the store stops searching once the search timeout is exceeded
+						timedOut = ctx.Err() != nil
+						break search
+					}
+
+					if channelResult.Error != nil {
+						err := fmt.Errorf("store %q returned an error during the search: %v", store.GetID(), channelResult.Error)
+						// Required defines if errors when initializing this store should be logged
+						if ignoreStoreErrors || (store.GetStoreConfig().Required != nil && !*store.GetStoreConfig().Required) {
+							if options.reportIgnoredStoreErrors {
+								send(DiscoveredContext{
+									Store: &store,
+									Error: &IgnoredStoreError{StoreID: store.GetID(), Err: err},
+								})
+							}
+							continue
+						}
+
+						send(DiscoveredContext{
+							Store: &store,
+							Error: err,
+						})
+						continue
+					}
+					channelResult.Tags = addPackageTag(channelResult)
+
+					if contexts, ok := indexedPathToContexts[channelResult.KubeconfigPath]; ok {
+						for _, contextName := range contexts {
+							localContextToPathMapping[contextName] = channelResult.KubeconfigPath
+							if len(channelResult.Tags) > 0 {
+								localContextToTagsMapping[contextName] = channelResult.Tags
+							}
+							sendDiscoveredContext(DiscoveredContext{
+								Path:  channelResult.KubeconfigPath,
+								Name:  contextName,
+								Tags:  channelResult.Tags,
+								Alias: aliasutil.GetContextForAlias(contextName, contextToAliasMapping),
+								Store: &store,
+							})
+						}
 						continue
 					}
 
-					resultChannel <- DiscoveredContext{
-						Error: fmt.Errorf("store %q returned an error during the search: %v", store.GetID(), channelResult.Error),
+					bytes, err := store.GetKubeconfigForPath(ctx, channelResult.KubeconfigPath, channelResult.Tags)
+					if err != nil {
+						// oversized kubeconfigs indicate a misconfigured or compromised store
+						if errors.Is(err, &storeerrors.ErrKubeconfigTooLarge{}) {
+							store.GetLogger().Warnf("skipping kubeconfig: %v", err)
+						}
+						if options.reportKubeconfigErrors && !errors.Is(err, &storeerrors.ErrKubeconfigNotFound{}) {
+							send(DiscoveredContext{
+								Error: fmt.Errorf("store %q failed to get the kubeconfig with path %q: %w", store.GetID(), channelResult.KubeconfigPath, err),
+							})
+							continue
+						}
+						// do not throw Error, try to parse the other files
+						// this will happen a lot when using vault as storage because the secrets key value needs to match the desired kubeconfig name
+						// this however cannot be checked without retrieving the actual secret (path discovery is only list operation)
+						continue
 					}
-					continue
-				}
 
-				bytes, err := store.GetKubeconfigForPath(channelResult.KubeconfigPath, channelResult.Tags)
-				if err != nil {
-					// do not throw Error, try to parse the other files
-					// this will happen a lot when using vault as storage because the secrets key value needs to match the desired kubeconfig name
-					// this however cannot be checked without retrieving the actual secret (path discovery is only list operation)
-					continue
-				}
+					// get the context names from the parsed kubeconfig
+					kubeconfigString, contexts, err := util.GetContextsNamesFromKubeconfig(bytes, store.GetContextPrefix(channelResult.KubeconfigPath))
+					if err != nil {
+						store.GetLogger().Debugf("failed to get kubeconfig context names for kubeconfig with path %q: %v", channelResult.KubeconfigPath, err)
+						send(DiscoveredContext{
+							Error: fmt.Errorf("failed to get kubeconfig context names for kubeconfig with path %q: %v", channelResult.KubeconfigPath, err),
+						})
+						// do not throw Error, try to parse the other files
+						continue
+					}
 
-				// get the context names from the parsed kubeconfig
-				kubeconfigString, contexts, err := util.GetContextsNamesFromKubeconfig(bytes, store.GetContextPrefix(channelResult.KubeconfigPath))
-				if err != nil {
-					store.GetLogger().Debugf("failed to get kubeconfig context names for kubeconfig with path %q: %v", channelResult.KubeconfigPath, err)
-					resultChannel <- DiscoveredContext{
-						Error: fmt.Errorf("failed to get kubeconfig context names for kubeconfig with path %q: %v", channelResult.KubeconfigPath, err),
+					// save kubeconfig content to in-memory map to avoid duplicate read operation in getSanitizedKubeconfigForKubeconfigPath
+					writeToPathToKubeconfig(store.GetID(), channelResult.KubeconfigPath, *kubeconfigString)
+
+					for _, contextName := range contexts {
+						// add to local contextToPath map to write the index for this store only
+						// excluded contexts are still written to the index, so that changes to the exclusions apply immediately
+						localContextToPathMapping[contextName] = channelResult.KubeconfigPath
+						if len(channelResult.Tags) > 0 {
+							localContextToTagsMapping[contextName] = channelResult.Tags
+						}
+
+						// write to result channel
+						sendDiscoveredContext(DiscoveredContext{
+							Path:  channelResult.KubeconfigPath,
+							Name:  contextName,
+							Tags:  channelResult.Tags,
+							Alias: aliasutil.GetContextForAlias(contextName, contextToAliasMapping),
+							Store: &store,
+							Error: nil,
+						})
 					}
-					// do not throw Error, try to parse the other files
-					continue
 				}
+			}
 
-				// save kubeconfig content to in-memory map to avoid duplicate read operation in getSanitizedKubeconfigForKubeconfigPath
-				writeToPathToKubeconfig(channelResult.KubeconfigPath, *kubeconfigString)
+			if timedOut {
+				store.GetLogger().Debugf("search for store %q exceeded the search timeout. Returning partial results.", store.GetID())
 
-				for _, contextName := range contexts {
-					// write to result channel
-					resultChannel <- DiscoveredContext{
-						Path:  channelResult.KubeconfigPath,
-						Name:  contextName,
-						Tags:  channelResult.Tags,
-						Alias: aliasutil.GetContextForAlias(contextName, contextToAliasMapping),
-						Store: &store,
-						Error: nil,
-					}
-					// add to local contextToPath map to write the index for this store only
-					localContextToPathMapping[contextName] = channelResult.KubeconfigPath
-					if len(channelResult.Tags) > 0 {
-						localContextToTagsMapping[contextName] = channelResult.Tags
+				// keep reading from the store, so that the search go routine of the store does not block
+				go func() {
+					for range storeSearchChannel {
 					}
+				}()
+
+				if !ignoreStoreErrors && (store.GetStoreConfig().Required == nil || *store.GetStoreConfig().Required) {
+					send(DiscoveredContext{
+						Store: &store,
+						Error: newSearchTimeoutError(store.GetID()),
+					})
+				} else if options.reportIgnoredStoreErrors {
+					send(DiscoveredContext{
+						Store: &store,
+						Error: &IgnoredStoreError{StoreID: store.GetID(), Err: newSearchTimeoutError(store.GetID())},
+					})
 				}
 			}
 
 			// write store index file now that the path discovery is complete
-			if len(localContextToPathMapping) > 0 {
+			// a partial search result must not be written to the index
+			if len(localContextToPathMapping) > 0 && !timedOut {
 				writeIndex(store, &index, localContextToPathMapping, localContextToTagsMapping)
 			}
 
+			// the duration of a search that exceeded the search timeout is the search timeout
+			if !timedOut {
+				recordSearchDuration(store.GetID(), searchStart)
+			}
+
 			// reading from this store is finished, decrease wait counter
 			wgResultChannel.Done()
 		}(kubeconfigStore, c, *searchIndex)
@@ -217,6 +476,232 @@ func DoSearch(stores []store.KubeconfigStore, config *types.Config, stateDir str
 	return &resultChannel, nil
 }
 
+// searchDeadline cancels the search of a store once the search timeout is exceeded.
+// Unlike a context deadline, the time between pause and resume does not count towards the search timeout.
+// A nil *searchDeadline is valid and never cancels the search.
+type searchDeadline struct {
+	lock      sync.Mutex
+	timer     *time.Timer
+	remaining time.Duration
+	started   time.Time
+	paused    bool
+}
+
+func newSearchDeadline(timeout time.Duration, cancel context.CancelFunc) *searchDeadline {
+	return &searchDeadline{
+		timer:     time.AfterFunc(timeout, cancel),
+		remaining: timeout,
+		started:   time.Now(),
+	}
+}
+
+// pause stops counting the time towards the search timeout
+func (d *searchDeadline) pause() {
+	if d == nil {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	// the search has already been cancelled if the timer fired
+	if d.timer.Stop() {
+		d.remaining -= time.Since(d.started)
+		d.paused = true
+	}
+}
+
+// resume continues counting the time towards the search timeout
+func (d *searchDeadline) resume() {
+	if d == nil {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.paused {
+		d.paused = false
+		d.started = time.Now()
+		d.timer.Reset(max(d.remaining, 0))
+	}
+}
+
+// stop releases the timer once the search is over
+func (d *searchDeadline) stop() {
+	if d == nil {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.paused = false
+	d.timer.Stop()
+}
+
+var (
+	// durations of the completed searches of the stores by store ID
+	searchDurations     = make(map[string]time.Duration)
+	searchDurationsLock = sync.Mutex{}
+)
+
+// recordSearchDuration remembers the duration of the search of the store since the given start
+func recordSearchDuration(storeID string, start time.Time) {
+	searchDurationsLock.Lock()
+	defer searchDurationsLock.Unlock()
+	searchDurations[storeID] = time.Since(start)
+}
+
+// GetSearchDurations returns the durations of the completed searches of the stores by store ID.
+// Includes the initialization of the stores and reading from the index.
+// Searches that are still running or exceeded the search timeout are not included.
+func GetSearchDurations() map[string]time.Duration {
+	searchDurationsLock.Lock()
+	defer searchDurationsLock.Unlock()
+	return maps.Clone(searchDurations)
+}
+
+// KubeconfigContextName returns the name of the discovered context in its kubeconfig.
+// The context name in the kubeconfig does not contain the store prefix.
+func KubeconfigContextName(kubeconfigStore store.KubeconfigStore, discoveredContext DiscoveredContext) string {
+	if prefix := kubeconfigStore.GetContextPrefix(discoveredContext.Path); len(prefix) > 0 {
+		return strings.TrimPrefix(discoveredContext.Name, fmt.Sprintf("%s/", prefix))
+	}
+	return discoveredContext.Name
+}
+
+// FindContext searches the kubeconfig stores for the context with the given name.
+// The name can be given with or without the store prefix or as an alias.
+func FindContext(desiredContext string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) (*DiscoveredContext, error) {
+	c, err := DoSearch(stores, config, stateDir, noIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	var mError *multierror.Error
+	for discoveredContext := range *c {
+		if discoveredContext.Error != nil {
+			// remember in case the wanted context name cannot be found
+			mError = multierror.Append(mError, discoveredContext.Error)
+			continue
+		}
+
+		if discoveredContext.Store == nil {
+			continue
+		}
+
+		kubeconfigStore := *discoveredContext.Store
+		prefix := kubeconfigStore.GetContextPrefix(discoveredContext.Path)
+
+		contextWithoutPrefix := discoveredContext.Name
+		if len(prefix) > 0 && strings.HasPrefix(discoveredContext.Name, prefix) {
+			contextWithoutPrefix = strings.TrimPrefix(discoveredContext.Name, fmt.Sprintf("%s/", prefix))
+		}
+
+		if desiredContext == discoveredContext.Name || desiredContext == contextWithoutPrefix || desiredContext == discoveredContext.Alias {
+			// drain the channel to let the search finish
+			go func() {
+				for range *c {
+				}
+			}()
+			return &discoveredContext, nil
+		}
+	}
+
+	if mError != nil {
+		return nil, fmt.Errorf("context with name %q not found. Possibly due to errors: %v", desiredContext, mError.Error())
+	}
+	return nil, fmt.Errorf("context with name %q not found", desiredContext)
+}
+
+// sanitizeContextName shows the sanitized context name in the search results if the context name contains illegal characters.
+// The sanitized name is set as the alias of the context, unless an alias is already defined.
+// The original context name remains the name of the discovered context, so that it is found in the kubeconfig.
+func sanitizeContextName(discoveredContext *DiscoveredContext, prefix string) {
+	if len(discoveredContext.Alias) > 0 {
+		return
+	}
+
+	name := discoveredContext.Name
+	if len(prefix) > 0 {
+		name = strings.TrimPrefix(name, fmt.Sprintf("%s/", prefix))
+	}
+
+	sanitizedName := util.SanitizeContextName(name)
+	if sanitizedName == name {
+		return
+	}
+
+	if len(prefix) > 0 {
+		sanitizedName = fmt.Sprintf("%s/%s", prefix, sanitizedName)
+	}
+
+	discoveredContext.Alias = sanitizedName
+}
+
+// addPackageTag returns the tags of the search result including the package assigned by the store
+func addPackageTag(result store.SearchResult) map[string]string {
+	if len(result.PackageName) == 0 {
+		return result.Tags
+	}
+
+	tags := maps.Clone(result.Tags)
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	tags[PackageTag] = result.PackageName
+	return tags
+}
+
+// assignPackage sets the package of the discovered context.
+// The package assigned by the store takes precedence over the packages configured for the store,
+// which are matched against the context name with and without the store prefix.
+func assignPackage(discoveredContext *DiscoveredContext, prefix string) {
+	if packageName, ok := discoveredContext.Tags[PackageTag]; ok {
+		discoveredContext.PackageName = packageName
+		return
+	}
+
+	packages := (*discoveredContext.Store).GetStoreConfig().Packages
+	discoveredContext.PackageName = util.GetPackageName(discoveredContext.Name, packages)
+	if len(discoveredContext.PackageName) == 0 && len(prefix) > 0 {
+		discoveredContext.PackageName = util.GetPackageName(strings.TrimPrefix(discoveredContext.Name, fmt.Sprintf("%s/", prefix)), packages)
+	}
+}
+
+// annotateContext adds the user-defined annotations of the context to the tags of the discovered context.
+// The annotations are looked up by the name of the context, so that they do not depend on aliases.
+func annotateContext(discoveredContext *DiscoveredContext, userAnnotations annotations.Annotations) {
+	discoveredContext.Annotations = userAnnotations[discoveredContext.Name]
+	discoveredContext.Tags = userAnnotations.AddTags(discoveredContext.Name, discoveredContext.Tags)
+}
+
+// isContextExcluded checks if the discovered context is excluded by the given exclusion patterns.
+// Matches the context name with and without the store prefix.
+func isContextExcluded(discoveredContext DiscoveredContext, prefix string, excludeContexts []string) bool {
+	if len(excludeContexts) == 0 {
+		return false
+	}
+
+	if util.IsContextExcluded(discoveredContext.Name, excludeContexts) {
+		return true
+	}
+
+	if len(prefix) > 0 {
+		return util.IsContextExcluded(strings.TrimPrefix(discoveredContext.Name, fmt.Sprintf("%s/", prefix)), excludeContexts)
+	}
+	return false
+}
+
+// matchesStoreTags checks if the tags of the discovered context match the required and forbidden tags of its store
+func matchesStoreTags(discoveredContext DiscoveredContext) bool {
+	storeConfig := (*discoveredContext.Store).GetStoreConfig()
+	return util.MatchesTags(discoveredContext.Tags, storeConfig.RequiredTags, storeConfig.ForbiddenTags)
+}
+
+// newSearchTimeoutError returns the terminal error for a store that exceeded its search timeout
+func newSearchTimeoutError(storeID string) error {
+	return &storeerrors.ErrStoreTimeout{StoreID: storeID, Err: errors.New("the search did not finish in time")}
+}
+
 func shouldReadFromIndex(searchIndex *index.SearchIndex, kubeconfigStore store.KubeconfigStore, config *types.Config) (bool, error) {
 	// never write an index for the store from env variables and --kubeconfig-path command line falg
 	if kubeconfigStore.GetID() == fmt.Sprintf("%s.%s", types.StoreKindFilesystem, "env-and-flag") {
@@ -0,0 +1,306 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+	"k8s.io/utils/ptr"
+)
+
+const searchTestKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+users:
+- name: dev
+`
+
+// failingStore finds two kubeconfigs, but fails to return the kubeconfig with the path "broken"
+type failingStore struct{}
+
+func (s *failingStore) GetID() string                               { return "vault.default" }
+func (s *failingStore) GetKind() types.StoreKind                    { return types.StoreKindVault }
+func (s *failingStore) GetContextPrefix(string) string              { return "" }
+func (s *failingStore) VerifyKubeconfigPaths(context.Context) error { return nil }
+func (s *failingStore) Probe(context.Context) error                 { return nil }
+func (s *failingStore) GetLogger() *logrus.Entry                    { return testutil.NewTestLogger() }
+func (s *failingStore) GetStoreConfig() types.KubeconfigStore       { return types.KubeconfigStore{} }
+func (s *failingStore) Stop(context.Context) error                  { return nil }
+func (s *failingStore) StartSearch(_ context.Context, channel chan store.SearchResult) {
+	channel <- testutil.FakeSearchResult("dev")
+	channel <- testutil.FakeSearchResult("broken")
+}
+
+func (s *failingStore) GetKubeconfigForPath(_ context.Context, path string, _ map[string]string) ([]byte, error) {
+	if path == "broken" {
+		return nil, errors.New("permission denied")
+	}
+	return []byte(searchTestKubeconfig), nil
+}
+
+// secretStore finds two paths, but the path "secret" does not contain a kubeconfig
+type secretStore struct {
+	failingStore
+}
+
+func (s *secretStore) StartSearch(_ context.Context, channel chan store.SearchResult) {
+	channel <- testutil.FakeSearchResult("dev")
+	channel <- testutil.FakeSearchResult("secret")
+}
+
+func (s *secretStore) GetKubeconfigForPath(_ context.Context, path string, _ map[string]string) ([]byte, error) {
+	if path == "secret" {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: errors.New(`key "config" not found`)}
+	}
+	return []byte(searchTestKubeconfig), nil
+}
+
+// erroringStore finds one kubeconfig and then fails the search
+type erroringStore struct {
+	failingStore
+}
+
+func (s *erroringStore) GetID() string { return "vault.erroring" }
+func (s *erroringStore) StartSearch(_ context.Context, channel chan store.SearchResult) {
+	channel <- testutil.FakeSearchResult("dev")
+	channel <- store.SearchResult{Error: errors.New("connection refused")}
+}
+
+// slowStore has a search timeout of 200ms and finds three kubeconfigs after the given delay
+type slowStore struct {
+	failingStore
+	delay time.Duration
+}
+
+func (s *slowStore) GetID() string { return "vault.slow" }
+func (s *slowStore) GetStoreConfig() types.KubeconfigStore {
+	return types.KubeconfigStore{Kind: types.StoreKindVault, SearchTimeout: ptr.To(200 * time.Millisecond)}
+}
+func (s *slowStore) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(s.delay):
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ctx.Done():
+			return
+		case channel <- testutil.FakeSearchResult("dev"):
+		}
+	}
+}
+
+// illegalNameStore finds a kubeconfig with a context name containing illegal characters
+type illegalNameStore struct {
+	failingStore
+}
+
+func (s *illegalNameStore) StartSearch(_ context.Context, channel chan store.SearchResult) {
+	channel <- testutil.FakeSearchResult("dev", "team=a")
+}
+
+func (s *illegalNameStore) GetKubeconfigForPath(context.Context, string, map[string]string) ([]byte, error) {
+	return []byte(strings.ReplaceAll(searchTestKubeconfig, "name: dev\n  context", "name: team/dev:1\n  context")), nil
+}
+
+var _ = Describe("DoSearch", func() {
+	var stateDir string
+
+	BeforeEach(func() {
+		var err error
+		stateDir, err = os.MkdirTemp("", "kubeswitch-search")
+		Expect(err).ToNot(HaveOccurred())
+		annotations.SetPath(filepath.Join(stateDir, "annotations.yaml"))
+	})
+
+	AfterEach(func() {
+		annotations.SetPath(annotations.DefaultPath)
+		Expect(os.RemoveAll(stateDir)).To(Succeed())
+	})
+
+	searchStore := func(s store.KubeconfigStore, config *types.Config, opts ...pkg.SearchOption) ([]string, []error) {
+		c, err := pkg.DoSearch([]store.KubeconfigStore{s}, config, stateDir, true, opts...)
+		Expect(err).ToNot(HaveOccurred())
+
+		var (
+			names []string
+			errs  []error
+		)
+		for discoveredContext := range *c {
+			if discoveredContext.Error != nil {
+				errs = append(errs, discoveredContext.Error)
+				continue
+			}
+			names = append(names, discoveredContext.Name)
+		}
+		return names, errs
+	}
+
+	search := func(opts ...pkg.SearchOption) ([]string, []error) {
+		return searchStore(&failingStore{}, &types.Config{}, opts...)
+	}
+
+	It("should skip kubeconfigs that cannot be retrieved", func() {
+		names, errs := search()
+		Expect(names).To(Equal([]string{"dev"}))
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("should report kubeconfigs that cannot be retrieved", func() {
+		names, errs := search(pkg.WithKubeconfigErrors())
+		Expect(names).To(Equal([]string{"dev"}))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0]).To(MatchError(ContainSubstring(`store "vault.default" failed to get the kubeconfig with path "broken": permission denied`)))
+	})
+
+	It("should not report paths without a kubeconfig", func() {
+		names, errs := searchStore(&secretStore{}, &types.Config{}, pkg.WithKubeconfigErrors())
+		Expect(names).To(Equal([]string{"dev"}))
+		Expect(errs).To(BeEmpty())
+	})
+
+	Context("ignored store errors", func() {
+		config := &types.Config{IgnoreStoreErrors: ptr.To(true)}
+
+		It("should drop ignored store errors by default", func() {
+			names, errs := searchStore(&erroringStore{}, config)
+			Expect(names).To(Equal([]string{"dev"}))
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("should report ignored store errors", func() {
+			names, errs := searchStore(&erroringStore{}, config, pkg.WithIgnoredStoreErrors())
+			Expect(names).To(Equal([]string{"dev"}))
+			Expect(errs).To(HaveLen(1))
+
+			var ignored *pkg.IgnoredStoreError
+			Expect(errors.As(errs[0], &ignored)).To(BeTrue())
+			Expect(ignored.StoreID).To(Equal("vault.erroring"))
+			Expect(ignored).To(MatchError(ContainSubstring("connection refused")))
+		})
+
+		It("should report store errors that are not ignored", func() {
+			_, errs := searchStore(&erroringStore{}, &types.Config{}, pkg.WithIgnoredStoreErrors())
+			Expect(errs).To(HaveLen(1))
+
+			var ignored *pkg.IgnoredStoreError
+			Expect(errors.As(errs[0], &ignored)).To(BeFalse())
+		})
+	})
+
+	It("should show the sanitized context name and keep the original name", func() {
+		c, err := pkg.DoSearch([]store.KubeconfigStore{&illegalNameStore{}}, &types.Config{SanitizeContextNames: ptr.To(true)}, stateDir, true)
+		Expect(err).ToNot(HaveOccurred())
+
+		var discovered []pkg.DiscoveredContext
+		for discoveredContext := range *c {
+			Expect(discoveredContext.Error).ToNot(HaveOccurred())
+			discovered = append(discovered, discoveredContext)
+		}
+		Expect(discovered).To(HaveLen(1))
+		Expect(discovered[0].Name).To(Equal("team/dev:1"))
+		Expect(discovered[0].Alias).To(Equal("team-dev-1"))
+		Expect(discovered[0].Tags).To(Equal(map[string]string{"team": "a"}))
+	})
+
+	It("should annotate the context by its name instead of its alias", func() {
+		userAnnotations := annotations.Annotations{}
+		Expect(userAnnotations.Set("team/dev:1", "owner", "team-a")).To(Succeed())
+		Expect(userAnnotations.Set("team-dev-1", "note", "alias")).To(Succeed())
+		Expect(userAnnotations.Save()).To(Succeed())
+
+		c, err := pkg.DoSearch([]store.KubeconfigStore{&illegalNameStore{}}, &types.Config{SanitizeContextNames: ptr.To(true)}, stateDir, true)
+		Expect(err).ToNot(HaveOccurred())
+
+		var discovered []pkg.DiscoveredContext
+		for discoveredContext := range *c {
+			Expect(discoveredContext.Error).ToNot(HaveOccurred())
+			discovered = append(discovered, discoveredContext)
+		}
+		Expect(discovered).To(HaveLen(1))
+		Expect(discovered[0].Alias).To(Equal("team-dev-1"))
+		Expect(discovered[0].Annotations).To(Equal(map[string]string{"owner": "team-a"}))
+		Expect(discovered[0].Tags).To(Equal(map[string]string{"team": "a", "user.owner": "team-a"}))
+	})
+
+	Context("search timeout", func() {
+		// readSlowly waits longer than the search timeout before reading the remaining search results
+		readSlowly := func(s store.KubeconfigStore, opts ...pkg.SearchOption) ([]string, []error) {
+			c, err := pkg.DoSearch([]store.KubeconfigStore{s}, &types.Config{}, stateDir, true, opts...)
+			Expect(err).ToNot(HaveOccurred())
+
+			var (
+				names []string
+				errs  []error
+			)
+			for discoveredContext := range *c {
+				if discoveredContext.Error != nil {
+					errs = append(errs, discoveredContext.Error)
+					continue
+				}
+				names = append(names, discoveredContext.Name)
+				if len(names) == 1 {
+					time.Sleep(400 * time.Millisecond)
+				}
+			}
+			return names, errs
+		}
+
+		It("should count the time waiting for the search results to be read", func() {
+			names, errs := readSlowly(&slowStore{})
+			Expect(len(names)).To(BeNumerically("<", 3))
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError(ContainSubstring("the search did not finish in time")))
+		})
+
+		It("should not count the time waiting for the search results to be read if paused", func() {
+			names, errs := readSlowly(&slowStore{}, pkg.WithPausedSearchTimeout())
+			Expect(names).To(HaveLen(3))
+			Expect(errs).To(BeEmpty())
+		})
+
+		It("should still cancel a paused search of a store exceeding the search timeout", func() {
+			names, errs := readSlowly(&slowStore{delay: time.Second}, pkg.WithPausedSearchTimeout())
+			Expect(names).To(BeEmpty())
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError(ContainSubstring("the search did not finish in time")))
+		})
+	})
+})
@@ -0,0 +1,106 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package share
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// URLPrefix is the prefix of all share URLs
+const URLPrefix = "kubeswitch://share/"
+
+var (
+	// ErrInvalidSignature is returned if the signature of a share URL does not match its payload
+	ErrInvalidSignature = errors.New("the signature of the shared URL is invalid")
+	// ErrExpired is returned if a share URL is expired
+	ErrExpired = errors.New("the shared URL is expired")
+)
+
+// Reference references a context of a store.
+// It is the payload of a share URL and does not contain any credentials.
+type Reference struct {
+	// StoreID is the ID of the store the context is discovered by
+	StoreID string `json:"store"`
+	// Context is the name of the context as shown by kubeswitch
+	Context string `json:"context"`
+	// Expiry is the time after which the URL cannot be opened anymore
+	Expiry time.Time `json:"expiry"`
+}
+
+// NewURL returns the URL for the reference signed with HMAC-SHA256 using the given secret.
+// The URL has the form kubeswitch://share/<base64-encoded-payload>/<signature>.
+func NewURL(reference Reference, secret []byte) (string, error) {
+	if len(secret) == 0 {
+		return "", fmt.Errorf("the secret to sign the URL must not be empty")
+	}
+
+	data, err := json.Marshal(reference)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return fmt.Sprintf("%s%s/%s", URLPrefix, payload, sign(payload, secret)), nil
+}
+
+// ParseURL verifies the signature and the expiry of the URL and returns the referenced context
+func ParseURL(url string, secret []byte, now time.Time) (*Reference, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("the secret to verify the URL must not be empty")
+	}
+
+	rest, ok := strings.CutPrefix(strings.TrimSpace(url), URLPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a share URL. Share URLs start with %q", url, URLPrefix)
+	}
+
+	payload, signature, ok := strings.Cut(rest, "/")
+	if !ok {
+		return nil, fmt.Errorf("the shared URL does not contain a signature")
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(sign(payload, secret))) {
+		return nil, ErrInvalidSignature
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the payload of the shared URL: %w", err)
+	}
+
+	reference := &Reference{}
+	if err := json.Unmarshal(data, reference); err != nil {
+		return nil, fmt.Errorf("failed to parse the payload of the shared URL: %w", err)
+	}
+
+	if !now.Before(reference.Expiry) {
+		return nil, fmt.Errorf("%w: the URL expired at %s", ErrExpired, reference.Expiry.Local().Format(time.RFC1123))
+	}
+	return reference, nil
+}
+
+// sign returns the base64 encoded HMAC-SHA256 of the payload
+func sign(payload string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
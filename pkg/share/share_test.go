@@ -0,0 +1,90 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package share_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/share"
+)
+
+var _ = Describe("Share URLs", func() {
+	var (
+		secret    = []byte("team-secret")
+		now       = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		reference = share.Reference{
+			StoreID: "eks.prod",
+			Context: "eks_prod--eu-west-1--cluster",
+			Expiry:  now.Add(time.Hour),
+		}
+	)
+
+	It("should create and parse a signed URL", func() {
+		url, err := share.NewURL(reference, secret)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(url).To(HavePrefix(share.URLPrefix))
+		Expect(strings.Split(strings.TrimPrefix(url, share.URLPrefix), "/")).To(HaveLen(2))
+
+		parsed, err := share.ParseURL(url, secret, now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.StoreID).To(Equal(reference.StoreID))
+		Expect(parsed.Context).To(Equal(reference.Context))
+		Expect(parsed.Expiry.Equal(reference.Expiry)).To(BeTrue())
+	})
+
+	It("should reject expired URLs", func() {
+		url, err := share.NewURL(reference, secret)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = share.ParseURL(url, secret, now.Add(time.Hour))
+		Expect(err).To(MatchError(share.ErrExpired))
+	})
+
+	It("should reject URLs signed with another secret", func() {
+		url, err := share.NewURL(reference, []byte("other-secret"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = share.ParseURL(url, secret, now)
+		Expect(err).To(MatchError(share.ErrInvalidSignature))
+	})
+
+	It("should reject URLs with a modified payload", func() {
+		url, err := share.NewURL(reference, secret)
+		Expect(err).ToNot(HaveOccurred())
+		_, signature, _ := strings.Cut(strings.TrimPrefix(url, share.URLPrefix), "/")
+
+		payload := base64.RawURLEncoding.EncodeToString([]byte(`{"store":"eks.prod","context":"admin","expiry":"2024-01-01T13:00:00Z"}`))
+		_, err = share.ParseURL(share.URLPrefix+payload+"/"+signature, secret, now)
+		Expect(err).To(MatchError(share.ErrInvalidSignature))
+	})
+
+	It("should reject invalid URLs", func() {
+		_, err := share.ParseURL("https://example.com", secret, now)
+		Expect(err).To(MatchError(ContainSubstring("is not a share URL")))
+
+		_, err = share.ParseURL(share.URLPrefix+"payload", secret, now)
+		Expect(err).To(MatchError(ContainSubstring("does not contain a signature")))
+	})
+
+	It("should require a secret", func() {
+		_, err := share.NewURL(reference, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,107 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sort
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// UntaggedGroupName is the name of the group of search results without the tag to group by
+const UntaggedGroupName = "(untagged)"
+
+// Group is a group of search results with the same value of the tag to group by
+type Group struct {
+	// Value is the tag value of the search results, empty for the group of search results without the tag
+	Value string
+	// Results are the search results of the group in their original order
+	Results []store.SearchResult
+}
+
+// Name returns the name of the group shown in the selection dialog
+func (g Group) Name() string {
+	if len(g.Value) == 0 {
+		return UntaggedGroupName
+	}
+	return g.Value
+}
+
+// GroupResults groups the search results of a store by the value of the given tag.
+// The groups are ordered according to the given group sort order.
+// Search results without the tag are grouped at the bottom. Within a group, the search results keep their order.
+// The given slice is not modified.
+func GroupResults(results []store.SearchResult, tag string, order types.GroupSortOrder) []Group {
+	var (
+		groups   []Group
+		indices  = map[string]int{}
+		untagged []store.SearchResult
+	)
+
+	for _, result := range results {
+		value, ok := result.Tags[tag]
+		if !ok || len(value) == 0 {
+			untagged = append(untagged, result)
+			continue
+		}
+		i, ok := indices[value]
+		if !ok {
+			i = len(groups)
+			indices[value] = i
+			groups = append(groups, Group{Value: value})
+		}
+		groups[i].Results = append(groups[i].Results, result)
+	}
+
+	slices.SortFunc(groups, func(a, b Group) int {
+		return compareGroups(a, b, order)
+	})
+
+	if len(untagged) > 0 {
+		groups = append(groups, Group{Results: untagged})
+	}
+	return groups
+}
+
+// GroupPosition returns the index in the given ordered groups at which the group is inserted
+// without reordering the given groups. The group of search results without the tag stays at the bottom.
+func GroupPosition(groups []Group, group Group, order types.GroupSortOrder) int {
+	if len(group.Value) == 0 {
+		return len(groups)
+	}
+	for i, g := range groups {
+		if len(g.Value) == 0 || compareGroups(group, g, order) < 0 {
+			return i
+		}
+	}
+	return len(groups)
+}
+
+// compareGroups compares two groups of tagged search results according to the group sort order
+func compareGroups(a, b Group, order types.GroupSortOrder) int {
+	switch order {
+	case types.GroupSortOrderDesc:
+		return strings.Compare(b.Value, a.Value)
+	case types.GroupSortOrderCountDesc:
+		if len(a.Results) != len(b.Results) {
+			return len(b.Results) - len(a.Results)
+		}
+		return strings.Compare(a.Value, b.Value)
+	default:
+		return strings.Compare(a.Value, b.Value)
+	}
+}
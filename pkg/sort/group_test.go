@@ -0,0 +1,119 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sort_test
+
+import (
+	"slices"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	sortutil "github.com/danielfoehrkn/kubeswitch/pkg/sort"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("GroupResults", func() {
+	results := []store.SearchResult{
+		{KubeconfigPath: "staging-eu", Tags: map[string]string{"env": "staging"}},
+		{KubeconfigPath: "dev", Tags: map[string]string{"env": "dev"}},
+		{KubeconfigPath: "local"},
+		{KubeconfigPath: "staging-us", Tags: map[string]string{"env": "staging"}},
+		{KubeconfigPath: "prod", Tags: map[string]string{"env": "prod", "team": "platform"}},
+	}
+
+	// entries returns the group names and the paths of the search results of the groups
+	entries := func(groups []sortutil.Group) []string {
+		var entries []string
+		for _, group := range groups {
+			entries = append(entries, "# "+group.Name())
+			for _, result := range group.Results {
+				entries = append(entries, result.KubeconfigPath)
+			}
+		}
+		return entries
+	}
+
+	It("should group alphabetically by tag value with the untagged results at the bottom", func() {
+		Expect(entries(sortutil.GroupResults(results, "env", types.GroupSortOrderAsc))).To(Equal([]string{
+			"# dev", "dev",
+			"# prod", "prod",
+			"# staging", "staging-eu", "staging-us",
+			"# (untagged)", "local",
+		}))
+	})
+
+	It("should group reverse alphabetically by tag value", func() {
+		Expect(entries(sortutil.GroupResults(results, "env", types.GroupSortOrderDesc))).To(Equal([]string{
+			"# staging", "staging-eu", "staging-us",
+			"# prod", "prod",
+			"# dev", "dev",
+			"# (untagged)", "local",
+		}))
+	})
+
+	It("should order the groups by their size", func() {
+		Expect(entries(sortutil.GroupResults(results, "env", types.GroupSortOrderCountDesc))).To(Equal([]string{
+			"# staging", "staging-eu", "staging-us",
+			"# dev", "dev",
+			"# prod", "prod",
+			"# (untagged)", "local",
+		}))
+	})
+
+	It("should not add a group for untagged results if all results have the tag", func() {
+		grouped := sortutil.GroupResults(results[:2], "env", "")
+		Expect(entries(grouped)).To(Equal([]string{"# dev", "dev", "# staging", "staging-eu"}))
+		Expect(grouped[0].Value).To(Equal("dev"))
+	})
+
+	It("should not modify the given search results", func() {
+		original := slices.Clone(results)
+		sortutil.GroupResults(results, "env", types.GroupSortOrderDesc)
+		Expect(results).To(Equal(original))
+	})
+})
+
+var _ = Describe("GroupPosition", func() {
+	groups := []sortutil.Group{
+		{Value: "dev", Results: make([]store.SearchResult, 3)},
+		{Value: "staging", Results: make([]store.SearchResult, 1)},
+		{Results: make([]store.SearchResult, 2)},
+	}
+
+	It("should insert a group alphabetically by tag value", func() {
+		Expect(sortutil.GroupPosition(groups, sortutil.Group{Value: "alpha"}, types.GroupSortOrderAsc)).To(Equal(0))
+		Expect(sortutil.GroupPosition(groups, sortutil.Group{Value: "prod"}, types.GroupSortOrderAsc)).To(Equal(1))
+		Expect(sortutil.GroupPosition(groups, sortutil.Group{Value: "test"}, "")).To(Equal(2))
+	})
+
+	It("should insert a group reverse alphabetically by tag value", func() {
+		descending := []sortutil.Group{groups[1], groups[0], groups[2]}
+		Expect(sortutil.GroupPosition(descending, sortutil.Group{Value: "test"}, types.GroupSortOrderDesc)).To(Equal(0))
+		Expect(sortutil.GroupPosition(descending, sortutil.Group{Value: "prod"}, types.GroupSortOrderDesc)).To(Equal(1))
+		Expect(sortutil.GroupPosition(descending, sortutil.Group{Value: "alpha"}, types.GroupSortOrderDesc)).To(Equal(2))
+	})
+
+	It("should insert a group by its size", func() {
+		Expect(sortutil.GroupPosition(groups, sortutil.Group{Value: "prod", Results: make([]store.SearchResult, 2)}, types.GroupSortOrderCountDesc)).To(Equal(1))
+		Expect(sortutil.GroupPosition(groups, sortutil.Group{Value: "prod", Results: make([]store.SearchResult, 4)}, types.GroupSortOrderCountDesc)).To(Equal(0))
+		Expect(sortutil.GroupPosition(groups, sortutil.Group{Value: "test", Results: make([]store.SearchResult, 1)}, types.GroupSortOrderCountDesc)).To(Equal(2))
+	})
+
+	It("should keep the untagged group at the bottom", func() {
+		Expect(sortutil.GroupPosition(groups, sortutil.Group{}, types.GroupSortOrderAsc)).To(Equal(3))
+		Expect(sortutil.GroupPosition(groups[:2], sortutil.Group{Value: "zeta"}, types.GroupSortOrderAsc)).To(Equal(2))
+	})
+})
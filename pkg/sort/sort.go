@@ -0,0 +1,99 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sort
+
+import (
+	"slices"
+	"strings"
+
+	historyutil "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/history/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// SearchResult is a single entry of the selection dialog
+type SearchResult struct {
+	// ContextName is the final context name as shown in the selection dialog
+	// (after the store prefix has been applied)
+	ContextName string
+	// StoreID is the ID of the store that discovered the context
+	StoreID string
+	// PriorityScore is the priority score of the store that discovered the context
+	PriorityScore int
+}
+
+// SortResults returns the results ordered according to the given sort order.
+// The given slice is not modified.
+func SortResults(results []SearchResult, order types.SortOrder) []SearchResult {
+	sorted := slices.Clone(results)
+
+	switch order {
+	case types.SortOrderAlphabetical:
+		slices.SortStableFunc(sorted, compareContextName)
+	case types.SortOrderStore:
+		slices.SortStableFunc(sorted, func(a, b SearchResult) int {
+			if c := strings.Compare(a.StoreID, b.StoreID); c != 0 {
+				return c
+			}
+			return compareContextName(a, b)
+		})
+	case types.SortOrderFrecency:
+		scores := getFrecencyScores()
+		slices.SortStableFunc(sorted, func(a, b SearchResult) int {
+			scoreA, scoreB := scores[a.ContextName], scores[b.ContextName]
+			if scoreA > scoreB {
+				return -1
+			}
+			if scoreA < scoreB {
+				return 1
+			}
+			return compareContextName(a, b)
+		})
+	case types.SortOrderPriority:
+		slices.SortStableFunc(sorted, func(a, b SearchResult) int {
+			if a.PriorityScore != b.PriorityScore {
+				return b.PriorityScore - a.PriorityScore
+			}
+			return compareContextName(a, b)
+		})
+	}
+
+	return sorted
+}
+
+func compareContextName(a, b SearchResult) int {
+	return strings.Compare(a.ContextName, b.ContextName)
+}
+
+// getFrecencyScores computes a score for each context in the history.
+// Every history entry adds to the score of its context, more recent entries weigh more.
+// Returns an empty map if the history cannot be read, so that the results are sorted alphabetically.
+func getFrecencyScores() map[string]float64 {
+	scores := map[string]float64{}
+
+	// the history is ordered from the most recent to the oldest entry
+	history, err := historyutil.ReadHistory()
+	if err != nil {
+		return scores
+	}
+
+	for i, entry := range history {
+		context, _, err := historyutil.ParseHistoryEntry(entry)
+		if err != nil || context == nil {
+			continue
+		}
+		scores[*context] += 1 / float64(i+1)
+	}
+	return scores
+}
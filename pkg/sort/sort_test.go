@@ -0,0 +1,67 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sort_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	sortutil "github.com/danielfoehrkn/kubeswitch/pkg/sort"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("SortResults", func() {
+	results := []sortutil.SearchResult{
+		{ContextName: "local/kind", StoreID: "filesystem.default"},
+		{ContextName: "eks/staging", StoreID: "eks.default", PriorityScore: 10},
+		{ContextName: "gke/prod", StoreID: "gke.default", PriorityScore: 5},
+		{ContextName: "eks/prod", StoreID: "eks.default", PriorityScore: 10},
+		{ContextName: "local/minikube", StoreID: "filesystem.default"},
+	}
+
+	contextNames := func(results []sortutil.SearchResult) []string {
+		var names []string
+		for _, result := range results {
+			names = append(names, result.ContextName)
+		}
+		return names
+	}
+
+	It("should keep the order for sort order none", func() {
+		Expect(sortutil.SortResults(results, types.SortOrderNone)).To(Equal(results))
+	})
+
+	It("should sort alphabetically", func() {
+		Expect(contextNames(sortutil.SortResults(results, types.SortOrderAlphabetical))).To(Equal([]string{
+			"eks/prod", "eks/staging", "gke/prod", "local/kind", "local/minikube",
+		}))
+	})
+
+	It("should sort by priority score and then by context name", func() {
+		Expect(contextNames(sortutil.SortResults(results, types.SortOrderPriority))).To(Equal([]string{
+			"eks/prod", "eks/staging", "gke/prod", "local/kind", "local/minikube",
+		}))
+
+		lowPriority := append([]sortutil.SearchResult{{ContextName: "a/dev", StoreID: "vault.default", PriorityScore: -1}}, results...)
+		Expect(contextNames(sortutil.SortResults(lowPriority, types.SortOrderPriority))).To(Equal([]string{
+			"eks/prod", "eks/staging", "gke/prod", "local/kind", "local/minikube", "a/dev",
+		}))
+	})
+
+	It("should not modify the given results", func() {
+		sortutil.SortResults(results, types.SortOrderPriority)
+		Expect(results[0].ContextName).To(Equal("local/kind"))
+	})
+})
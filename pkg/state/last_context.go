@@ -0,0 +1,63 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+)
+
+// lastContextFileName is the name of the file in the state directory containing the context active before the last switch
+const lastContextFileName = "last-context"
+
+// ReadLastContext returns the name of the context that was active before the last switch.
+// Returns an error if no switch has been recorded yet.
+func ReadLastContext(stateDir string) (string, error) {
+	bytes, err := os.ReadFile(filepath.Join(stateDir, lastContextFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("no previous context found. The previous context is recorded after switching contexts")
+		}
+		return "", fmt.Errorf("failed to read the previous context: %v", err)
+	}
+
+	contextName := strings.TrimSpace(string(bytes))
+	if len(contextName) == 0 {
+		return "", fmt.Errorf("no previous context found. The previous context is recorded after switching contexts")
+	}
+	return contextName, nil
+}
+
+// WriteLastContext persists the name of the context that was active before a switch,
+// so that it is available across terminal sessions
+func WriteLastContext(stateDir, contextName string) error {
+	if err := kubeswitchio.MkdirAll(stateDir, 0700); err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(filepath.Join(stateDir, lastContextFileName), []byte(contextName+"\n"), 0600)
+}
+
+// RememberPreviousContext records the context active before switching to the new context.
+// Nothing is recorded if no context was active or the context does not change.
+func RememberPreviousContext(stateDir, previousContext, newContext string) error {
+	if len(previousContext) == 0 || previousContext == newContext {
+		return nil
+	}
+	return WriteLastContext(stateDir, previousContext)
+}
@@ -0,0 +1,108 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/state"
+)
+
+var _ = Describe("Last context", func() {
+	var (
+		tmp      string
+		stateDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmp, err = os.MkdirTemp("", "state")
+		Expect(err).ToNot(HaveOccurred())
+		// the state directory is created on the first write
+		stateDir = filepath.Join(tmp, "switch-state")
+	})
+
+	AfterEach(func() {
+		kubeswitchio.SetWriter(kubeswitchio.FileWriter{})
+		Expect(os.RemoveAll(tmp)).To(Succeed())
+	})
+
+	It("should read the written context", func() {
+		Expect(state.WriteLastContext(stateDir, "dev")).To(Succeed())
+		Expect(state.ReadLastContext(stateDir)).To(Equal("dev"))
+
+		Expect(state.WriteLastContext(stateDir, "prod")).To(Succeed())
+		Expect(state.ReadLastContext(stateDir)).To(Equal("prod"))
+	})
+
+	It("should fail if no context was written", func() {
+		_, err := state.ReadLastContext(stateDir)
+		Expect(err).To(MatchError(ContainSubstring("no previous context found")))
+	})
+
+	It("should fail if the file is empty", func() {
+		Expect(os.MkdirAll(stateDir, 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(stateDir, "last-context"), []byte("  \n"), 0600)).To(Succeed())
+
+		_, err := state.ReadLastContext(stateDir)
+		Expect(err).To(MatchError(ContainSubstring("no previous context found")))
+	})
+
+	It("should ignore surrounding whitespace", func() {
+		Expect(os.MkdirAll(stateDir, 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(stateDir, "last-context"), []byte(" dev\r\n"), 0600)).To(Succeed())
+
+		Expect(state.ReadLastContext(stateDir)).To(Equal("dev"))
+	})
+
+	It("should only be readable by the user", func() {
+		Expect(state.WriteLastContext(stateDir, "dev")).To(Succeed())
+
+		info, err := os.Stat(filepath.Join(stateDir, "last-context"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+
+	It("should not write the context with --dry-run", func() {
+		kubeswitchio.SetWriter(kubeswitchio.NewDryRunWriter(false, ""))
+
+		Expect(state.WriteLastContext(stateDir, "dev")).To(Succeed())
+		Expect(filepath.Join(stateDir, "last-context")).ToNot(BeAnExistingFile())
+	})
+
+	Context("RememberPreviousContext", func() {
+		It("should remember the previous context", func() {
+			Expect(state.RememberPreviousContext(stateDir, "dev", "prod")).To(Succeed())
+			Expect(state.ReadLastContext(stateDir)).To(Equal("dev"))
+		})
+
+		It("should not remember anything if no context was active", func() {
+			Expect(state.RememberPreviousContext(stateDir, "", "prod")).To(Succeed())
+			_, err := state.ReadLastContext(stateDir)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should keep the previous context when switching to the active context again", func() {
+			Expect(state.RememberPreviousContext(stateDir, "dev", "prod")).To(Succeed())
+			Expect(state.RememberPreviousContext(stateDir, "prod", "prod")).To(Succeed())
+			Expect(state.ReadLastContext(stateDir)).To(Equal("dev"))
+		})
+	})
+})
@@ -0,0 +1,64 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+)
+
+// lastSeenFileName is the name of the file in the state directory recording when contexts were last found in a kubeconfig store
+const lastSeenFileName = "last-seen.json"
+
+// ReadLastSeen returns the time each context was last found in a kubeconfig store.
+// Returns an empty map if nothing has been recorded yet.
+func ReadLastSeen(stateDir string) (map[string]time.Time, error) {
+	lastSeen := make(map[string]time.Time)
+
+	bytes, err := os.ReadFile(filepath.Join(stateDir, lastSeenFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lastSeen, nil
+		}
+		return nil, fmt.Errorf("failed to read the last seen times of the contexts: %v", err)
+	}
+
+	if len(bytes) == 0 {
+		return lastSeen, nil
+	}
+
+	if err := json.Unmarshal(bytes, &lastSeen); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the last seen times of the contexts: %v", err)
+	}
+	return lastSeen, nil
+}
+
+// WriteLastSeen persists the time each context was last found in a kubeconfig store
+func WriteLastSeen(stateDir string, lastSeen map[string]time.Time) error {
+	bytes, err := json.MarshalIndent(lastSeen, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := kubeswitchio.MkdirAll(stateDir, 0700); err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(filepath.Join(stateDir, lastSeenFileName), append(bytes, '\n'), 0600)
+}
@@ -19,6 +19,7 @@ import (
 	"os"
 	"time"
 
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
 	"github.com/danielfoehrkn/kubeswitch/types"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -54,13 +55,6 @@ func GetHookState(log *logrus.Entry, hookStateFilepath string) (*types.HookState
 }
 
 func UpdateHookState(hookName, stateFileName string) error {
-	// creates or truncate/clean the existing state file (only state is last execution anyways atm.)
-	file, err := os.Create(stateFileName)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
 	state := &types.HookState{
 		HookName:          hookName,
 		LastExecutionTime: time.Now().UTC(),
@@ -71,10 +65,6 @@ func UpdateHookState(hookName, stateFileName string) error {
 		return err
 	}
 
-	_, err = file.Write(output)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// creates or truncate/clean the existing state file (only state is last execution anyways atm.)
+	return kubeswitchio.WriteFile(stateFileName, output, 0644)
 }
@@ -0,0 +1,88 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/smithy-go/logging"
+)
+
+// APICallLogger logs the raw requests and responses of the API clients of the stores
+type APICallLogger interface {
+	// WrapTransport returns a transport logging the requests and responses of the given transport
+	WrapTransport(next http.RoundTripper) http.RoundTripper
+	// Logf logs a request or response dumped by an API client that logs its API calls itself (AWS SDK)
+	Logf(format string, v ...interface{})
+}
+
+// apiCallLogger is set when debugging the API calls of a store
+var apiCallLogger APICallLogger
+
+// defaultTransport is the default HTTP transport before it is possibly replaced to log the API calls
+var defaultTransport = http.DefaultTransport.(*http.Transport)
+
+// SetAPICallLogger instruments the API clients of the stores created afterwards to log their API calls to the given logger
+func SetAPICallLogger(logger APICallLogger) {
+	apiCallLogger = logger
+}
+
+// wrapTransport wraps the transport of an API client to log its API calls, if enabled
+func wrapTransport(transport http.RoundTripper) http.RoundTripper {
+	if apiCallLogger == nil {
+		return transport
+	}
+	return apiCallLogger.WrapTransport(transport)
+}
+
+// transportWrapper returns the function wrapping the transports of client-go based API clients to log their API calls,
+// or nil if not enabled
+func transportWrapper() func(http.RoundTripper) http.RoundTripper {
+	if apiCallLogger == nil {
+		return nil
+	}
+	return apiCallLogger.WrapTransport
+}
+
+// cloneDefaultTransport returns a copy of the default HTTP transport
+func cloneDefaultTransport() *http.Transport {
+	return defaultTransport.Clone()
+}
+
+// NewHTTPClient returns an HTTP client with a copy of the default HTTP transport that logs its API calls, if enabled.
+// Used by the stores outside of this package.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: wrapTransport(cloneDefaultTransport()),
+		Timeout:   timeout,
+	}
+}
+
+// awsAPICallLogOptions returns the options of the AWS SDK to log the API calls including their bodies, if enabled
+func awsAPICallLogOptions() []func(*awsconfig.LoadOptions) error {
+	if apiCallLogger == nil {
+		return nil
+	}
+
+	return []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithClientLogMode(aws.LogRequestWithBody | aws.LogResponseWithBody),
+		awsconfig.WithLogger(logging.LoggerFunc(func(_ logging.Classification, format string, v ...interface{}) {
+			apiCallLogger.Logf(format, v...)
+		})),
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoselect
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// Select returns the mapping whose directory contains the working directory.
+// If the working directory is within multiple mapped directories, the mapping with the longest directory is returned.
+// Returns nil if no mapping matches.
+func Select(mappings []types.DirMapping, workingDirectory string) *types.DirMapping {
+	workingDirectory = filepath.Clean(workingDirectory)
+
+	var (
+		selected       *types.DirMapping
+		selectedLength int
+	)
+	for i, mapping := range mappings {
+		directory := filepath.Clean(util.ResolveKubeconfigPath(mapping.Directory))
+		if !isWithin(workingDirectory, directory) {
+			continue
+		}
+		if selected == nil || len(directory) > selectedLength {
+			selected = &mappings[i]
+			selectedLength = len(directory)
+		}
+	}
+	return selected
+}
+
+// FilterStores returns the store selected by the mapping.
+// The store of the mapping is matched against the store ID with and without the store kind.
+func FilterStores(stores []store.KubeconfigStore, mapping types.DirMapping) ([]store.KubeconfigStore, error) {
+	for _, s := range stores {
+		id := s.GetStoreConfig().ID
+		if s.GetID() == mapping.Store || (id != nil && *id == mapping.Store) {
+			return []store.KubeconfigStore{s}, nil
+		}
+	}
+	return nil, fmt.Errorf("the directory %q is mapped to the unknown store %q", mapping.Directory, mapping.Store)
+}
+
+// isWithin checks if the path is the directory or one of its subdirectories
+func isWithin(path, directory string) bool {
+	if path == directory {
+		return true
+	}
+	if !strings.HasSuffix(directory, string(filepath.Separator)) {
+		directory += string(filepath.Separator)
+	}
+	return strings.HasPrefix(path, directory)
+}
@@ -0,0 +1,99 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package autoselect_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/autoselect"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// fakeStore only implements the methods used to select a store
+type fakeStore struct {
+	store.KubeconfigStore
+	id     string
+	config types.KubeconfigStore
+}
+
+func (s *fakeStore) GetID() string {
+	return s.id
+}
+
+func (s *fakeStore) GetStoreConfig() types.KubeconfigStore {
+	return s.config
+}
+
+var _ = Describe("Autoselect", func() {
+	mappings := []types.DirMapping{
+		{Directory: "/work", Store: "filesystem.default"},
+		{Directory: "/work/project-a", Store: "eks-dev"},
+		{Directory: "/work/project-b", Store: "gke.prod"},
+	}
+
+	Describe("Select", func() {
+		It("should select the mapping of a subdirectory", func() {
+			Expect(autoselect.Select(mappings, "/work/project-a/subdir")).To(Equal(&mappings[1]))
+		})
+
+		It("should select the mapping with the longest directory", func() {
+			Expect(autoselect.Select(mappings, "/work/project-a")).To(Equal(&mappings[1]))
+			Expect(autoselect.Select(mappings, "/work/project-b/")).To(Equal(&mappings[2]))
+			Expect(autoselect.Select(mappings, "/work/project-c")).To(Equal(&mappings[0]))
+		})
+
+		It("should only match whole path components", func() {
+			Expect(autoselect.Select(mappings, "/work/project-abc")).To(Equal(&mappings[0]))
+			Expect(autoselect.Select(mappings, "/workspace")).To(BeNil())
+		})
+
+		It("should expand the home directory", func() {
+			home, err := os.UserHomeDir()
+			Expect(err).ToNot(HaveOccurred())
+
+			mapping := types.DirMapping{Directory: "~/work/project-a", Store: "eks-dev"}
+			Expect(autoselect.Select([]types.DirMapping{mapping}, filepath.Join(home, "work", "project-a", "subdir"))).To(Equal(&mapping))
+		})
+
+		It("should not select a mapping without mappings", func() {
+			Expect(autoselect.Select(nil, "/work/project-a")).To(BeNil())
+		})
+	})
+
+	Describe("FilterStores", func() {
+		stores := []store.KubeconfigStore{
+			&fakeStore{id: "filesystem.default"},
+			&fakeStore{id: "eks.eks-dev", config: types.KubeconfigStore{ID: ptr.To("eks-dev")}},
+			&fakeStore{id: "gke.prod", config: types.KubeconfigStore{ID: ptr.To("prod")}},
+		}
+
+		It("should select the store by its ID with and without the kind", func() {
+			Expect(autoselect.FilterStores(stores, mappings[0])).To(Equal(stores[0:1]))
+			Expect(autoselect.FilterStores(stores, mappings[1])).To(Equal(stores[1:2]))
+			Expect(autoselect.FilterStores(stores, mappings[2])).To(Equal(stores[2:3]))
+		})
+
+		It("should fail for an unknown store", func() {
+			_, err := autoselect.FilterStores(stores, types.DirMapping{Directory: "/work", Store: "unknown"})
+			Expect(err).To(MatchError(ContainSubstring(`mapped to the unknown store "unknown"`)))
+		})
+	})
+})
@@ -0,0 +1,167 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache contains a store wrapper caching the kubeconfigs of the wrapped store,
+// e.g. to reduce the API calls against cloud providers when switching frequently.
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// Cache stores kubeconfigs per kubeconfig path
+type Cache interface {
+	// Get returns the cached kubeconfig for the path and the time it was cached
+	Get(path string) ([]byte, time.Time, bool)
+	// Set caches the kubeconfig for the path
+	Set(path string, kubeconfig []byte) error
+}
+
+// Flusher is implemented by caches whose entries can be removed
+type Flusher interface {
+	// Flush removes all entries and returns the number of removed entries
+	Flush() (int, error)
+}
+
+// CachingStore caches the kubeconfigs returned by the wrapped store.
+// On a cache miss, the kubeconfig is read from the wrapped store and written to the cache.
+type CachingStore struct {
+	upstream store.KubeconfigStore
+	cache    Cache
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// flushableCachingStore is a CachingStore whose cache can be flushed, e.g. by "switch clean"
+type flushableCachingStore struct {
+	*CachingStore
+}
+
+// NewCachingStore wraps the store to cache its kubeconfigs in the given cache.
+// Cached kubeconfigs expire after the ttl. If the ttl is not positive, cached kubeconfigs do not expire.
+// The returned store implements Flusher if the cache does.
+func NewCachingStore(upstream store.KubeconfigStore, cache Cache, ttl time.Duration) store.KubeconfigStore {
+	s := &CachingStore{
+		upstream: upstream,
+		cache:    cache,
+		ttl:      ttl,
+		now:      time.Now,
+	}
+	if _, ok := cache.(Flusher); ok {
+		return &flushableCachingStore{s}
+	}
+	return s
+}
+
+// GetKubeconfigForPath implements the store.KubeconfigStore interface.
+// It intercepts calls to GetKubeconfigForPath and returns the cached kubeconfig if it did not expire.
+func (s *CachingStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	if kubeconfig, cachedAt, ok := s.cache.Get(path); ok {
+		if s.ttl <= 0 || s.now().Sub(cachedAt) < s.ttl {
+			s.GetLogger().Debugf("GetKubeconfigForPath: %s found in cache", path)
+			return kubeconfig, nil
+		}
+		s.GetLogger().Debugf("GetKubeconfigForPath: cached kubeconfig of %s expired", path)
+	} else {
+		s.GetLogger().Debugf("GetKubeconfigForPath: %s not cached", path)
+	}
+
+	kubeconfig, err := s.upstream.GetKubeconfigForPath(ctx, path, tags)
+	if err != nil {
+		// errors are not cached
+		return nil, err
+	}
+
+	if err := s.cache.Set(path, kubeconfig); err != nil {
+		// the kubeconfig can still be used
+		s.GetLogger().Warnf("failed to cache kubeconfig of %s: %v", path, err)
+	}
+	return kubeconfig, nil
+}
+
+// Cache returns the cache of the kubeconfigs
+func (s *CachingStore) Cache() Cache {
+	return s.cache
+}
+
+// PersistsKubeconfigs returns true if the kubeconfigs are cached on disk
+func (s *CachingStore) PersistsKubeconfigs() bool {
+	_, ok := s.cache.(*DiskCache)
+	return ok
+}
+
+func (s *flushableCachingStore) Flush() (int, error) {
+	return s.cache.(Flusher).Flush()
+}
+
+func (s *CachingStore) GetID() string {
+	return s.upstream.GetID()
+}
+
+func (s *CachingStore) GetKind() types.StoreKind {
+	return s.upstream.GetKind()
+}
+
+func (s *CachingStore) GetContextPrefix(path string) string {
+	return s.upstream.GetContextPrefix(path)
+}
+
+func (s *CachingStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	return s.upstream.VerifyKubeconfigPaths(ctx)
+}
+
+func (s *CachingStore) Probe(ctx context.Context) error {
+	return s.upstream.Probe(ctx)
+}
+
+func (s *CachingStore) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	s.upstream.StartSearch(ctx, channel)
+}
+
+func (s *CachingStore) GetLogger() *logrus.Entry {
+	return s.upstream.GetLogger()
+}
+
+// Stop stops the wrapped store and flushes the cache.
+// The kubeconfigs cached in memory are released. The kubeconfigs cached on disk are already written when they are cached.
+func (s *CachingStore) Stop(ctx context.Context) error {
+	if memoryCache, ok := s.cache.(*MemoryCache); ok {
+		memoryCache.clear()
+	}
+	return s.upstream.Stop(ctx)
+}
+
+func (s *CachingStore) GetStoreConfig() types.KubeconfigStore {
+	return s.upstream.GetStoreConfig()
+}
+
+func (s *CachingStore) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
+	previewer, ok := s.upstream.(store.Previewer)
+	if !ok {
+		// if the wrapped store is not a previewer, simply return an empty string, hence causing no visual distortion
+		return "", nil
+	}
+
+	return previewer.GetSearchPreview(path, optionalTags)
+}
+
+func (s *CachingStore) Unwrap() store.KubeconfigStore {
+	return s.upstream
+}
@@ -0,0 +1,222 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/cache"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// countingStore returns the path and the number of requests as kubeconfig
+type countingStore struct {
+	requests int
+	stops    int
+	err      error
+}
+
+func (f *countingStore) GetID() string                                        { return "eks.default" }
+func (f *countingStore) GetKind() types.StoreKind                             { return types.StoreKindEKS }
+func (f *countingStore) GetContextPrefix(string) string                       { return "" }
+func (f *countingStore) VerifyKubeconfigPaths(context.Context) error          { return nil }
+func (f *countingStore) Probe(context.Context) error                          { return nil }
+func (f *countingStore) GetLogger() *logrus.Entry                             { return testutil.NewTestLogger() }
+func (f *countingStore) GetStoreConfig() types.KubeconfigStore                { return types.KubeconfigStore{} }
+func (f *countingStore) StartSearch(context.Context, chan store.SearchResult) {}
+func (f *countingStore) Stop(context.Context) error                           { f.stops++; return nil }
+
+func (f *countingStore) GetKubeconfigForPath(_ context.Context, path string, _ map[string]string) ([]byte, error) {
+	f.requests++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return []byte(fmt.Sprintf("%s-%d", path, f.requests)), nil
+}
+
+var _ = Describe("CachingStore", func() {
+	var upstream *countingStore
+
+	BeforeEach(func() {
+		upstream = &countingStore{}
+	})
+
+	get := func(s store.KubeconfigStore, path string) string {
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), path, nil)
+		Expect(err).ToNot(HaveOccurred())
+		return string(kubeconfig)
+	}
+
+	It("should cache the kubeconfigs per path", func() {
+		s := cache.NewCachingStore(upstream, cache.NewMemoryCache(), 0)
+
+		Expect(get(s, "a")).To(Equal("a-1"))
+		Expect(get(s, "a")).To(Equal("a-1"))
+		Expect(get(s, "b")).To(Equal("b-2"))
+		Expect(upstream.requests).To(Equal(2))
+		Expect(store.Unwrap(s)).To(BeIdenticalTo(upstream))
+
+		_, flushable := s.(cache.Flusher)
+		Expect(flushable).To(BeFalse())
+	})
+
+	It("should fetch expired kubeconfigs again", func() {
+		s := cache.NewCachingStore(upstream, cache.NewMemoryCache(), 50*time.Millisecond)
+
+		Expect(get(s, "a")).To(Equal("a-1"))
+		time.Sleep(60 * time.Millisecond)
+		Expect(get(s, "a")).To(Equal("a-2"))
+		Expect(get(s, "a")).To(Equal("a-2"))
+	})
+
+	It("should not cache errors", func() {
+		s := cache.NewCachingStore(upstream, cache.NewMemoryCache(), 0)
+		upstream.err = errors.New("quota exceeded")
+
+		_, err := s.GetKubeconfigForPath(context.Background(), "a", nil)
+		Expect(err).To(MatchError("quota exceeded"))
+
+		upstream.err = nil
+		Expect(get(s, "a")).To(Equal("a-2"))
+	})
+
+	It("should release the kubeconfigs cached in memory when stopped", func() {
+		s := cache.NewCachingStore(upstream, cache.NewMemoryCache(), 0)
+		Expect(get(s, "a")).To(Equal("a-1"))
+
+		Expect(s.Stop(context.Background())).To(Succeed())
+		Expect(s.Stop(context.Background())).To(Succeed())
+		Expect(upstream.stops).To(Equal(2))
+		Expect(get(s, "a")).To(Equal("a-2"))
+	})
+
+	Context("DiskCache", func() {
+		var directory string
+
+		BeforeEach(func() {
+			var err error
+			directory, err = os.MkdirTemp("", "kubeswitch-disk-cache")
+			Expect(err).ToNot(HaveOccurred())
+			directory = filepath.Join(directory, "eks.default")
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(filepath.Dir(directory))).To(Succeed())
+		})
+
+		It("should persist the kubeconfigs across stores", func() {
+			Expect(get(cache.NewCachingStore(upstream, cache.NewDiskCache(directory), time.Hour), "a")).To(Equal("a-1"))
+			Expect(get(cache.NewCachingStore(upstream, cache.NewDiskCache(directory), time.Hour), "a")).To(Equal("a-1"))
+			Expect(upstream.requests).To(Equal(1))
+
+			// the file is named by the SHA256 of the path
+			data, err := os.ReadFile(filepath.Join(directory, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal("a-1"))
+		})
+
+		It("should fetch expired kubeconfigs again", func() {
+			s := cache.NewCachingStore(upstream, cache.NewDiskCache(directory), time.Hour)
+			Expect(get(s, "a")).To(Equal("a-1"))
+
+			file := filepath.Join(directory, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb")
+			past := time.Now().Add(-2 * time.Hour)
+			Expect(os.Chtimes(file, past, past)).To(Succeed())
+			Expect(get(s, "a")).To(Equal("a-2"))
+		})
+
+		It("should flush the cached kubeconfigs", func() {
+			s := cache.NewCachingStore(upstream, cache.NewDiskCache(directory), time.Hour)
+			Expect(get(s, "a")).To(Equal("a-1"))
+			Expect(get(s, "b")).To(Equal("b-2"))
+
+			flusher, ok := s.(cache.Flusher)
+			Expect(ok).To(BeTrue())
+			Expect(flusher.Flush()).To(Equal(2))
+			Expect(get(s, "a")).To(Equal("a-3"))
+		})
+
+		It("should compress the cached kubeconfigs", func() {
+			s := cache.NewCachingStore(upstream, cache.NewCompressedDiskCache(directory), time.Hour)
+			Expect(get(s, "a")).To(Equal("a-1"))
+			Expect(get(cache.NewCachingStore(upstream, cache.NewCompressedDiskCache(directory), time.Hour), "a")).To(Equal("a-1"))
+			Expect(upstream.requests).To(Equal(1))
+
+			data, err := os.ReadFile(filepath.Join(directory, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.yaml.gz"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data[:2]).To(Equal([]byte{0x1f, 0x8b}))
+		})
+
+		It("should read the kubeconfigs cached before the compression has been enabled", func() {
+			Expect(get(cache.NewCachingStore(upstream, cache.NewDiskCache(directory), time.Hour), "a")).To(Equal("a-1"))
+
+			compressed := cache.NewCachingStore(upstream, cache.NewCompressedDiskCache(directory), time.Hour)
+			Expect(get(compressed, "a")).To(Equal("a-1"))
+			Expect(upstream.requests).To(Equal(1))
+		})
+
+		It("should read the kubeconfigs cached before the compression has been disabled", func() {
+			Expect(get(cache.NewCachingStore(upstream, cache.NewCompressedDiskCache(directory), time.Hour), "a")).To(Equal("a-1"))
+
+			uncompressed := cache.NewCachingStore(upstream, cache.NewDiskCache(directory), time.Hour)
+			Expect(get(uncompressed, "a")).To(Equal("a-1"))
+			Expect(upstream.requests).To(Equal(1))
+		})
+
+		It("should replace the kubeconfig cached in the other format", func() {
+			uncompressed := cache.NewDiskCache(directory)
+			Expect(uncompressed.Set("a", []byte("a-old"))).To(Succeed())
+
+			compressed := cache.NewCompressedDiskCache(directory)
+			Expect(compressed.Set("a", []byte("a-new"))).To(Succeed())
+
+			files, err := os.ReadDir(directory)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(HaveLen(1))
+			Expect(files[0].Name()).To(Equal("ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb.yaml.gz"))
+
+			cached, _, ok := uncompressed.Get("a")
+			Expect(ok).To(BeTrue())
+			Expect(string(cached)).To(Equal("a-new"))
+		})
+
+		It("should reduce the disk usage of typical kubeconfigs by at least 25%", func() {
+			kubeconfig := typicalKubeconfig()
+			compressed := cache.NewCompressedDiskCache(directory)
+			Expect(compressed.Set("a", kubeconfig)).To(Succeed())
+
+			cached, _, ok := compressed.Get("a")
+			Expect(ok).To(BeTrue())
+			Expect(cached).To(Equal(kubeconfig))
+
+			stats, err := compressed.Stats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats.Files).To(Equal(1))
+			Expect(stats.UncompressedSize).To(BeEquivalentTo(len(kubeconfig)))
+			Expect(float64(stats.SizeOnDisk)).To(BeNumerically("<=", 0.75*float64(stats.UncompressedSize)))
+		})
+	})
+})
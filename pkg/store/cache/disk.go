@@ -0,0 +1,250 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+)
+
+const (
+	// DefaultDiskCacheDirectory is the default directory of the DiskCache relative to the state directory
+	DefaultDiskCacheDirectory = "kubeconfig-cache"
+	// compressedFileExtension is the file extension of gzip compressed kubeconfigs
+	compressedFileExtension = ".yaml.gz"
+)
+
+// DiskCache persists kubeconfigs across invocations of kubeswitch.
+// Each kubeconfig is written to an individual file in the cache directory named by the SHA256 of its path.
+// The modification time of the file is the time the kubeconfig was cached.
+// The files are not encrypted, hence the directory is only accessible by the user.
+type DiskCache struct {
+	directory string
+	// compress gzip compresses the kubeconfigs in files with the extension ".yaml.gz"
+	compress bool
+}
+
+// Stats are the statistics of the kubeconfigs persisted by a cache
+type Stats struct {
+	// Directory is the directory of the cache
+	Directory string
+	// Files is the number of cached kubeconfigs
+	Files int
+	// SizeOnDisk is the size of all cached files in bytes
+	SizeOnDisk int64
+	// UncompressedSize is the size of all cached kubeconfigs in bytes before compression
+	UncompressedSize int64
+}
+
+// StatsProvider is implemented by caches persisting kubeconfigs on disk
+type StatsProvider interface {
+	// Stats returns the statistics of the cached kubeconfigs
+	Stats() (Stats, error)
+}
+
+// NewDiskCache returns a cache persisting kubeconfigs in the given directory.
+// The directory must not be shared by multiple stores, as the paths of different stores might collide.
+func NewDiskCache(directory string) *DiskCache {
+	return &DiskCache{directory: directory}
+}
+
+// NewCompressedDiskCache returns a cache persisting gzip compressed kubeconfigs in the given directory.
+// Kubeconfigs cached without compression are still read, so that enabling compression does not invalidate the cache.
+func NewCompressedDiskCache(directory string) *DiskCache {
+	return &DiskCache{directory: directory, compress: true}
+}
+
+// Get returns the cached kubeconfig in the configured format.
+// Falls back to the kubeconfig cached in the other format, e.g. before compression has been enabled.
+func (c *DiskCache) Get(path string) ([]byte, time.Time, bool) {
+	for _, compressed := range []bool{c.compress, !c.compress} {
+		if kubeconfig, modTime, ok := get(c.file(path, compressed), compressed); ok {
+			return kubeconfig, modTime, true
+		}
+	}
+	return nil, time.Time{}, false
+}
+
+// get reads the cached kubeconfig from the file
+func get(file string, compressed bool) ([]byte, time.Time, bool) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	kubeconfig, err := os.ReadFile(file)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+
+	if compressed {
+		kubeconfig, err = decompress(kubeconfig)
+		if err != nil {
+			return nil, time.Time{}, false
+		}
+	}
+	return kubeconfig, info.ModTime(), true
+}
+
+func (c *DiskCache) Set(path string, kubeconfig []byte) error {
+	if err := kubeswitchio.MkdirAll(c.directory, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	if c.compress {
+		var err error
+		kubeconfig, err = compress(kubeconfig)
+		if err != nil {
+			return fmt.Errorf("failed to compress kubeconfig: %w", err)
+		}
+	}
+	if err := kubeswitchio.WriteFile(c.file(path, c.compress), kubeconfig, 0600); err != nil {
+		return err
+	}
+
+	// the kubeconfig cached in the other format is outdated now
+	outdated := c.file(path, !c.compress)
+	if _, err := os.Stat(outdated); err != nil {
+		return nil
+	}
+	if err := kubeswitchio.Remove(outdated); err != nil {
+		return fmt.Errorf("failed to delete outdated cache file: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the statistics of all files in the cache directory, compressed or not
+func (c *DiskCache) Stats() (Stats, error) {
+	stats := Stats{Directory: c.directory}
+
+	files, err := os.ReadDir(c.directory)
+	if errors.Is(err, os.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		info, err := f.Info()
+		if err != nil {
+			return stats, err
+		}
+
+		uncompressedSize := info.Size()
+		if strings.HasSuffix(f.Name(), compressedFileExtension) {
+			if uncompressedSize, err = gzipUncompressedSize(filepath.Join(c.directory, f.Name())); err != nil {
+				return stats, err
+			}
+		}
+
+		stats.Files++
+		stats.SizeOnDisk += info.Size()
+		stats.UncompressedSize += uncompressedSize
+	}
+	return stats, nil
+}
+
+// Flush deletes all cached kubeconfigs
+func (c *DiskCache) Flush() (int, error) {
+	files, err := os.ReadDir(c.directory)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if err := kubeswitchio.Remove(filepath.Join(c.directory, f.Name())); err != nil {
+			return deleted, fmt.Errorf("failed to delete file '%s': %w", f.Name(), err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// file returns the cache file of the given kubeconfig path, with or without compression
+func (c *DiskCache) file(path string, compressed bool) string {
+	hash := sha256.Sum256([]byte(path))
+	name := hex.EncodeToString(hash[:])
+	if compressed {
+		name += compressedFileExtension
+	}
+	return filepath.Join(c.directory, name)
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// gzipUncompressedSize reads the uncompressed size from the trailer of the gzip file
+// without decompressing it. The size is only correct for files smaller than 4GiB.
+func gzipUncompressedSize(file string) (int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	trailer := make([]byte, 4)
+	if _, err := f.Seek(-4, io.SeekEnd); err != nil {
+		return 0, fmt.Errorf("failed to read size of compressed file %q: %w", file, err)
+	}
+	if _, err := io.ReadFull(f, trailer); err != nil {
+		return 0, fmt.Errorf("failed to read size of compressed file %q: %w", file, err)
+	}
+	return int64(binary.LittleEndian.Uint32(trailer)), nil
+}
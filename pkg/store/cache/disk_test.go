@@ -0,0 +1,107 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/cache"
+)
+
+// typicalKubeconfig returns a kubeconfig like the kubeconfig of a cloud provider cluster:
+// one cluster with an embedded CA certificate, one context and a user authenticating with a token
+func typicalKubeconfig() []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	caData := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}))
+
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: shoot--dev--cluster
+clusters:
+- name: shoot--dev--cluster
+  cluster:
+    certificate-authority-data: %s
+    server: https://api.cluster.dev.example.com
+contexts:
+- name: shoot--dev--cluster
+  context:
+    cluster: shoot--dev--cluster
+    user: shoot--dev--cluster-token
+users:
+- name: shoot--dev--cluster-token
+  user:
+    token: eyJhbGciOiJSUzI1NiIsImtpZCI6IjEifQ
+`, caData))
+}
+
+func BenchmarkDiskCache(b *testing.B) {
+	kubeconfig := typicalKubeconfig()
+
+	for _, benchmark := range []struct {
+		name     string
+		newCache func(directory string) *cache.DiskCache
+	}{
+		{name: "uncompressed", newCache: cache.NewDiskCache},
+		{name: "compressed", newCache: cache.NewCompressedDiskCache},
+	} {
+		b.Run(benchmark.name, func(b *testing.B) {
+			directory := b.TempDir()
+			diskCache := benchmark.newCache(directory)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				path := fmt.Sprintf("path-%d", i%100)
+				if err := diskCache.Set(path, kubeconfig); err != nil {
+					b.Fatal(err)
+				}
+				if _, _, ok := diskCache.Get(path); !ok {
+					b.Fatal("kubeconfig not cached")
+				}
+			}
+			b.StopTimer()
+
+			stats, err := diskCache.Stats()
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportMetric(float64(stats.SizeOnDisk)/float64(stats.Files), "bytes-on-disk/kubeconfig")
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryCache caches kubeconfigs in memory for the lifetime of the process
+type MemoryCache struct {
+	lock    sync.RWMutex
+	entries map[string]memoryCacheEntry
+}
+
+type memoryCacheEntry struct {
+	kubeconfig []byte
+	cachedAt   time.Time
+}
+
+// NewMemoryCache returns an empty in-memory cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]memoryCacheEntry),
+	}
+}
+
+func (c *MemoryCache) Get(path string) ([]byte, time.Time, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	entry, ok := c.entries[path]
+	return entry.kubeconfig, entry.cachedAt, ok
+}
+
+// clear releases all cached kubeconfigs
+func (c *MemoryCache) clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	clear(c.entries)
+}
+
+func (c *MemoryCache) Set(path string, kubeconfig []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries[path] = memoryCacheEntry{
+		kubeconfig: kubeconfig,
+		cachedAt:   time.Now(),
+	}
+	return nil
+}
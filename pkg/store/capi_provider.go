@@ -0,0 +1,77 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CapiProviderGeneric is the CAPI provider used if no provider is configured
+const CapiProviderGeneric = "generic"
+
+// CapiProviderDefaults describes where the kubeconfig secrets of the clusters of a CAPI infrastructure provider are stored
+type CapiProviderDefaults struct {
+	// SecretNameFormat is the name of the kubeconfig secret. "%s" is replaced with the cluster name.
+	SecretNameFormat string
+	// Namespace is the namespace of the kubeconfig secret.
+	// The kubeconfig secret is in the namespace of the cluster if empty.
+	Namespace string
+}
+
+// ProviderDefaults contains the kubeconfig secret location of the clusters by CAPI provider
+var ProviderDefaults = map[string]CapiProviderDefaults{
+	CapiProviderGeneric: {SecretNameFormat: "%s-kubeconfig"},
+	"aws":               {SecretNameFormat: "%s-kubeconfig"},
+	"azure":             {SecretNameFormat: "%s-kubeconfig"},
+	"gcp":               {SecretNameFormat: "%s-kubeconfig"},
+	"openstack":         {SecretNameFormat: "capo-%s-kubeconfig", Namespace: "capo-system"},
+	"vsphere":           {SecretNameFormat: "%s-kubeconfig"},
+	"docker":            {SecretNameFormat: "%s-kubeconfig"},
+}
+
+// SecretKey returns the key of the kubeconfig secret of the cluster
+func (d CapiProviderDefaults) SecretKey(clusterNamespace, clusterName string) client.ObjectKey {
+	namespace := d.Namespace
+	if len(namespace) == 0 {
+		namespace = clusterNamespace
+	}
+
+	return client.ObjectKey{
+		Namespace: namespace,
+		Name:      fmt.Sprintf(d.SecretNameFormat, clusterName),
+	}
+}
+
+// getCapiProviderDefaults returns the defaults of the configured provider
+func getCapiProviderDefaults(provider string) (CapiProviderDefaults, error) {
+	if len(provider) == 0 {
+		provider = CapiProviderGeneric
+	}
+
+	defaults, ok := ProviderDefaults[provider]
+	if !ok {
+		providers := make([]string, 0, len(ProviderDefaults))
+		for p := range ProviderDefaults {
+			providers = append(providers, p)
+		}
+		sort.Strings(providers)
+		return CapiProviderDefaults{}, fmt.Errorf("unknown CAPI provider %q. Supported providers: %s", provider, strings.Join(providers, ", "))
+	}
+	return defaults, nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultBaseURL is the URL of the Cloudflare API
+	DefaultBaseURL = "https://api.cloudflare.com/client/v4"
+	// TunnelDomain is the domain of the public hostnames of the Cloudflare Tunnels
+	TunnelDomain = "cfargotunnel.com"
+)
+
+// IngressRule routes the requests for a public hostname of a Cloudflare Tunnel to a service in the private network
+type IngressRule struct {
+	// Hostname is the public hostname. The catch-all rule has no hostname.
+	Hostname string `json:"hostname,omitempty"`
+	// Service is the URL of the service in the private network, e.g. the API server of a cluster
+	Service string `json:"service"`
+	// OriginRequest contains the settings for the connection to the service
+	OriginRequest map[string]interface{} `json:"originRequest,omitempty"`
+}
+
+// tunnelConfiguration is the remotely managed configuration of a Cloudflare Tunnel
+type tunnelConfiguration struct {
+	Ingress []IngressRule `json:"ingress"`
+	// the remaining settings of the tunnel are preserved when updating the ingress rules
+	Settings map[string]json.RawMessage `json:"-"`
+}
+
+func (c *tunnelConfiguration) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &c.Settings); err != nil {
+		return err
+	}
+	if ingress, ok := c.Settings["ingress"]; ok {
+		delete(c.Settings, "ingress")
+		return json.Unmarshal(ingress, &c.Ingress)
+	}
+	return nil
+}
+
+func (c tunnelConfiguration) MarshalJSON() ([]byte, error) {
+	settings := make(map[string]interface{}, len(c.Settings)+1)
+	for key, value := range c.Settings {
+		settings[key] = value
+	}
+	settings["ingress"] = c.Ingress
+	return json.Marshal(settings)
+}
+
+// APIError is returned for unsuccessful requests to the Cloudflare API
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Messages are the error messages of the response
+	Messages []string
+}
+
+func (e *APIError) Error() string {
+	if len(e.Messages) == 0 {
+		return fmt.Sprintf("request to the Cloudflare API failed with status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("request to the Cloudflare API failed with status code %d: %s", e.StatusCode, strings.Join(e.Messages, ", "))
+}
+
+// Client manages the ingress rules of Cloudflare Tunnels
+type Client struct {
+	// BaseURL is the URL of the Cloudflare API
+	BaseURL string
+	// APIToken is the token to authenticate against the Cloudflare API.
+	// Requires the permission to edit the Cloudflare Tunnels of the account.
+	APIToken string
+	// HTTPClient is the client used for the requests
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the Cloudflare API authenticating with the given token
+func NewClient(apiToken string) *Client {
+	return &Client{
+		BaseURL:    DefaultBaseURL,
+		APIToken:   apiToken,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Hostname returns the public hostname of the cluster with the given name
+func Hostname(name string) string {
+	return fmt.Sprintf("%s.%s", name, TunnelDomain)
+}
+
+// FindHostname returns the ingress rule of the given public hostname
+func FindHostname(rules []IngressRule, hostname string) (IngressRule, bool) {
+	for _, rule := range rules {
+		if strings.EqualFold(rule.Hostname, hostname) {
+			return rule, true
+		}
+	}
+	return IngressRule{}, false
+}
+
+// GetIngressRules returns the ingress rules of the tunnel
+func (c *Client) GetIngressRules(ctx context.Context, accountID, tunnelID string) ([]IngressRule, error) {
+	configuration, err := c.getConfiguration(ctx, accountID, tunnelID)
+	if err != nil {
+		return nil, err
+	}
+	return configuration.Ingress, nil
+}
+
+// PublishHostname routes the public hostname through the tunnel to the given service.
+// An existing ingress rule for the hostname is replaced. New rules are added before the catch-all rule.
+func (c *Client) PublishHostname(ctx context.Context, accountID, tunnelID string, rule IngressRule) error {
+	configuration, err := c.getConfiguration(ctx, accountID, tunnelID)
+	if err != nil {
+		return err
+	}
+
+	configuration.Ingress = setIngressRule(configuration.Ingress, rule)
+
+	body, err := json.Marshal(map[string]interface{}{"config": configuration})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPut, configurationPath(accountID, tunnelID), body, nil)
+}
+
+// setIngressRule replaces the rule with the same hostname or inserts the rule before the catch-all rule
+func setIngressRule(rules []IngressRule, rule IngressRule) []IngressRule {
+	for i := range rules {
+		if strings.EqualFold(rules[i].Hostname, rule.Hostname) {
+			rules[i] = rule
+			return rules
+		}
+	}
+
+	// the last rule of a tunnel must be a catch-all rule without hostname
+	if len(rules) == 0 || len(rules[len(rules)-1].Hostname) > 0 {
+		return append(rules, rule, IngressRule{Service: "http_status:404"})
+	}
+
+	catchAll := rules[len(rules)-1]
+	return append(rules[:len(rules)-1], rule, catchAll)
+}
+
+func (c *Client) getConfiguration(ctx context.Context, accountID, tunnelID string) (*tunnelConfiguration, error) {
+	result := struct {
+		Config *tunnelConfiguration `json:"config"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, configurationPath(accountID, tunnelID), nil, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Config == nil {
+		return &tunnelConfiguration{}, nil
+	}
+	return result.Config, nil
+}
+
+func configurationPath(accountID, tunnelID string) string {
+	return fmt.Sprintf("/accounts/%s/cfd_tunnel/%s/configurations", accountID, tunnelID)
+}
+
+// do sends the request and decodes the result of the response envelope into the given result
+func (c *Client) do(ctx context.Context, method, path string, body []byte, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+c.APIToken)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	envelope := struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+		Result json.RawMessage `json:"result"`
+	}{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return &APIError{StatusCode: response.StatusCode, Messages: []string{fmt.Sprintf("failed to decode response: %v", err)}}
+	}
+
+	if !envelope.Success || response.StatusCode >= http.StatusBadRequest {
+		apiError := &APIError{StatusCode: response.StatusCode}
+		for _, e := range envelope.Errors {
+			apiError.Messages = append(apiError.Messages, fmt.Sprintf("%s (code %d)", e.Message, e.Code))
+		}
+		return apiError
+	}
+
+	if result == nil || len(envelope.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(envelope.Result, result)
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloudflare_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/cloudflare"
+)
+
+const configurationPath = "/accounts/account/cfd_tunnel/tunnel/configurations"
+
+var _ = Describe("Client", func() {
+	var (
+		server        *httptest.Server
+		client        *cloudflare.Client
+		configuration map[string]interface{}
+		authorization string
+	)
+
+	BeforeEach(func() {
+		configuration = map[string]interface{}{
+			"warp-routing": map[string]interface{}{"enabled": true},
+			"ingress": []interface{}{
+				map[string]interface{}{"hostname": "dev-a.cfargotunnel.com", "service": "https://10.0.0.1:6443"},
+				map[string]interface{}{"service": "http_status:404"},
+			},
+		}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization = r.Header.Get("Authorization")
+			if r.URL.Path != configurationPath {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"success":false,"errors":[{"code":1003,"message":"tunnel not found"}]}`))
+				return
+			}
+
+			if r.Method == http.MethodPut {
+				body, _ := io.ReadAll(r.Body)
+				update := map[string]map[string]interface{}{}
+				Expect(json.Unmarshal(body, &update)).To(Succeed())
+				configuration = update["config"]
+			}
+
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"result":  map[string]interface{}{"tunnel_id": "tunnel", "config": configuration},
+			})
+		}))
+
+		client = cloudflare.NewClient("token")
+		client.BaseURL = server.URL
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should return the ingress rules of the tunnel", func() {
+		rules, err := client.GetIngressRules(context.Background(), "account", "tunnel")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(authorization).To(Equal("Bearer token"))
+
+		rule, ok := cloudflare.FindHostname(rules, cloudflare.Hostname("dev-a"))
+		Expect(ok).To(BeTrue())
+		Expect(rule.Service).To(Equal("https://10.0.0.1:6443"))
+
+		_, ok = cloudflare.FindHostname(rules, cloudflare.Hostname("dev-b"))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should add the ingress rule before the catch-all rule and keep the remaining settings", func() {
+		err := client.PublishHostname(context.Background(), "account", "tunnel", cloudflare.IngressRule{
+			Hostname: cloudflare.Hostname("dev-b"),
+			Service:  "https://10.0.0.2:6443",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		rules, err := client.GetIngressRules(context.Background(), "account", "tunnel")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rules).To(Equal([]cloudflare.IngressRule{
+			{Hostname: "dev-a.cfargotunnel.com", Service: "https://10.0.0.1:6443"},
+			{Hostname: "dev-b.cfargotunnel.com", Service: "https://10.0.0.2:6443"},
+			{Service: "http_status:404"},
+		}))
+		Expect(configuration).To(HaveKey("warp-routing"))
+	})
+
+	It("should replace an existing ingress rule", func() {
+		err := client.PublishHostname(context.Background(), "account", "tunnel", cloudflare.IngressRule{
+			Hostname: cloudflare.Hostname("dev-a"),
+			Service:  "https://10.0.0.3:6443",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		rules, err := client.GetIngressRules(context.Background(), "account", "tunnel")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rules).To(HaveLen(2))
+		Expect(rules[0].Service).To(Equal("https://10.0.0.3:6443"))
+	})
+
+	It("should return the status code and messages of failed requests", func() {
+		_, err := client.GetIngressRules(context.Background(), "account", "unknown")
+
+		var apiError *cloudflare.APIError
+		Expect(errors.As(err, &apiError)).To(BeTrue())
+		Expect(apiError.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(apiError.Error()).To(ContainSubstring("tunnel not found (code 1003)"))
+	})
+})
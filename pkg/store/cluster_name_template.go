@@ -0,0 +1,104 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const (
+	// tags identifying the cluster of a kubeconfig path formatted with a cluster name template.
+	// The kubeconfig path cannot be parsed as it has an arbitrary format.
+	tagClusterName   = "clusterName"
+	tagProfile       = "profile"
+	tagProject       = "project"
+	tagLocation      = "location"
+	tagResourceGroup = "resourceGroup"
+)
+
+// ClusterNameTemplateData contains the variables available in the cluster name template of a store.
+// Variables that are not supported by a store are empty.
+type ClusterNameTemplateData struct {
+	// ClusterName is the name of the cluster
+	ClusterName string
+	// Region is the region (EKS) or location (GKE, Azure) of the cluster
+	Region string
+	// AccountID is the AWS account ID (EKS only)
+	AccountID string
+	// ProfileName is the AWS profile (EKS only)
+	ProfileName string
+	// ProjectName is the name of the GCP project (GKE only)
+	ProjectName string
+	// ProjectID is the ID of the GCP project (GKE only)
+	ProjectID string
+	// ResourceGroup is the resource group of the cluster (Azure only)
+	ResourceGroup string
+	// SubscriptionID is the ID of the Azure subscription (Azure only)
+	SubscriptionID string
+}
+
+// ClusterNameTemplate formats the kubeconfig paths of the clusters discovered by a store
+type ClusterNameTemplate struct {
+	template *template.Template
+	custom   bool
+}
+
+// NewClusterNameTemplate parses the cluster name template and verifies that it only uses known variables.
+// Uses the default template of the store if the cluster name template is empty.
+func NewClusterNameTemplate(clusterNameTemplate, defaultTemplate string) (*ClusterNameTemplate, error) {
+	custom := len(clusterNameTemplate) > 0
+	if !custom {
+		clusterNameTemplate = defaultTemplate
+	}
+
+	t, err := template.New("clusterName").Parse(clusterNameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster name template %q: %w", clusterNameTemplate, err)
+	}
+
+	// executing the template with empty data detects unknown variables
+	if err := t.Execute(&bytes.Buffer{}, ClusterNameTemplateData{}); err != nil {
+		return nil, fmt.Errorf("invalid cluster name template %q: %w", clusterNameTemplate, err)
+	}
+	return &ClusterNameTemplate{template: t, custom: custom}, nil
+}
+
+// IsCustom returns true if the kubeconfig paths are not formatted with the default template of the store.
+// Custom kubeconfig paths cannot be parsed, hence the cluster has to be identified using the tags of the search result.
+func (c *ClusterNameTemplate) IsCustom() bool {
+	return c.custom
+}
+
+// Execute returns the kubeconfig path for the given template data
+func (c *ClusterNameTemplate) Execute(data ClusterNameTemplateData) (string, error) {
+	var buffer bytes.Buffer
+	if err := c.template.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+
+	path := strings.TrimSpace(buffer.String())
+	if len(path) == 0 {
+		return "", fmt.Errorf("cluster name template returned an empty kubeconfig path for cluster %q", data.ClusterName)
+	}
+	return path, nil
+}
+
+// usesTemplateVariable checks if the cluster name template references the given variable, e.g. to avoid expensive lookups
+func usesTemplateVariable(clusterNameTemplate, variable string) bool {
+	return strings.Contains(clusterNameTemplate, "."+variable)
+}
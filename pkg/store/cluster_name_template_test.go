@@ -0,0 +1,83 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("ClusterNameTemplate", func() {
+	data := store.ClusterNameTemplateData{
+		ClusterName:    "my-cluster",
+		Region:         "eu-west-1",
+		AccountID:      "123456789012",
+		ProfileName:    "my-profile",
+		ProjectName:    "my-project",
+		ProjectID:      "my-project-id",
+		ResourceGroup:  "my-group",
+		SubscriptionID: "my-subscription",
+	}
+
+	execute := func(clusterNameTemplate, defaultTemplate string) string {
+		t, err := store.NewClusterNameTemplate(clusterNameTemplate, defaultTemplate)
+		Expect(err).ToNot(HaveOccurred())
+		path, err := t.Execute(data)
+		Expect(err).ToNot(HaveOccurred())
+		return path
+	}
+
+	It("should keep the existing kubeconfig path format if no template is configured", func() {
+		Expect(execute("", store.EKSDefaultClusterNameTemplate)).To(Equal("eks_my-profile--eu-west-1--my-cluster"))
+		Expect(execute("", store.GKEDefaultClusterNameTemplate)).To(Equal("gke_my-project--eu-west-1--my-cluster"))
+		Expect(execute("", store.AzureDefaultClusterNameTemplate)).To(Equal("az_my-group--my-cluster"))
+
+		t, err := store.NewClusterNameTemplate("", store.EKSDefaultClusterNameTemplate)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.IsCustom()).To(BeFalse())
+	})
+
+	It("should format the kubeconfig path using the custom template", func() {
+		Expect(execute("{{.AccountID}}-prod-{{.Region}}-{{.ClusterName}}", store.EKSDefaultClusterNameTemplate)).To(Equal("123456789012-prod-eu-west-1-my-cluster"))
+
+		t, err := store.NewClusterNameTemplate("{{.ClusterName}}", store.EKSDefaultClusterNameTemplate)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(t.IsCustom()).To(BeTrue())
+	})
+
+	It("should fail for invalid templates", func() {
+		_, err := store.NewClusterNameTemplate("{{.ClusterName", store.EKSDefaultClusterNameTemplate)
+		Expect(err).To(HaveOccurred())
+
+		_, err = store.NewClusterNameTemplate("{{.Unknown}}", store.EKSDefaultClusterNameTemplate)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail to verify the kubeconfig paths of a store with an invalid template", func() {
+		eksStore := &store.EKSStore{Config: &types.StoreConfigEKS{ClusterNameTemplate: "{{.Unknown}}"}}
+		Expect(eksStore.VerifyKubeconfigPaths(context.Background())).ToNot(Succeed())
+
+		gkeStore := &store.GKEStore{Config: &types.StoreConfigGKE{ClusterNameTemplate: "{{.Unknown}}"}}
+		Expect(gkeStore.VerifyKubeconfigPaths(context.Background())).ToNot(Succeed())
+
+		azureStore := &store.AzureStore{Config: &types.StoreConfigAzure{ClusterNameTemplate: "{{.ClusterName}}"}}
+		Expect(azureStore.VerifyKubeconfigPaths(context.Background())).To(Succeed())
+	})
+})
@@ -0,0 +1,257 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package composite
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// pathSeparator separates the ID of the child store from the kubeconfig path of the child store
+const pathSeparator = "::"
+
+// CompositeStore aggregates multiple kubeconfig stores under a single logical store
+type CompositeStore struct {
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	// Children are the aggregated kubeconfig stores
+	Children []store.KubeconfigStore
+	// childrenByID maps the ID of each child store to the child store
+	childrenByID map[string]store.KubeconfigStore
+}
+
+// NewCompositeStore creates a new composite store.
+// The child stores are created from the store configuration with the given function.
+// Child stores that are not required are skipped if they cannot be created.
+func NewCompositeStore(kubeconfigStore types.KubeconfigStore, newStore func(types.KubeconfigStore) (store.KubeconfigStore, error)) (*CompositeStore, error) {
+	storeConfig := &types.StoreConfigComposite{}
+	if kubeconfigStore.Config != nil {
+		buf, err := yaml.Marshal(kubeconfigStore.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := yaml.Unmarshal(buf, storeConfig); err != nil {
+			return nil, &storeerrors.ErrInvalidConfig{StoreID: configID(kubeconfigStore), Err: fmt.Errorf("failed to unmarshal composite store config: %w", err)}
+		}
+	}
+
+	if len(storeConfig.Stores) == 0 {
+		return nil, &storeerrors.ErrInvalidConfig{StoreID: configID(kubeconfigStore), Err: fmt.Errorf("the composite store requires at least one store in \"config.stores\"")}
+	}
+
+	var children []store.KubeconfigStore
+	childrenByID := make(map[string]store.KubeconfigStore, len(storeConfig.Stores))
+	for _, childConfig := range storeConfig.Stores {
+		child, err := newStore(childConfig)
+		if err != nil {
+			if childConfig.Required != nil && !*childConfig.Required {
+				continue
+			}
+			return nil, fmt.Errorf("unable to create store of kind %q for composite store: %w", childConfig.Kind, err)
+		}
+
+		if _, ok := childrenByID[child.GetID()]; ok {
+			return nil, &storeerrors.ErrInvalidConfig{StoreID: configID(kubeconfigStore), Err: fmt.Errorf("composite store contains multiple stores with the ID %q. Please set a unique ID for each store", child.GetID())}
+		}
+		childrenByID[child.GetID()] = child
+		children = append(children, child)
+	}
+
+	return &CompositeStore{
+		Logger:          logrus.New().WithField("store", types.StoreKindComposite),
+		KubeconfigStore: kubeconfigStore,
+		Children:        children,
+		childrenByID:    childrenByID,
+	}, nil
+}
+
+// GetID returns the unique store ID.
+// If no ID is configured, a deterministic hash of the IDs of the child stores is used.
+func (s *CompositeStore) GetID() string {
+	if s.KubeconfigStore.ID != nil {
+		return fmt.Sprintf("%s.%s", types.StoreKindComposite, *s.KubeconfigStore.ID)
+	}
+
+	ids := make([]string, 0, len(s.Children))
+	for _, child := range s.Children {
+		ids = append(ids, child.GetID())
+	}
+	sort.Strings(ids)
+
+	hash := sha256.Sum256([]byte(strings.Join(ids, ",")))
+	return fmt.Sprintf("%s.%x", types.StoreKindComposite, hash[:8])
+}
+
+func (s *CompositeStore) GetKind() types.StoreKind {
+	return types.StoreKindComposite
+}
+
+func (s *CompositeStore) GetStoreConfig() types.KubeconfigStore {
+	return s.KubeconfigStore
+}
+
+func (s *CompositeStore) GetLogger() *logrus.Entry {
+	return s.Logger
+}
+
+// Stop stops all child stores
+func (s *CompositeStore) Stop(ctx context.Context) error {
+	return store.StopAll(ctx, s.Children)
+}
+
+// GetContextPrefix prepends the ID of the composite store to the prefix of the child store
+func (s *CompositeStore) GetContextPrefix(path string) string {
+	var childPrefix string
+	if child, childPath, err := s.getChildForPath(path); err == nil {
+		childPrefix = child.GetContextPrefix(childPath)
+	}
+
+	if s.KubeconfigStore.ShowPrefix != nil && !*s.KubeconfigStore.ShowPrefix {
+		return childPrefix
+	}
+
+	prefix := string(types.StoreKindComposite)
+	if s.KubeconfigStore.ID != nil {
+		prefix = *s.KubeconfigStore.ID
+	}
+
+	if len(childPrefix) == 0 {
+		return prefix
+	}
+	return fmt.Sprintf("%s/%s", prefix, childPrefix)
+}
+
+// VerifyKubeconfigPaths verifies the search paths of all child stores
+// Errors of child stores that are not required are ignored
+func (s *CompositeStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	for _, child := range s.Children {
+		if err := child.VerifyKubeconfigPaths(ctx); err != nil {
+			if child.GetStoreConfig().Required != nil && !*child.GetStoreConfig().Required {
+				s.Logger.Debugf("ignoring error of child store %q: %v", child.GetID(), err)
+				continue
+			}
+			return fmt.Errorf("child store %q: %w", child.GetID(), err)
+		}
+	}
+	return nil
+}
+
+// Probe probes all child stores
+// Errors of child stores that are not required are ignored
+func (s *CompositeStore) Probe(ctx context.Context) error {
+	for _, child := range s.Children {
+		if err := child.Probe(ctx); err != nil {
+			if child.GetStoreConfig().Required != nil && !*child.GetStoreConfig().Required {
+				s.Logger.Debugf("ignoring error of child store %q: %v", child.GetID(), err)
+				continue
+			}
+			return fmt.Errorf("child store %q: %w", child.GetID(), err)
+		}
+	}
+	return nil
+}
+
+// StartSearch starts the search of all child stores concurrently and merges the results.
+// The kubeconfig paths are prefixed with the ID of the child store that discovered it.
+func (s *CompositeStore) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	wg := sync.WaitGroup{}
+	for _, child := range s.Children {
+		wg.Add(1)
+		go func(child store.KubeconfigStore) {
+			defer wg.Done()
+
+			childChannel := make(chan store.SearchResult)
+			go func() {
+				defer close(childChannel)
+				child.StartSearch(ctx, childChannel)
+			}()
+
+			for result := range childChannel {
+				if result.Error != nil {
+					result.Error = fmt.Errorf("child store %q: %w", child.GetID(), result.Error)
+				} else {
+					result.KubeconfigPath = fmt.Sprintf("%s%s%s", child.GetID(), pathSeparator, result.KubeconfigPath)
+				}
+
+				select {
+				case channel <- result:
+				case <-ctx.Done():
+					// the child store aborts its search as well, but might still send results
+					for range childChannel {
+					}
+					return
+				}
+			}
+		}(child)
+	}
+	wg.Wait()
+}
+
+// GetKubeconfigForPath delegates to the child store owning the path
+func (s *CompositeStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	child, childPath, err := s.getChildForPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return child.GetKubeconfigForPath(ctx, childPath, tags)
+}
+
+// GetSearchPreview delegates to the child store owning the path if it implements the Previewer interface
+func (s *CompositeStore) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
+	child, childPath, err := s.getChildForPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	previewer, ok := child.(store.Previewer)
+	if !ok {
+		return "", nil
+	}
+	return previewer.GetSearchPreview(childPath, optionalTags)
+}
+
+// getChildForPath returns the child store owning the path and the kubeconfig path of the child store
+func (s *CompositeStore) getChildForPath(path string) (store.KubeconfigStore, string, error) {
+	childID, childPath, found := strings.Cut(path, pathSeparator)
+	if !found {
+		return nil, "", &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("kubeconfig path %q does not belong to a child store", path)}
+	}
+
+	child, ok := s.childrenByID[childID]
+	if !ok {
+		return nil, "", &storeerrors.ErrStoreNotFound{StoreID: childID, Err: fmt.Errorf("child store for kubeconfig path %q not found", path)}
+	}
+	return child, childPath, nil
+}
+
+// configID returns the ID of the composite store before its child stores have been created
+func configID(kubeconfigStore types.KubeconfigStore) string {
+	if kubeconfigStore.ID != nil {
+		return fmt.Sprintf("%s.%s", types.StoreKindComposite, *kubeconfigStore.ID)
+	}
+	return string(types.StoreKindComposite)
+}
@@ -0,0 +1,43 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package composite
+
+import (
+	"context"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// FailoverStore retrieves the kubeconfigs from the first of multiple identical replicas of a kubeconfig store.
+// If a replica fails, the next replica is tried and the failed replica is skipped for the configured duration.
+type FailoverStore struct {
+	*replicaSet
+}
+
+// NewFailoverStore creates a new failover store.
+// The replicas are created from the store configuration with the given function.
+func NewFailoverStore(kubeconfigStore types.KubeconfigStore, newStore func(types.KubeconfigStore) (store.KubeconfigStore, error)) (*FailoverStore, error) {
+	replicas, err := newReplicaSet(kubeconfigStore, types.StoreKindFailover, newStore)
+	if err != nil {
+		return nil, err
+	}
+	return &FailoverStore{replicaSet: replicas}, nil
+}
+
+// GetKubeconfigForPath retrieves the kubeconfig from the first healthy replica in the configured order
+func (s *FailoverStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	return s.getKubeconfigForPath(ctx, path, tags, 0)
+}
@@ -0,0 +1,352 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package composite
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// DefaultRetryAfter is the default duration a failed replica is skipped
+const DefaultRetryAfter = 30 * time.Second
+
+// replicaSet contains identical replicas of a kubeconfig store and tracks their health.
+// It implements the store interface except for GetKubeconfigForPath, which depends on the order the replicas are tried in.
+type replicaSet struct {
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	// Replicas are the identical replicas of the kubeconfig store
+	Replicas []store.KubeconfigStore
+	// RetryAfter is the duration a replica is skipped after it failed
+	RetryAfter time.Duration
+
+	kind types.StoreKind
+	now  func() time.Time
+	// unhealthyUntil contains the time until which each replica is skipped
+	unhealthyUntil []time.Time
+	healthMutex    sync.Mutex
+}
+
+// newReplicaSet creates the replicas from the store configuration with the given function.
+// Replicas that are not required are skipped if they cannot be created.
+func newReplicaSet(kubeconfigStore types.KubeconfigStore, kind types.StoreKind, newStore func(types.KubeconfigStore) (store.KubeconfigStore, error)) (*replicaSet, error) {
+	storeConfig := &types.StoreConfigReplicas{}
+	if kubeconfigStore.Config != nil {
+		buf, err := yaml.Marshal(kubeconfigStore.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := yaml.Unmarshal(buf, storeConfig); err != nil {
+			return nil, &storeerrors.ErrInvalidConfig{StoreID: replicaSetConfigID(kubeconfigStore, kind), Err: fmt.Errorf("failed to unmarshal %s store config: %w", kind, err)}
+		}
+	}
+
+	if len(storeConfig.Stores) == 0 {
+		return nil, &storeerrors.ErrInvalidConfig{StoreID: replicaSetConfigID(kubeconfigStore, kind), Err: fmt.Errorf("the %s store requires at least one store in \"config.stores\"", kind)}
+	}
+
+	retryAfter := DefaultRetryAfter
+	if storeConfig.RetryAfter != nil {
+		retryAfter = *storeConfig.RetryAfter
+	}
+
+	var replicas []store.KubeconfigStore
+	for _, replicaConfig := range storeConfig.Stores {
+		replica, err := newStore(replicaConfig)
+		if err != nil {
+			if replicaConfig.Required != nil && !*replicaConfig.Required {
+				continue
+			}
+			return nil, fmt.Errorf("unable to create store of kind %q for %s store: %w", replicaConfig.Kind, kind, err)
+		}
+		replicas = append(replicas, replica)
+	}
+
+	if len(replicas) == 0 {
+		return nil, &storeerrors.ErrInvalidConfig{StoreID: replicaSetConfigID(kubeconfigStore, kind), Err: fmt.Errorf("none of the stores of the %s store could be created", kind)}
+	}
+
+	return &replicaSet{
+		Logger:          logrus.New().WithField("store", kind),
+		KubeconfigStore: kubeconfigStore,
+		Replicas:        replicas,
+		RetryAfter:      retryAfter,
+		kind:            kind,
+		now:             time.Now,
+		unhealthyUntil:  make([]time.Time, len(replicas)),
+	}, nil
+}
+
+// GetID returns the unique store ID.
+// If no ID is configured, a deterministic hash of the IDs of the replicas is used.
+func (s *replicaSet) GetID() string {
+	if s.KubeconfigStore.ID != nil {
+		return fmt.Sprintf("%s.%s", s.kind, *s.KubeconfigStore.ID)
+	}
+
+	ids := make([]string, 0, len(s.Replicas))
+	for _, replica := range s.Replicas {
+		ids = append(ids, replica.GetID())
+	}
+	sort.Strings(ids)
+
+	hash := sha256.Sum256([]byte(strings.Join(ids, ",")))
+	return fmt.Sprintf("%s.%x", s.kind, hash[:8])
+}
+
+func (s *replicaSet) GetKind() types.StoreKind {
+	return s.kind
+}
+
+func (s *replicaSet) GetStoreConfig() types.KubeconfigStore {
+	return s.KubeconfigStore
+}
+
+func (s *replicaSet) GetLogger() *logrus.Entry {
+	return s.Logger
+}
+
+// Stop stops all replicas
+func (s *replicaSet) Stop(ctx context.Context) error {
+	return store.StopAll(ctx, s.Replicas)
+}
+
+// GetContextPrefix returns the configured ID or the prefix of the first replica,
+// so that a context has the same name regardless of the replica it is retrieved from
+func (s *replicaSet) GetContextPrefix(path string) string {
+	if s.KubeconfigStore.ShowPrefix != nil && !*s.KubeconfigStore.ShowPrefix {
+		return ""
+	}
+
+	if s.KubeconfigStore.ID != nil {
+		return *s.KubeconfigStore.ID
+	}
+	return s.Replicas[0].GetContextPrefix(path)
+}
+
+// VerifyKubeconfigPaths verifies the search paths of all replicas.
+// Fails only if the search paths of every replica are invalid.
+func (s *replicaSet) VerifyKubeconfigPaths(ctx context.Context) error {
+	return s.forAnyReplica(func(replica store.KubeconfigStore) error {
+		return replica.VerifyKubeconfigPaths(ctx)
+	})
+}
+
+// Probe probes all replicas.
+// Fails only if no replica is reachable.
+func (s *replicaSet) Probe(ctx context.Context) error {
+	return s.forAnyReplica(func(replica store.KubeconfigStore) error {
+		return replica.Probe(ctx)
+	})
+}
+
+// forAnyReplica calls the function for every replica and only returns an error if it failed for every replica
+func (s *replicaSet) forAnyReplica(f func(replica store.KubeconfigStore) error) error {
+	var errs []error
+	for i, replica := range s.Replicas {
+		if err := f(replica); err != nil {
+			s.Logger.Debugf("replica %q failed: %v", replica.GetID(), err)
+			s.markUnhealthy(i)
+			errs = append(errs, fmt.Errorf("replica %q: %w", replica.GetID(), err))
+			continue
+		}
+		s.markHealthy(i)
+	}
+
+	if len(errs) == len(s.Replicas) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// StartSearch searches all replicas concurrently and only sends the first result for each kubeconfig path.
+// Search errors are only sent if the search failed for every replica.
+func (s *replicaSet) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	var (
+		wg          sync.WaitGroup
+		mutex       sync.Mutex
+		sentPaths   = map[string]struct{}{}
+		searchErrs  = make([]error, len(s.Replicas))
+		abortSearch bool
+	)
+
+	for i, replica := range s.Replicas {
+		wg.Add(1)
+		go func(i int, replica store.KubeconfigStore) {
+			defer wg.Done()
+
+			replicaChannel := make(chan store.SearchResult)
+			go func() {
+				defer close(replicaChannel)
+				replica.StartSearch(ctx, replicaChannel)
+			}()
+
+			for result := range replicaChannel {
+				if result.Error != nil {
+					searchErrs[i] = fmt.Errorf("replica %q: %w", replica.GetID(), result.Error)
+					continue
+				}
+
+				mutex.Lock()
+				_, sent := sentPaths[result.KubeconfigPath]
+				sentPaths[result.KubeconfigPath] = struct{}{}
+				mutex.Unlock()
+				if sent {
+					continue
+				}
+
+				select {
+				case channel <- result:
+				case <-ctx.Done():
+					mutex.Lock()
+					abortSearch = true
+					mutex.Unlock()
+					// the replica aborts its search as well, but might still send results
+					for range replicaChannel {
+					}
+					return
+				}
+			}
+		}(i, replica)
+	}
+	wg.Wait()
+
+	if abortSearch {
+		return
+	}
+
+	var errs []error
+	for i, err := range searchErrs {
+		if err != nil {
+			s.Logger.Debugf("search of replica failed: %v", err)
+			s.markUnhealthy(i)
+			errs = append(errs, err)
+			continue
+		}
+		s.markHealthy(i)
+	}
+
+	// the other replicas provided the search results
+	if len(errs) < len(s.Replicas) {
+		return
+	}
+
+	select {
+	case channel <- store.SearchResult{Error: errors.Join(errs...)}:
+	case <-ctx.Done():
+	}
+}
+
+// getKubeconfigForPath tries the replicas in order beginning with the given replica until one returns the kubeconfig.
+// Healthy replicas are tried first.
+func (s *replicaSet) getKubeconfigForPath(ctx context.Context, path string, tags map[string]string, first int) ([]byte, error) {
+	var errs []error
+	for _, i := range s.replicaOrder(first) {
+		replica := s.Replicas[i]
+		kubeconfig, err := replica.GetKubeconfigForPath(ctx, path, tags)
+		if err == nil {
+			s.markHealthy(i)
+			return kubeconfig, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		var (
+			clusterNotFound    *storeerrors.ErrClusterNotFound
+			kubeconfigNotFound *storeerrors.ErrKubeconfigNotFound
+		)
+		// a replica that does not know the kubeconfig is not unhealthy, but might not be up-to-date
+		if !errors.As(err, &clusterNotFound) && !errors.As(err, &kubeconfigNotFound) {
+			s.markUnhealthy(i)
+		}
+
+		s.Logger.Debugf("failed to get kubeconfig path %q from replica %q: %v", path, replica.GetID(), err)
+		errs = append(errs, fmt.Errorf("replica %q: %w", replica.GetID(), err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// GetSearchPreview returns the preview of the first replica in order that implements the Previewer interface and returns a preview
+func (s *replicaSet) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
+	var err error
+	for _, i := range s.replicaOrder(0) {
+		previewer, ok := s.Replicas[i].(store.Previewer)
+		if !ok {
+			continue
+		}
+
+		var preview string
+		if preview, err = previewer.GetSearchPreview(path, optionalTags); err == nil {
+			return preview, nil
+		}
+	}
+	return "", err
+}
+
+// replicaOrder returns the indices of the replicas beginning with the given replica.
+// Unhealthy replicas are moved to the end.
+func (s *replicaSet) replicaOrder(first int) []int {
+	s.healthMutex.Lock()
+	defer s.healthMutex.Unlock()
+
+	now := s.now()
+	healthy := make([]int, 0, len(s.Replicas))
+	var unhealthy []int
+	for i := range s.Replicas {
+		index := (first + i) % len(s.Replicas)
+		if now.Before(s.unhealthyUntil[index]) {
+			unhealthy = append(unhealthy, index)
+			continue
+		}
+		healthy = append(healthy, index)
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (s *replicaSet) markUnhealthy(replica int) {
+	s.healthMutex.Lock()
+	defer s.healthMutex.Unlock()
+	s.unhealthyUntil[replica] = s.now().Add(s.RetryAfter)
+}
+
+func (s *replicaSet) markHealthy(replica int) {
+	s.healthMutex.Lock()
+	defer s.healthMutex.Unlock()
+	s.unhealthyUntil[replica] = time.Time{}
+}
+
+// replicaSetConfigID returns the ID of the store before its replicas have been created
+func replicaSetConfigID(kubeconfigStore types.KubeconfigStore, kind types.StoreKind) string {
+	if kubeconfigStore.ID != nil {
+		return fmt.Sprintf("%s.%s", kind, *kubeconfigStore.ID)
+	}
+	return string(kind)
+}
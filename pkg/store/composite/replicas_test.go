@@ -0,0 +1,213 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package composite_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/composite"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// fakeReplica serves the same kubeconfig for every path and fails if it is down
+type fakeReplica struct {
+	id       string
+	paths    []string
+	down     bool
+	requests int
+	stops    int
+}
+
+func (f *fakeReplica) GetID() string                               { return f.id }
+func (f *fakeReplica) GetKind() types.StoreKind                    { return types.StoreKindVault }
+func (f *fakeReplica) GetContextPrefix(string) string              { return "" }
+func (f *fakeReplica) VerifyKubeconfigPaths(context.Context) error { return nil }
+func (f *fakeReplica) GetLogger() *logrus.Entry                    { return testutil.NewTestLogger() }
+func (f *fakeReplica) GetStoreConfig() types.KubeconfigStore       { return types.KubeconfigStore{} }
+func (f *fakeReplica) Stop(context.Context) error                  { f.stops++; return nil }
+
+func (f *fakeReplica) Probe(context.Context) error {
+	if f.down {
+		return fmt.Errorf("replica %s is down", f.id)
+	}
+	return nil
+}
+
+func (f *fakeReplica) StartSearch(_ context.Context, channel chan store.SearchResult) {
+	if f.down {
+		channel <- store.SearchResult{Error: fmt.Errorf("replica %s is down", f.id)}
+		return
+	}
+	for _, path := range f.paths {
+		channel <- store.SearchResult{KubeconfigPath: path}
+	}
+}
+
+func (f *fakeReplica) GetKubeconfigForPath(_ context.Context, path string, _ map[string]string) ([]byte, error) {
+	f.requests++
+	if f.down {
+		return nil, fmt.Errorf("replica %s is down", f.id)
+	}
+	return []byte(f.id), nil
+}
+
+var _ = Describe("Replica stores", func() {
+	var replicas []*fakeReplica
+
+	BeforeEach(func() {
+		replicas = []*fakeReplica{
+			{id: "a", paths: []string{"x", "y"}},
+			{id: "b", paths: []string{"x", "y", "z"}},
+			{id: "c", paths: []string{"x"}},
+		}
+	})
+
+	newReplica := func() func(types.KubeconfigStore) (store.KubeconfigStore, error) {
+		next := 0
+		return func(types.KubeconfigStore) (store.KubeconfigStore, error) {
+			replica := replicas[next]
+			next++
+			return replica, nil
+		}
+	}
+
+	storeConfig := func(kind types.StoreKind) types.KubeconfigStore {
+		return types.KubeconfigStore{Kind: kind, Config: map[string]interface{}{
+			"stores": []interface{}{
+				map[string]interface{}{"kind": "vault"},
+				map[string]interface{}{"kind": "vault"},
+				map[string]interface{}{"kind": "vault"},
+			},
+		}}
+	}
+
+	// search returns the sorted kubeconfig paths and the errors of the search
+	search := func(s store.KubeconfigStore) ([]string, []error) {
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+
+		var paths []string
+		for _, result := range results {
+			paths = append(paths, result.KubeconfigPath)
+		}
+		sort.Strings(paths)
+
+		if err == nil {
+			return paths, nil
+		}
+		return paths, err.(interface{ Unwrap() []error }).Unwrap()
+	}
+
+	getKubeconfig := func(s store.KubeconfigStore) string {
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "x", nil)
+		Expect(err).ToNot(HaveOccurred())
+		return string(kubeconfig)
+	}
+
+	It("should stop all replicas", func() {
+		s, err := composite.NewFailoverStore(storeConfig(types.StoreKindFailover), newReplica())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(s.Stop(context.Background())).To(Succeed())
+		Expect(s.Stop(context.Background())).To(Succeed())
+		for _, replica := range replicas {
+			Expect(replica.stops).To(Equal(2))
+		}
+	})
+
+	Describe("RoundRobinStore", func() {
+		It("should search all replicas and deduplicate the results", func() {
+			s, err := composite.NewRoundRobinStore(storeConfig(types.StoreKindRoundRobin), newReplica())
+			Expect(err).ToNot(HaveOccurred())
+
+			paths, errs := search(s)
+			Expect(errs).To(BeEmpty())
+			Expect(paths).To(Equal([]string{"x", "y", "z"}))
+		})
+
+		It("should only report search errors if all replicas failed", func() {
+			replicas[0].down = true
+			s, err := composite.NewRoundRobinStore(storeConfig(types.StoreKindRoundRobin), newReplica())
+			Expect(err).ToNot(HaveOccurred())
+
+			_, errs := search(s)
+			Expect(errs).To(BeEmpty())
+
+			replicas[1].down = true
+			replicas[2].down = true
+			_, errs = search(s)
+			Expect(errs).To(HaveLen(1))
+		})
+
+		It("should distribute the requests in round-robin order", func() {
+			s, err := composite.NewRoundRobinStore(storeConfig(types.StoreKindRoundRobin), newReplica())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect([]string{getKubeconfig(s), getKubeconfig(s), getKubeconfig(s), getKubeconfig(s)}).To(Equal([]string{"a", "b", "c", "a"}))
+		})
+
+		It("should route around failed replicas", func() {
+			replicas[1].down = true
+			s, err := composite.NewRoundRobinStore(storeConfig(types.StoreKindRoundRobin), newReplica())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect([]string{getKubeconfig(s), getKubeconfig(s), getKubeconfig(s), getKubeconfig(s)}).To(Equal([]string{"a", "c", "c", "a"}))
+			// the failed replica is skipped after the first failure
+			Expect(replicas[1].requests).To(Equal(1))
+		})
+	})
+
+	Describe("FailoverStore", func() {
+		It("should use the first replica", func() {
+			s, err := composite.NewFailoverStore(storeConfig(types.StoreKindFailover), newReplica())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect([]string{getKubeconfig(s), getKubeconfig(s)}).To(Equal([]string{"a", "a"}))
+		})
+
+		It("should fail over to the next replica", func() {
+			replicas[0].down = true
+			s, err := composite.NewFailoverStore(storeConfig(types.StoreKindFailover), newReplica())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect([]string{getKubeconfig(s), getKubeconfig(s)}).To(Equal([]string{"b", "b"}))
+			Expect(replicas[0].requests).To(Equal(1))
+		})
+
+		It("should return an error if all replicas failed", func() {
+			for _, replica := range replicas {
+				replica.down = true
+			}
+			s, err := composite.NewFailoverStore(storeConfig(types.StoreKindFailover), newReplica())
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = s.GetKubeconfigForPath(context.Background(), "x", nil)
+			Expect(err).To(HaveOccurred())
+			Expect(s.Probe(context.Background())).To(HaveOccurred())
+		})
+
+		It("should require at least one store", func() {
+			_, err := composite.NewFailoverStore(types.KubeconfigStore{Kind: types.StoreKindFailover}, newReplica())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,47 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package composite
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// RoundRobinStore distributes the requests for kubeconfigs across identical replicas of a kubeconfig store in round-robin order.
+// If a replica fails, the next replica is tried and the failed replica is skipped for the configured duration.
+type RoundRobinStore struct {
+	*replicaSet
+	// next is the number of kubeconfigs requested so far, determining the replica to try first
+	next atomic.Uint64
+}
+
+// NewRoundRobinStore creates a new round-robin store.
+// The replicas are created from the store configuration with the given function.
+func NewRoundRobinStore(kubeconfigStore types.KubeconfigStore, newStore func(types.KubeconfigStore) (store.KubeconfigStore, error)) (*RoundRobinStore, error) {
+	replicas, err := newReplicaSet(kubeconfigStore, types.StoreKindRoundRobin, newStore)
+	if err != nil {
+		return nil, err
+	}
+	return &RoundRobinStore{replicaSet: replicas}, nil
+}
+
+// GetKubeconfigForPath retrieves the kubeconfig beginning with the next replica in round-robin order
+func (s *RoundRobinStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	first := int((s.next.Add(1) - 1) % uint64(len(s.Replicas)))
+	return s.getKubeconfigForPath(ctx, path, tags, first)
+}
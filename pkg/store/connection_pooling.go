@@ -0,0 +1,44 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// pooledMaxIdleConns is the maximum number of idle connections kept across all hosts
+	pooledMaxIdleConns = 20
+	// pooledMaxIdleConnsPerHost is the maximum number of idle connections kept per host
+	pooledMaxIdleConnsPerHost = 10
+	// pooledIdleConnTimeout is the duration after which idle connections are closed
+	pooledIdleConnTimeout = 90 * time.Second
+)
+
+// configureConnectionPooling configures the transport to keep idle connections to reuse them across requests,
+// or to close the connection after every request if connection pooling is disabled.
+// Connection pooling is enabled if not configured.
+func configureConnectionPooling(transport *http.Transport, connectionPooling *bool) {
+	if connectionPooling != nil && !*connectionPooling {
+		transport.DisableKeepAlives = true
+		return
+	}
+
+	transport.DisableKeepAlives = false
+	transport.MaxIdleConns = pooledMaxIdleConns
+	transport.MaxIdleConnsPerHost = pooledMaxIdleConnsPerHost
+	transport.IdleConnTimeout = pooledIdleConnTimeout
+}
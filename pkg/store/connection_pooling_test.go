@@ -0,0 +1,164 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const pooledKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+users:
+- name: dev
+  user:
+    token: token
+`
+
+// newConnectionCountingServer starts a TLS server serving a kubeconfig and counting the established connections.
+// Returns the server and the path to its CA certificate.
+func newConnectionCountingServer(directory string, connections *int32) (*httptest.Server, string, error) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(pooledKubeconfig))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(connections, 1)
+		}
+	}
+	server.StartTLS()
+
+	caFile := filepath.Join(directory, "ca.crt")
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caCert, 0600); err != nil {
+		server.Close()
+		return nil, "", err
+	}
+	return server, caFile, nil
+}
+
+func newPooledHTTPStore(url, caFile string, connectionPooling bool) (*store.HTTPStore, error) {
+	return store.NewHTTPStore(types.KubeconfigStore{
+		Kind: types.StoreKindHTTP,
+		Config: map[string]interface{}{
+			"endpoints":         []map[string]interface{}{{"url": url, "tlsCACertFile": caFile}},
+			"connectionPooling": connectionPooling,
+		},
+	})
+}
+
+var _ = Describe("Connection pooling", func() {
+	var (
+		server      *httptest.Server
+		tempDir     string
+		caFile      string
+		connections int32
+	)
+
+	BeforeEach(func() {
+		connections = 0
+
+		var err error
+		tempDir, err = os.MkdirTemp("", "connection-pooling")
+		Expect(err).ToNot(HaveOccurred())
+		server, caFile, err = newConnectionCountingServer(tempDir, &connections)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	getKubeconfigs := func(connectionPooling bool) {
+		s, err := newPooledHTTPStore(server.URL, caFile, connectionPooling)
+		Expect(err).ToNot(HaveOccurred())
+
+		for i := 0; i < 10; i++ {
+			_, err := s.GetKubeconfigForPath(context.Background(), server.URL, nil)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+
+	It("should reuse the connection by default", func() {
+		getKubeconfigs(true)
+		Expect(atomic.LoadInt32(&connections)).To(Equal(int32(1)))
+	})
+
+	It("should establish a new connection per request if disabled", func() {
+		getKubeconfigs(false)
+		Expect(atomic.LoadInt32(&connections)).To(Equal(int32(10)))
+	})
+})
+
+// BenchmarkConnectionPooling measures 100 sequential GetKubeconfigForPath calls of the HTTP store against a TLS server
+func BenchmarkConnectionPooling(b *testing.B) {
+	for _, benchmark := range []struct {
+		name              string
+		connectionPooling bool
+	}{
+		{name: "enabled", connectionPooling: true},
+		{name: "disabled", connectionPooling: false},
+	} {
+		b.Run(benchmark.name, func(b *testing.B) {
+			var connections int32
+			server, caFile, err := newConnectionCountingServer(b.TempDir(), &connections)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer server.Close()
+
+			s, err := newPooledHTTPStore(server.URL, caFile, benchmark.connectionPooling)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < 100; j++ {
+					if _, err := s.GetKubeconfigForPath(context.Background(), server.URL, nil); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(atomic.LoadInt32(&connections))/float64(b.N), "connections/op")
+		})
+	}
+}
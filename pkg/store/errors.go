@@ -0,0 +1,90 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// storeIDForConfig returns the ID of the store with the given configuration.
+// Used for the errors of store constructors, as the store does not exist yet.
+func storeIDForConfig(store types.KubeconfigStore) string {
+	id := "default"
+	if store.ID != nil {
+		id = *store.ID
+	}
+	return fmt.Sprintf("%s.%s", store.Kind, id)
+}
+
+// invalidConfig returns a *storeerrors.ErrInvalidConfig for the store with the given configuration
+func invalidConfig(store types.KubeconfigStore, err error) error {
+	return &storeerrors.ErrInvalidConfig{StoreID: storeIDForConfig(store), Err: err}
+}
+
+// wrapTimeout returns a *storeerrors.ErrStoreTimeout if the error is caused by an exceeded deadline.
+// Otherwise, returns the error unchanged.
+func wrapTimeout(storeID string, err error) error {
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return &storeerrors.ErrStoreTimeout{StoreID: storeID, Err: err}
+	}
+	return err
+}
+
+// wrapKubernetesError returns the typed error for an error of the Kubernetes API:
+// a *storeerrors.ErrKubeconfigNotFound if the requested object does not exist,
+// a *storeerrors.ErrAuthFailed if the request is not authorized and
+// a *storeerrors.ErrStoreTimeout if the request timed out.
+func wrapKubernetesError(storeID string, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case apierrors.IsNotFound(err):
+		return &storeerrors.ErrKubeconfigNotFound{StoreID: storeID, Err: err}
+	case apierrors.IsUnauthorized(err), apierrors.IsForbidden(err):
+		return &storeerrors.ErrAuthFailed{StoreID: storeID, Err: err}
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return &storeerrors.ErrStoreTimeout{StoreID: storeID, Err: err}
+	}
+	return wrapTimeout(storeID, err)
+}
+
+// wrapHTTPError returns the typed error for a failed request against the HTTP API of a store with the given status code:
+// a *storeerrors.ErrClusterNotFound for 404, a *storeerrors.ErrAuthFailed for 401 and 403 and
+// a *storeerrors.ErrStoreTimeout if the request timed out.
+func wrapHTTPError(storeID string, statusCode int, err error) error {
+	switch statusCode {
+	case http.StatusNotFound:
+		return &storeerrors.ErrClusterNotFound{StoreID: storeID, Err: err}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &storeerrors.ErrAuthFailed{StoreID: storeID, Err: err}
+	}
+	return wrapTimeout(storeID, err)
+}
+
+// wrapKubernetesClusterError returns the typed error for an error of the Kubernetes API when getting the resource of a cluster.
+// Same as wrapKubernetesError, but returns a *storeerrors.ErrClusterNotFound if the cluster resource does not exist.
+func wrapKubernetesClusterError(storeID string, err error) error {
+	if apierrors.IsNotFound(err) {
+		return &storeerrors.ErrClusterNotFound{StoreID: storeID, Err: err}
+	}
+	return wrapKubernetesError(storeID, err)
+}
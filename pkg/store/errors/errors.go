@@ -0,0 +1,240 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package errors defines the typed errors returned by the kubeconfig stores.
+// Use errors.As to handle a specific kind of error, e.g.
+//
+//	var notFound *storeerrors.ErrClusterNotFound
+//	if errors.As(err, &notFound) {
+//		...
+//	}
+//
+// A zero value of an error type matches any error of the same type with errors.Is.
+package errors
+
+import (
+	"fmt"
+)
+
+// format returns the message of a store error
+func format(storeID, message string, err error) string {
+	if len(storeID) > 0 {
+		message = fmt.Sprintf("store %q: %s", storeID, message)
+	}
+	if err != nil {
+		message = fmt.Sprintf("%s: %v", message, err)
+	}
+	return message
+}
+
+// ErrStoreNotFound is returned if a store with the requested ID is not configured
+type ErrStoreNotFound struct {
+	// StoreID is the ID of the store returning the error
+	StoreID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ErrStoreNotFound) Error() string {
+	return format(e.StoreID, "store not found", e.Err)
+}
+
+func (e *ErrStoreNotFound) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an ErrStoreNotFound without store ID and underlying error
+func (e *ErrStoreNotFound) Is(target error) bool {
+	t, ok := target.(*ErrStoreNotFound)
+	return ok && len(t.StoreID) == 0 && t.Err == nil
+}
+
+// ErrAuthFailed is returned if a store cannot authenticate against its backend
+type ErrAuthFailed struct {
+	// StoreID is the ID of the store returning the error
+	StoreID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ErrAuthFailed) Error() string {
+	return format(e.StoreID, "authentication failed", e.Err)
+}
+
+func (e *ErrAuthFailed) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an ErrAuthFailed without store ID and underlying error
+func (e *ErrAuthFailed) Is(target error) bool {
+	t, ok := target.(*ErrAuthFailed)
+	return ok && len(t.StoreID) == 0 && t.Err == nil
+}
+
+// ErrClusterNotFound is returned by GetKubeconfigForPath if the cluster
+// backing the requested kubeconfig path does not exist (anymore)
+type ErrClusterNotFound struct {
+	// StoreID is the ID of the store returning the error
+	StoreID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ErrClusterNotFound) Error() string {
+	return format(e.StoreID, "cluster not found", e.Err)
+}
+
+func (e *ErrClusterNotFound) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an ErrClusterNotFound without store ID and underlying error
+func (e *ErrClusterNotFound) Is(target error) bool {
+	t, ok := target.(*ErrClusterNotFound)
+	return ok && len(t.StoreID) == 0 && t.Err == nil
+}
+
+// ErrKubeconfigNotFound is returned by GetKubeconfigForPath if there is no kubeconfig
+// at the requested path
+type ErrKubeconfigNotFound struct {
+	// StoreID is the ID of the store returning the error
+	StoreID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ErrKubeconfigNotFound) Error() string {
+	return format(e.StoreID, "kubeconfig not found", e.Err)
+}
+
+func (e *ErrKubeconfigNotFound) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an ErrKubeconfigNotFound without store ID and underlying error
+func (e *ErrKubeconfigNotFound) Is(target error) bool {
+	t, ok := target.(*ErrKubeconfigNotFound)
+	return ok && len(t.StoreID) == 0 && t.Err == nil
+}
+
+// ErrStoreTimeout is returned if a store did not respond in time, e.g. when the search
+// of a store did not finish before the search timeout
+type ErrStoreTimeout struct {
+	// StoreID is the ID of the store returning the error
+	StoreID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ErrStoreTimeout) Error() string {
+	return format(e.StoreID, "store timed out", e.Err)
+}
+
+func (e *ErrStoreTimeout) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an ErrStoreTimeout without store ID and underlying error
+func (e *ErrStoreTimeout) Is(target error) bool {
+	t, ok := target.(*ErrStoreTimeout)
+	return ok && len(t.StoreID) == 0 && t.Err == nil
+}
+
+// ErrSearchLimit is returned if a store stopped the search because it reached a limit
+type ErrSearchLimit struct {
+	// StoreID is the ID of the store returning the error
+	StoreID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ErrSearchLimit) Error() string {
+	return format(e.StoreID, "search limit reached", e.Err)
+}
+
+func (e *ErrSearchLimit) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an ErrSearchLimit without store ID and underlying error
+func (e *ErrSearchLimit) Is(target error) bool {
+	t, ok := target.(*ErrSearchLimit)
+	return ok && len(t.StoreID) == 0 && t.Err == nil
+}
+
+// ErrReadOnly is returned by operations that would write to a store marked as read-only
+type ErrReadOnly struct {
+	// StoreID is the ID of the store returning the error
+	StoreID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ErrReadOnly) Error() string {
+	return format(e.StoreID, "store is read-only", e.Err)
+}
+
+func (e *ErrReadOnly) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an ErrReadOnly without store ID and underlying error
+func (e *ErrReadOnly) Is(target error) bool {
+	t, ok := target.(*ErrReadOnly)
+	return ok && len(t.StoreID) == 0 && t.Err == nil
+}
+
+// ErrInvalidConfig is returned if the configuration of a store is invalid
+type ErrInvalidConfig struct {
+	// StoreID is the ID of the store returning the error
+	StoreID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ErrInvalidConfig) Error() string {
+	return format(e.StoreID, "invalid store configuration", e.Err)
+}
+
+func (e *ErrInvalidConfig) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an ErrInvalidConfig without store ID and underlying error
+func (e *ErrInvalidConfig) Is(target error) bool {
+	t, ok := target.(*ErrInvalidConfig)
+	return ok && len(t.StoreID) == 0 && t.Err == nil
+}
+
+// ErrKubeconfigTooLarge is returned if a kubeconfig returned by a store exceeds the maximum kubeconfig size
+type ErrKubeconfigTooLarge struct {
+	// StoreID is the ID of the store returning the error
+	StoreID string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *ErrKubeconfigTooLarge) Error() string {
+	return format(e.StoreID, "kubeconfig too large", e.Err)
+}
+
+func (e *ErrKubeconfigTooLarge) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is an ErrKubeconfigTooLarge without store ID and underlying error
+func (e *ErrKubeconfigTooLarge) Is(target error) bool {
+	t, ok := target.(*ErrKubeconfigTooLarge)
+	return ok && len(t.StoreID) == 0 && t.Err == nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+)
+
+var _ = Describe("Store errors", func() {
+	It("formats the message with the store ID and the underlying error", func() {
+		err := &storeerrors.ErrAuthFailed{StoreID: "gke.default", Err: fmt.Errorf("token expired")}
+		Expect(err.Error()).To(Equal(`store "gke.default": authentication failed: token expired`))
+	})
+
+	It("omits the store ID and the underlying error if not set", func() {
+		Expect((&storeerrors.ErrReadOnly{}).Error()).To(Equal("store is read-only"))
+	})
+
+	It("can be extracted with errors.As from a wrapped error", func() {
+		err := fmt.Errorf("search failed: %w", &storeerrors.ErrStoreTimeout{StoreID: "eks.prod", Err: context.DeadlineExceeded})
+
+		var timeout *storeerrors.ErrStoreTimeout
+		Expect(errors.As(err, &timeout)).To(BeTrue())
+		Expect(timeout.StoreID).To(Equal("eks.prod"))
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+
+		var notFound *storeerrors.ErrClusterNotFound
+		Expect(errors.As(err, &notFound)).To(BeFalse())
+	})
+
+	It("matches a zero value of the same type with errors.Is", func() {
+		err := fmt.Errorf("wrapped: %w", &storeerrors.ErrClusterNotFound{StoreID: "azure.default", Err: fmt.Errorf("404")})
+
+		Expect(errors.Is(err, &storeerrors.ErrClusterNotFound{})).To(BeTrue())
+		Expect(errors.Is(err, &storeerrors.ErrClusterNotFound{StoreID: "other"})).To(BeFalse())
+		Expect(errors.Is(err, &storeerrors.ErrKubeconfigNotFound{})).To(BeFalse())
+	})
+})
@@ -16,6 +16,7 @@ package gardener
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 
 	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
@@ -58,13 +59,15 @@ func GetStoreConfig(store types.KubeconfigStore) (*types.StoreConfigGardener, er
 	return storeConfig, nil
 }
 
-func GetGardenClient(config *types.StoreConfigGardener) (client.Client, error) {
+// GetGardenClient returns a client for the Gardener API.
+// The optional wrapTransport function wraps the transport of the client, e.g. to log the API calls.
+func GetGardenClient(gardenerAPIKubeconfigPath string, wrapTransport func(http.RoundTripper) http.RoundTripper) (client.Client, error) {
 	scheme := runtime.NewScheme()
 	utilruntime.Must(corev1.AddToScheme(scheme))
 	utilruntime.Must(gardencorev1beta1.AddToScheme(scheme))
 	utilruntime.Must(seedmanagementv1alpha1.AddToScheme(scheme))
 
-	gardenerAPIKubeconfigPath := util.ExpandEnv(config.GardenerAPIKubeconfigPath)
+	gardenerAPIKubeconfigPath = util.ResolveKubeconfigPath(gardenerAPIKubeconfigPath)
 
 	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 		&clientcmd.ClientConfigLoadingRules{ExplicitPath: gardenerAPIKubeconfigPath},
@@ -74,6 +77,7 @@ func GetGardenClient(config *types.StoreConfigGardener) (client.Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to create rest config: %v", err)
 	}
+	restConfig.Wrap(wrapTransport)
 
 	k8sclient, err := client.New(restConfig, client.Options{
 		Scheme: scheme,
@@ -95,6 +99,17 @@ func GetSeedIdentifier(landscape, seedName string) string {
 	return fmt.Sprintf("%s--seed--%s", landscape, seedName)
 }
 
+// GetProjectName returns the name of the Gardener project of the given namespace.
+// The namespace of a project is "garden-<project-name>", except for the "garden" project.
+// Returns false if the namespace does not belong to a project.
+func GetProjectName(namespace string) (string, bool) {
+	if namespace == "garden" {
+		return "garden", true
+	}
+	projectName, ok := strings.CutPrefix(namespace, "garden-")
+	return projectName, ok && len(projectName) > 0
+}
+
 // GetShootIdentifier returns the Shoot identifier in the form <landscape>--shoot--<project-name>--<shoot-name>
 func GetShootIdentifier(landscape, project, shoot string) string {
 	return fmt.Sprintf("%s--shoot--%s--%s", landscape, project, shoot)
@@ -55,6 +55,11 @@ func ValidateGardenerStoreConfiguration(path *field.Path, store types.Kubeconfig
 		return nil, errors
 	}
 
+	if len(config.Landscapes) > 0 {
+		errors = append(errors, validateLandscapes(configPath.Child("landscapes"), config.Landscapes)...)
+		return config.LandscapeName, errors
+	}
+
 	if len(config.GardenerAPIKubeconfigPath) == 0 {
 		errors = append(errors, field.Invalid(configPath.Child("gardenerAPIKubeconfigPath"), config.GardenerAPIKubeconfigPath, "The kubeconfig to the Gardener API server must be set"))
 	}
@@ -65,3 +70,38 @@ func ValidateGardenerStoreConfiguration(path *field.Path, store types.Kubeconfig
 
 	return config.LandscapeName, errors
 }
+
+// validateLandscapes validates the landscapes of a Gardener store searching multiple landscapes
+func validateLandscapes(path *field.Path, landscapes []types.LandscapeConfig) field.ErrorList {
+	var (
+		errors   = field.ErrorList{}
+		prefixes = sets.NewString()
+	)
+
+	for i, landscape := range landscapes {
+		landscapePath := path.Index(i)
+
+		if len(landscape.GardenletKubeconfigPath) == 0 {
+			errors = append(errors, field.Required(landscapePath.Child("gardenletKubeconfigPath"), "The kubeconfig to the Gardener API server of the landscape must be set"))
+		}
+
+		if len(landscape.Namespace) > 0 && landscape.Namespace != "garden" && !strings.HasPrefix(landscape.Namespace, "garden-") {
+			errors = append(errors, field.Invalid(landscapePath.Child("namespace"), landscape.Namespace, "The namespace can only equal \"garden\" or have the prefix \"garden-\""))
+		}
+
+		// the kubeconfig paths of the landscapes are prefixed with the name or identity, hence they must be unique
+		prefix := landscape.Name
+		if len(prefix) == 0 {
+			prefix = landscape.Identity
+		}
+		if len(prefix) == 0 {
+			continue
+		}
+
+		if prefixes.Has(prefix) {
+			errors = append(errors, field.Duplicate(landscapePath, prefix))
+		}
+		prefixes.Insert(prefix)
+	}
+	return errors
+}
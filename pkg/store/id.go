@@ -0,0 +1,81 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	gardenerstore "github.com/danielfoehrkn/kubeswitch/pkg/store/gardener"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// IDForConfig returns the ID of the store created for the given store configuration without creating the store,
+// e.g. to match the stores of a reloaded config against the running stores.
+// It returns the same ID as GetID() of the created store.
+func IDForConfig(kubeconfigStore types.KubeconfigStore) string {
+	switch kubeconfigStore.Kind {
+	case types.StoreKindAkamai:
+		return akamaiStoreID()
+	case types.StoreKindGardener:
+		// the configuration is validated when creating the store
+		config, _ := gardenerstore.GetStoreConfig(kubeconfigStore)
+		return gardenerStoreID(kubeconfigStore, config)
+	case types.StoreKindFallback:
+		config := &types.StoreConfigFallback{}
+		if kubeconfigStore.Config != nil {
+			buf, err := yaml.Marshal(kubeconfigStore.Config)
+			if err == nil {
+				_ = yaml.Unmarshal(buf, config)
+			}
+		}
+		return fallbackStoreID(kubeconfigStore, IDForConfig(config.Primary), IDForConfig(config.Fallback))
+	}
+
+	id := "default"
+	if kubeconfigStore.ID != nil {
+		id = *kubeconfigStore.ID
+	}
+	return fmt.Sprintf("%s.%s", kubeconfigStore.Kind, id)
+}
+
+// akamaiStoreID returns the ID of the Akamai store, which does not support configuring an ID
+func akamaiStoreID() string {
+	return fmt.Sprintf("%s.default", types.StoreKindAkamai)
+}
+
+// gardenerStoreID returns the ID of a Gardener store which defaults to the name of the landscape
+func gardenerStoreID(kubeconfigStore types.KubeconfigStore, config *types.StoreConfigGardener) string {
+	id := "default"
+	if kubeconfigStore.ID != nil {
+		id = *kubeconfigStore.ID
+	} else if config != nil && config.LandscapeName != nil {
+		id = *config.LandscapeName
+	}
+	return fmt.Sprintf("%s.%s", types.StoreKindGardener, id)
+}
+
+// fallbackStoreID returns the ID of a fallback store.
+// If no ID is configured, a deterministic hash of the IDs of the primary and fallback store is used.
+func fallbackStoreID(kubeconfigStore types.KubeconfigStore, primaryID, fallbackID string) string {
+	if kubeconfigStore.ID != nil {
+		return fmt.Sprintf("%s.%s", types.StoreKindFallback, *kubeconfigStore.ID)
+	}
+
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s,%s", primaryID, fallbackID)))
+	return fmt.Sprintf("%s.%x", types.StoreKindFallback, hash[:8])
+}
@@ -0,0 +1,72 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("IDForConfig", func() {
+	// newStore creates the stores of the kinds with an ID that does not only depend on the kind and the configured ID
+	var newStore func(kubeconfigStore types.KubeconfigStore) (store.KubeconfigStore, error)
+	newStore = func(kubeconfigStore types.KubeconfigStore) (store.KubeconfigStore, error) {
+		switch kubeconfigStore.Kind {
+		case types.StoreKindAkamai:
+			return store.NewAkamaiStore(kubeconfigStore)
+		case types.StoreKindGardener:
+			return store.NewGardenerStore(kubeconfigStore, "")
+		case types.StoreKindFallback:
+			return store.NewFallbackStore(kubeconfigStore, newStore)
+		default:
+			return store.NewFilesystemStore("config", kubeconfigStore)
+		}
+	}
+
+	fallbackConfig := map[string]interface{}{
+		"primary":  map[string]interface{}{"kind": "gardener", "config": map[string]interface{}{"landscapeName": "dev"}},
+		"fallback": map[string]interface{}{"kind": "filesystem", "id": "team", "paths": []interface{}{"/kubeconfigs"}},
+	}
+
+	DescribeTable("should return the ID of the created store",
+		func(kubeconfigStore types.KubeconfigStore, expectedID string) {
+			s, err := newStore(kubeconfigStore)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.GetID()).To(Equal(expectedID))
+			Expect(store.IDForConfig(kubeconfigStore)).To(Equal(expectedID))
+		},
+		Entry("filesystem store", types.KubeconfigStore{Kind: types.StoreKindFilesystem, Paths: []string{"/kubeconfigs"}}, "filesystem.default"),
+		Entry("filesystem store with an ID", types.KubeconfigStore{ID: ptr.To("team"), Kind: types.StoreKindFilesystem, Paths: []string{"/kubeconfigs"}}, "filesystem.team"),
+		Entry("Akamai store with an ID", types.KubeconfigStore{ID: ptr.To("team"), Kind: types.StoreKindAkamai}, "akamai.default"),
+		Entry("Gardener store", types.KubeconfigStore{Kind: types.StoreKindGardener, Config: map[string]interface{}{}}, "gardener.default"),
+		Entry("Gardener store with a landscape name", types.KubeconfigStore{Kind: types.StoreKindGardener, Config: map[string]interface{}{"landscapeName": "dev"}}, "gardener.dev"),
+		Entry("Gardener store with an ID and a landscape name", types.KubeconfigStore{ID: ptr.To("team"), Kind: types.StoreKindGardener, Config: map[string]interface{}{"landscapeName": "dev"}}, "gardener.team"),
+		Entry("fallback store with an ID", types.KubeconfigStore{ID: ptr.To("team"), Kind: types.StoreKindFallback, Config: fallbackConfig}, "fallback.team"),
+	)
+
+	It("should return the hash of the IDs of the primary and fallback store for fallback stores without an ID", func() {
+		kubeconfigStore := types.KubeconfigStore{Kind: types.StoreKindFallback, Config: fallbackConfig}
+
+		s, err := newStore(kubeconfigStore)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.IDForConfig(kubeconfigStore)).To(Equal(s.GetID()))
+		Expect(s.GetID()).To(MatchRegexp("^fallback\\.[0-9a-f]{16}$"))
+	})
+})
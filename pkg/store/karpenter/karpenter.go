@@ -0,0 +1,124 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package karpenter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/disiqueira/gotree"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// NodePoolResource is the resource of the Karpenter NodePools
+var NodePoolResource = schema.GroupVersionResource{Group: "karpenter.sh", Version: "v1", Resource: "nodepools"}
+
+// NodePool is the summary of a Karpenter NodePool
+type NodePool struct {
+	// Name is the name of the NodePool
+	Name string
+	// Requirements are the instance type requirements of the nodes of the NodePool,
+	// e.g. "karpenter.k8s.aws/instance-family In [m5, c5]"
+	Requirements []string
+	// Nodes is the number of nodes provisioned for the NodePool
+	Nodes int64
+}
+
+// ListNodePools lists the Karpenter NodePools of the cluster sorted by name.
+// Returns an error if the cluster is unreachable or Karpenter is not installed.
+func ListNodePools(ctx context.Context, restConfig *rest.Config) ([]NodePool, error) {
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := client.Resource(NodePoolResource).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Karpenter NodePools: %w", err)
+	}
+
+	nodePools := make([]NodePool, 0, len(list.Items))
+	for _, item := range list.Items {
+		nodePools = append(nodePools, toNodePool(item))
+	}
+	sort.Slice(nodePools, func(i, j int) bool {
+		return nodePools[i].Name < nodePools[j].Name
+	})
+	return nodePools, nil
+}
+
+// AddToTree adds the NodePools and their total node count to the preview tree
+func AddToTree(tree gotree.Tree, nodePools []NodePool) {
+	var total int64
+	for _, nodePool := range nodePools {
+		total += nodePool.Nodes
+	}
+
+	karpenterTree := tree.Add(fmt.Sprintf("Karpenter NodePools (%d nodes)", total))
+	for _, nodePool := range nodePools {
+		nodePoolTree := karpenterTree.Add(nodePool.Name)
+		nodePoolTree.Add(fmt.Sprintf("Nodes: %d", nodePool.Nodes))
+		for _, requirement := range nodePool.Requirements {
+			nodePoolTree.Add(requirement)
+		}
+	}
+}
+
+// toNodePool summarizes a NodePool object
+func toNodePool(item unstructured.Unstructured) NodePool {
+	nodePool := NodePool{Name: item.GetName()}
+
+	requirements, _, _ := unstructured.NestedSlice(item.Object, "spec", "template", "spec", "requirements")
+	for _, r := range requirements {
+		requirement, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		key, _, _ := unstructured.NestedString(requirement, "key")
+		if !isInstanceTypeRequirement(key) {
+			continue
+		}
+		operator, _, _ := unstructured.NestedString(requirement, "operator")
+		values, _, _ := unstructured.NestedStringSlice(requirement, "values")
+
+		if len(values) == 0 {
+			nodePool.Requirements = append(nodePool.Requirements, fmt.Sprintf("%s %s", key, operator))
+			continue
+		}
+		nodePool.Requirements = append(nodePool.Requirements, fmt.Sprintf("%s %s [%s]", key, operator, strings.Join(values, ", ")))
+	}
+
+	// the status contains the resources of all nodes of the NodePool, including the number of nodes
+	if nodes, found, _ := unstructured.NestedString(item.Object, "status", "resources", "nodes"); found {
+		if quantity, err := resource.ParseQuantity(nodes); err == nil {
+			nodePool.Nodes = quantity.Value()
+		}
+	}
+	return nodePool
+}
+
+// isInstanceTypeRequirement checks if the requirement restricts the instance types,
+// e.g. node.kubernetes.io/instance-type or karpenter.k8s.aws/instance-family
+func isInstanceTypeRequirement(key string) bool {
+	return strings.Contains(key, "instance-")
+}
@@ -0,0 +1,96 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package karpenter_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/disiqueira/gotree"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/rest"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/karpenter"
+)
+
+const nodePoolList = `{
+  "apiVersion": "karpenter.sh/v1",
+  "kind": "NodePoolList",
+  "metadata": {},
+  "items": [
+    {
+      "apiVersion": "karpenter.sh/v1",
+      "kind": "NodePool",
+      "metadata": {"name": "spot"},
+      "spec": {"template": {"spec": {"requirements": [
+        {"key": "karpenter.sh/capacity-type", "operator": "In", "values": ["spot"]},
+        {"key": "karpenter.k8s.aws/instance-family", "operator": "In", "values": ["m5", "c5"]}
+      ]}}},
+      "status": {"resources": {"cpu": "16", "nodes": "3"}}
+    },
+    {
+      "apiVersion": "karpenter.sh/v1",
+      "kind": "NodePool",
+      "metadata": {"name": "default"},
+      "spec": {"template": {"spec": {"requirements": [
+        {"key": "node.kubernetes.io/instance-type", "operator": "In", "values": ["m5.large"]}
+      ]}}}
+    }
+  ]
+}`
+
+var _ = Describe("Karpenter", func() {
+	var server *httptest.Server
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("lists the NodePools with their instance type requirements and nodes", func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/apis/karpenter.sh/v1/nodepools"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(nodePoolList))
+		}))
+
+		nodePools, err := karpenter.ListNodePools(context.Background(), &rest.Config{Host: server.URL})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(nodePools).To(Equal([]karpenter.NodePool{
+			{
+				Name:         "default",
+				Requirements: []string{"node.kubernetes.io/instance-type In [m5.large]"},
+			},
+			{
+				Name:         "spot",
+				Requirements: []string{"karpenter.k8s.aws/instance-family In [m5, c5]"},
+				Nodes:        3,
+			},
+		}))
+
+		tree := gotree.New("cluster")
+		karpenter.AddToTree(tree, nodePools)
+		Expect(tree.Print()).To(ContainSubstring("Karpenter NodePools (3 nodes)"))
+		Expect(tree.Print()).To(ContainSubstring("karpenter.k8s.aws/instance-family In [m5, c5]"))
+	})
+
+	It("returns an error if Karpenter is not installed", func() {
+		server = httptest.NewServer(http.NotFoundHandler())
+
+		_, err := karpenter.ListNodePools(context.Background(), &rest.Config{Host: server.URL})
+		Expect(err).To(HaveOccurred())
+	})
+})
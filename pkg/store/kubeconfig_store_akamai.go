@@ -26,6 +26,7 @@ import (
 	"golang.org/x/oauth2"
 	"gopkg.in/yaml.v3"
 
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
 	"github.com/danielfoehrkn/kubeswitch/types"
 	"github.com/linode/linodego"
 	"github.com/sirupsen/logrus"
@@ -36,12 +37,12 @@ func NewAkamaiStore(store types.KubeconfigStore) (*AkamaiStore, error) {
 	if store.Config != nil {
 		buf, err := yaml.Marshal(store.Config)
 		if err != nil {
-			return nil, fmt.Errorf("failed to process akamai store config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to process akamai store config: %w", err))
 		}
 
 		err = yaml.Unmarshal(buf, akamaiStoreConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal akami config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to unmarshal akami config: %w", err))
 		}
 	}
 
@@ -59,29 +60,32 @@ func (s *AkamaiStore) InitializeAkamaiStore() error {
 	if token == "" {
 		envToken, ok := os.LookupEnv("LINODE_TOKEN")
 		if !ok {
-			return fmt.Errorf("linode token not set")
+			return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("linode token not set")}
 		}
 		token = envToken
 	}
 
 	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 
+	transport := cloneDefaultTransport()
 	oauth2Client := &http.Client{
 		Transport: &oauth2.Transport{
 			Source: tokenSource,
+			Base:   transport,
 		},
 	}
 
 	linodeClient := linodego.NewClient(oauth2Client)
 
 	s.Client = &linodeClient
+	s.transport = transport
 
 	return nil
 }
 
 // GetID returns the unique store ID
 func (s *AkamaiStore) GetID() string {
-	return fmt.Sprintf("%s.default", s.GetKind())
+	return akamaiStoreID()
 }
 
 func (s *AkamaiStore) GetKind() types.StoreKind {
@@ -92,11 +96,28 @@ func (s *AkamaiStore) GetContextPrefix(path string) string {
 	return fmt.Sprintf("%s/%s", s.GetKind(), path)
 }
 
-func (s *AkamaiStore) VerifyKubeconfigPaths() error {
+func (s *AkamaiStore) VerifyKubeconfigPaths(ctx context.Context) error {
 	// NOOP
 	return nil
 }
 
+// Probe checks that the Linode API is reachable by getting the profile of the token
+func (s *AkamaiStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	if s.Client == nil {
+		if err := s.InitializeAkamaiStore(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.Client.GetProfile(ctx); err != nil {
+		return wrapTimeout(s.GetID(), fmt.Errorf("failed to get Linode profile: %w", err))
+	}
+	return nil
+}
+
 func (s *AkamaiStore) GetStoreConfig() types.KubeconfigStore {
 	return s.KubeconfigStore
 }
@@ -105,10 +126,18 @@ func (s *AkamaiStore) GetLogger() *logrus.Entry {
 	return s.Logger
 }
 
-func (s *AkamaiStore) StartSearch(channel chan SearchResult) {
+// Stop closes the idle connections of the Linode client
+func (s *AkamaiStore) Stop(_ context.Context) error {
+	if s.transport != nil {
+		s.transport.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (s *AkamaiStore) StartSearch(ctx context.Context, channel chan SearchResult) {
 	s.Logger.Debug("Akamai: start search")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	if err := s.InitializeAkamaiStore(); err != nil {
@@ -140,7 +169,7 @@ func (s *AkamaiStore) StartSearch(channel chan SearchResult) {
 	}
 }
 
-func (s *AkamaiStore) GetKubeconfigForPath(path string, tags map[string]string) ([]byte, error) {
+func (s *AkamaiStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
 	s.Logger.Debugf("Akamai: get kubeconfig for path %s", path)
 
 	// initialize client
@@ -150,16 +179,22 @@ func (s *AkamaiStore) GetKubeconfigForPath(path string, tags map[string]string)
 
 	clusterID, err := strconv.Atoi(tags["clusterID"])
 	if err != nil {
-		return nil, fmt.Errorf("failed to get clusterID: %w", err)
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("failed to get clusterID: %w", err)}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	// get kubeconfig
 	LKEkubeconfig, err := s.Client.GetLKEClusterKubeconfig(ctx, clusterID)
 	if err != nil {
-		return nil, err
+		if linodego.IsNotFound(err) {
+			return nil, &storeerrors.ErrClusterNotFound{StoreID: s.GetID(), Err: err}
+		}
+		if linodego.ErrHasStatus(err, http.StatusUnauthorized, http.StatusForbidden) {
+			return nil, &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: err}
+		}
+		return nil, wrapTimeout(s.GetID(), err)
 	}
 
 	// decode base64 kubeconfig
@@ -16,6 +16,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -26,9 +27,13 @@ import (
 	"gopkg.in/yaml.v3"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+	"k8s.io/utils/ptr"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice"
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
 	"github.com/danielfoehrkn/kubeswitch/types"
 )
 
@@ -42,12 +47,12 @@ func NewAzureStore(store types.KubeconfigStore, stateDir string) (*AzureStore, e
 	if store.Config != nil {
 		buf, err := yaml.Marshal(store.Config)
 		if err != nil {
-			return nil, err
+			return nil, invalidConfig(store, err)
 		}
 
 		err = yaml.Unmarshal(buf, storeConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal Azure config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to unmarshal Azure config: %w", err))
 		}
 	}
 
@@ -64,7 +69,7 @@ func NewAzureStore(store types.KubeconfigStore, stateDir string) (*AzureStore, e
 func (s *AzureStore) InitializeAzureStore() error {
 	cred, err := azidentity.NewDefaultAzureCredential(nil)
 	if err != nil {
-		return fmt.Errorf("obtaining Azure credentials failed: %v", err)
+		return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("obtaining Azure credentials failed: %w", err)}
 	}
 
 	// TODO: upgrading to version github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice v0.1.0
@@ -81,17 +86,20 @@ func (s *AzureStore) InitializeAzureStore() error {
 		endpoint = *s.Config.Endpoint
 	}
 
-	con := arm.NewConnection(endpoint, cred, nil)
+	con := arm.NewConnection(endpoint, credentials.NewAzureTokenCredential(s.CredentialCache, s.GetID(), cred), nil)
 	s.AksClient = armcontainerservice.NewManagedClustersClient(con, *s.Config.SubscriptionID)
 
 	s.Logger.Debugf("Authenticated to subscription %s", *s.Config.SubscriptionID)
 	return nil
 }
 
+// AzureDefaultClusterNameTemplate formats the kubeconfig paths of AKS clusters if no cluster name template is configured
+const AzureDefaultClusterNameTemplate = "az_{{.ResourceGroup}}--{{.ClusterName}}"
+
 // StartSearch starts the search for AKS clusters
 // Limitation: Two seperate subscriptions should not have the same (resource_group, cluster-name) touple
-func (s *AzureStore) StartSearch(channel chan SearchResult) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func (s *AzureStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	if err := s.InitializeAzureStore(); err != nil {
@@ -102,6 +110,14 @@ func (s *AzureStore) StartSearch(channel chan SearchResult) {
 		return
 	}
 
+	clusterNameTemplate, err := NewClusterNameTemplate(s.Config.ClusterNameTemplate, AzureDefaultClusterNameTemplate)
+	if err != nil {
+		channel <- SearchResult{
+			Error: &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: err},
+		}
+		return
+	}
+
 	// uses dedicated lists per resource group
 	if len(s.Config.ResourceGroups) > 0 {
 		// TODO: optimize using goroutines to hide I/O latency
@@ -114,7 +130,7 @@ func (s *AzureStore) StartSearch(channel chan SearchResult) {
 
 			for pager.NextPage(ctx) {
 				s.Logger.Debugf("next page found for resource group %q", resourceGroup)
-				s.returnSearchResultsForClusters(channel, pager.PageResponse().ManagedClusterListResult.Value)
+				s.returnSearchResultsForClusters(channel, clusterNameTemplate, pager.PageResponse().ManagedClusterListResult.Value)
 			}
 
 			if pager.Err() != nil {
@@ -135,7 +151,7 @@ func (s *AzureStore) StartSearch(channel chan SearchResult) {
 
 	for pager.NextPage(ctx) {
 		s.Logger.Debugf("next page found")
-		s.returnSearchResultsForClusters(channel, pager.PageResponse().ManagedClusterListResult.Value)
+		s.returnSearchResultsForClusters(channel, clusterNameTemplate, pager.PageResponse().ManagedClusterListResult.Value)
 	}
 	s.Logger.Debugf("Search done for AKS")
 }
@@ -158,7 +174,7 @@ func handleAzureError(channel chan SearchResult, err error) {
 	}
 }
 
-func (s *AzureStore) returnSearchResultsForClusters(channel chan SearchResult, managedClusters []*armcontainerservice.ManagedCluster) {
+func (s *AzureStore) returnSearchResultsForClusters(channel chan SearchResult, clusterNameTemplate *ClusterNameTemplate, managedClusters []*armcontainerservice.ManagedCluster) {
 	for _, cluster := range managedClusters {
 		s.Logger.Debugf("Found cluster with name %q and id %q", *cluster.Name, *cluster.ID)
 		if cluster.Name == nil {
@@ -187,20 +203,37 @@ func (s *AzureStore) returnSearchResultsForClusters(channel chan SearchResult, m
 		resourceGroup := &split[4]
 		s.Logger.Debugf("Obtained resource group %s", *resourceGroup)
 
-		kubeconfigPath := getAzureKubeconfigPath(*resourceGroup, *cluster.Name)
+		// defaults to az_<resource-group>--<cluster-name>
+		kubeconfigPath, err := clusterNameTemplate.Execute(ClusterNameTemplateData{
+			ClusterName:    *cluster.Name,
+			Region:         ptr.Deref(cluster.Location, ""),
+			ResourceGroup:  *resourceGroup,
+			SubscriptionID: ptr.Deref(s.Config.SubscriptionID, ""),
+		})
+		if err != nil {
+			channel <- SearchResult{
+				Error: err,
+			}
+			continue
+		}
 		s.insertIntoClusterCache(kubeconfigPath, cluster)
 
+		var tags map[string]string
+		if clusterNameTemplate.IsCustom() {
+			tags = map[string]string{
+				tagResourceGroup: *resourceGroup,
+				tagClusterName:   *cluster.Name,
+			}
+		}
+
 		channel <- SearchResult{
 			KubeconfigPath: kubeconfigPath,
+			Tags:           tags,
 			Error:          nil,
 		}
 	}
 }
 
-func getAzureKubeconfigPath(resourceGroup, clusterName string) string {
-	return fmt.Sprintf("az_%s--%s", resourceGroup, clusterName)
-}
-
 func (s *AzureStore) GetContextPrefix(path string) string {
 	if s.GetStoreConfig().ShowPrefix != nil && !*s.GetStoreConfig().ShowPrefix {
 		return ""
@@ -239,8 +272,17 @@ func (s *AzureStore) GetLogger() *logrus.Entry {
 	return s.Logger
 }
 
-func (s *AzureStore) GetKubeconfigForPath(path string, tags map[string]string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// Stop releases the clusters discovered by the search.
+// The connections of the AKS client are managed by the Azure SDK.
+func (s *AzureStore) Stop(_ context.Context) error {
+	s.DiscoveredClustersMutex.Lock()
+	defer s.DiscoveredClustersMutex.Unlock()
+	clear(s.DiscoveredClusters)
+	return nil
+}
+
+func (s *AzureStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	if !s.IsInitialized() {
@@ -248,9 +290,9 @@ func (s *AzureStore) GetKubeconfigForPath(path string, tags map[string]string) (
 			return nil, fmt.Errorf("failed to initialize Azure store: %w", err)
 		}
 	}
-	resourceGroup, clusterName, err := parseAzureIdentifier(path)
+	resourceGroup, clusterName, err := parseAzureIdentifier(path, tags)
 	if err != nil {
-		return nil, err
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: err}
 	}
 
 	s.Logger.Debugf("AKS: GetKubeconfigForPath for group : %q and cluster: %q", resourceGroup, clusterName)
@@ -258,7 +300,7 @@ func (s *AzureStore) GetKubeconfigForPath(path string, tags map[string]string) (
 	resp, err := s.AksClient.Get(ctx, resourceGroup, clusterName, nil)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to obtain kubeconfig for AKS cluster %q in resource group %q: %w", clusterName, resourceGroup, err)
+		return nil, wrapAzureError(s.GetID(), fmt.Errorf("failed to obtain kubeconfig for AKS cluster %q in resource group %q: %w", clusterName, resourceGroup, err))
 	}
 
 	var kubeconfigs []*armcontainerservice.CredentialResult
@@ -267,14 +309,14 @@ func (s *AzureStore) GetKubeconfigForPath(path string, tags map[string]string) (
 	if resp.Properties.AADProfile != nil {
 		resp_user, err := s.AksClient.ListClusterUserCredentials(ctx, resourceGroup, clusterName, nil)
 		if err != nil {
-			return nil, fmt.Errorf("failed to obtain kubeconfig for AKS cluster %q in resource group %q: %w", clusterName, resourceGroup, err)
+			return nil, wrapAzureError(s.GetID(), fmt.Errorf("failed to obtain kubeconfig for AKS cluster %q in resource group %q: %w", clusterName, resourceGroup, err))
 		}
 
 		kubeconfigs = resp_user.Kubeconfigs
 	} else {
 		resp_admin, err := s.AksClient.ListClusterAdminCredentials(ctx, resourceGroup, clusterName, nil)
 		if err != nil {
-			return nil, fmt.Errorf("failed to obtain kubeconfig for AKS cluster %q in resource group %q: %w", clusterName, resourceGroup, err)
+			return nil, wrapAzureError(s.GetID(), fmt.Errorf("failed to obtain kubeconfig for AKS cluster %q in resource group %q: %w", clusterName, resourceGroup, err))
 		}
 
 		kubeconfigs = resp_admin.Kubeconfigs
@@ -285,19 +327,55 @@ func (s *AzureStore) GetKubeconfigForPath(path string, tags map[string]string) (
 			return kubeconfig.Value, err
 		}
 	}
-	return nil, fmt.Errorf("no admin kubeconfig found for AKS cluster %q in resource group %q", clusterName, resourceGroup)
+	return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("no admin kubeconfig found for AKS cluster %q in resource group %q", clusterName, resourceGroup)}
 }
 
-func (s *AzureStore) VerifyKubeconfigPaths() error {
-	// NOOP
+// VerifyKubeconfigPaths verifies the cluster name template
+func (s *AzureStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	if _, err := NewClusterNameTemplate(s.Config.ClusterNameTemplate, AzureDefaultClusterNameTemplate); err != nil {
+		return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: err}
+	}
 	return nil
 }
 
+// Probe checks that the AKS API is reachable by requesting the first page of clusters
+func (s *AzureStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	if s.AksClient == nil {
+		if err := s.InitializeAzureStore(); err != nil {
+			return fmt.Errorf("failed to initialize store: %w", err)
+		}
+	}
+
+	pager := s.AksClient.List(nil)
+	pager.NextPage(ctx)
+	if err := pager.Err(); err != nil {
+		return wrapAzureError(s.GetID(), fmt.Errorf("failed to list AKS clusters: %w", err))
+	}
+	return nil
+}
+
+// wrapAzureError returns the typed error for a failed request against the Azure API
+func wrapAzureError(storeID string, err error) error {
+	var httpResponse azcore.HTTPResponse
+	if errors.As(err, &httpResponse) && httpResponse.RawResponse() != nil {
+		return wrapHTTPError(storeID, httpResponse.RawResponse().StatusCode, err)
+	}
+	return wrapTimeout(storeID, err)
+}
+
 // ParseIdentifier takes a kubeconfig identifier and
 // returns the
 // 1) the Azure resource group
 // 2) the name of the AKS cluster
-func parseAzureIdentifier(path string) (string, string, error) {
+// The cluster is identified using the tags if the kubeconfig path has been formatted with a custom cluster name template.
+func parseAzureIdentifier(path string, tags map[string]string) (string, string, error) {
+	if clusterName, ok := tags[tagClusterName]; ok {
+		return tags[tagResourceGroup], clusterName, nil
+	}
+
 	split := strings.Split(path, "--")
 	switch len(split) {
 	case 2:
@@ -307,7 +385,7 @@ func parseAzureIdentifier(path string) (string, string, error) {
 	}
 }
 
-func (s *AzureStore) GetSearchPreview(path string, _ map[string]string) (string, error) {
+func (s *AzureStore) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
 	if !s.IsInitialized() {
 		// this takes too long, initialize concurrently
 		go func() {
@@ -322,7 +400,7 @@ func (s *AzureStore) GetSearchPreview(path string, _ map[string]string) (string,
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	resourceGroup, clusterName, err := parseAzureIdentifier(path)
+	resourceGroup, clusterName, err := parseAzureIdentifier(path, optionalTags)
 	if err != nil {
 		return "", err
 	}
@@ -16,9 +16,14 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
+	"os"
 	"time"
 
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/cloudflare"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
 	"github.com/danielfoehrkn/kubeswitch/types"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
@@ -27,7 +32,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/clientcmd"
 	clusterv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
-	utilkubeconfig "sigs.k8s.io/cluster-api/util/kubeconfig"
+	capisecret "sigs.k8s.io/cluster-api/util/secret"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -36,19 +41,42 @@ func NewCapiStore(store types.KubeconfigStore, stateDir string) (*CapiStore, err
 	if store.Config != nil {
 		buf, err := yaml.Marshal(store.Config)
 		if err != nil {
-			return nil, err
+			return nil, invalidConfig(store, err)
 		}
 
 		err = yaml.Unmarshal(buf, storeConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal Azure config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to unmarshal CAPI config: %w", err))
 		}
 	}
 
+	var tunnelClient *cloudflare.Client
+	if tunnelConfig := storeConfig.CloudflareTunnel; tunnelConfig != nil {
+		if len(tunnelConfig.TunnelID) == 0 || len(tunnelConfig.AccountID) == 0 {
+			return nil, invalidConfig(store, fmt.Errorf("the tunnelID and accountID of the Cloudflare Tunnel must be configured"))
+		}
+
+		apiToken := tunnelConfig.APIToken
+		if len(apiToken) == 0 {
+			apiToken = os.Getenv("CLOUDFLARE_API_TOKEN")
+		}
+		if len(apiToken) == 0 {
+			return nil, invalidConfig(store, fmt.Errorf("the Cloudflare API token must be configured in the store config or via the environment variable CLOUDFLARE_API_TOKEN"))
+		}
+		tunnelClient = cloudflare.NewClient(apiToken)
+	}
+
+	providerDefaults, err := getCapiProviderDefaults(storeConfig.Provider)
+	if err != nil {
+		return nil, invalidConfig(store, err)
+	}
+
 	return &CapiStore{
-		KubeconfigStore: store,
-		Logger:          logrus.New().WithField("store", types.StoreKindCapi),
-		Config:          storeConfig,
+		KubeconfigStore:  store,
+		Logger:           logrus.New().WithField("store", types.StoreKindCapi),
+		Config:           storeConfig,
+		ProviderDefaults: providerDefaults,
+		TunnelClient:     tunnelClient,
 	}, nil
 }
 
@@ -79,7 +107,23 @@ func (s *CapiStore) GetContextPrefix(path string) string {
 }
 
 // VerifyKubeconfigPaths verifies the kubeconfig paths
-func (s *CapiStore) VerifyKubeconfigPaths() error {
+func (s *CapiStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	return nil
+}
+
+// Probe checks that the CAPI management cluster is reachable by listing a single cluster
+func (s *CapiStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	k8sclient, err := s.getCapiClient()
+	if err != nil {
+		return err
+	}
+
+	if err := k8sclient.List(ctx, &clusterv1beta1.ClusterList{}, client.Limit(1)); err != nil {
+		return wrapKubernetesError(s.GetID(), fmt.Errorf("failed to list CAPI clusters: %w", err))
+	}
 	return nil
 }
 
@@ -95,8 +139,9 @@ func (s *CapiStore) getCapiClient() (client.Client, error) {
 
 	restConfig, err := clientConfig.ClientConfig()
 	if err != nil {
-		return nil, fmt.Errorf("unable to create rest config: %v", err)
+		return nil, &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("unable to create rest config: %w", err)}
 	}
+	restConfig.Wrap(transportWrapper())
 
 	k8sclient, err := client.New(restConfig, client.Options{
 		Scheme: scheme,
@@ -108,12 +153,15 @@ func (s *CapiStore) getCapiClient() (client.Client, error) {
 }
 
 // StartSearch starts the search over the configured search paths
-func (s *CapiStore) StartSearch(channel chan SearchResult) {
+func (s *CapiStore) StartSearch(ctx context.Context, channel chan SearchResult) {
 	s.Logger.Debug("CAPI: start search")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
 	defer cancel()
 
+	// the ingress rules of the Cloudflare Tunnel are retrieved once per search
+	s.resetIngressRules()
+
 	// initialize CAPI client
 	if err := s.InitializeCapiStore(); err != nil {
 		channel <- SearchResult{
@@ -148,29 +196,182 @@ func (s *CapiStore) StartSearch(channel chan SearchResult) {
 	}
 }
 
-// GetKubeconfigForPath returns the kubeconfig for the path
-func (s *CapiStore) GetKubeconfigForPath(path string, tags map[string]string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+// GetKubeconfigForPath returns the kubeconfig for the path.
+// If a Cloudflare Tunnel is configured and the public hostname of the cluster is routed through the tunnel,
+// the API server of the kubeconfig is replaced with the public hostname.
+func (s *CapiStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
 	defer cancel()
 
 	s.Logger.Debug("CAPI: GetKubeconfigForPath", "path", path)
 
-	obj := client.ObjectKey{
-		Namespace: tags["namespace"],
-		Name:      tags["name"],
-	}
-	dataBytes, err := utilkubeconfig.FromSecret(ctx, s.Client, obj)
+	dataBytes, err := s.getClusterKubeconfig(ctx, tags)
 	if err != nil {
-		s.Logger.Debug("CAPI: GetKubeconfigForPath", "error", err)
 		return nil, err
 	}
+
+	if s.TunnelClient == nil {
+		return dataBytes, nil
+	}
+	return s.routeThroughTunnel(ctx, path, dataBytes)
+}
+
+// getClusterKubeconfig returns the kubeconfig of the cluster from the secret created by CAPI.
+// The name and namespace of the secret depend on the configured provider.
+func (s *CapiStore) getClusterKubeconfig(ctx context.Context, tags map[string]string) ([]byte, error) {
+	// the client is not initialized if the search results are read from the index
+	if s.Client == nil {
+		if err := s.InitializeCapiStore(); err != nil {
+			return nil, err
+		}
+	}
+
+	key := s.ProviderDefaults.SecretKey(tags["namespace"], tags["name"])
+	kubeconfigSecret := &corev1.Secret{}
+	if err := s.Client.Get(ctx, key, kubeconfigSecret); err != nil {
+		s.Logger.Debug("CAPI: GetKubeconfigForPath", "error", err)
+		return nil, wrapKubernetesError(s.GetID(), fmt.Errorf("failed to get kubeconfig secret %s: %w", key, err))
+	}
+
+	dataBytes, ok := kubeconfigSecret.Data[capisecret.KubeconfigDataName]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig secret %s does not contain the key %q", key, capisecret.KubeconfigDataName)
+	}
 	return dataBytes, nil
 }
 
+// routeThroughTunnel replaces the API server of the kubeconfig with the public hostname of the cluster,
+// if the hostname is routed through the Cloudflare Tunnel.
+// Otherwise, the kubeconfig is returned unchanged.
+func (s *CapiStore) routeThroughTunnel(ctx context.Context, path string, kubeconfig []byte) ([]byte, error) {
+	tunnelConfig := s.Config.CloudflareTunnel
+	hostname := cloudflare.Hostname(path)
+
+	rules, err := s.getIngressRules(ctx)
+	if err != nil {
+		return nil, wrapCloudflareError(s.GetID(), fmt.Errorf("failed to get the ingress rules of Cloudflare Tunnel %q: %w", tunnelConfig.TunnelID, err))
+	}
+
+	if _, ok := cloudflare.FindHostname(rules, hostname); !ok {
+		s.Logger.Debugf("CAPI: hostname %q is not routed through Cloudflare Tunnel %q. Using the API server of the cluster.", hostname, tunnelConfig.TunnelID)
+		return kubeconfig, nil
+	}
+
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig of cluster %q: %w", path, err)
+	}
+
+	for _, cluster := range config.Clusters {
+		// the certificate of the API server is still verified with the CA of the cluster.
+		// The CA and the client certificates of the kubeconfig are kept.
+		if len(cluster.TLSServerName) == 0 {
+			server, err := url.Parse(cluster.Server)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse the API server of cluster %q: %w", path, err)
+			}
+			cluster.TLSServerName = server.Hostname()
+		}
+		cluster.Server = fmt.Sprintf("https://%s", hostname)
+	}
+	return clientcmd.Write(*config)
+}
+
+// getIngressRules returns the ingress rules of the Cloudflare Tunnel.
+// The rules are only retrieved once per search.
+func (s *CapiStore) getIngressRules(ctx context.Context) ([]cloudflare.IngressRule, error) {
+	s.ingressRulesMutex.Lock()
+	defer s.ingressRulesMutex.Unlock()
+
+	if s.ingressRules != nil {
+		return s.ingressRules, nil
+	}
+
+	tunnelConfig := s.Config.CloudflareTunnel
+	rules, err := s.TunnelClient.GetIngressRules(ctx, tunnelConfig.AccountID, tunnelConfig.TunnelID)
+	if err != nil {
+		return nil, err
+	}
+
+	// an empty, non-nil slice caches a tunnel without ingress rules
+	if rules == nil {
+		rules = []cloudflare.IngressRule{}
+	}
+	s.ingressRules = rules
+	return rules, nil
+}
+
+// resetIngressRules drops the cached ingress rules of the Cloudflare Tunnel
+func (s *CapiStore) resetIngressRules() {
+	s.ingressRulesMutex.Lock()
+	defer s.ingressRulesMutex.Unlock()
+	s.ingressRules = nil
+}
+
+// ProvisionCloudflareTunnel routes the public hostname of the cluster with the given path
+// through the configured Cloudflare Tunnel to the API server of the cluster.
+// Returns the public hostname.
+func (s *CapiStore) ProvisionCloudflareTunnel(ctx context.Context, path string, tags map[string]string) (string, error) {
+	if s.TunnelClient == nil {
+		return "", &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: fmt.Errorf("no Cloudflare Tunnel is configured")}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	kubeconfig, err := s.getClusterKubeconfig(ctx, tags)
+	if err != nil {
+		return "", err
+	}
+
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse kubeconfig of cluster %q: %w", path, err)
+	}
+
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok || config.Clusters[kubeContext.Cluster] == nil {
+		return "", fmt.Errorf("failed to determine the API server of cluster %q: the kubeconfig has no valid current context", path)
+	}
+
+	tunnelConfig := s.Config.CloudflareTunnel
+	hostname := cloudflare.Hostname(path)
+	rule := cloudflare.IngressRule{
+		Hostname: hostname,
+		Service:  config.Clusters[kubeContext.Cluster].Server,
+		// cloudflared does not know the CA of the cluster
+		OriginRequest: map[string]interface{}{"noTLSVerify": true},
+	}
+
+	if err := s.TunnelClient.PublishHostname(ctx, tunnelConfig.AccountID, tunnelConfig.TunnelID, rule); err != nil {
+		return "", wrapCloudflareError(s.GetID(), fmt.Errorf("failed to route hostname %q through Cloudflare Tunnel %q: %w", hostname, tunnelConfig.TunnelID, err))
+	}
+	s.resetIngressRules()
+	return hostname, nil
+}
+
+// wrapCloudflareError returns the typed error for a failed request against the Cloudflare API
+func wrapCloudflareError(storeID string, err error) error {
+	var apiError *cloudflare.APIError
+	if errors.As(err, &apiError) {
+		return wrapHTTPError(storeID, apiError.StatusCode, err)
+	}
+	return wrapTimeout(storeID, err)
+}
+
 func (s *CapiStore) GetLogger() *logrus.Entry {
 	return s.Logger
 }
 
+// Stop releases the cached ingress rules and closes the idle connections of the Cloudflare client
+func (s *CapiStore) Stop(_ context.Context) error {
+	s.resetIngressRules()
+	if s.TunnelClient != nil && s.TunnelClient.HTTPClient != nil {
+		s.TunnelClient.HTTPClient.CloseIdleConnections()
+	}
+	return nil
+}
+
 func (s *CapiStore) GetStoreConfig() types.KubeconfigStore {
 	return s.KubeconfigStore
 }
@@ -0,0 +1,204 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("CapiStore", func() {
+	newStore := func(provider string) (*store.CapiStore, error) {
+		return store.NewCapiStore(types.KubeconfigStore{
+			ID:   ptr.To("management"),
+			Kind: types.StoreKindCapi,
+			Config: map[string]interface{}{
+				"kubeconfigPath": "/path/to/kubeconfig",
+				"provider":       provider,
+			},
+		}, "")
+	}
+
+	It("should look up the kubeconfig secret of each provider", func() {
+		for provider, expected := range map[string]client.ObjectKey{
+			"":          {Namespace: "team-a", Name: "prod-kubeconfig"},
+			"generic":   {Namespace: "team-a", Name: "prod-kubeconfig"},
+			"aws":       {Namespace: "team-a", Name: "prod-kubeconfig"},
+			"azure":     {Namespace: "team-a", Name: "prod-kubeconfig"},
+			"gcp":       {Namespace: "team-a", Name: "prod-kubeconfig"},
+			"openstack": {Namespace: "capo-system", Name: "capo-prod-kubeconfig"},
+			"vsphere":   {Namespace: "team-a", Name: "prod-kubeconfig"},
+			"docker":    {Namespace: "team-a", Name: "prod-kubeconfig"},
+		} {
+			s, err := newStore(provider)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.ProviderDefaults.SecretKey("team-a", "prod")).To(Equal(expected), "provider %q", provider)
+		}
+	})
+
+	It("should reject unknown providers", func() {
+		_, err := newStore("openstak")
+		Expect(err).To(MatchError(ContainSubstring("unknown CAPI provider \"openstak\"")))
+	})
+
+	Context("Cloudflare Tunnel", func() {
+		const capiKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://10.0.0.1:6443
+    certificate-authority-data: Y2x1c3Rlci1jYQ==
+contexts:
+- name: prod-admin@prod
+  context:
+    cluster: prod
+    user: prod-admin
+current-context: prod-admin@prod
+users:
+- name: prod-admin
+  user:
+    client-certificate-data: Y2xpZW50LWNlcnQ=
+    client-key-data: Y2xpZW50LWtleQ==
+`
+
+		var (
+			server   *httptest.Server
+			s        *store.CapiStore
+			ingress  []interface{}
+			requests int
+			tags     = map[string]string{"namespace": "team-a", "name": "prod"}
+		)
+
+		BeforeEach(func() {
+			requests = 0
+			ingress = []interface{}{map[string]interface{}{"service": "http_status:404"}}
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.Method == http.MethodPut {
+					update := map[string]map[string][]interface{}{}
+					Expect(json.NewDecoder(r.Body).Decode(&update)).To(Succeed())
+					ingress = update["config"]["ingress"]
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"success": true,
+					"result":  map[string]interface{}{"config": map[string]interface{}{"ingress": ingress}},
+				})
+			}))
+
+			var err error
+			s, err = store.NewCapiStore(types.KubeconfigStore{
+				ID:   ptr.To("management"),
+				Kind: types.StoreKindCapi,
+				Config: map[string]interface{}{
+					"kubeconfigPath": "/path/to/kubeconfig",
+					"cloudflareTunnel": map[string]interface{}{
+						"accountID": "account",
+						"tunnelID":  "tunnel",
+						"apiToken":  "token",
+					},
+				},
+			}, "")
+			Expect(err).ToNot(HaveOccurred())
+			s.TunnelClient.BaseURL = server.URL
+			s.Client = &secretClient{secrets: map[client.ObjectKey]*corev1.Secret{
+				{Namespace: "team-a", Name: "prod-kubeconfig"}: {Data: map[string][]byte{"value": []byte(capiKubeconfig)}},
+			}}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should keep the API server of clusters not routed through the tunnel", func() {
+			kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "team-a-prod", tags)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(kubeconfig)).To(Equal(capiKubeconfig))
+		})
+
+		It("should route the API server through the tunnel and keep verifying the certificate of the API server", func() {
+			_, err := s.ProvisionCloudflareTunnel(context.Background(), "team-a-prod", tags)
+			Expect(err).ToNot(HaveOccurred())
+
+			kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "team-a-prod", tags)
+			Expect(err).ToNot(HaveOccurred())
+
+			config, err := clientcmd.Load(kubeconfig)
+			Expect(err).ToNot(HaveOccurred())
+			cluster := config.Clusters["prod"]
+			Expect(cluster.Server).To(Equal("https://team-a-prod.cfargotunnel.com"))
+			Expect(cluster.TLSServerName).To(Equal("10.0.0.1"))
+			Expect(cluster.InsecureSkipTLSVerify).To(BeFalse())
+			Expect(string(cluster.CertificateAuthorityData)).To(Equal("cluster-ca"))
+
+			user := config.AuthInfos["prod-admin"]
+			Expect(string(user.ClientCertificateData)).To(Equal("client-cert"))
+			Expect(string(user.ClientKeyData)).To(Equal("client-key"))
+		})
+
+		It("should retrieve the ingress rules only once", func() {
+			for i := 0; i < 3; i++ {
+				_, err := s.GetKubeconfigForPath(context.Background(), "team-a-prod", tags)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(requests).To(Equal(1))
+		})
+
+		It("should retrieve the ingress rules again after routing a cluster through the tunnel", func() {
+			_, err := s.GetKubeconfigForPath(context.Background(), "team-a-prod", tags)
+			Expect(err).ToNot(HaveOccurred())
+
+			// one request to read and one request to update the configuration of the tunnel
+			_, err = s.ProvisionCloudflareTunnel(context.Background(), "team-a-prod", tags)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal(3))
+
+			kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "team-a-prod", tags)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(Equal(4))
+			Expect(string(kubeconfig)).To(ContainSubstring("https://team-a-prod.cfargotunnel.com"))
+		})
+	})
+})
+
+// secretClient returns the configured secrets.
+// All other methods of the client are not implemented.
+type secretClient struct {
+	client.Client
+	secrets map[client.ObjectKey]*corev1.Secret
+}
+
+func (c *secretClient) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	secret, ok := c.secrets[key]
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, key.Name)
+	}
+	secret.DeepCopyInto(obj.(*corev1.Secret))
+	return nil
+}
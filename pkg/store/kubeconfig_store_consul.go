@@ -0,0 +1,257 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+func NewConsulStore(kubeconfigName string, kubeconfigStore types.KubeconfigStore) (*ConsulStore, error) {
+	consulStoreConfig := &types.StoreConfigConsul{}
+	if kubeconfigStore.Config != nil {
+		buf, err := yaml.Marshal(kubeconfigStore.Config)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to process consul store config: %w", err))
+		}
+
+		err = yaml.Unmarshal(buf, consulStoreConfig)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to unmarshal consul config: %w", err))
+		}
+	}
+
+	switch consulStoreConfig.Discovery {
+	case "":
+		consulStoreConfig.Discovery = types.ConsulDiscoveryStructured
+	case types.ConsulDiscoveryStructured, types.ConsulDiscoveryFlat:
+	default:
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("unknown Consul discovery %q. Valid values are %q and %q", consulStoreConfig.Discovery, types.ConsulDiscoveryStructured, types.ConsulDiscoveryFlat))
+	}
+
+	// the default configuration reads the CONSUL_* environment variables
+	clientConfig := consulapi.DefaultConfig()
+	if len(consulStoreConfig.Address) > 0 {
+		clientConfig.Address = consulStoreConfig.Address
+	}
+	if len(consulStoreConfig.Token) > 0 {
+		clientConfig.Token = consulStoreConfig.Token
+	}
+	if len(consulStoreConfig.TLSCACertFile) > 0 {
+		clientConfig.TLSConfig.CAFile = consulStoreConfig.TLSCACertFile
+	}
+	if len(consulStoreConfig.DataCenter) > 0 {
+		clientConfig.Datacenter = consulStoreConfig.DataCenter
+	}
+	if len(consulStoreConfig.Namespace) > 0 {
+		clientConfig.Namespace = consulStoreConfig.Namespace
+	}
+
+	client, err := consulapi.NewClient(clientConfig)
+	if err != nil {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to create Consul client: %w", err))
+	}
+
+	return &ConsulStore{
+		Logger:          logrus.New().WithField("store", types.StoreKindConsul),
+		KubeconfigStore: kubeconfigStore,
+		KubeconfigName:  kubeconfigName,
+		Client:          client,
+		Config:          consulStoreConfig,
+		transport:       clientConfig.Transport,
+	}, nil
+}
+
+func (s *ConsulStore) GetID() string {
+	id := "default"
+	if s.KubeconfigStore.ID != nil {
+		id = *s.KubeconfigStore.ID
+	}
+	return fmt.Sprintf("%s.%s", types.StoreKindConsul, id)
+}
+
+func (s *ConsulStore) GetKind() types.StoreKind {
+	return types.StoreKindConsul
+}
+
+func (s *ConsulStore) GetContextPrefix(p string) string {
+	if s.GetStoreConfig().ShowPrefix != nil && !*s.GetStoreConfig().ShowPrefix {
+		return ""
+	}
+
+	// return the name of the parent "directory" of the key
+	return path.Base(path.Dir(p))
+}
+
+func (s *ConsulStore) GetStoreConfig() types.KubeconfigStore {
+	return s.KubeconfigStore
+}
+
+func (s *ConsulStore) GetLogger() *logrus.Entry {
+	return s.Logger
+}
+
+// Stop closes the idle connections of the Consul client
+func (s *ConsulStore) Stop(_ context.Context) error {
+	if s.transport != nil {
+		s.transport.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (s *ConsulStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	if len(s.KubeconfigStore.Paths) == 0 {
+		return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: fmt.Errorf("at least one key prefix must be configured in the paths of the Consul store")}
+	}
+	return nil
+}
+
+// Probe checks that the Consul agent is reachable by requesting the current Raft leader
+func (s *ConsulStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	if _, err := s.Client.Status().LeaderWithQueryOptions((&consulapi.QueryOptions{}).WithContext(ctx)); err != nil {
+		return wrapConsulError(s.GetID(), fmt.Errorf("failed to reach Consul: %w", err))
+	}
+	return nil
+}
+
+func (s *ConsulStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	wg := sync.WaitGroup{}
+	for _, prefix := range s.KubeconfigStore.Paths {
+		wg.Add(1)
+		go func(prefix string) {
+			defer wg.Done()
+
+			if s.Config.Discovery == types.ConsulDiscoveryFlat {
+				s.searchFlat(ctx, prefix, channel)
+				return
+			}
+			s.searchStructured(ctx, normalizeConsulPrefix(prefix), channel)
+		}(prefix)
+	}
+	wg.Wait()
+}
+
+// searchFlat sends every key below the given prefix
+func (s *ConsulStore) searchFlat(ctx context.Context, prefix string, channel chan SearchResult) {
+	keys, _, err := s.Client.KV().Keys(prefix, "", (&consulapi.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		sendSearchResult(ctx, channel, SearchResult{
+			KubeconfigPath: "",
+			Error:          fmt.Errorf("failed to list keys with prefix %q in Consul: %w", prefix, err),
+		})
+		return
+	}
+
+	for _, key := range keys {
+		// skip "directory" keys
+		if strings.HasSuffix(key, "/") {
+			continue
+		}
+		if !sendSearchResult(ctx, channel, SearchResult{
+			KubeconfigPath: key,
+			Error:          nil,
+		}) {
+			return
+		}
+	}
+}
+
+// searchStructured lists the keys on the level of the given prefix, sends all keys matching the kubeconfig name
+// and recursively searches all "directories".
+// Returns false if the search is cancelled.
+func (s *ConsulStore) searchStructured(ctx context.Context, prefix string, channel chan SearchResult) bool {
+	keys, _, err := s.Client.KV().Keys(prefix, "/", (&consulapi.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return sendSearchResult(ctx, channel, SearchResult{
+			KubeconfigPath: "",
+			Error:          fmt.Errorf("failed to list keys with prefix %q in Consul: %w", prefix, err),
+		})
+	}
+
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			// the prefix itself is returned if it exists as key
+			if key != prefix && !s.searchStructured(ctx, key, channel) {
+				return false
+			}
+			continue
+		}
+
+		matched, err := path.Match(s.KubeconfigName, path.Base(key))
+		if err != nil {
+			sendSearchResult(ctx, channel, SearchResult{
+				KubeconfigPath: "",
+				Error:          fmt.Errorf("invalid kubeconfig name pattern %q: %w", s.KubeconfigName, err),
+			})
+			return false
+		}
+		if matched && !sendSearchResult(ctx, channel, SearchResult{
+			KubeconfigPath: key,
+			Error:          nil,
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *ConsulStore) GetKubeconfigForPath(ctx context.Context, p string, _ map[string]string) ([]byte, error) {
+	s.Logger.Debugf("Consul: get kubeconfig for key %s", p)
+
+	pair, _, err := s.Client.KV().Get(p, (&consulapi.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return nil, wrapConsulError(s.GetID(), fmt.Errorf("failed to get key %q from Consul: %w", p, err))
+	}
+	if pair == nil {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("key %q does not exist in Consul", p)}
+	}
+	return pair.Value, nil
+}
+
+// wrapConsulError returns the typed error for a failed request against the Consul API
+func wrapConsulError(storeID string, err error) error {
+	var statusError consulapi.StatusError
+	if errors.As(err, &statusError) {
+		if statusError.Code == http.StatusNotFound {
+			return &storeerrors.ErrKubeconfigNotFound{StoreID: storeID, Err: err}
+		}
+		return wrapHTTPError(storeID, statusError.Code, err)
+	}
+	return wrapTimeout(storeID, err)
+}
+
+// normalizeConsulPrefix makes sure the prefix denotes a "directory"
+func normalizeConsulPrefix(prefix string) string {
+	prefix = strings.TrimPrefix(prefix, "/")
+	if len(prefix) > 0 && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return prefix
+}
@@ -0,0 +1,175 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// newConsulKVServer returns a server mocking the key/value API of Consul
+func newConsulKVServer(kv map[string]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/status/leader" {
+			_ = json.NewEncoder(w).Encode("127.0.0.1:8300")
+			return
+		}
+
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		query := r.URL.Query()
+
+		if !query.Has("keys") {
+			value, ok := kv[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]consulapi.KVPair{{Key: key, Value: []byte(value)}})
+			return
+		}
+
+		separator := query.Get("separator")
+		keys := map[string]struct{}{}
+		for k := range kv {
+			if !strings.HasPrefix(k, key) {
+				continue
+			}
+			if i := strings.Index(k[len(key):], separator); separator != "" && i >= 0 {
+				k = k[:len(key)+i+1]
+			}
+			keys[k] = struct{}{}
+		}
+
+		var result []string
+		for k := range keys {
+			result = append(result, k)
+		}
+		sort.Strings(result)
+		_ = json.NewEncoder(w).Encode(result)
+	}))
+}
+
+var _ = Describe("ConsulStore", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = newConsulKVServer(map[string]string{
+			"kubeconfigs/dev/config":         "dev",
+			"kubeconfigs/dev/notes":          "not a kubeconfig",
+			"kubeconfigs/prod/eu/config":     "prod-eu",
+			"kubeconfigs/prod/us/config":     "prod-us",
+			"other/config":                   "other",
+			"kubeconfigs/prod/us/config-old": "prod-us-old",
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newStore := func(discovery types.ConsulDiscovery) *store.ConsulStore {
+		s, err := store.NewConsulStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindConsul,
+			Paths: []string{"kubeconfigs"},
+			Config: map[string]interface{}{
+				"address":   server.URL,
+				"discovery": string(discovery),
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.VerifyKubeconfigPaths(context.Background())).To(Succeed())
+		return s
+	}
+
+	search := func(s *store.ConsulStore) []string {
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+
+		var paths []string
+		for _, result := range results {
+			paths = append(paths, result.KubeconfigPath)
+		}
+		return paths
+	}
+
+	It("should discover the kubeconfigs in the key hierarchy", func() {
+		s := newStore(types.ConsulDiscoveryStructured)
+		Expect(search(s)).To(ConsistOf(
+			"kubeconfigs/dev/config",
+			"kubeconfigs/prod/eu/config",
+			"kubeconfigs/prod/us/config",
+		))
+		Expect(s.GetContextPrefix("kubeconfigs/prod/eu/config")).To(Equal("eu"))
+	})
+
+	It("should discover all keys below the prefix", func() {
+		Expect(search(newStore(types.ConsulDiscoveryFlat))).To(ConsistOf(
+			"kubeconfigs/dev/config",
+			"kubeconfigs/dev/notes",
+			"kubeconfigs/prod/eu/config",
+			"kubeconfigs/prod/us/config",
+			"kubeconfigs/prod/us/config-old",
+		))
+	})
+
+	It("should get the kubeconfig for a key", func() {
+		s := newStore(types.ConsulDiscoveryStructured)
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "kubeconfigs/prod/eu/config", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig)).To(Equal("prod-eu"))
+
+		_, err = s.GetKubeconfigForPath(context.Background(), "kubeconfigs/missing", nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should stop the search once cancelled", func() {
+		Expect(testutil.CancelSearch(newStore(types.ConsulDiscoveryStructured), 1, time.Second)).To(Succeed())
+		Expect(testutil.CancelSearch(newStore(types.ConsulDiscoveryFlat), 1, time.Second)).To(Succeed())
+	})
+
+	It("should not wait for Consul once the search is cancelled", func() {
+		unresponsiveServer, closeServer := newUnresponsiveServer()
+		defer closeServer()
+
+		s, err := store.NewConsulStore("config", types.KubeconfigStore{
+			Kind:   types.StoreKindConsul,
+			Paths:  []string{"kubeconfigs"},
+			Config: map[string]interface{}{"address": unresponsiveServer.URL},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(testutil.CancelSearch(s, 0, time.Second)).To(Succeed())
+	})
+
+	It("should probe the Consul agent", func() {
+		s := newStore(types.ConsulDiscoveryStructured)
+		Expect(s.Probe(context.Background())).To(Succeed())
+
+		server.Close()
+		Expect(s.Probe(context.Background())).ToNot(Succeed())
+	})
+})
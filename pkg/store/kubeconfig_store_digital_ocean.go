@@ -17,6 +17,7 @@ package store
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
@@ -27,8 +28,8 @@ import (
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
 	"github.com/danielfoehrkn/kubeswitch/types"
-	"github.com/digitalocean/doctl/do"
 	"github.com/digitalocean/godo"
 )
 
@@ -56,36 +57,37 @@ func NewDigitalOceanStore(store types.KubeconfigStore) (*DigitalOceanStore, erro
 	}
 
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to load doctl config file")
+		return nil, invalidConfig(store, errors.Wrap(err, "failed to load doctl config file"))
 	}
 
 	return &DigitalOceanStore{
 		Logger:          logrus.New().WithField("store", types.StoreKindDigitalOcean),
 		KubeconfigStore: store,
 		Config:          *doctlConfig,
+		transport:       cloneDefaultTransport(),
 	}, nil
 }
 
 // InitializeDigitalOceanStore initializes the DigitalOcean store with digital ocean clients
-func (d *DigitalOceanStore) InitializeDigitalOceanStore() error {
-	contextToKubernetesService := make(map[string]do.KubernetesService)
+func (d *DigitalOceanStore) InitializeDigitalOceanStore(ctx context.Context) error {
+	contextToKubernetesService := make(map[string]godo.KubernetesService)
 	accessToken := d.Config.DefaultAuthContextAccessToken
-	defaultContextClient, err := d.getDoClient(accessToken)
+	defaultContextClient, err := d.getDoClient(ctx, accessToken)
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("failed to intialize the client for the default digital ocean account/context (context: %s)", d.Config.DefaultContextName))
+		return &storeerrors.ErrAuthFailed{StoreID: d.GetID(), Err: errors.Wrap(err, fmt.Sprintf("failed to intialize the client for the default digital ocean account/context (context: %s)", d.Config.DefaultContextName))}
 	}
 
-	contextToKubernetesService[d.Config.DefaultContextName] = do.NewKubernetesService(defaultContextClient)
+	contextToKubernetesService[d.Config.DefaultContextName] = defaultContextClient.Kubernetes
 	d.Logger.Debugf("Created digital ocean client for context: %s", d.Config.DefaultContextName)
 
 	// if there are multiple contexts configured
 	for doctlContextName, token := range d.Config.AuthContexts {
-		doClient, err := d.getDoClient(token)
+		doClient, err := d.getDoClient(ctx, token)
 		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("failed to intialize digital ocean client (context: %s)", d.Config.DefaultContextName))
+			return &storeerrors.ErrAuthFailed{StoreID: d.GetID(), Err: errors.Wrap(err, fmt.Sprintf("failed to intialize digital ocean client (context: %s)", d.Config.DefaultContextName))}
 		}
 
-		contextToKubernetesService[doctlContextName] = do.NewKubernetesService(doClient)
+		contextToKubernetesService[doctlContextName] = doClient.Kubernetes
 		d.Logger.Debugf("Created digital ocean client for context: %s", doctlContextName)
 	}
 	d.ContextToKubernetesService = contextToKubernetesService
@@ -94,9 +96,13 @@ func (d *DigitalOceanStore) InitializeDigitalOceanStore() error {
 
 // getDoClient creates the digital ocean client for a given access token
 // inspired by: https://github.com/digitalocean/doctl/blob/7f1c9db38d19cd1104dc96537c00c6436768955a/doit.go#L235
-func (d *DigitalOceanStore) getDoClient(accessToken string) (*godo.Client, error) {
+func (d *DigitalOceanStore) getDoClient(ctx context.Context, accessToken string) (*godo.Client, error) {
 	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken})
-	oauthClient := oauth2.NewClient(context.Background(), tokenSource)
+	if d.transport != nil {
+		// the clients of all doctl contexts share the connections of the store
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{Transport: d.transport})
+	}
+	oauthClient := oauth2.NewClient(ctx, tokenSource)
 
 	args := []godo.ClientOpt{
 		godo.SetUserAgent("kubeswitch-client"),
@@ -125,12 +131,11 @@ func (d *DigitalOceanStore) getDoClient(accessToken string) (*godo.Client, error
 }
 
 // StartSearch starts the search for Digital Ocean clusters
-func (d *DigitalOceanStore) StartSearch(channel chan SearchResult) {
-	if err := d.InitializeDigitalOceanStore(); err != nil {
-		err := fmt.Errorf("failed to initialize store: %w", err)
-		channel <- SearchResult{
-			Error: err,
-		}
+func (d *DigitalOceanStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	if err := d.InitializeDigitalOceanStore(ctx); err != nil {
+		sendSearchResult(ctx, channel, SearchResult{
+			Error: fmt.Errorf("failed to initialize store: %w", err),
+		})
 		return
 	}
 
@@ -139,16 +144,16 @@ func (d *DigitalOceanStore) StartSearch(channel chan SearchResult) {
 
 	for doctlContextName, doSvc := range d.ContextToKubernetesService {
 		// parallelize.
-		go func(resultChannel chan SearchResult, doctlCtxName string, svc do.KubernetesService) {
+		go func(resultChannel chan SearchResult, doctlCtxName string, svc godo.KubernetesService) {
 			// reading from this context is finished, decrease wait counter
 			defer wgResultChannel.Done()
 
 			d.Logger.Debugf("Digital Ocean: Start listing clusters for context %q", doctlCtxName)
-			clusters, err := svc.List()
+			clusters, err := listDigitalOceanClusters(ctx, svc)
 			if err != nil {
-				channel <- SearchResult{
-					Error: fmt.Errorf("error listing DOKS clusters for context %s: %w", doctlCtxName, err),
-				}
+				sendSearchResult(ctx, channel, SearchResult{
+					Error: wrapDigitalOceanError(d.GetID(), fmt.Errorf("error listing DOKS clusters for context %s: %w", doctlCtxName, err)),
+				})
 				return
 			}
 
@@ -162,7 +167,7 @@ func (d *DigitalOceanStore) StartSearch(channel chan SearchResult) {
 				}
 				nodePools = fmt.Sprintf("%s]", nodePools)
 
-				channel <- SearchResult{
+				if !sendSearchResult(ctx, channel, SearchResult{
 					KubeconfigPath: kubeconfigPath,
 					Tags: map[string]string{
 						tagDOKSClusterID:    cluster.ID,
@@ -173,6 +178,8 @@ func (d *DigitalOceanStore) StartSearch(channel chan SearchResult) {
 						tagNodePools:        nodePools,
 					},
 					Error: nil,
+				}) {
+					return
 				}
 			}
 
@@ -190,6 +197,29 @@ func (d *DigitalOceanStore) StartSearch(channel chan SearchResult) {
 	d.Logger.Debugf("Digital Ocean: Search done for all contexts")
 }
 
+// listDigitalOceanClusters lists the DOKS clusters of all pages
+func listDigitalOceanClusters(ctx context.Context, svc godo.KubernetesService) ([]*godo.KubernetesCluster, error) {
+	var clusters []*godo.KubernetesCluster
+	opt := &godo.ListOptions{Page: 1, PerPage: 200}
+	for {
+		page, resp, err := svc.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, page...)
+
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			return clusters, nil
+		}
+
+		currentPage, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = currentPage + 1
+	}
+}
+
 func getDigitalOceanKubeconfigPath(context, region, clusterName string) string {
 	// required to be unique for each cluster
 	return fmt.Sprintf("do_%s--%s--%s", context, region, clusterName)
@@ -238,12 +268,20 @@ func (s *DigitalOceanStore) GetLogger() *logrus.Entry {
 	return s.Logger
 }
 
+// Stop closes the idle connections of the DigitalOcean clients
+func (s *DigitalOceanStore) Stop(_ context.Context) error {
+	if s.transport != nil {
+		s.transport.CloseIdleConnections()
+	}
+	return nil
+}
+
 // GetKubeconfigForPath gets the kubeconfig bytes for the given kubeconfig path and tags
 // For this store, instead of using the path to identify the kubeconfig in the backing store, the cluster ID in the tags metadata
 // is used. Reason: the clusterID is a long non-intuitive string that we don't want to
-func (d *DigitalOceanStore) GetKubeconfigForPath(path string, tags map[string]string) ([]byte, error) {
+func (d *DigitalOceanStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
 	if !d.IsInitialized() {
-		if err := d.InitializeDigitalOceanStore(); err != nil {
+		if err := d.InitializeDigitalOceanStore(ctx); err != nil {
 			return nil, fmt.Errorf("failed to initialize Digital Ocean store: %w", err)
 		}
 	}
@@ -258,11 +296,11 @@ func (d *DigitalOceanStore) GetKubeconfigForPath(path string, tags map[string]st
 
 	// the tags are either set from the initial search or when using an index, are stoerd in the index file itself.
 	if clusterID, ok = tags[tagDOKSClusterID]; !ok {
-		return nil, fmt.Errorf("failed to GetKubeconfigForPath: %s. Required cluster ID not found in the metadata tags: %v", path, tags)
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: d.GetID(), Err: fmt.Errorf("failed to GetKubeconfigForPath: %s. Required cluster ID not found in the metadata tags: %v", path, tags)}
 	}
 
 	if doctlContextName, ok = tags[tagDoctlContextName]; !ok {
-		return nil, fmt.Errorf("failed to GetKubeconfigForPath: %s. Required doctl context name not found in the metadata tags: %v", path, tags)
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: d.GetID(), Err: fmt.Errorf("failed to GetKubeconfigForPath: %s. Required doctl context name not found in the metadata tags: %v", path, tags)}
 	}
 
 	region = tags[tagRegion]
@@ -270,19 +308,49 @@ func (d *DigitalOceanStore) GetKubeconfigForPath(path string, tags map[string]st
 
 	d.Logger.Debugf("Digital Ocean: GetKubeconfigForPath (context: %s, region: %s, DOKS cluster name: %s, DOKS cluster ID: %s)", doctlContextName, region, name, clusterID)
 
-	kubeconfigBytes, err := d.ContextToKubernetesService[doctlContextName].GetKubeConfig(clusterID)
+	svc, ok := d.ContextToKubernetesService[doctlContextName]
+	if !ok {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: d.GetID(), Err: fmt.Errorf("failed to GetKubeconfigForPath: %s. The doctl context %q is not configured", path, doctlContextName)}
+	}
+
+	kubeconfig, _, err := svc.GetKubeConfig(ctx, clusterID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to obtain kubeconfig for DOKS cluster (context: %s, region: %s, DOKS cluster name: %s, cluster_id: %s): %w", doctlContextName, region, name, clusterID, err)
+		return nil, wrapDigitalOceanError(d.GetID(), fmt.Errorf("failed to obtain kubeconfig for DOKS cluster (context: %s, region: %s, DOKS cluster name: %s, cluster_id: %s): %w", doctlContextName, region, name, clusterID, err))
 	}
 
-	return kubeconfigBytes, nil
+	return kubeconfig.KubeconfigYAML, nil
 }
 
-func (s *DigitalOceanStore) VerifyKubeconfigPaths() error {
+func (s *DigitalOceanStore) VerifyKubeconfigPaths(ctx context.Context) error {
 	// NOOP
 	return nil
 }
 
+// Probe checks that the DigitalOcean API is reachable by getting the account of the default doctl context
+func (s *DigitalOceanStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	doClient, err := s.getDoClient(ctx, s.Config.DefaultAuthContextAccessToken)
+	if err != nil {
+		return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("failed to intialize the client for the default digital ocean account/context (context: %s): %w", s.Config.DefaultContextName, err)}
+	}
+
+	if _, _, err := doClient.Account.Get(ctx); err != nil {
+		return wrapDigitalOceanError(s.GetID(), fmt.Errorf("failed to get the digital ocean account (context: %s): %w", s.Config.DefaultContextName, err))
+	}
+	return nil
+}
+
+// wrapDigitalOceanError returns the typed error for a failed request against the DigitalOcean API
+func wrapDigitalOceanError(storeID string, err error) error {
+	var errorResponse *godo.ErrorResponse
+	if errors.As(err, &errorResponse) && errorResponse.Response != nil {
+		return wrapHTTPError(storeID, errorResponse.Response.StatusCode, err)
+	}
+	return wrapTimeout(storeID, err)
+}
+
 // ParseIdentifier takes a kubeconfig identifier and
 // returns the
 // 1) the `doctl` context name
@@ -0,0 +1,110 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/doks"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("DigitalOceanStore", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case r.URL.Path == "/v2/kubernetes/clusters" && r.URL.Query().Get("page") == "1":
+				_, _ = fmt.Fprintf(w, `{"kubernetes_clusters": [
+					{"id": "1", "name": "dev", "region": "fra1", "version": "1.30.2-do.0", "node_pools": [{"name": "default"}]},
+					{"id": "2", "name": "staging", "region": "fra1"}
+				], "links": {"pages": {"next": "%[1]s/v2/kubernetes/clusters?page=2", "last": "%[1]s/v2/kubernetes/clusters?page=2"}}}`, server.URL)
+			case r.URL.Path == "/v2/kubernetes/clusters" && r.URL.Query().Get("page") == "2":
+				_, _ = fmt.Fprintf(w, `{"kubernetes_clusters": [
+					{"id": "3", "name": "prod", "region": "ams3"}
+				], "links": {"pages": {"prev": "%[1]s/v2/kubernetes/clusters?page=1", "first": "%[1]s/v2/kubernetes/clusters?page=1"}}}`, server.URL)
+			case r.URL.Path == "/v2/kubernetes/clusters/3/kubeconfig":
+				w.Header().Set("Content-Type", "application/yaml")
+				_, _ = w.Write([]byte("prod"))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newStore := func(apiURL string) *store.DigitalOceanStore {
+		return &store.DigitalOceanStore{
+			Logger:          testutil.NewTestLogger(),
+			KubeconfigStore: types.KubeconfigStore{Kind: types.StoreKindDigitalOcean},
+			Config: doks.DoctlConfig{
+				DefaultContextName:            "default",
+				DefaultAuthContextAccessToken: "token",
+				ApiUrl:                        apiURL,
+			},
+		}
+	}
+
+	It("should discover the clusters of all pages", func() {
+		results, err := testutil.CollectResults(newStore(server.URL), testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(ConsistOf(
+			testutil.FakeSearchResult("do_default--fra1--dev", "id=1", "ctx=default", "name=dev", "region=fra1", "version=1.30.2-do.0", "pools=[ default]"),
+			testutil.FakeSearchResult("do_default--fra1--staging", "id=2", "ctx=default", "name=staging", "region=fra1", "version=", "pools=[]"),
+			testutil.FakeSearchResult("do_default--ams3--prod", "id=3", "ctx=default", "name=prod", "region=ams3", "version=", "pools=[]"),
+		))
+	})
+
+	It("should get the kubeconfig of a cluster", func() {
+		kubeconfig, err := newStore(server.URL).GetKubeconfigForPath(context.Background(), "do_default--ams3--prod", map[string]string{"id": "3", "ctx": "default"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig)).To(Equal("prod"))
+	})
+
+	It("should fail for an unknown doctl context", func() {
+		_, err := newStore(server.URL).GetKubeconfigForPath(context.Background(), "do_other--ams3--prod", map[string]string{"id": "3", "ctx": "other"})
+		Expect(err).To(MatchError(ContainSubstring(`The doctl context "other" is not configured`)))
+	})
+
+	It("should stop the search once cancelled", func() {
+		Expect(testutil.CancelSearch(newStore(server.URL), 1, time.Second)).To(Succeed())
+	})
+
+	It("should not wait for the DigitalOcean API once the search is cancelled", func() {
+		unresponsiveServer, closeServer := newUnresponsiveServer()
+		defer closeServer()
+
+		Expect(testutil.CancelSearch(newStore(unresponsiveServer.URL), 0, time.Second)).To(Succeed())
+	})
+})
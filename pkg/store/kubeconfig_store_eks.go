@@ -15,52 +15,88 @@
 package store
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
 	awseks "github.com/aws/aws-sdk-go-v2/service/eks"
 	awsekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/smithy-go/logging"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/karpenter"
 	"github.com/danielfoehrkn/kubeswitch/types"
 	"github.com/disiqueira/gotree"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
+const (
+	// eksAuthenticationModeAPI is the authentication mode of clusters only using the EKS access entry API
+	eksAuthenticationModeAPI = "API"
+	// eksAuthenticationModeAPIAndConfigMap is the authentication mode of clusters using both the EKS access entry API and the aws-auth ConfigMap
+	eksAuthenticationModeAPIAndConfigMap = "API_AND_CONFIG_MAP"
+)
+
+// EKSDefaultClusterNameTemplate formats the kubeconfig paths of EKS clusters if no cluster name template is configured
+const EKSDefaultClusterNameTemplate = "eks_{{.ProfileName}}--{{.Region}}--{{.ClusterName}}"
+
+// nodePoolPreviewTTL is the duration the Karpenter NodePools of the enriched preview are cached
+const nodePoolPreviewTTL = 30 * time.Second
+
+// nodePoolPreview are the Karpenter NodePools of a cluster shown in the enriched preview.
+// The NodePools are nil if they could not be listed.
+type nodePoolPreview struct {
+	nodePools []karpenter.NodePool
+	fetchedAt time.Time
+}
+
 func NewEKSStore(store types.KubeconfigStore, stateDir string) (*EKSStore, error) {
 	eksStoreConfig := &types.StoreConfigEKS{}
 	if store.Config != nil {
 		buf, err := yaml.Marshal(store.Config)
 		if err != nil {
-			return nil, err
+			return nil, invalidConfig(store, err)
 		}
 
 		err = yaml.Unmarshal(buf, eksStoreConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal eks config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to unmarshal eks config: %w", err))
 		}
 
 		if eksStoreConfig.Region == nil {
 			defaultregion, ok := os.LookupEnv("AWS_DEFAULT_REGION")
 			if !ok {
-				return nil, fmt.Errorf("failed to set aws region from config or environment")
+				return nil, invalidConfig(store, fmt.Errorf("failed to set aws region from config or environment"))
 			}
 			eksStoreConfig.Region = &defaultregion
 		}
 	} else {
 		profile, ok := os.LookupEnv("AWS_PROFILE")
 		if !ok {
-			return nil, fmt.Errorf("failed to set aws profile from config or environment")
+			return nil, invalidConfig(store, fmt.Errorf("failed to set aws profile from config or environment"))
 		}
 
 		region, ok := os.LookupEnv("AWS_REGION")
 		defaultregion, ok2 := os.LookupEnv("AWS_DEFAULT_REGION")
 		if !ok && !ok2 {
-			return nil, fmt.Errorf("failed to set aws region from config or environment")
+			return nil, invalidConfig(store, fmt.Errorf("failed to set aws region from config or environment"))
 		}
 		if ok2 {
 			region = defaultregion
@@ -71,10 +107,10 @@ func NewEKSStore(store types.KubeconfigStore, stateDir string) (*EKSStore, error
 	}
 
 	if len(eksStoreConfig.Profile) == 0 {
-		return nil, fmt.Errorf("profile is required")
+		return nil, invalidConfig(store, fmt.Errorf("profile is required"))
 	}
 	if eksStoreConfig.Region == nil || len(*eksStoreConfig.Region) == 0 {
-		return nil, fmt.Errorf("region is required")
+		return nil, invalidConfig(store, fmt.Errorf("region is required"))
 	}
 
 	return &EKSStore{
@@ -95,17 +131,135 @@ func (s *EKSStore) InitializeEKSStore() error {
 
 	optFns = append(optFns, awsconfig.WithRegion(*s.Config.Region))
 	optFns = append(optFns, awsconfig.WithSharedConfigProfile(s.Config.Profile))
+	optFns = append(optFns, awsAPICallLogOptions()...)
+
+	if s.Config.UseIMDSv2 == nil || *s.Config.UseIMDSv2 {
+		optFns = append(optFns, awsconfig.WithEC2IMDSClientEnableState(imds.ClientEnabled))
+		optFns = append(optFns, awsconfig.WithEC2RoleCredentialOptions(func(o *ec2rolecreds.Options) {
+			o.Client = imds.New(imds.Options{}, IMDSv2Options)
+		}))
+	}
 
 	cfg, err := awsconfig.LoadDefaultConfig(ctx, optFns...)
 	if err != nil {
-		return err
+		return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: err}
 	}
 
-	s.Client = awseks.NewFromConfig(cfg)
+	if s.CredentialCache != nil {
+		profile := s.Config.Profile
+		if len(profile) == 0 {
+			profile = "default"
+		}
+		cfg.Credentials = aws.NewCredentialsCache(credentials.NewAWSCredentialsProvider(s.CredentialCache, s.GetID(), profile, cfg.Credentials))
+	}
+
+	s.Client = s.NewClient(cfg)
+	s.STSClient = sts.NewFromConfig(cfg)
 
 	return nil
 }
 
+// wrapAWSError returns the typed error for a failed request against the AWS API
+func wrapAWSError(storeID string, err error) error {
+	var responseError interface{ HTTPStatusCode() int }
+	if errors.As(err, &responseError) {
+		return wrapHTTPError(storeID, responseError.HTTPStatusCode(), err)
+	}
+	return wrapTimeout(storeID, err)
+}
+
+// EKSGetTokenArgs returns the arguments of the AWS CLI to retrieve a token for the cluster.
+// Clusters using EKS Pod Identity are identified by the cluster ID (--cluster-id) instead of the name (--cluster-name).
+// If useEKSPodIdentity is not set, EKS Pod Identity is assumed for clusters using the EKS access entry API,
+// i.e., the authentication mode of the access config of the cluster is "API" or "API_AND_CONFIG_MAP".
+func EKSGetTokenArgs(region string, cluster *awsekstypes.Cluster, authenticationMode string, useEKSPodIdentity *bool) []string {
+	podIdentity := useEKSPodIdentity != nil && *useEKSPodIdentity
+	if useEKSPodIdentity == nil {
+		switch authenticationMode {
+		case eksAuthenticationModeAPI, eksAuthenticationModeAPIAndConfigMap:
+			podIdentity = true
+		}
+	}
+
+	if !podIdentity {
+		return []string{"--region", region, "eks", "get-token", "--cluster-name", *cluster.Name}
+	}
+
+	// only local clusters on AWS Outposts have an ID differing from the name
+	clusterID := *cluster.Name
+	if cluster.Id != nil {
+		clusterID = *cluster.Id
+	}
+	return []string{"--region", region, "eks", "get-token", "--cluster-id", clusterID}
+}
+
+// NewClient returns an EKS client recording the authentication modes of the described clusters
+func (s *EKSStore) NewClient(cfg aws.Config, optFns ...func(*awseks.Options)) *awseks.Client {
+	optFns = append(optFns, func(o *awseks.Options) {
+		o.APIOptions = append(o.APIOptions, s.readAuthenticationModes)
+	})
+	return awseks.NewFromConfig(cfg, optFns...)
+}
+
+// readAuthenticationModes records the authentication mode of the clusters described by the EKS API.
+// The access config of a cluster is not decoded by the EKS client, as it predates the EKS access entry API.
+// Hence, the authentication mode is read from the raw response of DescribeCluster.
+func (s *EKSStore) readAuthenticationModes(stack *middleware.Stack) error {
+	return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("ReadAuthenticationMode", func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+		out, metadata, err := next.HandleDeserialize(ctx, in)
+		if err != nil || awsmiddleware.GetOperationName(ctx) != "DescribeCluster" {
+			return out, metadata, err
+		}
+
+		response, ok := out.RawResponse.(*smithyhttp.Response)
+		if !ok || response.StatusCode != http.StatusOK {
+			return out, metadata, err
+		}
+
+		body, err := io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if err != nil {
+			return out, metadata, err
+		}
+		response.Body = io.NopCloser(bytes.NewReader(body))
+
+		var describeCluster struct {
+			Cluster struct {
+				Name         string `json:"name"`
+				AccessConfig struct {
+					AuthenticationMode string `json:"authenticationMode"`
+				} `json:"accessConfig"`
+			} `json:"cluster"`
+		}
+		if err := json.Unmarshal(body, &describeCluster); err != nil {
+			// the EKS client reports the malformed response
+			return out, metadata, nil
+		}
+
+		s.authenticationModesLock.Lock()
+		defer s.authenticationModesLock.Unlock()
+		if s.authenticationModes == nil {
+			s.authenticationModes = make(map[string]string)
+		}
+		s.authenticationModes[describeCluster.Cluster.Name] = describeCluster.Cluster.AccessConfig.AuthenticationMode
+		return out, metadata, nil
+	}), middleware.After)
+}
+
+// getAuthenticationMode returns the authentication mode of the described cluster or an empty string if it is unknown
+func (s *EKSStore) getAuthenticationMode(clusterName string) string {
+	s.authenticationModesLock.Lock()
+	defer s.authenticationModesLock.Unlock()
+	return s.authenticationModes[clusterName]
+}
+
+// IMDSv2Options configures the EC2 instance metadata client to require a session token (IMDSv2).
+// Failing to fetch a token results in an error instead of silently falling back to IMDSv1.
+func IMDSv2Options(o *imds.Options) {
+	o.ClientEnableState = imds.ClientEnabled
+	o.EnableFallback = aws.FalseTernary
+}
+
 func (s *EKSStore) IsInitialized() bool {
 	return s.Client != nil && s.Config != nil
 }
@@ -135,6 +289,19 @@ func (s *EKSStore) GetLogger() *logrus.Entry {
 	return s.Logger
 }
 
+// Stop releases the cached node pools and authentication modes.
+// The connections of the EKS client are managed by the AWS SDK.
+func (s *EKSStore) Stop(_ context.Context) error {
+	s.nodePoolPreviewsLock.Lock()
+	clear(s.nodePoolPreviews)
+	s.nodePoolPreviewsLock.Unlock()
+
+	s.authenticationModesLock.Lock()
+	clear(s.authenticationModes)
+	s.authenticationModesLock.Unlock()
+	return nil
+}
+
 func (s *EKSStore) GetContextPrefix(path string) string {
 	if s.GetStoreConfig().ShowPrefix != nil && !*s.GetStoreConfig().ShowPrefix {
 		return ""
@@ -143,13 +310,33 @@ func (s *EKSStore) GetContextPrefix(path string) string {
 	return strings.ReplaceAll(path, "--", "-")
 }
 
-func (s *EKSStore) VerifyKubeconfigPaths() error {
-	// NOOP
+// VerifyKubeconfigPaths verifies the cluster name template
+func (s *EKSStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	if _, err := NewClusterNameTemplate(s.Config.ClusterNameTemplate, EKSDefaultClusterNameTemplate); err != nil {
+		return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: err}
+	}
 	return nil
 }
 
-func (s *EKSStore) StartSearch(channel chan SearchResult) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// Probe checks that the AWS credentials are valid by getting the caller identity
+func (s *EKSStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	if !s.IsInitialized() {
+		if err := s.InitializeEKSStore(); err != nil {
+			return fmt.Errorf("failed to initialize store: %w", err)
+		}
+	}
+
+	if _, err := s.STSClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{}); err != nil {
+		return wrapAWSError(s.GetID(), fmt.Errorf("failed to get AWS caller identity: %w", err))
+	}
+	return nil
+}
+
+func (s *EKSStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	if err := s.InitializeEKSStore(); err != nil {
@@ -160,37 +347,124 @@ func (s *EKSStore) StartSearch(channel chan SearchResult) {
 		return
 	}
 
+	if err := s.listClusters(ctx, func(result SearchResult) {
+		channel <- result
+	}); err != nil {
+		channel <- SearchResult{
+			Error: err,
+		}
+		return
+	}
+	s.GetLogger().Debugf("Search done for EKS")
+}
+
+// SearchChangesSince lists the names of the EKS clusters.
+// EKS does not offer an API to list the clusters changed since the given time,
+// hence the clusters whose kubeconfig path is not yet indexed are returned as added
+// and the indexed kubeconfig paths of clusters that no longer exist are returned as removed.
+// In contrast to a full search, the clusters do not have to be described.
+func (s *EKSStore) SearchChangesSince(ctx context.Context, _ time.Time, existing []string) ([]SearchResult, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	if !s.IsInitialized() {
+		if err := s.InitializeEKSStore(); err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize store. This is most likely a problem with your provided aws credentials: %w", err)
+		}
+	}
+
+	indexed := make(map[string]struct{}, len(existing))
+	for _, path := range existing {
+		indexed[path] = struct{}{}
+	}
+
+	var added []SearchResult
+	current := map[string]struct{}{}
+	if err := s.listClusters(ctx, func(result SearchResult) {
+		current[result.KubeconfigPath] = struct{}{}
+		if _, ok := indexed[result.KubeconfigPath]; !ok {
+			added = append(added, result)
+		}
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	var removed []string
+	for _, path := range existing {
+		if _, ok := current[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	return added, removed, nil
+}
+
+// listClusters calls the given function with the search result of every EKS cluster
+func (s *EKSStore) listClusters(ctx context.Context, found func(result SearchResult)) error {
+	clusterNameTemplate, err := NewClusterNameTemplate(s.Config.ClusterNameTemplate, EKSDefaultClusterNameTemplate)
+	if err != nil {
+		return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: err}
+	}
+
+	templateData := ClusterNameTemplateData{
+		Region:      *s.Config.Region,
+		ProfileName: s.Config.Profile,
+	}
+
+	// only look up the account ID if required, as this is an additional request
+	if usesTemplateVariable(s.Config.ClusterNameTemplate, "AccountID") {
+		identity, err := s.STSClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			return fmt.Errorf("failed to get AWS account ID: %w", err)
+		}
+		templateData.AccountID = aws.ToString(identity.Account)
+	}
+
 	opts := &awseks.ListClustersInput{}
 	pager := awseks.NewListClustersPaginator(s.Client, opts)
 	for pager.HasMorePages() {
 		s.GetLogger().Debugf("next page found")
 		resp, err := pager.NextPage(ctx)
 		if err != nil {
-			channel <- SearchResult{
-				Error: err,
-			}
-			return
+			return err
 		}
 
 		for _, clusterName := range resp.Clusters {
 			// kubeconfig path used to uniquely identify this cluster
-			// eks_<profile>--<region>--<eks-cluster-name>
-			kubeconfigPath := fmt.Sprintf("eks_%s--%s--%s", s.Config.Profile, *s.Config.Region, clusterName)
+			// defaults to eks_<profile>--<region>--<eks-cluster-name>
+			templateData.ClusterName = clusterName
+			kubeconfigPath, err := clusterNameTemplate.Execute(templateData)
+			if err != nil {
+				return err
+			}
 
-			channel <- SearchResult{
-				KubeconfigPath: kubeconfigPath,
-				Error:          nil,
+			var tags map[string]string
+			if clusterNameTemplate.IsCustom() {
+				tags = map[string]string{
+					tagProfile:     s.Config.Profile,
+					tagRegion:      *s.Config.Region,
+					tagClusterName: clusterName,
+				}
 			}
+
+			found(SearchResult{
+				KubeconfigPath: kubeconfigPath,
+				Tags:           tags,
+			})
 		}
 	}
-	s.GetLogger().Debugf("Search done for EKS")
+	return nil
 }
 
 // ParseIdentifier takes a kubeconfig identifier and
 // returns the
 // 1) the EKS resource group
 // 2) the name of the EKS cluster
-func parseEksIdentifier(path string) (string, string, string, error) {
+// The cluster is identified using the tags if the kubeconfig path has been formatted with a custom cluster name template.
+func parseEksIdentifier(path string, tags map[string]string) (string, string, string, error) {
+	if clusterName, ok := tags[tagClusterName]; ok {
+		return tags[tagProfile], tags[tagRegion], clusterName, nil
+	}
+
 	split := strings.Split(path, "--")
 	switch len(split) {
 	case 3:
@@ -200,8 +474,8 @@ func parseEksIdentifier(path string) (string, string, string, error) {
 	}
 }
 
-func (s *EKSStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+func (s *EKSStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	if !s.IsInitialized() {
@@ -209,16 +483,20 @@ func (s *EKSStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byt
 			return nil, fmt.Errorf("failed to initialize EKS store: %w", err)
 		}
 	}
-	_, _, clusterName, err := parseEksIdentifier(path)
+	_, _, clusterName, err := parseEksIdentifier(path, tags)
 	if err != nil {
-		return nil, err
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: err}
 	}
 
 	cluster := s.DiscoveredClusters[path]
 	if cluster == nil {
 		resp, err := s.Client.DescribeCluster(ctx, &awseks.DescribeClusterInput{Name: &clusterName})
 		if err != nil {
-			return nil, err
+			var notFound *awsekstypes.ResourceNotFoundException
+			if errors.As(err, &notFound) {
+				return nil, &storeerrors.ErrClusterNotFound{StoreID: s.GetID(), Err: err}
+			}
+			return nil, wrapAWSError(s.GetID(), err)
 		}
 		s.DiscoveredClusters[path] = resp.Cluster
 		cluster = resp.Cluster
@@ -261,14 +539,7 @@ func (s *EKSStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byt
 					ExecProvider: &types.ExecProvider{
 						APIVersion: "client.authentication.k8s.io/v1beta1",
 						Command:    "aws",
-						Args: []string{
-							"--region",
-							*s.Config.Region,
-							"eks",
-							"get-token",
-							"--cluster-name",
-							*cluster.Name,
-						},
+						Args:       EKSGetTokenArgs(*s.Config.Region, cluster, s.getAuthenticationMode(*cluster.Name), s.Config.UseEKSPodIdentity),
 						Env: []types.EnvMap{
 							{Name: "AWS_PROFILE", Value: s.Config.Profile},
 						},
@@ -298,7 +569,7 @@ func (s *EKSStore) GetSearchPreview(path string, optionalTags map[string]string)
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	profile, region, clusterName, err := parseEksIdentifier(path)
+	profile, region, clusterName, err := parseEksIdentifier(path, optionalTags)
 	if err != nil {
 		return "", err
 	}
@@ -332,9 +603,60 @@ func (s *EKSStore) GetSearchPreview(path string, optionalTags map[string]string)
 	asciTree.Add(fmt.Sprintf("AWS Profile: %s", profile))
 	asciTree.Add(fmt.Sprintf("Region: %s", region))
 
+	if s.Config.EnrichPreview {
+		if nodePools := s.getKarpenterNodePools(path, optionalTags); nodePools != nil {
+			karpenter.AddToTree(asciTree, nodePools)
+		}
+	}
+
 	return asciTree.Print(), nil
 }
 
+// getKarpenterNodePools returns the Karpenter NodePools of the cluster for the enriched preview.
+// Returns nil if the cluster is unreachable or Karpenter is not installed.
+// The result is cached, as the preview is rendered on every cursor movement.
+func (s *EKSStore) getKarpenterNodePools(path string, tags map[string]string) []karpenter.NodePool {
+	s.nodePoolPreviewsLock.Lock()
+	preview, ok := s.nodePoolPreviews[path]
+	s.nodePoolPreviewsLock.Unlock()
+	if ok && time.Since(preview.fetchedAt) < nodePoolPreviewTTL {
+		return preview.nodePools
+	}
+
+	nodePools, err := s.listKarpenterNodePools(path, tags)
+	if err != nil {
+		s.Logger.Debugf("failed to list the Karpenter NodePools for the preview of %q: %v", path, err)
+	}
+
+	s.nodePoolPreviewsLock.Lock()
+	defer s.nodePoolPreviewsLock.Unlock()
+	if s.nodePoolPreviews == nil {
+		s.nodePoolPreviews = make(map[string]nodePoolPreview)
+	}
+	s.nodePoolPreviews[path] = nodePoolPreview{nodePools: nodePools, fetchedAt: time.Now()}
+	return nodePools
+}
+
+// listKarpenterNodePools connects to the cluster with the kubeconfig of the path and lists the Karpenter NodePools
+func (s *EKSStore) listKarpenterNodePools(path string, tags map[string]string) ([]karpenter.NodePool, error) {
+	// low timeout, as the preview must not block
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	kubeconfig, err := s.GetKubeconfigForPath(ctx, path, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	restConfig.Timeout = 3 * time.Second
+
+	return karpenter.ListNodePools(ctx, restConfig)
+}
+
 // AWSLogrusBridgeLogger is a Logger implementation that wraps the standard library logger, and delegates logging to it's
 // Printf method.
 type AWSLogrusBridgeLogger struct {
@@ -0,0 +1,197 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
+	awseks "github.com/aws/aws-sdk-go-v2/service/eks"
+	awsekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+	"k8s.io/utils/ptr"
+)
+
+// newIMDSv1Server returns a server mocking an instance metadata service that only supports IMDSv1
+// and records the paths of all metadata requests issued without a session token
+func newIMDSv1Server(tokenlessRequests *[]string, lock *sync.Mutex) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Path == "/latest/api/token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if r.Header.Get("X-aws-ec2-metadata-token") == "" {
+			lock.Lock()
+			*tokenlessRequests = append(*tokenlessRequests, r.URL.Path)
+			lock.Unlock()
+		}
+		_, _ = w.Write([]byte("my-role"))
+	}))
+}
+
+var _ = Describe("EKSStore", func() {
+	var (
+		server            *httptest.Server
+		tokenlessRequests []string
+		lock              sync.Mutex
+	)
+
+	BeforeEach(func() {
+		tokenlessRequests = nil
+		server = newIMDSv1Server(&tokenlessRequests, &lock)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should reject the IMDSv1 flow when IMDSv2 is required", func() {
+		client := imds.New(imds.Options{Endpoint: server.URL}, store.IMDSv2Options)
+
+		_, err := client.GetMetadata(context.Background(), &imds.GetMetadataInput{Path: "iam/security-credentials/"})
+		Expect(err).To(HaveOccurred())
+		Expect(tokenlessRequests).To(BeEmpty())
+	})
+
+	It("should fall back to the IMDSv1 flow by default", func() {
+		client := imds.New(imds.Options{Endpoint: server.URL})
+
+		_, err := client.GetMetadata(context.Background(), &imds.GetMetadataInput{Path: "iam/security-credentials/"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tokenlessRequests).To(ConsistOf("/latest/meta-data/iam/security-credentials/"))
+	})
+})
+
+var _ = Describe("EKSGetTokenArgs", func() {
+	cluster := &awsekstypes.Cluster{Name: ptr.To("my-cluster")}
+
+	It("should use the cluster name for clusters using the aws-auth ConfigMap", func() {
+		Expect(store.EKSGetTokenArgs("eu-west-1", cluster, "CONFIG_MAP", nil)).
+			To(Equal([]string{"--region", "eu-west-1", "eks", "get-token", "--cluster-name", "my-cluster"}))
+		Expect(store.EKSGetTokenArgs("eu-west-1", cluster, "", nil)).
+			To(ContainElement("--cluster-name"))
+	})
+
+	It("should use the cluster ID for clusters using the access entry API", func() {
+		Expect(store.EKSGetTokenArgs("eu-west-1", cluster, "API", nil)).
+			To(Equal([]string{"--region", "eu-west-1", "eks", "get-token", "--cluster-id", "my-cluster"}))
+		Expect(store.EKSGetTokenArgs("eu-west-1", cluster, "API_AND_CONFIG_MAP", nil)).
+			To(ContainElement("--cluster-id"))
+	})
+
+	It("should prefer the configured setting over the authentication mode", func() {
+		Expect(store.EKSGetTokenArgs("eu-west-1", cluster, "API", ptr.To(false))).
+			To(ContainElement("--cluster-name"))
+
+		outpostCluster := &awsekstypes.Cluster{Name: ptr.To("my-cluster"), Id: ptr.To("0123abcd")}
+		Expect(store.EKSGetTokenArgs("eu-west-1", outpostCluster, "CONFIG_MAP", ptr.To(true))).
+			To(Equal([]string{"--region", "eu-west-1", "eks", "get-token", "--cluster-id", "0123abcd"}))
+	})
+
+	It("should detect the authentication mode from the DescribeCluster response", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"cluster": {
+				"name": "my-cluster",
+				"arn": "arn:aws:eks:eu-west-1:123:cluster/my-cluster",
+				"endpoint": "https://my-cluster.eks.amazonaws.com",
+				"certificateAuthority": {"data": "Y2E="},
+				"accessConfig": {"authenticationMode": "API_AND_CONFIG_MAP"}
+			}}`))
+		}))
+		defer server.Close()
+
+		s, err := store.NewEKSStore(types.KubeconfigStore{
+			Kind:   types.StoreKindEKS,
+			Config: map[string]interface{}{"profile": "user1", "region": "eu-west-1"},
+		}, "")
+		Expect(err).ToNot(HaveOccurred())
+		s.Logger = testutil.NewTestLogger()
+		s.Client = s.NewClient(aws.Config{Region: "eu-west-1", Credentials: aws.AnonymousCredentials{}}, func(o *awseks.Options) {
+			o.EndpointResolver = awseks.EndpointResolverFromURL(server.URL)
+		})
+
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "eks_user1--eu-west-1--my-cluster", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig)).To(ContainSubstring("--cluster-id"))
+		Expect(string(kubeconfig)).ToNot(ContainSubstring("--cluster-name"))
+	})
+})
+
+var _ = Describe("EKSStore SearchChangesSince", func() {
+	var (
+		server *httptest.Server
+		s      *store.EKSStore
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Query().Get("nextToken") {
+			case "":
+				_, _ = w.Write([]byte(`{"clusters": ["existing"], "nextToken": "page-2"}`))
+			default:
+				_, _ = w.Write([]byte(`{"clusters": ["new"]}`))
+			}
+		}))
+
+		var err error
+		s, err = store.NewEKSStore(types.KubeconfigStore{
+			Kind:   types.StoreKindEKS,
+			Config: map[string]interface{}{"profile": "user1", "region": "eu-west-1"},
+		}, "")
+		Expect(err).ToNot(HaveOccurred())
+		s.Logger = testutil.NewTestLogger()
+		s.Client = s.NewClient(aws.Config{Region: "eu-west-1", Credentials: aws.AnonymousCredentials{}}, func(o *awseks.Options) {
+			o.EndpointResolver = awseks.EndpointResolverFromURL(server.URL)
+		})
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should return the clusters not contained in the index and the indexed clusters that no longer exist", func() {
+		added, removed, err := s.SearchChangesSince(context.Background(), time.Now(), []string{
+			"eks_user1--eu-west-1--existing",
+			"eks_user1--eu-west-1--deleted",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(added).To(Equal([]store.SearchResult{{KubeconfigPath: "eks_user1--eu-west-1--new"}}))
+		Expect(removed).To(Equal([]string{"eks_user1--eu-west-1--deleted"}))
+	})
+
+	It("should tag the clusters if a custom cluster name template is configured", func() {
+		s.Config.ClusterNameTemplate = "{{.ClusterName}}"
+
+		added, removed, err := s.SearchChangesSince(context.Background(), time.Now(), []string{"existing"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(added).To(HaveLen(1))
+		Expect(added[0].KubeconfigPath).To(Equal("new"))
+		Expect(added[0].Tags).To(HaveKeyWithValue("clusterName", "new"))
+		Expect(removed).To(BeEmpty())
+	})
+})
@@ -0,0 +1,307 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+)
+
+const (
+	etcdRequestTimeout = 10 * time.Second
+	// etcdMaxConcurrentLeaseRequests limits the concurrent requests for the TTL of leases during a search
+	etcdMaxConcurrentLeaseRequests = 10
+)
+
+func NewEtcdStore(kubeconfigName string, kubeconfigStore types.KubeconfigStore) (*EtcdStore, error) {
+	etcdStoreConfig := &types.StoreConfigEtcd{}
+	if kubeconfigStore.Config != nil {
+		buf, err := yaml.Marshal(kubeconfigStore.Config)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to process etcd store config: %w", err))
+		}
+
+		err = yaml.Unmarshal(buf, etcdStoreConfig)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to unmarshal etcd config: %w", err))
+		}
+	}
+
+	if len(etcdStoreConfig.Endpoints) == 0 {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("at least one etcd endpoint must be configured"))
+	}
+
+	tlsConfig, err := getEtcdTLSConfig(etcdStoreConfig)
+	if err != nil {
+		return nil, invalidConfig(kubeconfigStore, err)
+	}
+
+	// the client connects lazily
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   etcdStoreConfig.Endpoints,
+		DialTimeout: 5 * time.Second,
+		TLS:         tlsConfig,
+		Username:    etcdStoreConfig.Username,
+		Password:    etcdStoreConfig.Password,
+		Logger:      zap.NewNop(),
+	})
+	if err != nil {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to create etcd client: %w", err))
+	}
+
+	return &EtcdStore{
+		Logger:          logrus.New().WithField("store", types.StoreKindEtcd),
+		KubeconfigStore: kubeconfigStore,
+		KubeconfigName:  kubeconfigName,
+		Client:          client,
+		Config:          etcdStoreConfig,
+	}, nil
+}
+
+// getEtcdTLSConfig returns the TLS configuration for the etcd client or nil if TLS is not configured
+func getEtcdTLSConfig(config *types.StoreConfigEtcd) (*tls.Config, error) {
+	if len(config.TLSCACertFile) == 0 && len(config.TLSCertFile) == 0 {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if len(config.TLSCACertFile) > 0 {
+		caCert, err := os.ReadFile(config.TLSCACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate file of etcd: %w", err)
+		}
+
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse CA certificate file %q of etcd", config.TLSCACertFile)
+		}
+		tlsConfig.RootCAs = certPool
+	}
+
+	if len(config.TLSCertFile) > 0 {
+		certificate, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load etcd client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+func (s *EtcdStore) GetID() string {
+	id := "default"
+	if s.KubeconfigStore.ID != nil {
+		id = *s.KubeconfigStore.ID
+	}
+	return fmt.Sprintf("%s.%s", types.StoreKindEtcd, id)
+}
+
+func (s *EtcdStore) GetKind() types.StoreKind {
+	return types.StoreKindEtcd
+}
+
+func (s *EtcdStore) GetContextPrefix(p string) string {
+	if s.GetStoreConfig().ShowPrefix != nil && !*s.GetStoreConfig().ShowPrefix {
+		return ""
+	}
+
+	// return the name of the parent "directory" of the key
+	return path.Base(path.Dir(p))
+}
+
+func (s *EtcdStore) GetStoreConfig() types.KubeconfigStore {
+	return s.KubeconfigStore
+}
+
+func (s *EtcdStore) GetLogger() *logrus.Entry {
+	return s.Logger
+}
+
+// Stop closes the connections of the etcd client
+func (s *EtcdStore) Stop(_ context.Context) error {
+	if s.Client == nil {
+		return nil
+	}
+	return s.stopper.Do(s.Client.Close)
+}
+
+func (s *EtcdStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	if len(s.KubeconfigStore.Paths) == 0 {
+		return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: fmt.Errorf("at least one key prefix must be configured in the paths of the etcd store")}
+	}
+	return nil
+}
+
+// Probe checks that etcd is reachable by requesting the status of the first endpoint
+func (s *EtcdStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	endpoints := s.Client.Endpoints()
+	if len(endpoints) == 0 {
+		return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: fmt.Errorf("no etcd endpoints configured")}
+	}
+
+	if _, err := s.Client.Status(ctx, endpoints[0]); err != nil {
+		return wrapEtcdError(s.GetID(), fmt.Errorf("failed to get the status of etcd endpoint %q: %w", endpoints[0], err))
+	}
+	return nil
+}
+
+func (s *EtcdStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	wg := sync.WaitGroup{}
+	for _, prefix := range s.KubeconfigStore.Paths {
+		wg.Add(1)
+		go func(prefix string) {
+			defer wg.Done()
+			s.searchPrefix(ctx, prefix, channel)
+		}(prefix)
+	}
+	wg.Wait()
+}
+
+// searchPrefix sends all keys with the given prefix whose last path segment matches the kubeconfig name.
+// The remaining TTL of keys attached to a lease is added as "ttl" tag.
+func (s *EtcdStore) searchPrefix(ctx context.Context, prefix string, channel chan SearchResult) {
+	requestCtx, cancel := context.WithTimeout(ctx, etcdRequestTimeout)
+	defer cancel()
+
+	response, err := s.Client.Get(requestCtx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		sendSearchResult(ctx, channel, SearchResult{
+			KubeconfigPath: "",
+			Error:          wrapEtcdError(s.GetID(), fmt.Errorf("failed to list keys with prefix %q in etcd: %w", prefix, err)),
+		})
+		return
+	}
+
+	var kvs []*mvccpb.KeyValue
+	for _, kv := range response.Kvs {
+		matched, err := path.Match(s.KubeconfigName, path.Base(string(kv.Key)))
+		if err != nil {
+			sendSearchResult(ctx, channel, SearchResult{
+				KubeconfigPath: "",
+				Error:          fmt.Errorf("invalid kubeconfig name pattern %q: %w", s.KubeconfigName, err),
+			})
+			return
+		}
+		if matched {
+			kvs = append(kvs, kv)
+		}
+	}
+
+	ttls := s.getLeaseTTLs(requestCtx, kvs)
+	for _, kv := range kvs {
+		var tags map[string]string
+		if ttl, ok := ttls[clientv3.LeaseID(kv.Lease)]; ok {
+			tags = map[string]string{
+				"ttl": ttl.String(),
+			}
+		}
+
+		if !sendSearchResult(ctx, channel, SearchResult{
+			KubeconfigPath: string(kv.Key),
+			Tags:           tags,
+			Error:          nil,
+		}) {
+			return
+		}
+	}
+}
+
+// getLeaseTTLs returns the remaining TTL of the leases attached to the given keys.
+// Keys typically share leases, so the TTL of each lease is requested only once and the requests are sent concurrently.
+// Leases without remaining TTL or whose TTL cannot be requested are omitted.
+func (s *EtcdStore) getLeaseTTLs(ctx context.Context, kvs []*mvccpb.KeyValue) map[clientv3.LeaseID]time.Duration {
+	var (
+		ttls      = make(map[clientv3.LeaseID]time.Duration)
+		ttlsLock  sync.Mutex
+		wg        sync.WaitGroup
+		semaphore = make(chan struct{}, etcdMaxConcurrentLeaseRequests)
+		requested = make(map[clientv3.LeaseID]struct{})
+	)
+
+	for _, kv := range kvs {
+		leaseID := clientv3.LeaseID(kv.Lease)
+		if leaseID == clientv3.NoLease {
+			continue
+		}
+		if _, ok := requested[leaseID]; ok {
+			continue
+		}
+		requested[leaseID] = struct{}{}
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(leaseID clientv3.LeaseID) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			lease, err := s.Client.TimeToLive(ctx, leaseID)
+			if err != nil {
+				s.Logger.Debugf("failed to get TTL of lease %x: %v", leaseID, err)
+				return
+			}
+			if lease.TTL > 0 {
+				ttlsLock.Lock()
+				ttls[leaseID] = time.Duration(lease.TTL) * time.Second
+				ttlsLock.Unlock()
+			}
+		}(leaseID)
+	}
+	wg.Wait()
+	return ttls
+}
+
+func (s *EtcdStore) GetKubeconfigForPath(ctx context.Context, p string, _ map[string]string) ([]byte, error) {
+	s.Logger.Debugf("etcd: get kubeconfig for key %s", p)
+
+	ctx, cancel := context.WithTimeout(ctx, etcdRequestTimeout)
+	defer cancel()
+
+	response, err := s.Client.Get(ctx, p)
+	if err != nil {
+		return nil, wrapEtcdError(s.GetID(), fmt.Errorf("failed to get key %q from etcd: %w", p, err))
+	}
+	if len(response.Kvs) == 0 {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("key %q does not exist in etcd", p)}
+	}
+	return response.Kvs[0].Value, nil
+}
+
+// wrapEtcdError returns the typed error for a failed request against etcd
+func wrapEtcdError(storeID string, err error) error {
+	if errors.Is(err, rpctypes.ErrAuthFailed) || errors.Is(err, rpctypes.ErrPermissionDenied) || errors.Is(err, rpctypes.ErrInvalidAuthToken) {
+		return &storeerrors.ErrAuthFailed{StoreID: storeID, Err: err}
+	}
+	return wrapTimeout(storeID, err)
+}
@@ -0,0 +1,189 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	"google.golang.org/grpc"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// fakeEtcdServer implements the etcd KV and lease APIs used by the etcd store
+type fakeEtcdServer struct {
+	etcdserverpb.UnimplementedKVServer
+	etcdserverpb.UnimplementedLeaseServer
+
+	kvs []*mvccpb.KeyValue
+	// ttls are the remaining TTLs in seconds by lease ID. Leases without TTL fail.
+	ttls map[int64]int64
+
+	lock        sync.Mutex
+	ttlRequests map[int64]int
+
+	// unresponsive delays the responses until the request is cancelled
+	unresponsive bool
+}
+
+func (s *fakeEtcdServer) Range(ctx context.Context, request *etcdserverpb.RangeRequest) (*etcdserverpb.RangeResponse, error) {
+	if s.unresponsive {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	response := &etcdserverpb.RangeResponse{Header: &etcdserverpb.ResponseHeader{}}
+	for _, kv := range s.kvs {
+		// keys are only requested by prefix or by their exact name
+		if (len(request.RangeEnd) > 0 && bytes.HasPrefix(kv.Key, request.Key)) || bytes.Equal(kv.Key, request.Key) {
+			match := &mvccpb.KeyValue{Key: kv.Key, Lease: kv.Lease}
+			if !request.KeysOnly {
+				match.Value = kv.Value
+			}
+			response.Kvs = append(response.Kvs, match)
+		}
+	}
+	response.Count = int64(len(response.Kvs))
+	return response, nil
+}
+
+func (s *fakeEtcdServer) LeaseTimeToLive(_ context.Context, request *etcdserverpb.LeaseTimeToLiveRequest) (*etcdserverpb.LeaseTimeToLiveResponse, error) {
+	s.lock.Lock()
+	s.ttlRequests[request.ID]++
+	s.lock.Unlock()
+
+	ttl, ok := s.ttls[request.ID]
+	if !ok {
+		return nil, rpctypes.ErrGRPCLeaseNotFound
+	}
+	return &etcdserverpb.LeaseTimeToLiveResponse{Header: &etcdserverpb.ResponseHeader{}, ID: request.ID, TTL: ttl}, nil
+}
+
+var _ = Describe("EtcdStore", func() {
+	var (
+		server     *grpc.Server
+		fakeServer *fakeEtcdServer
+		endpoint   string
+		s          *store.EtcdStore
+	)
+
+	BeforeEach(func() {
+		fakeServer = &fakeEtcdServer{
+			kvs: []*mvccpb.KeyValue{
+				{Key: []byte("/kubeconfigs/dev/config"), Value: []byte("dev"), Lease: 1},
+				{Key: []byte("/kubeconfigs/staging/config"), Value: []byte("staging"), Lease: 1},
+				{Key: []byte("/kubeconfigs/prod/config"), Value: []byte("prod")},
+				{Key: []byte("/kubeconfigs/expired/config"), Value: []byte("expired"), Lease: 2},
+				{Key: []byte("/kubeconfigs/dev/notes"), Value: []byte("not a kubeconfig"), Lease: 1},
+				{Key: []byte("/other/config"), Value: []byte("other")},
+			},
+			ttls:        map[int64]int64{1: 90},
+			ttlRequests: map[int64]int{},
+		}
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		endpoint = listener.Addr().String()
+
+		server = grpc.NewServer()
+		etcdserverpb.RegisterKVServer(server, fakeServer)
+		etcdserverpb.RegisterLeaseServer(server, fakeServer)
+		go func() {
+			_ = server.Serve(listener)
+		}()
+
+		s, err = store.NewEtcdStore("config", types.KubeconfigStore{
+			Kind:   types.StoreKindEtcd,
+			Paths:  []string{"/kubeconfigs/"},
+			Config: map[string]interface{}{"endpoints": []string{endpoint}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(s.Stop(context.Background())).To(Succeed())
+		server.Stop()
+	})
+
+	It("should require an endpoint", func() {
+		_, err := store.NewEtcdStore("config", types.KubeconfigStore{Kind: types.StoreKindEtcd})
+		Expect(err).To(MatchError(&storeerrors.ErrInvalidConfig{}))
+	})
+
+	It("should require a key prefix", func() {
+		s.KubeconfigStore.Paths = nil
+		Expect(s.VerifyKubeconfigPaths(context.Background())).To(MatchError(&storeerrors.ErrInvalidConfig{}))
+	})
+
+	It("should find the keys matching the kubeconfig name with the TTL of their lease", func() {
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(ConsistOf(
+			testutil.FakeSearchResult("/kubeconfigs/dev/config", "ttl=1m30s"),
+			testutil.FakeSearchResult("/kubeconfigs/staging/config", "ttl=1m30s"),
+			testutil.FakeSearchResult("/kubeconfigs/prod/config"),
+			testutil.FakeSearchResult("/kubeconfigs/expired/config"),
+		))
+	})
+
+	It("should request the TTL of each lease only once", func() {
+		_, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+
+		fakeServer.lock.Lock()
+		defer fakeServer.lock.Unlock()
+		Expect(fakeServer.ttlRequests).To(Equal(map[int64]int{1: 1, 2: 1}))
+	})
+
+	It("should support patterns as kubeconfig name", func() {
+		s.KubeconfigName = "*"
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		testutil.AssertResultContains(GinkgoT(), results, "/kubeconfigs/dev/notes")
+		testutil.AssertResultCount(GinkgoT(), results, 5)
+	})
+
+	It("should stop the search once cancelled", func() {
+		Expect(testutil.CancelSearch(s, 1, time.Second)).To(Succeed())
+	})
+
+	It("should not wait for etcd once the search is cancelled", func() {
+		fakeServer.unresponsive = true
+		Expect(testutil.CancelSearch(s, 0, time.Second)).To(Succeed())
+	})
+
+	It("should get the kubeconfig for a key", func() {
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "/kubeconfigs/prod/config", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig)).To(Equal("prod"))
+	})
+
+	It("should return a not found error for a missing key", func() {
+		_, err := s.GetKubeconfigForPath(context.Background(), "/kubeconfigs/missing/config", nil)
+		Expect(err).To(MatchError(&storeerrors.ErrKubeconfigNotFound{}))
+	})
+})
@@ -0,0 +1,468 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const (
+	// fallbackPrimaryPathPrefix is the prefix of kubeconfig paths discovered by the primary store
+	fallbackPrimaryPathPrefix = "primary::"
+	// fallbackFallbackPathPrefix is the prefix of kubeconfig paths discovered by the fallback store
+	fallbackFallbackPathPrefix = "fallback::"
+	// fallbackPathSeparator separates the path of the primary store from the path of the fallback store
+	// providing the same contexts
+	fallbackPathSeparator = "::fallback::"
+	// fallbackTagPrefix is prepended to the tags of the fallback store
+	// if they are merged into the tags of a search result of the primary store
+	fallbackTagPrefix = "fallback."
+	// fallbackKubeconfigConcurrency is the maximum number of kubeconfigs retrieved concurrently from a store during the search
+	fallbackKubeconfigConcurrency = 10
+)
+
+// FallbackStore wraps a primary and a fallback kubeconfig store.
+// Kubeconfigs are retrieved from the primary store. If the primary store does not know the cluster
+// or does not respond within the fallback delay, the kubeconfig is retrieved from the fallback store instead.
+type FallbackStore struct {
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	Primary         KubeconfigStore
+	Fallback        KubeconfigStore
+	// FallbackDelay is the maximum duration to wait for the primary store.
+	// If nil, the primary store is awaited until it responds.
+	FallbackDelay *time.Duration
+
+	// kubeconfigs contains the kubeconfigs retrieved during the search to determine the context names.
+	// They are handed out once to avoid retrieving them again right after the search.
+	kubeconfigs      map[string][]byte
+	kubeconfigsMutex sync.Mutex
+}
+
+// fallbackSearchResult is a search result of the primary or fallback store
+// together with the context names of the kubeconfig
+type fallbackSearchResult struct {
+	SearchResult
+	contexts []string
+}
+
+// NewFallbackStore creates a new fallback store.
+// The primary and fallback stores are created from the store configuration with the given function.
+func NewFallbackStore(kubeconfigStore types.KubeconfigStore, newStore func(types.KubeconfigStore) (KubeconfigStore, error)) (*FallbackStore, error) {
+	storeConfig := &types.StoreConfigFallback{}
+	if kubeconfigStore.Config != nil {
+		buf, err := yaml.Marshal(kubeconfigStore.Config)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, err)
+		}
+
+		if err := yaml.Unmarshal(buf, storeConfig); err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to unmarshal fallback store config: %w", err))
+		}
+	}
+
+	if len(storeConfig.Primary.Kind) == 0 || len(storeConfig.Fallback.Kind) == 0 {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("the fallback store requires a store in \"config.primary\" and \"config.fallback\""))
+	}
+
+	primary, err := newStore(storeConfig.Primary)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create primary store of kind %q: %w", storeConfig.Primary.Kind, err)
+	}
+
+	fallback, err := newStore(storeConfig.Fallback)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create fallback store of kind %q: %w", storeConfig.Fallback.Kind, err)
+	}
+
+	return &FallbackStore{
+		Logger:          logrus.New().WithField("store", types.StoreKindFallback),
+		KubeconfigStore: kubeconfigStore,
+		Primary:         primary,
+		Fallback:        fallback,
+		FallbackDelay:   storeConfig.FallbackDelay,
+		kubeconfigs:     map[string][]byte{},
+	}, nil
+}
+
+// GetID returns the unique store ID.
+// If no ID is configured, a deterministic hash of the IDs of the primary and fallback store is used.
+func (s *FallbackStore) GetID() string {
+	return fallbackStoreID(s.KubeconfigStore, s.Primary.GetID(), s.Fallback.GetID())
+}
+
+func (s *FallbackStore) GetKind() types.StoreKind {
+	return types.StoreKindFallback
+}
+
+func (s *FallbackStore) GetStoreConfig() types.KubeconfigStore {
+	return s.KubeconfigStore
+}
+
+func (s *FallbackStore) GetLogger() *logrus.Entry {
+	return s.Logger
+}
+
+// Stop stops the primary and the fallback store
+func (s *FallbackStore) Stop(ctx context.Context) error {
+	return StopAll(ctx, []KubeconfigStore{s.Primary, s.Fallback})
+}
+
+// GetContextPrefix returns the same prefix for all kubeconfig paths,
+// so that a context has the same name regardless of the store it is retrieved from
+func (s *FallbackStore) GetContextPrefix(_ string) string {
+	if s.KubeconfigStore.ShowPrefix != nil && !*s.KubeconfigStore.ShowPrefix {
+		return ""
+	}
+
+	if s.KubeconfigStore.ID != nil {
+		return *s.KubeconfigStore.ID
+	}
+	return string(types.StoreKindFallback)
+}
+
+// VerifyKubeconfigPaths verifies the search paths of the primary and fallback store
+// Errors of stores that are not required are ignored
+func (s *FallbackStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	for _, child := range []KubeconfigStore{s.Primary, s.Fallback} {
+		if err := child.VerifyKubeconfigPaths(ctx); err != nil {
+			if child.GetStoreConfig().Required != nil && !*child.GetStoreConfig().Required {
+				s.Logger.Debugf("ignoring error of store %q: %v", child.GetID(), err)
+				continue
+			}
+			return fmt.Errorf("store %q: %w", child.GetID(), err)
+		}
+	}
+	return nil
+}
+
+// Probe probes the primary and fallback store
+// Errors of stores that are not required are ignored
+func (s *FallbackStore) Probe(ctx context.Context) error {
+	for _, child := range []KubeconfigStore{s.Primary, s.Fallback} {
+		if err := child.Probe(ctx); err != nil {
+			if child.GetStoreConfig().Required != nil && !*child.GetStoreConfig().Required {
+				s.Logger.Debugf("ignoring error of store %q: %v", child.GetID(), err)
+				continue
+			}
+			return fmt.Errorf("store %q: %w", child.GetID(), err)
+		}
+	}
+	return nil
+}
+
+// StartSearch searches the primary and fallback store concurrently and merges the results.
+// Results of the primary store override results of the fallback store with the same context names.
+// The paths of overridden results are kept in the path of the primary result to fall back to them later.
+func (s *FallbackStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	var (
+		wg              sync.WaitGroup
+		primaryResults  []fallbackSearchResult
+		fallbackResults []fallbackSearchResult
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		primaryResults = s.searchChild(ctx, s.Primary, fallbackPrimaryPathPrefix, channel)
+	}()
+	go func() {
+		defer wg.Done()
+		fallbackResults = s.searchChild(ctx, s.Fallback, fallbackFallbackPathPrefix, channel)
+	}()
+	wg.Wait()
+
+	primaryIndexByContext := map[string]int{}
+	for i, result := range primaryResults {
+		for _, name := range result.contexts {
+			if _, ok := primaryIndexByContext[name]; !ok {
+				primaryIndexByContext[name] = i
+			}
+		}
+	}
+
+	fallbackPaths := make([]string, len(primaryResults))
+	fallbackTags := make([]map[string]string, len(primaryResults))
+	var uncoveredResults []fallbackSearchResult
+	for _, result := range fallbackResults {
+		covered := len(result.contexts) > 0
+		for _, name := range result.contexts {
+			i, ok := primaryIndexByContext[name]
+			if !ok {
+				covered = false
+				continue
+			}
+
+			if len(fallbackPaths[i]) == 0 {
+				fallbackPaths[i] = result.KubeconfigPath
+				fallbackTags[i] = result.Tags
+			}
+		}
+
+		if !covered {
+			uncoveredResults = append(uncoveredResults, result)
+		}
+	}
+
+	for i, result := range primaryResults {
+		searchResult := SearchResult{
+			KubeconfigPath: fallbackPrimaryPathPrefix + result.KubeconfigPath,
+			Tags:           result.Tags,
+		}
+
+		if len(fallbackPaths[i]) > 0 {
+			searchResult.KubeconfigPath += fallbackPathSeparator + fallbackPaths[i]
+			searchResult.Tags = mergeFallbackTags(result.Tags, fallbackTags[i])
+		}
+
+		if !sendSearchResult(ctx, channel, searchResult) {
+			return
+		}
+	}
+
+	for _, result := range uncoveredResults {
+		searchResult := SearchResult{
+			KubeconfigPath: fallbackFallbackPathPrefix + result.KubeconfigPath,
+			Tags:           result.Tags,
+		}
+		if !sendSearchResult(ctx, channel, searchResult) {
+			return
+		}
+	}
+}
+
+// searchChild collects the search results of the given store and retrieves their kubeconfigs concurrently to determine the context names.
+// The retrieved kubeconfigs are kept to hand them out once by GetKubeconfigForPath.
+// Search errors are sent to the channel immediately.
+func (s *FallbackStore) searchChild(ctx context.Context, child KubeconfigStore, pathPrefix string, channel chan SearchResult) []fallbackSearchResult {
+	childChannel := make(chan SearchResult)
+	go func() {
+		defer close(childChannel)
+		child.StartSearch(ctx, childChannel)
+	}()
+
+	var (
+		wg        sync.WaitGroup
+		semaphore = make(chan struct{}, fallbackKubeconfigConcurrency)
+		lock      sync.Mutex
+		// found maps the index of the search result to the search result, so that the order of the search is kept
+		found = map[int]fallbackSearchResult{}
+		count int
+	)
+
+	for result := range childChannel {
+		if result.Error != nil {
+			if child.GetStoreConfig().Required != nil && !*child.GetStoreConfig().Required {
+				s.Logger.Debugf("ignoring search error of store %q: %v", child.GetID(), result.Error)
+				continue
+			}
+
+			if !sendSearchResult(ctx, channel, SearchResult{Error: fmt.Errorf("store %q: %w", child.GetID(), result.Error)}) {
+				// the store aborts its search as well, but might still send results
+				for range childChannel {
+				}
+				wg.Wait()
+				return nil
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, result SearchResult) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			kubeconfig, err := child.GetKubeconfigForPath(ctx, result.KubeconfigPath, result.Tags)
+			if err != nil {
+				s.Logger.Debugf("skipping kubeconfig path %q of store %q: %v", result.KubeconfigPath, child.GetID(), err)
+				return
+			}
+
+			_, contexts, err := util.GetContextsNamesFromKubeconfig(kubeconfig, "")
+			if err != nil {
+				s.Logger.Debugf("skipping kubeconfig path %q of store %q: %v", result.KubeconfigPath, child.GetID(), err)
+				return
+			}
+
+			s.kubeconfigsMutex.Lock()
+			s.kubeconfigs[pathPrefix+result.KubeconfigPath] = kubeconfig
+			s.kubeconfigsMutex.Unlock()
+
+			lock.Lock()
+			found[i] = fallbackSearchResult{SearchResult: result, contexts: contexts}
+			lock.Unlock()
+		}(count, result)
+		count++
+	}
+	wg.Wait()
+
+	results := make([]fallbackSearchResult, 0, len(found))
+	for i := 0; i < count; i++ {
+		if result, ok := found[i]; ok {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// GetKubeconfigForPath retrieves the kubeconfig from the primary store.
+// If the primary store does not know the cluster or does not respond within the fallback delay,
+// the kubeconfig is retrieved from the fallback store.
+func (s *FallbackStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	// paths only discovered by the fallback store carry the unmodified tags of the fallback store
+	if fallbackPath, ok := strings.CutPrefix(path, fallbackFallbackPathPrefix); ok {
+		return s.getChildKubeconfig(ctx, s.Fallback, fallbackFallbackPathPrefix, fallbackPath, tags)
+	}
+
+	primaryPath, ok := strings.CutPrefix(path, fallbackPrimaryPathPrefix)
+	if !ok {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("kubeconfig path %q does not belong to the primary or fallback store", path)}
+	}
+
+	primaryPath, fallbackPath, hasFallback := strings.Cut(primaryPath, fallbackPathSeparator)
+	if !hasFallback {
+		return s.getChildKubeconfig(ctx, s.Primary, fallbackPrimaryPathPrefix, primaryPath, tags)
+	}
+
+	primaryTags, fallbackTags := splitFallbackTags(tags)
+	kubeconfig, err := s.getPrimaryKubeconfig(ctx, primaryPath, primaryTags)
+	if err == nil {
+		return kubeconfig, nil
+	}
+
+	var (
+		clusterNotFound    *storeerrors.ErrClusterNotFound
+		kubeconfigNotFound *storeerrors.ErrKubeconfigNotFound
+	)
+	if !errors.As(err, &clusterNotFound) && !errors.As(err, &kubeconfigNotFound) && !errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+
+	s.Logger.Debugf("using fallback store for kubeconfig path %q: %v", primaryPath, err)
+	return s.getChildKubeconfig(ctx, s.Fallback, fallbackFallbackPathPrefix, fallbackPath, fallbackTags)
+}
+
+// getPrimaryKubeconfig retrieves the kubeconfig from the primary store
+// and gives up once the fallback delay has passed
+func (s *FallbackStore) getPrimaryKubeconfig(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	if s.FallbackDelay == nil {
+		return s.getChildKubeconfig(ctx, s.Primary, fallbackPrimaryPathPrefix, path, tags)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *s.FallbackDelay)
+	defer cancel()
+
+	type response struct {
+		kubeconfig []byte
+		err        error
+	}
+
+	// not every store respects the context, hence do not wait for the primary store to return
+	responses := make(chan response, 1)
+	go func() {
+		kubeconfig, err := s.getChildKubeconfig(ctx, s.Primary, fallbackPrimaryPathPrefix, path, tags)
+		responses <- response{kubeconfig: kubeconfig, err: err}
+	}()
+
+	select {
+	case r := <-responses:
+		return r.kubeconfig, r.err
+	case <-ctx.Done():
+		return nil, &storeerrors.ErrStoreTimeout{StoreID: s.Primary.GetID(), Err: fmt.Errorf("primary store did not respond within %s: %w", *s.FallbackDelay, ctx.Err())}
+	}
+}
+
+// getChildKubeconfig returns the kubeconfig retrieved during the search or retrieves it from the given store
+func (s *FallbackStore) getChildKubeconfig(ctx context.Context, child KubeconfigStore, pathPrefix, path string, tags map[string]string) ([]byte, error) {
+	s.kubeconfigsMutex.Lock()
+	kubeconfig, ok := s.kubeconfigs[pathPrefix+path]
+	delete(s.kubeconfigs, pathPrefix+path)
+	s.kubeconfigsMutex.Unlock()
+
+	if ok {
+		return kubeconfig, nil
+	}
+	return child.GetKubeconfigForPath(ctx, path, tags)
+}
+
+// GetSearchPreview delegates to the primary store, or the fallback store for paths only discovered by the fallback store
+func (s *FallbackStore) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
+	child, childPath, tags := s.Fallback, strings.TrimPrefix(path, fallbackFallbackPathPrefix), optionalTags
+	if primaryPath, ok := strings.CutPrefix(path, fallbackPrimaryPathPrefix); ok {
+		primaryPath, _, _ = strings.Cut(primaryPath, fallbackPathSeparator)
+		child, childPath = s.Primary, primaryPath
+		tags, _ = splitFallbackTags(optionalTags)
+	}
+
+	previewer, ok := child.(Previewer)
+	if !ok {
+		return "", nil
+	}
+	return previewer.GetSearchPreview(childPath, tags)
+}
+
+// mergeFallbackTags adds the tags of the fallback store with a prefix to the tags of the primary store
+func mergeFallbackTags(primaryTags, fallbackTags map[string]string) map[string]string {
+	if len(fallbackTags) == 0 {
+		return primaryTags
+	}
+
+	tags := make(map[string]string, len(primaryTags)+len(fallbackTags))
+	for key, value := range primaryTags {
+		tags[key] = value
+	}
+	for key, value := range fallbackTags {
+		tags[fallbackTagPrefix+key] = value
+	}
+	return tags
+}
+
+// splitFallbackTags splits merged tags into the tags of the primary and the fallback store
+func splitFallbackTags(tags map[string]string) (map[string]string, map[string]string) {
+	var primaryTags, fallbackTags map[string]string
+	for key, value := range tags {
+		if fallbackKey, ok := strings.CutPrefix(key, fallbackTagPrefix); ok {
+			if fallbackTags == nil {
+				fallbackTags = map[string]string{}
+			}
+			fallbackTags[fallbackKey] = value
+			continue
+		}
+
+		if primaryTags == nil {
+			primaryTags = map[string]string{}
+		}
+		primaryTags[key] = value
+	}
+	return primaryTags, fallbackTags
+}
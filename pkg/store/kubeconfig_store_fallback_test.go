@@ -0,0 +1,246 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// fakeStore serves the kubeconfigs of a map with a configurable delay
+type fakeStore struct {
+	id          string
+	kubeconfigs map[string][]byte
+	delay       time.Duration
+	// lock guards the request counters, as kubeconfigs are retrieved concurrently
+	lock        sync.Mutex
+	requests    int
+	inFlight    int
+	maxInFlight int
+	stops       int
+	stopDelay   time.Duration
+	stopErr     error
+}
+
+func (f *fakeStore) GetID() string                               { return f.id }
+func (f *fakeStore) GetKind() types.StoreKind                    { return types.StoreKindFilesystem }
+func (f *fakeStore) GetContextPrefix(string) string              { return "" }
+func (f *fakeStore) VerifyKubeconfigPaths(context.Context) error { return nil }
+func (f *fakeStore) Probe(context.Context) error                 { return nil }
+func (f *fakeStore) GetLogger() *logrus.Entry                    { return testutil.NewTestLogger() }
+func (f *fakeStore) GetStoreConfig() types.KubeconfigStore       { return types.KubeconfigStore{} }
+
+func (f *fakeStore) Stop(ctx context.Context) error {
+	f.stops++
+	if f.stopDelay > 0 {
+		select {
+		case <-time.After(f.stopDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return f.stopErr
+}
+
+func (f *fakeStore) StartSearch(_ context.Context, channel chan store.SearchResult) {
+	for path := range f.kubeconfigs {
+		channel <- store.SearchResult{KubeconfigPath: path, Tags: map[string]string{"store": f.id}}
+	}
+}
+
+func (f *fakeStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	f.lock.Lock()
+	f.requests++
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	f.lock.Unlock()
+	defer func() {
+		f.lock.Lock()
+		f.inFlight--
+		f.lock.Unlock()
+	}()
+
+	if tags["store"] != f.id {
+		return nil, fmt.Errorf("unexpected tags %v", tags)
+	}
+
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	kubeconfig, ok := f.kubeconfigs[path]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", store.ErrClusterNotFound, path)
+	}
+	return kubeconfig, nil
+}
+
+func fakeKubeconfig(user string, contexts ...string) []byte {
+	kubeconfig := "apiVersion: v1\nkind: Config\ncontexts:\n"
+	for _, name := range contexts {
+		kubeconfig += fmt.Sprintf("- name: %s\n  context:\n    cluster: %s\n    user: %s\n", name, name, user)
+	}
+	return []byte(kubeconfig)
+}
+
+var _ = Describe("FallbackStore", func() {
+	var (
+		primary, fallback *fakeStore
+		fallbackDelay     *time.Duration
+	)
+
+	BeforeEach(func() {
+		primary = &fakeStore{id: "primary", kubeconfigs: map[string][]byte{
+			"a": fakeKubeconfig("primary", "dev", "staging"),
+		}}
+		fallback = &fakeStore{id: "fallback", kubeconfigs: map[string][]byte{
+			"x": fakeKubeconfig("fallback", "dev"),
+			"y": fakeKubeconfig("fallback", "prod"),
+		}}
+		fallbackDelay = nil
+	})
+
+	newFallbackStore := func() *store.FallbackStore {
+		config := map[string]interface{}{
+			"primary":  map[string]interface{}{"kind": "filesystem"},
+			"fallback": map[string]interface{}{"kind": "filesystem"},
+		}
+		if fallbackDelay != nil {
+			config["fallbackDelay"] = fallbackDelay.String()
+		}
+
+		children := []*fakeStore{primary, fallback}
+		s, err := store.NewFallbackStore(types.KubeconfigStore{Kind: types.StoreKindFallback, Config: config}, func(types.KubeconfigStore) (store.KubeconfigStore, error) {
+			child := children[0]
+			children = children[1:]
+			return child, nil
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return s
+	}
+
+	It("should require a primary and a fallback store", func() {
+		_, err := store.NewFallbackStore(types.KubeconfigStore{Kind: types.StoreKindFallback, Config: map[string]interface{}{
+			"primary": map[string]interface{}{"kind": "filesystem"},
+		}}, nil)
+		Expect(err).To(MatchError(ContainSubstring("requires a store")))
+	})
+
+	It("should override fallback results with primary results for the same context name", func() {
+		s := newFallbackStore()
+		Expect(s.FallbackDelay).To(BeNil())
+
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(ConsistOf(
+			store.SearchResult{KubeconfigPath: "primary::a::fallback::x", Tags: map[string]string{"store": "primary", "fallback.store": "fallback"}},
+			store.SearchResult{KubeconfigPath: "fallback::y", Tags: map[string]string{"store": "fallback"}},
+		))
+		Expect(s.GetContextPrefix("primary::a::fallback::x")).To(Equal(s.GetContextPrefix("fallback::y")))
+
+		// the kubeconfigs retrieved during the search are reused once
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "fallback::y", map[string]string{"store": "fallback"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeconfig).To(Equal(fallback.kubeconfigs["y"]))
+		Expect(fallback.requests).To(Equal(2))
+	})
+
+	It("should reuse the kubeconfigs of merged search results retrieved during the search", func() {
+		s := newFallbackStore()
+		_, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(primary.requests).To(Equal(1))
+
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "primary::a::fallback::x", map[string]string{"store": "primary", "fallback.store": "fallback"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeconfig).To(Equal(primary.kubeconfigs["a"]))
+		Expect(primary.requests).To(Equal(1))
+
+		// handed out once only, so that changes of the kubeconfig are picked up
+		_, err = s.GetKubeconfigForPath(context.Background(), "primary::a::fallback::x", map[string]string{"store": "primary", "fallback.store": "fallback"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(primary.requests).To(Equal(2))
+	})
+
+	It("should retrieve the kubeconfigs of a store concurrently during the search", func() {
+		for i := 0; i < 20; i++ {
+			fallback.kubeconfigs[fmt.Sprintf("cluster-%d", i)] = fakeKubeconfig("fallback", fmt.Sprintf("cluster-%d", i))
+		}
+		fallback.delay = 50 * time.Millisecond
+		s := newFallbackStore()
+
+		start := time.Now()
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(HaveLen(22))
+		// 22 kubeconfigs retrieved sequentially would take more than a second
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(fallback.maxInFlight).To(BeNumerically(">", 1))
+		Expect(fallback.maxInFlight).To(BeNumerically("<=", 10))
+	})
+
+	It("should retrieve the kubeconfig from the primary store", func() {
+		s := newFallbackStore()
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "primary::a::fallback::x", map[string]string{"store": "primary", "fallback.store": "fallback"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeconfig).To(Equal(primary.kubeconfigs["a"]))
+		Expect(fallback.requests).To(BeZero())
+	})
+
+	It("should fall back if the primary store does not know the cluster", func() {
+		s := newFallbackStore()
+		delete(primary.kubeconfigs, "a")
+
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "primary::a::fallback::x", map[string]string{"store": "primary", "fallback.store": "fallback"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeconfig).To(Equal(fallback.kubeconfigs["x"]))
+	})
+
+	It("should fall back if the primary store does not respond within the fallback delay", func() {
+		delay := 10 * time.Millisecond
+		fallbackDelay = &delay
+		s := newFallbackStore()
+		Expect(*s.FallbackDelay).To(Equal(delay))
+		primary.delay = time.Minute
+
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "primary::a::fallback::x", map[string]string{"store": "primary", "fallback.store": "fallback"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeconfig).To(Equal(fallback.kubeconfigs["x"]))
+	})
+
+	It("should not fall back on other errors or without a fallback path", func() {
+		s := newFallbackStore()
+		delete(primary.kubeconfigs, "a")
+
+		_, err := s.GetKubeconfigForPath(context.Background(), "primary::a", map[string]string{"store": "primary"})
+		Expect(err).To(MatchError(store.ErrClusterNotFound))
+
+		_, err = s.GetKubeconfigForPath(context.Background(), "primary::a::fallback::x", map[string]string{"store": "other"})
+		Expect(err).To(MatchError(ContainSubstring("unexpected tags")))
+		Expect(fallback.requests).To(BeZero())
+	})
+})
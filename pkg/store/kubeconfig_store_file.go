@@ -15,7 +15,10 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -24,7 +27,13 @@ import (
 
 	"github.com/karrick/godirwalk"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
 
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/keychain"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
 	"github.com/danielfoehrkn/kubeswitch/types"
 )
 
@@ -32,10 +41,29 @@ func NewFilesystemStore(
 	kubeconfigName string,
 	kubeconfigStore types.KubeconfigStore,
 ) (*FilesystemStore, error) {
+	filesystemStoreConfig := &types.StoreConfigFilesystem{}
+	if kubeconfigStore.Config != nil {
+		buf, err := yaml.Marshal(kubeconfigStore.Config)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, err)
+		}
+
+		if err := yaml.Unmarshal(buf, filesystemStoreConfig); err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to unmarshal filesystem config: %w", err))
+		}
+	}
+
+	expandKubeconfigEnv := true
+	if filesystemStoreConfig.ExpandKubeconfigEnv != nil {
+		expandKubeconfigEnv = *filesystemStoreConfig.ExpandKubeconfigEnv
+	}
+
 	return &FilesystemStore{
-		Logger:          logrus.New().WithField("store", types.StoreKindFilesystem),
-		KubeconfigStore: kubeconfigStore,
-		KubeconfigName:  kubeconfigName,
+		Logger:              logrus.New().WithField("store", types.StoreKindFilesystem),
+		KubeconfigStore:     kubeconfigStore,
+		KubeconfigName:      kubeconfigName,
+		ExpandKubeconfigEnv: expandKubeconfigEnv,
+		KeychainBackend:     filesystemStoreConfig.KeychainBackend,
 	}, nil
 }
 
@@ -68,7 +96,15 @@ func (s *FilesystemStore) GetLogger() *logrus.Entry {
 	return s.Logger
 }
 
-func (s *FilesystemStore) StartSearch(channel chan SearchResult) {
+// Stop releases the kubeconfig paths found by VerifyKubeconfigPaths.
+// The filesystem store holds no connections or background goroutines.
+func (s *FilesystemStore) Stop(_ context.Context) error {
+	s.kubeconfigDirectories = nil
+	s.kubeconfigFilepaths = nil
+	return nil
+}
+
+func (s *FilesystemStore) StartSearch(ctx context.Context, channel chan SearchResult) {
 	for _, path := range s.kubeconfigFilepaths {
 		channel <- SearchResult{
 			KubeconfigPath: path,
@@ -79,12 +115,13 @@ func (s *FilesystemStore) StartSearch(channel chan SearchResult) {
 	wg := sync.WaitGroup{}
 	for _, path := range s.kubeconfigDirectories {
 		wg.Add(1)
-		go s.searchDirectory(&wg, path, channel)
+		go s.searchDirectory(ctx, &wg, path, channel)
 	}
 	wg.Wait()
 }
 
 func (s *FilesystemStore) searchDirectory(
+	ctx context.Context,
 	wg *sync.WaitGroup,
 	searchPath string,
 	channel chan SearchResult,
@@ -93,6 +130,11 @@ func (s *FilesystemStore) searchDirectory(
 
 	if err := godirwalk.Walk(searchPath, &godirwalk.Options{
 		Callback: func(osPathname string, _ *godirwalk.Dirent) error {
+			// aborts the walk
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
 			fileName := filepath.Base(osPathname)
 			matched, err := filepath.Match(s.KubeconfigName, fileName)
 			if err != nil {
@@ -108,7 +150,7 @@ func (s *FilesystemStore) searchDirectory(
 		},
 		Unsorted:            false, // (optional) set true for faster yet non-deterministic enumeration
 		FollowSymbolicLinks: true,
-	}); err != nil {
+	}); err != nil && ctx.Err() == nil {
 		channel <- SearchResult{
 			KubeconfigPath: "",
 			Error:          fmt.Errorf("failed to find kubeconfig files in directory: %v", err),
@@ -116,11 +158,18 @@ func (s *FilesystemStore) searchDirectory(
 	}
 }
 
-func (s *FilesystemStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byte, error) {
-	return os.ReadFile(path)
+func (s *FilesystemStore) GetKubeconfigForPath(ctx context.Context, path string, _ map[string]string) ([]byte, error) {
+	kubeconfig, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: err}
+	}
+	if err != nil || !s.KeychainBackend {
+		return kubeconfig, err
+	}
+	return keychain.ResolveTokens(kubeconfig)
 }
 
-func (s *FilesystemStore) VerifyKubeconfigPaths() error {
+func (s *FilesystemStore) VerifyKubeconfigPaths(ctx context.Context) error {
 	var (
 		duplicatePath              = make(map[string]*struct{})
 		validKubeconfigFilepaths   []string
@@ -129,14 +178,14 @@ func (s *FilesystemStore) VerifyKubeconfigPaths() error {
 		homeDir                    = usr.HomeDir
 	)
 
-	for _, path := range s.KubeconfigStore.Paths {
+	for _, path := range s.getPaths() {
 		// do not add duplicate paths
-		if duplicatePath[path] != nil {
+		if duplicatePath[path.path] != nil {
 			continue
 		}
-		duplicatePath[path] = &struct{}{}
+		duplicatePath[path.path] = &struct{}{}
 
-		kubeconfigPath := path
+		kubeconfigPath := path.path
 		if kubeconfigPath == "~" {
 			kubeconfigPath = homeDir
 		} else if strings.HasPrefix(kubeconfigPath, "~/") {
@@ -147,9 +196,13 @@ func (s *FilesystemStore) VerifyKubeconfigPaths() error {
 
 		info, err := os.Stat(kubeconfigPath)
 		if os.IsNotExist(err) {
+			if path.fromEnv {
+				// same as kubectl, do not fail on non-existing files in the KUBECONFIG environment variable
+				s.Logger.Warnf("kubeconfig path %q does not exist", kubeconfigPath)
+			}
 			continue
 		} else if err != nil {
-			return fmt.Errorf("failed to read from the configured kubeconfig directory %q: %v", path, err)
+			return fmt.Errorf("failed to read from the configured kubeconfig directory %q: %v", path.path, err)
 		}
 
 		if info.IsDir() {
@@ -160,12 +213,172 @@ func (s *FilesystemStore) VerifyKubeconfigPaths() error {
 	}
 
 	if len(validKubeconfigDirectories) == 0 && len(validKubeconfigFilepaths) == 0 {
-		return fmt.Errorf(
+		return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: fmt.Errorf(
 			"none of the %d specified kubeconfig path(s) exist. Either specifiy an existing path via flag '--kubeconfig-path' or in the switch config file",
 			len(s.KubeconfigStore.Paths),
-		)
+		)}
 	}
 	s.kubeconfigDirectories = validKubeconfigDirectories
 	s.kubeconfigFilepaths = validKubeconfigFilepaths
 	return nil
 }
+
+// Probe checks that at least one of the configured kubeconfig paths exists
+func (s *FilesystemStore) Probe(ctx context.Context) error {
+	usr, err := user.Current()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range s.getPaths() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		kubeconfigPath := path.path
+		if kubeconfigPath == "~" {
+			kubeconfigPath = usr.HomeDir
+		} else if strings.HasPrefix(kubeconfigPath, "~/") {
+			kubeconfigPath = filepath.Join(usr.HomeDir, kubeconfigPath[2:])
+		}
+
+		_, err := os.Stat(kubeconfigPath)
+		if err == nil {
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read from the configured kubeconfig path %q: %v", path.path, err)
+		}
+	}
+	return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: fmt.Errorf("none of the configured kubeconfig paths exist")}
+}
+
+// GetDefaultOutputDirectory returns the directory new kubeconfig files of the store are written to.
+// This is the first configured kubeconfig directory, so that the written kubeconfig files are found by the search.
+func (s *FilesystemStore) GetDefaultOutputDirectory() (string, error) {
+	if len(s.kubeconfigDirectories) == 0 && len(s.kubeconfigFilepaths) == 0 {
+		if err := s.VerifyKubeconfigPaths(context.Background()); err != nil {
+			return "", err
+		}
+	}
+
+	if len(s.kubeconfigDirectories) == 0 {
+		return "", &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: fmt.Errorf("the store does not have a kubeconfig directory configured, only kubeconfig files")}
+	}
+	return s.kubeconfigDirectories[0], nil
+}
+
+// GetKubeconfigPathForContext returns the path of the kubeconfig written for the context with the given name.
+// Every kubeconfig is written to its own directory in the default output directory of the store,
+// as the store only finds kubeconfig files with the configured name.
+func (s *FilesystemStore) GetKubeconfigPathForContext(contextName string) (string, error) {
+	if strings.ContainsAny(s.KubeconfigName, "*?[") {
+		return "", fmt.Errorf("cannot derive the kubeconfig file name from the pattern %q of the filesystem store %q", s.KubeconfigName, s.GetID())
+	}
+
+	directory, err := s.GetDefaultOutputDirectory()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, util.SanitizeContextName(contextName), s.KubeconfigName), nil
+}
+
+// WriteKubeconfig writes the kubeconfig to the path returned by GetKubeconfigPathForContext.
+// Fails if a kubeconfig already exists at this path.
+func (s *FilesystemStore) WriteKubeconfig(_ context.Context, contextName string, kubeconfig []byte) (string, error) {
+	path, err := s.GetKubeconfigPathForContext(contextName)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("kubeconfig %q already exists", path)
+	}
+
+	if err := kubeswitchio.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := kubeswitchio.WriteFile(path, kubeconfig, 0600); err != nil {
+		return "", fmt.Errorf("failed to write kubeconfig to %q: %w", path, err)
+	}
+	return path, nil
+}
+
+// DeleteContext removes the context together with its cluster and user, unless they are referenced by other contexts.
+// The kubeconfig file is removed if it does not contain any other context.
+// The kubeconfig is not loaded with clientcmd.LoadFromFile, as it resolves relative file paths in the kubeconfig
+// which would then be written back as absolute paths.
+func (s *FilesystemStore) DeleteContext(_ context.Context, path, contextName string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read kubeconfig %q: %w", path, err)
+	}
+
+	kubeconfig, err := clientcmd.Load(data)
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig %q: %w", path, err)
+	}
+
+	context, ok := kubeconfig.Contexts[contextName]
+	if !ok {
+		return fmt.Errorf("context %q not found in kubeconfig %q", contextName, path)
+	}
+	delete(kubeconfig.Contexts, contextName)
+
+	if len(kubeconfig.Contexts) == 0 {
+		return kubeswitchio.Remove(path)
+	}
+
+	clusterInUse, userInUse := false, false
+	for _, other := range kubeconfig.Contexts {
+		clusterInUse = clusterInUse || other.Cluster == context.Cluster
+		userInUse = userInUse || other.AuthInfo == context.AuthInfo
+	}
+	if !clusterInUse {
+		delete(kubeconfig.Clusters, context.Cluster)
+	}
+	if !userInUse {
+		delete(kubeconfig.AuthInfos, context.AuthInfo)
+	}
+	if kubeconfig.CurrentContext == contextName {
+		kubeconfig.CurrentContext = ""
+	}
+
+	data, err = clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(path, data, 0600)
+}
+
+type filesystemPath struct {
+	path string
+	// fromEnv is true if the path has been obtained by expanding an environment variable
+	fromEnv bool
+}
+
+// getPaths returns the configured paths.
+// If enabled, environment variables are expanded and path lists such as the KUBECONFIG environment variable
+// are split into the individual paths (separated by ":" or ";" on Windows).
+func (s *FilesystemStore) getPaths() []filesystemPath {
+	var paths []filesystemPath
+	for _, path := range s.KubeconfigStore.Paths {
+		if !s.ExpandKubeconfigEnv {
+			paths = append(paths, filesystemPath{path: path})
+			continue
+		}
+
+		if !strings.Contains(path, "$") {
+			paths = append(paths, filesystemPath{path: util.ResolveKubeconfigPath(path)})
+			continue
+		}
+
+		for _, expandedPath := range filepath.SplitList(os.ExpandEnv(path)) {
+			if len(expandedPath) == 0 {
+				continue
+			}
+			paths = append(paths, filesystemPath{path: util.ResolveKubeconfigPath(expandedPath), fromEnv: true})
+		}
+	}
+	return paths
+}
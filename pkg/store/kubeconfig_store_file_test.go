@@ -0,0 +1,190 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("FilesystemStore", func() {
+	var (
+		tempDir       string
+		kubeconfigEnv string
+		files         []string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-filesystem-store")
+		Expect(err).ToNot(HaveOccurred())
+
+		files = nil
+		for _, name := range []string{"a", "b", "c"} {
+			file := filepath.Join(tempDir, name)
+			Expect(os.WriteFile(file, []byte{}, 0600)).To(Succeed())
+			files = append(files, file)
+		}
+
+		kubeconfigEnv = os.Getenv("KUBECONFIG")
+		// includes a non-existing file which should be ignored
+		Expect(os.Setenv("KUBECONFIG", strings.Join(append(files, filepath.Join(tempDir, "missing")), string(os.PathListSeparator)))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Setenv("KUBECONFIG", kubeconfigEnv)).To(Succeed())
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	search := func(s *store.FilesystemStore) []string {
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+
+		var paths []string
+		for _, result := range results {
+			paths = append(paths, result.KubeconfigPath)
+		}
+		return paths
+	}
+
+	It("should discover every file of the KUBECONFIG path list", func() {
+		s, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{"$KUBECONFIG"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.VerifyKubeconfigPaths(context.Background())).To(Succeed())
+		Expect(search(s)).To(ConsistOf(files))
+	})
+
+	It("should not expand the KUBECONFIG path list if disabled", func() {
+		s, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:   types.StoreKindFilesystem,
+			Paths:  []string{"$KUBECONFIG"},
+			Config: map[string]interface{}{"expandKubeconfigEnv": false},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.VerifyKubeconfigPaths(context.Background())).ToNot(Succeed())
+	})
+	It("should probe the configured paths", func() {
+		s, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{"$KUBECONFIG"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Probe(context.Background())).To(Succeed())
+
+		s, err = store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{filepath.Join(tempDir, "missing")},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.Probe(context.Background())).ToNot(Succeed())
+	})
+
+	Context("writing and deleting contexts", func() {
+		const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: a
+  cluster:
+    server: https://a
+- name: b
+  cluster:
+    server: https://b
+users:
+- name: user
+  user:
+    token: token
+contexts:
+- name: a
+  context:
+    cluster: a
+    user: user
+- name: b
+  context:
+    cluster: b
+    user: user
+current-context: a
+`
+
+		var s *store.FilesystemStore
+
+		BeforeEach(func() {
+			var err error
+			s, err = store.NewFilesystemStore("config", types.KubeconfigStore{
+				Kind:  types.StoreKindFilesystem,
+				Paths: []string{tempDir},
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should write the kubeconfig to its own directory", func() {
+			path, err := s.WriteKubeconfig(context.Background(), "prod/a", []byte(kubeconfig))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(tempDir, "prod-a", "config")))
+
+			data, err := s.GetKubeconfigForPath(context.Background(), path, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(kubeconfig))
+
+			_, err = s.WriteKubeconfig(context.Background(), "prod/a", []byte(kubeconfig))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should only remove the context and its unused cluster", func() {
+			path := filepath.Join(tempDir, "a")
+			Expect(os.WriteFile(path, []byte(kubeconfig), 0600)).To(Succeed())
+
+			Expect(s.DeleteContext(context.Background(), path, "a")).To(Succeed())
+
+			remaining, err := clientcmd.LoadFromFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remaining.Contexts).To(HaveLen(1))
+			Expect(remaining.Contexts).To(HaveKey("b"))
+			Expect(remaining.Clusters).To(HaveLen(1))
+			Expect(remaining.AuthInfos).To(HaveKey("user"))
+			Expect(remaining.CurrentContext).To(BeEmpty())
+
+			Expect(s.DeleteContext(context.Background(), path, "b")).To(Succeed())
+			Expect(path).ToNot(BeAnExistingFile())
+		})
+
+		It("should keep relative file paths of the remaining contexts", func() {
+			path := filepath.Join(tempDir, "a")
+			withRelativePath := strings.Replace(kubeconfig, "server: https://b", "server: https://b\n    certificate-authority: ca.crt", 1)
+			Expect(os.WriteFile(path, []byte(withRelativePath), 0600)).To(Succeed())
+
+			Expect(s.DeleteContext(context.Background(), path, "a")).To(Succeed())
+
+			data, err := os.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			remaining, err := clientcmd.Load(data)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(remaining.Clusters).To(HaveKey("b"))
+			Expect(remaining.Clusters["b"].CertificateAuthority).To(Equal("ca.crt"))
+		})
+	})
+})
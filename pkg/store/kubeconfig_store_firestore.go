@@ -0,0 +1,268 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const (
+	firestoreRequestTimeout = 30 * time.Second
+	// firestoreFieldContextName is the document field identifying the kubeconfig
+	firestoreFieldContextName = "contextName"
+	// firestoreFieldKubeconfig is the document field containing the base64 encoded kubeconfig
+	firestoreFieldKubeconfig = "kubeconfigBase64"
+)
+
+// defaultFirestoreTagFields are the document fields added as tags if no tag fields are configured
+var defaultFirestoreTagFields = []string{"env", "region", "team"}
+
+func NewFirestoreStore(kubeconfigStore types.KubeconfigStore) (*FirestoreStore, error) {
+	firestoreStoreConfig := &types.StoreConfigFirestore{}
+	if kubeconfigStore.Config != nil {
+		buf, err := yaml.Marshal(kubeconfigStore.Config)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to process Firestore store config: %w", err))
+		}
+
+		err = yaml.Unmarshal(buf, firestoreStoreConfig)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to unmarshal Firestore config: %w", err))
+		}
+	}
+
+	if len(firestoreStoreConfig.ProjectID) == 0 {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("the projectID of the Firestore store must be configured"))
+	}
+
+	if len(firestoreStoreConfig.Collection) == 0 {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("the collection of the Firestore store must be configured"))
+	}
+
+	if len(firestoreStoreConfig.FilterField) == 0 && len(firestoreStoreConfig.FilterValue) > 0 {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("the filterField of the Firestore store must be configured when setting a filterValue"))
+	}
+
+	if firestoreStoreConfig.TagFields == nil {
+		firestoreStoreConfig.TagFields = defaultFirestoreTagFields
+	}
+
+	var opts []option.ClientOption
+	if len(firestoreStoreConfig.ServiceAccountFile) > 0 {
+		opts = append(opts, option.WithCredentialsFile(firestoreStoreConfig.ServiceAccountFile))
+	}
+
+	// the client connects lazily
+	client, err := firestore.NewClient(context.Background(), firestoreStoreConfig.ProjectID, opts...)
+	if err != nil {
+		return nil, &storeerrors.ErrAuthFailed{StoreID: storeIDForConfig(kubeconfigStore), Err: fmt.Errorf("failed to create Firestore client: %w", err)}
+	}
+
+	return &FirestoreStore{
+		Logger:          logrus.New().WithField("store", types.StoreKindFirestore),
+		KubeconfigStore: kubeconfigStore,
+		Client:          client,
+		Config:          firestoreStoreConfig,
+	}, nil
+}
+
+func (s *FirestoreStore) GetID() string {
+	id := "default"
+	if s.KubeconfigStore.ID != nil {
+		id = *s.KubeconfigStore.ID
+	}
+	return fmt.Sprintf("%s.%s", types.StoreKindFirestore, id)
+}
+
+func (s *FirestoreStore) GetKind() types.StoreKind {
+	return types.StoreKindFirestore
+}
+
+func (s *FirestoreStore) GetContextPrefix(p string) string {
+	if s.GetStoreConfig().ShowPrefix != nil && !*s.GetStoreConfig().ShowPrefix {
+		return ""
+	}
+
+	// the path is the context name of the document
+	return p
+}
+
+func (s *FirestoreStore) GetStoreConfig() types.KubeconfigStore {
+	return s.KubeconfigStore
+}
+
+func (s *FirestoreStore) GetLogger() *logrus.Entry {
+	return s.Logger
+}
+
+// Stop closes the connections of the Firestore client
+func (s *FirestoreStore) Stop(_ context.Context) error {
+	if s.Client == nil {
+		return nil
+	}
+	return s.stopper.Do(s.Client.Close)
+}
+
+func (s *FirestoreStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	// the documents are determined by the configured collection
+	return nil
+}
+
+// Probe checks that the collection can be queried
+func (s *FirestoreStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	query, err := s.query()
+	if err != nil {
+		return err
+	}
+
+	_, err = query.Select().Limit(1).Documents(ctx).Next()
+	if err != nil && !errors.Is(err, iterator.Done) {
+		return wrapFirestoreError(s.GetID(), fmt.Errorf("failed to query Firestore collection %q: %w", s.Config.Collection, err))
+	}
+	return nil
+}
+
+// query returns the query for all documents of the configured collection matching the configured filter
+func (s *FirestoreStore) query() (firestore.Query, error) {
+	collection := s.Client.Collection(s.Config.Collection)
+	if collection == nil {
+		return firestore.Query{}, &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: fmt.Errorf("%q is not a valid Firestore collection path", s.Config.Collection)}
+	}
+
+	query := collection.Query
+	if len(s.Config.FilterField) > 0 {
+		query = query.Where(s.Config.FilterField, "==", s.Config.FilterValue)
+	}
+	return query, nil
+}
+
+// StartSearch sends the context name of all documents in the collection.
+// The kubeconfig is not transferred during the search, only the context name and the tag fields.
+func (s *FirestoreStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	ctx, cancel := context.WithTimeout(ctx, firestoreRequestTimeout)
+	defer cancel()
+
+	query, err := s.query()
+	if err != nil {
+		channel <- SearchResult{
+			KubeconfigPath: "",
+			Error:          err,
+		}
+		return
+	}
+
+	fields := append([]string{firestoreFieldContextName}, s.Config.TagFields...)
+	documents := query.Select(fields...).Documents(ctx)
+	defer documents.Stop()
+
+	for {
+		document, err := documents.Next()
+		if errors.Is(err, iterator.Done) {
+			return
+		}
+		if err != nil {
+			channel <- SearchResult{
+				KubeconfigPath: "",
+				Error:          wrapFirestoreError(s.GetID(), fmt.Errorf("failed to query Firestore collection %q: %w", s.Config.Collection, err)),
+			}
+			return
+		}
+
+		data := document.Data()
+		contextName, ok := data[firestoreFieldContextName].(string)
+		if !ok || len(contextName) == 0 {
+			s.Logger.Debugf("skipping Firestore document %q without %q field", document.Ref.ID, firestoreFieldContextName)
+			continue
+		}
+
+		tags := map[string]string{}
+		for _, field := range s.Config.TagFields {
+			if value, ok := data[field]; ok && value != nil {
+				tags[field] = fmt.Sprint(value)
+			}
+		}
+
+		channel <- SearchResult{
+			KubeconfigPath: contextName,
+			Tags:           tags,
+			Error:          nil,
+		}
+	}
+}
+
+func (s *FirestoreStore) GetKubeconfigForPath(ctx context.Context, p string, _ map[string]string) ([]byte, error) {
+	s.Logger.Debugf("Firestore: get kubeconfig for context name %s", p)
+
+	ctx, cancel := context.WithTimeout(ctx, firestoreRequestTimeout)
+	defer cancel()
+
+	query, err := s.query()
+	if err != nil {
+		return nil, err
+	}
+
+	documents := query.Where(firestoreFieldContextName, "==", p).Limit(1).Documents(ctx)
+	defer documents.Stop()
+
+	document, err := documents.Next()
+	if errors.Is(err, iterator.Done) {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("no document with context name %q exists in Firestore collection %q", p, s.Config.Collection)}
+	}
+	if err != nil {
+		return nil, wrapFirestoreError(s.GetID(), fmt.Errorf("failed to get document with context name %q from Firestore: %w", p, err))
+	}
+
+	encoded, ok := document.Data()[firestoreFieldKubeconfig].(string)
+	if !ok {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("Firestore document %q does not contain the field %q", document.Ref.ID, firestoreFieldKubeconfig)}
+	}
+
+	kubeconfig, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode field %q of Firestore document %q: %w", firestoreFieldKubeconfig, document.Ref.ID, err)
+	}
+	return kubeconfig, nil
+}
+
+// wrapFirestoreError returns the typed error for a failed request against Firestore
+func wrapFirestoreError(storeID string, err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return &storeerrors.ErrKubeconfigNotFound{StoreID: storeID, Err: err}
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return &storeerrors.ErrAuthFailed{StoreID: storeID, Err: err}
+	case codes.DeadlineExceeded:
+		return &storeerrors.ErrStoreTimeout{StoreID: storeID, Err: err}
+	}
+	return wrapTimeout(storeID, err)
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"errors"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("FirestoreStore", func() {
+	BeforeEach(func() {
+		// avoids looking up the Application Default Credentials
+		Expect(os.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Unsetenv("FIRESTORE_EMULATOR_HOST")).To(Succeed())
+	})
+
+	newFirestoreStore := func(config map[string]interface{}) (*store.FirestoreStore, error) {
+		return store.NewFirestoreStore(types.KubeconfigStore{
+			Kind:   types.StoreKindFirestore,
+			Config: config,
+		})
+	}
+
+	It("should use the context name as context prefix", func() {
+		s, err := newFirestoreStore(map[string]interface{}{
+			"projectID":  "my-project",
+			"collection": "registry/production/clusters",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.GetID()).To(Equal("firestore.default"))
+		Expect(s.GetContextPrefix("prod-eu")).To(Equal("prod-eu"))
+		Expect(s.Config.TagFields).To(Equal([]string{"env", "region", "team"}))
+	})
+
+	It("should reject invalid configurations", func() {
+		for _, config := range []map[string]interface{}{
+			{"collection": "clusters"},
+			{"projectID": "my-project"},
+			{"projectID": "my-project", "collection": "clusters", "filterValue": "prod"},
+		} {
+			_, err := newFirestoreStore(config)
+			var invalidConfig *storeerrors.ErrInvalidConfig
+			Expect(errors.As(err, &invalidConfig)).To(BeTrue(), "config %v", config)
+		}
+	})
+})
@@ -16,6 +16,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -36,6 +37,8 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
 	gardenerstore "github.com/danielfoehrkn/kubeswitch/pkg/store/gardener"
 	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/alias/state"
 	"github.com/danielfoehrkn/kubeswitch/pkg/util"
@@ -53,6 +56,9 @@ const (
 	defaultGardenloginConfigPath = "$HOME/.garden/gardenlogin.yaml"
 )
 
+// gardenloginConfigLock serializes the updates of the Gardenlogin config by the landscapes searched in parallel
+var gardenloginConfigLock sync.Mutex
+
 // GardenloginConfig represents the config for the Gardenlogin-exec-provider that is
 // required to work with the kubeconfig files obtained from the GardenConfig cluster
 // If missing, this configuration is generated based on the Kubeswitch config
@@ -75,54 +81,101 @@ type GardenConfig struct {
 func NewGardenerStore(store types.KubeconfigStore, stateDir string) (*GardenerStore, error) {
 	config, err := gardenerstore.GetStoreConfig(store)
 	if err != nil {
-		return nil, err
+		return nil, invalidConfig(store, err)
+	}
+
+	landscapeConfigs := config.Landscapes
+	if len(landscapeConfigs) == 0 {
+		// a single landscape configured with the top-level fields of the store configuration
+		landscapeConfig := types.LandscapeConfig{
+			GardenletKubeconfigPath: config.GardenerAPIKubeconfigPath,
+		}
+		if config.LandscapeName != nil {
+			landscapeConfig.Name = *config.LandscapeName
+		}
+		landscapeConfigs = []types.LandscapeConfig{landscapeConfig}
 	}
 
-	var landscapeName string
-	if config != nil && config.LandscapeName != nil {
-		landscapeName = *config.LandscapeName
+	landscapes := make([]*GardenerLandscape, 0, len(landscapeConfigs))
+	for _, landscapeConfig := range landscapeConfigs {
+		landscapes = append(landscapes, &GardenerLandscape{
+			Config:            landscapeConfig,
+			LandscapeIdentity: landscapeConfig.Identity,
+			LandscapeName:     landscapeConfig.Name,
+		})
 	}
 
 	return &GardenerStore{
-		Logger:                   logrus.New().WithField("store", types.StoreKindGardener),
-		KubeconfigStore:          store,
-		Config:                   config,
-		LandscapeName:            landscapeName,
-		StateDirectory:           stateDir,
-		PathToShootLock:          sync.RWMutex{},
-		PathToManagedSeedLock:    sync.RWMutex{},
-		CaSecretNameToSecretLock: sync.RWMutex{},
+		Logger:          logrus.New().WithField("store", types.StoreKindGardener),
+		KubeconfigStore: store,
+		Config:          config,
+		StateDirectory:  stateDir,
+		Landscapes:      landscapes,
 	}, nil
 }
 
-// InitializeGardenerStore initializes the store using the provided Gardener kubeconfig
-// decoupled from the NewGardenerStore() to be called when starting the search to reduce
+// InitializeGardenerStore initializes all landscapes of the store that are not initialized yet.
+// Decoupled from the NewGardenerStore() to be called when starting the search to reduce
 // time when the CLI can start showing the fuzzy search
 func (s *GardenerStore) InitializeGardenerStore() error {
-	var err error
-	s.Client, err = gardenerstore.GetGardenClient(s.Config)
+	var errs []error
+	for _, landscape := range s.Landscapes {
+		if err := s.initializeLandscape(landscape); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// initializeLandscape initializes the landscape using the provided Gardener kubeconfig
+func (s *GardenerStore) initializeLandscape(landscape *GardenerLandscape) error {
+	landscape.InitLock.Lock()
+	defer landscape.InitLock.Unlock()
+
+	if landscape.IsInitialized() {
+		return nil
+	}
+
+	gardenClient, err := gardenerstore.GetGardenClient(landscape.Config.GardenletKubeconfigPath, transportWrapper())
 	if err != nil {
-		return err
+		return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: err}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	identity := landscape.Config.Identity
+	if len(identity) == 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
 
-	cm := &corev1.ConfigMap{}
-	if err := s.Client.Get(ctx, client.ObjectKey{Name: CmNameClusterIdentity, Namespace: metav1.NamespaceSystem}, cm); err != nil {
-		return fmt.Errorf("unable to get gardener landscape identity from config map %s/%s: %w", metav1.NamespaceSystem, CmNameClusterIdentity, err)
+		cm := &corev1.ConfigMap{}
+		if err := gardenClient.Get(ctx, client.ObjectKey{Name: CmNameClusterIdentity, Namespace: metav1.NamespaceSystem}, cm); err != nil {
+			return wrapKubernetesError(s.GetID(), fmt.Errorf("unable to get gardener landscape identity from config map %s/%s: %w", metav1.NamespaceSystem, CmNameClusterIdentity, err))
+		}
+
+		var ok bool
+		identity, ok = cm.Data[KeyClusterIdentity]
+		if !ok {
+			return fmt.Errorf("unable to get gardener landscape identity from config map %s/%s: data key %q not found", metav1.NamespaceSystem, CmNameClusterIdentity, KeyClusterIdentity)
+		}
 	}
 
-	identity, ok := cm.Data[KeyClusterIdentity]
-	if !ok {
-		return fmt.Errorf("unable to get gardener landscape identity from config map %s/%s: data key %q not found", metav1.NamespaceSystem, CmNameClusterIdentity, KeyClusterIdentity)
+	if err := ensureGardenloginConfig(identity, landscape.Config.GardenletKubeconfigPath); err != nil {
+		return err
 	}
-	s.LandscapeIdentity = identity
-	s.GardenClient = gardenclient.NewGardenClient(s.Client, s.LandscapeIdentity)
 
-	// possibly concurrent access to file when multiple stores
+	landscape.Client = gardenClient
+	landscape.LandscapeIdentity = identity
+	landscape.GardenClient = gardenclient.NewGardenClient(gardenClient, identity)
+	return nil
+}
+
+// ensureGardenloginConfig makes sure that the Gardenlogin config contains an entry for the landscape
+func ensureGardenloginConfig(identity, gardenerAPIKubeconfigPath string) error {
+	// possibly concurrent access to file when multiple stores or landscapes
+	gardenloginConfigLock.Lock()
+	defer gardenloginConfigLock.Unlock()
+
 	// For now, ignore the env variables: `GL_HOME` & `GL_CONFIG_NAME` that could be used to set alternative config directories
-	gardenloginConfigPath := os.ExpandEnv(defaultGardenloginConfigPath)
+	gardenloginConfigPath := util.ResolveKubeconfigPath(defaultGardenloginConfigPath)
 	if _, err := os.Stat(gardenloginConfigPath); err != nil {
 		if !os.IsNotExist(err) {
 			return err
@@ -131,8 +184,8 @@ func (s *GardenerStore) InitializeGardenerStore() error {
 		// the default configuration does not exist. Write based on the Kubeswitch configuration file
 		if err := writeGardenloginConfig(gardenloginConfigPath, &GardenloginConfig{Gardens: []GardenConfig{
 			{
-				Identity:   s.LandscapeIdentity,
-				Kubeconfig: s.Config.GardenerAPIKubeconfigPath,
+				Identity:   identity,
+				Kubeconfig: gardenerAPIKubeconfigPath,
 			},
 		}}); err != nil {
 			return fmt.Errorf("failed to write Gardenlogin config: %v", err)
@@ -146,24 +199,19 @@ func (s *GardenerStore) InitializeGardenerStore() error {
 		return err
 	}
 
-	foundEntry := false
 	for _, entry := range gardenloginConfig.Gardens {
-		if entry.Identity == s.LandscapeIdentity {
-			foundEntry = true
-			break
+		if entry.Identity == identity {
+			return nil
 		}
 	}
 
-	if !foundEntry {
-		gardenloginConfig.Gardens = append(gardenloginConfig.Gardens, GardenConfig{
-			Identity:   s.LandscapeIdentity,
-			Kubeconfig: s.Config.GardenerAPIKubeconfigPath,
-		})
-		if err := writeGardenloginConfig(gardenloginConfigPath, gardenloginConfig); err != nil {
-			return fmt.Errorf("failed to write Gardenlogin config: %v", err)
-		}
+	gardenloginConfig.Gardens = append(gardenloginConfig.Gardens, GardenConfig{
+		Identity:   identity,
+		Kubeconfig: gardenerAPIKubeconfigPath,
+	})
+	if err := writeGardenloginConfig(gardenloginConfigPath, gardenloginConfig); err != nil {
+		return fmt.Errorf("failed to write Gardenlogin config: %v", err)
 	}
-
 	return nil
 }
 
@@ -188,48 +236,57 @@ func getGardenloginConfig(path string) (*GardenloginConfig, error) {
 
 // writeGardenloginConfig writes the given gardenlogin config to path
 func writeGardenloginConfig(path string, config *GardenloginConfig) error {
-	// creates or truncate/clean the existing file
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
 	output, err := yaml.Marshal(config)
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(output)
-	if err != nil {
-		return err
-	}
-	return nil
+	// creates or truncate/clean the existing file
+	return kubeswitchio.WriteFile(path, output, 0644)
 }
 
-// StartSearch starts the search for Shoots and Managed Seeds
-func (s *GardenerStore) StartSearch(channel chan SearchResult) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// StartSearch starts the search for Shoots and Managed Seeds on all landscapes in parallel
+func (s *GardenerStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	wg := sync.WaitGroup{}
+	for _, landscape := range s.Landscapes {
+		wg.Add(1)
+		go func(landscape *GardenerLandscape) {
+			defer wg.Done()
+			s.searchLandscape(ctx, channel, landscape)
+		}(landscape)
+	}
+	wg.Wait()
+}
+
+// searchLandscape searches for Shoots and Managed Seeds on the given landscape
+func (s *GardenerStore) searchLandscape(ctx context.Context, channel chan SearchResult, landscape *GardenerLandscape) {
+	// the timeout only applies to the requests to the Gardener API, not to sending the search results
+	listCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	if err := s.InitializeGardenerStore(); err != nil {
-		err := fmt.Errorf("failed to initialize store. This is most likely a problem with your provided kubeconfig: %v", err)
+	if err := s.initializeLandscape(landscape); err != nil {
+		err := fmt.Errorf("failed to initialize Gardener landscape %q. This is most likely a problem with your provided kubeconfig: %v", landscape.displayName(), err)
 		channel <- SearchResult{
 			Error: err,
 		}
 		return
 	}
 
+	paths := s.KubeconfigStore.Paths
+	if len(landscape.Config.Namespace) > 0 {
+		paths = []string{landscape.Config.Namespace}
+	}
+
 	// specifying no path equals to all namespaces being searched
-	if len(s.KubeconfigStore.Paths) == 0 {
-		s.KubeconfigStore.Paths = []string{AllNamespacesDenominator}
+	if len(paths) == 0 {
+		paths = []string{AllNamespacesDenominator}
 	}
 
 	var (
 		shootList  []gardencorev1beta1.Shoot
 		secretList []corev1.Secret
 	)
-	for _, path := range s.KubeconfigStore.Paths {
+	for _, path := range paths {
 		var (
 			shoots      *gardencorev1beta1.ShootList
 			listOptions = client.ListOptions{}
@@ -239,10 +296,10 @@ func (s *GardenerStore) StartSearch(channel chan SearchResult) {
 			listOptions.Namespace = path
 		}
 
-		shoots, err := s.GardenClient.ListShoots(ctx, &listOptions)
+		shoots, err := landscape.GardenClient.ListShoots(listCtx, &listOptions)
 		if err != nil {
 			channel <- SearchResult{
-				Error: fmt.Errorf("failed to call list Shoots from the Gardener API for namespace %q: %v", path, err),
+				Error: fmt.Errorf("failed to call list Shoots from the Gardener API of landscape %q for namespace %q: %v", landscape.displayName(), path, err),
 			}
 			return
 		}
@@ -250,9 +307,9 @@ func (s *GardenerStore) StartSearch(channel chan SearchResult) {
 		selector := labels.SelectorFromSet(labels.Set{"gardener.cloud/role": "ca-cluster"})
 		listOptions.LabelSelector = selector
 		secrets := &corev1.SecretList{}
-		if err := s.Client.List(ctx, secrets, &listOptions); err != nil {
+		if err := landscape.Client.List(listCtx, secrets, &listOptions); err != nil {
 			channel <- SearchResult{
-				Error: fmt.Errorf("failed to list CA secrets for namespace %q: %v", path, err),
+				Error: fmt.Errorf("failed to list CA secrets of landscape %q for namespace %q: %v", landscape.displayName(), path, err),
 			}
 			return
 		}
@@ -266,19 +323,21 @@ func (s *GardenerStore) StartSearch(channel chan SearchResult) {
 	}
 
 	managedSeeds := &seedmanagementv1alpha1.ManagedSeedList{}
-	if err := s.Client.List(ctx, managedSeeds, &client.ListOptions{}); err != nil {
+	if err := landscape.Client.List(listCtx, managedSeeds, &client.ListOptions{}); err != nil {
 		// do not return here as many older Gardener installations do not have the
 		// resource group for managed seeds yet
-		s.Logger.Debugf("failed to list managed seeds: %v", err)
+		s.Logger.Debugf("failed to list managed seeds of landscape %q: %v", landscape.displayName(), err)
 	}
 
 	// for memoization
-	s.CacheCaSecretNameToSecret = make(map[string]corev1.Secret, len(secretList))
+	landscape.CaSecretNameToSecretLock.Lock()
+	landscape.CacheCaSecretNameToSecret = make(map[string]corev1.Secret, len(secretList))
+	landscape.CaSecretNameToSecretLock.Unlock()
 	for _, secret := range secretList {
-		s.writeCacheCaSecretNameToSecretLock(fmt.Sprintf("%s:%s", secret.Namespace, secret.Name), secret)
+		landscape.writeCacheCaSecretNameToSecretLock(fmt.Sprintf("%s:%s", secret.Namespace, secret.Name), secret)
 	}
 
-	s.sendKubeconfigPaths(channel, shootList, managedSeeds.Items)
+	s.sendKubeconfigPaths(ctx, channel, landscape, shootList, managedSeeds.Items)
 }
 
 func (s *GardenerStore) GetContextPrefix(path string) string {
@@ -292,21 +351,110 @@ func (s *GardenerStore) GetContextPrefix(path string) string {
 	return strings.ReplaceAll(path, "--", "-")
 }
 
-// IsInitialized checks if the store has been initialized already
+// IsInitialized checks if all landscapes of the store have been initialized already
 func (s *GardenerStore) IsInitialized() bool {
-	return s.Client != nil && len(s.LandscapeIdentity) > 0
+	for _, landscape := range s.Landscapes {
+		if !landscape.IsInitialized() {
+			return false
+		}
+	}
+	return true
 }
 
-func (s *GardenerStore) GetID() string {
-	id := "default"
+// landscapeForPath returns the initialized landscape the kubeconfig path belongs to
+func (s *GardenerStore) landscapeForPath(path string) (*GardenerLandscape, error) {
+	landscape, err := s.findLandscape(func(landscape *GardenerLandscape) bool {
+		return landscape.owns(path)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Gardener store: %w", err)
+	}
+	if landscape == nil {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("unknown Gardener landscape for path %q", path)}
+	}
+	return landscape, nil
+}
+
+// FindLandscape returns the initialized landscape with the given identity or nil if the store has no such landscape.
+// Only the landscapes required to find the landscape are initialized. A landscape failing to initialize
+// only results in an error if it owns the identity or if no other landscape has the identity.
+func (s *GardenerStore) FindLandscape(identity string) (*GardenerLandscape, error) {
+	return s.findLandscape(func(landscape *GardenerLandscape) bool {
+		return landscape.LandscapeIdentity == identity
+	})
+}
+
+// findLandscape initializes and returns the landscape matching the given function.
+// Landscapes with a configured name or identity are matched without initializing them.
+// Returns nil if no landscape matches.
+func (s *GardenerStore) findLandscape(matches func(*GardenerLandscape) bool) (*GardenerLandscape, error) {
+	// the landscape name or the configured identity is known without initializing the landscape
+	for _, landscape := range s.Landscapes {
+		if matches(landscape) {
+			if err := s.initializeLandscape(landscape); err != nil {
+				return nil, err
+			}
+			return landscape, nil
+		}
+	}
+
+	// otherwise, the landscape identity has to be read from the Gardener API
+	var initErr error
+	for _, landscape := range s.Landscapes {
+		if landscape.IsInitialized() {
+			continue
+		}
+
+		if err := s.initializeLandscape(landscape); err != nil {
+			initErr = err
+			continue
+		}
+
+		if matches(landscape) {
+			return landscape, nil
+		}
+	}
+	return nil, initErr
+}
+
+// IsInitialized checks if the landscape has been initialized already
+func (l *GardenerLandscape) IsInitialized() bool {
+	return l.Client != nil && len(l.LandscapeIdentity) > 0
+}
+
+// Prefix returns the landscape name if configured or the landscape identity otherwise.
+// It is the first segment of the kubeconfig paths of the landscape.
+func (l *GardenerLandscape) Prefix() string {
+	if len(l.LandscapeName) > 0 {
+		return l.LandscapeName
+	}
+	return l.LandscapeIdentity
+}
+
+// displayName returns a name of the landscape for messages, also if it is not initialized yet
+func (l *GardenerLandscape) displayName() string {
+	if prefix := l.Prefix(); len(prefix) > 0 {
+		return prefix
+	}
+	return l.Config.GardenletKubeconfigPath
+}
+
+// owns checks if the kubeconfig path belongs to the landscape
+func (l *GardenerLandscape) owns(path string) bool {
+	if len(l.LandscapeIdentity) > 0 && gardenerstore.GetGardenKubeconfigPath(l.LandscapeIdentity) == path {
+		return true
+	}
 
-	if s.KubeconfigStore.ID != nil {
-		id = *s.KubeconfigStore.ID
-	} else if s.Config != nil && s.Config.LandscapeName != nil {
-		id = *s.Config.LandscapeName
+	landscape, _, _, _, _, err := gardenerstore.ParseIdentifier(path)
+	if err != nil {
+		return false
 	}
+	return (len(l.LandscapeName) > 0 && landscape == l.LandscapeName) ||
+		(len(l.LandscapeIdentity) > 0 && landscape == l.LandscapeIdentity)
+}
 
-	return fmt.Sprintf("%s.%s", types.StoreKindGardener, id)
+func (s *GardenerStore) GetID() string {
+	return gardenerStoreID(s.KubeconfigStore, s.Config)
 }
 
 func (s *GardenerStore) GetKind() types.StoreKind {
@@ -321,11 +469,29 @@ func (s *GardenerStore) GetLogger() *logrus.Entry {
 	return s.Logger
 }
 
-func (s *GardenerStore) GetControlplaneKubeconfigForShoot(shootName, project string) ([]byte, *string, error) {
-	if !s.IsInitialized() {
-		if err := s.InitializeGardenerStore(); err != nil {
-			return nil, nil, fmt.Errorf("failed to initialize Gardener store: %w", err)
-		}
+// Stop releases the Shoots, ManagedSeeds and CA secrets cached per landscape.
+// The connections of the Kubernetes clients are managed by client-go.
+func (s *GardenerStore) Stop(_ context.Context) error {
+	for _, landscape := range s.Landscapes {
+		landscape.PathToShootLock.Lock()
+		clear(landscape.CachePathToShoot)
+		landscape.PathToShootLock.Unlock()
+
+		landscape.PathToManagedSeedLock.Lock()
+		clear(landscape.CachePathToManagedSeed)
+		landscape.PathToManagedSeedLock.Unlock()
+
+		landscape.CaSecretNameToSecretLock.Lock()
+		clear(landscape.CacheCaSecretNameToSecret)
+		landscape.CaSecretNameToSecretLock.Unlock()
+	}
+	return nil
+}
+
+// GetControlplaneKubeconfigForShoot returns the kubeconfig for the controlplane namespace of the Shoot in its Seed cluster
+func (s *GardenerStore) GetControlplaneKubeconfigForShoot(landscape *GardenerLandscape, shootName, project string) ([]byte, *string, error) {
+	if err := s.initializeLandscape(landscape); err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize Gardener store: %w", err)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -339,9 +505,9 @@ func (s *GardenerStore) GetControlplaneKubeconfigForShoot(shootName, project str
 	}
 
 	// we get the Shoot for the managed Seed
-	shoot, err := s.GardenClient.GetShoot(ctx, shootNamespace, shootName)
+	shoot, err := landscape.GardenClient.GetShoot(ctx, shootNamespace, shootName)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, wrapKubernetesClusterError(s.GetID(), err)
 	}
 
 	if shoot.Spec.SeedName == nil || *shoot.Spec.SeedName == "" {
@@ -354,7 +520,7 @@ func (s *GardenerStore) GetControlplaneKubeconfigForShoot(shootName, project str
 
 	// this actually tries to find a ManagedSeed with the given name in the garden ns
 	// then uses the Shoot referenced in the Managed seed to obtain the kubeconfig for the managed seed's Shoot
-	clientConfig, err := s.GardenClient.GetSeedClientConfig(ctx, *shoot.Spec.SeedName)
+	clientConfig, err := landscape.GardenClient.GetSeedClientConfig(ctx, *shoot.Spec.SeedName)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -395,7 +561,7 @@ func (s *GardenerStore) GetControlplaneKubeconfigForShoot(shootName, project str
 	}
 
 	// add meta information to kubeconfig (ignored by kubectl)
-	if err := config.SetGardenerStoreMetaInformation(s.LandscapeIdentity, string(gardenerstore.GardenerResourceSeed), "garden", *shoot.Spec.SeedName); err != nil {
+	if err := config.SetGardenerStoreMetaInformation(landscape.LandscapeIdentity, string(gardenerstore.GardenerResourceSeed), "garden", *shoot.Spec.SeedName); err != nil {
 		return nil, nil, err
 	}
 
@@ -407,67 +573,59 @@ func (s *GardenerStore) GetControlplaneKubeconfigForShoot(shootName, project str
 	return bytes, shoot.Spec.SeedName, nil
 }
 
-func (s *GardenerStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byte, error) {
-	if !s.IsInitialized() {
-		if err := s.InitializeGardenerStore(); err != nil {
-			return nil, fmt.Errorf("failed to initialize Gardener store: %w", err)
-		}
+func (s *GardenerStore) GetKubeconfigForPath(ctx context.Context, path string, _ map[string]string) ([]byte, error) {
+	gardenerLandscape, err := s.landscapeForPath(path)
+	if err != nil {
+		return nil, err
 	}
 
-	if gardenerstore.GetGardenKubeconfigPath(s.LandscapeIdentity) == path {
-		if s.Config == nil || len(s.Config.GardenerAPIKubeconfigPath) == 0 {
-			return nil, fmt.Errorf("cannot get garden kubeconfig. Field 'gardenerAPIKubeconfigPath' is not configured in the Gardener store configuration in the SwitchConfig file")
+	if gardenerstore.GetGardenKubeconfigPath(gardenerLandscape.LandscapeIdentity) == path {
+		if len(gardenerLandscape.Config.GardenletKubeconfigPath) == 0 {
+			return nil, &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: fmt.Errorf("cannot get garden kubeconfig. Field 'gardenerAPIKubeconfigPath' is not configured in the Gardener store configuration in the SwitchConfig file")}
 		}
-		return os.ReadFile(s.Config.GardenerAPIKubeconfigPath)
+		return os.ReadFile(gardenerLandscape.Config.GardenletKubeconfigPath)
 	}
 
-	landscape, resource, name, namespace, gardenerProjectName, err := gardenerstore.ParseIdentifier(path)
+	_, resource, name, namespace, gardenerProjectName, err := gardenerstore.ParseIdentifier(path)
 	if err != nil {
-		return nil, err
-	}
-
-	if landscape != s.LandscapeName && landscape != s.LandscapeIdentity {
-		return nil, fmt.Errorf("unknown Gardener landscape %q", landscape)
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: err}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	var clientConfig clientcmd.ClientConfig
+	var bytes []byte
 	switch resource {
 	case gardenerstore.GardenerResourceSeed:
-		managedSeed, ok := s.readFromCachePathToManagedSeed(path)
+		s.Logger.Debugf("Getting kubeconfig for %s %s", resource, name)
+
+		managedSeed, ok := gardenerLandscape.readFromCachePathToManagedSeed(path)
+		if !ok {
+			// the cache is only filled by the search, e.g. not when using the search index
+			s.Logger.Debugf("managed seed %q is not cached. Fetching it from the Gardener API", name)
+			if err := gardenerLandscape.Client.Get(ctx, client.ObjectKey{Namespace: "garden", Name: name}, &managedSeed); err != nil {
+				return nil, wrapKubernetesClusterError(s.GetID(), fmt.Errorf("failed to get managed seed %q: %w", name, err))
+			}
+			gardenerLandscape.writeCachePathToManagedSeed(path, managedSeed)
+		}
 
-		// we know the namespace and name for the Shoot for the ManagedSeed
-		// so we can use that knowledge to get the correct index to get the corresponding Shoot from the cache
-		namespace = "garden"
-		if ok {
-			path = gardenerstore.GetShootIdentifier(landscape, "garden", managedSeed.Spec.Shoot.Name)
+		bytes, err = s.FetchManagedSeedKubeconfig(ctx, gardenerLandscape, managedSeed)
+		if err != nil {
+			return nil, err
 		}
-		fallthrough
 	case gardenerstore.GardenerResourceShoot:
 		s.Logger.Debugf("Getting kubeconfig for %s (%s/%s)", resource, namespace, name)
 
-		shoot, _ := s.readFromCachePathToShoot(path)
+		shoot, _ := gardenerLandscape.readFromCachePathToShoot(path)
 		caClusterSecretName := fmt.Sprintf("%s:%s.%s", namespace, name, gardenclient.ShootProjectSecretSuffixCACluster)
-		caSecret, _ := s.readFromCacheCaSecretNameToSecretLock(caClusterSecretName)
+		caSecret, _ := gardenerLandscape.readFromCacheCaSecretNameToSecretLock(caClusterSecretName)
 
-		clientConfig, err = s.GardenClient.GetShootClientConfig(ctx, namespace, name, shoot, caSecret)
+		bytes, err = s.getShootKubeconfig(ctx, gardenerLandscape, namespace, name, shoot, caSecret)
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate Shoot kubeconfig: %w", err)
+			return nil, err
 		}
 	default:
-		return nil, fmt.Errorf("unknown Gardener resource %q", resource)
-	}
-
-	rawConfig, err := clientConfig.RawConfig()
-	if err != nil {
-		return nil, err
-	}
-
-	bytes, err := clientcmd.Write(rawConfig)
-	if err != nil {
-		return nil, err
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("unknown Gardener resource %q", resource)}
 	}
 
 	config, err := kubeconfigutil.NewKubeconfig(bytes)
@@ -491,16 +649,102 @@ func (s *GardenerStore) GetKubeconfigForPath(path string, _ map[string]string) (
 
 	// add meta information to kubeconfig (ignored by kubectl)
 	// this allows the "controlplane" command to unambiguously determine the Shoot for this Gardener store
-	if err := config.SetGardenerStoreMetaInformation(s.LandscapeIdentity, string(resource), gardenerProjectName, name); err != nil {
+	if err := config.SetGardenerStoreMetaInformation(gardenerLandscape.LandscapeIdentity, string(resource), gardenerProjectName, name); err != nil {
 		return nil, err
 	}
 
 	return config.GetBytes()
 }
 
+// FetchManagedSeedKubeconfig returns the kubeconfig of the Shoot that is registered as the given ManagedSeed.
+// The Shoot and the CA secret of the Shoot are read from the caches filled by the search.
+// On a cache miss, they are fetched from the Gardener API and added to the cache if the search created it.
+func (s *GardenerStore) FetchManagedSeedKubeconfig(ctx context.Context, landscape *GardenerLandscape, managedSeed seedmanagementv1alpha1.ManagedSeed) ([]byte, error) {
+	if managedSeed.Spec.Shoot == nil || len(managedSeed.Spec.Shoot.Name) == 0 {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("managed seed %q does not reference a shoot", managedSeed.Name)}
+	}
+
+	// the shoot of a managed seed is in the namespace of the managed seed, usually the garden namespace
+	namespace := managedSeed.Namespace
+	if len(namespace) == 0 {
+		namespace = "garden"
+	}
+	shootName := managedSeed.Spec.Shoot.Name
+	projectName, ok := gardenerstore.GetProjectName(namespace)
+	if !ok {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("namespace %q of managed seed %q does not belong to a Gardener project", namespace, managedSeed.Name)}
+	}
+
+	shootPath := gardenerstore.GetShootIdentifier(landscape.Prefix(), projectName, shootName)
+	shoot, ok := landscape.readFromCachePathToShoot(shootPath)
+	if !ok {
+		s.logCacheMiss(landscape.isShootCacheFilled(), "shoot %s/%s of managed seed %q is not cached. Fetching it from the Gardener API", namespace, shootName, managedSeed.Name)
+
+		fetchedShoot, err := landscape.GardenClient.GetShoot(ctx, namespace, shootName)
+		if err != nil {
+			return nil, wrapKubernetesClusterError(s.GetID(), fmt.Errorf("failed to get shoot %s/%s of managed seed %q: %w", namespace, shootName, managedSeed.Name, err))
+		}
+		shoot = *fetchedShoot
+	}
+
+	// the CA secret is named after the shoot, not after the managed seed
+	caSecretName := fmt.Sprintf("%s.%s", shootName, gardenclient.ShootProjectSecretSuffixCACluster)
+	caSecretKey := fmt.Sprintf("%s:%s", namespace, caSecretName)
+	caSecret, ok := landscape.readFromCacheCaSecretNameToSecretLock(caSecretKey)
+	if !ok {
+		s.logCacheMiss(landscape.isCaSecretCacheFilled(), "CA secret %s/%s of managed seed %q is not cached. Fetching it from the Gardener API", namespace, caSecretName, managedSeed.Name)
+
+		if err := landscape.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: caSecretName}, &caSecret); err != nil {
+			return nil, wrapKubernetesClusterError(s.GetID(), fmt.Errorf("failed to get CA secret %s/%s of managed seed %q: %w", namespace, caSecretName, managedSeed.Name, err))
+		}
+	}
+
+	bytes, err := s.getShootKubeconfig(ctx, landscape, namespace, shootName, shoot, caSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	landscape.writeCachePathToShoot(shootPath, shoot)
+	landscape.writeCacheCaSecretNameToSecretLock(caSecretKey, caSecret)
+	return bytes, nil
+}
+
+// getShootKubeconfig generates the kubeconfig of the Shoot.
+// The Shoot and its CA secret are fetched from the Gardener API if they are not given.
+func (s *GardenerStore) getShootKubeconfig(ctx context.Context, landscape *GardenerLandscape, namespace, name string, shoot gardencorev1beta1.Shoot, caSecret corev1.Secret) ([]byte, error) {
+	clientConfig, err := landscape.GardenClient.GetShootClientConfig(ctx, namespace, name, shoot, caSecret)
+	if err != nil {
+		return nil, wrapKubernetesClusterError(s.GetID(), fmt.Errorf("failed to generate Shoot kubeconfig: %w", err))
+	}
+
+	rawConfig, err := clientConfig.RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	return clientcmd.Write(rawConfig)
+}
+
+// logCacheMiss logs a warning if the cache has been filled by the search and still misses the entry.
+// Otherwise, the search did not run (e.g. when using the search index) and the cache miss is expected.
+func (s *GardenerStore) logCacheMiss(cacheFilled bool, format string, args ...interface{}) {
+	if cacheFilled {
+		s.Logger.Warnf(format, args...)
+		return
+	}
+	s.Logger.Debugf(format, args...)
+}
+
 func (s *GardenerStore) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
+	var landscape *GardenerLandscape
+	for _, l := range s.Landscapes {
+		if l.owns(path) {
+			landscape = l
+			break
+		}
+	}
+
 	// To improve UX, we return an error immediately and load the store in the background
-	if !s.IsInitialized() {
+	if landscape == nil || !landscape.IsInitialized() {
 		go func() {
 			if err := s.InitializeGardenerStore(); err != nil {
 				s.Logger.Debugf("failed to initialize Gardener store: %v", err)
@@ -509,12 +753,9 @@ func (s *GardenerStore) GetSearchPreview(path string, optionalTags map[string]st
 		return "", fmt.Errorf("gardener store is not initalized yet")
 	}
 
-	landscapeName := fmt.Sprintf("%s: %s", "Gardener landscape", s.LandscapeIdentity)
-	if len(s.LandscapeName) > 0 {
-		landscapeName = fmt.Sprintf("%s: %s", "Gardener landscape", s.LandscapeName)
-	}
+	landscapeName := fmt.Sprintf("%s: %s", "Gardener landscape", landscape.Prefix())
 
-	if gardenerstore.GetGardenKubeconfigPath(s.LandscapeIdentity) == path {
+	if gardenerstore.GetGardenKubeconfigPath(landscape.LandscapeIdentity) == path {
 		asciTree := gotree.New(fmt.Sprintf("%s (*)", landscapeName))
 		return asciTree.Print(), nil
 	}
@@ -537,7 +778,7 @@ func (s *GardenerStore) GetSearchPreview(path string, optionalTags map[string]st
 		asciTree.Add(fmt.Sprintf("Project: %s", projectName))
 
 		shoot := &gardencorev1beta1.Shoot{}
-		if err := s.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, shoot); err != nil {
+		if err := landscape.Client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, shoot); err != nil {
 			if apierrors.IsNotFound(err) {
 				return "", fmt.Errorf("kubeconfig secret for %s (%s/%s) not found", resource, namespace, name)
 			}
@@ -556,32 +797,34 @@ func (s *GardenerStore) GetSearchPreview(path string, optionalTags map[string]st
 	}
 }
 
-func (s *GardenerStore) sendKubeconfigPaths(channel chan SearchResult, shoots []gardencorev1beta1.Shoot, managedSeeds []seedmanagementv1alpha1.ManagedSeed) {
-	var landscapeName = s.LandscapeIdentity
+func (s *GardenerStore) sendKubeconfigPaths(ctx context.Context, channel chan SearchResult, landscape *GardenerLandscape, shoots []gardencorev1beta1.Shoot, managedSeeds []seedmanagementv1alpha1.ManagedSeed) {
+	// all search result use the landscape name instead of the identity if configured
+	// e.g dev-shoot-<shoot-name>
+	var landscapeName = landscape.Prefix()
 
 	// first, send the garden context name configured in the switch config
 	// the GetKubeconfigForPath() knows that this is a "special" path getting
 	// the kubeconfig from the filesystem (set in SwitchConfig for the GardenerStore) instead of
 	// from the Gardener API
-	gardenKubeconfigPath := gardenerstore.GetGardenKubeconfigPath(s.LandscapeIdentity)
+	gardenKubeconfigPath := gardenerstore.GetGardenKubeconfigPath(landscape.LandscapeIdentity)
 	channel <- SearchResult{
 		KubeconfigPath: gardenKubeconfigPath,
 		Error:          nil,
 	}
 
-	// all search result use the landscape name instead of the identity if configured
-	// e.g dev-shoot-<shoot-name>
-	if len(s.LandscapeName) > 0 {
-		landscapeName = *s.Config.LandscapeName
-
-		err := s.createGardenKubeconfigAlias(gardenKubeconfigPath)
+	if len(landscape.LandscapeName) > 0 {
+		err := s.createGardenKubeconfigAlias(ctx, gardenKubeconfigPath)
 		if err != nil {
-			s.Logger.Warnf("failed to write alias %s for context name %s", fmt.Sprintf("%s-garden", landscapeName), fmt.Sprintf("%s-garden", s.LandscapeIdentity))
+			s.Logger.Warnf("failed to write alias %s for context name %s", fmt.Sprintf("%s-garden", landscapeName), fmt.Sprintf("%s-garden", landscape.LandscapeIdentity))
 		}
 	}
 
-	s.CachePathToManagedSeed = make(map[string]seedmanagementv1alpha1.ManagedSeed, len(managedSeeds))
-	s.CachePathToShoot = make(map[string]gardencorev1beta1.Shoot, len(shoots))
+	landscape.PathToManagedSeedLock.Lock()
+	landscape.CachePathToManagedSeed = make(map[string]seedmanagementv1alpha1.ManagedSeed, len(managedSeeds))
+	landscape.PathToManagedSeedLock.Unlock()
+	landscape.PathToShootLock.Lock()
+	landscape.CachePathToShoot = make(map[string]gardencorev1beta1.Shoot, len(shoots))
+	landscape.PathToShootLock.Unlock()
 
 	shootNamesManagedSeed := make(map[string]struct{})
 	for _, managedSeed := range managedSeeds {
@@ -591,29 +834,24 @@ func (s *GardenerStore) sendKubeconfigPaths(channel chan SearchResult, shoots []
 		kubeconfigPath := gardenerstore.GetSeedIdentifier(landscapeName, managedSeed.Name)
 
 		// for memoization
-		s.writeCachePathToManagedSeed(kubeconfigPath, managedSeed)
+		landscape.writeCachePathToManagedSeed(kubeconfigPath, managedSeed)
 	}
 
 	// loop over all Shoots/ShootedSeeds and construct and send their kubeconfig paths as search result
 	for _, shoot := range shoots {
+		if ctx.Err() != nil {
+			return
+		}
+
 		seedName := shoot.Spec.SeedName
 		if seedName == nil {
 			// shoots that are not scheduled to Seed yet do not have a control plane
 			continue
 		}
 
-		var projectName string
-		if shoot.Namespace == "garden" {
-			projectName = "garden"
-		} else {
-			// need to deduct the project name from the Shoot namespace garden-<projectname>
-			split := strings.SplitN(shoot.Namespace, "garden-", 2)
-			switch len(split) {
-			case 2:
-				projectName = split[1]
-			default:
-				continue
-			}
+		projectName, ok := gardenerstore.GetProjectName(shoot.Namespace)
+		if !ok {
+			continue
 		}
 
 		var kubeconfigPath string
@@ -628,7 +866,7 @@ func (s *GardenerStore) sendKubeconfigPaths(channel chan SearchResult, shoots []
 		}
 
 		// for memoization
-		s.writeCachePathToShoot(kubeconfigPath, shoot)
+		landscape.writeCachePathToShoot(kubeconfigPath, shoot)
 
 		_, isAlreadyReferencedByManagedSeed := shootNamesManagedSeed[fmt.Sprintf("%s:%s", shoot.Namespace, shoot.Name)]
 		if isAlreadyReferencedByManagedSeed {
@@ -644,18 +882,18 @@ func (s *GardenerStore) sendKubeconfigPaths(channel chan SearchResult, shoots []
 	// the reason why the paths for managed seeds are populated here in the end (even though they are available before),
 	// is so that the corresponding Shoot resource for the ManagedSeed is already available the cache s.CachePathToShoot[]
 	// when populating the path. This avoids cache misses.
-	s.PathToManagedSeedLock.RLock()
-	for pathForSeed := range s.CachePathToManagedSeed {
+	landscape.PathToManagedSeedLock.RLock()
+	for pathForSeed := range landscape.CachePathToManagedSeed {
 		channel <- SearchResult{
 			KubeconfigPath: pathForSeed,
 			Error:          nil,
 		}
 	}
-	s.PathToManagedSeedLock.RUnlock()
+	landscape.PathToManagedSeedLock.RUnlock()
 }
 
-func (s *GardenerStore) createGardenKubeconfigAlias(gardenKubeconfigPath string) error {
-	bytes, err := s.GetKubeconfigForPath(gardenKubeconfigPath, nil)
+func (s *GardenerStore) createGardenKubeconfigAlias(ctx context.Context, gardenKubeconfigPath string) error {
+	bytes, err := s.GetKubeconfigForPath(ctx, gardenKubeconfigPath, nil)
 	if err != nil {
 		return err
 	}
@@ -686,47 +924,92 @@ func (s *GardenerStore) createGardenKubeconfigAlias(gardenKubeconfigPath string)
 	return nil
 }
 
-func (s *GardenerStore) VerifyKubeconfigPaths() error {
+func (s *GardenerStore) VerifyKubeconfigPaths(ctx context.Context) error {
 	// NOOP as we do not allow any paths to be configured for the Gardener store
 	// searches through all namespaces
 	return nil
 }
 
-func (s *GardenerStore) writeCachePathToShoot(key string, value gardencorev1beta1.Shoot) {
-	s.PathToShootLock.Lock()
-	defer s.PathToShootLock.Unlock()
-	s.CachePathToShoot[key] = value
+// Probe checks that the Gardener API of every landscape is reachable by reading the landscape identity
+func (s *GardenerStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	for _, landscape := range s.Landscapes {
+		gardenClient, err := gardenerstore.GetGardenClient(landscape.Config.GardenletKubeconfigPath, transportWrapper())
+		if err != nil {
+			return err
+		}
+
+		cm := &corev1.ConfigMap{}
+		if err := gardenClient.Get(ctx, client.ObjectKey{Name: CmNameClusterIdentity, Namespace: metav1.NamespaceSystem}, cm); err != nil {
+			return fmt.Errorf("unable to get gardener landscape identity of landscape %q from config map %s/%s: %w", landscape.displayName(), metav1.NamespaceSystem, CmNameClusterIdentity, err)
+		}
+	}
+	return nil
 }
 
-func (s *GardenerStore) readFromCachePathToShoot(key string) (gardencorev1beta1.Shoot, bool) {
-	s.PathToShootLock.RLock()
-	defer s.PathToShootLock.RUnlock()
-	shoot, ok := s.CachePathToShoot[key]
+func (l *GardenerLandscape) writeCachePathToShoot(key string, value gardencorev1beta1.Shoot) {
+	l.PathToShootLock.Lock()
+	defer l.PathToShootLock.Unlock()
+	// the cache is created by the search
+	if l.CachePathToShoot == nil {
+		return
+	}
+	l.CachePathToShoot[key] = value
+}
+
+func (l *GardenerLandscape) readFromCachePathToShoot(key string) (gardencorev1beta1.Shoot, bool) {
+	l.PathToShootLock.RLock()
+	defer l.PathToShootLock.RUnlock()
+	shoot, ok := l.CachePathToShoot[key]
 	return shoot, ok
 }
 
-func (s *GardenerStore) writeCachePathToManagedSeed(key string, value seedmanagementv1alpha1.ManagedSeed) {
-	s.PathToManagedSeedLock.Lock()
-	defer s.PathToManagedSeedLock.Unlock()
-	s.CachePathToManagedSeed[key] = value
+func (l *GardenerLandscape) writeCachePathToManagedSeed(key string, value seedmanagementv1alpha1.ManagedSeed) {
+	l.PathToManagedSeedLock.Lock()
+	defer l.PathToManagedSeedLock.Unlock()
+	// the cache is created by the search
+	if l.CachePathToManagedSeed == nil {
+		return
+	}
+	l.CachePathToManagedSeed[key] = value
 }
 
-func (s *GardenerStore) readFromCachePathToManagedSeed(key string) (seedmanagementv1alpha1.ManagedSeed, bool) {
-	s.PathToManagedSeedLock.RLock()
-	defer s.PathToManagedSeedLock.RUnlock()
-	managedSeed, ok := s.CachePathToManagedSeed[key]
+func (l *GardenerLandscape) readFromCachePathToManagedSeed(key string) (seedmanagementv1alpha1.ManagedSeed, bool) {
+	l.PathToManagedSeedLock.RLock()
+	defer l.PathToManagedSeedLock.RUnlock()
+	managedSeed, ok := l.CachePathToManagedSeed[key]
 	return managedSeed, ok
 }
 
-func (s *GardenerStore) writeCacheCaSecretNameToSecretLock(key string, value corev1.Secret) {
-	s.CaSecretNameToSecretLock.Lock()
-	defer s.CaSecretNameToSecretLock.Unlock()
-	s.CacheCaSecretNameToSecret[key] = value
+func (l *GardenerLandscape) writeCacheCaSecretNameToSecretLock(key string, value corev1.Secret) {
+	l.CaSecretNameToSecretLock.Lock()
+	defer l.CaSecretNameToSecretLock.Unlock()
+	// the cache is created by the search
+	if l.CacheCaSecretNameToSecret == nil {
+		return
+	}
+	l.CacheCaSecretNameToSecret[key] = value
+}
+
+// isShootCacheFilled checks if the search already filled the cache of the Shoots
+func (l *GardenerLandscape) isShootCacheFilled() bool {
+	l.PathToShootLock.RLock()
+	defer l.PathToShootLock.RUnlock()
+	return l.CachePathToShoot != nil
+}
+
+// isCaSecretCacheFilled checks if the search already filled the cache of the CA secrets
+func (l *GardenerLandscape) isCaSecretCacheFilled() bool {
+	l.CaSecretNameToSecretLock.RLock()
+	defer l.CaSecretNameToSecretLock.RUnlock()
+	return l.CacheCaSecretNameToSecret != nil
 }
 
-func (s *GardenerStore) readFromCacheCaSecretNameToSecretLock(key string) (corev1.Secret, bool) {
-	s.CaSecretNameToSecretLock.RLock()
-	defer s.CaSecretNameToSecretLock.RUnlock()
-	secret, ok := s.CacheCaSecretNameToSecret[key]
+func (l *GardenerLandscape) readFromCacheCaSecretNameToSecretLock(key string) (corev1.Secret, bool) {
+	l.CaSecretNameToSecretLock.RLock()
+	defer l.CaSecretNameToSecretLock.RUnlock()
+	secret, ok := l.CacheCaSecretNameToSecret[key]
 	return secret, ok
 }
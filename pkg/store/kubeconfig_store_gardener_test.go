@@ -0,0 +1,316 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	seedmanagementv1alpha1 "github.com/gardener/gardener/pkg/apis/seedmanagement/v1alpha1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	gardenclient "github.com/danielfoehrkn/kubeswitch/pkg/store/gardener/copied_gardenctlv2"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// fakeGardenClient serves Shoots and Secrets from maps and counts the requests
+type fakeGardenClient struct {
+	client.Client
+	shoots   map[client.ObjectKey]gardencorev1beta1.Shoot
+	secrets  map[client.ObjectKey]corev1.Secret
+	requests []string
+}
+
+func (f *fakeGardenClient) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	f.requests = append(f.requests, key.String())
+
+	var ok bool
+	switch o := obj.(type) {
+	case *gardencorev1beta1.Shoot:
+		*o, ok = f.shoots[key]
+	case *corev1.Secret:
+		*o, ok = f.secrets[key]
+	default:
+		return fmt.Errorf("unexpected object %T", obj)
+	}
+	if !ok {
+		return apierrors.NewNotFound(schema.GroupResource{}, key.String())
+	}
+	return nil
+}
+
+func (f *fakeGardenClient) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOptions := &client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+	f.requests = append(f.requests, fmt.Sprintf("list %T %s", list, listOptions.Namespace))
+
+	switch l := list.(type) {
+	case *gardencorev1beta1.ShootList:
+		for key, shoot := range f.shoots {
+			if len(listOptions.Namespace) == 0 || key.Namespace == listOptions.Namespace {
+				l.Items = append(l.Items, shoot)
+			}
+		}
+	case *corev1.SecretList, *seedmanagementv1alpha1.ManagedSeedList:
+	default:
+		return fmt.Errorf("unexpected list %T", list)
+	}
+	return nil
+}
+
+var _ = Describe("GardenerStore", func() {
+	var (
+		gardenClient *fakeGardenClient
+		landscape    *store.GardenerLandscape
+		s            *store.GardenerStore
+		managedSeed  seedmanagementv1alpha1.ManagedSeed
+		shoot        gardencorev1beta1.Shoot
+		caSecret     corev1.Secret
+	)
+
+	BeforeEach(func() {
+		// the name of the managed seed differs from the name of its shoot
+		managedSeed = seedmanagementv1alpha1.ManagedSeed{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "garden", Name: "aws-eu1"},
+			Spec:       seedmanagementv1alpha1.ManagedSeedSpec{Shoot: &seedmanagementv1alpha1.Shoot{Name: "soil-aws-eu1"}},
+		}
+		shoot = gardencorev1beta1.Shoot{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "garden", Name: "soil-aws-eu1"},
+			Spec:       gardencorev1beta1.ShootSpec{Kubernetes: gardencorev1beta1.Kubernetes{Version: "1.29.0"}},
+			Status: gardencorev1beta1.ShootStatus{AdvertisedAddresses: []gardencorev1beta1.ShootAdvertisedAddress{
+				{Name: "external", URL: "https://api.soil-aws-eu1.example.com"},
+			}},
+		}
+		caSecret = corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "garden", Name: "soil-aws-eu1.ca-cluster"},
+			Data:       map[string][]byte{"ca.crt": []byte("ca")},
+		}
+
+		gardenClient = &fakeGardenClient{
+			shoots:  map[client.ObjectKey]gardencorev1beta1.Shoot{client.ObjectKeyFromObject(&shoot): shoot},
+			secrets: map[client.ObjectKey]corev1.Secret{client.ObjectKeyFromObject(&caSecret): caSecret},
+		}
+		landscape = &store.GardenerLandscape{
+			GardenClient:      gardenclient.NewGardenClient(gardenClient, "landscape-dev"),
+			Client:            gardenClient,
+			LandscapeIdentity: "landscape-dev",
+		}
+		s = &store.GardenerStore{
+			Logger:     testutil.NewTestLogger(),
+			Landscapes: []*store.GardenerLandscape{landscape},
+		}
+	})
+
+	expectShootKubeconfig := func(kubeconfig []byte) {
+		config, err := clientcmd.Load(kubeconfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters).To(HaveKey("garden--soil-aws-eu1-external"))
+		Expect(config.Clusters["garden--soil-aws-eu1-external"].Server).To(Equal("https://api.soil-aws-eu1.example.com"))
+		Expect(config.Clusters["garden--soil-aws-eu1-external"].CertificateAuthorityData).To(Equal([]byte("ca")))
+	}
+
+	It("should fetch the kubeconfig of the shoot of the managed seed from the Gardener API", func() {
+		kubeconfig, err := s.FetchManagedSeedKubeconfig(context.Background(), landscape, managedSeed)
+		Expect(err).ToNot(HaveOccurred())
+		expectShootKubeconfig(kubeconfig)
+		Expect(gardenClient.requests).To(Equal([]string{"garden/soil-aws-eu1", "garden/soil-aws-eu1.ca-cluster"}))
+	})
+
+	It("should use and update the caches filled by the search", func() {
+		landscape.CachePathToShoot = map[string]gardencorev1beta1.Shoot{"landscape-dev--shoot--garden--soil-aws-eu1": shoot}
+		landscape.CacheCaSecretNameToSecret = map[string]corev1.Secret{}
+
+		kubeconfig, err := s.FetchManagedSeedKubeconfig(context.Background(), landscape, managedSeed)
+		Expect(err).ToNot(HaveOccurred())
+		expectShootKubeconfig(kubeconfig)
+		Expect(gardenClient.requests).To(Equal([]string{"garden/soil-aws-eu1.ca-cluster"}))
+		Expect(landscape.CacheCaSecretNameToSecret).To(HaveKey("garden:soil-aws-eu1.ca-cluster"))
+
+		_, err = s.FetchManagedSeedKubeconfig(context.Background(), landscape, managedSeed)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(gardenClient.requests).To(HaveLen(1))
+	})
+
+	It("should read the shoot of a managed seed outside the garden namespace from the cache of its project", func() {
+		managedSeed.Namespace = "garden-infra"
+		projectShoot := *shoot.DeepCopy()
+		projectShoot.Namespace = "garden-infra"
+		// the cached shoot is only found with the project of the namespace of the managed seed
+		landscape.CachePathToShoot = map[string]gardencorev1beta1.Shoot{
+			"landscape-dev--shoot--infra--soil-aws-eu1":  projectShoot,
+			"landscape-dev--shoot--garden--soil-aws-eu1": {},
+		}
+		landscape.CacheCaSecretNameToSecret = map[string]corev1.Secret{"garden-infra:soil-aws-eu1.ca-cluster": caSecret}
+
+		kubeconfig, err := s.FetchManagedSeedKubeconfig(context.Background(), landscape, managedSeed)
+		Expect(err).ToNot(HaveOccurred())
+		config, err := clientcmd.Load(kubeconfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Clusters).To(HaveKey("garden-infra--soil-aws-eu1-external"))
+		Expect(gardenClient.requests).To(BeEmpty())
+	})
+
+	It("should fail if the namespace of the managed seed does not belong to a project", func() {
+		managedSeed.Namespace = "kube-system"
+		_, err := s.FetchManagedSeedKubeconfig(context.Background(), landscape, managedSeed)
+		Expect(err).To(MatchError(&storeerrors.ErrKubeconfigNotFound{}))
+	})
+
+	It("should fail if the managed seed does not reference a shoot", func() {
+		managedSeed.Spec.Shoot = nil
+		_, err := s.FetchManagedSeedKubeconfig(context.Background(), landscape, managedSeed)
+		Expect(err).To(MatchError(ContainSubstring("does not reference a shoot")))
+	})
+
+	Context("multiple landscapes", func() {
+		var (
+			tempDir          string
+			gardenKubeconfig string
+			live             *store.GardenerLandscape
+			broken           *store.GardenerLandscape
+		)
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = os.MkdirTemp("", "gardener-store")
+			Expect(err).ToNot(HaveOccurred())
+
+			gardenKubeconfig = filepath.Join(tempDir, "garden-kubeconfig")
+			Expect(os.WriteFile(gardenKubeconfig, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: garden
+  cluster:
+    server: https://api.garden.example.com
+contexts:
+- name: garden
+  context:
+    cluster: garden
+    user: garden
+current-context: garden
+users:
+- name: garden
+  user:
+    token: token
+`), 0600)).To(Succeed())
+			landscape.Config = types.LandscapeConfig{GardenletKubeconfigPath: gardenKubeconfig}
+
+			scheduledShoot := func(namespace, name string) gardencorev1beta1.Shoot {
+				return gardencorev1beta1.Shoot{
+					ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+					Spec:       gardencorev1beta1.ShootSpec{SeedName: ptr.To("aws-eu1")},
+				}
+			}
+
+			liveClient := &fakeGardenClient{shoots: map[client.ObjectKey]gardencorev1beta1.Shoot{
+				{Namespace: "garden", Name: "soil"}:     scheduledShoot("garden", "soil"),
+				{Namespace: "garden-team", Name: "web"}: scheduledShoot("garden-team", "web"),
+			}}
+			live = &store.GardenerLandscape{
+				Config:            types.LandscapeConfig{Name: "live", GardenletKubeconfigPath: gardenKubeconfig, Namespace: "garden-team"},
+				GardenClient:      gardenclient.NewGardenClient(liveClient, "landscape-live"),
+				Client:            liveClient,
+				LandscapeIdentity: "landscape-live",
+				LandscapeName:     "live",
+			}
+
+			// the kubeconfig of the landscape does not exist, hence it fails to initialize
+			broken = &store.GardenerLandscape{
+				Config:        types.LandscapeConfig{Name: "broken", GardenletKubeconfigPath: filepath.Join(tempDir, "missing")},
+				LandscapeName: "broken",
+			}
+
+			gardenClient.shoots[client.ObjectKey{Namespace: "garden-team", Name: "app"}] = scheduledShoot("garden-team", "app")
+			s.KubeconfigStore = types.KubeconfigStore{ID: ptr.To("all"), Kind: types.StoreKindGardener}
+			s.Config = &types.StoreConfigGardener{}
+			s.StateDirectory = tempDir
+			s.Landscapes = []*store.GardenerLandscape{landscape, live, broken}
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tempDir)).To(Succeed())
+		})
+
+		It("should search each landscape and only report the errors of the failing landscape", func() {
+			results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+			Expect(err).To(MatchError(ContainSubstring(`failed to initialize Gardener landscape "broken"`)))
+
+			var paths []string
+			for _, result := range results {
+				paths = append(paths, result.KubeconfigPath)
+			}
+			Expect(paths).To(ConsistOf(
+				"landscape-dev-garden",
+				"landscape-dev--shoot--team--app",
+				"landscape-live-garden",
+				"live--shoot--team--web",
+			))
+
+			// the landscape with a namespace only searches this namespace
+			Expect(live.Client.(*fakeGardenClient).requests).To(ContainElement("list *v1beta1.ShootList garden-team"))
+			Expect(gardenClient.requests).To(ContainElement("list *v1beta1.ShootList "))
+		})
+
+		It("should return the kubeconfig of the landscape owning the path without initializing the other landscapes", func() {
+			kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "landscape-dev-garden", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(kubeconfig)).To(ContainSubstring("https://api.garden.example.com"))
+			Expect(broken.IsInitialized()).To(BeFalse())
+		})
+
+		It("should only fail for the paths of the landscape failing to initialize", func() {
+			_, err := s.GetKubeconfigForPath(context.Background(), "broken--shoot--team--app", nil)
+			Expect(err).To(MatchError(ContainSubstring("failed to initialize Gardener store")))
+
+			// the identity of the broken landscape is unknown, hence it might own an unknown path
+			_, err = s.GetKubeconfigForPath(context.Background(), "unknown--shoot--team--app", nil)
+			Expect(err).To(MatchError(ContainSubstring("failed to initialize Gardener store")))
+
+			s.Landscapes = []*store.GardenerLandscape{landscape, live}
+			_, err = s.GetKubeconfigForPath(context.Background(), "unknown--shoot--team--app", nil)
+			Expect(err).To(MatchError(&storeerrors.ErrKubeconfigNotFound{}))
+		})
+
+		It("should find the landscape by its identity", func() {
+			found, err := s.FindLandscape("landscape-live")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeIdenticalTo(live))
+			Expect(broken.IsInitialized()).To(BeFalse())
+
+			// the identity of the broken landscape is unknown without initializing it
+			_, err = s.FindLandscape("landscape-other")
+			Expect(err).To(MatchError(ContainSubstring("unable to create rest config")))
+
+			s.Landscapes = []*store.GardenerLandscape{landscape, live}
+			found, err = s.FindLandscape("landscape-other")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeNil())
+		})
+	})
+})
@@ -16,6 +16,7 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -29,8 +30,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	apiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
 
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
 	"github.com/danielfoehrkn/kubeswitch/types"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/cloudresourcemanager/v1"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
 )
 
 var (
@@ -48,18 +54,18 @@ func NewGKEStore(store types.KubeconfigStore, stateDir string) (*GKEStore, error
 	if store.Config != nil {
 		buf, err := yaml.Marshal(store.Config)
 		if err != nil {
-			return nil, err
+			return nil, invalidConfig(store, err)
 		}
 
 		err = yaml.Unmarshal(buf, gkeStoreConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal gke config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to unmarshal gke config: %w", err))
 		}
 	}
 
 	binaryPath, err := getGcloudBinaryPath()
 	if err != nil {
-		return nil, fmt.Errorf("gcloud must be installaed when useing the GKE store: %v", err)
+		return nil, invalidConfig(store, fmt.Errorf("gcloud must be installaed when useing the GKE store: %w", err))
 	}
 	gcloudBinaryPath = binaryPath
 
@@ -90,37 +96,37 @@ func (s *GKEStore) InitializeGKEStore() error {
 	// Later, also support API keys provided with the store configuration
 	// please see: https://pkg.go.dev/google.golang.org/api/container/v1
 	// and: https://cloud.google.com/docs/authentication/production#automatically
-	client, err := container.NewService(ctx)
+	client, err := container.NewService(ctx, s.clientOptions(ctx)...)
 	if err != nil {
 		// this can happen when there are no application-default credentials available on the local disk
 		// try to re-authenticate using local gcloud installation
 		if len(gcloudBinaryPath) == 0 {
-			return fmt.Errorf("failed to create Google Kubernetes Engine client. Please check that the `gcloud` CLI is installed and `gcloud auth application-default login` has run: %w", err)
+			return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("failed to create Google Kubernetes Engine client. Please check that the `gcloud` CLI is installed and `gcloud auth application-default login` has run: %w", err)}
 		}
 
 		// gcloud auth application-default login
 		_, err_exec := exec.Command(gcloudBinaryPath, "auth", "application-default", "login").Output()
 		if err_exec != nil {
-			return fmt.Errorf("failed to acquire missing credentials via gcloud: %v: Failed to create client: %v", err_exec, err)
+			return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("failed to acquire missing credentials via gcloud: %v: Failed to create client: %v", err_exec, err)}
 		}
 
 		s.Logger.Infof("Sucessfully obtained application default credentials.")
 
 		// try again with obtained credentials
-		client, err = container.NewService(ctx)
+		client, err = container.NewService(ctx, s.clientOptions(ctx)...)
 		if err != nil {
-			return fmt.Errorf("failed to create Google Kubernetes Engine client: %w", err)
+			return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("failed to create Google Kubernetes Engine client: %w", err)}
 		}
 	}
 
 	if s.Config.GCPAccount != nil {
 		isActive, err := isAccountActive(*s.Config.GCPAccount)
 		if err != nil {
-			return fmt.Errorf("failed to check if Google Cloud account %q is active: %w", *s.Config.GCPAccount, err)
+			return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("failed to check if Google Cloud account %q is active: %w", *s.Config.GCPAccount, err)}
 		}
 
 		if !isActive {
-			return fmt.Errorf("google cloud account %q is not active. Please use `gcloud config set account %s` to activate the account", *s.Config.GCPAccount, *s.Config.GCPAccount)
+			return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("google cloud account %q is not active. Please use `gcloud config set account %s` to activate the account", *s.Config.GCPAccount, *s.Config.GCPAccount)}
 		}
 	}
 
@@ -129,9 +135,9 @@ func (s *GKEStore) InitializeGKEStore() error {
 	// Discover projects in this account
 	allowedProjectIDs := sets.NewString(s.Config.ProjectIDs...)
 
-	cloudResourceManagerService, err := cloudresourcemanager.NewService(ctx)
+	cloudResourceManagerService, err := cloudresourcemanager.NewService(ctx, s.clientOptions(ctx)...)
 	if err != nil {
-		return fmt.Errorf("failed to create cloud resource manager client: %w", err)
+		return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("failed to create cloud resource manager client: %w", err)}
 	}
 
 	req := cloudResourceManagerService.Projects.List()
@@ -149,13 +155,13 @@ func (s *GKEStore) InitializeGKEStore() error {
 		// so the actual request against the API returns 401
 		// Try to re-authenticate using gcloud!
 		if len(gcloudBinaryPath) == 0 {
-			return fmt.Errorf("failed to list Google cloud projects. This indicates either connectivity issues or invalid credentials. Make sure you are connected to the internet and that the `gcloud` CLI is installed for authentication. (Try running: `gcloud auth application-default login`): %w", err)
+			return wrapGoogleError(s.GetID(), fmt.Errorf("failed to list Google cloud projects. This indicates either connectivity issues or invalid credentials. Make sure you are connected to the internet and that the `gcloud` CLI is installed for authentication. (Try running: `gcloud auth application-default login`): %w", err))
 		}
 
 		// gcloud auth application-default login
 		_, errExec := exec.Command(gcloudBinaryPath, "auth", "application-default", "login").Output()
 		if errExec != nil {
-			return fmt.Errorf("failed to list Google Cloud projects probably due to permission issues. Also failed to acquire application-default credentials via gcloud OIDC authentication flow: %v: %v", errExec, err)
+			return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("failed to list Google Cloud projects probably due to permission issues. Also failed to acquire application-default credentials via gcloud OIDC authentication flow: %v: %v", errExec, err)}
 		}
 
 		s.Logger.Infof("Sucessfully obtained application default credentials.")
@@ -168,8 +174,31 @@ func (s *GKEStore) InitializeGKEStore() error {
 	return nil
 }
 
-func (s *GKEStore) StartSearch(channel chan SearchResult) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+// clientOptions returns the options for the Google API clients.
+// If the credential cache is configured, the OAuth2 tokens of the application default credentials are cached.
+func (s *GKEStore) clientOptions(ctx context.Context) []option.ClientOption {
+	if s.CredentialCache == nil {
+		return nil
+	}
+
+	tokenSource, err := google.DefaultTokenSource(ctx, container.CloudPlatformScope)
+	if err != nil {
+		// let the client creation fail with the original error
+		return nil
+	}
+
+	account := "default"
+	if s.Config.GCPAccount != nil {
+		account = *s.Config.GCPAccount
+	}
+	return []option.ClientOption{option.WithTokenSource(credentials.NewOAuth2TokenSource(s.CredentialCache, s.GetID(), account, tokenSource))}
+}
+
+// GKEDefaultClusterNameTemplate formats the kubeconfig paths of GKE clusters if no cluster name template is configured
+const GKEDefaultClusterNameTemplate = "gke_{{.ProjectName}}--{{.Region}}--{{.ClusterName}}"
+
+func (s *GKEStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	if err := s.InitializeGKEStore(); err != nil {
@@ -180,31 +209,128 @@ func (s *GKEStore) StartSearch(channel chan SearchResult) {
 		return
 	}
 
+	clusterNameTemplate, err := NewClusterNameTemplate(s.Config.ClusterNameTemplate, GKEDefaultClusterNameTemplate)
+	if err != nil {
+		channel <- SearchResult{
+			Error: &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: err},
+		}
+		return
+	}
+
 	for projectName, projectId := range s.ProjectNameToID {
-		resp, err := s.GkeClient.Projects.Zones.Clusters.List(projectId, "-").Context(ctx).Do()
+		clusters, err := s.listClusters(ctx, clusterNameTemplate, projectName, projectId)
 		if err != nil {
 			channel <- SearchResult{
-				Error: fmt.Errorf("failed to list GKE clusters for project with ID %q: %w", projectId, err),
+				Error: err,
 			}
 			continue
 		}
 
-		// for every GKE cluster in the project
-		for _, f := range resp.Clusters {
-			// kubeconfig path used to uniquely identify this cluster
-			// gke_<project-name>--<zone>--<gke-cluster-name>
+		for _, cluster := range clusters {
+			channel <- cluster.result
+		}
+	}
+}
 
-			kubeconfigPath := fmt.Sprintf("gke_%s--%s--%s", projectName, f.Location, f.Name)
+// SearchChangesSince lists the GKE clusters of all projects.
+// Clusters created after the given time are returned as added even if their kubeconfig path is already indexed,
+// as the cluster has been recreated with the same name.
+// The indexed kubeconfig paths of clusters that no longer exist are returned as removed.
+func (s *GKEStore) SearchChangesSince(ctx context.Context, since time.Time, existing []string) ([]SearchResult, []string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
 
-			// cache for when getting the kubeconfig for the unique path later
-			s.DiscoveredClusters[kubeconfigPath] = f
+	if !s.IsInitialized() {
+		if err := s.InitializeGKEStore(); err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize store: %w", err)
+		}
+	}
 
-			channel <- SearchResult{
-				KubeconfigPath: kubeconfigPath,
-				Error:          nil,
+	clusterNameTemplate, err := NewClusterNameTemplate(s.Config.ClusterNameTemplate, GKEDefaultClusterNameTemplate)
+	if err != nil {
+		return nil, nil, &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: err}
+	}
+
+	indexed := sets.NewString(existing...)
+	current := sets.NewString()
+	var added []SearchResult
+	for projectName, projectId := range s.ProjectNameToID {
+		// the removed clusters cannot be determined if a project cannot be listed
+		clusters, err := s.listClusters(ctx, clusterNameTemplate, projectName, projectId)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, cluster := range clusters {
+			current.Insert(cluster.result.KubeconfigPath)
+			if !indexed.Has(cluster.result.KubeconfigPath) || createdAfter(cluster.cluster, since) {
+				added = append(added, cluster.result)
 			}
 		}
 	}
+	return added, indexed.Difference(current).List(), nil
+}
+
+// createdAfter returns true if the GKE cluster has been created after the given time or if its creation time is unknown
+func createdAfter(cluster *container.Cluster, since time.Time) bool {
+	createTime, err := time.Parse(time.RFC3339, cluster.CreateTime)
+	if err != nil {
+		return true
+	}
+	return createTime.After(since)
+}
+
+// gkeCluster is a GKE cluster together with its search result
+type gkeCluster struct {
+	result  SearchResult
+	cluster *container.Cluster
+}
+
+// listClusters returns the GKE clusters of the project in all locations
+func (s *GKEStore) listClusters(ctx context.Context, clusterNameTemplate *ClusterNameTemplate, projectName, projectId string) ([]gkeCluster, error) {
+	resp, err := s.GkeClient.Projects.Zones.Clusters.List(projectId, "-").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GKE clusters for project with ID %q: %w", projectId, err)
+	}
+
+	clusters := make([]gkeCluster, 0, len(resp.Clusters))
+	// for every GKE cluster in the project
+	for _, f := range resp.Clusters {
+		// kubeconfig path used to uniquely identify this cluster
+		// defaults to gke_<project-name>--<zone>--<gke-cluster-name>
+		kubeconfigPath, err := clusterNameTemplate.Execute(ClusterNameTemplateData{
+			ClusterName: f.Name,
+			Region:      f.Location,
+			ProjectName: projectName,
+			ProjectID:   projectId,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// cache for when getting the kubeconfig for the unique path later
+		s.DiscoveredClustersMutex.Lock()
+		s.DiscoveredClusters[kubeconfigPath] = f
+		s.DiscoveredClustersMutex.Unlock()
+
+		var tags map[string]string
+		if clusterNameTemplate.IsCustom() {
+			tags = map[string]string{
+				tagProject:     projectName,
+				tagLocation:    f.Location,
+				tagClusterName: f.Name,
+			}
+		}
+
+		clusters = append(clusters, gkeCluster{
+			result: SearchResult{
+				KubeconfigPath: kubeconfigPath,
+				Tags:           tags,
+			},
+			cluster: f,
+		})
+	}
+	return clusters, nil
 }
 
 func (s *GKEStore) GetContextPrefix(path string) string {
@@ -245,8 +371,17 @@ func (s *GKEStore) GetLogger() *logrus.Entry {
 	return s.Logger
 }
 
-func (s *GKEStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// Stop releases the clusters discovered by the search.
+// The connections of the GKE client are managed by the Google API client.
+func (s *GKEStore) Stop(_ context.Context) error {
+	s.DiscoveredClustersMutex.Lock()
+	defer s.DiscoveredClustersMutex.Unlock()
+	clear(s.DiscoveredClusters)
+	return nil
+}
+
+func (s *GKEStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	if !s.IsInitialized() {
@@ -254,14 +389,16 @@ func (s *GKEStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byt
 			return nil, fmt.Errorf("failed to initialize GKE store: %w", err)
 		}
 	}
-	projectName, location, clusterName, err := parseIdentifier(path)
+	projectName, location, clusterName, err := parseIdentifier(path, tags)
 	if err != nil {
-		return nil, err
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: err}
 	}
 
 	projectID := s.ProjectNameToID[strings.TrimPrefix(projectName, "gke_")]
 
+	s.DiscoveredClustersMutex.RLock()
 	cluster := s.DiscoveredClusters[path]
+	s.DiscoveredClustersMutex.RUnlock()
 
 	// cluster has not been discovered from the GCP API yet
 	// this is the case when a search index is used
@@ -271,7 +408,7 @@ func (s *GKEStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byt
 		name := fmt.Sprintf("projects/%s/locations/%s/clusters/%s", projectID, location, clusterName)
 		resp, err := s.GkeClient.Projects.Locations.Clusters.Get(name).Context(ctx).Do()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get GKE cluster with name %q for project with ID %q: %w", clusterName, projectID, err)
+			return nil, wrapGoogleError(s.GetID(), fmt.Errorf("failed to get GKE cluster with name %q for project with ID %q: %w", clusterName, projectID, err))
 		}
 		cluster = resp
 	}
@@ -280,7 +417,7 @@ func (s *GKEStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byt
 	contextName := fmt.Sprintf("gke_%s", cluster.Name)
 
 	if cluster.MasterAuth == nil {
-		return nil, fmt.Errorf("no authentication information found for GKE cluster with name %q in project with ID %q", clusterName, projectID)
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("no authentication information found for GKE cluster with name %q in project with ID %q", clusterName, projectID)}
 	}
 
 	// need to provide a CA certificate in the kubeconfig (if not using insecure configuration)
@@ -356,17 +493,50 @@ func getGcloudBinaryPath() (string, error) {
 	return path, nil
 }
 
-func (s *GKEStore) VerifyKubeconfigPaths() error {
-	// NOOP
+// VerifyKubeconfigPaths verifies the cluster name template
+func (s *GKEStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	if _, err := NewClusterNameTemplate(s.Config.ClusterNameTemplate, GKEDefaultClusterNameTemplate); err != nil {
+		return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: err}
+	}
+	return nil
+}
+
+// Probe checks that the Google Cloud credentials are valid by listing the first page of projects
+func (s *GKEStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	cloudResourceManagerService, err := cloudresourcemanager.NewService(ctx, s.clientOptions(ctx)...)
+	if err != nil {
+		return &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("failed to create cloud resource manager client: %w", err)}
+	}
+
+	if _, err := cloudResourceManagerService.Projects.List().PageSize(1).Context(ctx).Do(); err != nil {
+		return wrapGoogleError(s.GetID(), fmt.Errorf("failed to list Google Cloud projects: %w", err))
+	}
 	return nil
 }
 
+// wrapGoogleError returns the typed error for a failed request against the Google Cloud API
+func wrapGoogleError(storeID string, err error) error {
+	var apiError *googleapi.Error
+	if errors.As(err, &apiError) {
+		return wrapHTTPError(storeID, apiError.Code, err)
+	}
+	return wrapTimeout(storeID, err)
+}
+
 // ParseIdentifier takes a kubeconfig identifier and
 // returns the
 // 1) the GCP project name
 // 2) the location (zone or region if regional cluster) of the GKE cluster
 // 3) the name of the GKE cluster
-func parseIdentifier(path string) (string, string, string, error) {
+// The cluster is identified using the tags if the kubeconfig path has been formatted with a custom cluster name template.
+func parseIdentifier(path string, tags map[string]string) (string, string, string, error) {
+	if clusterName, ok := tags[tagClusterName]; ok {
+		return tags[tagProject], tags[tagLocation], clusterName, nil
+	}
+
 	split := strings.Split(path, "--")
 	switch len(split) {
 	case 3:
@@ -0,0 +1,100 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/api/container/v1"
+	"google.golang.org/api/option"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("GKEStore", func() {
+	var (
+		server *httptest.Server
+		s      *store.GKEStore
+		since  = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/projects/project-id/zones/-/clusters" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"clusters": [
+				{"name": "old", "location": "europe-west1", "createTime": "2024-01-01T00:00:00+00:00"},
+				{"name": "recreated", "location": "europe-west1", "createTime": "2024-07-01T00:00:00+00:00"},
+				{"name": "new", "location": "europe-west1", "createTime": "2024-07-01T00:00:00+00:00"}
+			]}`))
+		}))
+
+		client, err := container.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+		Expect(err).ToNot(HaveOccurred())
+
+		s = &store.GKEStore{
+			Logger:             testutil.NewTestLogger(),
+			KubeconfigStore:    types.KubeconfigStore{Kind: types.StoreKindGKE},
+			GkeClient:          client,
+			Config:             &types.StoreConfigGKE{},
+			DiscoveredClusters: map[string]*container.Cluster{},
+			ProjectNameToID:    map[string]string{"project": "project-id"},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("SearchChangesSince", func() {
+		It("should return the clusters created since the given time and the clusters that no longer exist", func() {
+			added, removed, err := s.SearchChangesSince(context.Background(), since, []string{
+				"gke_project--europe-west1--old",
+				"gke_project--europe-west1--recreated",
+				"gke_project--europe-west1--deleted",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(added).To(ConsistOf(
+				store.SearchResult{KubeconfigPath: "gke_project--europe-west1--recreated"},
+				store.SearchResult{KubeconfigPath: "gke_project--europe-west1--new"},
+			))
+			Expect(removed).To(ConsistOf("gke_project--europe-west1--deleted"))
+		})
+
+		It("should return clusters not contained in the index as added", func() {
+			added, removed, err := s.SearchChangesSince(context.Background(), since, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(added).To(HaveLen(3))
+			Expect(removed).To(BeEmpty())
+		})
+
+		It("should fail if the clusters of a project cannot be listed", func() {
+			s.ProjectNameToID["other"] = "other-id"
+
+			_, _, err := s.SearchChangesSince(context.Background(), since, []string{"gke_project--europe-west1--old"})
+			Expect(err).To(MatchError(ContainSubstring(`failed to list GKE clusters for project with ID "other-id"`)))
+		})
+	})
+})
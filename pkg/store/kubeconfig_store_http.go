@@ -0,0 +1,486 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awscredentials "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const defaultHTTPRequestTimeout = 30 * time.Second
+
+// emptyPayloadHash is the SHA256 hash of the empty request body used for the AWS Signature Version 4
+var emptyPayloadHash = func() string {
+	hash := sha256.Sum256(nil)
+	return hex.EncodeToString(hash[:])
+}()
+
+// httpListEntry is an entry of the JSON array returned by the list endpoint of the HTTP store
+type httpListEntry struct {
+	Path string            `json:"path"`
+	Name string            `json:"name"`
+	Tags map[string]string `json:"tags"`
+}
+
+func NewHTTPStore(kubeconfigStore types.KubeconfigStore) (*HTTPStore, error) {
+	httpStoreConfig := &types.StoreConfigHTTP{}
+	if kubeconfigStore.Config != nil {
+		buf, err := yaml.Marshal(kubeconfigStore.Config)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to process http store config: %w", err))
+		}
+
+		err = yaml.Unmarshal(buf, httpStoreConfig)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to unmarshal http config: %w", err))
+		}
+	}
+
+	if len(httpStoreConfig.Endpoints) == 0 && len(httpStoreConfig.ListEndpoint) == 0 {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("at least one endpoint or the list endpoint must be configured for the http store"))
+	}
+
+	if len(httpStoreConfig.ListEndpoint) > 0 {
+		if err := validateHTTPURL(httpStoreConfig.ListEndpoint); err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("invalid list endpoint: %w", err))
+		}
+	}
+
+	for i, endpoint := range httpStoreConfig.Endpoints {
+		if err := validateHTTPURL(endpoint.URL); err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("invalid endpoint %d: %w", i, err))
+		}
+		if len(endpoint.Method) == 0 {
+			httpStoreConfig.Endpoints[i].Method = http.MethodGet
+		}
+	}
+
+	timeout := defaultHTTPRequestTimeout
+	if httpStoreConfig.Timeout != nil {
+		timeout = *httpStoreConfig.Timeout
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if len(httpStoreConfig.ProxyURL) > 0 {
+		proxyURL, err := url.Parse(httpStoreConfig.ProxyURL)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("invalid proxy URL %q: %w", httpStoreConfig.ProxyURL, err))
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	// one client per CA certificate file, the empty file denotes the system certificate pool
+	clients := map[string]*http.Client{}
+	caCertFiles := []string{"", httpStoreConfig.TLSCACertFile}
+	for _, endpoint := range httpStoreConfig.Endpoints {
+		caCertFiles = append(caCertFiles, endpoint.TLSCACertFile)
+	}
+	for _, caCertFile := range caCertFiles {
+		if _, ok := clients[caCertFile]; ok {
+			continue
+		}
+
+		client, err := newHTTPStoreClient(caCertFile, timeout, proxy, httpStoreConfig.ConnectionPooling)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, err)
+		}
+		clients[caCertFile] = client
+	}
+
+	// one signer per SigV4 configuration
+	signers := map[*types.SigV4Config]*sigV4Signer{}
+	sigV4Configs := []*types.SigV4Config{httpStoreConfig.SigV4}
+	for _, endpoint := range httpStoreConfig.Endpoints {
+		sigV4Configs = append(sigV4Configs, endpoint.SigV4)
+	}
+	for _, sigV4Config := range sigV4Configs {
+		if _, ok := signers[sigV4Config]; ok || sigV4Config == nil {
+			continue
+		}
+
+		signer, err := newSigV4Signer(sigV4Config)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, err)
+		}
+		signers[sigV4Config] = signer
+	}
+
+	return &HTTPStore{
+		Logger:          logrus.New().WithField("store", types.StoreKindHTTP),
+		KubeconfigStore: kubeconfigStore,
+		Config:          httpStoreConfig,
+		clients:         clients,
+		signers:         signers,
+		names:           map[string]string{},
+	}, nil
+}
+
+// sigV4Signer signs requests with AWS Signature Version 4
+type sigV4Signer struct {
+	signer      *v4.Signer
+	credentials aws.CredentialsProvider
+	region      string
+	service     string
+}
+
+// newSigV4Signer returns a signer using the static credentials of the configuration,
+// or the credentials of the default AWS credential chain if no access key is configured
+func newSigV4Signer(config *types.SigV4Config) (*sigV4Signer, error) {
+	if len(config.Region) == 0 || len(config.Service) == 0 {
+		return nil, fmt.Errorf("the region and the service must be configured for sigV4")
+	}
+
+	var credentialsProvider aws.CredentialsProvider
+	if accessKeyID := os.ExpandEnv(config.AccessKeyID); len(accessKeyID) > 0 {
+		secretAccessKey := os.ExpandEnv(config.SecretAccessKey)
+		if len(secretAccessKey) == 0 {
+			return nil, fmt.Errorf("the secret access key must be configured for sigV4 if the access key id is set")
+		}
+		credentialsProvider = awscredentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, os.ExpandEnv(config.SessionToken))
+	} else {
+		// only reads the shared configuration files, the credentials are retrieved when signing the first request
+		cfg, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithSharedConfigProfile(config.Profile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS configuration for sigV4: %w", err)
+		}
+		credentialsProvider = cfg.Credentials
+	}
+
+	return &sigV4Signer{
+		signer:      v4.NewSigner(),
+		credentials: credentialsProvider,
+		region:      config.Region,
+		service:     config.Service,
+	}, nil
+}
+
+// sign adds the X-Amz-Date and Authorization headers to the request.
+// The requests of the HTTP store do not have a body.
+func (s *sigV4Signer) sign(ctx context.Context, request *http.Request) error {
+	credentials, err := s.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+	return s.signer.SignHTTP(ctx, credentials, request, emptyPayloadHash, s.service, s.region, time.Now())
+}
+
+// validateHTTPURL checks that the given URL is an absolute HTTP(S) URL
+func validateHTTPURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fmt.Errorf("%q is not an absolute http or https URL", rawURL)
+	}
+	return nil
+}
+
+// newHTTPStoreClient returns an HTTP client trusting the CA certificates in the given file,
+// or the system certificate pool if no file is given
+func newHTTPStoreClient(caCertFile string, timeout time.Duration, proxy func(*http.Request) (*url.URL, error), connectionPooling *bool) (*http.Client, error) {
+	transport := cloneDefaultTransport()
+	transport.Proxy = proxy
+	configureConnectionPooling(transport, connectionPooling)
+
+	if len(caCertFile) > 0 {
+		caCert, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate file: %w", err)
+		}
+
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("failed to parse CA certificate file %q", caCertFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: certPool}
+	}
+
+	return &http.Client{
+		Transport: wrapTransport(transport),
+		Timeout:   timeout,
+	}, nil
+}
+
+func (s *HTTPStore) GetID() string {
+	id := "default"
+	if s.KubeconfigStore.ID != nil {
+		id = *s.KubeconfigStore.ID
+	}
+	return fmt.Sprintf("%s.%s", types.StoreKindHTTP, id)
+}
+
+func (s *HTTPStore) GetKind() types.StoreKind {
+	return types.StoreKindHTTP
+}
+
+func (s *HTTPStore) GetContextPrefix(p string) string {
+	if s.GetStoreConfig().ShowPrefix != nil && !*s.GetStoreConfig().ShowPrefix {
+		return ""
+	}
+
+	// prefer the name returned by the list endpoint
+	s.namesMutex.RLock()
+	name, ok := s.names[p]
+	s.namesMutex.RUnlock()
+	if ok && len(name) > 0 {
+		return name
+	}
+
+	// otherwise, return the host of the URL
+	u, err := url.Parse(p)
+	if err != nil || len(u.Hostname()) == 0 {
+		return p
+	}
+	return u.Hostname()
+}
+
+func (s *HTTPStore) GetStoreConfig() types.KubeconfigStore {
+	return s.KubeconfigStore
+}
+
+func (s *HTTPStore) GetLogger() *logrus.Entry {
+	return s.Logger
+}
+
+// Stop closes the idle connections of the HTTP clients
+func (s *HTTPStore) Stop(_ context.Context) error {
+	for _, client := range s.clients {
+		client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (s *HTTPStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	// the kubeconfigs are determined by the configured endpoints
+	return nil
+}
+
+// Probe checks that the list endpoint, or the first endpoint if no list endpoint is configured, is reachable
+func (s *HTTPStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	endpoint := s.listEndpoint()
+	if len(s.Config.ListEndpoint) == 0 {
+		endpoint = s.Config.Endpoints[0]
+	}
+
+	if _, err := s.fetch(ctx, endpoint); err != nil {
+		return fmt.Errorf("failed to reach http endpoint: %w", err)
+	}
+	return nil
+}
+
+// StartSearch sends the URLs of the configured endpoints and of the kubeconfigs returned by the list endpoint
+func (s *HTTPStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	for _, endpoint := range s.Config.Endpoints {
+		channel <- SearchResult{
+			KubeconfigPath: endpoint.URL,
+			Error:          nil,
+		}
+	}
+
+	if len(s.Config.ListEndpoint) == 0 {
+		return
+	}
+
+	entries, err := s.list(ctx)
+	if err != nil {
+		channel <- SearchResult{
+			KubeconfigPath: "",
+			Error:          err,
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		s.namesMutex.Lock()
+		s.names[entry.Path] = entry.Name
+		s.namesMutex.Unlock()
+
+		channel <- SearchResult{
+			KubeconfigPath: entry.Path,
+			Tags:           entry.Tags,
+			Error:          nil,
+		}
+	}
+}
+
+// list returns the kubeconfigs returned by the list endpoint with paths resolved against the list endpoint
+func (s *HTTPStore) list(ctx context.Context) ([]httpListEntry, error) {
+	body, err := s.fetch(ctx, s.listEndpoint())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kubeconfigs: %w", err)
+	}
+
+	var entries []httpListEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode response of list endpoint %q: %w", s.Config.ListEndpoint, err)
+	}
+
+	base, err := url.Parse(s.Config.ListEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]httpListEntry, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry.Path) == 0 {
+			s.Logger.Debugf("skipping entry %q of list endpoint without path", entry.Name)
+			continue
+		}
+
+		ref, err := url.Parse(entry.Path)
+		if err != nil {
+			s.Logger.Warnf("skipping entry of list endpoint with invalid path %q: %v", entry.Path, err)
+			continue
+		}
+		entry.Path = base.ResolveReference(ref).String()
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+func (s *HTTPStore) GetKubeconfigForPath(ctx context.Context, p string, _ map[string]string) ([]byte, error) {
+	s.Logger.Debugf("HTTP: get kubeconfig for URL %s", p)
+	return s.fetch(ctx, s.endpointForPath(p))
+}
+
+// endpointForPath returns the configured endpoint with the given URL.
+// Kubeconfigs discovered via the list endpoint are requested with the settings of the list endpoint.
+// The headers, the bearer token and the sigV4 signature are only sent if the kubeconfig is served by the same scheme and host as the list endpoint.
+func (s *HTTPStore) endpointForPath(p string) types.HTTPEndpoint {
+	for _, endpoint := range s.Config.Endpoints {
+		if endpoint.URL == p {
+			return endpoint
+		}
+	}
+
+	endpoint := s.listEndpoint()
+	endpoint.URL = p
+	if !sameOrigin(s.Config.ListEndpoint, p) {
+		s.Logger.Debugf("HTTP: not sending the headers, the bearer token and the sigV4 signature of the list endpoint to %s", p)
+		endpoint.Headers = nil
+		endpoint.BearerTokenFile = ""
+		endpoint.SigV4 = nil
+	}
+	return endpoint
+}
+
+// sameOrigin returns true if both URLs have the same scheme and host, including the port
+func sameOrigin(a, b string) bool {
+	urlA, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	urlB, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(urlA.Scheme, urlB.Scheme) && strings.EqualFold(urlA.Host, urlB.Host)
+}
+
+// listEndpoint returns the list endpoint with the store-wide request settings
+func (s *HTTPStore) listEndpoint() types.HTTPEndpoint {
+	return types.HTTPEndpoint{
+		URL:             s.Config.ListEndpoint,
+		Method:          http.MethodGet,
+		Headers:         s.Config.Headers,
+		BearerTokenFile: s.Config.BearerTokenFile,
+		TLSCACertFile:   s.Config.TLSCACertFile,
+		SigV4:           s.Config.SigV4,
+	}
+}
+
+// fetch sends the request for the given endpoint and returns the response body
+func (s *HTTPStore) fetch(ctx context.Context, endpoint types.HTTPEndpoint) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, endpoint.Method, endpoint.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %q: %w", endpoint.URL, err)
+	}
+
+	for key, value := range endpoint.Headers {
+		request.Header.Set(key, value)
+	}
+
+	if len(endpoint.BearerTokenFile) > 0 {
+		token, err := os.ReadFile(endpoint.BearerTokenFile)
+		if err != nil {
+			return nil, &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("failed to read bearer token file: %w", err)}
+		}
+		request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", strings.TrimSpace(string(token))))
+	}
+
+	// signed last, so that the signature covers the configured headers
+	if endpoint.SigV4 != nil {
+		if err := s.signers[endpoint.SigV4].sign(ctx, request); err != nil {
+			return nil, &storeerrors.ErrAuthFailed{StoreID: s.GetID(), Err: fmt.Errorf("failed to sign request for %q: %w", endpoint.URL, err)}
+		}
+	}
+
+	response, err := s.clients[endpoint.TLSCACertFile].Do(request)
+	if err != nil {
+		return nil, wrapHTTPStoreError(s.GetID(), 0, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, wrapHTTPStoreError(s.GetID(), 0, fmt.Errorf("failed to read response of %q: %w", endpoint.URL, err))
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, wrapHTTPStoreError(s.GetID(), response.StatusCode, fmt.Errorf("request to %q failed with status %q", endpoint.URL, response.Status))
+	}
+	return body, nil
+}
+
+// wrapHTTPStoreError returns the typed error for a failed request of the HTTP store
+func wrapHTTPStoreError(storeID string, statusCode int, err error) error {
+	if statusCode == http.StatusNotFound {
+		return &storeerrors.ErrKubeconfigNotFound{StoreID: storeID, Err: err}
+	}
+
+	// the timeout of the HTTP client does not wrap context.DeadlineExceeded
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &storeerrors.ErrStoreTimeout{StoreID: storeID, Err: err}
+	}
+	return wrapHTTPError(storeID, statusCode, err)
+}
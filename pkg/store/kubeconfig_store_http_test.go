@@ -0,0 +1,316 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("HTTPStore", func() {
+	var (
+		server        *httptest.Server
+		tokenDir      string
+		signedHeaders http.Header
+	)
+
+	BeforeEach(func() {
+		var err error
+		tokenDir, err = os.MkdirTemp("", "http-store")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(tokenDir, "token"), []byte("secret\n"), 0600)).To(Succeed())
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/static":
+				if r.Method != http.MethodPost || r.Header.Get("X-Team") != "platform" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				_, _ = w.Write([]byte("static"))
+			case "/signed":
+				signedHeaders = r.Header.Clone()
+				_, _ = w.Write([]byte("signed"))
+			case "/slow":
+				time.Sleep(200 * time.Millisecond)
+				_, _ = w.Write([]byte("slow"))
+			case "/list", "/kubeconfigs/dev":
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if r.URL.Path == "/kubeconfigs/dev" {
+					_, _ = w.Write([]byte("dev"))
+					return
+				}
+				_, _ = w.Write([]byte(`[{"path": "kubeconfigs/dev", "name": "dev", "tags": {"env": "dev"}}, {"name": "no-path"}]`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(tokenDir)).To(Succeed())
+	})
+
+	newStore := func(config map[string]interface{}) *store.HTTPStore {
+		s, err := store.NewHTTPStore(types.KubeconfigStore{
+			Kind:   types.StoreKindHTTP,
+			Config: config,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return s
+	}
+
+	search := func(s *store.HTTPStore) []store.SearchResult {
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		return results
+	}
+
+	It("should require an endpoint", func() {
+		_, err := store.NewHTTPStore(types.KubeconfigStore{Kind: types.StoreKindHTTP})
+		Expect(err).To(HaveOccurred())
+
+		_, err = store.NewHTTPStore(types.KubeconfigStore{
+			Kind:   types.StoreKindHTTP,
+			Config: map[string]interface{}{"listEndpoint": "/list"},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fetch the kubeconfig of a configured endpoint", func() {
+		s := newStore(map[string]interface{}{
+			"endpoints": []interface{}{
+				map[string]interface{}{
+					"url":     server.URL + "/static",
+					"method":  "POST",
+					"headers": map[string]interface{}{"X-Team": "platform"},
+				},
+			},
+		})
+
+		results := search(s)
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].KubeconfigPath).To(Equal(server.URL + "/static"))
+		Expect(s.GetContextPrefix(results[0].KubeconfigPath)).To(Equal("127.0.0.1"))
+
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), results[0].KubeconfigPath, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig)).To(Equal("static"))
+	})
+
+	It("should discover kubeconfigs via the list endpoint", func() {
+		s := newStore(map[string]interface{}{
+			"listEndpoint":    server.URL + "/list",
+			"bearerTokenFile": filepath.Join(tokenDir, "token"),
+		})
+
+		results := search(s)
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].KubeconfigPath).To(Equal(server.URL + "/kubeconfigs/dev"))
+		Expect(results[0].Tags).To(Equal(map[string]string{"env": "dev"}))
+		Expect(s.GetContextPrefix(results[0].KubeconfigPath)).To(Equal("dev"))
+
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), results[0].KubeconfigPath, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig)).To(Equal("dev"))
+	})
+
+	It("should only send the headers and the bearer token to the host of the list endpoint", func() {
+		var otherHeaders http.Header
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			otherHeaders = r.Header.Clone()
+			_, _ = w.Write([]byte("other"))
+		}))
+		defer other.Close()
+
+		list := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(fmt.Sprintf(`[{"path": "%s/kubeconfigs/other", "name": "other"}]`, other.URL)))
+		}))
+		defer list.Close()
+
+		s := newStore(map[string]interface{}{
+			"listEndpoint":    list.URL + "/list",
+			"headers":         map[string]interface{}{"X-Team": "platform"},
+			"bearerTokenFile": filepath.Join(tokenDir, "token"),
+		})
+
+		results := search(s)
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].KubeconfigPath).To(Equal(other.URL + "/kubeconfigs/other"))
+
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), results[0].KubeconfigPath, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig)).To(Equal("other"))
+		Expect(otherHeaders).ToNot(HaveKey("Authorization"))
+		Expect(otherHeaders).ToNot(HaveKey("X-Team"))
+	})
+
+	It("should return typed errors", func() {
+		s := newStore(map[string]interface{}{
+			"listEndpoint": server.URL + "/list",
+			"timeout":      "50ms",
+		})
+
+		var authErr *storeerrors.ErrAuthFailed
+		Expect(errors.As(s.Probe(context.Background()), &authErr)).To(BeTrue())
+
+		var notFoundErr *storeerrors.ErrKubeconfigNotFound
+		_, err := s.GetKubeconfigForPath(context.Background(), server.URL+"/missing", nil)
+		Expect(errors.As(err, &notFoundErr)).To(BeTrue())
+
+		var timeoutErr *storeerrors.ErrStoreTimeout
+		_, err = s.GetKubeconfigForPath(context.Background(), server.URL+"/slow", nil)
+		Expect(errors.As(err, &timeoutErr)).To(BeTrue())
+	})
+
+	It("should sign the requests with AWS Signature Version 4", func() {
+		s := newStore(map[string]interface{}{
+			"endpoints": []interface{}{
+				map[string]interface{}{
+					"url":     server.URL + "/signed",
+					"headers": map[string]interface{}{"X-Team": "platform"},
+					"sigV4": map[string]interface{}{
+						"region":          "eu-central-1",
+						"service":         "execute-api",
+						"accessKeyID":     "AKIDEXAMPLE",
+						"secretAccessKey": "secret",
+						"sessionToken":    "session",
+					},
+				},
+			},
+		})
+
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), server.URL+"/signed", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig)).To(Equal("signed"))
+
+		Expect(signedHeaders.Get("X-Amz-Date")).To(MatchRegexp(`^\d{8}T\d{6}Z$`))
+		Expect(signedHeaders.Get("X-Amz-Security-Token")).To(Equal("session"))
+		Expect(signedHeaders.Get("Authorization")).To(MatchRegexp(
+			`^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/\d{8}/eu-central-1/execute-api/aws4_request, SignedHeaders=host;x-amz-date;x-amz-security-token;x-team, Signature=[0-9a-f]{64}$`))
+	})
+
+	It("should only sign the requests to the host of the list endpoint with the sigV4 configuration of the store", func() {
+		var otherHeaders http.Header
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			otherHeaders = r.Header.Clone()
+			_, _ = w.Write([]byte("other"))
+		}))
+		defer other.Close()
+
+		s := newStore(map[string]interface{}{
+			"listEndpoint": server.URL + "/list",
+			"sigV4": map[string]interface{}{
+				"region":          "eu-central-1",
+				"service":         "execute-api",
+				"accessKeyID":     "AKIDEXAMPLE",
+				"secretAccessKey": "secret",
+			},
+		})
+
+		_, err := s.GetKubeconfigForPath(context.Background(), server.URL+"/signed", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signedHeaders.Get("Authorization")).To(HavePrefix("AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+
+		_, err = s.GetKubeconfigForPath(context.Background(), other.URL+"/kubeconfigs/other", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(otherHeaders).ToNot(HaveKey("Authorization"))
+		Expect(otherHeaders).ToNot(HaveKey("X-Amz-Date"))
+	})
+
+	It("should expand environment variables in the sigV4 credentials", func() {
+		Expect(os.Setenv("KUBESWITCH_TEST_ACCESS_KEY_ID", "AKIDFROMENV")).To(Succeed())
+		Expect(os.Setenv("KUBESWITCH_TEST_SECRET_ACCESS_KEY", "secret")).To(Succeed())
+		defer os.Unsetenv("KUBESWITCH_TEST_ACCESS_KEY_ID")
+		defer os.Unsetenv("KUBESWITCH_TEST_SECRET_ACCESS_KEY")
+
+		s := newStore(map[string]interface{}{
+			"endpoints": []interface{}{
+				map[string]interface{}{
+					"url": server.URL + "/signed",
+					"sigV4": map[string]interface{}{
+						"region":          "eu-central-1",
+						"service":         "execute-api",
+						"accessKeyID":     "${KUBESWITCH_TEST_ACCESS_KEY_ID}",
+						"secretAccessKey": "${KUBESWITCH_TEST_SECRET_ACCESS_KEY}",
+					},
+				},
+			},
+		})
+
+		_, err := s.GetKubeconfigForPath(context.Background(), server.URL+"/signed", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(signedHeaders.Get("Authorization")).To(HavePrefix("AWS4-HMAC-SHA256 Credential=AKIDFROMENV/"))
+	})
+
+	It("should require the secret access key if the access key id is set", func() {
+		_, err := store.NewHTTPStore(types.KubeconfigStore{
+			Kind: types.StoreKindHTTP,
+			Config: map[string]interface{}{
+				"listEndpoint": server.URL + "/list",
+				"sigV4": map[string]interface{}{
+					"region":          "eu-central-1",
+					"service":         "execute-api",
+					"accessKeyID":     "AKIDEXAMPLE",
+					"secretAccessKey": "${KUBESWITCH_TEST_UNSET_SECRET_ACCESS_KEY}",
+				},
+			},
+		})
+		Expect(err).To(MatchError(ContainSubstring("the secret access key must be configured")))
+	})
+
+	It("should require the region and the service for AWS Signature Version 4", func() {
+		_, err := store.NewHTTPStore(types.KubeconfigStore{
+			Kind: types.StoreKindHTTP,
+			Config: map[string]interface{}{
+				"listEndpoint": server.URL + "/list",
+				"sigV4":        map[string]interface{}{"service": "execute-api", "accessKeyID": "AKIDEXAMPLE", "secretAccessKey": "secret"},
+			},
+		})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should send the requests via the configured proxy", func() {
+		s := newStore(map[string]interface{}{
+			"endpoints": []interface{}{
+				map[string]interface{}{"url": "http://kubeconfigs.internal/kubeconfigs/dev"},
+			},
+			"proxyURL": server.URL,
+		})
+
+		// the test server acting as proxy serves the path, but rejects the request without bearer token
+		_, err := s.GetKubeconfigForPath(context.Background(), "http://kubeconfigs.internal/kubeconfigs/dev", nil)
+		var authErr *storeerrors.ErrAuthFailed
+		Expect(errors.As(err, &authErr)).To(BeTrue())
+	})
+})
@@ -15,12 +15,17 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/disiqueira/gotree"
 	"github.com/ovh/go-ovh/ovh"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
 	"github.com/danielfoehrkn/kubeswitch/types"
 )
 
@@ -29,26 +34,26 @@ func NewOVHStore(store types.KubeconfigStore) (*OVHStore, error) {
 	if store.Config != nil {
 		buf, err := yaml.Marshal(store.Config)
 		if err != nil {
-			return nil, fmt.Errorf("failed to process OVH store config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to process OVH store config: %w", err))
 		}
 
 		err = yaml.Unmarshal(buf, ovhStoreConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal OVH config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to unmarshal OVH config: %w", err))
 		}
 	}
 
 	ovhApplicationKey := ovhStoreConfig.OVHApplicationKey
 	if len(ovhApplicationKey) == 0 {
-		return nil, fmt.Errorf("When using the OVH kubeconfig store, the application key for OVH has to be provided via a SwitchConfig file")
+		return nil, invalidConfig(store, fmt.Errorf("When using the OVH kubeconfig store, the application key for OVH has to be provided via a SwitchConfig file"))
 	}
 	ovhApplicationSecret := ovhStoreConfig.OVHApplicationSecret
 	if len(ovhApplicationSecret) == 0 {
-		return nil, fmt.Errorf("When using the OVH kubeconfig store, the application secret for OVH has to be provided via a SwitchConfig file")
+		return nil, invalidConfig(store, fmt.Errorf("When using the OVH kubeconfig store, the application secret for OVH has to be provided via a SwitchConfig file"))
 	}
 	ovhConsumerKey := ovhStoreConfig.OVHConsumerKey
 	if len(ovhConsumerKey) == 0 {
-		return nil, fmt.Errorf("When using the OVH kubeconfig store, the consumer key for OVH has to be provided via a SwitchConfig file")
+		return nil, invalidConfig(store, fmt.Errorf("When using the OVH kubeconfig store, the consumer key for OVH has to be provided via a SwitchConfig file"))
 	}
 	ovhEndpoint := ovhStoreConfig.OVHEndpoint
 	if len(ovhEndpoint) == 0 {
@@ -57,7 +62,7 @@ func NewOVHStore(store types.KubeconfigStore) (*OVHStore, error) {
 
 	ovhClient, err := ovh.NewClient(ovhEndpoint, ovhApplicationKey, ovhApplicationSecret, ovhConsumerKey)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to initialize OVH client due to error: %w", err)
+		return nil, &storeerrors.ErrAuthFailed{StoreID: storeIDForConfig(store), Err: fmt.Errorf("Failed to initialize OVH client due to error: %w", err)}
 	}
 
 	return &OVHStore{
@@ -68,10 +73,23 @@ func NewOVHStore(store types.KubeconfigStore) (*OVHStore, error) {
 	}, nil
 }
 
+const (
+	// tagOVHProject is the tag that contains the ID of the OVH project of the cluster
+	tagOVHProject = "project"
+	// tagOVHClusterID is the tag that contains the ID of the cluster
+	tagOVHClusterID = "id"
+)
+
 type OVHKube struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Project string
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	Version      string `json:"version"`
+	Region       string `json:"region"`
+	UpdatePolicy string `json:"updatePolicy"`
+	Project      string
+	// Nodes is the number of nodes of the cluster. Only fetched for the search preview, nil if unknown.
+	Nodes *int
 }
 
 func (r *OVHStore) GetID() string {
@@ -106,75 +124,184 @@ func (r *OVHStore) GetLogger() *logrus.Entry {
 	return r.Logger
 }
 
-func (r *OVHStore) StartSearch(channel chan SearchResult) {
+// Stop releases the clusters cached for the search preview and closes the idle connections of the OVH client
+func (r *OVHStore) Stop(_ context.Context) error {
+	r.OVHKubeCacheMutex.Lock()
+	clear(r.OVHKubeCache)
+	r.OVHKubeCacheMutex.Unlock()
+
+	if r.Client != nil && r.Client.Client != nil {
+		r.Client.Client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (r *OVHStore) StartSearch(ctx context.Context, channel chan SearchResult) {
 	r.Logger.Debug("OVH: start search")
 
 	projects := []string{}
 	// list OVH projects
-	err := r.Client.Get("/cloud/project", &projects)
+	err := r.Client.GetWithContext(ctx, "/cloud/project", &projects)
 	if err != nil {
-		channel <- SearchResult{
+		sendSearchResult(ctx, channel, SearchResult{
 			KubeconfigPath: "",
 			Error:          err,
-		}
+		})
 		return
 	}
 
 	// for each project, list Kubernetes cluster
 	for _, project := range projects {
 		clustersID := []string{}
-		err := r.Client.Get(fmt.Sprintf("/cloud/project/%v/kube", project), &clustersID)
+		err := r.Client.GetWithContext(ctx, fmt.Sprintf("/cloud/project/%v/kube", project), &clustersID)
 		if err != nil {
-			channel <- SearchResult{
+			sendSearchResult(ctx, channel, SearchResult{
 				KubeconfigPath: "",
 				Error:          err,
-			}
+			})
 			return
 		}
 
 		for _, id := range clustersID {
 			var kube OVHKube
-			err := r.Client.Get(fmt.Sprintf("/cloud/project/%v/kube/%v", project, id), &kube)
+			err := r.Client.GetWithContext(ctx, fmt.Sprintf("/cloud/project/%v/kube/%v", project, id), &kube)
 			if err != nil {
-				channel <- SearchResult{
+				sendSearchResult(ctx, channel, SearchResult{
 					KubeconfigPath: "",
 					Error:          err,
-				}
+				})
 				return
 			}
 			kube.Project = project
-			r.OVHKubeCache[kube.ID] = kube
+			r.insertIntoClusterCache(kube)
 
-			channel <- SearchResult{
+			if !sendSearchResult(ctx, channel, SearchResult{
 				KubeconfigPath: kube.Name,
-				Error:          nil,
+				Tags: map[string]string{
+					tagOVHProject:   project,
+					tagOVHClusterID: kube.ID,
+				},
+				Error: nil,
+			}) {
+				return
 			}
 		}
 
 	}
 }
 
-func (r *OVHStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byte, error) {
+func (r *OVHStore) GetKubeconfigForPath(ctx context.Context, path string, _ map[string]string) ([]byte, error) {
 	r.Logger.Debugf("OVH: getting secret for path %q", path)
 
-	var cluster OVHKube
-	for _, c := range r.OVHKubeCache {
-		if c.Name == path {
-			cluster = c
-		}
-	}
+	cluster, _ := r.readFromClusterCache(path)
 
 	response := struct {
 		Content string `json:"content"`
 	}{}
-	err := r.Client.Post(fmt.Sprintf("/cloud/project/%v/kube/%v/kubeconfig", cluster.Project, cluster.ID), nil, &response)
+	if len(cluster.ID) == 0 {
+		return nil, &storeerrors.ErrClusterNotFound{StoreID: r.GetID(), Err: fmt.Errorf("cluster %q not found", path)}
+	}
+
+	err := r.Client.PostWithContext(ctx, fmt.Sprintf("/cloud/project/%v/kube/%v/kubeconfig", cluster.Project, cluster.ID), nil, &response)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get kubeconfig for cluster '%s': %w", path, err)
+		return nil, wrapOVHError(r.GetID(), fmt.Errorf("failed to get kubeconfig for cluster '%s': %w", path, err))
 	}
 	return []byte(response.Content), nil
 
 }
 
-func (r *OVHStore) VerifyKubeconfigPaths() error {
+// GetSearchPreview shows the status, version, region, number of nodes and update policy of the cluster.
+// Clusters which have not been discovered yet, e.g. when a search index is used, are fetched from the OVH API
+// using the project and cluster ID in the tags.
+func (r *OVHStore) GetSearchPreview(path string, tags map[string]string) (string, error) {
+	// low timeout to not pile up many requests, but timeout fast
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	cluster, ok := r.readFromClusterCache(path)
+	if !ok {
+		project, clusterID := tags[tagOVHProject], tags[tagOVHClusterID]
+		if len(project) == 0 || len(clusterID) == 0 {
+			return "", &storeerrors.ErrClusterNotFound{StoreID: r.GetID(), Err: fmt.Errorf("cluster %q has not been discovered yet", path)}
+		}
+
+		if err := r.Client.GetWithContext(ctx, fmt.Sprintf("/cloud/project/%v/kube/%v", project, clusterID), &cluster); err != nil {
+			return "", wrapOVHError(r.GetID(), fmt.Errorf("failed to get cluster %q: %w", path, err))
+		}
+		cluster.Project = project
+		r.insertIntoClusterCache(cluster)
+	}
+
+	if cluster.Nodes == nil {
+		var nodes []struct {
+			ID string `json:"id"`
+		}
+		if err := r.Client.GetWithContext(ctx, fmt.Sprintf("/cloud/project/%v/kube/%v/node", cluster.Project, cluster.ID), &nodes); err != nil {
+			// still show the cluster details
+			r.Logger.Debugf("failed to list the nodes of cluster %q: %v", path, err)
+		} else {
+			count := len(nodes)
+			cluster.Nodes = &count
+			r.insertIntoClusterCache(cluster)
+		}
+	}
+
+	asciTree := gotree.New(cluster.Name)
+	asciTree.Add(fmt.Sprintf("Status: %s", cluster.Status))
+	asciTree.Add(fmt.Sprintf("Kubernetes Version: %s", cluster.Version))
+	asciTree.Add(fmt.Sprintf("Region: %s", cluster.Region))
+	if cluster.Nodes != nil {
+		asciTree.Add(fmt.Sprintf("Nodes: %d", *cluster.Nodes))
+	}
+	asciTree.Add(fmt.Sprintf("Update Policy: %s", cluster.UpdatePolicy))
+	asciTree.Add(fmt.Sprintf("Project: %s", cluster.Project))
+
+	return asciTree.Print(), nil
+}
+
+// readFromClusterCache returns the discovered cluster with the given name
+func (r *OVHStore) readFromClusterCache(name string) (OVHKube, bool) {
+	r.OVHKubeCacheMutex.RLock()
+	defer r.OVHKubeCacheMutex.RUnlock()
+	for _, cluster := range r.OVHKubeCache {
+		if cluster.Name == name {
+			return cluster, true
+		}
+	}
+	return OVHKube{}, false
+}
+
+// insertIntoClusterCache adds the cluster to the discovered clusters, keyed by its ID
+func (r *OVHStore) insertIntoClusterCache(cluster OVHKube) {
+	r.OVHKubeCacheMutex.Lock()
+	defer r.OVHKubeCacheMutex.Unlock()
+	if r.OVHKubeCache == nil {
+		r.OVHKubeCache = make(map[string]OVHKube)
+	}
+	r.OVHKubeCache[cluster.ID] = cluster
+}
+
+func (r *OVHStore) VerifyKubeconfigPaths(ctx context.Context) error {
 	return nil
 }
+
+// Probe checks that the OVH API is reachable by listing the cloud projects
+func (r *OVHStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	projects := []string{}
+	if err := r.Client.GetWithContext(ctx, "/cloud/project", &projects); err != nil {
+		return wrapOVHError(r.GetID(), fmt.Errorf("failed to list OVH projects: %w", err))
+	}
+	return nil
+}
+
+// wrapOVHError returns the typed error for a failed request against the OVH API
+func wrapOVHError(storeID string, err error) error {
+	var apiError *ovh.APIError
+	if errors.As(err, &apiError) {
+		return wrapHTTPError(storeID, apiError.Code, err)
+	}
+	return wrapTimeout(storeID, err)
+}
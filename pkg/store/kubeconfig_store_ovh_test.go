@@ -0,0 +1,116 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/ovh/go-ovh/ovh"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+)
+
+var _ = Describe("OVHStore", func() {
+	var (
+		server *httptest.Server
+		s      *store.OVHStore
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/auth/time":
+				_, _ = w.Write([]byte(fmt.Sprint(time.Now().Unix())))
+			case "/cloud/project":
+				_, _ = w.Write([]byte(`["project"]`))
+			case "/cloud/project/project/kube":
+				_, _ = w.Write([]byte(`["cluster", "staging"]`))
+			case "/cloud/project/project/kube/staging":
+				_, _ = w.Write([]byte(`{"id": "staging", "name": "staging", "status": "READY"}`))
+			case "/cloud/project/project/kube/cluster":
+				_, _ = w.Write([]byte(`{"id": "cluster", "name": "prod", "status": "READY", "version": "1.30", "region": "GRA7", "updatePolicy": "ALWAYS_UPDATE"}`))
+			case "/cloud/project/project/kube/cluster/node":
+				_, _ = w.Write([]byte(`[{"id": "a"}, {"id": "b"}]`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		client, err := ovh.NewClient(server.URL, "key", "secret", "consumer")
+		Expect(err).ToNot(HaveOccurred())
+
+		// the cluster cache is intentionally not initialized
+		s = &store.OVHStore{Logger: testutil.NewTestLogger(), Client: client}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should fetch clusters which have not been discovered for the preview", func() {
+		preview, err := s.GetSearchPreview("prod", map[string]string{"project": "project", "id": "cluster"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview).To(ContainSubstring("Status: READY"))
+		Expect(preview).To(ContainSubstring("Kubernetes Version: 1.30"))
+		Expect(preview).To(ContainSubstring("Region: GRA7"))
+		Expect(preview).To(ContainSubstring("Nodes: 2"))
+		Expect(preview).To(ContainSubstring("Update Policy: ALWAYS_UPDATE"))
+	})
+
+	It("should discover the clusters of all projects", func() {
+		s.OVHKubeCache = make(map[string]store.OVHKube)
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(ConsistOf(
+			testutil.FakeSearchResult("prod", "project=project", "id=cluster"),
+			testutil.FakeSearchResult("staging", "project=project", "id=staging"),
+		))
+	})
+
+	It("should stop the search once cancelled", func() {
+		s.OVHKubeCache = make(map[string]store.OVHKube)
+		Expect(testutil.CancelSearch(s, 1, time.Second)).To(Succeed())
+	})
+
+	It("should not wait for the OVH API once the search is cancelled", func() {
+		// only the server time used to sign the requests is returned
+		unresponsiveServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/auth/time" {
+				_, _ = w.Write([]byte(fmt.Sprint(time.Now().Unix())))
+				return
+			}
+			<-r.Context().Done()
+		}))
+		defer unresponsiveServer.Close()
+
+		client, err := ovh.NewClient(unresponsiveServer.URL, "key", "secret", "consumer")
+		Expect(err).ToNot(HaveOccurred())
+
+		s = &store.OVHStore{Logger: testutil.NewTestLogger(), Client: client, OVHKubeCache: make(map[string]store.OVHKube)}
+		Expect(testutil.CancelSearch(s, 0, time.Second)).To(Succeed())
+	})
+
+	It("should fail for clusters without tags which have not been discovered", func() {
+		_, err := s.GetSearchPreview("prod", nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -15,9 +15,17 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/disiqueira/gotree"
 	"github.com/rancher/norman/clientbase"
+	normantypes "github.com/rancher/norman/types"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 
@@ -30,32 +38,35 @@ func NewRancherStore(store types.KubeconfigStore) (*RancherStore, error) {
 	if store.Config != nil {
 		buf, err := yaml.Marshal(store.Config)
 		if err != nil {
-			return nil, fmt.Errorf("failed to process Rancher store config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to process Rancher store config: %w", err))
 		}
 
 		err = yaml.Unmarshal(buf, rancherStoreConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal Rancher config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to unmarshal Rancher config: %w", err))
 		}
 	}
 
 	rancherAPIAddress := rancherStoreConfig.RancherAPIAddress
 	if len(rancherAPIAddress) == 0 {
-		return nil, fmt.Errorf("when using the Rancher kubeconfig store, the address of Rancher has to be provided via SwitchConfig file")
+		return nil, invalidConfig(store, fmt.Errorf("when using the Rancher kubeconfig store, the address of Rancher has to be provided via SwitchConfig file"))
 	}
 
 	rancherToken := rancherStoreConfig.RancherToken
 	if len(rancherToken) == 0 {
-		return nil, fmt.Errorf("when using the Rancher kubeconfig store, a Rancher API token must be provided via SwitchConfig file")
+		return nil, invalidConfig(store, fmt.Errorf("when using the Rancher kubeconfig store, a Rancher API token must be provided via SwitchConfig file"))
 	}
 
 	return &RancherStore{
 		Logger:          logrus.New().WithField("store", types.StoreKindRancher),
 		KubeconfigStore: store,
 		ClientOpts: &clientbase.ClientOpts{
-			URL:      rancherAPIAddress,
-			TokenKey: rancherToken,
+			URL:        rancherAPIAddress,
+			TokenKey:   rancherToken,
+			HTTPClient: &http.Client{},
 		},
+		ConnectionPooling: rancherStoreConfig.ConnectionPooling,
+		ClusterCache:      make(map[string]*managementClient.Cluster),
 	}, nil
 }
 
@@ -86,43 +97,70 @@ func (r *RancherStore) GetLogger() *logrus.Entry {
 	return r.Logger
 }
 
+// Stop releases the clusters cached for the search preview and closes the idle connections of the Rancher client
+func (r *RancherStore) Stop(_ context.Context) error {
+	r.ClusterCacheMutex.Lock()
+	clear(r.ClusterCache)
+	r.ClusterCacheMutex.Unlock()
+
+	if r.transport != nil {
+		r.transport.CloseIdleConnections()
+	}
+	return nil
+}
+
 // initClient initializes the Rancher client
 // It is called once at the beginning of the search and every time a kubenfig is requested
 // It is a NOOP if the client is already initialized
-func (r *RancherStore) initClient() error {
+func (r *RancherStore) initClient(ctx context.Context) error {
 	if r.Client != nil { // already initialized
 		return nil
 	}
 
-	client, err := managementClient.NewClient(r.ClientOpts)
+	// creating the client requests the API schemas, but does not accept a context
+	client, err := callWithContext(ctx, func() (*managementClient.Client, error) {
+		return managementClient.NewClient(r.ClientOpts)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create Rancher client: %w", err)
+		return wrapRancherError(r.GetID(), fmt.Errorf("failed to create Rancher client: %w", err))
+	}
+
+	// the Rancher client replaces the transport of the HTTP client when it is created
+	if r.ClientOpts.HTTPClient != nil {
+		if transport, ok := r.ClientOpts.HTTPClient.Transport.(*http.Transport); ok {
+			configureConnectionPooling(transport, r.ConnectionPooling)
+			r.transport = transport
+		}
+		r.ClientOpts.HTTPClient.Transport = wrapTransport(r.ClientOpts.HTTPClient.Transport)
 	}
 
 	r.Client = client
 	return nil
 }
 
-func (r *RancherStore) StartSearch(channel chan SearchResult) {
+func (r *RancherStore) StartSearch(ctx context.Context, channel chan SearchResult) {
 	r.Logger.Debug("Rancher: start search")
 
-	if err := r.initClient(); err != nil {
-		channel <- SearchResult{
+	if err := r.initClient(ctx); err != nil {
+		sendSearchResult(ctx, channel, SearchResult{
 			KubeconfigPath: "",
 			Error:          fmt.Errorf("failed to initialize Rancher client: %w", err),
-		}
+		})
 		return
 	}
 
-	cluster, err := r.Client.Cluster.ListAll(nil)
+	// the Rancher client does not accept a context
+	cluster, err := callWithContext(ctx, func() (*managementClient.ClusterCollection, error) {
+		return r.Client.Cluster.ListAll(nil)
+	})
 	if err != nil {
-		channel <- SearchResult{
+		sendSearchResult(ctx, channel, SearchResult{
 			KubeconfigPath: "",
-			Error:          err,
-		}
+			Error:          wrapRancherError(r.GetID(), err),
+		})
 		return
 	}
-	for _, v := range cluster.Data {
+	for i, v := range cluster.Data {
 		id := v.ID
 		if id == "local" {
 			// rancher uses "local" as id for its base cluster
@@ -130,38 +168,149 @@ func (r *RancherStore) StartSearch(channel chan SearchResult) {
 			// As a workaround the id of the store is used for the local cluster
 			id = r.GetID()
 		}
-		channel <- SearchResult{
+		r.insertIntoClusterCache(id, &cluster.Data[i])
+		if !sendSearchResult(ctx, channel, SearchResult{
 			KubeconfigPath: id,
 			Error:          nil,
+		}) {
+			return
 		}
 	}
 }
 
-func (r *RancherStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byte, error) {
+func (r *RancherStore) GetKubeconfigForPath(ctx context.Context, path string, _ map[string]string) ([]byte, error) {
 	r.Logger.Debugf("Rancher: getting secret for path %q", path)
 
-	if err := r.initClient(); err != nil {
+	if err := r.initClient(ctx); err != nil {
 		return nil, fmt.Errorf("failed to initialize Rancher client: %w", err)
 	}
 
-	clusterID := path
-	if clusterID == r.GetID() {
-		// local cluster was replaced in StartSearch; restore original id
-		clusterID = "local"
-	}
-
-	cluster, err := r.Client.Cluster.ByID(clusterID)
+	cluster, err := callWithContext(ctx, func() (*managementClient.Cluster, error) {
+		return r.Client.Cluster.ByID(r.getClusterID(path))
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cluster '%s': %w", path, err)
+		return nil, wrapRancherError(r.GetID(), fmt.Errorf("failed to get cluster '%s': %w", path, err))
 	}
 
-	kubeconfig, err := r.Client.Cluster.ActionGenerateKubeconfig(cluster)
+	kubeconfig, err := callWithContext(ctx, func() (*managementClient.GenerateKubeConfigOutput, error) {
+		return r.Client.Cluster.ActionGenerateKubeconfig(cluster)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get kubeconfig for cluster '%s': %w", path, err)
+		return nil, wrapRancherError(r.GetID(), fmt.Errorf("failed to get kubeconfig for cluster '%s': %w", path, err))
 	}
 	return []byte(kubeconfig.Config), nil
 }
 
-func (r *RancherStore) VerifyKubeconfigPaths() error {
+// GetSearchPreview shows the name, provider, version, state and node count of the cluster together with its Rancher annotations.
+// Clusters which have not been discovered yet, e.g. when a search index is used, are fetched from the Rancher API.
+func (r *RancherStore) GetSearchPreview(path string, _ map[string]string) (string, error) {
+	cluster := r.readFromClusterCache(path)
+	if cluster == nil {
+		// low timeout to not pile up many requests, but timeout fast
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		if err := r.initClient(ctx); err != nil {
+			return "", fmt.Errorf("failed to initialize Rancher client: %w", err)
+		}
+
+		var err error
+		cluster, err = callWithContext(ctx, func() (*managementClient.Cluster, error) {
+			return r.Client.Cluster.ByID(r.getClusterID(path))
+		})
+		if err != nil {
+			return "", wrapRancherError(r.GetID(), fmt.Errorf("failed to get cluster '%s': %w", path, err))
+		}
+		r.insertIntoClusterCache(path, cluster)
+	}
+
+	asciTree := gotree.New(cluster.Name)
+
+	if strings.EqualFold(cluster.State, "error") || cluster.Transitioning == "error" {
+		asciTree.Add(fmt.Sprintf("WARNING: cluster is in error state: %s", cluster.TransitioningMessage))
+	}
+
+	provider := cluster.Provider
+	if len(provider) == 0 {
+		provider = cluster.Driver
+	}
+	if len(provider) > 0 {
+		asciTree.Add(fmt.Sprintf("Provider: %s", provider))
+	}
+
+	if cluster.Version != nil {
+		asciTree.Add(fmt.Sprintf("Kubernetes Version: %s", cluster.Version.GitVersion))
+	}
+	asciTree.Add(fmt.Sprintf("State: %s", cluster.State))
+	asciTree.Add(fmt.Sprintf("Nodes: %d", cluster.NodeCount))
+
+	var annotationKeys []string
+	for key := range cluster.Annotations {
+		if strings.Contains(key, "cattle.io/") {
+			annotationKeys = append(annotationKeys, key)
+		}
+	}
+	if len(annotationKeys) > 0 {
+		sort.Strings(annotationKeys)
+		annotations := asciTree.Add("Annotations")
+		for _, key := range annotationKeys {
+			annotations.Add(fmt.Sprintf("%s: %s", key, cluster.Annotations[key]))
+		}
+	}
+
+	return asciTree.Print(), nil
+}
+
+// getClusterID returns the Rancher cluster ID for the kubeconfig path
+func (r *RancherStore) getClusterID(path string) string {
+	if path == r.GetID() {
+		// local cluster was replaced in StartSearch; restore original id
+		return "local"
+	}
+	return path
+}
+
+// readFromClusterCache returns the cluster for the kubeconfig path or nil if the cluster has not been discovered yet
+func (r *RancherStore) readFromClusterCache(path string) *managementClient.Cluster {
+	r.ClusterCacheMutex.RLock()
+	defer r.ClusterCacheMutex.RUnlock()
+	return r.ClusterCache[path]
+}
+
+// insertIntoClusterCache adds the cluster for the kubeconfig path to the cache
+func (r *RancherStore) insertIntoClusterCache(path string, cluster *managementClient.Cluster) {
+	r.ClusterCacheMutex.Lock()
+	defer r.ClusterCacheMutex.Unlock()
+	if r.ClusterCache == nil {
+		r.ClusterCache = make(map[string]*managementClient.Cluster)
+	}
+	r.ClusterCache[path] = cluster
+}
+
+func (r *RancherStore) VerifyKubeconfigPaths(ctx context.Context) error {
 	return nil
 }
+
+// Probe checks that the Rancher API is reachable by listing a single cluster
+func (r *RancherStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	if err := r.initClient(ctx); err != nil {
+		return fmt.Errorf("failed to initialize Rancher client: %w", err)
+	}
+
+	return wrapRancherError(r.GetID(), probeAsync(ctx, func() error {
+		_, err := r.Client.Cluster.List(&normantypes.ListOpts{Filters: map[string]interface{}{"limit": 1}})
+		return err
+	}))
+}
+
+// wrapRancherError returns the typed error for a failed request against the Rancher API
+func wrapRancherError(storeID string, err error) error {
+	var apiError *clientbase.APIError
+	if errors.As(err, &apiError) {
+		return wrapHTTPError(storeID, apiError.StatusCode, err)
+	}
+	return wrapTimeout(storeID, err)
+}
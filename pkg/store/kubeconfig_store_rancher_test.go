@@ -0,0 +1,88 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	managementClient "github.com/rancher/rancher/pkg/client/generated/management/v3"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("RancherStore", func() {
+	var s *store.RancherStore
+
+	BeforeEach(func() {
+		s = &store.RancherStore{
+			Logger: testutil.NewTestLogger(),
+			ClusterCache: map[string]*managementClient.Cluster{
+				"c-abc": {
+					Name:      "prod",
+					Provider:  "eks",
+					State:     "active",
+					NodeCount: 3,
+					Version:   &managementClient.Info{GitVersion: "v1.30.2"},
+					Annotations: map[string]string{
+						"authz.management.cattle.io/creator-role-bindings": "{}",
+						"unrelated": "value",
+					},
+				},
+				"c-def": {
+					Name:                 "broken",
+					Driver:               "rke2",
+					State:                "error",
+					TransitioningMessage: "etcd is unhealthy",
+				},
+			},
+		}
+	})
+
+	It("should show the cluster details in the preview", func() {
+		preview, err := s.GetSearchPreview("c-abc", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview).To(ContainSubstring("prod"))
+		Expect(preview).To(ContainSubstring("Provider: eks"))
+		Expect(preview).To(ContainSubstring("Kubernetes Version: v1.30.2"))
+		Expect(preview).To(ContainSubstring("State: active"))
+		Expect(preview).To(ContainSubstring("Nodes: 3"))
+		Expect(preview).To(ContainSubstring("authz.management.cattle.io/creator-role-bindings: {}"))
+		Expect(preview).ToNot(ContainSubstring("unrelated"))
+		Expect(preview).ToNot(ContainSubstring("WARNING"))
+	})
+
+	It("should not wait for Rancher once the search is cancelled", func() {
+		unresponsiveServer, closeServer := newUnresponsiveServer()
+		defer closeServer()
+
+		s, err := store.NewRancherStore(types.KubeconfigStore{
+			Kind:   types.StoreKindRancher,
+			Config: map[string]interface{}{"rancherAPIAddress": unresponsiveServer.URL + "/v3", "rancherToken": "token"},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(testutil.CancelSearch(s, 0, time.Second)).To(Succeed())
+	})
+
+	It("should warn about clusters in error state", func() {
+		preview, err := s.GetSearchPreview("c-def", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview).To(ContainSubstring("Provider: rke2"))
+		Expect(preview).To(ContainSubstring("WARNING: cluster is in error state: etcd is unhealthy"))
+	})
+})
@@ -15,14 +15,21 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
+	"github.com/disiqueira/gotree"
 	"github.com/scaleway/scaleway-sdk-go/api/account/v3"
 	"github.com/scaleway/scaleway-sdk-go/api/k8s/v1"
 	"github.com/scaleway/scaleway-sdk-go/scw"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
 	"github.com/danielfoehrkn/kubeswitch/types"
 )
 
@@ -31,27 +38,27 @@ func NewScalewayStore(store types.KubeconfigStore) (*ScalewayStore, error) {
 	if store.Config != nil {
 		buf, err := yaml.Marshal(store.Config)
 		if err != nil {
-			return nil, fmt.Errorf("failed to process Scaleway store config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to process Scaleway store config: %w", err))
 		}
 
 		err = yaml.Unmarshal(buf, scalewayStoreConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal Scaleway config: %w", err)
+			return nil, invalidConfig(store, fmt.Errorf("failed to unmarshal Scaleway config: %w", err))
 		}
 	}
 	logger := logrus.New().WithField("store", types.StoreKindScaleway)
 
 	scalewayAccessKey := scalewayStoreConfig.ScalewayAccessKey
 	if len(scalewayAccessKey) == 0 {
-		return nil, fmt.Errorf("When using the Scaleway kubeconfig store, the access key for Scaleway has to be provided via a SwitchConfig file")
+		return nil, invalidConfig(store, fmt.Errorf("When using the Scaleway kubeconfig store, the access key for Scaleway has to be provided via a SwitchConfig file"))
 	}
 	scalewayOrganizationID := scalewayStoreConfig.ScalewayOrganizationID
 	if len(scalewayOrganizationID) == 0 {
-		return nil, fmt.Errorf("When using the Scaleway kubeconfig store, the organization ID for Scaleway has to be provided via a SwitchConfig file")
+		return nil, invalidConfig(store, fmt.Errorf("When using the Scaleway kubeconfig store, the organization ID for Scaleway has to be provided via a SwitchConfig file"))
 	}
 	scalewaySecretKey := scalewayStoreConfig.ScalewaySecretKey
 	if len(scalewaySecretKey) == 0 {
-		return nil, fmt.Errorf("When using the Scaleway kubeconfig store, the secret key for Scaleway has to be provided via a SwitchConfig file")
+		return nil, invalidConfig(store, fmt.Errorf("When using the Scaleway kubeconfig store, the secret key for Scaleway has to be provided via a SwitchConfig file"))
 	}
 	scalewayRegion := scalewayStoreConfig.ScalewayRegion
 	if len(scalewayRegion) == 0 {
@@ -59,13 +66,20 @@ func NewScalewayStore(store types.KubeconfigStore) (*ScalewayStore, error) {
 		scalewayRegion = "fr-par"
 	}
 
+	// the same timeouts as the default HTTP client of the Scaleway SDK, whose connections cannot be closed
+	transport := cloneDefaultTransport()
+	transport.TLSHandshakeTimeout = 5 * time.Second
+	transport.ResponseHeaderTimeout = 30 * time.Second
+	transport.MaxIdleConnsPerHost = 20
+
 	client, err := scw.NewClient(
 		scw.WithDefaultOrganizationID(scalewayOrganizationID),
 		scw.WithAuth(scalewayAccessKey, scalewaySecretKey),
 		scw.WithDefaultRegion(scw.Region(scalewayRegion)),
+		scw.WithHTTPClient(&http.Client{Timeout: 30 * time.Second, Transport: transport}),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to initialize Scaleway client due to error: %w", err)
+		return nil, &storeerrors.ErrAuthFailed{StoreID: storeIDForConfig(store), Err: fmt.Errorf("Failed to initialize Scaleway client due to error: %w", err)}
 	}
 
 	return &ScalewayStore{
@@ -73,6 +87,7 @@ func NewScalewayStore(store types.KubeconfigStore) (*ScalewayStore, error) {
 		KubeconfigStore:    store,
 		Client:             client,
 		DiscoveredClusters: make(map[string]ScalewayKube),
+		transport:          transport,
 	}, nil
 }
 
@@ -80,6 +95,10 @@ type ScalewayKube struct {
 	ID      string
 	Name    string
 	Project string
+	// Cluster contains the details of the cluster shown in the search preview
+	Cluster *k8s.Cluster
+	// Pools are the node pools of the cluster. They are only fetched for the search preview.
+	Pools []*k8s.Pool
 }
 
 func (s *ScalewayStore) GetID() string {
@@ -114,45 +133,58 @@ func (s *ScalewayStore) GetLogger() *logrus.Entry {
 	return s.Logger
 }
 
-func (s *ScalewayStore) StartSearch(channel chan SearchResult) {
+// Stop releases the clusters discovered by the search and closes the idle connections of the Scaleway client
+func (s *ScalewayStore) Stop(_ context.Context) error {
+	s.DiscoveredClustersMutex.Lock()
+	clear(s.DiscoveredClusters)
+	s.DiscoveredClustersMutex.Unlock()
+
+	if s.transport != nil {
+		s.transport.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (s *ScalewayStore) StartSearch(ctx context.Context, channel chan SearchResult) {
 	s.Logger.Debug("Scaleway: start search")
 
 	papi := account.NewProjectAPI(s.Client)
 	if papi == nil {
-		channel <- SearchResult{
+		sendSearchResult(ctx, channel, SearchResult{
 			KubeconfigPath: "",
 			Error:          fmt.Errorf("Failed to create scaleway project API"),
-		}
+		})
 		return
 	}
 	pres, err := papi.ListProjects(
 		&account.ProjectAPIListProjectsRequest{},
+		scw.WithContext(ctx),
 	)
 	if err != nil {
-		channel <- SearchResult{
+		sendSearchResult(ctx, channel, SearchResult{
 			KubeconfigPath: "",
 			Error:          fmt.Errorf("Could no list projects in Scaleway err: %w", err),
-		}
+		})
 		return
 	}
 	// list projects
 
 	kapi := k8s.NewAPI(s.Client)
 	if kapi == nil {
-		channel <- SearchResult{
+		sendSearchResult(ctx, channel, SearchResult{
 			KubeconfigPath: "",
 			Error:          fmt.Errorf("Failed to create Kubernetes API instance for scaleway err: %w", err),
-		}
+		})
 		return
 	}
 
 	for _, project := range pres.Projects {
-		cres, err := kapi.ListClusters(&k8s.ListClustersRequest{ProjectID: &project.ID})
+		cres, err := kapi.ListClusters(&k8s.ListClustersRequest{ProjectID: &project.ID}, scw.WithContext(ctx))
 		if err != nil {
-			channel <- SearchResult{
+			sendSearchResult(ctx, channel, SearchResult{
 				KubeconfigPath: "",
 				Error:          fmt.Errorf("Failed to retrieve Kubernetes cluster for project %v err: %w", project.Name, err),
-			}
+			})
 			return
 		}
 		if cres.TotalCount == 0 {
@@ -160,36 +192,165 @@ func (s *ScalewayStore) StartSearch(channel chan SearchResult) {
 			continue
 		}
 		for _, cluster := range cres.Clusters {
-			s.DiscoveredClusters[cluster.ID] = ScalewayKube{ID: cluster.ID, Name: cluster.Name, Project: project.ID}
-			channel <- SearchResult{
+			s.insertIntoClusterCache(ScalewayKube{ID: cluster.ID, Name: cluster.Name, Project: project.ID, Cluster: cluster})
+			if !sendSearchResult(ctx, channel, SearchResult{
 				KubeconfigPath: cluster.Name,
 				Error:          nil,
+			}) {
+				return
 			}
 		}
 	}
 }
 
-func (s *ScalewayStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byte, error) {
+func (s *ScalewayStore) GetKubeconfigForPath(ctx context.Context, path string, _ map[string]string) ([]byte, error) {
 	s.Logger.Debugf("Scaleway: getting secret for path %q", path)
 
-	var cluster ScalewayKube
-	for _, c := range s.DiscoveredClusters {
-		if c.Name == path {
-			cluster = c
-		}
+	cluster, ok := s.readFromClusterCache(path)
+	if !ok {
+		return nil, &storeerrors.ErrClusterNotFound{StoreID: s.GetID(), Err: fmt.Errorf("cluster %q not found", path)}
 	}
 
 	kapi := k8s.NewAPI(s.Client)
 
 	config, err := kapi.GetClusterKubeConfig(&k8s.GetClusterKubeConfigRequest{
 		ClusterID: cluster.ID,
-	})
+	}, scw.WithContext(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get kubeconfig for cluster '%s': %w", path, err)
+		return nil, wrapScalewayError(s.GetID(), fmt.Errorf("failed to get kubeconfig for cluster '%s': %w", path, err))
 	}
 	return config.GetRaw(), nil
 }
 
-func (r *ScalewayStore) VerifyKubeconfigPaths() error {
+// GetSearchPreview shows the status, version, region and node pools of the cluster.
+// Clusters which have not been discovered yet, e.g. when a search index is used, are fetched from the Scaleway API.
+func (s *ScalewayStore) GetSearchPreview(path string, _ map[string]string) (string, error) {
+	// low timeout to not pile up many requests, but timeout fast
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	kapi := k8s.NewAPI(s.Client)
+
+	cluster, ok := s.readFromClusterCache(path)
+	if !ok || cluster.Cluster == nil {
+		details, err := s.getCluster(ctx, kapi, path)
+		if err != nil {
+			return "", err
+		}
+		cluster = ScalewayKube{ID: details.ID, Name: details.Name, Project: details.ProjectID, Cluster: details}
+		s.insertIntoClusterCache(cluster)
+	}
+
+	if cluster.Pools == nil {
+		resp, err := kapi.ListPools(&k8s.ListPoolsRequest{
+			Region:    cluster.Cluster.Region,
+			ClusterID: cluster.ID,
+		}, scw.WithContext(ctx), scw.WithAllPages())
+		if err != nil {
+			// still show the cluster details
+			s.Logger.Debugf("failed to list the node pools of cluster %q: %v", path, err)
+		} else {
+			cluster.Pools = resp.Pools
+			s.insertIntoClusterCache(cluster)
+		}
+	}
+
+	details := cluster.Cluster
+	asciTree := gotree.New(details.Name)
+	asciTree.Add(fmt.Sprintf("Status: %s", details.Status))
+	asciTree.Add(fmt.Sprintf("Kubernetes Version: %s", details.Version))
+	asciTree.Add(fmt.Sprintf("Region: %s", details.Region))
+	asciTree.Add(fmt.Sprintf("Project ID: %s", details.ProjectID))
+
+	if details.CreatedAt != nil {
+		asciTree.Add(fmt.Sprintf("Created: %s", details.CreatedAt.Format(time.RFC3339)))
+	}
+
+	if len(details.Tags) > 0 {
+		asciTree.Add(fmt.Sprintf("Tags: %s", strings.Join(details.Tags, ", ")))
+	}
+
+	if len(cluster.Pools) > 0 {
+		pools := asciTree.Add("Node Pools")
+		for _, pool := range cluster.Pools {
+			pools.Add(fmt.Sprintf("%s: %d x %s (%s, zone %s)", pool.Name, pool.Size, pool.NodeType, pool.Status, pool.Zone))
+		}
+	}
+
+	return asciTree.Print(), nil
+}
+
+// getCluster fetches the details of the cluster with the given name from the Scaleway API
+func (s *ScalewayStore) getCluster(ctx context.Context, kapi *k8s.API, name string) (*k8s.Cluster, error) {
+	resp, err := kapi.ListClusters(&k8s.ListClustersRequest{Name: &name}, scw.WithContext(ctx), scw.WithAllPages())
+	if err != nil {
+		return nil, wrapScalewayError(s.GetID(), fmt.Errorf("failed to get cluster %q: %w", name, err))
+	}
+
+	// the name filter also matches clusters containing the name
+	for _, cluster := range resp.Clusters {
+		if cluster.Name == name {
+			return cluster, nil
+		}
+	}
+	return nil, &storeerrors.ErrClusterNotFound{StoreID: s.GetID(), Err: fmt.Errorf("cluster %q not found", name)}
+}
+
+// readFromClusterCache returns the discovered cluster with the given name
+func (s *ScalewayStore) readFromClusterCache(name string) (ScalewayKube, bool) {
+	s.DiscoveredClustersMutex.RLock()
+	defer s.DiscoveredClustersMutex.RUnlock()
+	for _, cluster := range s.DiscoveredClusters {
+		if cluster.Name == name {
+			return cluster, true
+		}
+	}
+	return ScalewayKube{}, false
+}
+
+// insertIntoClusterCache adds the cluster to the discovered clusters, keyed by its ID
+func (s *ScalewayStore) insertIntoClusterCache(cluster ScalewayKube) {
+	s.DiscoveredClustersMutex.Lock()
+	defer s.DiscoveredClustersMutex.Unlock()
+	if s.DiscoveredClusters == nil {
+		s.DiscoveredClusters = make(map[string]ScalewayKube)
+	}
+	s.DiscoveredClusters[cluster.ID] = cluster
+}
+
+func (r *ScalewayStore) VerifyKubeconfigPaths(ctx context.Context) error {
 	return nil
 }
+
+// Probe checks that the Scaleway API is reachable by listing a single project
+func (r *ScalewayStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	if _, err := account.NewProjectAPI(r.Client).ListProjects(
+		&account.ProjectAPIListProjectsRequest{PageSize: scw.Uint32Ptr(1)},
+		scw.WithContext(ctx),
+	); err != nil {
+		return wrapScalewayError(r.GetID(), fmt.Errorf("could not list projects in Scaleway: %w", err))
+	}
+	return nil
+}
+
+// wrapScalewayError returns the typed error for a failed request against the Scaleway API
+func wrapScalewayError(storeID string, err error) error {
+	var (
+		notFound         *scw.ResourceNotFoundError
+		permissionDenied *scw.PermissionsDeniedError
+		authDenied       *scw.DeniedAuthenticationError
+		responseError    *scw.ResponseError
+	)
+	switch {
+	case errors.As(err, &notFound):
+		return &storeerrors.ErrClusterNotFound{StoreID: storeID, Err: err}
+	case errors.As(err, &permissionDenied), errors.As(err, &authDenied):
+		return &storeerrors.ErrAuthFailed{StoreID: storeID, Err: err}
+	case errors.As(err, &responseError):
+		return wrapHTTPError(storeID, responseError.StatusCode, err)
+	}
+	return wrapTimeout(storeID, err)
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/scaleway/scaleway-sdk-go/scw"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+)
+
+var _ = Describe("ScalewayStore", func() {
+	var (
+		server   *httptest.Server
+		requests int
+		s        *store.ScalewayStore
+	)
+
+	BeforeEach(func() {
+		requests = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Header().Set("Content-Type", "application/json")
+			switch r.URL.Path {
+			case "/account/v3/projects":
+				_, _ = w.Write([]byte(`{"total_count": 1, "projects": [{"id": "project", "name": "default"}]}`))
+			case "/k8s/v1/regions/fr-par/clusters":
+				_, _ = w.Write([]byte(`{"total_count": 2, "clusters": [
+					{"id": "1", "name": "prod-eu-2", "status": "ready", "region": "fr-par"},
+					{"id": "2", "name": "prod-eu", "status": "ready", "version": "1.30.2", "region": "fr-par", "project_id": "project", "tags": ["team=platform"], "created_at": "2024-05-01T10:00:00Z"}
+				]}`))
+			case "/k8s/v1/regions/fr-par/clusters/2/pools":
+				_, _ = w.Write([]byte(`{"total_count": 1, "pools": [{"id": "p", "name": "default", "status": "ready", "node_type": "DEV1-M", "size": 3, "zone": "fr-par-1"}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		client, err := scw.NewClient(
+			scw.WithAPIURL(server.URL),
+			scw.WithAuth("SCWXXXXXXXXXXXXXXXXX", "11111111-1111-1111-1111-111111111111"),
+			scw.WithDefaultOrganizationID("11111111-1111-1111-1111-111111111111"),
+			scw.WithDefaultRegion(scw.RegionFrPar),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		// the discovered clusters are intentionally not initialized
+		s = &store.ScalewayStore{Logger: testutil.NewTestLogger(), Client: client}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should fetch clusters which have not been discovered for the preview", func() {
+		preview, err := s.GetSearchPreview("prod-eu", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(preview).To(ContainSubstring("prod-eu"))
+		Expect(preview).To(ContainSubstring("Status: ready"))
+		Expect(preview).To(ContainSubstring("Kubernetes Version: 1.30.2"))
+		Expect(preview).To(ContainSubstring("Region: fr-par"))
+		Expect(preview).To(ContainSubstring("Created: 2024-05-01T10:00:00Z"))
+		Expect(preview).To(ContainSubstring("Tags: team=platform"))
+		Expect(preview).To(ContainSubstring("default: 3 x DEV1-M (ready, zone fr-par-1)"))
+		Expect(requests).To(Equal(2))
+
+		// the cluster and its node pools are cached
+		_, err = s.GetSearchPreview("prod-eu", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requests).To(Equal(2))
+	})
+
+	It("should discover the clusters of all projects", func() {
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(ConsistOf(
+			testutil.FakeSearchResult("prod-eu-2"),
+			testutil.FakeSearchResult("prod-eu"),
+		))
+	})
+
+	It("should stop the search once cancelled", func() {
+		Expect(testutil.CancelSearch(s, 1, time.Second)).To(Succeed())
+	})
+
+	It("should not wait for the Scaleway API once the search is cancelled", func() {
+		unresponsiveServer, closeServer := newUnresponsiveServer()
+		defer closeServer()
+
+		client, err := scw.NewClient(
+			scw.WithAPIURL(unresponsiveServer.URL),
+			scw.WithAuth("SCWXXXXXXXXXXXXXXXXX", "11111111-1111-1111-1111-111111111111"),
+			scw.WithDefaultOrganizationID("11111111-1111-1111-1111-111111111111"),
+			scw.WithDefaultRegion(scw.RegionFrPar),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		s = &store.ScalewayStore{Logger: testutil.NewTestLogger(), Client: client}
+		Expect(testutil.CancelSearch(s, 0, time.Second)).To(Succeed())
+	})
+
+	It("should fail for unknown clusters", func() {
+		_, err := s.GetSearchPreview("dev", nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -17,21 +17,27 @@ package store
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/exec"
 	"path"
 	paths "path"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"text/template"
 
 	"github.com/hashicorp/vault/api"
 	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v3"
 
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
 	"github.com/danielfoehrkn/kubeswitch/types"
 )
 
@@ -45,7 +51,7 @@ func NewVaultStore(vaultAPIAddressFromFlag, vaultTokenFileName, kubeconfigName s
 
 		err = yaml.Unmarshal(buf, vaultStoreConfig)
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal vault config: %w", err)
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to unmarshal vault config: %w", err))
 		}
 	}
 
@@ -60,7 +66,7 @@ func NewVaultStore(vaultAPIAddressFromFlag, vaultTokenFileName, kubeconfigName s
 	}
 
 	if len(vaultAPI) == 0 {
-		return nil, fmt.Errorf("when using the vault kubeconfig store, the API address of the vault has to be provided either by command line argument \"vaultAPI\", via environment variable \"VAULT_ADDR\" or via SwitchConfig file")
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("when using the vault kubeconfig store, the API address of the vault has to be provided either by command line argument \"vaultAPI\", via environment variable \"VAULT_ADDR\" or via SwitchConfig file"))
 	}
 
 	home, err := os.UserHomeDir()
@@ -82,7 +88,7 @@ func NewVaultStore(vaultAPIAddressFromFlag, vaultTokenFileName, kubeconfigName s
 	}
 
 	if len(vaultToken) == 0 {
-		return nil, fmt.Errorf("when using the vault kubeconfig store, a vault API token must be provided. Per default, the token file in \"~.vault-token\" is used. The default token can be overriden via the environment variable \"VAULT_TOKEN\"")
+		return nil, &storeerrors.ErrAuthFailed{StoreID: storeIDForConfig(kubeconfigStore), Err: fmt.Errorf("when using the vault kubeconfig store, a vault API token must be provided. Per default, the token file in \"~.vault-token\" is used. The default token can be overriden via the environment variable \"VAULT_TOKEN\"")}
 	}
 
 	engineversion := vaultStoreConfig.VaultEngineVersion
@@ -100,8 +106,13 @@ func NewVaultStore(vaultAPIAddressFromFlag, vaultTokenFileName, kubeconfigName s
 	}
 	client, err := vaultapi.NewClient(vaultConfig)
 	if err != nil {
-		return nil, err
+		return nil, invalidConfig(kubeconfigStore, err)
+	}
+	// the client is shared by all requests of the store
+	if transport, ok := vaultConfig.HttpClient.Transport.(*http.Transport); ok {
+		configureConnectionPooling(transport, vaultStoreConfig.ConnectionPooling)
 	}
+	vaultConfig.HttpClient.Transport = wrapTransport(vaultConfig.HttpClient.Transport)
 	client.SetToken(vaultToken)
 
 	return &VaultStore{
@@ -111,6 +122,9 @@ func NewVaultStore(vaultAPIAddressFromFlag, vaultTokenFileName, kubeconfigName s
 		VaultKeyKubeconfig: vaultKeyKubeconfig,
 		Client:             client,
 		EngineVersion:      engineversion,
+
+		KubeconfigJSONPointer: vaultStoreConfig.KubeconfigJSONPointer,
+		PathPrefix:            vaultStoreConfig.PathPrefix,
 	}, nil
 }
 
@@ -143,6 +157,15 @@ func (s *VaultStore) GetLogger() *logrus.Entry {
 	return s.Logger
 }
 
+// Stop closes the idle connections of the Vault client
+func (s *VaultStore) Stop(_ context.Context) error {
+	if s.Client == nil {
+		return nil
+	}
+	s.Client.CloneConfig().HttpClient.CloseIdleConnections()
+	return nil
+}
+
 // recursivePathTraversal dfs-traverses the secrets tree rooted at the given path
 // and calls the `visit` functor for each of the directory and leaf paths.
 // Note: for kv-v2, a "metadata" path is expected and "metadata" paths will be
@@ -212,7 +235,7 @@ func (s *VaultStore) recursivePathTraversal(wg *sync.WaitGroup, ctx context.Cont
 	}
 }
 
-func (s *VaultStore) StartSearch(channel chan SearchResult) {
+func (s *VaultStore) StartSearch(ctx context.Context, channel chan SearchResult) {
 	wg := sync.WaitGroup{}
 	// start multiple recursive searches from different root paths
 	for _, path := range s.vaultPaths {
@@ -228,7 +251,7 @@ func (s *VaultStore) StartSearch(channel chan SearchResult) {
 		s.Logger.Debugf("discovering secrets from vault under path %q", secretsPath)
 
 		wg.Add(1)
-		go s.recursivePathTraversal(&wg, context.Background(), s.Client, secretsPath, func(path string, directory bool) error {
+		go s.recursivePathTraversal(&wg, ctx, s.Client, secretsPath, func(path string, directory bool) error {
 			// found an actual secret, but remove "metadata/" from the path
 			rawPath := shimKVv2Metadata(path)
 			channel <- SearchResult{
@@ -258,7 +281,7 @@ func getBytesFromSecretValue(v interface{}) ([]byte, error) {
 	return bytes, nil
 }
 
-func (s *VaultStore) GetKubeconfigForPath(path string, _ map[string]string) ([]byte, error) {
+func (s *VaultStore) GetKubeconfigForPath(ctx context.Context, path string, _ map[string]string) ([]byte, error) {
 
 	// Checking secret engine version. If it's v2, we should shim /metadata/
 	// to secret path if necessary.
@@ -271,17 +294,21 @@ func (s *VaultStore) GetKubeconfigForPath(path string, _ map[string]string) ([]b
 	}
 
 	s.Logger.Debugf("vault: getting secret for path %q", secretsPath)
-	secret, err := s.Client.Logical().Read(secretsPath)
+	secret, err := s.Client.Logical().ReadWithContext(ctx, secretsPath)
 	if err != nil {
-		return nil, fmt.Errorf("could not read secret with path '%s': %v", secretsPath, err)
+		return nil, wrapVaultError(s.GetID(), fmt.Errorf("could not read secret with path '%s': %w", secretsPath, err))
 	}
 
 	if secret == nil {
-		return nil, fmt.Errorf("no kubeconfig found for path %s", secretsPath)
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("no kubeconfig found for path %s", secretsPath)}
+	}
+
+	if len(s.KubeconfigJSONPointer) > 0 {
+		return s.getKubeconfigForJSONPointer(secretsPath, secret.Data)
 	}
 
 	if (s.EngineVersion == "v1") && len(secret.Data) != 1 {
-		return nil, fmt.Errorf("cannot read kubeconfig from %q. Only support one entry in the secret if we using v1", secretsPath)
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("cannot read kubeconfig from %q. Only support one entry in the secret if we using v1", secretsPath)}
 	}
 
 	if s.EngineVersion == "v1" {
@@ -291,7 +318,7 @@ func (s *VaultStore) GetKubeconfigForPath(path string, _ map[string]string) ([]b
 				return nil, err
 			}
 			if !matched {
-				return nil, fmt.Errorf("cannot read kubeconfig from %q. Key %q does not match desired kubeconfig name", secretsPath, s.KubeconfigName)
+				return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("cannot read kubeconfig from %q. Key %q does not match desired kubeconfig name", secretsPath, s.KubeconfigName)}
 			}
 
 			if matched {
@@ -301,14 +328,14 @@ func (s *VaultStore) GetKubeconfigForPath(path string, _ map[string]string) ([]b
 				}
 				if len(bytes) == 0 {
 					s.Logger.Debugf("vault: data is empty from %q", secretsPath)
-					return nil, fmt.Errorf("kubeconfig is empty from %q", secretsPath)
+					return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("kubeconfig is empty from %q", secretsPath)}
 				}
 				return bytes, nil
 			}
 		}
 	} else {
 		if secret.Data["data"] == nil {
-			return nil, fmt.Errorf("cannot read kubeconfig from %q. Secret is empty.", secretsPath)
+			return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("cannot read kubeconfig from %q. Secret is empty.", secretsPath)}
 		}
 		value, ok := secret.Data["data"].(map[string]interface{})[s.VaultKeyKubeconfig]
 		if ok {
@@ -319,13 +346,48 @@ func (s *VaultStore) GetKubeconfigForPath(path string, _ map[string]string) ([]b
 			return bytes, nil
 		}
 	}
-	return nil, fmt.Errorf("should not happen")
+	return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("cannot read kubeconfig from %q. Key %q not found", secretsPath, s.VaultKeyKubeconfig)}
+}
+
+// getKubeconfigForJSONPointer extracts the kubeconfig from the secret data using the configured JSON Pointer.
+// For the KV v2 engine, the pointer is evaluated against the secret's data (not the metadata).
+func (s *VaultStore) getKubeconfigForJSONPointer(secretsPath string, data map[string]interface{}) ([]byte, error) {
+	var document interface{} = data
+	if s.EngineVersion == "v2" {
+		if data["data"] == nil {
+			return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("cannot read kubeconfig from %q. Secret is empty.", secretsPath)}
+		}
+		document = data["data"]
+	}
+
+	value, err := util.JSONPointerGet(document, s.KubeconfigJSONPointer)
+	if err != nil {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("cannot read kubeconfig from %q: %v", secretsPath, err)}
+	}
+
+	bytes, err := getBytesFromSecretValue(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read kubeconfig from %q: %v", secretsPath, err)
+	}
+	if len(bytes) == 0 {
+		return nil, &storeerrors.ErrKubeconfigNotFound{StoreID: s.GetID(), Err: fmt.Errorf("kubeconfig is empty from %q", secretsPath)}
+	}
+	return bytes, nil
 }
 
-func (s *VaultStore) VerifyKubeconfigPaths() error {
+func (s *VaultStore) VerifyKubeconfigPaths(ctx context.Context) error {
 	var duplicatePath = make(map[string]*struct{})
 
+	pathPrefix, err := ExecutePathPrefixTemplate(s.PathPrefix)
+	if err != nil {
+		return invalidConfig(s.KubeconfigStore, err)
+	}
+
 	for _, path := range s.KubeconfigStore.Paths {
+		if len(pathPrefix) > 0 {
+			path = paths.Join(pathPrefix, path)
+		}
+
 		// do not add duplicate paths
 		if duplicatePath[path] != nil {
 			continue
@@ -342,7 +404,7 @@ func (s *VaultStore) VerifyKubeconfigPaths() error {
 			secretsPath = path
 		}
 
-		_, err := s.Client.Logical().Read(secretsPath)
+		_, err := s.Client.Logical().ReadWithContext(ctx, secretsPath)
 		if err != nil {
 			return err
 		}
@@ -352,6 +414,75 @@ func (s *VaultStore) VerifyKubeconfigPaths() error {
 	return nil
 }
 
+// pathPrefixData contains the values available in the path prefix template.
+// The git branch and the hostname are only determined if used by the template.
+type pathPrefixData struct {
+	Env map[string]string
+}
+
+// GitBranch returns the git branch of the current working directory
+func (pathPrefixData) GitBranch() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the git branch of the current working directory: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Hostname returns the hostname
+func (pathPrefixData) Hostname() (string, error) {
+	return os.Hostname()
+}
+
+// ExecutePathPrefixTemplate evaluates the path prefix template of the vault store.
+// Fails if the template is invalid or references an environment variable that is not set.
+func ExecutePathPrefixTemplate(pathPrefix string) (string, error) {
+	if len(pathPrefix) == 0 {
+		return "", nil
+	}
+
+	tmpl, err := template.New("pathPrefix").Option("missingkey=error").Parse(pathPrefix)
+	if err != nil {
+		return "", fmt.Errorf("the path prefix %q is not a valid template: %w", pathPrefix, err)
+	}
+
+	data := pathPrefixData{Env: make(map[string]string)}
+	for _, env := range os.Environ() {
+		if key, value, ok := strings.Cut(env, "="); ok {
+			data.Env[key] = value
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to evaluate the path prefix %q. Please check that all referenced environment variables are set: %w", pathPrefix, err)
+	}
+	return b.String(), nil
+}
+
+// Probe checks that the Vault server is reachable using its health endpoint
+func (s *VaultStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	if _, err := s.Client.Sys().HealthWithContext(ctx); err != nil {
+		return wrapVaultError(s.GetID(), fmt.Errorf("failed to check the health of Vault: %w", err))
+	}
+	return nil
+}
+
+// wrapVaultError returns the typed error for a failed request against the Vault API
+func wrapVaultError(storeID string, err error) error {
+	var responseError *vaultapi.ResponseError
+	if errors.As(err, &responseError) {
+		if responseError.StatusCode == http.StatusNotFound {
+			return &storeerrors.ErrKubeconfigNotFound{StoreID: storeID, Err: err}
+		}
+		return wrapHTTPError(storeID, responseError.StatusCode, err)
+	}
+	return wrapTimeout(storeID, err)
+}
+
 // shimKVv2Path aligns the supported legacy path to KV v2 specs by inserting
 // /data/ into the path for reading secrets. Paths for metadata are not modified.
 func shimKVv2Path(rawPath, mountPath string) string {
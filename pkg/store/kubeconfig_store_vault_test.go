@@ -0,0 +1,139 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("ExecutePathPrefixTemplate", func() {
+	BeforeEach(func() {
+		Expect(os.Setenv("KUBESWITCH_TEST_TEAM", "platform")).To(Succeed())
+		Expect(os.Setenv("KUBESWITCH_TEST_ENVIRONMENT", "dev")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.Unsetenv("KUBESWITCH_TEST_TEAM")).To(Succeed())
+		Expect(os.Unsetenv("KUBESWITCH_TEST_ENVIRONMENT")).To(Succeed())
+	})
+
+	It("should return an empty prefix without template", func() {
+		Expect(store.ExecutePathPrefixTemplate("")).To(BeEmpty())
+	})
+
+	It("should evaluate the environment variables", func() {
+		Expect(store.ExecutePathPrefixTemplate("secret/data/k8s/{{.Env.KUBESWITCH_TEST_TEAM}}/{{.Env.KUBESWITCH_TEST_ENVIRONMENT}}")).To(Equal("secret/data/k8s/platform/dev"))
+	})
+
+	It("should evaluate the hostname", func() {
+		hostname, err := os.Hostname()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.ExecutePathPrefixTemplate("secret/{{.Hostname}}")).To(Equal("secret/" + hostname))
+	})
+
+	It("should fail for a missing environment variable", func() {
+		_, err := store.ExecutePathPrefixTemplate("secret/{{.Env.KUBESWITCH_TEST_MISSING}}")
+		Expect(err).To(MatchError(ContainSubstring("KUBESWITCH_TEST_MISSING")))
+	})
+
+	It("should fail for an invalid template", func() {
+		_, err := store.ExecutePathPrefixTemplate("secret/{{.Env.KUBESWITCH_TEST_TEAM")
+		Expect(err).To(MatchError(ContainSubstring("is not a valid template")))
+	})
+})
+
+// vaultResponse is the response of the fake Vault API
+type vaultResponse struct {
+	status int
+	body   string
+}
+
+var _ = Describe("VaultStore", func() {
+	var (
+		server     *httptest.Server
+		vaultStore *store.VaultStore
+		// secrets maps the paths of the KV v2 API to the returned status code and body
+		secrets map[string]vaultResponse
+	)
+
+	respond := func(path string, status int, body string) {
+		secrets[path] = vaultResponse{status: status, body: body}
+	}
+
+	BeforeEach(func() {
+		secrets = map[string]vaultResponse{}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, ok := secrets[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(secret.status)
+			_, _ = w.Write([]byte(secret.body))
+		}))
+
+		client, err := vaultapi.NewClient(&vaultapi.Config{Address: server.URL})
+		Expect(err).ToNot(HaveOccurred())
+		vaultStore = &store.VaultStore{
+			Logger:             testutil.NewTestLogger(),
+			KubeconfigStore:    types.KubeconfigStore{Kind: types.StoreKindVault},
+			Client:             client,
+			VaultKeyKubeconfig: "config",
+			EngineVersion:      "v2",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should return the kubeconfig of the secret", func() {
+		respond("/v1/secret/data/dev", http.StatusOK, `{"data": {"data": {"config": "kind: Config"}}}`)
+
+		Expect(vaultStore.GetKubeconfigForPath(context.Background(), "secret/dev", nil)).To(Equal([]byte("kind: Config")))
+	})
+
+	It("should report secrets without a kubeconfig as not found", func() {
+		respond("/v1/secret/data/other", http.StatusOK, `{"data": {"data": {"password": "secret"}}}`)
+		respond("/v1/secret/data/empty", http.StatusOK, `{"data": {"data": null}}`)
+
+		_, err := vaultStore.GetKubeconfigForPath(context.Background(), "secret/other", nil)
+		Expect(err).To(MatchError(&storeerrors.ErrKubeconfigNotFound{}))
+		_, err = vaultStore.GetKubeconfigForPath(context.Background(), "secret/empty", nil)
+		Expect(err).To(MatchError(&storeerrors.ErrKubeconfigNotFound{}))
+		_, err = vaultStore.GetKubeconfigForPath(context.Background(), "secret/missing", nil)
+		Expect(err).To(MatchError(&storeerrors.ErrKubeconfigNotFound{}))
+	})
+
+	It("should not report secrets that cannot be read as not found", func() {
+		respond("/v1/secret/data/dev", http.StatusForbidden, `{"errors": ["permission denied"]}`)
+
+		_, err := vaultStore.GetKubeconfigForPath(context.Background(), "secret/dev", nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err).ToNot(MatchError(&storeerrors.ErrKubeconfigNotFound{}))
+	})
+})
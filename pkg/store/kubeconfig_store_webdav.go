@@ -0,0 +1,242 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/studio-b12/gowebdav"
+	"gopkg.in/yaml.v3"
+
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const defaultWebDAVRecursiveDepth = 3
+
+func NewWebDAVStore(kubeconfigName string, kubeconfigStore types.KubeconfigStore) (*WebDAVStore, error) {
+	webDAVStoreConfig := &types.StoreConfigWebDAV{}
+	if kubeconfigStore.Config != nil {
+		buf, err := yaml.Marshal(kubeconfigStore.Config)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to process webdav store config: %w", err))
+		}
+
+		err = yaml.Unmarshal(buf, webDAVStoreConfig)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to unmarshal webdav config: %w", err))
+		}
+	}
+
+	if len(webDAVStoreConfig.URL) == 0 {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("the URL of the WebDAV server must be configured"))
+	}
+
+	var client *gowebdav.Client
+	switch webDAVStoreConfig.AuthType {
+	case "", types.WebDAVAuthTypeBasic:
+		client = gowebdav.NewClient(webDAVStoreConfig.URL, webDAVStoreConfig.Username, webDAVStoreConfig.Password)
+	case types.WebDAVAuthTypeDigest:
+		authorizer := gowebdav.NewEmptyAuth()
+		authorizer.AddAuthenticator("digest", func(_ *http.Client, rs *http.Response, _ string) (gowebdav.Authenticator, error) {
+			return gowebdav.NewDigestAuth(webDAVStoreConfig.Username, webDAVStoreConfig.Password, rs)
+		})
+		client = gowebdav.NewAuthClient(webDAVStoreConfig.URL, authorizer)
+	case types.WebDAVAuthTypeBearer:
+		client = gowebdav.NewAuthClient(webDAVStoreConfig.URL, gowebdav.NewEmptyAuth())
+		client.SetHeader("Authorization", fmt.Sprintf("Bearer %s", webDAVStoreConfig.Password))
+	default:
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("unknown WebDAV auth type %q. Valid auth types are %q, %q and %q", webDAVStoreConfig.AuthType, types.WebDAVAuthTypeBasic, types.WebDAVAuthTypeDigest, types.WebDAVAuthTypeBearer))
+	}
+
+	// the client uses its own transport, so that its connections can be closed by Stop
+	transport := cloneDefaultTransport()
+	if len(webDAVStoreConfig.TLSCACertFile) > 0 {
+		caCert, err := os.ReadFile(webDAVStoreConfig.TLSCACertFile)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to read CA certificate file of the WebDAV server: %w", err))
+		}
+
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caCert) {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to parse CA certificate file %q of the WebDAV server", webDAVStoreConfig.TLSCACertFile))
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: certPool}
+	}
+	client.SetTransport(wrapTransport(transport))
+
+	return &WebDAVStore{
+		Logger:          logrus.New().WithField("store", types.StoreKindWebDAV),
+		KubeconfigStore: kubeconfigStore,
+		KubeconfigName:  kubeconfigName,
+		Client:          client,
+		transport:       transport,
+		Config:          webDAVStoreConfig,
+	}, nil
+}
+
+func (s *WebDAVStore) GetID() string {
+	id := "default"
+	if s.KubeconfigStore.ID != nil {
+		id = *s.KubeconfigStore.ID
+	}
+	return fmt.Sprintf("%s.%s", types.StoreKindWebDAV, id)
+}
+
+func (s *WebDAVStore) GetKind() types.StoreKind {
+	return types.StoreKindWebDAV
+}
+
+func (s *WebDAVStore) GetContextPrefix(p string) string {
+	if s.GetStoreConfig().ShowPrefix != nil && !*s.GetStoreConfig().ShowPrefix {
+		return ""
+	}
+
+	// return the name of the parent directory
+	return path.Base(path.Dir(p))
+}
+
+func (s *WebDAVStore) GetStoreConfig() types.KubeconfigStore {
+	return s.KubeconfigStore
+}
+
+func (s *WebDAVStore) GetLogger() *logrus.Entry {
+	return s.Logger
+}
+
+// Stop closes the idle connections of the WebDAV client
+func (s *WebDAVStore) Stop(_ context.Context) error {
+	if s.transport != nil {
+		s.transport.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (s *WebDAVStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	if len(s.getSearchPaths()) == 0 {
+		return &storeerrors.ErrInvalidConfig{StoreID: s.GetID(), Err: fmt.Errorf("at least one search path must be configured for the WebDAV store")}
+	}
+	return nil
+}
+
+// Probe checks that the WebDAV server is reachable
+func (s *WebDAVStore) Probe(ctx context.Context) error {
+	ctx, cancel := probeContext(ctx)
+	defer cancel()
+
+	if err := probeAsync(ctx, s.Client.Connect); err != nil {
+		return wrapWebDAVError(s.GetID(), fmt.Errorf("failed to connect to the WebDAV server: %w", err))
+	}
+	return nil
+}
+
+func (s *WebDAVStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	depth := defaultWebDAVRecursiveDepth
+	if s.Config.RecursiveDepth != nil {
+		depth = *s.Config.RecursiveDepth
+	}
+
+	wg := sync.WaitGroup{}
+	for _, searchPath := range s.getSearchPaths() {
+		wg.Add(1)
+		go func(searchPath string) {
+			defer wg.Done()
+			s.searchDirectory(ctx, searchPath, depth, channel)
+		}(searchPath)
+	}
+	wg.Wait()
+}
+
+// searchDirectory lists the given directory on the WebDAV server and sends all files matching the kubeconfig name.
+// Subdirectories are searched until the given depth is exhausted.
+// Returns false if the search is cancelled.
+func (s *WebDAVStore) searchDirectory(ctx context.Context, directory string, depth int, channel chan SearchResult) bool {
+	files, err := callWithContext(ctx, func() ([]os.FileInfo, error) {
+		return s.Client.ReadDir(directory)
+	})
+	if err != nil {
+		return sendSearchResult(ctx, channel, SearchResult{
+			KubeconfigPath: "",
+			Error:          wrapWebDAVError(s.GetID(), fmt.Errorf("failed to list directory %q on the WebDAV server: %w", directory, err)),
+		})
+	}
+
+	for _, file := range files {
+		filePath := path.Join(directory, file.Name())
+		if file.IsDir() {
+			if depth > 0 && !s.searchDirectory(ctx, filePath, depth-1, channel) {
+				return false
+			}
+			continue
+		}
+
+		matched, err := path.Match(s.KubeconfigName, file.Name())
+		if err != nil {
+			sendSearchResult(ctx, channel, SearchResult{
+				KubeconfigPath: "",
+				Error:          fmt.Errorf("invalid kubeconfig name pattern %q: %w", s.KubeconfigName, err),
+			})
+			return false
+		}
+		if matched && !sendSearchResult(ctx, channel, SearchResult{
+			KubeconfigPath: filePath,
+			Error:          nil,
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *WebDAVStore) GetKubeconfigForPath(ctx context.Context, p string, _ map[string]string) ([]byte, error) {
+	s.Logger.Debugf("WebDAV: get kubeconfig for path %s", p)
+	kubeconfig, err := callWithContext(ctx, func() ([]byte, error) {
+		return s.Client.Read(p)
+	})
+	if err != nil {
+		return nil, wrapWebDAVError(s.GetID(), err)
+	}
+	return kubeconfig, nil
+}
+
+// wrapWebDAVError returns the typed error for a failed request against the WebDAV server
+func wrapWebDAVError(storeID string, err error) error {
+	var statusError gowebdav.StatusError
+	if errors.As(err, &statusError) {
+		if statusError.Status == http.StatusNotFound {
+			return &storeerrors.ErrKubeconfigNotFound{StoreID: storeID, Err: err}
+		}
+		return wrapHTTPError(storeID, statusError.Status, err)
+	}
+	return wrapTimeout(storeID, err)
+}
+
+// getSearchPaths returns the configured search paths and falls back to the paths of the kubeconfig store
+func (s *WebDAVStore) getSearchPaths() []string {
+	if len(s.Config.SearchPaths) > 0 {
+		return s.Config.SearchPaths
+	}
+	return s.KubeconfigStore.Paths
+}
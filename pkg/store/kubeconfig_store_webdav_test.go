@@ -0,0 +1,193 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/webdav"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("WebDAVStore", func() {
+	var (
+		server  *httptest.Server
+		rootDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		rootDir, err = os.MkdirTemp("", "webdav-store")
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, file := range []string{
+			"kubeconfigs/config",
+			"kubeconfigs/dev/config",
+			"kubeconfigs/dev/other",
+			"kubeconfigs/dev/team/cluster/config",
+		} {
+			Expect(os.MkdirAll(filepath.Join(rootDir, filepath.Dir(file)), 0700)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(rootDir, file), []byte(file), 0600)).To(Succeed())
+		}
+
+		handler := &webdav.Handler{
+			FileSystem: webdav.Dir(rootDir),
+			LockSystem: webdav.NewMemLS(),
+		}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			username, password, ok := r.BasicAuth()
+			if r.Header.Get("Authorization") != "Bearer token" && (!ok || username != "user" || password != "secret") {
+				w.Header().Set("WWW-Authenticate", `Basic realm="kubeswitch"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(rootDir)).To(Succeed())
+	})
+
+	newStore := func(config map[string]interface{}, paths ...string) *store.WebDAVStore {
+		s, err := store.NewWebDAVStore("config", types.KubeconfigStore{
+			Kind:   types.StoreKindWebDAV,
+			Paths:  paths,
+			Config: config,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return s
+	}
+
+	basicAuthConfig := func() map[string]interface{} {
+		return map[string]interface{}{
+			"url":      server.URL,
+			"username": "user",
+			"password": "secret",
+		}
+	}
+
+	It("should require the URL of the WebDAV server", func() {
+		_, err := store.NewWebDAVStore("config", types.KubeconfigStore{Kind: types.StoreKindWebDAV})
+		Expect(err).To(MatchError(&storeerrors.ErrInvalidConfig{}))
+	})
+
+	It("should reject unknown auth types", func() {
+		_, err := store.NewWebDAVStore("config", types.KubeconfigStore{
+			Kind:   types.StoreKindWebDAV,
+			Config: map[string]interface{}{"url": server.URL, "authType": "ntlm"},
+		})
+		Expect(err).To(MatchError(ContainSubstring(`unknown WebDAV auth type "ntlm"`)))
+	})
+
+	It("should require a search path", func() {
+		s := newStore(basicAuthConfig())
+		Expect(s.VerifyKubeconfigPaths(context.Background())).To(MatchError(&storeerrors.ErrInvalidConfig{}))
+	})
+
+	It("should find the kubeconfigs in the search paths up to the default depth", func() {
+		s := newStore(basicAuthConfig(), "/kubeconfigs")
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(ConsistOf(
+			testutil.FakeSearchResult("/kubeconfigs/config"),
+			testutil.FakeSearchResult("/kubeconfigs/dev/config"),
+			testutil.FakeSearchResult("/kubeconfigs/dev/team/cluster/config"),
+		))
+	})
+
+	It("should only search the configured depth of subdirectories", func() {
+		config := basicAuthConfig()
+		config["searchPaths"] = []string{"/kubeconfigs"}
+		config["recursiveDepth"] = 1
+		s := newStore(config)
+
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(ConsistOf(
+			testutil.FakeSearchResult("/kubeconfigs/config"),
+			testutil.FakeSearchResult("/kubeconfigs/dev/config"),
+		))
+	})
+
+	It("should authenticate with a bearer token", func() {
+		s := newStore(map[string]interface{}{
+			"url":      server.URL,
+			"authType": "bearer",
+			"password": "token",
+		}, "/kubeconfigs/dev")
+
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		testutil.AssertResultCount(GinkgoT(), results, 2)
+	})
+
+	It("should report a failed authentication when listing a directory", func() {
+		config := basicAuthConfig()
+		config["password"] = "wrong"
+		s := newStore(config, "/kubeconfigs")
+
+		_, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).To(MatchError(&storeerrors.ErrAuthFailed{}))
+	})
+
+	It("should read the kubeconfig for a path", func() {
+		s := newStore(basicAuthConfig(), "/kubeconfigs")
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "/kubeconfigs/dev/config", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig)).To(Equal("kubeconfigs/dev/config"))
+	})
+
+	It("should return a not found error for a missing kubeconfig", func() {
+		s := newStore(basicAuthConfig(), "/kubeconfigs")
+		_, err := s.GetKubeconfigForPath(context.Background(), "/kubeconfigs/missing/config", nil)
+		Expect(err).To(MatchError(&storeerrors.ErrKubeconfigNotFound{}))
+	})
+
+	It("should stop the search once cancelled", func() {
+		s := newStore(basicAuthConfig(), "/kubeconfigs")
+		Expect(testutil.CancelSearch(s, 1, time.Second)).To(Succeed())
+	})
+
+	It("should not wait for the WebDAV server once the search is cancelled", func() {
+		unresponsiveServer, closeServer := newUnresponsiveServer()
+		defer closeServer()
+
+		s := newStore(map[string]interface{}{"url": unresponsiveServer.URL}, "/kubeconfigs")
+		Expect(testutil.CancelSearch(s, 0, time.Second)).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := s.GetKubeconfigForPath(ctx, "/kubeconfigs/config", nil)
+		Expect(err).To(MatchError(&storeerrors.ErrStoreTimeout{}))
+	})
+
+	It("should use the parent directory as context prefix", func() {
+		s := newStore(basicAuthConfig(), "/kubeconfigs")
+		Expect(s.GetContextPrefix("/kubeconfigs/dev/config")).To(Equal("dev"))
+	})
+})
@@ -0,0 +1,173 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// LazyStore defers the creation of a store until it is first used.
+// Creating a store typically loads credentials and constructs the API clients.
+type LazyStore struct {
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	create          func() (KubeconfigStore, error)
+	once            sync.Once
+	initialized     atomic.Bool
+	upstream        KubeconfigStore
+	err             error
+}
+
+// NewLazyStore returns a store logging to the given logger which calls create on first use
+func NewLazyStore(logger *logrus.Entry, kubeconfigStore types.KubeconfigStore, create func() (KubeconfigStore, error)) *LazyStore {
+	return &LazyStore{
+		Logger:          logger,
+		KubeconfigStore: kubeconfigStore,
+		create:          create,
+	}
+}
+
+// Initialize creates the underlying store and verifies its kubeconfig paths.
+// Only the first call creates the store, concurrent calls block until it is created.
+func (s *LazyStore) Initialize(ctx context.Context) error {
+	s.once.Do(func() {
+		defer s.initialized.Store(true)
+
+		s.Logger.Debugf("initializing store")
+		s.upstream, s.err = s.create()
+		if s.err != nil {
+			return
+		}
+		s.err = s.upstream.VerifyKubeconfigPaths(ctx)
+	})
+	return s.err
+}
+
+// IsInitialized returns true once the underlying store has been created (or failed to be created)
+func (s *LazyStore) IsInitialized() bool {
+	return s.initialized.Load()
+}
+
+// GetID returns the ID based on the store configuration, as the underlying store might not be created yet
+func (s *LazyStore) GetID() string {
+	id := "default"
+	if s.KubeconfigStore.ID != nil {
+		id = *s.KubeconfigStore.ID
+	}
+	return fmt.Sprintf("%s.%s", s.KubeconfigStore.Kind, id)
+}
+
+func (s *LazyStore) GetKind() types.StoreKind {
+	return s.KubeconfigStore.Kind
+}
+
+func (s *LazyStore) GetContextPrefix(path string) string {
+	if err := s.Initialize(context.Background()); err != nil {
+		return ""
+	}
+	return s.upstream.GetContextPrefix(path)
+}
+
+// VerifyKubeconfigPaths initializes the store, as the kubeconfig paths can only be verified by the underlying store
+func (s *LazyStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	if err := s.Initialize(ctx); err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+	return nil
+}
+
+func (s *LazyStore) Probe(ctx context.Context) error {
+	if err := s.Initialize(ctx); err != nil {
+		return fmt.Errorf("failed to initialize store: %w", err)
+	}
+	return s.upstream.Probe(ctx)
+}
+
+func (s *LazyStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	if err := s.Initialize(ctx); err != nil {
+		channel <- SearchResult{
+			Error: fmt.Errorf("failed to initialize store: %w", err),
+		}
+		return
+	}
+	s.upstream.StartSearch(ctx, channel)
+}
+
+func (s *LazyStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	if err := s.Initialize(ctx); err != nil {
+		return nil, fmt.Errorf("failed to initialize store: %w", err)
+	}
+	return s.upstream.GetKubeconfigForPath(ctx, path, tags)
+}
+
+func (s *LazyStore) GetLogger() *logrus.Entry {
+	return s.Logger
+}
+
+// Stop stops the underlying store if it has been created.
+// Does not wait for an ongoing initialization.
+func (s *LazyStore) Stop(ctx context.Context) error {
+	if !s.IsInitialized() || s.upstream == nil {
+		return nil
+	}
+	return s.upstream.Stop(ctx)
+}
+
+func (s *LazyStore) GetStoreConfig() types.KubeconfigStore {
+	return s.KubeconfigStore
+}
+
+func (s *LazyStore) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
+	if err := s.Initialize(context.Background()); err != nil {
+		return "", err
+	}
+
+	previewer, ok := s.upstream.(Previewer)
+	if !ok {
+		// if the wrapped store is not a previewer, simply return an empty string, hence causing no visual distortion
+		return "", nil
+	}
+	return previewer.GetSearchPreview(path, optionalTags)
+}
+
+// Unwrap initializes and returns the underlying store
+func (s *LazyStore) Unwrap() KubeconfigStore {
+	if err := s.Initialize(context.Background()); err != nil {
+		return nil
+	}
+	return s.upstream
+}
+
+// IsLoading returns true if the given store or one of the stores it wraps is a LazyStore that is still initializing
+func IsLoading(s KubeconfigStore) bool {
+	for s != nil {
+		if lazy, ok := s.(*LazyStore); ok {
+			return !lazy.IsInitialized()
+		}
+		wrapper, ok := s.(Wrapper)
+		if !ok {
+			return false
+		}
+		s = wrapper.Unwrap()
+	}
+	return false
+}
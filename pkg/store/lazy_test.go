@@ -0,0 +1,128 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("LazyStore", func() {
+	var (
+		tempDir         string
+		kubeconfigStore types.KubeconfigStore
+		created         atomic.Int32
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-lazy-store")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(tempDir, "config"), []byte{}, 0600)).To(Succeed())
+
+		kubeconfigStore = types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{tempDir},
+		}
+		created.Store(0)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	create := func() (store.KubeconfigStore, error) {
+		created.Add(1)
+		return store.NewFilesystemStore("config", kubeconfigStore)
+	}
+
+	It("should create the store once on first use", func() {
+		s := store.NewLazyStore(testutil.NewTestLogger(), kubeconfigStore, create)
+		Expect(s.GetID()).To(Equal("filesystem.default"))
+		Expect(s.IsInitialized()).To(BeFalse())
+		Expect(store.IsLoading(s)).To(BeTrue())
+		Expect(created.Load()).To(BeZero())
+
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(ConsistOf(testutil.FakeSearchResult(filepath.Join(tempDir, "config"))))
+
+		results, err = testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		testutil.AssertResultCount(GinkgoT(), results, 1)
+		Expect(created.Load()).To(BeEquivalentTo(1))
+		Expect(store.IsLoading(s)).To(BeFalse())
+		Expect(store.Unwrap(s)).To(BeAssignableToTypeOf(&store.FilesystemStore{}))
+	})
+
+	It("should initialize the store when verifying the kubeconfig paths", func() {
+		s := store.NewLazyStore(testutil.NewTestLogger(), kubeconfigStore, create)
+		Expect(s.VerifyKubeconfigPaths(context.Background())).To(Succeed())
+		Expect(s.IsInitialized()).To(BeTrue())
+		Expect(created.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should report invalid kubeconfig paths when verifying them", func() {
+		kubeconfigStore.Paths = []string{filepath.Join(tempDir, "missing")}
+		s := store.NewLazyStore(testutil.NewTestLogger(), kubeconfigStore, create)
+		Expect(s.VerifyKubeconfigPaths(context.Background())).To(MatchError(ContainSubstring("failed to initialize store")))
+	})
+
+	It("should report initialization errors when verifying the kubeconfig paths", func() {
+		s := store.NewLazyStore(testutil.NewTestLogger(), kubeconfigStore, func() (store.KubeconfigStore, error) {
+			return nil, errors.New("invalid credentials")
+		})
+		Expect(s.VerifyKubeconfigPaths(context.Background())).To(MatchError(ContainSubstring("invalid credentials")))
+	})
+
+	It("should log to the given logger", func() {
+		var output bytes.Buffer
+		logger := logrus.New()
+		logger.SetOutput(&output)
+		logger.SetLevel(logrus.DebugLevel)
+		entry := logger.WithField("store", "filesystem")
+
+		s := store.NewLazyStore(entry, kubeconfigStore, create)
+		Expect(s.GetLogger()).To(BeIdenticalTo(entry))
+		Expect(s.VerifyKubeconfigPaths(context.Background())).To(Succeed())
+		Expect(output.String()).To(ContainSubstring("initializing store"))
+	})
+
+	It("should report initialization errors", func() {
+		s := store.NewLazyStore(testutil.NewTestLogger(), kubeconfigStore, func() (store.KubeconfigStore, error) {
+			return nil, errors.New("invalid credentials")
+		})
+
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).To(MatchError(ContainSubstring("invalid credentials")))
+		Expect(results).To(BeEmpty())
+
+		_, err = s.GetKubeconfigForPath(context.Background(), "config", nil)
+		Expect(err).To(MatchError(ContainSubstring("invalid credentials")))
+		Expect(store.Unwrap(s)).To(BeNil())
+	})
+})
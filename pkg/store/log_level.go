@@ -0,0 +1,51 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// SetLogLevel applies the log level configured for the store to the store's logger.
+// Stores without a configured log level log through the standard logger, so that
+// later changes of the global log level (e.g. by --debug or a subcommand) also apply to them.
+func SetLogLevel(s KubeconfigStore) {
+	entry := s.GetLogger()
+	if entry == nil {
+		return
+	}
+
+	logLevel := s.GetStoreConfig().LogLevel
+	if len(logLevel) == 0 {
+		entry.Logger = logrus.StandardLogger()
+		return
+	}
+
+	level, err := logrus.ParseLevel(logLevel)
+	if err != nil {
+		logrus.Warnf("invalid log level %q for store %q: %v", logLevel, s.GetID(), err)
+		entry.Logger = logrus.StandardLogger()
+		return
+	}
+
+	// never change the level of the standard logger for a single store
+	if entry.Logger == nil || entry.Logger == logrus.StandardLogger() {
+		logger := logrus.New()
+		logger.SetFormatter(logrus.StandardLogger().Formatter)
+		logger.SetOutput(logrus.StandardLogger().Out)
+		entry.Logger = logger
+	}
+	entry.Logger.SetLevel(level)
+}
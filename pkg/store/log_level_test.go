@@ -0,0 +1,92 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("SetLogLevel", func() {
+	var (
+		tempDir     string
+		globalLevel logrus.Level
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-log-level")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(tempDir, "config"), []byte{}, 0600)).To(Succeed())
+
+		globalLevel = logrus.GetLevel()
+		logrus.SetLevel(logrus.InfoLevel)
+	})
+
+	AfterEach(func() {
+		logrus.SetLevel(globalLevel)
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	newFilesystemStore := func(logLevel string) store.KubeconfigStore {
+		s, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:     types.StoreKindFilesystem,
+			Paths:    []string{tempDir},
+			LogLevel: logLevel,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return s
+	}
+
+	It("should follow later changes of the global log level if the store has no log level", func() {
+		s := newFilesystemStore("")
+		store.SetLogLevel(s)
+		Expect(s.GetLogger().Logger.IsLevelEnabled(logrus.DebugLevel)).To(BeFalse())
+
+		logrus.SetLevel(logrus.DebugLevel)
+		Expect(s.GetLogger().Logger.IsLevelEnabled(logrus.DebugLevel)).To(BeTrue())
+	})
+
+	It("should keep the log level configured for the store", func() {
+		s := newFilesystemStore("error")
+		store.SetLogLevel(s)
+		Expect(s.GetLogger().Logger.GetLevel()).To(Equal(logrus.ErrorLevel))
+
+		logrus.SetLevel(logrus.DebugLevel)
+		Expect(s.GetLogger().Logger.GetLevel()).To(Equal(logrus.ErrorLevel))
+	})
+
+	It("should not change the global log level for stores logging through the standard logger", func() {
+		s := &store.EKSStore{KubeconfigStore: types.KubeconfigStore{Kind: types.StoreKindEKS, LogLevel: "warn"}}
+		Expect(s.GetLogger().Logger).To(BeIdenticalTo(logrus.StandardLogger()))
+
+		store.SetLogLevel(s)
+		Expect(s.GetLogger().Logger.GetLevel()).To(Equal(logrus.WarnLevel))
+		Expect(logrus.GetLevel()).To(Equal(logrus.InfoLevel))
+	})
+
+	It("should ignore an invalid log level", func() {
+		s := newFilesystemStore("verbose")
+		store.SetLogLevel(s)
+		Expect(s.GetLogger().Logger).To(BeIdenticalTo(logrus.StandardLogger()))
+	})
+})
@@ -0,0 +1,222 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespaces
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/becheran/wildmatch-go"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// DefaultTimeout is the default timeout for listing the namespaces of a cluster
+const DefaultTimeout = 3 * time.Second
+
+// NewStore wraps the store to add a context "<context>/<namespace>" for each namespace of the cluster of a context
+// to the kubeconfigs returned by the store.
+// Only namespaces matching the wildcard pattern filter are added. An empty filter matches all namespaces.
+func NewStore(upstream store.KubeconfigStore, timeout time.Duration, filter string) store.KubeconfigStore {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	s := &namespaceStore{
+		upstream:   upstream,
+		timeout:    timeout,
+		namespaces: map[string][]string{},
+	}
+	if len(filter) > 0 {
+		s.filter = wildmatch.NewWildMatch(filter)
+	}
+	return s
+}
+
+type namespaceStore struct {
+	upstream store.KubeconfigStore
+	timeout  time.Duration
+	filter   *wildmatch.WildMatch
+
+	// namespaces caches the namespaces per kubeconfig path and context,
+	// so that the namespaces are only listed once (e.g. not again for the preview)
+	namespaces     map[string][]string
+	namespacesLock sync.Mutex
+}
+
+// GetKubeconfigForPath implements the store.KubeconfigStore interface.
+// It intercepts calls to GetKubeconfigForPath and adds a context per namespace to the fetched kubeconfig.
+// If the namespaces of a context cannot be listed, only the original context is kept.
+func (s *namespaceStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	data, err := s.upstream.GetKubeconfigForPath(ctx, path, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig with path %q: %w", path, err)
+	}
+
+	kubeconfig, err := kubeconfigutil.NewKubeconfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig with path %q: %w", path, err)
+	}
+
+	contextNames := make([]string, 0, len(config.Contexts))
+	for contextName := range config.Contexts {
+		contextNames = append(contextNames, contextName)
+	}
+	sort.Strings(contextNames)
+
+	modified := false
+	for _, contextName := range contextNames {
+		for _, namespace := range s.getNamespaces(ctx, path, contextName, config) {
+			derivedContextName := fmt.Sprintf("%s/%s", contextName, namespace)
+			if _, exists := config.Contexts[derivedContextName]; exists {
+				continue
+			}
+
+			if err := kubeconfig.CopyContext(contextName, derivedContextName); err != nil {
+				return nil, err
+			}
+			if err := kubeconfig.SetNamespace(derivedContextName, namespace); err != nil {
+				return nil, err
+			}
+			modified = true
+		}
+	}
+
+	if !modified {
+		return data, nil
+	}
+	return kubeconfig.GetBytes()
+}
+
+// getNamespaces returns the namespaces matching the filter for the context of the kubeconfig with the given path
+func (s *namespaceStore) getNamespaces(ctx context.Context, path, contextName string, config *clientcmdapi.Config) []string {
+	key := fmt.Sprintf("%s/%s", path, contextName)
+
+	s.namespacesLock.Lock()
+	namespaces, ok := s.namespaces[key]
+	s.namespacesLock.Unlock()
+	if ok {
+		return namespaces
+	}
+
+	// do not hold the lock while listing, so that the namespaces of different clusters are listed concurrently
+	namespaces, err := s.listNamespaces(ctx, contextName, config)
+	if err != nil {
+		s.GetLogger().Debugf("failed to list namespaces for context %q of kubeconfig with path %q: %v", contextName, path, err)
+	}
+
+	// also remember failures, so that unreachable clusters do not delay every subsequent read
+	s.namespacesLock.Lock()
+	s.namespaces[key] = namespaces
+	s.namespacesLock.Unlock()
+	return namespaces
+}
+
+// listNamespaces lists the namespaces matching the filter using the credentials of the given context
+func (s *namespaceStore) listNamespaces(ctx context.Context, contextName string, config *clientcmdapi.Config) ([]string, error) {
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(*config, contextName, &clientcmd.ConfigOverrides{}, nil).ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	restConfig.Timeout = s.timeout
+
+	client, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	list, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]string, 0, len(list.Items))
+	for _, namespace := range list.Items {
+		if s.filter != nil && !s.filter.IsMatch(namespace.Name) {
+			continue
+		}
+		namespaces = append(namespaces, namespace.Name)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+func (s *namespaceStore) GetID() string {
+	return s.upstream.GetID()
+}
+
+func (s *namespaceStore) GetKind() types.StoreKind {
+	return s.upstream.GetKind()
+}
+
+func (s *namespaceStore) GetContextPrefix(path string) string {
+	return s.upstream.GetContextPrefix(path)
+}
+
+func (s *namespaceStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	return s.upstream.VerifyKubeconfigPaths(ctx)
+}
+
+func (s *namespaceStore) Probe(ctx context.Context) error {
+	return s.upstream.Probe(ctx)
+}
+
+func (s *namespaceStore) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	s.upstream.StartSearch(ctx, channel)
+}
+
+func (s *namespaceStore) GetLogger() *logrus.Entry {
+	return s.upstream.GetLogger()
+}
+
+func (s *namespaceStore) Stop(ctx context.Context) error {
+	return s.upstream.Stop(ctx)
+}
+
+func (s *namespaceStore) GetStoreConfig() types.KubeconfigStore {
+	return s.upstream.GetStoreConfig()
+}
+
+func (s *namespaceStore) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
+	previewer, ok := s.upstream.(store.Previewer)
+	if !ok {
+		// if the wrapped store is not a previewer, simply return an empty string, hence causing no visual distortion
+		return "", nil
+	}
+
+	return previewer.GetSearchPreview(path, optionalTags)
+}
+
+func (s *namespaceStore) Unwrap() store.KubeconfigStore {
+	return s.upstream
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package namespaces_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/namespaces"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// fakeStore serves a single kubeconfig
+type fakeStore struct {
+	kubeconfig []byte
+}
+
+func (f *fakeStore) GetID() string                                        { return "filesystem.default" }
+func (f *fakeStore) GetKind() types.StoreKind                             { return types.StoreKindFilesystem }
+func (f *fakeStore) GetContextPrefix(string) string                       { return "" }
+func (f *fakeStore) VerifyKubeconfigPaths(context.Context) error          { return nil }
+func (f *fakeStore) Probe(context.Context) error                          { return nil }
+func (f *fakeStore) GetLogger() *logrus.Entry                             { return testutil.NewTestLogger() }
+func (f *fakeStore) GetStoreConfig() types.KubeconfigStore                { return types.KubeconfigStore{} }
+func (f *fakeStore) StartSearch(context.Context, chan store.SearchResult) {}
+func (f *fakeStore) Stop(context.Context) error                           { return nil }
+
+func (f *fakeStore) GetKubeconfigForPath(context.Context, string, map[string]string) ([]byte, error) {
+	return f.kubeconfig, nil
+}
+
+func kubeconfigForServer(server string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+kubeswitch-context: kubeswitch-field
+clusters:
+- name: dev
+  cluster:
+    server: %s
+    insecure-skip-tls-verify: true
+users:
+- name: dev
+  user:
+    token: secret
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+current-context: dev
+`, server))
+}
+
+var _ = Describe("Store", func() {
+	var (
+		server   *httptest.Server
+		requests int32
+		delay    time.Duration
+	)
+
+	BeforeEach(func() {
+		atomic.StoreInt32(&requests, 0)
+		delay = 0
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			if r.URL.Path != "/api/v1/namespaces" || r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			time.Sleep(delay)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"kind":"NamespaceList","apiVersion":"v1","items":[{"metadata":{"name":"kube-system"}},{"metadata":{"name":"team-b"}},{"metadata":{"name":"team-a"}}]}`)
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	getContexts := func(s store.KubeconfigStore) map[string]string {
+		data, err := s.GetKubeconfigForPath(context.Background(), "path", nil)
+		Expect(err).ToNot(HaveOccurred())
+		config, err := clientcmd.Load(data)
+		Expect(err).ToNot(HaveOccurred())
+
+		contexts := map[string]string{}
+		for name, kubeContext := range config.Contexts {
+			Expect(kubeContext.Cluster).To(Equal("dev"))
+			Expect(kubeContext.AuthInfo).To(Equal("dev"))
+			contexts[name] = kubeContext.Namespace
+		}
+		return contexts
+	}
+
+	It("should add a context per namespace", func() {
+		s := namespaces.NewStore(&fakeStore{kubeconfig: kubeconfigForServer(server.URL)}, 0, "")
+		Expect(getContexts(s)).To(Equal(map[string]string{
+			"dev":             "",
+			"dev/kube-system": "kube-system",
+			"dev/team-a":      "team-a",
+			"dev/team-b":      "team-b",
+		}))
+	})
+
+	It("should only add contexts for namespaces matching the filter", func() {
+		s := namespaces.NewStore(&fakeStore{kubeconfig: kubeconfigForServer(server.URL)}, 0, "team-*")
+		Expect(getContexts(s)).To(Equal(map[string]string{
+			"dev":        "",
+			"dev/team-a": "team-a",
+			"dev/team-b": "team-b",
+		}))
+	})
+
+	It("should preserve the kubeswitch fields of the kubeconfig", func() {
+		s := namespaces.NewStore(&fakeStore{kubeconfig: kubeconfigForServer(server.URL)}, 0, "")
+		data, err := s.GetKubeconfigForPath(context.Background(), "path", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("kubeswitch-context: kubeswitch-field"))
+	})
+
+	It("should list the namespaces only once per kubeconfig path and context", func() {
+		s := namespaces.NewStore(&fakeStore{kubeconfig: kubeconfigForServer(server.URL)}, 0, "")
+		getContexts(s)
+		getContexts(s)
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	})
+
+	It("should only return the original context if the namespaces cannot be listed", func() {
+		kubeconfig := kubeconfigForServer(server.URL + "/forbidden")
+		s := namespaces.NewStore(&fakeStore{kubeconfig: kubeconfig}, 0, "")
+		data, err := s.GetKubeconfigForPath(context.Background(), "path", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(kubeconfig))
+	})
+
+	It("should only return the original context if listing the namespaces times out", func() {
+		delay = 200 * time.Millisecond
+		s := namespaces.NewStore(&fakeStore{kubeconfig: kubeconfigForServer(server.URL)}, 50*time.Millisecond, "")
+		Expect(getContexts(s)).To(Equal(map[string]string{"dev": ""}))
+	})
+})
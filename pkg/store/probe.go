@@ -0,0 +1,47 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultProbeTimeout is the timeout for probing a store if the given context has no deadline
+const DefaultProbeTimeout = 5 * time.Second
+
+// probeContext returns a context bounded by the DefaultProbeTimeout unless the given context already has a deadline
+func probeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DefaultProbeTimeout)
+}
+
+// probeAsync runs the given probe for clients that do not accept a context.
+// Returns the context error if the probe does not finish in time.
+func probeAsync(ctx context.Context, probe func() error) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- probe()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,360 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awseks "github.com/aws/aws-sdk-go-v2/service/eks"
+	awsekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+	container "google.golang.org/api/container/v1"
+	"gopkg.in/yaml.v3"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// provisionPollInterval is the interval to check if the cloud provider finished creating the cluster
+	provisionPollInterval = 15 * time.Second
+	// provisionMaxWait is the maximum duration to wait for the cloud provider to create the cluster
+	provisionMaxWait = 45 * time.Minute
+)
+
+// GKEProvisionConfig is the cluster creation config for GKE clusters
+type GKEProvisionConfig struct {
+	ProjectID         string `yaml:"projectID"`
+	Location          string `yaml:"location"`
+	InitialNodeCount  int64  `yaml:"initialNodeCount"`
+	MachineType       string `yaml:"machineType"`
+	KubernetesVersion string `yaml:"kubernetesVersion"`
+}
+
+// EKSProvisionConfig is the cluster creation config for EKS clusters
+type EKSProvisionConfig struct {
+	RoleARN           string   `yaml:"roleARN"`
+	SubnetIDs         []string `yaml:"subnetIDs"`
+	SecurityGroupIDs  []string `yaml:"securityGroupIDs"`
+	KubernetesVersion string   `yaml:"kubernetesVersion"`
+}
+
+// AKSProvisionConfig is the cluster creation config for AKS clusters
+type AKSProvisionConfig struct {
+	ResourceGroup     string `yaml:"resourceGroup"`
+	Location          string `yaml:"location"`
+	NodeCount         int32  `yaml:"nodeCount"`
+	VMSize            string `yaml:"vmSize"`
+	KubernetesVersion string `yaml:"kubernetesVersion"`
+}
+
+const gkeDefaultProvisionConfig = `# ID of the Google Cloud project to create the cluster in.
+# Can be omitted if the store discovers exactly one project.
+projectID: ""
+# region (regional cluster) or zone (zonal cluster) of the cluster
+location: europe-west1
+# number of nodes per zone of the default node pool
+initialNodeCount: 1
+machineType: e2-standard-2
+# Kubernetes version of the control plane. Defaults to the version of the default release channel.
+kubernetesVersion: ""
+`
+
+const eksDefaultProvisionConfig = `# Required: ARN of the IAM role that allows EKS to manage resources on your behalf
+roleARN: ""
+# Required: IDs of at least two subnets in different availability zones
+subnetIDs: []
+# IDs of additional security groups for the control plane network interfaces
+securityGroupIDs: []
+# Kubernetes version of the control plane. Defaults to the latest version supported by EKS.
+kubernetesVersion: ""
+`
+
+const aksDefaultProvisionConfig = `# Required: existing resource group to create the cluster in
+resourceGroup: ""
+location: westeurope
+# number of nodes of the system node pool
+nodeCount: 1
+vmSize: Standard_D2s_v3
+# Kubernetes version of the cluster. Defaults to the default version of AKS.
+kubernetesVersion: ""
+`
+
+// DefaultProvisionConfig returns the documented default cluster creation config
+// for stores of the given kind
+func DefaultProvisionConfig(kind types.StoreKind) (string, error) {
+	switch kind {
+	case types.StoreKindGKE:
+		return gkeDefaultProvisionConfig, nil
+	case types.StoreKindEKS:
+		return eksDefaultProvisionConfig, nil
+	case types.StoreKindAzure:
+		return aksDefaultProvisionConfig, nil
+	default:
+		return "", fmt.Errorf("provisioning clusters is not supported for stores of kind %q", kind)
+	}
+}
+
+// parseProvisionConfig decodes the default config into out and overwrites the fields set in the given config.
+// Unknown fields are rejected to detect typos.
+func parseProvisionConfig(defaultConfig string, config []byte, out interface{}) error {
+	if err := yaml.Unmarshal([]byte(defaultConfig), out); err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(config))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse cluster config: %w", err)
+	}
+	return nil
+}
+
+// ProvisionCluster creates a GKE cluster with a default node pool and waits for the create operation to finish
+func (s *GKEStore) ProvisionCluster(ctx context.Context, name string, config []byte) (string, error) {
+	cfg := GKEProvisionConfig{}
+	if err := parseProvisionConfig(gkeDefaultProvisionConfig, config, &cfg); err != nil {
+		return "", err
+	}
+
+	if !s.IsInitialized() {
+		if err := s.InitializeGKEStore(); err != nil {
+			return "", fmt.Errorf("failed to initialize store: %w", err)
+		}
+	}
+
+	clusterNameTemplate, err := NewClusterNameTemplate(s.Config.ClusterNameTemplate, GKEDefaultClusterNameTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	// the project name is required for the kubeconfig path
+	projectName := ""
+	for n, id := range s.ProjectNameToID {
+		if id == cfg.ProjectID || (len(cfg.ProjectID) == 0 && len(s.ProjectNameToID) == 1) {
+			projectName = n
+			cfg.ProjectID = id
+		}
+	}
+	if len(projectName) == 0 {
+		if len(cfg.ProjectID) == 0 {
+			return "", fmt.Errorf("the store discovered %d projects. Please set the projectID in the cluster config", len(s.ProjectNameToID))
+		}
+		return "", fmt.Errorf("project with ID %q is not discovered by the store", cfg.ProjectID)
+	}
+
+	parent := fmt.Sprintf("projects/%s/locations/%s", cfg.ProjectID, cfg.Location)
+	operation, err := s.GkeClient.Projects.Locations.Clusters.Create(parent, &container.CreateClusterRequest{
+		Cluster: &container.Cluster{
+			Name:                  name,
+			InitialNodeCount:      cfg.InitialNodeCount,
+			InitialClusterVersion: cfg.KubernetesVersion,
+			NodeConfig: &container.NodeConfig{
+				MachineType: cfg.MachineType,
+			},
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("failed to create GKE cluster %q: %w", name, err)
+	}
+
+	// like the EKS waiter, wait at most provisionMaxWait for the operation to finish
+	ctx, cancel := context.WithTimeout(ctx, provisionMaxWait)
+	defer cancel()
+
+	operationName := fmt.Sprintf("%s/operations/%s", parent, operation.Name)
+	for operation.Status != "DONE" {
+		s.GetLogger().Debugf("waiting for operation %q with status %q", operationName, operation.Status)
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("GKE cluster %q was not created in time: %w", name, ctx.Err())
+		case <-time.After(provisionPollInterval):
+		}
+
+		operation, err = s.GkeClient.Projects.Locations.Operations.Get(operationName).Context(ctx).Do()
+		if err != nil {
+			return "", fmt.Errorf("failed to get the status of the creation of GKE cluster %q: %w", name, err)
+		}
+	}
+
+	if operation.Error != nil {
+		return "", fmt.Errorf("failed to create GKE cluster %q: %s", name, operation.Error.Message)
+	}
+
+	return clusterNameTemplate.Execute(ClusterNameTemplateData{
+		ClusterName: name,
+		Region:      cfg.Location,
+		ProjectName: projectName,
+		ProjectID:   cfg.ProjectID,
+	})
+}
+
+// ProvisionCluster creates an EKS cluster and waits until it is active.
+// EKS does not create nodes together with the cluster, node groups have to be added separately.
+func (s *EKSStore) ProvisionCluster(ctx context.Context, name string, config []byte) (string, error) {
+	cfg := EKSProvisionConfig{}
+	if err := parseProvisionConfig(eksDefaultProvisionConfig, config, &cfg); err != nil {
+		return "", err
+	}
+
+	if len(cfg.RoleARN) == 0 {
+		return "", fmt.Errorf("the roleARN has to be set in the cluster config")
+	}
+	if len(cfg.SubnetIDs) < 2 {
+		return "", fmt.Errorf("at least two subnetIDs have to be set in the cluster config")
+	}
+
+	if !s.IsInitialized() {
+		if err := s.InitializeEKSStore(); err != nil {
+			return "", fmt.Errorf("failed to initialize store: %w", err)
+		}
+	}
+
+	clusterNameTemplate, err := NewClusterNameTemplate(s.Config.ClusterNameTemplate, EKSDefaultClusterNameTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	input := &awseks.CreateClusterInput{
+		Name:    aws.String(name),
+		RoleArn: aws.String(cfg.RoleARN),
+		ResourcesVpcConfig: &awsekstypes.VpcConfigRequest{
+			SubnetIds:        cfg.SubnetIDs,
+			SecurityGroupIds: cfg.SecurityGroupIDs,
+		},
+	}
+	if len(cfg.KubernetesVersion) > 0 {
+		input.Version = aws.String(cfg.KubernetesVersion)
+	}
+
+	if _, err := s.Client.CreateCluster(ctx, input); err != nil {
+		return "", fmt.Errorf("failed to create EKS cluster %q: %w", name, err)
+	}
+
+	waiter := awseks.NewClusterActiveWaiter(s.Client, func(o *awseks.ClusterActiveWaiterOptions) {
+		o.MinDelay = provisionPollInterval
+	})
+	if err := waiter.Wait(ctx, &awseks.DescribeClusterInput{Name: aws.String(name)}, provisionMaxWait); err != nil {
+		return "", fmt.Errorf("EKS cluster %q did not become active: %w", name, err)
+	}
+
+	templateData := ClusterNameTemplateData{
+		ClusterName: name,
+		Region:      *s.Config.Region,
+		ProfileName: s.Config.Profile,
+	}
+
+	if usesTemplateVariable(s.Config.ClusterNameTemplate, "AccountID") {
+		identity, err := s.STSClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get AWS account ID: %w", err)
+		}
+		templateData.AccountID = aws.ToString(identity.Account)
+	}
+
+	return clusterNameTemplate.Execute(templateData)
+}
+
+// ProvisionCluster creates an AKS cluster with a system node pool and waits for the creation to finish
+func (s *AzureStore) ProvisionCluster(ctx context.Context, name string, config []byte) (string, error) {
+	cfg := AKSProvisionConfig{}
+	if err := parseProvisionConfig(aksDefaultProvisionConfig, config, &cfg); err != nil {
+		return "", err
+	}
+
+	if len(cfg.ResourceGroup) == 0 {
+		return "", fmt.Errorf("the resourceGroup has to be set in the cluster config")
+	}
+
+	if !s.IsInitialized() {
+		if err := s.InitializeAzureStore(); err != nil {
+			return "", fmt.Errorf("failed to initialize store: %w", err)
+		}
+	}
+
+	clusterNameTemplate, err := NewClusterNameTemplate(s.Config.ClusterNameTemplate, AzureDefaultClusterNameTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	properties := &armcontainerservice.ManagedClusterProperties{
+		DNSPrefix: ptr.To(name),
+		AgentPoolProfiles: []*armcontainerservice.ManagedClusterAgentPoolProfile{
+			{
+				Name: ptr.To("system"),
+				ManagedClusterAgentPoolProfileProperties: armcontainerservice.ManagedClusterAgentPoolProfileProperties{
+					Count:  ptr.To(cfg.NodeCount),
+					VMSize: ptr.To(cfg.VMSize),
+					Mode:   armcontainerservice.AgentPoolModeSystem.ToPtr(),
+				},
+			},
+		},
+	}
+	if len(cfg.KubernetesVersion) > 0 {
+		properties.KubernetesVersion = ptr.To(cfg.KubernetesVersion)
+	}
+
+	// creating a cluster that already exists would update the existing cluster instead of failing
+	_, err = s.AksClient.Get(ctx, cfg.ResourceGroup, name, nil)
+	if err == nil {
+		return "", fmt.Errorf("AKS cluster %q already exists in resource group %q", name, cfg.ResourceGroup)
+	}
+	var clusterNotFound *storeerrors.ErrClusterNotFound
+	if !errors.As(wrapAzureError(s.GetID(), err), &clusterNotFound) {
+		return "", fmt.Errorf("failed to check if AKS cluster %q exists: %w", name, err)
+	}
+
+	poller, err := s.AksClient.BeginCreateOrUpdate(ctx, cfg.ResourceGroup, name, armcontainerservice.ManagedCluster{
+		Resource: armcontainerservice.Resource{
+			Location: ptr.To(cfg.Location),
+		},
+		Identity: &armcontainerservice.ManagedClusterIdentity{
+			Type: armcontainerservice.ResourceIdentityTypeSystemAssigned.ToPtr(),
+		},
+		Properties: properties,
+	}, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create AKS cluster %q: %w", name, err)
+	}
+
+	// PollUntilDone only returns once the creation finished or the context is done
+	pollCtx, cancel := context.WithTimeout(ctx, provisionMaxWait)
+	defer cancel()
+
+	resp, err := poller.PollUntilDone(pollCtx, provisionPollInterval)
+	if err != nil {
+		return "", fmt.Errorf("failed to create AKS cluster %q: %w", name, err)
+	}
+
+	kubeconfigPath, err := clusterNameTemplate.Execute(ClusterNameTemplateData{
+		ClusterName:    name,
+		Region:         cfg.Location,
+		ResourceGroup:  cfg.ResourceGroup,
+		SubscriptionID: ptr.Deref(s.Config.SubscriptionID, ""),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.insertIntoClusterCache(kubeconfigPath, &resp.ManagedCluster)
+	return kubeconfigPath, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	container "google.golang.org/api/container/v1"
+	"google.golang.org/api/option"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("Provisioning clusters", func() {
+	Describe("DefaultProvisionConfig", func() {
+		It("returns the default config of supported store kinds", func() {
+			for _, kind := range []types.StoreKind{types.StoreKindGKE, types.StoreKindEKS, types.StoreKindAzure} {
+				config, err := store.DefaultProvisionConfig(kind)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(config).ToNot(BeEmpty())
+			}
+		})
+
+		It("fails for unsupported store kinds", func() {
+			_, err := store.DefaultProvisionConfig(types.StoreKindFilesystem)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("GKEStore", func() {
+		var (
+			server          *httptest.Server
+			request         *container.CreateClusterRequest
+			operationStatus string
+			s               *store.GKEStore
+		)
+
+		BeforeEach(func() {
+			request = nil
+			operationStatus = "DONE"
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == "/v1/projects/project-id/locations/europe-west3/clusters":
+					request = &container.CreateClusterRequest{}
+					Expect(json.NewDecoder(r.Body).Decode(request)).To(Succeed())
+					_, _ = w.Write([]byte(fmt.Sprintf(`{"name": "operation-1", "status": %q}`, operationStatus)))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+
+			client, err := container.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+			Expect(err).ToNot(HaveOccurred())
+
+			s = &store.GKEStore{
+				Logger:          testutil.NewTestLogger(),
+				GkeClient:       client,
+				Config:          &types.StoreConfigGKE{},
+				ProjectNameToID: map[string]string{"my-project": "project-id"},
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("creates the cluster with the default config overwritten by the given config", func() {
+			path, err := s.ProvisionCluster(context.Background(), "new-cluster", []byte("location: europe-west3\ninitialNodeCount: 2\n"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(Equal("gke_my-project--europe-west3--new-cluster"))
+
+			Expect(request).ToNot(BeNil())
+			Expect(request.Cluster.Name).To(Equal("new-cluster"))
+			Expect(request.Cluster.InitialNodeCount).To(Equal(int64(2)))
+			Expect(request.Cluster.NodeConfig.MachineType).To(Equal("e2-standard-2"))
+		})
+
+		It("rejects unknown fields in the config", func() {
+			_, err := s.ProvisionCluster(context.Background(), "new-cluster", []byte("machinetype: e2-standard-4\n"))
+			Expect(err).To(MatchError(ContainSubstring("field machinetype not found")))
+			Expect(request).To(BeNil())
+		})
+
+		It("stops waiting for the operation when the context is done", func() {
+			operationStatus = "RUNNING"
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			_, err := s.ProvisionCluster(ctx, "new-cluster", []byte("location: europe-west3\n"))
+			Expect(err).To(MatchError(ContainSubstring(`GKE cluster "new-cluster" was not created in time`)))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+			Expect(request).ToNot(BeNil())
+		})
+
+		It("fails for projects not discovered by the store", func() {
+			_, err := s.ProvisionCluster(context.Background(), "new-cluster", []byte("projectID: other\n"))
+			Expect(err).To(MatchError(ContainSubstring("is not discovered by the store")))
+		})
+	})
+
+	It("requires the resource group for AKS clusters", func() {
+		s := &store.AzureStore{Logger: testutil.NewTestLogger(), Config: &types.StoreConfigAzure{}}
+		_, err := s.ProvisionCluster(context.Background(), "new-cluster", nil)
+		Expect(err).To(MatchError(ContainSubstring("resourceGroup")))
+	})
+
+	It("requires the role and subnets for EKS clusters", func() {
+		s := &store.EKSStore{Logger: testutil.NewTestLogger(), Config: &types.StoreConfigEKS{}}
+		_, err := s.ProvisionCluster(context.Background(), "new-cluster", []byte("roleARN: arn:aws:iam::123:role/eks\nsubnetIDs: [subnet-a]\n"))
+		Expect(err).To(MatchError(ContainSubstring("two subnetIDs")))
+	})
+})
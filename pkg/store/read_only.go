@@ -0,0 +1,33 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+)
+
+// ErrStoreReadOnly is returned by operations that would write to a store marked as read-only
+//
+// Deprecated: use errors.As with *storeerrors.ErrReadOnly instead.
+var ErrStoreReadOnly error = &storeerrors.ErrReadOnly{}
+
+// CheckWritable returns a *storeerrors.ErrReadOnly if the store is marked as read-only.
+// Commands writing kubeconfigs to a store have to call it before modifying the store.
+func CheckWritable(s KubeconfigStore) error {
+	if s.GetStoreConfig().ReadOnly {
+		return &storeerrors.ErrReadOnly{StoreID: s.GetID()}
+	}
+	return nil
+}
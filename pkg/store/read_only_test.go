@@ -0,0 +1,82 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("ReadOnly", func() {
+	var (
+		tempDir         string
+		kubeconfigStore types.KubeconfigStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-read-only")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(tempDir, "config"), []byte("kind: Config"), 0600)).To(Succeed())
+
+		kubeconfigStore = types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{tempDir},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should allow writing to stores that are not read-only", func() {
+		s, err := store.NewFilesystemStore("config", kubeconfigStore)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.CheckWritable(s)).To(Succeed())
+	})
+
+	It("should reject writing to read-only stores", func() {
+		kubeconfigStore.ReadOnly = true
+		s, err := store.NewFilesystemStore("config", kubeconfigStore)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(store.CheckWritable(s)).To(MatchError(store.ErrStoreReadOnly))
+
+		lazy := store.NewLazyStore(testutil.NewTestLogger(), kubeconfigStore, func() (store.KubeconfigStore, error) { return s, nil })
+		Expect(store.CheckWritable(lazy)).To(MatchError(store.ErrStoreReadOnly))
+	})
+
+	It("should not affect searching and reading read-only stores", func() {
+		kubeconfigStore.ReadOnly = true
+		s, err := store.NewFilesystemStore("config", kubeconfigStore)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s.VerifyKubeconfigPaths(context.Background())).To(Succeed())
+
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(results).To(ConsistOf(testutil.FakeSearchResult(filepath.Join(tempDir, "config"))))
+
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), results[0].KubeconfigPath, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig)).To(Equal("kind: Config"))
+	})
+})
@@ -0,0 +1,144 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	celtypes "github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// celPathVariable is the variable of the CEL expression containing the requested path
+const celPathVariable = "path"
+
+// celEvaluator evaluates a CEL expression.
+// If the variable "path" is empty, the expression returns a list of maps with the keys path and tags,
+// e.g. [{"path": "prod", "tags": {"env": "prod"}}]. Otherwise, it returns the kubeconfig for the path.
+type celEvaluator struct {
+	ast   *cel.Ast
+	store *ScriptStore
+}
+
+func newCELEvaluator(expression string, store *ScriptStore) (*celEvaluator, error) {
+	env, err := newCELEnv(context.Background(), store)
+	if err != nil {
+		return nil, err
+	}
+
+	// the expression is not type-checked, as it returns a list or a string depending on the path
+	ast, issues := env.Parse(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("failed to compile cel expression: %w", issues.Err())
+	}
+
+	return &celEvaluator{
+		ast:   ast,
+		store: store,
+	}, nil
+}
+
+func (e *celEvaluator) list(ctx context.Context) ([]entry, error) {
+	out, err := e.eval(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// convert the result via JSON to support arbitrarily nested CEL values
+	value, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, fmt.Errorf("cel expression must return a list if the path is empty, got %s: %w", out.Type().TypeName(), err)
+	}
+	buf, err := protojson.Marshal(value.(*structpb.Value))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return nil, fmt.Errorf("cel expression must return a list of maps with the keys path and tags if the path is empty: %w", err)
+	}
+	return entries, nil
+}
+
+func (e *celEvaluator) getKubeconfig(ctx context.Context, path string) (string, error) {
+	out, err := e.eval(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	kubeconfig, ok := out.Value().(string)
+	if !ok {
+		return "", fmt.Errorf("cel expression must return a string if the path is set, got %s", out.Type().TypeName())
+	}
+	return kubeconfig, nil
+}
+
+// eval evaluates the expression with the given path.
+// The program is created for every evaluation, so that the helper functions use the given context.
+func (e *celEvaluator) eval(ctx context.Context, path string) (ref.Val, error) {
+	env, err := newCELEnv(ctx, e.store)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := env.Program(e.ast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cel program: %w", err)
+	}
+
+	out, _, err := program.ContextEval(ctx, map[string]interface{}{celPathVariable: path})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate cel expression: %w", err)
+	}
+	return out, nil
+}
+
+// newCELEnv returns the CEL environment declaring the variable path and the helper functions http.get, json.parse and env.get
+func newCELEnv(ctx context.Context, store *ScriptStore) (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable(celPathVariable, cel.StringType),
+		cel.Function("http.get",
+			cel.Overload("http_get_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(func(url ref.Val) ref.Val {
+					body, err := store.httpGet(ctx, url.Value().(string))
+					if err != nil {
+						return celtypes.WrapErr(err)
+					}
+					return celtypes.String(body)
+				}))),
+		cel.Function("json.parse",
+			cel.Overload("json_parse_string", []*cel.Type{cel.StringType}, cel.DynType,
+				cel.UnaryBinding(func(str ref.Val) ref.Val {
+					var value interface{}
+					if err := json.Unmarshal([]byte(str.Value().(string)), &value); err != nil {
+						return celtypes.NewErr("failed to parse json: %v", err)
+					}
+					return celtypes.DefaultTypeAdapter.NativeToValue(value)
+				}))),
+		cel.Function("env.get",
+			cel.Overload("env_get_string", []*cel.Type{cel.StringType}, cel.StringType,
+				cel.UnaryBinding(func(key ref.Val) ref.Val {
+					return celtypes.String(os.Getenv(key.Value().(string)))
+				}))),
+	)
+}
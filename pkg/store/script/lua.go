@@ -0,0 +1,227 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Shopify/go-lua"
+)
+
+const (
+	// luaListFunction is the function of the Lua script returning the kubeconfigs
+	luaListFunction = "listKubeconfigs"
+	// luaGetKubeconfigFunction is the function of the Lua script returning the kubeconfig for a path
+	luaGetKubeconfigFunction = "getKubeconfig"
+	// luaExecutionTimeout is the maximum duration of a single run of the Lua script, including its requests
+	luaExecutionTimeout = 5 * time.Minute
+	// luaHookInstructions is the number of instructions after which the running script checks if it has to stop
+	luaHookInstructions = 1000
+)
+
+// luaEvaluator evaluates a Lua script defining the functions listKubeconfigs() and getKubeconfig(path).
+// listKubeconfigs() returns a list of tables with the fields path and tags, e.g. {{path = "prod", tags = {env = "prod"}}}.
+type luaEvaluator struct {
+	script string
+	name   string
+	store  *ScriptStore
+}
+
+func newLuaEvaluator(script, name string, store *ScriptStore) (*luaEvaluator, error) {
+	// only load the script to check its syntax
+	if err := lua.LoadBuffer(lua.NewState(), script, name, "t"); err != nil {
+		return nil, fmt.Errorf("failed to compile lua script: %v", err)
+	}
+
+	return &luaEvaluator{
+		script: script,
+		name:   name,
+		store:  store,
+	}, nil
+}
+
+func (e *luaEvaluator) list(ctx context.Context) ([]entry, error) {
+	l, err := e.call(ctx, luaListFunction)
+	if err != nil {
+		return nil, err
+	}
+
+	if !l.IsTable(-1) {
+		return nil, fmt.Errorf("%s() must return a list, got %s", luaListFunction, lua.TypeNameOf(l, -1))
+	}
+
+	var entries []entry
+	for i := 1; i <= l.RawLength(-1); i++ {
+		l.RawGetInt(-1, i)
+		entry, err := luaToEntry(l)
+		if err != nil {
+			return nil, fmt.Errorf("invalid element %d returned by %s(): %w", i, luaListFunction, err)
+		}
+		entries = append(entries, entry)
+		l.Pop(1)
+	}
+	return entries, nil
+}
+
+func (e *luaEvaluator) getKubeconfig(ctx context.Context, path string) (string, error) {
+	l, err := e.call(ctx, luaGetKubeconfigFunction, path)
+	if err != nil {
+		return "", err
+	}
+
+	kubeconfig, ok := l.ToString(-1)
+	if !ok {
+		return "", fmt.Errorf("%s(path) must return a string, got %s", luaGetKubeconfigFunction, lua.TypeNameOf(l, -1))
+	}
+	return kubeconfig, nil
+}
+
+// call runs the script in a new Lua state and calls the global function with the given string arguments.
+// The result of the function is on the top of the stack of the returned state.
+// The script is stopped once the context is done or after luaExecutionTimeout, e.g. if it loops forever.
+func (e *luaEvaluator) call(ctx context.Context, function string, args ...string) (*lua.State, error) {
+	ctx, cancel := context.WithTimeout(ctx, luaExecutionTimeout)
+	defer cancel()
+
+	l := lua.NewState()
+	lua.OpenLibraries(l)
+	e.registerHelpers(ctx, l)
+	lua.SetDebugHook(l, func(l *lua.State, _ lua.Debug) {
+		if err := ctx.Err(); err != nil {
+			lua.Errorf(l, "script stopped: %s", err.Error())
+		}
+	}, lua.MaskCount, luaHookInstructions)
+
+	if err := lua.LoadBuffer(l, e.script, e.name, "t"); err != nil {
+		return nil, fmt.Errorf("failed to compile lua script: %v", err)
+	}
+	if err := l.ProtectedCall(0, 0, 0); err != nil {
+		return nil, fmt.Errorf("failed to run lua script: %v", err)
+	}
+
+	l.Global(function)
+	if !l.IsFunction(-1) {
+		return nil, fmt.Errorf("lua script does not define the function %s", function)
+	}
+	for _, arg := range args {
+		l.PushString(arg)
+	}
+	if err := l.ProtectedCall(len(args), 1, 0); err != nil {
+		return nil, fmt.Errorf("failed to call %s: %v", function, err)
+	}
+	return l, nil
+}
+
+// registerHelpers registers the global tables http, json and env containing the helper functions
+func (e *luaEvaluator) registerHelpers(ctx context.Context, l *lua.State) {
+	registerLibrary(l, "http", []lua.RegistryFunction{{Name: "get", Function: func(l *lua.State) int {
+		body, err := e.store.httpGet(ctx, lua.CheckString(l, 1))
+		if err != nil {
+			lua.Errorf(l, "%s", err.Error())
+		}
+		l.PushString(body)
+		return 1
+	}}})
+
+	registerLibrary(l, "json", []lua.RegistryFunction{{Name: "parse", Function: func(l *lua.State) int {
+		var value interface{}
+		if err := json.Unmarshal([]byte(lua.CheckString(l, 1)), &value); err != nil {
+			lua.Errorf(l, "failed to parse json: %s", err.Error())
+		}
+		pushValue(l, value)
+		return 1
+	}}})
+
+	registerLibrary(l, "env", []lua.RegistryFunction{{Name: "get", Function: func(l *lua.State) int {
+		l.PushString(os.Getenv(lua.CheckString(l, 1)))
+		return 1
+	}}})
+}
+
+func registerLibrary(l *lua.State, name string, functions []lua.RegistryFunction) {
+	lua.NewLibrary(l, functions)
+	l.SetGlobal(name)
+}
+
+// pushValue pushes the value decoded from JSON onto the stack
+func pushValue(l *lua.State, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		l.CreateTable(0, len(v))
+		for key, fieldValue := range v {
+			pushValue(l, fieldValue)
+			l.SetField(-2, key)
+		}
+	case []interface{}:
+		l.CreateTable(len(v), 0)
+		for i, item := range v {
+			pushValue(l, item)
+			l.RawSetInt(-2, i+1)
+		}
+	case string:
+		l.PushString(v)
+	case float64:
+		l.PushNumber(v)
+	case bool:
+		l.PushBoolean(v)
+	default:
+		l.PushNil()
+	}
+}
+
+// luaToEntry converts the table on the top of the stack to an entry
+func luaToEntry(l *lua.State) (entry, error) {
+	if !l.IsTable(-1) {
+		return entry{}, fmt.Errorf("expected a table, got %s", lua.TypeNameOf(l, -1))
+	}
+
+	result := entry{}
+
+	l.Field(-1, "path")
+	path, ok := l.ToString(-1)
+	if !ok && !l.IsNil(-1) {
+		return entry{}, fmt.Errorf("field path must be a string, got %s", lua.TypeNameOf(l, -1))
+	}
+	result.Path = path
+	l.Pop(1)
+
+	l.Field(-1, "tags")
+	defer l.Pop(1)
+	if l.IsNil(-1) {
+		return result, nil
+	}
+	if !l.IsTable(-1) {
+		return entry{}, fmt.Errorf("field tags must be a table, got %s", lua.TypeNameOf(l, -1))
+	}
+
+	result.Tags = map[string]string{}
+	for l.PushNil(); l.Next(-2); l.Pop(1) {
+		// the key must not be converted, as this would confuse the traversal of the table
+		if l.TypeOf(-2) != lua.TypeString {
+			return entry{}, fmt.Errorf("the keys of the tags must be strings, got %s", lua.TypeNameOf(l, -2))
+		}
+		key, _ := l.ToString(-2)
+		value, ok := l.ToString(-1)
+		if !ok {
+			return entry{}, fmt.Errorf("the value of the tag %q must be a string, got %s", key, lua.TypeNameOf(l, -1))
+		}
+		result.Tags[key] = value
+	}
+	return result, nil
+}
@@ -0,0 +1,264 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package script
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// defaultHTTPRequestTimeout is the maximum duration of a request of the http.get helper function
+const defaultHTTPRequestTimeout = 30 * time.Second
+
+// entry is a kubeconfig generated by the script
+type entry struct {
+	Path string            `json:"path"`
+	Tags map[string]string `json:"tags"`
+}
+
+// evaluator evaluates the script of the store
+type evaluator interface {
+	// list returns the kubeconfigs generated by the script
+	list(ctx context.Context) ([]entry, error)
+	// getKubeconfig returns the kubeconfig generated by the script for the given path
+	getKubeconfig(ctx context.Context, path string) (string, error)
+}
+
+// ScriptStore generates the kubeconfigs with a user-defined Lua script or CEL expression,
+// e.g. to discover the clusters of an internal CMDB API.
+// The script can use the helper functions http.get(url), json.parse(str) and env.get(key).
+type ScriptStore struct {
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	Config          *types.StoreConfigScript
+	// language is the language of the script, either lua or cel
+	language string
+	// client is used by the http.get helper function
+	client *http.Client
+}
+
+// NewScriptStore creates a new script store.
+// The script is compiled to report syntax errors early, but only evaluated when searching.
+func NewScriptStore(kubeconfigStore types.KubeconfigStore) (*ScriptStore, error) {
+	scriptStoreConfig := &types.StoreConfigScript{}
+	if kubeconfigStore.Config != nil {
+		buf, err := yaml.Marshal(kubeconfigStore.Config)
+		if err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to process script store config: %w", err))
+		}
+
+		if err := yaml.Unmarshal(buf, scriptStoreConfig); err != nil {
+			return nil, invalidConfig(kubeconfigStore, fmt.Errorf("failed to unmarshal script store config: %w", err))
+		}
+	}
+
+	if len(scriptStoreConfig.ScriptFile) == 0 {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("the script store requires the \"config.scriptFile\""))
+	}
+
+	language := scriptStoreConfig.ScriptLanguage
+	if len(language) == 0 {
+		language = types.ScriptLanguageLua
+		if filepath.Ext(scriptStoreConfig.ScriptFile) == ".cel" {
+			language = types.ScriptLanguageCEL
+		}
+	}
+	if language != types.ScriptLanguageLua && language != types.ScriptLanguageCEL {
+		return nil, invalidConfig(kubeconfigStore, fmt.Errorf("unsupported script language %q. Supported languages are %q", language, []string{types.ScriptLanguageLua, types.ScriptLanguageCEL}))
+	}
+
+	s := &ScriptStore{
+		Logger:          logrus.New().WithField("store", types.StoreKindScript),
+		KubeconfigStore: kubeconfigStore,
+		Config:          scriptStoreConfig,
+		language:        language,
+		client:          store.NewHTTPClient(defaultHTTPRequestTimeout),
+	}
+
+	if _, err := s.newEvaluator(); err != nil {
+		return nil, invalidConfig(kubeconfigStore, err)
+	}
+	return s, nil
+}
+
+// newEvaluator reads and compiles the script file.
+// The file is read for every search, so that changes to the script are picked up.
+func (s *ScriptStore) newEvaluator() (evaluator, error) {
+	script, err := os.ReadFile(s.Config.ScriptFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script file: %w", err)
+	}
+
+	if s.language == types.ScriptLanguageCEL {
+		return newCELEvaluator(string(script), s)
+	}
+	return newLuaEvaluator(string(script), s.Config.ScriptFile, s)
+}
+
+func (s *ScriptStore) GetID() string {
+	id := "default"
+	if s.KubeconfigStore.ID != nil {
+		id = *s.KubeconfigStore.ID
+	}
+	return fmt.Sprintf("%s.%s", types.StoreKindScript, id)
+}
+
+func (s *ScriptStore) GetKind() types.StoreKind {
+	return types.StoreKindScript
+}
+
+// GetContextPrefix returns the path generated by the script
+func (s *ScriptStore) GetContextPrefix(path string) string {
+	if s.GetStoreConfig().ShowPrefix != nil && !*s.GetStoreConfig().ShowPrefix {
+		return ""
+	}
+	return path
+}
+
+func (s *ScriptStore) GetStoreConfig() types.KubeconfigStore {
+	return s.KubeconfigStore
+}
+
+func (s *ScriptStore) GetLogger() *logrus.Entry {
+	return s.Logger
+}
+
+// Stop closes the idle connections of the HTTP client of the http.get helper function
+func (s *ScriptStore) Stop(_ context.Context) error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+func (s *ScriptStore) VerifyKubeconfigPaths(_ context.Context) error {
+	// the kubeconfigs are determined by the script
+	return nil
+}
+
+// Probe checks that the script file can be read and compiled.
+// The script is not evaluated, as it could have side effects.
+func (s *ScriptStore) Probe(_ context.Context) error {
+	_, err := s.newEvaluator()
+	return err
+}
+
+// StartSearch sends the paths of the kubeconfigs generated by the script
+func (s *ScriptStore) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	entries, err := s.list(ctx)
+	if err != nil {
+		channel <- store.SearchResult{
+			KubeconfigPath: "",
+			Error:          err,
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		select {
+		case channel <- store.SearchResult{
+			KubeconfigPath: entry.Path,
+			Tags:           entry.Tags,
+			Error:          nil,
+		}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// list evaluates the script and returns the generated kubeconfigs with a path
+func (s *ScriptStore) list(ctx context.Context) ([]entry, error) {
+	evaluator, err := s.newEvaluator()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := evaluator.list(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kubeconfigs with script %q: %w", s.Config.ScriptFile, err)
+	}
+
+	result := make([]entry, 0, len(entries))
+	for _, entry := range entries {
+		if len(entry.Path) == 0 {
+			s.Logger.Debugf("skipping kubeconfig generated by the script without path")
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+func (s *ScriptStore) GetKubeconfigForPath(ctx context.Context, path string, _ map[string]string) ([]byte, error) {
+	s.Logger.Debugf("Script: get kubeconfig for path %s", path)
+
+	evaluator, err := s.newEvaluator()
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfig, err := evaluator.getKubeconfig(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig for path %q with script %q: %w", path, s.Config.ScriptFile, err)
+	}
+	if len(kubeconfig) == 0 {
+		return nil, fmt.Errorf("script %q returned an empty kubeconfig for path %q", s.Config.ScriptFile, path)
+	}
+	return []byte(kubeconfig), nil
+}
+
+// httpGet implements the http.get helper function returning the body of the response
+func (s *ScriptStore) httpGet(ctx context.Context, url string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response of %q: %w", url, err)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return "", fmt.Errorf("request to %q failed with status %d: %s", url, response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return string(body), nil
+}
+
+func invalidConfig(kubeconfigStore types.KubeconfigStore, err error) error {
+	id := "default"
+	if kubeconfigStore.ID != nil {
+		id = *kubeconfigStore.ID
+	}
+	return &storeerrors.ErrInvalidConfig{StoreID: fmt.Sprintf("%s.%s", types.StoreKindScript, id), Err: err}
+}
@@ -0,0 +1,247 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package script_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/script"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const luaScript = `
+function listKubeconfigs()
+  local clusters = json.parse(http.get(env.get("CMDB_URL") .. "/clusters"))
+  local result = {}
+  for i, cluster in ipairs(clusters) do
+    result[i] = {path = cluster.name, tags = {env = cluster.env}}
+  end
+  return result
+end
+
+function getKubeconfig(path)
+  return http.get(env.get("CMDB_URL") .. "/clusters/" .. path .. "/kubeconfig")
+end
+`
+
+const celExpression = `
+path == ""
+  ? json.parse(http.get(env.get("CMDB_URL") + "/clusters")).map(c, {"path": c.name, "tags": {"env": c.env}})
+  : http.get(env.get("CMDB_URL") + "/clusters/" + path + "/kubeconfig")
+`
+
+var _ = Describe("ScriptStore", func() {
+	var (
+		server  *httptest.Server
+		tempDir string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/clusters":
+				_, _ = w.Write([]byte(`[{"name": "prod", "env": "production"}, {"name": "dev", "env": "development"}]`))
+			case "/clusters/prod/kubeconfig":
+				_, _ = w.Write([]byte("kubeconfig-prod"))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte("cluster not found"))
+			}
+		}))
+		Expect(os.Setenv("CMDB_URL", server.URL)).To(Succeed())
+
+		var err error
+		tempDir, err = os.MkdirTemp("", "script-store")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.Unsetenv("CMDB_URL")).To(Succeed())
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	newStore := func(fileName, content string, config map[string]interface{}) (*script.ScriptStore, error) {
+		scriptFile := filepath.Join(tempDir, fileName)
+		Expect(os.WriteFile(scriptFile, []byte(content), 0644)).To(Succeed())
+
+		if config == nil {
+			config = map[string]interface{}{}
+		}
+		config["scriptFile"] = scriptFile
+		return script.NewScriptStore(types.KubeconfigStore{
+			Kind:   types.StoreKindScript,
+			Config: config,
+		})
+	}
+
+	search := func(s store.KubeconfigStore) []store.SearchResult {
+		channel := make(chan store.SearchResult)
+		go func() {
+			defer close(channel)
+			s.StartSearch(context.Background(), channel)
+		}()
+
+		var results []store.SearchResult
+		for result := range channel {
+			results = append(results, result)
+		}
+		return results
+	}
+
+	for _, language := range []struct {
+		name     string
+		fileName string
+		content  string
+	}{
+		{name: "lua", fileName: "cmdb.lua", content: luaScript},
+		{name: "cel", fileName: "cmdb.cel", content: celExpression},
+	} {
+		language := language
+
+		Describe(language.name, func() {
+			It("should discover the kubeconfigs returned by the script", func() {
+				s, err := newStore(language.fileName, language.content, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(s.GetID()).To(Equal("script.default"))
+
+				Expect(search(s)).To(ConsistOf(
+					store.SearchResult{KubeconfigPath: "prod", Tags: map[string]string{"env": "production"}},
+					store.SearchResult{KubeconfigPath: "dev", Tags: map[string]string{"env": "development"}},
+				))
+			})
+
+			It("should return the kubeconfig returned by the script", func() {
+				s, err := newStore(language.fileName, language.content, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				kubeconfig, err := s.GetKubeconfigForPath(context.Background(), "prod", nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(kubeconfig)).To(Equal("kubeconfig-prod"))
+			})
+
+			It("should fail if a request of the script fails", func() {
+				s, err := newStore(language.fileName, language.content, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = s.GetKubeconfigForPath(context.Background(), "unknown", nil)
+				Expect(err).To(MatchError(ContainSubstring("cluster not found")))
+			})
+
+			It("should pick up changes to the script", func() {
+				s, err := newStore(language.fileName, language.content, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				updated := strings.ReplaceAll(language.content, "/kubeconfig", "/unknown")
+				Expect(os.WriteFile(s.Config.ScriptFile, []byte(updated), 0644)).To(Succeed())
+
+				_, err = s.GetKubeconfigForPath(context.Background(), "prod", nil)
+				Expect(err).To(MatchError(ContainSubstring("status 404")))
+			})
+		})
+	}
+
+	It("should use the configured script language", func() {
+		s, err := newStore("cmdb.script", celExpression, map[string]interface{}{"scriptLanguage": types.ScriptLanguageCEL})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(search(s)).To(HaveLen(2))
+	})
+
+	It("should skip kubeconfigs without path", func() {
+		s, err := newStore("cmdb.lua", `function listKubeconfigs() return {{path = "prod"}, {tags = {env = "dev"}}} end`, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(search(s)).To(ConsistOf(store.SearchResult{KubeconfigPath: "prod"}))
+	})
+
+	It("should return an error if the lua script returns an invalid result", func() {
+		s, err := newStore("cmdb.lua", `function listKubeconfigs() return {{path = "prod", tags = "prod"}} end`, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		results := search(s)
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Error).To(MatchError(ContainSubstring("field tags must be a table")))
+	})
+
+	It("should return an error if the lua script does not define the function", func() {
+		s, err := newStore("cmdb.lua", `function listKubeconfigs() return {} end`, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(search(s)).To(BeEmpty())
+
+		_, err = s.GetKubeconfigForPath(context.Background(), "prod", nil)
+		Expect(err).To(MatchError(ContainSubstring("does not define the function getKubeconfig")))
+	})
+
+	It("should return an error if the cel expression does not return a list", func() {
+		s, err := newStore("cmdb.cel", `"prod"`, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		results := search(s)
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Error).To(MatchError(ContainSubstring("must return a list of maps")))
+	})
+
+	It("should stop a lua script that does not finish once the context is done", func() {
+		s, err := newStore("cmdb.lua", `
+function listKubeconfigs() return {} end
+function getKubeconfig(path)
+  while true do end
+end
+`, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err = s.GetKubeconfigForPath(ctx, "prod", nil)
+		Expect(err).To(MatchError(ContainSubstring("failed to call getKubeconfig: runtime error: script stopped: context deadline exceeded")))
+	})
+
+	It("should stop a lua script that does not finish loading once the context is done", func() {
+		s, err := newStore("cmdb.lua", `
+local i = 0
+while true do i = i + 1 end
+`, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = s.GetKubeconfigForPath(ctx, "prod", nil)
+		Expect(err).To(MatchError(ContainSubstring("failed to run lua script: runtime error: script stopped: context canceled")))
+	})
+
+	It("should fail if the script does not compile", func() {
+		_, err := newStore("cmdb.lua", "function listKubeconfigs(", nil)
+		Expect(errors.As(err, new(*storeerrors.ErrInvalidConfig))).To(BeTrue())
+
+		_, err = newStore("cmdb.cel", "path ==", nil)
+		Expect(errors.As(err, new(*storeerrors.ErrInvalidConfig))).To(BeTrue())
+	})
+
+	It("should fail for an unsupported script language", func() {
+		_, err := newStore("cmdb.js", "", map[string]interface{}{"scriptLanguage": "javascript"})
+		Expect(err).To(MatchError(ContainSubstring("unsupported script language")))
+	})
+})
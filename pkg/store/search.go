@@ -0,0 +1,51 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import "context"
+
+// sendSearchResult sends the search result unless the search is cancelled.
+// Returns false if the search is cancelled and should be stopped.
+func sendSearchResult(ctx context.Context, channel chan SearchResult, result SearchResult) bool {
+	select {
+	case channel <- result:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// callWithContext calls a client that does not accept a context and returns once the context is done.
+// The call itself is not aborted, but its result is discarded.
+func callWithContext[T any](ctx context.Context, call func() (T, error)) (T, error) {
+	type response struct {
+		value T
+		err   error
+	}
+
+	responses := make(chan response, 1)
+	go func() {
+		value, err := call()
+		responses <- response{value: value, err: err}
+	}()
+
+	select {
+	case r := <-responses:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
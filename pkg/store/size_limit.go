@@ -0,0 +1,113 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// DefaultMaxKubeconfigSize is the maximum size in bytes of a kubeconfig returned by a store if not configured otherwise
+const DefaultMaxKubeconfigSize = 1 << 20
+
+// NewSizeLimitedStore wraps the store to reject kubeconfigs larger than maxSize bytes
+// with a *storeerrors.ErrKubeconfigTooLarge before they are parsed.
+// Uses the DefaultMaxKubeconfigSize if maxSize is not positive.
+func NewSizeLimitedStore(upstream KubeconfigStore, maxSize int) KubeconfigStore {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxKubeconfigSize
+	}
+	return &sizeLimitedStore{
+		upstream: upstream,
+		maxSize:  maxSize,
+	}
+}
+
+type sizeLimitedStore struct {
+	upstream KubeconfigStore
+	maxSize  int
+}
+
+// GetKubeconfigForPath implements the KubeconfigStore interface.
+// It intercepts calls to GetKubeconfigForPath and checks the size of the fetched kubeconfig.
+func (s *sizeLimitedStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	kubeconfig, err := s.upstream.GetKubeconfigForPath(ctx, path, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(kubeconfig) > s.maxSize {
+		return nil, &storeerrors.ErrKubeconfigTooLarge{
+			StoreID: s.upstream.GetID(),
+			Err:     fmt.Errorf("kubeconfig %q has %d bytes, exceeding the maximum of %d bytes", path, len(kubeconfig), s.maxSize),
+		}
+	}
+	return kubeconfig, nil
+}
+
+func (s *sizeLimitedStore) GetID() string {
+	return s.upstream.GetID()
+}
+
+func (s *sizeLimitedStore) GetKind() types.StoreKind {
+	return s.upstream.GetKind()
+}
+
+func (s *sizeLimitedStore) GetContextPrefix(path string) string {
+	return s.upstream.GetContextPrefix(path)
+}
+
+func (s *sizeLimitedStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	return s.upstream.VerifyKubeconfigPaths(ctx)
+}
+
+func (s *sizeLimitedStore) Probe(ctx context.Context) error {
+	return s.upstream.Probe(ctx)
+}
+
+func (s *sizeLimitedStore) StartSearch(ctx context.Context, channel chan SearchResult) {
+	s.upstream.StartSearch(ctx, channel)
+}
+
+func (s *sizeLimitedStore) GetLogger() *logrus.Entry {
+	return s.upstream.GetLogger()
+}
+
+func (s *sizeLimitedStore) Stop(ctx context.Context) error {
+	return s.upstream.Stop(ctx)
+}
+
+func (s *sizeLimitedStore) GetStoreConfig() types.KubeconfigStore {
+	return s.upstream.GetStoreConfig()
+}
+
+func (s *sizeLimitedStore) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
+	previewer, ok := s.upstream.(Previewer)
+	if !ok {
+		// if the wrapped store is not a previewer, simply return an empty string, hence causing no visual distortion
+		return "", nil
+	}
+
+	return previewer.GetSearchPreview(path, optionalTags)
+}
+
+func (s *sizeLimitedStore) Unwrap() KubeconfigStore {
+	return s.upstream
+}
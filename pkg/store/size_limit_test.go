@@ -0,0 +1,83 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("SizeLimitedStore", func() {
+	var (
+		tempDir         string
+		kubeconfigPath  string
+		filesystemStore store.KubeconfigStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-size-limit")
+		Expect(err).ToNot(HaveOccurred())
+
+		kubeconfigPath = filepath.Join(tempDir, "config")
+		Expect(os.WriteFile(kubeconfigPath, make([]byte, 100), 0600)).To(Succeed())
+
+		filesystemStore, err = store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{tempDir},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should return kubeconfigs within the limit", func() {
+		s := store.NewSizeLimitedStore(filesystemStore, 100)
+
+		kubeconfig, err := s.GetKubeconfigForPath(context.Background(), kubeconfigPath, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(kubeconfig).To(HaveLen(100))
+		Expect(store.Unwrap(s)).To(BeIdenticalTo(filesystemStore))
+	})
+
+	It("should reject kubeconfigs exceeding the limit", func() {
+		s := store.NewSizeLimitedStore(filesystemStore, 99)
+
+		_, err := s.GetKubeconfigForPath(context.Background(), kubeconfigPath, nil)
+		var tooLarge *storeerrors.ErrKubeconfigTooLarge
+		Expect(errors.As(err, &tooLarge)).To(BeTrue())
+		Expect(tooLarge.StoreID).To(Equal("filesystem.default"))
+		Expect(err.Error()).To(ContainSubstring("has 100 bytes, exceeding the maximum of 99 bytes"))
+	})
+
+	It("should use the default limit if no limit is configured", func() {
+		Expect(os.WriteFile(kubeconfigPath, make([]byte, store.DefaultMaxKubeconfigSize+1), 0600)).To(Succeed())
+		s := store.NewSizeLimitedStore(filesystemStore, 0)
+
+		_, err := s.GetKubeconfigForPath(context.Background(), kubeconfigPath, nil)
+		Expect(errors.Is(err, &storeerrors.ErrKubeconfigTooLarge{})).To(BeTrue())
+	})
+})
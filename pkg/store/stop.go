@@ -0,0 +1,75 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultStopTimeout is the timeout for stopping the stores if the given context has no deadline
+const DefaultStopTimeout = 5 * time.Second
+
+// StopAll stops the given stores concurrently.
+// Returns the errors of all stores or the context error if not all stores stopped before the context is done.
+// Uses the DefaultStopTimeout if the context has no deadline.
+func StopAll(ctx context.Context, stores []KubeconfigStore) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultStopTimeout)
+		defer cancel()
+	}
+
+	errs := make([]error, len(stores))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for i, s := range stores {
+			wg.Add(1)
+			go func(i int, s KubeconfigStore) {
+				defer wg.Done()
+				if err := s.Stop(ctx); err != nil {
+					errs[i] = fmt.Errorf("failed to stop store %q: %w", s.GetID(), err)
+				}
+			}(i, s)
+		}
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+		return errors.Join(errs...)
+	case <-ctx.Done():
+		return fmt.Errorf("not all stores stopped in time: %w", ctx.Err())
+	}
+}
+
+// stopOnce releases the resources of a store only on the first call of Stop, so that Stop is idempotent
+type stopOnce struct {
+	once sync.Once
+	err  error
+}
+
+// Do calls stop on the first call and returns its error on every call
+func (s *stopOnce) Do(stop func() error) error {
+	s.once.Do(func() {
+		s.err = stop()
+	})
+	return s.err
+}
@@ -0,0 +1,239 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice"
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	managementClient "github.com/rancher/rancher/pkg/client/generated/management/v3"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	container "google.golang.org/api/container/v1"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("Stop", func() {
+	Describe("StopAll", func() {
+		It("should stop all stores", func() {
+			stores := []*fakeStore{{id: "a"}, {id: "b"}}
+			Expect(store.StopAll(context.Background(), []store.KubeconfigStore{stores[0], stores[1]})).To(Succeed())
+			Expect(stores[0].stops).To(Equal(1))
+			Expect(stores[1].stops).To(Equal(1))
+		})
+
+		It("should return the errors of all stores", func() {
+			failing := &fakeStore{id: "failing", stopErr: errors.New("connection reset")}
+			Expect(store.StopAll(context.Background(), []store.KubeconfigStore{&fakeStore{id: "a"}, failing})).To(MatchError(`failed to stop store "failing": connection reset`))
+		})
+
+		It("should not wait for stores longer than the timeout", func() {
+			slow := &fakeStore{id: "slow", stopDelay: time.Minute}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			err := store.StopAll(ctx, []store.KubeconfigStore{slow})
+			Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		})
+	})
+
+	It("should stop the primary and the fallback store", func() {
+		primary, fallback := &fakeStore{id: "primary"}, &fakeStore{id: "fallback"}
+		s := &store.FallbackStore{Primary: primary, Fallback: fallback}
+
+		Expect(s.Stop(context.Background())).To(Succeed())
+		Expect(s.Stop(context.Background())).To(Succeed())
+		Expect(primary.stops).To(Equal(2))
+		Expect(fallback.stops).To(Equal(2))
+	})
+
+	It("should only close the client of the etcd store once", func() {
+		client, err := clientv3.New(clientv3.Config{Endpoints: []string{"127.0.0.1:2379"}})
+		Expect(err).ToNot(HaveOccurred())
+
+		s := &store.EtcdStore{Client: client}
+		Expect(s.Stop(context.Background())).To(Succeed())
+		Expect(s.Stop(context.Background())).To(Succeed())
+	})
+
+	It("should not create a lazy store to stop it", func() {
+		s := store.NewLazyStore(testutil.NewTestLogger(), types.KubeconfigStore{Kind: types.StoreKindFilesystem}, func() (store.KubeconfigStore, error) {
+			Fail("the store must not be created")
+			return nil, nil
+		})
+		Expect(s.Stop(context.Background())).To(Succeed())
+		Expect(s.Stop(context.Background())).To(Succeed())
+	})
+
+	It("should stop the store created by a lazy store", func() {
+		upstream := &fakeStore{id: "upstream"}
+		s := store.NewLazyStore(testutil.NewTestLogger(), types.KubeconfigStore{Kind: types.StoreKindFilesystem}, func() (store.KubeconfigStore, error) {
+			return upstream, nil
+		})
+		Expect(s.Initialize(context.Background())).To(Succeed())
+
+		Expect(s.Stop(context.Background())).To(Succeed())
+		Expect(s.Stop(context.Background())).To(Succeed())
+		Expect(upstream.stops).To(Equal(2))
+	})
+
+	Describe("closing the connections", func() {
+		var (
+			server *httptest.Server
+			closed atomic.Int32
+		)
+
+		BeforeEach(func() {
+			closed.Store(0)
+			server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`"127.0.0.1:8300"`))
+			}))
+			server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+				if state == http.StateClosed {
+					closed.Add(1)
+				}
+			}
+			server.Start()
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should close the idle connections of the WebDAV store", func() {
+			s, err := store.NewWebDAVStore("config", types.KubeconfigStore{
+				Kind:   types.StoreKindWebDAV,
+				Config: map[string]interface{}{"url": server.URL},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			_, err = s.GetKubeconfigForPath(context.Background(), "config", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Consistently(closed.Load, "50ms").Should(BeZero())
+
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Eventually(closed.Load).Should(BeEquivalentTo(1))
+		})
+
+		It("should close the idle connections of the Consul store", func() {
+			s, err := store.NewConsulStore("config", types.KubeconfigStore{
+				Kind:   types.StoreKindConsul,
+				Paths:  []string{"kubeconfigs"},
+				Config: map[string]interface{}{"address": server.URL},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.Probe(context.Background())).To(Succeed())
+			Consistently(closed.Load, "50ms").Should(BeZero())
+
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Eventually(closed.Load).Should(BeEquivalentTo(1))
+		})
+	})
+
+	Describe("releasing the cached state", func() {
+		It("should release the clusters discovered by the Azure store", func() {
+			s := &store.AzureStore{DiscoveredClusters: map[string]*armcontainerservice.ManagedCluster{"az_rg--aks": {}}}
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.DiscoveredClusters).To(BeEmpty())
+		})
+
+		It("should release the clusters discovered by the GKE store", func() {
+			s := &store.GKEStore{DiscoveredClusters: map[string]*container.Cluster{"gke_project--europe-west1--cluster": {}}}
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.DiscoveredClusters).To(BeEmpty())
+		})
+
+		It("should release the clusters cached by the OVH store", func() {
+			s := &store.OVHStore{OVHKubeCache: map[string]store.OVHKube{"id": {ID: "id"}}}
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.OVHKubeCache).To(BeEmpty())
+		})
+
+		It("should release the clusters cached by the Rancher store", func() {
+			s := &store.RancherStore{ClusterCache: map[string]*managementClient.Cluster{"c-1": {}}}
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.ClusterCache).To(BeEmpty())
+		})
+
+		It("should release the clusters discovered by the Scaleway store", func() {
+			s := &store.ScalewayStore{DiscoveredClusters: map[string]store.ScalewayKube{"cluster": {ID: "id"}}}
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.DiscoveredClusters).To(BeEmpty())
+		})
+
+		It("should release the Shoots cached per landscape of the Gardener store", func() {
+			landscape := &store.GardenerLandscape{CachePathToShoot: map[string]gardencorev1beta1.Shoot{"garden-dev--shoot": {}}}
+			s := &store.GardenerStore{Landscapes: []*store.GardenerLandscape{landscape}}
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(landscape.CachePathToShoot).To(BeEmpty())
+		})
+
+		It("should not find kubeconfigs with a stopped filesystem store", func() {
+			directory, err := os.MkdirTemp("", "kubeswitch-stop")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(directory)
+			Expect(os.WriteFile(filepath.Join(directory, "config"), []byte("kubeconfig"), 0600)).To(Succeed())
+
+			s, err := store.NewFilesystemStore("config", types.KubeconfigStore{Kind: types.StoreKindFilesystem, Paths: []string{directory}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(s.VerifyKubeconfigPaths(context.Background())).To(Succeed())
+
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.Stop(context.Background())).To(Succeed())
+			results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(BeEmpty())
+		})
+	})
+
+	It("should stop stores whose clients have not been created", func() {
+		for _, s := range []store.KubeconfigStore{
+			&store.AkamaiStore{},
+			&store.CapiStore{},
+			&store.ConsulStore{},
+			&store.DigitalOceanStore{},
+			&store.EKSStore{},
+			&store.EtcdStore{},
+			&store.FirestoreStore{},
+			&store.RancherStore{},
+			&store.ScalewayStore{},
+			&store.VaultStore{},
+			&store.WebDAVStore{},
+		} {
+			Expect(s.Stop(context.Background())).To(Succeed())
+			Expect(s.Stop(context.Background())).To(Succeed())
+		}
+	})
+})
@@ -0,0 +1,171 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testutil contains helpers for testing kubeconfig stores.
+// The helpers work with the standard testing package as well as with Ginkgo (via GinkgoT()),
+// so that they can also be used in the test suites of store plugins.
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+)
+
+// DefaultTimeout is a timeout for the search of a store that is sufficient for stores backed by local test servers
+const DefaultTimeout = 10 * time.Second
+
+// TestingT is the subset of *testing.T used by the assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// CollectResults runs the search of the store and returns the search results once the search is finished.
+// The errors of failed search results are joined into the returned error.
+// If the search does not finish within the timeout, the search results discovered so far are returned with an error.
+func CollectResults(s store.KubeconfigStore, timeout time.Duration) ([]store.SearchResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	channel := make(chan store.SearchResult)
+	go func() {
+		s.StartSearch(ctx, channel)
+		close(channel)
+	}()
+
+	var (
+		results []store.SearchResult
+		errs    []error
+	)
+	for {
+		select {
+		case result, ok := <-channel:
+			if !ok {
+				return results, errors.Join(errs...)
+			}
+			if result.Error != nil {
+				errs = append(errs, result.Error)
+				continue
+			}
+			results = append(results, result)
+		case <-ctx.Done():
+			// drain the channel, so that a search ignoring the context does not block forever
+			go func() {
+				for range channel {
+				}
+			}()
+			errs = append(errs, fmt.Errorf("search of store %q did not finish within %s", s.GetID(), timeout.String()))
+			return results, errors.Join(errs...)
+		}
+	}
+}
+
+// CancelSearch runs the search of the store, reads the given number of search results and cancels the search.
+// Returns an error if the search does not stop within the timeout after the cancellation,
+// e.g. because the store blocks on sending further search results or waits for a request ignoring the context.
+func CancelSearch(s store.KubeconfigStore, read int, timeout time.Duration) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	channel := make(chan store.SearchResult)
+	done := make(chan struct{})
+	go func() {
+		s.StartSearch(ctx, channel)
+		close(done)
+	}()
+
+	for i := 0; i < read; i++ {
+		select {
+		case <-channel:
+		case <-done:
+			return fmt.Errorf("search of store %q finished after %d of %d search results", s.GetID(), i, read)
+		case <-time.After(timeout):
+			return fmt.Errorf("search of store %q did not send %d search results within %s", s.GetID(), read, timeout.String())
+		}
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		// drain the channel, so that the search does not block forever
+		go func() {
+			for {
+				select {
+				case <-channel:
+				case <-done:
+					return
+				}
+			}
+		}()
+		return fmt.Errorf("search of store %q did not stop within %s after cancelling it", s.GetID(), timeout.String())
+	}
+}
+
+// AssertResultContains fails the test if none of the search results has the given kubeconfig path
+func AssertResultContains(t TestingT, results []store.SearchResult, path string) {
+	t.Helper()
+	for _, result := range results {
+		if result.KubeconfigPath == path {
+			return
+		}
+	}
+
+	paths := make([]string, 0, len(results))
+	for _, result := range results {
+		paths = append(paths, result.KubeconfigPath)
+	}
+	t.Errorf("expected a search result with kubeconfig path %q, got %q", path, paths)
+}
+
+// AssertResultCount fails the test if the number of search results differs from the expected number
+func AssertResultCount(t TestingT, results []store.SearchResult, expected int) {
+	t.Helper()
+	if len(results) != expected {
+		t.Errorf("expected %d search results, got %d", expected, len(results))
+	}
+}
+
+// NewTestLogger returns a logger for stores under test logging everything to STDERR
+func NewTestLogger() *logrus.Entry {
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.DebugLevel)
+	return logrus.NewEntry(logger).WithField("store", "test")
+}
+
+// FakeSearchResult returns a search result with the given kubeconfig path and tags.
+// The tags are given as "key=value" pairs.
+func FakeSearchResult(path string, tags ...string) store.SearchResult {
+	result := store.SearchResult{KubeconfigPath: path}
+	if len(tags) == 0 {
+		return result
+	}
+
+	result.Tags = make(map[string]string, len(tags))
+	for _, tag := range tags {
+		key, value, _ := strings.Cut(tag, "=")
+		result.Tags[key] = value
+	}
+	return result
+}
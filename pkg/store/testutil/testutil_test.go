@@ -0,0 +1,137 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// fakeStore sends the configured search results and optionally blocks afterwards ignoring the context
+type fakeStore struct {
+	results []store.SearchResult
+	block   bool
+	// stopOnCancel stops sending search results once the search is cancelled
+	stopOnCancel bool
+}
+
+func (f *fakeStore) GetID() string                               { return "filesystem.fake" }
+func (f *fakeStore) GetKind() types.StoreKind                    { return types.StoreKindFilesystem }
+func (f *fakeStore) GetContextPrefix(string) string              { return "" }
+func (f *fakeStore) VerifyKubeconfigPaths(context.Context) error { return nil }
+func (f *fakeStore) Probe(context.Context) error                 { return nil }
+func (f *fakeStore) GetLogger() *logrus.Entry                    { return testutil.NewTestLogger() }
+func (f *fakeStore) GetStoreConfig() types.KubeconfigStore       { return types.KubeconfigStore{} }
+func (f *fakeStore) Stop(context.Context) error                  { return nil }
+
+func (f *fakeStore) GetKubeconfigForPath(context.Context, string, map[string]string) ([]byte, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f *fakeStore) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	for _, result := range f.results {
+		if !f.stopOnCancel {
+			channel <- result
+			continue
+		}
+		select {
+		case channel <- result:
+		case <-ctx.Done():
+			return
+		}
+	}
+	if f.block {
+		select {}
+	}
+}
+
+// recordingT records the failures of the assertions
+type recordingT struct {
+	failures []string
+}
+
+func (r *recordingT) Helper() {}
+func (r *recordingT) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+var _ = Describe("testutil", func() {
+	It("should collect the search results and join the errors", func() {
+		s := &fakeStore{results: []store.SearchResult{
+			testutil.FakeSearchResult("dev", "env=dev", "team=platform"),
+			{Error: errors.New("access denied")},
+			testutil.FakeSearchResult("prod"),
+		}}
+
+		results, err := testutil.CollectResults(s, testutil.DefaultTimeout)
+		Expect(err).To(MatchError("access denied"))
+		Expect(results).To(Equal([]store.SearchResult{
+			{KubeconfigPath: "dev", Tags: map[string]string{"env": "dev", "team": "platform"}},
+			{KubeconfigPath: "prod"},
+		}))
+	})
+
+	It("should return the search results discovered before the timeout", func() {
+		s := &fakeStore{results: []store.SearchResult{testutil.FakeSearchResult("dev")}, block: true}
+
+		results, err := testutil.CollectResults(s, 50*time.Millisecond)
+		Expect(err).To(MatchError(ContainSubstring("did not finish within 50ms")))
+		Expect(results).To(ConsistOf(testutil.FakeSearchResult("dev")))
+	})
+
+	Context("CancelSearch", func() {
+		results := []store.SearchResult{testutil.FakeSearchResult("dev"), testutil.FakeSearchResult("prod")}
+
+		It("should succeed if the search stops once cancelled", func() {
+			Expect(testutil.CancelSearch(&fakeStore{results: results, stopOnCancel: true}, 1, time.Second)).To(Succeed())
+		})
+
+		It("should fail if the search does not stop once cancelled", func() {
+			err := testutil.CancelSearch(&fakeStore{results: results}, 1, 50*time.Millisecond)
+			Expect(err).To(MatchError(`search of store "filesystem.fake" did not stop within 50ms after cancelling it`))
+		})
+
+		It("should fail if the search finishes before reading the search results", func() {
+			err := testutil.CancelSearch(&fakeStore{results: results, stopOnCancel: true}, 3, time.Second)
+			Expect(err).To(MatchError(`search of store "filesystem.fake" finished after 2 of 3 search results`))
+		})
+	})
+
+	It("should assert the search results", func() {
+		results := []store.SearchResult{testutil.FakeSearchResult("dev"), testutil.FakeSearchResult("prod")}
+
+		t := &recordingT{}
+		testutil.AssertResultContains(t, results, "dev")
+		testutil.AssertResultCount(t, results, 2)
+		Expect(t.failures).To(BeEmpty())
+
+		testutil.AssertResultContains(t, results, "staging")
+		testutil.AssertResultCount(t, results, 3)
+		Expect(t.failures).To(ConsistOf(
+			`expected a search result with kubeconfig path "staging", got ["dev" "prod"]`,
+			"expected 3 search results, got 2",
+		))
+	})
+})
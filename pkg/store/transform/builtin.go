@@ -0,0 +1,169 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ExtensionName is the name of the kubeconfig extension containing the labels added by the AddLabel transformer
+const ExtensionName = "kubeswitch"
+
+// Extension is the content of the kubeswitch extension of a kubeconfig
+type Extension struct {
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// ServerRewrite replaces the hostname of the API servers.
+// The port of the API server is kept.
+type ServerRewrite struct {
+	// From is the hostname to replace
+	From string
+	// To is the new hostname
+	To string
+}
+
+func (t ServerRewrite) Transform(kubeconfig []byte) ([]byte, error) {
+	return modify(kubeconfig, func(config *clientcmdapi.Config) error {
+		for name, cluster := range config.Clusters {
+			server, err := url.Parse(cluster.Server)
+			if err != nil {
+				return fmt.Errorf("failed to parse server of cluster %q: %w", name, err)
+			}
+			if server.Hostname() != t.From {
+				continue
+			}
+
+			if port := server.Port(); len(port) > 0 {
+				server.Host = net.JoinHostPort(t.To, port)
+			} else {
+				server.Host = t.To
+			}
+			cluster.Server = server.String()
+		}
+		return nil
+	})
+}
+
+// InjectProxyURL sets the proxy URL of all clusters
+type InjectProxyURL struct {
+	// URL is the proxy URL
+	URL string
+}
+
+func (t InjectProxyURL) Transform(kubeconfig []byte) ([]byte, error) {
+	return modify(kubeconfig, func(config *clientcmdapi.Config) error {
+		for _, cluster := range config.Clusters {
+			cluster.ProxyURL = t.URL
+		}
+		return nil
+	})
+}
+
+// StripToken removes the static bearer tokens and token files of all users
+type StripToken struct{}
+
+func (t StripToken) Transform(kubeconfig []byte) ([]byte, error) {
+	return modify(kubeconfig, func(config *clientcmdapi.Config) error {
+		for _, authInfo := range config.AuthInfos {
+			authInfo.Token = ""
+			authInfo.TokenFile = ""
+		}
+		return nil
+	})
+}
+
+// SetNamespace sets the namespace of all contexts
+type SetNamespace struct {
+	// Namespace is the namespace of the contexts
+	Namespace string
+}
+
+func (t SetNamespace) Transform(kubeconfig []byte) ([]byte, error) {
+	return modify(kubeconfig, func(config *clientcmdapi.Config) error {
+		for _, context := range config.Contexts {
+			context.Namespace = t.Namespace
+		}
+		return nil
+	})
+}
+
+// AddLabel adds a label to the kubeswitch extension of the kubeconfig.
+// Existing labels with the same key are overwritten.
+type AddLabel struct {
+	// Key is the key of the label
+	Key string
+	// Value is the value of the label
+	Value string
+}
+
+func (t AddLabel) Transform(kubeconfig []byte) ([]byte, error) {
+	return modify(kubeconfig, func(config *clientcmdapi.Config) error {
+		extension, err := GetExtension(config)
+		if err != nil {
+			return err
+		}
+
+		if extension.Labels == nil {
+			extension.Labels = make(map[string]string)
+		}
+		extension.Labels[t.Key] = t.Value
+
+		raw, err := json.Marshal(extension)
+		if err != nil {
+			return err
+		}
+		config.Extensions[ExtensionName] = &runtime.Unknown{Raw: raw, ContentType: runtime.ContentTypeJSON}
+		return nil
+	})
+}
+
+// GetExtension returns the kubeswitch extension of the given kubeconfig
+func GetExtension(config *clientcmdapi.Config) (*Extension, error) {
+	extension := &Extension{}
+	object, ok := config.Extensions[ExtensionName]
+	if !ok {
+		return extension, nil
+	}
+
+	unknown, ok := object.(*runtime.Unknown)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type %T of the %q extension", object, ExtensionName)
+	}
+	if err := json.Unmarshal(unknown.Raw, extension); err != nil {
+		return nil, fmt.Errorf("failed to parse the %q extension: %w", ExtensionName, err)
+	}
+	return extension, nil
+}
+
+// modify parses the kubeconfig, applies the given modification and serializes the modified kubeconfig
+func modify(kubeconfig []byte, modification func(config *clientcmdapi.Config) error) ([]byte, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	if err := modification(config); err != nil {
+		return nil, err
+	}
+	return clientcmd.Write(*config)
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// NewStore wraps the store to pass the kubeconfigs returned by the store through the given transformer
+func NewStore(upstream store.KubeconfigStore, transformer Transformer) store.KubeconfigStore {
+	return &transformingStore{
+		upstream:    upstream,
+		transformer: transformer,
+	}
+}
+
+type transformingStore struct {
+	upstream    store.KubeconfigStore
+	transformer Transformer
+}
+
+// GetKubeconfigForPath implements the store.KubeconfigStore interface.
+// It intercepts calls to GetKubeconfigForPath and transforms the fetched kubeconfig.
+func (s *transformingStore) GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error) {
+	kubeconfig, err := s.upstream.GetKubeconfigForPath(ctx, path, tags)
+	if err != nil {
+		return nil, err
+	}
+	return s.transformer.Transform(kubeconfig)
+}
+
+func (s *transformingStore) GetID() string {
+	return s.upstream.GetID()
+}
+
+func (s *transformingStore) GetKind() types.StoreKind {
+	return s.upstream.GetKind()
+}
+
+func (s *transformingStore) GetContextPrefix(path string) string {
+	return s.upstream.GetContextPrefix(path)
+}
+
+func (s *transformingStore) VerifyKubeconfigPaths(ctx context.Context) error {
+	return s.upstream.VerifyKubeconfigPaths(ctx)
+}
+
+func (s *transformingStore) Probe(ctx context.Context) error {
+	return s.upstream.Probe(ctx)
+}
+
+func (s *transformingStore) StartSearch(ctx context.Context, channel chan store.SearchResult) {
+	s.upstream.StartSearch(ctx, channel)
+}
+
+func (s *transformingStore) GetLogger() *logrus.Entry {
+	return s.upstream.GetLogger()
+}
+
+func (s *transformingStore) Stop(ctx context.Context) error {
+	return s.upstream.Stop(ctx)
+}
+
+func (s *transformingStore) GetStoreConfig() types.KubeconfigStore {
+	return s.upstream.GetStoreConfig()
+}
+
+func (s *transformingStore) GetSearchPreview(path string, optionalTags map[string]string) (string, error) {
+	previewer, ok := s.upstream.(store.Previewer)
+	if !ok {
+		// if the wrapped store is not a previewer, simply return an empty string, hence causing no visual distortion
+		return "", nil
+	}
+
+	return previewer.GetSearchPreview(path, optionalTags)
+}
+
+func (s *transformingStore) Unwrap() store.KubeconfigStore {
+	return s.upstream
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform
+
+import (
+	"fmt"
+
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// Transformer modifies the kubeconfig returned by a store
+type Transformer interface {
+	// Transform returns the modified kubeconfig
+	Transform(kubeconfig []byte) ([]byte, error)
+}
+
+// Pipeline is a transformer running multiple transformers in order
+type Pipeline []Transformer
+
+// Transform passes the kubeconfig through all transformers of the pipeline.
+// Aborts on the first failing transformer.
+func (p Pipeline) Transform(kubeconfig []byte) ([]byte, error) {
+	var err error
+	for _, transformer := range p {
+		if kubeconfig, err = transformer.Transform(kubeconfig); err != nil {
+			return nil, err
+		}
+	}
+	return kubeconfig, nil
+}
+
+// New returns a pipeline of the configured transformers
+func New(configs []types.Transformer) (Pipeline, error) {
+	pipeline := make(Pipeline, 0, len(configs))
+	for _, config := range configs {
+		var transformer Transformer
+		switch config.Type {
+		case types.TransformerTypeServerRewrite:
+			transformer = ServerRewrite{From: config.From, To: config.To}
+		case types.TransformerTypeInjectProxyURL:
+			transformer = InjectProxyURL{URL: config.URL}
+		case types.TransformerTypeStripToken:
+			transformer = StripToken{}
+		case types.TransformerTypeSetNamespace:
+			transformer = SetNamespace{Namespace: config.Namespace}
+		case types.TransformerTypeAddLabel:
+			transformer = AddLabel{Key: config.Key, Value: config.Value}
+		default:
+			return nil, fmt.Errorf("unknown transformer type %q", config.Type)
+		}
+		pipeline = append(pipeline, transformer)
+	}
+	return pipeline, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transform_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/transform"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var kubeconfig = []byte(`apiVersion: v1
+kind: Config
+current-context: ctx
+clusters:
+- name: cluster
+  cluster:
+    server: https://api.internal.example.com:6443
+- name: other
+  cluster:
+    server: https://api.other.example.com
+contexts:
+- name: ctx
+  context:
+    cluster: cluster
+    user: user
+    namespace: default
+users:
+- name: user
+  user:
+    token: secret
+`)
+
+var _ = Describe("Transformers", func() {
+	apply := func(transformer transform.Transformer) *clientcmdapi.Config {
+		transformed, err := transformer.Transform(kubeconfig)
+		Expect(err).ToNot(HaveOccurred())
+		config, err := clientcmd.Load(transformed)
+		Expect(err).ToNot(HaveOccurred())
+		return config
+	}
+
+	It("should rewrite the hostname of matching servers", func() {
+		config := apply(transform.ServerRewrite{From: "api.internal.example.com", To: "api.example.com"})
+		Expect(config.Clusters["cluster"].Server).To(Equal("https://api.example.com:6443"))
+		Expect(config.Clusters["other"].Server).To(Equal("https://api.other.example.com"))
+	})
+
+	It("should inject the proxy URL", func() {
+		config := apply(transform.InjectProxyURL{URL: "socks5://localhost:1080"})
+		Expect(config.Clusters["cluster"].ProxyURL).To(Equal("socks5://localhost:1080"))
+		Expect(config.Clusters["other"].ProxyURL).To(Equal("socks5://localhost:1080"))
+	})
+
+	It("should strip the token", func() {
+		config := apply(transform.StripToken{})
+		Expect(config.AuthInfos["user"].Token).To(BeEmpty())
+	})
+
+	It("should set the namespace", func() {
+		config := apply(transform.SetNamespace{Namespace: "kube-system"})
+		Expect(config.Contexts["ctx"].Namespace).To(Equal("kube-system"))
+	})
+
+	It("should add labels to the kubeswitch extension", func() {
+		transformed, err := transform.Pipeline{
+			transform.AddLabel{Key: "team", Value: "a"},
+			transform.AddLabel{Key: "env", Value: "prod"},
+			transform.AddLabel{Key: "team", Value: "b"},
+		}.Transform(kubeconfig)
+		Expect(err).ToNot(HaveOccurred())
+
+		config, err := clientcmd.Load(transformed)
+		Expect(err).ToNot(HaveOccurred())
+		extension, err := transform.GetExtension(config)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(extension.Labels).To(Equal(map[string]string{"team": "b", "env": "prod"}))
+	})
+
+	It("should fail for an invalid kubeconfig", func() {
+		_, err := transform.StripToken{}.Transform([]byte("invalid"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("New", func() {
+	It("should apply the configured transformers in order", func() {
+		pipeline, err := transform.New([]types.Transformer{
+			{Type: types.TransformerTypeSetNamespace, Namespace: "first"},
+			{Type: types.TransformerTypeSetNamespace, Namespace: "second"},
+			{Type: types.TransformerTypeStripToken},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pipeline).To(HaveLen(3))
+
+		transformed, err := pipeline.Transform(kubeconfig)
+		Expect(err).ToNot(HaveOccurred())
+		config, err := clientcmd.Load(transformed)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Contexts["ctx"].Namespace).To(Equal("second"))
+		Expect(config.AuthInfos["user"].Token).To(BeEmpty())
+	})
+
+	It("should fail for an unknown transformer type", func() {
+		_, err := transform.New([]types.Transformer{{Type: "unknown"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
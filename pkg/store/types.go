@@ -15,18 +15,27 @@
 package store
 
 import (
+	"context"
+	"net/http"
 	"sync"
+	"time"
 
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
 	"github.com/danielfoehrkn/kubeswitch/pkg/store/doks"
-	"github.com/digitalocean/doctl/do"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
 
+	"cloud.google.com/go/firestore"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice"
 	awseks "github.com/aws/aws-sdk-go-v2/service/eks"
 	eks "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/cloudflare"
 	gardenclient "github.com/danielfoehrkn/kubeswitch/pkg/store/gardener/copied_gardenctlv2"
 	"github.com/danielfoehrkn/kubeswitch/types"
+	"github.com/digitalocean/godo"
 	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
 	seedmanagementv1alpha1 "github.com/gardener/gardener/pkg/apis/seedmanagement/v1alpha1"
+	consulapi "github.com/hashicorp/consul/api"
 	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/linode/linodego"
 	"github.com/ovh/go-ovh/ovh"
@@ -34,11 +43,25 @@ import (
 	managementClient "github.com/rancher/rancher/pkg/client/generated/management/v3"
 	"github.com/scaleway/scaleway-sdk-go/scw"
 	"github.com/sirupsen/logrus"
+	"github.com/studio-b12/gowebdav"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	gkev1 "google.golang.org/api/container/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// ErrSearchTimeout is returned as the terminal search result of a store
+// that did not finish the search within its configured search timeout
+//
+// Deprecated: use errors.As with *storeerrors.ErrStoreTimeout instead.
+var ErrSearchTimeout error = &storeerrors.ErrStoreTimeout{}
+
+// ErrClusterNotFound is returned by GetKubeconfigForPath if the cluster
+// referenced by the kubeconfig path does not exist (anymore) in the backing store
+//
+// Deprecated: use errors.As with *storeerrors.ErrClusterNotFound instead.
+var ErrClusterNotFound error = &storeerrors.ErrClusterNotFound{}
+
 // SearchResult is a full kubeconfig path discovered from the kubeconfig store
 // given the contained kubeconfig path, the store knows how to retrieve and return the
 // actual kubeconfig
@@ -50,6 +73,9 @@ type SearchResult struct {
 	// This metadata is later handed over in the getKubeconfigForPath() function when retrieving the kubeconfig bytes for the path and might contain
 	// information necessary to retrieve the kubeconfig from the backing store (such a unique ID for the cluster required for the API)
 	Tags map[string]string
+	// PackageName is the logical cluster group (e.g. "ring-0") the contexts of the kubeconfig belong to.
+	// Overrides the packages configured for the store.
+	PackageName string
 	// Error is an error which occured when trying to discover kubeconfig paths in the backing store
 	Error error
 }
@@ -70,22 +96,33 @@ type KubeconfigStore interface {
 
 	// VerifyKubeconfigPaths verifies that the configured search paths are valid
 	// can also include additional preprocessing
-	VerifyKubeconfigPaths() error
+	VerifyKubeconfigPaths(ctx context.Context) error
+
+	// Probe performs the minimal request against the backing store to check that it is reachable.
+	// Unlike StartSearch, it does not discover any kubeconfigs.
+	// Uses the DefaultProbeTimeout if the context has no deadline.
+	Probe(ctx context.Context) error
 
 	// StartSearch starts the search over the configured search paths
-	// and populates the results via the given channel
-	StartSearch(channel chan SearchResult)
+	// and populates the results via the given channel.
+	// The search is aborted once the context is done.
+	StartSearch(ctx context.Context, channel chan SearchResult)
 
 	// GetKubeconfigForPath returns the byte representation of the kubeconfig
 	// the kubeconfig has to fetch the kubeconfig from its backing store (e.g., uses the HTTP API)
 	// Optional tags might help identify the cluster in the backing store, but typically such information is already encoded in the kubeconfig path (implementation specific)
-	GetKubeconfigForPath(path string, tags map[string]string) ([]byte, error)
+	GetKubeconfigForPath(ctx context.Context, path string, tags map[string]string) ([]byte, error)
 
 	// GetLogger returns the logger of the store
 	GetLogger() *logrus.Entry
 
 	// GetStoreConfig returns the store's configuration from the switch config file
 	GetStoreConfig() types.KubeconfigStore
+
+	// Stop releases the resources of the store, e.g. closes the connections of its API clients
+	// and stops its background goroutines. The store must not be used afterwards.
+	// Calling Stop multiple times is safe.
+	Stop(ctx context.Context) error
 }
 
 // Previewer can be optionally implemented by stores to show custom preview content
@@ -94,10 +131,82 @@ type Previewer interface {
 	GetSearchPreview(path string, optionalTags map[string]string) (string, error)
 }
 
+// ChangeDetector can be optionally implemented by stores with a native API to list the changes of the kubeconfig paths.
+// Used to refresh the search index without listing all kubeconfig paths.
+type ChangeDetector interface {
+	// SearchChangesSince returns the kubeconfig paths added and removed since the given time.
+	// The existing kubeconfig paths are the paths of the store contained in the index.
+	SearchChangesSince(ctx context.Context, since time.Time, existing []string) (added []SearchResult, removed []string, err error)
+}
+
+// Writer can be optionally implemented by stores that can add kubeconfigs to their backing store.
+// Callers have to check that the store is not read-only with CheckWritable before writing.
+type Writer interface {
+	// WriteKubeconfig writes the kubeconfig containing the context with the given name to the backing store
+	// and returns the path of the written kubeconfig in the store
+	WriteKubeconfig(ctx context.Context, contextName string, kubeconfig []byte) (string, error)
+}
+
+// ContextDeleter can be optionally implemented by stores that can remove contexts from their backing store.
+// Callers have to check that the store is not read-only with CheckWritable before deleting.
+type ContextDeleter interface {
+	// DeleteContext removes the context from the kubeconfig at the given path.
+	// The kubeconfig itself is removed if it does not contain any other context.
+	DeleteContext(ctx context.Context, path, contextName string) error
+}
+
+// Provisioner can be optionally implemented by stores that can create new clusters with the cloud provider.
+// Callers have to check that the store is not read-only with CheckWritable before provisioning.
+type Provisioner interface {
+	// ProvisionCluster creates the cluster with the given name using the provider-specific YAML config,
+	// blocks until the provider finished the creation and returns the kubeconfig path of the new cluster in the store
+	ProvisionCluster(ctx context.Context, name string, config []byte) (string, error)
+}
+
+// Wrapper is implemented by stores wrapping another store, such as caches
+type Wrapper interface {
+	// Unwrap returns the wrapped store
+	Unwrap() KubeconfigStore
+}
+
+// Unwrap returns the innermost store wrapped by the given store
+func Unwrap(s KubeconfigStore) KubeconfigStore {
+	for {
+		wrapper, ok := s.(Wrapper)
+		if !ok {
+			return s
+		}
+		s = wrapper.Unwrap()
+	}
+}
+
+// Persister is implemented by stores that can persist the fetched kubeconfigs across invocations, such as disk caches
+type Persister interface {
+	// PersistsKubeconfigs returns true if the fetched kubeconfigs are available to later invocations
+	PersistsKubeconfigs() bool
+}
+
+// PersistsKubeconfigs returns true if the store or a store wrapped by it persists the fetched kubeconfigs
+func PersistsKubeconfigs(s KubeconfigStore) bool {
+	for {
+		if persister, ok := s.(Persister); ok && persister.PersistsKubeconfigs() {
+			return true
+		}
+		wrapper, ok := s.(Wrapper)
+		if !ok {
+			return false
+		}
+		s = wrapper.Unwrap()
+	}
+}
+
 type FilesystemStore struct {
-	Logger                *logrus.Entry
-	KubeconfigStore       types.KubeconfigStore
-	KubeconfigName        string
+	Logger              *logrus.Entry
+	KubeconfigStore     types.KubeconfigStore
+	KubeconfigName      string
+	ExpandKubeconfigEnv bool
+	// KeychainBackend resolves user tokens referencing a macOS Keychain item
+	KeychainBackend       bool
 	kubeconfigDirectories []string
 	kubeconfigFilepaths   []string
 }
@@ -107,20 +216,33 @@ type VaultStore struct {
 	KubeconfigStore    types.KubeconfigStore
 	Client             *vaultapi.Client
 	VaultKeyKubeconfig string
-	KubeconfigName     string
-	EngineVersion      string
-	vaultPaths         []string
+	// KubeconfigJSONPointer selects the kubeconfig in the secret data if set
+	KubeconfigJSONPointer string
+	// PathPrefix is the template of the prefix of the configured paths
+	PathPrefix     string
+	KubeconfigName string
+	EngineVersion  string
+	vaultPaths     []string
 }
 
 type GardenerStore struct {
-	Logger                    *logrus.Entry
-	KubeconfigStore           types.KubeconfigStore
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	Config          *types.StoreConfigGardener
+	StateDirectory  string
+	// Landscapes are the Gardener landscapes searched by the store.
+	// Contains a single landscape if configured with the top-level fields of the store configuration.
+	Landscapes []*GardenerLandscape
+}
+
+// GardenerLandscape is a Gardener landscape searched by the GardenerStore
+type GardenerLandscape struct {
+	Config                    types.LandscapeConfig
 	GardenClient              gardenclient.Client
 	Client                    client.Client
-	Config                    *types.StoreConfigGardener
 	LandscapeIdentity         string
 	LandscapeName             string
-	StateDirectory            string
+	InitLock                  sync.Mutex
 	CachePathToShoot          map[string]gardencorev1beta1.Shoot
 	PathToShootLock           sync.RWMutex
 	CachePathToManagedSeed    map[string]seedmanagementv1alpha1.ManagedSeed
@@ -133,12 +255,22 @@ type EKSStore struct {
 	Logger          *logrus.Entry
 	KubeconfigStore types.KubeconfigStore
 	Client          *awseks.Client
-	Config          *types.StoreConfigEKS
+	// STSClient is used to probe the validity of the AWS credentials
+	STSClient *sts.Client
+	Config    *types.StoreConfigEKS
 	// DiscoveredClusters maps the kubeconfig path (az_<resource-group>--<cluster-name>) -> cluster
 	// This is a cache for the clusters discovered during the initial search for kubeconfig paths
 	// when not using a search index
 	DiscoveredClusters map[string]*eks.Cluster
 	StateDirectory     string
+	// CredentialCache caches the cloud provider credentials across invocations. Optional.
+	CredentialCache *credentials.CredentialCache
+	// nodePoolPreviews caches the Karpenter NodePools of the enriched preview per kubeconfig path
+	nodePoolPreviews     map[string]nodePoolPreview
+	nodePoolPreviewsLock sync.Mutex
+	// authenticationModes caches the authentication mode of the access config per described cluster name
+	authenticationModes     map[string]string
+	authenticationModesLock sync.Mutex
 }
 
 type GKEStore struct {
@@ -146,6 +278,9 @@ type GKEStore struct {
 	KubeconfigStore types.KubeconfigStore
 	GkeClient       *gkev1.Service
 	Config          *types.StoreConfigGKE
+	// DiscoveredClustersMutex synchronizes the access to the DiscoveredClusters map,
+	// as the projects are searched concurrently to retrieving kubeconfigs
+	DiscoveredClustersMutex sync.RWMutex
 	// DiscoveredClusters maps the kubeconfig path (gke--project-name--clusterName) -> cluster
 	// This is a cache for the clusters discovered during the initial search for kubeconfig paths
 	// when not using a search index
@@ -154,6 +289,8 @@ type GKEStore struct {
 	// used to construct the kubeconfig path containing the project name instead of a technical project id
 	ProjectNameToID map[string]string
 	StateDirectory  string
+	// CredentialCache caches the cloud provider credentials across invocations. Optional.
+	CredentialCache *credentials.CredentialCache
 }
 
 type AzureStore struct {
@@ -170,6 +307,8 @@ type AzureStore struct {
 	// when not using a search index
 	DiscoveredClusters map[string]*armcontainerservice.ManagedCluster
 	StateDirectory     string
+	// CredentialCache caches the cloud provider credentials across invocations. Optional.
+	CredentialCache *credentials.CredentialCache
 }
 
 type RancherStore struct {
@@ -177,20 +316,37 @@ type RancherStore struct {
 	KubeconfigStore types.KubeconfigStore
 	ClientOpts      *clientbase.ClientOpts
 	Client          *managementClient.Client
+	// ConnectionPooling configures if idle connections to the Rancher API are reused. Defaults to true.
+	ConnectionPooling *bool
+	// ClusterCacheMutex synchronizes the access to the ClusterCache map,
+	// as the search preview is rendered concurrently to the search
+	ClusterCacheMutex sync.RWMutex
+	// ClusterCache contains the clusters shown in the search preview by kubeconfig path
+	ClusterCache map[string]*managementClient.Cluster
+	// transport holds the connections of the Rancher client, which are closed by Stop
+	transport *http.Transport
 }
 
 type OVHStore struct {
 	Logger          *logrus.Entry
 	KubeconfigStore types.KubeconfigStore
 	Client          *ovh.Client
-	OVHKubeCache    map[string]OVHKube // map[clusterID]OVHKube
+	// OVHKubeCacheMutex synchronizes the access to the OVHKubeCache map,
+	// as the search preview is rendered concurrently to the search
+	OVHKubeCacheMutex sync.RWMutex
+	OVHKubeCache      map[string]OVHKube // map[clusterID]OVHKube
 }
 
 type ScalewayStore struct {
-	Logger             *logrus.Entry
-	KubeconfigStore    types.KubeconfigStore
-	Client             *scw.Client
-	DiscoveredClusters map[string]ScalewayKube
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	Client          *scw.Client
+	// DiscoveredClustersMutex synchronizes the access to the DiscoveredClusters map,
+	// as the search preview is rendered concurrently to the search
+	DiscoveredClustersMutex sync.RWMutex
+	DiscoveredClusters      map[string]ScalewayKube
+	// transport holds the connections of the Scaleway client, which are closed by Stop
+	transport *http.Transport
 }
 
 type DigitalOceanStore struct {
@@ -201,8 +357,10 @@ type DigitalOceanStore struct {
 	DiscoveredClustersMutex                   sync.RWMutex
 	ContextNameAndClusterNameToClusterIDMutex sync.RWMutex
 	KubeconfigStore                           types.KubeconfigStore
-	ContextToKubernetesService                map[string]do.KubernetesService
+	ContextToKubernetesService                map[string]godo.KubernetesService
 	Config                                    doks.DoctlConfig
+	// transport holds the connections of the DigitalOcean clients, which are closed by Stop
+	transport *http.Transport
 }
 
 type AkamaiStore struct {
@@ -210,6 +368,45 @@ type AkamaiStore struct {
 	KubeconfigStore types.KubeconfigStore
 	Client          *linodego.Client
 	Config          *types.StoreConfigAkamai
+	// transport holds the connections of the Linode client, which are closed by Stop
+	transport *http.Transport
+}
+
+type WebDAVStore struct {
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	KubeconfigName  string
+	Client          *gowebdav.Client
+	Config          *types.StoreConfigWebDAV
+	// transport holds the connections of the WebDAV client, which are closed by Stop
+	transport *http.Transport
+}
+
+type ConsulStore struct {
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	KubeconfigName  string
+	Client          *consulapi.Client
+	Config          *types.StoreConfigConsul
+	// transport holds the connections of the Consul client, which are closed by Stop
+	transport *http.Transport
+}
+
+type EtcdStore struct {
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	KubeconfigName  string
+	Client          *clientv3.Client
+	Config          *types.StoreConfigEtcd
+	stopper         stopOnce
+}
+
+type FirestoreStore struct {
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	Client          *firestore.Client
+	Config          *types.StoreConfigFirestore
+	stopper         stopOnce
 }
 
 type CapiStore struct {
@@ -217,4 +414,24 @@ type CapiStore struct {
 	KubeconfigStore types.KubeconfigStore
 	Client          client.Client
 	Config          *types.StoreConfigCapi
+	// ProviderDefaults locates the kubeconfig secrets of the clusters of the configured provider
+	ProviderDefaults CapiProviderDefaults
+	// TunnelClient manages the Cloudflare Tunnel if configured
+	TunnelClient *cloudflare.Client
+	// ingressRules caches the ingress rules of the Cloudflare Tunnel for the current search
+	ingressRules      []cloudflare.IngressRule
+	ingressRulesMutex sync.Mutex
+}
+
+type HTTPStore struct {
+	Logger          *logrus.Entry
+	KubeconfigStore types.KubeconfigStore
+	Config          *types.StoreConfigHTTP
+	// clients contains an HTTP client per configured CA certificate file
+	clients map[string]*http.Client
+	// signers contains the AWS Signature Version 4 signer per configured SigV4 block
+	signers map[*types.SigV4Config]*sigV4Signer
+	// names contains the names returned by the list endpoint per kubeconfig URL
+	names      map[string]string
+	namesMutex sync.RWMutex
 }
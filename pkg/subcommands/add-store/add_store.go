@@ -0,0 +1,425 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package addstore
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	"github.com/danielfoehrkn/kubeswitch/pkg/config/validation"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// verifyTimeout is the maximum time to wait for the first search result when verifying the credentials of a store
+const verifyTimeout = 30 * time.Second
+
+// field is a field of the kubeconfig store the user is prompted for
+type field struct {
+	// key is the yaml key of the field
+	key string
+	// config defines if the field is part of the store-specific configuration
+	config bool
+	// description is shown in the prompt and as comment for optional fields
+	description string
+	required    bool
+	// list fields are entered comma-separated
+	list         bool
+	defaultValue string
+	// envVars are environment variables that are offered as default value if set
+	envVars  []string
+	validate func(string) error
+}
+
+var (
+	idField = field{
+		key:         "id",
+		description: "unique ID of the store, required when configuring multiple stores of the same kind",
+	}
+	refreshIndexAfterField = field{
+		key:         "refreshIndexAfter",
+		description: "how often the search index is refreshed, e.g. 1h. Does not use an index if not set",
+		validate:    validateDuration,
+	}
+	pathsField = field{
+		key:         "paths",
+		description: "paths to search for kubeconfigs",
+		required:    true,
+		list:        true,
+	}
+	kubeconfigNameField = field{
+		key:         "kubeconfigName",
+		description: "name or pattern of the kubeconfig files",
+	}
+)
+
+// storeFields contains the fields the user is prompted for, per supported store kind
+var storeFields = map[types.StoreKind][]field{
+	types.StoreKindFilesystem: {
+		withDefault(pathsField, "~/.kube"),
+		kubeconfigNameField,
+	},
+	types.StoreKindVault: {
+		pathsField,
+		kubeconfigNameField,
+		{key: "vaultAPIAddress", config: true, description: "URL of the Vault API", required: true, envVars: []string{"VAULT_ADDR"}, validate: validateURL},
+	},
+	types.StoreKindEKS: {
+		{key: "region", config: true, description: "AWS region to search for clusters", required: true, envVars: []string{"AWS_REGION", "AWS_DEFAULT_REGION"}},
+		{key: "profile", config: true, description: "named AWS profile used for authentication", envVars: []string{"AWS_PROFILE"}},
+	},
+	types.StoreKindGKE: {
+		{key: "gcpAccount", config: true, description: "gcloud account to discover clusters from. Uses the active account if not set"},
+		{key: "projectIDs", config: true, description: "projects to search for clusters. Searches all projects if not set", list: true, envVars: []string{"CLOUDSDK_CORE_PROJECT", "GOOGLE_CLOUD_PROJECT"}},
+	},
+	types.StoreKindAzure: {
+		{key: "subscriptionID", config: true, description: "Azure subscription to discover clusters from", envVars: []string{"AZURE_SUBSCRIPTION_ID"}},
+		{key: "resourceGroups", config: true, description: "resource groups to search for clusters. Searches all resource groups if not set", list: true},
+	},
+	types.StoreKindGardener: {
+		{key: "gardenerAPIKubeconfigPath", config: true, description: "path to the kubeconfig of the Gardener API server", required: true, validate: validateFile},
+		{key: "landscapeName", config: true, description: "custom name of the Gardener landscape"},
+	},
+	types.StoreKindRancher: {
+		{key: "rancherAPIAddress", config: true, description: "URL of the Rancher API, e.g. https://rancher.example.com/v3", required: true, envVars: []string{"RANCHER_URL"}, validate: validateURL},
+		{key: "rancherToken", config: true, description: "token to authenticate against the Rancher API", required: true, envVars: []string{"RANCHER_TOKEN"}},
+	},
+	types.StoreKindCapi: {
+		{key: "kubeconfigPath", config: true, description: "path to the kubeconfig of the management cluster", required: true, validate: validateFile},
+	},
+	types.StoreKindWebDAV: {
+		{key: "url", config: true, description: "URL of the WebDAV server", required: true, validate: validateURL},
+		{key: "authType", config: true, description: "authentication method: basic, digest or bearer", defaultValue: string(types.WebDAVAuthTypeBasic), validate: validateOneOf(string(types.WebDAVAuthTypeBasic), string(types.WebDAVAuthTypeDigest), string(types.WebDAVAuthTypeBearer))},
+		{key: "username", config: true, description: "username for basic and digest authentication"},
+		{key: "password", config: true, description: "password or bearer token"},
+		{key: "searchPaths", config: true, description: "directories on the WebDAV server to search for kubeconfigs", required: true, list: true},
+	},
+	types.StoreKindConsul: {
+		withDescription(pathsField, "key prefixes to search for kubeconfigs"),
+		{key: "address", config: true, description: "address of the Consul server", envVars: []string{"CONSUL_HTTP_ADDR"}},
+		{key: "token", config: true, description: "ACL token", envVars: []string{"CONSUL_HTTP_TOKEN"}},
+	},
+	types.StoreKindEtcd: {
+		withDescription(pathsField, "key prefixes to search for kubeconfigs"),
+		{key: "endpoints", config: true, description: "URLs of the etcd members", required: true, list: true, envVars: []string{"ETCDCTL_ENDPOINTS"}},
+	},
+	types.StoreKindFirestore: {
+		{key: "projectID", config: true, description: "Google Cloud project of the Firestore database", required: true, envVars: []string{"GOOGLE_CLOUD_PROJECT"}},
+		{key: "collection", config: true, description: "path of the collection containing the kubeconfig documents", required: true},
+		{key: "serviceAccountFile", config: true, description: "path to a service account key file, defaults to the Application Default Credentials", envVars: []string{"GOOGLE_APPLICATION_CREDENTIALS"}, validate: validateFile},
+	},
+	types.StoreKindHTTP: {
+		{key: "listEndpoint", config: true, description: "URL returning a JSON array of the kubeconfigs to discover", required: true, validate: validateURL},
+		{key: "bearerTokenFile", config: true, description: "path to a file containing the bearer token", validate: validateFile},
+	},
+	types.StoreKindScript: {
+		{key: "scriptFile", config: true, description: "path to the Lua script or CEL expression generating the kubeconfigs", required: true, validate: validateFile},
+		{key: "scriptLanguage", config: true, description: "language of the script: lua or cel, defaults to cel for files ending with .cel", validate: validateOneOf(types.ScriptLanguageLua, types.ScriptLanguageCEL)},
+	},
+}
+
+// AddStore interactively prompts for the configuration of a kubeconfig store of the given kind
+// and appends the store to the config file.
+// For stores other than the filesystem store, the credentials are verified by searching the store.
+// If dryRun is set, the store is only printed.
+func AddStore(kind types.StoreKind, configPath string, dryRun bool, newStore func(types.KubeconfigStore) (store.KubeconfigStore, error)) error {
+	fields, ok := storeFields[kind]
+	if !ok {
+		return fmt.Errorf("store kind %q is not supported. Supported kinds are %q", kind, SupportedKinds())
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	storeNode := newMapping()
+	appendScalar(storeNode, "kind", string(kind), "")
+
+	var configNode *yaml.Node
+	for _, f := range append([]field{idField}, append(fields, refreshIndexAfterField)...) {
+		value, err := prompt(reader, f)
+		if err != nil {
+			return err
+		}
+		if len(value) == 0 {
+			continue
+		}
+
+		comment := ""
+		if !f.required {
+			comment = fmt.Sprintf("optional: %s", f.description)
+		}
+
+		node := storeNode
+		if f.config {
+			if configNode == nil {
+				configNode = newMapping()
+			}
+			node = configNode
+		}
+
+		if f.list {
+			appendList(node, f.key, splitList(value), comment)
+			continue
+		}
+		appendScalar(node, f.key, value, comment)
+	}
+
+	if configNode != nil {
+		storeNode.Content = append(storeNode.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "config"}, configNode)
+	}
+
+	kubeconfigStore := types.KubeconfigStore{}
+	if err := storeNode.Decode(&kubeconfigStore); err != nil {
+		return fmt.Errorf("failed to decode kubeconfig store: %w", err)
+	}
+
+	if errList := validation.ValidateConfig(&types.Config{
+		Version:          "v1alpha1",
+		KubeconfigStores: []types.KubeconfigStore{kubeconfigStore},
+	}); len(errList) > 0 {
+		return fmt.Errorf("the kubeconfig store is invalid: %s", errList.ToAggregate().Error())
+	}
+
+	if kind != types.StoreKindFilesystem {
+		fmt.Printf("Verifying the credentials of the %s store...\n", kind)
+		if err := verifyStore(kubeconfigStore, newStore); err != nil {
+			fmt.Printf("Verification failed: %v\n", err)
+			save, err := confirm(reader, "Add the store anyway?")
+			if err != nil {
+				return err
+			}
+			if !save {
+				fmt.Println("The store has not been added.")
+				return nil
+			}
+		} else {
+			fmt.Println("Verification succeeded.")
+		}
+	}
+
+	if dryRun {
+		output, err := switchconfig.EncodeKubeconfigStore(storeNode)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(output))
+		return nil
+	}
+
+	if err := switchconfig.AddKubeconfigStore(configPath, storeNode); err != nil {
+		return fmt.Errorf("failed to add store to config %q: %w", configPath, err)
+	}
+	fmt.Printf("Added %s store to %q\n", kind, configPath)
+	return nil
+}
+
+// prompt asks the user for the value of the field until a valid value is given
+func prompt(reader *bufio.Reader, f field) (string, error) {
+	defaultValue := f.defaultValue
+	for _, envVar := range f.envVars {
+		if value := os.Getenv(envVar); len(value) > 0 {
+			defaultValue = value
+			break
+		}
+	}
+
+	for {
+		requiredHint := ""
+		if f.required {
+			requiredHint = " (required)"
+		}
+		if len(defaultValue) > 0 {
+			fmt.Printf("%s - %s%s [%s]: ", f.key, f.description, requiredHint, defaultValue)
+		} else {
+			fmt.Printf("%s - %s%s: ", f.key, f.description, requiredHint)
+		}
+
+		input, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || len(input) == 0) {
+			return "", fmt.Errorf("failed to read input: %w", err)
+		}
+
+		value := strings.TrimSpace(input)
+		if len(value) == 0 {
+			value = defaultValue
+		}
+
+		if len(value) == 0 {
+			if !f.required {
+				return "", nil
+			}
+			fmt.Println("  a value is required")
+			continue
+		}
+
+		if f.validate != nil {
+			if err := f.validate(value); err != nil {
+				fmt.Printf("  invalid value: %v\n", err)
+				continue
+			}
+		}
+		return value, nil
+	}
+}
+
+func confirm(reader *bufio.Reader, question string) (bool, error) {
+	fmt.Printf("%s [y/N]: ", question)
+	input, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("failed to read input: %w", err)
+	}
+	answer := strings.ToLower(strings.TrimSpace(input))
+	return answer == "y" || answer == "yes", nil
+}
+
+// verifyStore searches the store and waits for the first search result to verify that the store can be accessed
+func verifyStore(kubeconfigStore types.KubeconfigStore, newStore func(types.KubeconfigStore) (store.KubeconfigStore, error)) error {
+	s, err := newStore(kubeconfigStore)
+	if err != nil {
+		return err
+	}
+
+	// aborts the search once the first search result is received
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	if err := s.VerifyKubeconfigPaths(ctx); err != nil {
+		return err
+	}
+
+	channel := make(chan store.SearchResult)
+	go func() {
+		s.StartSearch(ctx, channel)
+		close(channel)
+	}()
+
+	select {
+	case result, ok := <-channel:
+		// drain the remaining results so that the search can finish
+		go func() {
+			for range channel {
+			}
+		}()
+
+		if ok && result.Error != nil {
+			return result.Error
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("the store did not return any search result within %s", verifyTimeout)
+	}
+}
+
+func newMapping() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+// appendScalar appends the value as string, so that values like "123", "yes" or "null"
+// are quoted and not resolved to another type when the config is loaded
+func appendScalar(mapping *yaml.Node, key, value, comment string) {
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key, LineComment: comment},
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value},
+	)
+}
+
+func appendList(mapping *yaml.Node, key string, values []string, comment string) {
+	list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, value := range values {
+		list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key, LineComment: comment},
+		list,
+	)
+}
+
+// splitList splits comma-separated values
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// SupportedKinds returns the store kinds that can be added interactively
+func SupportedKinds() []string {
+	var kinds []string
+	for _, kind := range types.ValidStoreKinds.List() {
+		if _, ok := storeFields[types.StoreKind(kind)]; ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+func withDefault(f field, defaultValue string) field {
+	f.defaultValue = defaultValue
+	return f
+}
+
+func withDescription(f field, description string) field {
+	f.description = description
+	return f
+}
+
+func validateURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return fmt.Errorf("%q is not an absolute URL", value)
+	}
+	return nil
+}
+
+func validateFile(value string) error {
+	info, err := os.Stat(util.ExpandEnv(value))
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%q is a directory", value)
+	}
+	return nil
+}
+
+func validateDuration(value string) error {
+	_, err := time.ParseDuration(value)
+	return err
+}
+
+func validateOneOf(values ...string) func(string) error {
+	return func(value string) error {
+		for _, v := range values {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %q", values)
+	}
+}
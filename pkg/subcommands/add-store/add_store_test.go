@@ -0,0 +1,155 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package addstore_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	addstore "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/add-store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("AddStore", func() {
+	var (
+		tempDir    string
+		configPath string
+		stdin      *os.File
+		newStore   func(types.KubeconfigStore) (store.KubeconfigStore, error)
+		verified   []types.KubeconfigStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-add-store")
+		Expect(err).ToNot(HaveOccurred())
+		configPath = filepath.Join(tempDir, "switch-config.yaml")
+		Expect(os.WriteFile(filepath.Join(tempDir, "config"), []byte{}, 0600)).To(Succeed())
+
+		stdin = os.Stdin
+		verified = nil
+		// verifies the credentials by searching a filesystem store containing a single kubeconfig
+		newStore = func(kubeconfigStore types.KubeconfigStore) (store.KubeconfigStore, error) {
+			verified = append(verified, kubeconfigStore)
+			return store.NewFilesystemStore("config", types.KubeconfigStore{
+				Kind:  types.StoreKindFilesystem,
+				Paths: []string{tempDir},
+			})
+		}
+	})
+
+	AfterEach(func() {
+		os.Stdin = stdin
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	// input makes the lines the answers of the prompts
+	input := func(lines ...string) {
+		file, err := os.CreateTemp(tempDir, "stdin")
+		Expect(err).ToNot(HaveOccurred())
+		for _, line := range lines {
+			_, err = file.WriteString(line + "\n")
+			Expect(err).ToNot(HaveOccurred())
+		}
+		_, err = file.Seek(0, 0)
+		Expect(err).ToNot(HaveOccurred())
+		os.Stdin = file
+	}
+
+	loadStores := func() []types.KubeconfigStore {
+		config, err := switchconfig.LoadConfigFromFile(configPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config).ToNot(BeNil())
+		return config.KubeconfigStores
+	}
+
+	It("should reject unsupported store kinds", func() {
+		Expect(addstore.AddStore(types.StoreKindComposite, configPath, false, newStore)).To(MatchError(ContainSubstring("is not supported")))
+	})
+
+	It("should add a filesystem store without verifying it", func() {
+		// id, paths, kubeconfigName, refreshIndexAfter
+		input("", "~/kubeconfigs, /tmp/kubeconfigs", "", "")
+		Expect(addstore.AddStore(types.StoreKindFilesystem, configPath, false, newStore)).To(Succeed())
+		Expect(verified).To(BeEmpty())
+
+		stores := loadStores()
+		Expect(stores).To(HaveLen(1))
+		Expect(stores[0].Kind).To(Equal(types.StoreKindFilesystem))
+		Expect(stores[0].ID).To(BeNil())
+		Expect(stores[0].Paths).To(Equal([]string{"~/kubeconfigs", "/tmp/kubeconfigs"}))
+	})
+
+	It("should keep values that look like other types as strings", func() {
+		input("123", "~/.kube", "null", "")
+		Expect(addstore.AddStore(types.StoreKindFilesystem, configPath, false, newStore)).To(Succeed())
+
+		stores := loadStores()
+		Expect(stores).To(HaveLen(1))
+		Expect(stores[0].ID).To(HaveValue(Equal("123")))
+		Expect(stores[0].KubeconfigName).To(HaveValue(Equal("null")))
+	})
+
+	It("should prompt again for invalid values", func() {
+		input("", "~/.kube", "", "soon", "1h")
+		Expect(addstore.AddStore(types.StoreKindFilesystem, configPath, false, newStore)).To(Succeed())
+
+		stores := loadStores()
+		Expect(stores).To(HaveLen(1))
+		Expect(stores[0].RefreshIndexAfter).To(HaveValue(Equal(time.Hour)))
+	})
+
+	It("should verify the credentials of other stores and append the store to the existing stores", func() {
+		Expect(os.WriteFile(configPath, []byte("kind: SwitchConfig\nversion: v1alpha1\nkubeconfigStores:\n- kind: filesystem\n  paths:\n  - ~/.kube\n"), 0600)).To(Succeed())
+
+		// id, paths, kubeconfigName, vaultAPIAddress, refreshIndexAfter
+		input("team", "secret/kubeconfigs", "", "https://vault.example.com", "")
+		Expect(addstore.AddStore(types.StoreKindVault, configPath, false, newStore)).To(Succeed())
+		Expect(verified).To(HaveLen(1))
+		Expect(verified[0].Config).To(HaveKeyWithValue("vaultAPIAddress", "https://vault.example.com"))
+
+		stores := loadStores()
+		Expect(stores).To(HaveLen(2))
+		Expect(stores[1].Kind).To(Equal(types.StoreKindVault))
+		Expect(stores[1].ID).To(HaveValue(Equal("team")))
+	})
+
+	It("should not add a store failing the verification unless confirmed", func() {
+		newStore = func(types.KubeconfigStore) (store.KubeconfigStore, error) {
+			return nil, errors.New("permission denied")
+		}
+
+		input("", "secret/kubeconfigs", "", "https://vault.example.com", "", "n")
+		Expect(addstore.AddStore(types.StoreKindVault, configPath, false, newStore)).To(Succeed())
+		Expect(configPath).ToNot(BeAnExistingFile())
+
+		input("", "secret/kubeconfigs", "", "https://vault.example.com", "", "y")
+		Expect(addstore.AddStore(types.StoreKindVault, configPath, false, newStore)).To(Succeed())
+		Expect(loadStores()).To(HaveLen(1))
+	})
+
+	It("should not write the config file in dry-run mode", func() {
+		input("", "~/.kube", "", "")
+		Expect(addstore.AddStore(types.StoreKindFilesystem, configPath, true, newStore)).To(Succeed())
+		Expect(configPath).ToNot(BeAnExistingFile())
+	})
+})
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
 	"github.com/danielfoehrkn/kubeswitch/types"
 	"gopkg.in/yaml.v2"
 )
@@ -106,22 +107,11 @@ func (a *Alias) ContainsAlias(alias string) *string {
 
 // WriteAllAliases overwrites the alias state file with new Content
 func (a *Alias) WriteAllAliases() error {
-	// overwrite the existing state file (only state is last execution anyways atm.)
-	file, err := os.Create(a.aliasFilepath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
 	output, err := yaml.Marshal(a.Content)
 	if err != nil {
 		return err
 	}
 
-	_, err = file.Write(output)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	// overwrite the existing state file (only state is last execution anyways atm.)
+	return kubeswitchio.WriteFile(a.aliasFilepath, output, 0644)
 }
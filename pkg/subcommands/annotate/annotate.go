@@ -0,0 +1,93 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package annotate
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// Annotate sets the given annotations in the form KEY=VALUE for the context
+func Annotate(contextName string, pairs []string) error {
+	a, err := annotations.Load()
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return fmt.Errorf("please provide the annotation in the form KEY=VALUE. Got %q", pair)
+		}
+		if err := a.Set(contextName, key, value); err != nil {
+			return err
+		}
+	}
+
+	if err := a.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Annotated context %q\n", contextName)
+	return nil
+}
+
+// RemoveAnnotations removes the annotations with the given keys from the context.
+// Removes all annotations of the context if no keys are given.
+func RemoveAnnotations(contextName string, keys []string) error {
+	a, err := annotations.Load()
+	if err != nil {
+		return err
+	}
+
+	removed := a.Remove(contextName, keys...)
+	if len(removed) == 0 {
+		return fmt.Errorf("context %q does not have the annotations to remove", contextName)
+	}
+
+	if err := a.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed annotations %s from context %q\n", strings.Join(removed, ", "), contextName)
+	return nil
+}
+
+// ListAnnotations prints the annotations of the context
+func ListAnnotations(contextName string) error {
+	a, err := annotations.Load()
+	if err != nil {
+		return err
+	}
+
+	keys := a.Keys(contextName)
+	if len(keys) == 0 {
+		fmt.Printf("No annotations for context %q\n", contextName)
+		return nil
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Key", "Value"})
+	for _, key := range keys {
+		t.AppendRow(table.Row{key, a[contextName][key]})
+	}
+	t.Render()
+	return nil
+}
@@ -0,0 +1,296 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batchfetch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// progressBarWidth is the number of characters of the progress bar
+const progressBarWidth = 40
+
+var logger = logrus.New()
+
+// Options configures which kubeconfigs are fetched
+type Options struct {
+	// ContextsFile is the path of the file listing the contexts to fetch, one context per line.
+	// Empty lines and lines starting with "#" are ignored.
+	ContextsFile string
+	// Selector is a regex selecting the contexts to fetch. Used if no ContextsFile is given.
+	Selector string
+	// Concurrency is the maximum number of kubeconfigs fetched in parallel
+	Concurrency int
+	// ErrorOutput is the path of the file the errors are written to. Optional.
+	ErrorOutput string
+}
+
+// fetch is the kubeconfig of a store fetched for one or more contexts
+type fetch struct {
+	discoveredContext pkg.DiscoveredContext
+	contexts          []string
+	err               error
+}
+
+// BatchFetch retrieves the kubeconfigs of the given contexts in parallel from the stores,
+// so that the kubeconfigs are in the cache of the stores when switching to the contexts later.
+// Contexts of stores without a persistent cache, e.g. with the default in-memory cache, are not fetched and reported as failed.
+// Reports the result per context and returns an error if the kubeconfig of at least one context could not be fetched.
+func BatchFetch(options Options, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	if options.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	var (
+		wantedContexts []string
+		selector       *regexp.Regexp
+		err            error
+	)
+	switch {
+	case len(options.ContextsFile) > 0 && len(options.Selector) > 0:
+		return fmt.Errorf("a contexts file and --from-selector cannot be combined")
+	case len(options.ContextsFile) > 0:
+		wantedContexts, err = readContextsFile(options.ContextsFile)
+		if err != nil {
+			return err
+		}
+		if len(wantedContexts) == 0 {
+			return fmt.Errorf("the file %q does not contain any context", options.ContextsFile)
+		}
+	case len(options.Selector) > 0:
+		selector, err = regexp.Compile(options.Selector)
+		if err != nil {
+			return fmt.Errorf("invalid selector %q: %w", options.Selector, err)
+		}
+	default:
+		return fmt.Errorf("please provide a file listing the contexts or a selector with --from-selector")
+	}
+
+	fetches, notFound, err := resolveContexts(wantedContexts, selector, stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	if len(fetches) == 0 && len(notFound) == 0 {
+		return fmt.Errorf("no context matches the selector %q", options.Selector)
+	}
+
+	// the kubeconfigs fetched for stores without a persistent cache would be discarded when the process exits
+	var toFetch []*fetch
+	for _, f := range fetches {
+		kubeconfigStore := *f.discoveredContext.Store
+		if !store.PersistsKubeconfigs(kubeconfigStore) {
+			f.err = fmt.Errorf("store %q has no persistent cache. Configure a cache of kind \"disk\" or \"filesystem\" for the store", kubeconfigStore.GetID())
+			continue
+		}
+		toFetch = append(toFetch, f)
+	}
+
+	fetchKubeconfigs(toFetch, options.Concurrency)
+
+	var failures []string
+	for _, f := range fetches {
+		for _, contextName := range f.contexts {
+			if f.err != nil {
+				fmt.Printf("✗ %s: %v\n", contextName, f.err)
+				failures = append(failures, fmt.Sprintf("%s: %v", contextName, f.err))
+				continue
+			}
+			fmt.Printf("✓ %s\n", contextName)
+		}
+	}
+	for _, contextName := range wantedContexts {
+		err, ok := notFound[contextName]
+		if !ok {
+			continue
+		}
+		fmt.Printf("✗ %s: %v\n", contextName, err)
+		failures = append(failures, fmt.Sprintf("%s: %v", contextName, err))
+	}
+
+	if len(options.ErrorOutput) > 0 && len(failures) > 0 {
+		if err := kubeswitchio.WriteFile(options.ErrorOutput, []byte(strings.Join(failures, "\n")+"\n"), 0600); err != nil {
+			return fmt.Errorf("failed to write errors to %q: %w", options.ErrorOutput, err)
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to fetch the kubeconfigs of %d context(s)", len(failures))
+	}
+	return nil
+}
+
+// readContextsFile returns the context names listed in the file
+func readContextsFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contexts file: %w", err)
+	}
+	defer file.Close()
+
+	var contexts []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		contexts = append(contexts, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read contexts file: %w", err)
+	}
+	return contexts, nil
+}
+
+// resolveContexts searches the stores for the wanted contexts or the contexts matching the selector.
+// Contexts sharing a kubeconfig are fetched once.
+// Returns the wanted contexts that were not found together with the reason.
+func resolveContexts(wantedContexts []string, selector *regexp.Regexp, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) ([]*fetch, map[string]error, error) {
+	c, err := pkg.DoSearch(stores, config, stateDir, noIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wanted := make(map[string]struct{}, len(wantedContexts))
+	for _, contextName := range wantedContexts {
+		wanted[contextName] = struct{}{}
+	}
+
+	var (
+		mError     *multierror.Error
+		fetches    []*fetch
+		fetchByKey = map[string]*fetch{}
+		found      = map[string]struct{}{}
+	)
+	for discoveredContext := range *c {
+		if discoveredContext.Error != nil {
+			mError = multierror.Append(mError, discoveredContext.Error)
+			continue
+		}
+
+		if discoveredContext.Store == nil {
+			continue
+		}
+
+		kubeconfigStore := *discoveredContext.Store
+		contextName, ok := matchContext(discoveredContext, kubeconfigStore.GetContextPrefix(discoveredContext.Path), wanted, selector)
+		if !ok {
+			continue
+		}
+
+		if _, ok := found[contextName]; ok {
+			continue
+		}
+		found[contextName] = struct{}{}
+
+		key := fmt.Sprintf("%s:%s", kubeconfigStore.GetID(), discoveredContext.Path)
+		f, ok := fetchByKey[key]
+		if !ok {
+			f = &fetch{discoveredContext: discoveredContext}
+			fetchByKey[key] = f
+			fetches = append(fetches, f)
+		}
+		f.contexts = append(f.contexts, contextName)
+	}
+
+	notFound := map[string]error{}
+	for _, contextName := range wantedContexts {
+		if _, ok := found[contextName]; ok {
+			continue
+		}
+
+		if mError != nil {
+			notFound[contextName] = fmt.Errorf("context not found. Possibly due to errors: %v", mError.Error())
+			continue
+		}
+		notFound[contextName] = fmt.Errorf("context not found")
+	}
+	return fetches, notFound, nil
+}
+
+// matchContext returns the name of the context as given in the contexts file, or the full context name if it matches the selector
+func matchContext(discoveredContext pkg.DiscoveredContext, prefix string, wanted map[string]struct{}, selector *regexp.Regexp) (string, bool) {
+	if selector != nil {
+		return discoveredContext.Name, selector.MatchString(discoveredContext.Name)
+	}
+
+	contextWithoutPrefix := discoveredContext.Name
+	if len(prefix) > 0 {
+		contextWithoutPrefix = strings.TrimPrefix(discoveredContext.Name, fmt.Sprintf("%s/", prefix))
+	}
+
+	for _, name := range []string{discoveredContext.Name, contextWithoutPrefix, discoveredContext.Alias} {
+		if _, ok := wanted[name]; ok && len(name) > 0 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// fetchKubeconfigs retrieves the kubeconfigs in parallel and shows the progress
+func fetchKubeconfigs(fetches []*fetch, concurrency int) {
+	var (
+		wg        sync.WaitGroup
+		mutex     sync.Mutex
+		completed int
+		semaphore = make(chan struct{}, concurrency)
+	)
+
+	printProgress(0, len(fetches))
+	for _, f := range fetches {
+		wg.Add(1)
+		go func(f *fetch) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			kubeconfigStore := *f.discoveredContext.Store
+			logger.Debugf("Fetching kubeconfig %q of store %q", f.discoveredContext.Path, kubeconfigStore.GetID())
+			_, f.err = kubeconfigStore.GetKubeconfigForPath(context.Background(), f.discoveredContext.Path, f.discoveredContext.Tags)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			completed++
+			printProgress(completed, len(fetches))
+		}(f)
+	}
+	wg.Wait()
+	fmt.Fprintln(os.Stderr)
+}
+
+// printProgress prints a progress bar to stderr, overwriting the previous one
+func printProgress(completed, total int) {
+	percentage := 100
+	if total > 0 {
+		percentage = completed * 100 / total
+	}
+
+	filled := percentage * progressBarWidth / 100
+	fmt.Fprintf(os.Stderr, "\rFetching kubeconfigs [%s%s] %3d%% (%d/%d)", strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled), percentage, completed, total)
+}
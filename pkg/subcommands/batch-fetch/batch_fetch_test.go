@@ -0,0 +1,169 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batchfetch_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storecache "github.com/danielfoehrkn/kubeswitch/pkg/store/cache"
+	batchfetch "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/batch-fetch"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+users:
+- name: admin
+  user:
+    token: admin-token
+`
+
+// captureStdout returns everything written to the stdout by the given function
+func captureStdout(f func() error) (string, error) {
+	reader, writer, err := os.Pipe()
+	Expect(err).ToNot(HaveOccurred())
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+
+	err = f()
+	Expect(writer.Close()).To(Succeed())
+	return <-output, err
+}
+
+var _ = Describe("BatchFetch", func() {
+	var (
+		tempDir        string
+		kubeconfigPath string
+		diskCache      *storecache.DiskCache
+		stores         []store.KubeconfigStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-batch-fetch")
+		Expect(err).ToNot(HaveOccurred())
+		annotations.SetPath(filepath.Join(tempDir, "annotations.yaml"))
+
+		kubeconfigsDir := filepath.Join(tempDir, "kubeconfigs")
+		Expect(os.MkdirAll(filepath.Join(kubeconfigsDir, "team"), 0700)).To(Succeed())
+		kubeconfigPath = filepath.Join(kubeconfigsDir, "team", "config")
+		Expect(os.WriteFile(kubeconfigPath, []byte(kubeconfig), 0600)).To(Succeed())
+
+		filesystemStore, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{kubeconfigsDir},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		diskCache = storecache.NewDiskCache(filepath.Join(tempDir, "cache"))
+		stores = []store.KubeconfigStore{storecache.NewCachingStore(filesystemStore, diskCache, 0)}
+	})
+
+	AfterEach(func() {
+		annotations.SetPath(annotations.DefaultPath)
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	batchFetch := func(options batchfetch.Options) (string, error) {
+		if options.Concurrency == 0 {
+			options.Concurrency = 2
+		}
+		return captureStdout(func() error {
+			return batchfetch.BatchFetch(options, stores, &types.Config{}, tempDir, true)
+		})
+	}
+
+	It("should write the kubeconfigs of the contexts matching the selector to the disk cache", func() {
+		output, err := batchFetch(batchfetch.Options{Selector: "team/.*"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output).To(ContainSubstring("✓ team/dev"))
+		Expect(output).To(ContainSubstring("✓ team/prod"))
+
+		cached, _, ok := diskCache.Get(kubeconfigPath)
+		Expect(ok).To(BeTrue())
+		Expect(string(cached)).To(Equal(kubeconfig))
+	})
+
+	It("should fetch the contexts listed in the contexts file and report the contexts not found", func() {
+		contextsFile := filepath.Join(tempDir, "contexts.txt")
+		Expect(os.WriteFile(contextsFile, []byte("# comment\n\nteam/dev\nunknown\n"), 0600)).To(Succeed())
+		errorOutput := filepath.Join(tempDir, "errors.txt")
+
+		output, err := batchFetch(batchfetch.Options{ContextsFile: contextsFile, ErrorOutput: errorOutput})
+		Expect(err).To(MatchError("failed to fetch the kubeconfigs of 1 context(s)"))
+		Expect(output).To(ContainSubstring("✓ team/dev"))
+		Expect(output).To(ContainSubstring("✗ unknown: context not found"))
+		Expect(output).ToNot(ContainSubstring("team/prod"))
+
+		errors, err := os.ReadFile(errorOutput)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(errors)).To(Equal("unknown: context not found\n"))
+
+		_, _, ok := diskCache.Get(kubeconfigPath)
+		Expect(ok).To(BeTrue())
+	})
+
+	It("should report the contexts of stores without a persistent cache as failed", func() {
+		stores = []store.KubeconfigStore{storecache.NewCachingStore(store.Unwrap(stores[0]), storecache.NewMemoryCache(), 0)}
+
+		output, err := batchFetch(batchfetch.Options{Selector: "team/dev"})
+		Expect(err).To(MatchError("failed to fetch the kubeconfigs of 1 context(s)"))
+		Expect(output).To(ContainSubstring(`✗ team/dev: store "filesystem.default" has no persistent cache`))
+	})
+
+	It("should reject a contexts file combined with a selector", func() {
+		_, err := batchFetch(batchfetch.Options{ContextsFile: "contexts.txt", Selector: ".*"})
+		Expect(err).To(MatchError("a contexts file and --from-selector cannot be combined"))
+	})
+
+	It("should fail if no context matches the selector", func() {
+		_, err := batchFetch(batchfetch.Options{Selector: "unknown"})
+		Expect(err).To(MatchError(`no context matches the selector "unknown"`))
+	})
+})
@@ -0,0 +1,178 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+)
+
+// marker identifies the shell integration added by kubeswitch in a rc file
+const marker = "# kubeswitch shell integration"
+
+// Shells are the supported shells
+var Shells = []string{"bash", "zsh", "fish", "powershell"}
+
+// Bootstrap adds the shell integration of kubeswitch to the rc file of the given shell.
+// If the shell is empty, it is detected from the $SHELL environment variable.
+// If the rc file is empty, the default rc file of the shell is used.
+// If dryRun is set, only prints the shell integration that would be added.
+func Bootstrap(shell, rcFile, executable string, dryRun bool) error {
+	if len(shell) == 0 {
+		detected, err := DetectShell()
+		if err != nil {
+			return err
+		}
+		shell = detected
+	}
+
+	snippet, err := Snippet(shell, executable)
+	if err != nil {
+		return err
+	}
+
+	if len(rcFile) == 0 {
+		rcFile, err = RCFile(shell)
+		if err != nil {
+			return err
+		}
+	}
+
+	existing, err := os.ReadFile(rcFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %q: %w", rcFile, err)
+	}
+
+	if isInstalled(string(existing), shell, executable) {
+		fmt.Printf("kubeswitch shell integration already installed in %q\n", rcFile)
+		return nil
+	}
+
+	if dryRun {
+		fmt.Printf("Would add the following to %q:\n\n%s", rcFile, snippet)
+		return nil
+	}
+
+	if err := kubeswitchio.MkdirAll(filepath.Dir(rcFile), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %q: %w", rcFile, err)
+	}
+
+	// separate the shell integration from the existing content
+	if len(existing) > 0 {
+		snippet = "\n" + snippet
+		if !strings.HasSuffix(string(existing), "\n") {
+			snippet = "\n" + snippet
+		}
+	}
+
+	if err := kubeswitchio.AppendFile(rcFile, []byte(snippet), 0644); err != nil {
+		return fmt.Errorf("failed to write to %q: %w", rcFile, err)
+	}
+
+	fmt.Printf("Added kubeswitch shell integration to %q. Open a new shell or source the file to use it.\n", rcFile)
+	return nil
+}
+
+// DetectShell returns the shell of the user based on the $SHELL environment variable.
+// Defaults to powershell on Windows.
+func DetectShell() (string, error) {
+	shellPath := os.Getenv("SHELL")
+	if len(shellPath) == 0 {
+		if runtime.GOOS == "windows" {
+			return "powershell", nil
+		}
+		return "", fmt.Errorf("unable to detect the shell: $SHELL is not set. Please specify the shell with --shell")
+	}
+
+	shell := strings.TrimSuffix(filepath.Base(shellPath), ".exe")
+	switch shell {
+	case "pwsh":
+		return "powershell", nil
+	case "bash", "zsh", "fish", "powershell":
+		return shell, nil
+	}
+	return "", fmt.Errorf("unsupported shell %q. Supported shells are: %s", shell, strings.Join(Shells, ", "))
+}
+
+// RCFile returns the default rc file of the given shell in which the shell integration is installed
+func RCFile(shell string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	switch shell {
+	case "bash":
+		return filepath.Join(home, ".bashrc"), nil
+	case "zsh":
+		if zdotdir := os.Getenv("ZDOTDIR"); len(zdotdir) > 0 {
+			return filepath.Join(zdotdir, ".zshrc"), nil
+		}
+		return filepath.Join(home, ".zshrc"), nil
+	case "fish":
+		configDir := os.Getenv("XDG_CONFIG_HOME")
+		if len(configDir) == 0 {
+			configDir = filepath.Join(home, ".config")
+		}
+		// fish autoloads the function file when calling kubeswitch for the first time
+		return filepath.Join(configDir, "fish", "functions", "kubeswitch.fish"), nil
+	case "powershell":
+		// the default location of $PROFILE for the current user and host
+		if runtime.GOOS == "windows" {
+			return filepath.Join(home, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1"), nil
+		}
+		return filepath.Join(home, ".config", "powershell", "Microsoft.PowerShell_profile.ps1"), nil
+	}
+	return "", fmt.Errorf("unsupported shell %q. Supported shells are: %s", shell, strings.Join(Shells, ", "))
+}
+
+// Snippet returns the shell integration for the given shell.
+// It loads the shell function and the completion script using the given switcher executable.
+func Snippet(shell, executable string) (string, error) {
+	var lines []string
+	switch shell {
+	case "bash", "zsh":
+		lines = []string{
+			fmt.Sprintf("source <(%s init %s)", executable, shell),
+			fmt.Sprintf("source <(%s completion %s --cmd switch)", executable, shell),
+		}
+	case "fish":
+		lines = []string{
+			fmt.Sprintf("%s init fish | source", executable),
+			fmt.Sprintf("%s completion fish --cmd kubeswitch | source", executable),
+		}
+	case "powershell":
+		lines = []string{
+			fmt.Sprintf("& %s init powershell | Out-String | Invoke-Expression", executable),
+			// the completion script defines the completer block for the kubeswitch function and registers it
+			fmt.Sprintf("& %s completion powershell --cmd kubeswitch | Out-String | Invoke-Expression", executable),
+		}
+	default:
+		return "", fmt.Errorf("unsupported shell %q. Supported shells are: %s", shell, strings.Join(Shells, ", "))
+	}
+
+	return fmt.Sprintf("%s\n%s\n", marker, strings.Join(lines, "\n")), nil
+}
+
+// isInstalled checks if the rc file content already contains the shell integration,
+// either added by kubeswitch or manually as described in the installation guide
+func isInstalled(content, shell, executable string) bool {
+	return strings.Contains(content, marker) || strings.Contains(content, fmt.Sprintf("%s init %s", executable, shell))
+}
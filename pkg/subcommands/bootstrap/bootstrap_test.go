@@ -0,0 +1,117 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/bootstrap"
+)
+
+var _ = Describe("Bootstrap", func() {
+	Describe("Snippet", func() {
+		It("should load the shell function and the completion for bash", func() {
+			snippet, err := bootstrap.Snippet("bash", "/usr/local/bin/switcher")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snippet).To(Equal(`# kubeswitch shell integration
+source <(/usr/local/bin/switcher init bash)
+source <(/usr/local/bin/switcher completion bash --cmd switch)
+`))
+		})
+
+		It("should load the shell function and the completion for fish", func() {
+			snippet, err := bootstrap.Snippet("fish", "switcher")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snippet).To(ContainSubstring("switcher init fish | source\n"))
+			Expect(snippet).To(ContainSubstring("switcher completion fish --cmd kubeswitch | source\n"))
+		})
+
+		It("should load the shell function and the completion for powershell", func() {
+			snippet, err := bootstrap.Snippet("powershell", "switcher.exe")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snippet).To(Equal(`# kubeswitch shell integration
+& switcher.exe init powershell | Out-String | Invoke-Expression
+& switcher.exe completion powershell --cmd kubeswitch | Out-String | Invoke-Expression
+`))
+		})
+
+		It("should only register completer blocks defined by the loaded powershell completion script", func() {
+			snippet, err := bootstrap.Snippet("powershell", "switcher.exe")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snippet).ToNot(ContainSubstring("CompleterBlock"))
+
+			// the script printed by "switcher completion powershell --cmd kubeswitch"
+			script := bytes.Buffer{}
+			Expect((&cobra.Command{Use: "kubeswitch"}).GenPowerShellCompletion(&script)).To(Succeed())
+			Expect(script.String()).To(ContainSubstring("[scriptblock]${__kubeswitchCompleterBlock} = {"))
+			Expect(script.String()).To(ContainSubstring("Register-ArgumentCompleter -CommandName 'kubeswitch' -ScriptBlock ${__kubeswitchCompleterBlock}"))
+		})
+
+		It("should fail for unsupported shells", func() {
+			_, err := bootstrap.Snippet("tcsh", "switcher")
+			Expect(err).To(MatchError(ContainSubstring(`unsupported shell "tcsh"`)))
+		})
+	})
+
+	Describe("Bootstrap", func() {
+		var (
+			tempDir string
+			rcFile  string
+		)
+
+		BeforeEach(func() {
+			var err error
+			tempDir, err = os.MkdirTemp("", "kubeswitch-bootstrap")
+			Expect(err).ToNot(HaveOccurred())
+			rcFile = filepath.Join(tempDir, "profile", "Microsoft.PowerShell_profile.ps1")
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(tempDir)).To(Succeed())
+		})
+
+		It("should append the shell integration to the rc file only once", func() {
+			Expect(os.MkdirAll(filepath.Dir(rcFile), 0755)).To(Succeed())
+			Expect(os.WriteFile(rcFile, []byte("Set-Alias k kubectl"), 0644)).To(Succeed())
+
+			Expect(bootstrap.Bootstrap("powershell", rcFile, "switcher.exe", false)).To(Succeed())
+			Expect(bootstrap.Bootstrap("powershell", rcFile, "switcher.exe", false)).To(Succeed())
+
+			snippet, err := bootstrap.Snippet("powershell", "switcher.exe")
+			Expect(err).ToNot(HaveOccurred())
+			content, err := os.ReadFile(rcFile)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(content)).To(Equal("Set-Alias k kubectl\n\n" + snippet))
+			Expect(strings.Count(string(content), "# kubeswitch shell integration")).To(Equal(1))
+		})
+
+		It("should create the rc file", func() {
+			Expect(bootstrap.Bootstrap("powershell", rcFile, "switcher.exe", false)).To(Succeed())
+			Expect(rcFile).To(BeAnExistingFile())
+		})
+
+		It("should not write the rc file in dry-run mode", func() {
+			Expect(bootstrap.Bootstrap("powershell", rcFile, "switcher.exe", true)).To(Succeed())
+			Expect(rcFile).ToNot(BeAnExistingFile())
+		})
+	})
+})
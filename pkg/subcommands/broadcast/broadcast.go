@@ -0,0 +1,267 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broadcast
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-cmd/cmd"
+	"github.com/sirupsen/logrus"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	list_contexts "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/list-contexts"
+	setcontext "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/set-context"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var logger = logrus.New()
+
+// result is the outcome of the command executed against a single context
+type result struct {
+	context string
+	status  cmd.Status
+	// resources are the resources in the output matching the resource filter
+	resources []resource
+	// filterErr is set if the output could not be filtered
+	filterErr error
+}
+
+// Broadcast executes the given command in parallel against all contexts matching the selector regex.
+// The output is displayed grouped by context name.
+// If a resource filter is given, only the resources in the JSON output matching the filter are displayed.
+// Contexts without matching resources are omitted unless showEmpty is set.
+// If aggregate is set, the matching resources of all contexts are displayed in a single table.
+// Returns an error if the command failed for at least one context.
+func Broadcast(selector string, command []string, maxConcurrent int, saveOutput bool, filter *ResourceFilter, showEmpty, aggregate bool, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	selectorRegex, err := regexp.Compile(selector)
+	if err != nil {
+		return fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+
+	if maxConcurrent < 1 {
+		return fmt.Errorf("--max-concurrent must be at least 1")
+	}
+
+	if filter == nil && (showEmpty || aggregate) {
+		return fmt.Errorf("--show-empty and --aggregate can only be used together with --filter-resource")
+	}
+
+	allContexts, err := list_contexts.ListContexts("*", stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	// the temporary kubeconfig files are created sequentially, as the search is not safe for concurrent use
+	contextToKubeconfig := make(map[string]string)
+	var contexts []string
+	for _, context := range allContexts {
+		if !selectorRegex.MatchString(context) {
+			continue
+		}
+
+		tmpKubeconfigFile, _, err := setcontext.SetContext(context, stores, config, stateDir, noIndex, false)
+		if err != nil {
+			return err
+		}
+		contextToKubeconfig[context] = *tmpKubeconfigFile
+		contexts = append(contexts, context)
+	}
+
+	if len(contexts) == 0 {
+		return fmt.Errorf("no context matches the selector %q", selector)
+	}
+
+	results := make([]result, len(contexts))
+	semaphore := make(chan struct{}, maxConcurrent)
+	wg := sync.WaitGroup{}
+	for i, context := range contexts {
+		wg.Add(1)
+		go func(i int, context string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			logger.Debugf("Executing on context %q", context)
+			results[i] = result{
+				context: context,
+				status:  execute(command, contextToKubeconfig[context], config),
+			}
+		}(i, context)
+	}
+	wg.Wait()
+
+	if filter != nil {
+		for i := range results {
+			applyFilter(filter, &results[i])
+		}
+	}
+
+	var outputDirectory string
+	if saveOutput {
+		outputDirectory = fmt.Sprintf("kubeswitch-broadcast-%s", time.Now().Format("20060102-150405"))
+		if err := kubeswitchio.MkdirAll(outputDirectory, 0700); err != nil {
+			return fmt.Errorf("failed to create output directory %q: %w", outputDirectory, err)
+		}
+	}
+
+	var failedContexts []string
+	for _, r := range results {
+		switch {
+		case filter == nil:
+			printResult(r)
+		case aggregate:
+			printErrors(r)
+		default:
+			printFilteredResult(r, showEmpty)
+		}
+
+		if r.status.Error != nil || r.status.Exit != 0 {
+			failedContexts = append(failedContexts, fmt.Sprintf("%s (exit code %d)", r.context, r.status.Exit))
+		} else if r.filterErr != nil {
+			failedContexts = append(failedContexts, fmt.Sprintf("%s (%v)", r.context, r.filterErr))
+		}
+
+		if saveOutput {
+			if err := writeResult(outputDirectory, r); err != nil {
+				logger.Warnf("failed to save output for context %q: %v", r.context, err)
+			}
+		}
+	}
+
+	if aggregate {
+		printAggregatedResources(results, showEmpty)
+	}
+
+	if saveOutput {
+		fmt.Printf("Output saved to %s\n", outputDirectory)
+	}
+
+	if len(failedContexts) > 0 {
+		return fmt.Errorf("command failed for %d of %d contexts: %s", len(failedContexts), len(results), strings.Join(failedContexts, ", "))
+	}
+	return nil
+}
+
+// execute runs the command with the given kubeconfig and returns the buffered status
+func execute(command []string, kubeconfigPath string, config *types.Config) cmd.Status {
+	var envCmd *cmd.Cmd
+	if config != nil && config.ExecShell != nil {
+		envCmd = cmd.NewCmd(*config.ExecShell, "-c", strings.Join(command, " "))
+	} else {
+		envCmd = cmd.NewCmd(command[0], command[1:]...)
+	}
+
+	envCmd.Env = append(os.Environ(), fmt.Sprintf("KUBECONFIG=%s", kubeconfigPath))
+	return <-envCmd.Start()
+}
+
+// applyFilter filters the output of a successful command with the resource filter
+func applyFilter(filter *ResourceFilter, r *result) {
+	if r.status.Error != nil || r.status.Exit != 0 {
+		return
+	}
+	r.resources, r.filterErr = filter.filter(strings.Join(r.status.Stdout, "\n"))
+}
+
+// printResult prints the output of the command prefixed with the context name
+func printResult(r result) {
+	for _, line := range r.status.Stdout {
+		fmt.Printf("[%s] %s\n", r.context, line)
+	}
+	printErrors(r)
+}
+
+// printErrors prints the error output of the command prefixed with the context name
+func printErrors(r result) {
+	for _, line := range r.status.Stderr {
+		fmt.Fprintf(os.Stderr, "[%s] %s\n", r.context, line)
+	}
+	if r.status.Error != nil {
+		fmt.Fprintf(os.Stderr, "[%s] %v\n", r.context, r.status.Error)
+	}
+	if r.filterErr != nil {
+		fmt.Fprintf(os.Stderr, "[%s] %v\n", r.context, r.filterErr)
+	}
+}
+
+// printFilteredResult prints the resources matching the resource filter as a table prefixed with the context name.
+// Contexts without matching resources are only printed if showEmpty is set.
+func printFilteredResult(r result, showEmpty bool) {
+	defer printErrors(r)
+
+	if len(r.resources) == 0 {
+		if showEmpty && r.filterErr == nil && r.status.Error == nil && r.status.Exit == 0 {
+			fmt.Printf("[%s] No matching resources\n", r.context)
+		}
+		return
+	}
+
+	rows := make([][]string, 0, len(r.resources))
+	for _, resource := range r.resources {
+		rows = append(rows, []string{resource.kind, resource.namespace, resource.name})
+	}
+	table := formatTable([]string{"KIND", "NAMESPACE", "NAME"}, rows)
+	for _, line := range strings.Split(strings.TrimSuffix(table, "\n"), "\n") {
+		fmt.Printf("[%s] %s\n", r.context, line)
+	}
+}
+
+// printAggregatedResources prints the resources matching the resource filter of all contexts in a single table.
+// Contexts without matching resources are only listed if showEmpty is set.
+func printAggregatedResources(results []result, showEmpty bool) {
+	var rows [][]string
+	for _, r := range results {
+		if len(r.resources) == 0 && showEmpty && r.filterErr == nil && r.status.Error == nil && r.status.Exit == 0 {
+			rows = append(rows, []string{r.context, "<none>", "<none>", "<none>"})
+			continue
+		}
+		for _, resource := range r.resources {
+			rows = append(rows, []string{r.context, resource.kind, resource.namespace, resource.name})
+		}
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No matching resources")
+		return
+	}
+	fmt.Print(formatTable([]string{"CLUSTER", "KIND", "NAMESPACE", "NAME"}, rows))
+}
+
+// formatTable renders the rows as a table with the given header
+func formatTable(header []string, rows [][]string) string {
+	builder := strings.Builder{}
+	w := tabwriter.NewWriter(&builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	_ = w.Flush()
+	return builder.String()
+}
+
+// writeResult writes the output of the command to a file named after the context in the output directory
+func writeResult(outputDirectory string, r result) error {
+	filename := strings.NewReplacer("/", "_", string(os.PathSeparator), "_").Replace(r.context)
+	content := strings.Join(append(r.status.Stdout, r.status.Stderr...), "\n")
+	return kubeswitchio.WriteFile(filepath.Join(outputDirectory, filename), []byte(content), 0600)
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broadcast
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/go-cmd/cmd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// captureStdout returns everything written to STDOUT while running f
+func captureStdout(f func()) string {
+	reader, writer, err := os.Pipe()
+	Expect(err).ToNot(HaveOccurred())
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+
+	f()
+	Expect(writer.Close()).To(Succeed())
+	return <-output
+}
+
+var _ = Describe("Broadcast output", func() {
+	var filter *ResourceFilter
+
+	BeforeEach(func() {
+		var err error
+		filter, err = ParseResourceFilter("{.status.phase}=Pending")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	// filteredResult returns the filtered result of a successful command with the given JSON output
+	filteredResult := func(context, output string) result {
+		r := result{context: context, status: cmd.Status{Stdout: strings.Split(output, "\n")}}
+		applyFilter(filter, &r)
+		return r
+	}
+
+	Describe("printFilteredResult", func() {
+		It("should print the matching resources of a list as a table prefixed with the context name", func() {
+			output := captureStdout(func() {
+				printFilteredResult(filteredResult("dev", podList), false)
+			})
+			Expect(output).To(Equal(`[dev] KIND  NAMESPACE    NAME
+[dev] Pod   default      web-2
+[dev] Pod   kube-system  dns
+`))
+		})
+
+		It("should print a matching single object", func() {
+			output := captureStdout(func() {
+				printFilteredResult(filteredResult("dev", pod), false)
+			})
+			Expect(output).To(Equal(`[dev] KIND  NAMESPACE  NAME
+[dev] Pod   default    web-2
+`))
+		})
+
+		It("should omit a context without matching resources", func() {
+			output := captureStdout(func() {
+				printFilteredResult(filteredResult("dev", `{"kind": "List", "items": []}`), false)
+			})
+			Expect(output).To(BeEmpty())
+		})
+
+		It("should print a context without matching resources with --show-empty", func() {
+			output := captureStdout(func() {
+				printFilteredResult(filteredResult("dev", `{"kind": "List", "items": []}`), true)
+			})
+			Expect(output).To(Equal("[dev] No matching resources\n"))
+		})
+
+		It("should not print a failed context as empty with --show-empty", func() {
+			r := result{context: "dev", status: cmd.Status{Exit: 1}}
+			applyFilter(filter, &r)
+			Expect(captureStdout(func() { printFilteredResult(r, true) })).To(BeEmpty())
+
+			r = filteredResult("dev", "not json")
+			Expect(r.filterErr).To(HaveOccurred())
+			Expect(captureStdout(func() { printFilteredResult(r, true) })).To(BeEmpty())
+		})
+	})
+
+	Describe("printAggregatedResources", func() {
+		It("should print the matching resources of all contexts in a single table", func() {
+			output := captureStdout(func() {
+				printAggregatedResources([]result{
+					filteredResult("dev", podList),
+					filteredResult("prod", pod),
+					filteredResult("staging", `{"kind": "List", "items": []}`),
+				}, false)
+			})
+			Expect(output).To(Equal(`CLUSTER  KIND  NAMESPACE    NAME
+dev      Pod   default      web-2
+dev      Pod   kube-system  dns
+prod     Pod   default      web-2
+`))
+		})
+
+		It("should list the contexts without matching resources with --show-empty", func() {
+			output := captureStdout(func() {
+				printAggregatedResources([]result{
+					filteredResult("prod", pod),
+					filteredResult("staging", `{"kind": "List", "items": []}`),
+					{context: "failed", status: cmd.Status{Error: errors.New("not found")}},
+				}, true)
+			})
+			Expect(output).To(Equal(`CLUSTER  KIND    NAMESPACE  NAME
+prod     Pod     default    web-2
+staging  <none>  <none>     <none>
+`))
+		})
+
+		It("should print a notice if no context has matching resources", func() {
+			output := captureStdout(func() {
+				printAggregatedResources([]result{filteredResult("staging", `{"kind": "List", "items": []}`)}, false)
+			})
+			Expect(output).To(Equal("No matching resources\n"))
+		})
+	})
+
+	Describe("Broadcast", func() {
+		It("should only allow --show-empty and --aggregate with --filter-resource", func() {
+			Expect(Broadcast(".*", []string{"kubectl"}, 1, false, nil, true, false, nil, nil, "", true)).To(MatchError(ContainSubstring("can only be used together with --filter-resource")))
+			Expect(Broadcast(".*", []string{"kubectl"}, 1, false, nil, false, true, nil, nil, "", true)).To(MatchError(ContainSubstring("can only be used together with --filter-resource")))
+		})
+	})
+})
@@ -0,0 +1,122 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broadcast
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// ResourceFilter filters the resources in the JSON output of a command by the value of a JSONPath expression
+type ResourceFilter struct {
+	expression string
+	value      string
+	jsonPath   *jsonpath.JSONPath
+}
+
+// resource is a resource matching the resource filter
+type resource struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// ParseResourceFilter parses a filter of the form <jsonpath>=<value>, e.g. "{.status.phase}=Pending".
+// The curly braces around the JSONPath expression are optional.
+func ParseResourceFilter(filter string) (*ResourceFilter, error) {
+	// the JSONPath expression itself may contain "=" in filter expressions
+	index := strings.LastIndex(filter, "=")
+	if index <= 0 {
+		return nil, fmt.Errorf("invalid resource filter %q: expected <jsonpath>=<value>", filter)
+	}
+
+	expression := filter[:index]
+	if !strings.HasPrefix(expression, "{") {
+		expression = fmt.Sprintf("{%s}", expression)
+	}
+
+	jsonPath := jsonpath.New("filter-resource").AllowMissingKeys(true)
+	if err := jsonPath.Parse(expression); err != nil {
+		return nil, fmt.Errorf("invalid JSONPath expression %q: %w", expression, err)
+	}
+
+	return &ResourceFilter{
+		expression: expression,
+		value:      filter[index+1:],
+		jsonPath:   jsonPath,
+	}, nil
+}
+
+// filter parses the JSON output of the command and returns the resources for which the JSONPath expression yields the expected value.
+// The output can either be a single resource or a list of resources.
+func (f *ResourceFilter) filter(output string) ([]resource, error) {
+	object := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(output), &object); err != nil {
+		return nil, fmt.Errorf("output is not valid JSON, please use \"-o json\": %w", err)
+	}
+
+	items := []interface{}{object}
+	if list, ok := object["items"].([]interface{}); ok {
+		items = list
+	}
+
+	var resources []resource
+	for _, item := range items {
+		matches, err := f.matches(item)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			resources = append(resources, toResource(item))
+		}
+	}
+	return resources, nil
+}
+
+// matches returns true if any of the values found by the JSONPath expression equals the expected value
+func (f *ResourceFilter) matches(item interface{}) (bool, error) {
+	results, err := f.jsonPath.FindResults(item)
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate JSONPath expression %q: %w", f.expression, err)
+	}
+
+	for _, result := range results {
+		for _, value := range result {
+			if fmt.Sprint(value.Interface()) == f.value {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// toResource extracts the kind, namespace and name of the given resource
+func toResource(item interface{}) resource {
+	r := resource{}
+	object, ok := item.(map[string]interface{})
+	if !ok {
+		return r
+	}
+
+	r.kind, _ = object["kind"].(string)
+	if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+		r.namespace, _ = metadata["namespace"].(string)
+		r.name, _ = metadata["name"].(string)
+	}
+	return r
+}
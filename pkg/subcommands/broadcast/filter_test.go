@@ -0,0 +1,167 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broadcast
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const podList = `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {"kind": "Pod", "metadata": {"namespace": "default", "name": "web-1"}, "status": {"phase": "Running"}},
+    {"kind": "Pod", "metadata": {"namespace": "default", "name": "web-2"}, "status": {"phase": "Pending"}},
+    {"kind": "Pod", "metadata": {"namespace": "kube-system", "name": "dns", "labels": {"app": "dns"}}, "status": {"phase": "Pending", "restarts": 3}}
+  ]
+}`
+
+const pod = `{"kind": "Pod", "metadata": {"namespace": "default", "name": "web-2"}, "status": {"phase": "Pending"}}`
+
+var _ = Describe("ResourceFilter", func() {
+	parse := func(filter string) *ResourceFilter {
+		f, err := ParseResourceFilter(filter)
+		Expect(err).ToNot(HaveOccurred())
+		return f
+	}
+
+	Describe("ParseResourceFilter", func() {
+		It("should parse a JSONPath expression with curly braces", func() {
+			f := parse("{.status.phase}=Pending")
+			Expect(f.expression).To(Equal("{.status.phase}"))
+			Expect(f.value).To(Equal("Pending"))
+		})
+
+		It("should add the curly braces around the JSONPath expression", func() {
+			f := parse(".status.phase=Pending")
+			Expect(f.expression).To(Equal("{.status.phase}"))
+			Expect(f.value).To(Equal("Pending"))
+		})
+
+		It("should split at the last equal sign", func() {
+			f := parse(`{.spec.containers[?(@.name=="web")].image}=nginx`)
+			Expect(f.expression).To(Equal(`{.spec.containers[?(@.name=="web")].image}`))
+			Expect(f.value).To(Equal("nginx"))
+		})
+
+		It("should allow an empty value", func() {
+			Expect(parse(".status.phase=").value).To(BeEmpty())
+		})
+
+		It("should reject a filter without value", func() {
+			_, err := ParseResourceFilter(".status.phase")
+			Expect(err).To(MatchError(`invalid resource filter ".status.phase": expected <jsonpath>=<value>`))
+		})
+
+		It("should reject a filter without JSONPath expression", func() {
+			_, err := ParseResourceFilter("=Pending")
+			Expect(err).To(MatchError(ContainSubstring("expected <jsonpath>=<value>")))
+		})
+
+		It("should reject an invalid JSONPath expression", func() {
+			_, err := ParseResourceFilter("{.status.phase=Pending")
+			Expect(err).To(MatchError(ContainSubstring("invalid JSONPath expression")))
+		})
+	})
+
+	Describe("filter", func() {
+		It("should return the matching resources of a list", func() {
+			resources, err := parse("{.status.phase}=Pending").filter(podList)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources).To(Equal([]resource{
+				{kind: "Pod", namespace: "default", name: "web-2"},
+				{kind: "Pod", namespace: "kube-system", name: "dns"},
+			}))
+		})
+
+		It("should return a single matching resource", func() {
+			resources, err := parse("{.status.phase}=Pending").filter(pod)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources).To(Equal([]resource{{kind: "Pod", namespace: "default", name: "web-2"}}))
+		})
+
+		It("should not return a single resource that does not match", func() {
+			resources, err := parse("{.status.phase}=Running").filter(pod)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources).To(BeEmpty())
+		})
+
+		It("should return no resources of an empty list", func() {
+			resources, err := parse("{.status.phase}=Pending").filter(`{"kind": "List", "items": []}`)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources).To(BeEmpty())
+		})
+
+		It("should not match resources missing the field", func() {
+			resources, err := parse("{.metadata.labels.app}=dns").filter(podList)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources).To(Equal([]resource{{kind: "Pod", namespace: "kube-system", name: "dns"}}))
+		})
+
+		It("should compare non-string values by their string representation", func() {
+			resources, err := parse("{.status.restarts}=3").filter(podList)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resources).To(HaveLen(1))
+		})
+
+		It("should fail for output that is not JSON", func() {
+			_, err := parse("{.status.phase}=Pending").filter("NAME   READY\nweb-1  1/1")
+			Expect(err).To(MatchError(ContainSubstring(`output is not valid JSON, please use "-o json"`)))
+		})
+	})
+
+	Describe("matches", func() {
+		It("should match if any of the found values equals the expected value", func() {
+			item := map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"image": "busybox"},
+						map[string]interface{}{"image": "nginx"},
+					},
+				},
+			}
+
+			matches, err := parse("{.spec.containers[*].image}=nginx").matches(item)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matches).To(BeTrue())
+
+			matches, err = parse("{.spec.containers[*].image}=redis").matches(item)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matches).To(BeFalse())
+		})
+	})
+
+	Describe("toResource", func() {
+		It("should extract the kind, namespace and name", func() {
+			Expect(toResource(map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"namespace": "default", "name": "web"},
+			})).To(Equal(resource{kind: "Deployment", namespace: "default", name: "web"}))
+		})
+
+		It("should leave the namespace of cluster-scoped resources empty", func() {
+			Expect(toResource(map[string]interface{}{
+				"kind":     "Node",
+				"metadata": map[string]interface{}{"name": "node-1"},
+			})).To(Equal(resource{kind: "Node", name: "node-1"}))
+		})
+
+		It("should return an empty resource for values that are not objects", func() {
+			Expect(toResource("web")).To(Equal(resource{}))
+			Expect(toResource(map[string]interface{}{"kind": 1})).To(Equal(resource{}))
+		})
+	})
+})
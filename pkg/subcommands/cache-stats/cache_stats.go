@@ -0,0 +1,94 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cachestats
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/jedib0t/go-pretty/v6/table"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storecache "github.com/danielfoehrkn/kubeswitch/pkg/store/cache"
+)
+
+// cachingStore is implemented by stores caching the kubeconfigs of the wrapped store
+type cachingStore interface {
+	Cache() storecache.Cache
+}
+
+// ShowStats prints the size on disk of the kubeconfigs cached by the disk caches of the stores
+// together with the size of the kubeconfigs before compression
+func ShowStats(stores []store.KubeconfigStore) error {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Store", "Directory", "Kubeconfigs", "Size on disk", "Uncompressed", "Saved"})
+
+	var total storecache.Stats
+	found := false
+	for _, s := range stores {
+		statsProvider := getStatsProvider(s)
+		if statsProvider == nil {
+			continue
+		}
+		found = true
+
+		stats, err := statsProvider.Stats()
+		if err != nil {
+			return fmt.Errorf("failed to read the cache of store %q: %w", s.GetID(), err)
+		}
+
+		t.AppendRow(table.Row{s.GetID(), stats.Directory, stats.Files, humanize.IBytes(uint64(stats.SizeOnDisk)), humanize.IBytes(uint64(stats.UncompressedSize)), saved(stats)})
+		total.Files += stats.Files
+		total.SizeOnDisk += stats.SizeOnDisk
+		total.UncompressedSize += stats.UncompressedSize
+	}
+
+	if !found {
+		fmt.Println("No store is configured with a disk cache")
+		return nil
+	}
+
+	t.AppendFooter(table.Row{"Total", "", total.Files, humanize.IBytes(uint64(total.SizeOnDisk)), humanize.IBytes(uint64(total.UncompressedSize)), saved(total)})
+	t.Render()
+	return nil
+}
+
+// getStatsProvider returns the cache of the store or of a store wrapped by it that provides statistics
+func getStatsProvider(s store.KubeconfigStore) storecache.StatsProvider {
+	for s != nil {
+		if cs, ok := s.(cachingStore); ok {
+			if statsProvider, ok := cs.Cache().(storecache.StatsProvider); ok {
+				return statsProvider
+			}
+		}
+
+		wrapper, ok := s.(store.Wrapper)
+		if !ok {
+			return nil
+		}
+		s = wrapper.Unwrap()
+	}
+	return nil
+}
+
+// saved returns the percentage of disk space saved by the compression
+func saved(stats storecache.Stats) string {
+	if stats.UncompressedSize == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", 100-float64(stats.SizeOnDisk)*100/float64(stats.UncompressedSize))
+}
@@ -0,0 +1,233 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// Directory is the directory in the state directory containing one file per checkpoint
+const Directory = "checkpoints"
+
+var logger = logrus.New()
+
+// Checkpoint is the list of discovered contexts at a point in time
+type Checkpoint struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Contexts maps the context name to the ID of the store that discovered it
+	Contexts map[string]string `json:"contexts"`
+}
+
+// Save discovers all contexts and saves them as a checkpoint with the given name.
+// Uses the current time as the name if no name is given.
+func Save(name string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	now := time.Now().UTC()
+	if len(name) == 0 {
+		name = now.Format("20060102-150405")
+	}
+	// the name is used as the file name of the checkpoint
+	name = util.SanitizeContextName(name)
+
+	contexts, failedStoreIDs, err := discoverContexts(stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+	if len(failedStoreIDs) > 0 {
+		logger.Warnf("the checkpoint does not contain the contexts of the failed stores: %s", strings.Join(sets.List(failedStoreIDs), ", "))
+	}
+
+	checkpoint := Checkpoint{
+		Name:      name,
+		CreatedAt: now,
+		Contexts:  contexts,
+	}
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	directory := filepath.Join(stateDir, Directory)
+	if err := kubeswitchio.MkdirAll(directory, 0700); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+
+	if err := kubeswitchio.WriteFile(filepath.Join(directory, fmt.Sprintf("%s.json", name)), data, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint %q: %w", name, err)
+	}
+
+	fmt.Printf("saved checkpoint %q with %d contexts\n", name, len(contexts))
+	return nil
+}
+
+// Diff compares the currently discovered contexts with a checkpoint and prints the added and removed contexts.
+// The checkpoint is selected by name. If no name is given, the most recent checkpoint older than "since" is used.
+func Diff(name string, since time.Duration, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	var (
+		checkpoint *Checkpoint
+		err        error
+	)
+
+	if len(name) > 0 {
+		checkpoint, err = load(filepath.Join(stateDir, Directory, fmt.Sprintf("%s.json", util.SanitizeContextName(name))))
+	} else {
+		checkpoint, err = findCheckpoint(stateDir, time.Now().Add(-since))
+	}
+	if err != nil {
+		return err
+	}
+
+	contexts, failedStoreIDs, err := discoverContexts(stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	added, removed := Compare(checkpoint, contexts, failedStoreIDs)
+
+	fmt.Printf("Comparing with checkpoint %q from %s\n", checkpoint.Name, checkpoint.CreatedAt.Local().Format(time.RFC1123))
+	if len(failedStoreIDs) > 0 {
+		fmt.Printf("Not comparing the contexts of the failed stores: %s\n", strings.Join(sets.List(failedStoreIDs), ", "))
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		fmt.Println("No contexts added or removed")
+		return nil
+	}
+
+	if len(added) > 0 {
+		fmt.Printf("Added contexts (%d):\n%s\n", len(added), strings.Join(added, "\n"))
+	}
+	if len(removed) > 0 {
+		fmt.Printf("Removed contexts (%d):\n%s\n", len(removed), strings.Join(removed, "\n"))
+	}
+	return nil
+}
+
+// discoverContexts returns all discovered context names mapped to the ID of the store that discovered it
+// Compare returns the contexts added and removed since the checkpoint as lines of the diff.
+// The contexts of failed stores are not reported as removed, as the stores did not return all of their contexts.
+func Compare(checkpoint *Checkpoint, contexts map[string]string, failedStoreIDs sets.Set[string]) ([]string, []string) {
+	var added, removed []string
+	for context, storeID := range contexts {
+		if _, ok := checkpoint.Contexts[context]; !ok {
+			added = append(added, fmt.Sprintf("+ %s (%s)", context, storeID))
+		}
+	}
+	for context, storeID := range checkpoint.Contexts {
+		if _, ok := contexts[context]; !ok && !failedStoreIDs.Has(storeID) {
+			removed = append(removed, fmt.Sprintf("- %s (%s)", context, storeID))
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// discoverContexts returns the discovered contexts mapped to the ID of their store and the IDs of the stores that failed the search
+func discoverContexts(stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) (map[string]string, sets.Set[string], error) {
+	c, err := pkg.DoSearch(stores, config, stateDir, noIndex, pkg.WithIgnoredStoreErrors())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	contexts := make(map[string]string)
+	failedStoreIDs := sets.New[string]()
+	for discoveredContext := range *c {
+		if discoveredContext.Error != nil {
+			if discoveredContext.Store != nil {
+				failedStoreIDs.Insert((*discoveredContext.Store).GetID())
+			}
+			logger.Warnf("error returned from search: %v", discoveredContext.Error)
+			continue
+		}
+
+		if discoveredContext.Store == nil {
+			continue
+		}
+
+		name := discoveredContext.Name
+		if len(discoveredContext.Alias) > 0 {
+			name = discoveredContext.Alias
+		}
+		contexts[name] = (*discoveredContext.Store).GetID()
+	}
+	return contexts, failedStoreIDs, nil
+}
+
+// findCheckpoint returns the most recent checkpoint created before the given time
+func findCheckpoint(stateDir string, before time.Time) (*Checkpoint, error) {
+	directory := filepath.Join(stateDir, Directory)
+	files, err := os.ReadDir(directory)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var latest *Checkpoint
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		checkpoint, err := load(filepath.Join(directory, file.Name()))
+		if err != nil {
+			logger.Warnf("%v", err)
+			continue
+		}
+
+		if checkpoint.CreatedAt.After(before) {
+			continue
+		}
+
+		if latest == nil || checkpoint.CreatedAt.After(latest.CreatedAt) {
+			latest = checkpoint
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no checkpoint found. Please run `switch checkpoint save` first")
+	}
+	return latest, nil
+}
+
+func load(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("checkpoint %q does not exist", strings.TrimSuffix(filepath.Base(path), ".json"))
+		}
+		return nil, err
+	}
+
+	checkpoint := &Checkpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %q: %w", path, err)
+	}
+	return checkpoint, nil
+}
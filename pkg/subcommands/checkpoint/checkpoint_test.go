@@ -0,0 +1,129 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checkpoint_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/checkpoint"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+users:
+- name: dev
+`
+
+// fakeStore finds a single kubeconfig containing the context "dev"
+type fakeStore struct{}
+
+func (s *fakeStore) GetID() string                               { return "filesystem.fake" }
+func (s *fakeStore) GetKind() types.StoreKind                    { return types.StoreKindFilesystem }
+func (s *fakeStore) GetContextPrefix(string) string              { return "" }
+func (s *fakeStore) VerifyKubeconfigPaths(context.Context) error { return nil }
+func (s *fakeStore) Probe(context.Context) error                 { return nil }
+func (s *fakeStore) GetLogger() *logrus.Entry                    { return testutil.NewTestLogger() }
+func (s *fakeStore) GetStoreConfig() types.KubeconfigStore       { return types.KubeconfigStore{} }
+func (s *fakeStore) Stop(context.Context) error                  { return nil }
+func (s *fakeStore) StartSearch(_ context.Context, channel chan store.SearchResult) {
+	channel <- testutil.FakeSearchResult("dev")
+}
+
+func (s *fakeStore) GetKubeconfigForPath(context.Context, string, map[string]string) ([]byte, error) {
+	return []byte(kubeconfig), nil
+}
+
+var _ = Describe("Checkpoint", func() {
+	Describe("Compare", func() {
+		saved := &checkpoint.Checkpoint{
+			Contexts: map[string]string{
+				"kept":    "vault.default",
+				"removed": "vault.default",
+				"failed":  "eks.default",
+			},
+		}
+
+		It("should report added and removed contexts with their store", func() {
+			added, removed := checkpoint.Compare(saved, map[string]string{
+				"kept":   "vault.default",
+				"failed": "eks.default",
+				"new":    "gke.default",
+			}, sets.New[string]())
+			Expect(added).To(Equal([]string{"+ new (gke.default)"}))
+			Expect(removed).To(Equal([]string{"- removed (vault.default)"}))
+		})
+
+		It("should not report the contexts of failed stores as removed", func() {
+			added, removed := checkpoint.Compare(saved, map[string]string{
+				"kept":    "vault.default",
+				"removed": "vault.default",
+			}, sets.New("eks.default"))
+			Expect(added).To(BeEmpty())
+			Expect(removed).To(BeEmpty())
+		})
+	})
+
+	Describe("Save", func() {
+		var stateDir string
+
+		BeforeEach(func() {
+			var err error
+			stateDir, err = os.MkdirTemp("", "kubeswitch-checkpoint")
+			Expect(err).ToNot(HaveOccurred())
+			annotations.SetPath(filepath.Join(stateDir, "annotations.yaml"))
+		})
+
+		AfterEach(func() {
+			annotations.SetPath(annotations.DefaultPath)
+			Expect(os.RemoveAll(stateDir)).To(Succeed())
+		})
+
+		It("should save the discovered contexts to a file named after the sanitized name", func() {
+			Expect(checkpoint.Save("../../weekly", []store.KubeconfigStore{&fakeStore{}}, &types.Config{}, stateDir, true)).To(Succeed())
+
+			entries, err := os.ReadDir(filepath.Join(stateDir, checkpoint.Directory))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(Equal("..-..-weekly.json"))
+
+			data, err := os.ReadFile(filepath.Join(stateDir, checkpoint.Directory, entries[0].Name()))
+			Expect(err).ToNot(HaveOccurred())
+			saved := &checkpoint.Checkpoint{}
+			Expect(json.Unmarshal(data, saved)).To(Succeed())
+			Expect(saved.Contexts).To(Equal(map[string]string{"dev": "filesystem.fake"}))
+		})
+	})
+})
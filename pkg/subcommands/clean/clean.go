@@ -19,6 +19,7 @@ import (
 	"os"
 
 	"github.com/danielfoehrkn/kubeswitch/pkg/cache"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
 	"github.com/danielfoehrkn/kubeswitch/pkg/store"
 	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
 )
@@ -27,7 +28,7 @@ func Clean(stores []store.KubeconfigStore) error {
 	// cleanup temporary kubeconfig files
 	tempDir := os.ExpandEnv(kubeconfigutil.TemporaryKubeconfigDir)
 	files, _ := os.ReadDir(tempDir)
-	err := os.RemoveAll(tempDir)
+	err := kubeswitchio.RemoveAll(tempDir)
 	if err != nil {
 		return err
 	}
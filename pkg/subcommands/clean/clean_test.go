@@ -0,0 +1,66 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clean_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/clean"
+)
+
+var _ = Describe("Clean", func() {
+	var (
+		home         string
+		originalHome string
+		tempDir      string
+	)
+
+	BeforeEach(func() {
+		var err error
+		home, err = os.MkdirTemp("", "kubeswitch-clean")
+		Expect(err).ToNot(HaveOccurred())
+		originalHome = os.Getenv("HOME")
+		Expect(os.Setenv("HOME", home)).To(Succeed())
+
+		tempDir = filepath.Join(home, ".kube", ".switch_tmp")
+		Expect(os.MkdirAll(tempDir, 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(tempDir, "config.1.tmp"), []byte("kind: Config"), 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		kubeswitchio.SetWriter(kubeswitchio.FileWriter{})
+		Expect(os.Setenv("HOME", originalHome)).To(Succeed())
+		Expect(os.RemoveAll(home)).To(Succeed())
+	})
+
+	It("should remove the temporary kubeconfig directory", func() {
+		Expect(clean.Clean(nil)).To(Succeed())
+		Expect(tempDir).ToNot(BeADirectory())
+	})
+
+	It("should only record the removal of the temporary kubeconfig directory in dry-run mode", func() {
+		writer := kubeswitchio.NewDryRunWriter(false, "")
+		kubeswitchio.SetWriter(writer)
+
+		Expect(clean.Clean(nil)).To(Succeed())
+		Expect(filepath.Join(tempDir, "config.1.tmp")).To(BeAnExistingFile())
+		Expect(writer.Summary()).To(Equal([]string{"Would remove " + tempDir + " and all of its contents"}))
+	})
+})
@@ -0,0 +1,190 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterprovision
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/index"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/wait"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var logger = logrus.New()
+
+// providers maps the supported providers to the kind of the store used to create the clusters
+var providers = map[string]types.StoreKind{
+	"gke": types.StoreKindGKE,
+	"eks": types.StoreKindEKS,
+	"aks": types.StoreKindAzure,
+}
+
+// Options are the options to provision a cluster
+type Options struct {
+	// Provider is the cloud provider to create the cluster with (gke, eks or aks)
+	Provider string
+	// Name is the name of the new cluster
+	Name string
+	// StoreID optionally selects the store of the provider. Defaults to the first configured store of the provider.
+	StoreID string
+	// ConfigPath is the path to the provider-specific cluster config. The default config is used if empty.
+	ConfigPath string
+	// Timeout is the maximum duration to wait for the API server of the new cluster after it has been created
+	Timeout time.Duration
+}
+
+// Providers returns the names of the supported providers
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PrintDefaultConfig prints the documented default cluster config of the provider
+func PrintDefaultConfig(provider string) error {
+	kind, err := getKind(provider)
+	if err != nil {
+		return err
+	}
+
+	config, err := store.DefaultProvisionConfig(kind)
+	if err != nil {
+		return err
+	}
+	fmt.Print(config)
+	return nil
+}
+
+// Provision creates the cluster with the store of the provider, adds it to the search index of the store
+// and waits until the API server of the cluster is ready.
+// In dry-run mode, only the cluster to create is printed.
+func Provision(options Options, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	kind, err := getKind(options.Provider)
+	if err != nil {
+		return err
+	}
+
+	kubeconfigStore, err := getStore(kind, options.StoreID, stores)
+	if err != nil {
+		return err
+	}
+
+	if err := store.CheckWritable(kubeconfigStore); err != nil {
+		return err
+	}
+
+	provisioner, ok := store.Unwrap(kubeconfigStore).(store.Provisioner)
+	if !ok {
+		return fmt.Errorf("store %q does not support provisioning clusters", kubeconfigStore.GetID())
+	}
+
+	var clusterConfig []byte
+	if len(options.ConfigPath) > 0 {
+		clusterConfig, err = os.ReadFile(options.ConfigPath)
+		if err != nil {
+			return fmt.Errorf("failed to read cluster config: %w", err)
+		}
+	}
+
+	if kubeswitchio.IsDryRun() {
+		if len(options.ConfigPath) > 0 {
+			fmt.Printf("Would create cluster %q with store %q using the cluster config %q\n", options.Name, kubeconfigStore.GetID(), options.ConfigPath)
+		} else {
+			fmt.Printf("Would create cluster %q with store %q using the default cluster config\n", options.Name, kubeconfigStore.GetID())
+		}
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Creating cluster %q with store %q. This can take several minutes...\n", options.Name, kubeconfigStore.GetID())
+	path, err := provisioner.ProvisionCluster(context.Background(), options.Name, clusterConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Cluster %q created\n", options.Name)
+
+	// the next search of the store rebuilds the index containing the new cluster
+	index.Invalidate(kubeconfigStore.GetLogger(), kubeconfigStore.GetKind(), stateDir, kubeconfigStore.GetID())
+
+	discoveredContext, err := findContextForPath(path, kubeconfigStore, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Waiting for context %q to become ready\n", discoveredContext.Name)
+	return wait.WaitForContextReady(discoveredContext, options.Timeout, wait.DefaultReadyThreshold)
+}
+
+func getKind(provider string) (types.StoreKind, error) {
+	kind, ok := providers[provider]
+	if !ok {
+		return "", fmt.Errorf("unsupported provider %q. Supported providers: %s", provider, strings.Join(Providers(), ", "))
+	}
+	return kind, nil
+}
+
+// getStore returns the store of the given kind with the given ID or the first store of the kind if no ID is given
+func getStore(kind types.StoreKind, storeID string, stores []store.KubeconfigStore) (store.KubeconfigStore, error) {
+	for _, s := range stores {
+		if s.GetKind() != kind {
+			continue
+		}
+		if len(storeID) == 0 || s.GetID() == storeID {
+			return s, nil
+		}
+	}
+
+	if len(storeID) > 0 {
+		return nil, fmt.Errorf("no store of kind %q with ID %q configured", kind, storeID)
+	}
+	return nil, fmt.Errorf("no store of kind %q configured. Please add the store to the SwitchConfig file", kind)
+}
+
+// findContextForPath searches the store for the context of the new cluster
+func findContextForPath(path string, kubeconfigStore store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) (*pkg.DiscoveredContext, error) {
+	c, err := pkg.DoSearch([]store.KubeconfigStore{kubeconfigStore}, config, stateDir, noIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *pkg.DiscoveredContext
+	for discoveredContext := range *c {
+		if discoveredContext.Error != nil {
+			logger.Debugf("error searching store %q: %v", kubeconfigStore.GetID(), discoveredContext.Error)
+			continue
+		}
+
+		if result == nil && discoveredContext.Path == path {
+			result = &discoveredContext
+		}
+	}
+
+	if result == nil {
+		return nil, fmt.Errorf("the created cluster with kubeconfig path %q was not found in store %q", path, kubeconfigStore.GetID())
+	}
+	return result, nil
+}
@@ -0,0 +1,207 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clusterprovision_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"k8s.io/utils/ptr"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/index"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	clusterprovision "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/cluster-provision"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// kubeconfig is the kubeconfig of the provisioned cluster. Its API server is not reachable.
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: new-cluster
+  cluster:
+    server: https://127.0.0.1:1
+contexts:
+- name: new-cluster
+  context:
+    cluster: new-cluster
+    user: admin
+users:
+- name: admin
+  user:
+    token: admin-token
+`
+
+// provisionStore is a GKE store that records the provisioned clusters and finds the cluster at kubeconfigPath
+type provisionStore struct {
+	id             string
+	readOnly       bool
+	kubeconfigPath string
+	provisioned    map[string][]byte
+}
+
+func (s *provisionStore) GetID() string                               { return s.id }
+func (s *provisionStore) GetKind() types.StoreKind                    { return types.StoreKindGKE }
+func (s *provisionStore) GetContextPrefix(string) string              { return "" }
+func (s *provisionStore) VerifyKubeconfigPaths(context.Context) error { return nil }
+func (s *provisionStore) Probe(context.Context) error                 { return nil }
+func (s *provisionStore) GetLogger() *logrus.Entry                    { return testutil.NewTestLogger() }
+func (s *provisionStore) Stop(context.Context) error                  { return nil }
+func (s *provisionStore) GetStoreConfig() types.KubeconfigStore {
+	return types.KubeconfigStore{Kind: types.StoreKindGKE, ReadOnly: s.readOnly}
+}
+func (s *provisionStore) StartSearch(_ context.Context, channel chan store.SearchResult) {
+	channel <- store.SearchResult{KubeconfigPath: s.kubeconfigPath}
+}
+func (s *provisionStore) GetKubeconfigForPath(context.Context, string, map[string]string) ([]byte, error) {
+	return []byte(kubeconfig), nil
+}
+func (s *provisionStore) ProvisionCluster(_ context.Context, name string, config []byte) (string, error) {
+	s.provisioned[name] = config
+	return "gke_project--europe-west1--" + name, nil
+}
+
+// apiStore is an EKS store that does not support provisioning clusters
+type apiStore struct{}
+
+func (apiStore) GetID() string                                        { return "eks.api" }
+func (apiStore) GetKind() types.StoreKind                             { return types.StoreKindEKS }
+func (apiStore) GetContextPrefix(string) string                       { return "" }
+func (apiStore) VerifyKubeconfigPaths(context.Context) error          { return nil }
+func (apiStore) Probe(context.Context) error                          { return nil }
+func (apiStore) StartSearch(context.Context, chan store.SearchResult) {}
+func (apiStore) GetLogger() *logrus.Entry                             { return testutil.NewTestLogger() }
+func (apiStore) Stop(context.Context) error                           { return nil }
+func (apiStore) GetStoreConfig() types.KubeconfigStore {
+	return types.KubeconfigStore{Kind: types.StoreKindEKS}
+}
+func (apiStore) GetKubeconfigForPath(context.Context, string, map[string]string) ([]byte, error) {
+	return nil, nil
+}
+
+var _ = Describe("Provision", func() {
+	var (
+		stateDir       string
+		kubeconfigPath string
+		gkeStore       *provisionStore
+		stores         []store.KubeconfigStore
+		options        clusterprovision.Options
+		config         *types.Config
+		noIndex        bool
+	)
+
+	BeforeEach(func() {
+		var err error
+		stateDir, err = os.MkdirTemp("", "kubeswitch-cluster-provision")
+		Expect(err).ToNot(HaveOccurred())
+
+		kubeconfigPath = "gke_project--europe-west1--new-cluster"
+		gkeStore = &provisionStore{id: "gke.default", kubeconfigPath: kubeconfigPath, provisioned: map[string][]byte{}}
+		stores = []store.KubeconfigStore{apiStore{}, gkeStore}
+		options = clusterprovision.Options{Provider: "gke", Name: "new-cluster", Timeout: 100 * time.Millisecond}
+		config = &types.Config{}
+		noIndex = true
+	})
+
+	AfterEach(func() {
+		kubeswitchio.SetWriter(kubeswitchio.FileWriter{})
+		Expect(os.RemoveAll(stateDir)).To(Succeed())
+	})
+
+	provision := func() error {
+		return clusterprovision.Provision(options, stores, config, stateDir, noIndex)
+	}
+
+	It("should reject unsupported providers", func() {
+		options.Provider = "openstack"
+		Expect(provision()).To(MatchError(`unsupported provider "openstack". Supported providers: aks, eks, gke`))
+	})
+
+	It("should fail if no store of the provider is configured", func() {
+		options.Provider = "aks"
+		Expect(provision()).To(MatchError(ContainSubstring(`no store of kind "azure" configured`)))
+	})
+
+	It("should fail if the store with the given ID is not configured", func() {
+		options.StoreID = "gke.other"
+		Expect(provision()).To(MatchError(`no store of kind "gke" with ID "gke.other" configured`))
+		Expect(gkeStore.provisioned).To(BeEmpty())
+	})
+
+	It("should fail if the store does not support provisioning clusters", func() {
+		options.Provider = "eks"
+		Expect(provision()).To(MatchError(`store "eks.api" does not support provisioning clusters`))
+	})
+
+	It("should not provision clusters with a read-only store", func() {
+		gkeStore.readOnly = true
+		Expect(provision()).To(MatchError(&storeerrors.ErrReadOnly{}))
+		Expect(gkeStore.provisioned).To(BeEmpty())
+	})
+
+	It("should not provision the cluster in dry-run mode", func() {
+		kubeswitchio.SetWriter(kubeswitchio.NewDryRunWriter(false, ""))
+		Expect(provision()).To(Succeed())
+		Expect(gkeStore.provisioned).To(BeEmpty())
+	})
+
+	It("should fail if the cluster config cannot be read", func() {
+		options.ConfigPath = filepath.Join(stateDir, "missing.yaml")
+		Expect(provision()).To(MatchError(ContainSubstring("failed to read cluster config")))
+		Expect(gkeStore.provisioned).To(BeEmpty())
+	})
+
+	It("should provision the cluster with the selected store and the given config", func() {
+		other := &provisionStore{id: "gke.other", kubeconfigPath: kubeconfigPath, provisioned: map[string][]byte{}}
+		stores = append(stores, other)
+		options.StoreID = "gke.other"
+		options.ConfigPath = filepath.Join(stateDir, "gke.yaml")
+		Expect(os.WriteFile(options.ConfigPath, []byte("location: europe-west1\n"), 0600)).To(Succeed())
+
+		Expect(provision()).To(MatchError(ContainSubstring("did not become ready within")))
+		Expect(gkeStore.provisioned).To(BeEmpty())
+		Expect(other.provisioned).To(HaveKeyWithValue("new-cluster", []byte("location: europe-west1\n")))
+	})
+
+	It("should invalidate the index of the store and wait for the context of the new cluster", func() {
+		// without invalidating the index, the search would only find the cluster in the index
+		config.RefreshIndexAfter = ptr.To(time.Hour)
+		noIndex = false
+		searchIndex, err := index.New(testutil.NewTestLogger(), types.StoreKindGKE, stateDir, gkeStore.GetID())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(searchIndex.Write(types.Index{
+			Kind:                 types.StoreKindGKE,
+			ContextToPathMapping: map[string]string{"old-cluster": "gke_project--europe-west1--old-cluster"},
+		})).To(Succeed())
+		Expect(searchIndex.WriteState(types.IndexState{Kind: types.StoreKindGKE, LastUpdateTime: time.Now().UTC()})).To(Succeed())
+
+		Expect(provision()).To(MatchError(ContainSubstring(`context "new-cluster" did not become ready within 100ms`)))
+		Expect(gkeStore.provisioned).To(HaveKey("new-cluster"))
+	})
+
+	It("should fail if the new cluster is not found in the store", func() {
+		gkeStore.kubeconfigPath = "gke_project--europe-west1--other-cluster"
+		Expect(provision()).To(MatchError(`the created cluster with kubeconfig path "gke_project--europe-west1--new-cluster" was not found in store "gke.default"`))
+		Expect(gkeStore.provisioned).To(HaveKey("new-cluster"))
+	})
+})
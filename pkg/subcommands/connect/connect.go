@@ -0,0 +1,262 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connect
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	setcontext "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/set-context"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// ProxiesDirectory is the directory in the state directory containing one file per running proxy
+const ProxiesDirectory = "proxies"
+
+var logger = logrus.New()
+
+// Proxy is a running kubectl proxy registered in the state directory
+type Proxy struct {
+	// ID is the identifier of the proxy. Equals the local port of the proxy
+	ID             string    `json:"id"`
+	Context        string    `json:"context"`
+	Port           int       `json:"port"`
+	PID            int       `json:"pid"`
+	KubeconfigPath string    `json:"kubeconfigPath"`
+	StartedAt      time.Time `json:"startedAt"`
+}
+
+// Connect starts a kubectl proxy for the given context.
+// If no context is given, the context is selected interactively.
+// If no port is given, a free port is selected.
+// If printURL is set, the proxy is started in the background and only the proxy URL is printed.
+func Connect(desiredContext string, port int, printURL bool, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex, showPreview bool) error {
+	var (
+		kubeconfigPath *string
+		contextName    *string
+		err            error
+	)
+
+	if len(desiredContext) > 0 {
+		kubeconfigPath, contextName, err = setcontext.SetContext(desiredContext, stores, config, stateDir, noIndex, false)
+	} else {
+		kubeconfigPath, contextName, err = pkg.Switcher(stores, config, stateDir, noIndex, showPreview, nil, "")
+	}
+	if err != nil {
+		return err
+	}
+	if kubeconfigPath == nil || contextName == nil {
+		return nil
+	}
+
+	if port == 0 {
+		if port, err = getFreePort(); err != nil {
+			return fmt.Errorf("failed to find a free port: %w", err)
+		}
+	}
+
+	cmd := exec.Command("kubectl", "proxy", "--kubeconfig", *kubeconfigPath, "--port", strconv.Itoa(port))
+	cmd.Stderr = os.Stderr
+	if !printURL {
+		cmd.Stdout = os.Stdout
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start kubectl proxy: %w", err)
+	}
+
+	proxy := Proxy{
+		ID:             strconv.Itoa(port),
+		Context:        *contextName,
+		Port:           port,
+		PID:            cmd.Process.Pid,
+		KubeconfigPath: *kubeconfigPath,
+		StartedAt:      time.Now().UTC(),
+	}
+	if err := register(stateDir, proxy); err != nil {
+		logger.Warnf("failed to register proxy: %v", err)
+	}
+
+	proxyURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+	if printURL {
+		// keep the proxy running in the background
+		fmt.Println(proxyURL)
+		return cmd.Process.Release()
+	}
+
+	fmt.Printf("Proxy for context %q listening on %s (id %s)\n", *contextName, proxyURL, proxy.ID)
+
+	// forward signals to gracefully stop the proxy
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		for sig := range signals {
+			_ = cmd.Process.Signal(sig)
+		}
+	}()
+
+	err = cmd.Wait()
+	unregister(stateDir, proxy)
+	// the proxy is expected to exit due to a forwarded signal
+	if exitErr, ok := err.(*exec.ExitError); ok && !exitErr.Exited() {
+		return nil
+	}
+	return err
+}
+
+// List prints all running proxies. Registrations of proxies that are not running anymore are removed.
+func List(stateDir string) error {
+	proxies, err := getProxies(stateDir)
+	if err != nil {
+		return err
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"ID", "Context", "URL", "PID", "Started"})
+	for _, proxy := range proxies {
+		t.AppendRow(table.Row{proxy.ID, proxy.Context, fmt.Sprintf("http://127.0.0.1:%d", proxy.Port), proxy.PID, proxy.StartedAt.Local().Format(time.RFC1123)})
+	}
+	t.Render()
+	return nil
+}
+
+// Stop stops the proxy with the given ID
+func Stop(id, stateDir string) error {
+	proxy, err := load(filepath.Join(stateDir, ProxiesDirectory, fmt.Sprintf("%s.json", id)))
+	if err != nil {
+		return err
+	}
+
+	if process, err := os.FindProcess(proxy.PID); err == nil {
+		if err := process.Signal(syscall.SIGTERM); err != nil {
+			logger.Debugf("failed to stop proxy with PID %d: %v", proxy.PID, err)
+		}
+	}
+
+	unregister(stateDir, *proxy)
+	fmt.Printf("Stopped proxy %s for context %q\n", proxy.ID, proxy.Context)
+	return nil
+}
+
+// getProxies returns all registered proxies that are still running
+func getProxies(stateDir string) ([]Proxy, error) {
+	directory := filepath.Join(stateDir, ProxiesDirectory)
+	files, err := os.ReadDir(directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var proxies []Proxy
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		proxy, err := load(filepath.Join(directory, file.Name()))
+		if err != nil {
+			logger.Warnf("%v", err)
+			continue
+		}
+
+		if !IsRunning(proxy.PID) {
+			unregister(stateDir, *proxy)
+			continue
+		}
+		proxies = append(proxies, *proxy)
+	}
+
+	sort.Slice(proxies, func(i, j int) bool {
+		return proxies[i].StartedAt.Before(proxies[j].StartedAt)
+	})
+	return proxies, nil
+}
+
+// IsRunning returns true if the process with the given PID is running.
+// Not supported on Windows, where signal 0 cannot be sent to processes.
+func IsRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+func register(stateDir string, proxy Proxy) error {
+	directory := filepath.Join(stateDir, ProxiesDirectory)
+	if err := kubeswitchio.MkdirAll(directory, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(proxy, "", "  ")
+	if err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(filepath.Join(directory, fmt.Sprintf("%s.json", proxy.ID)), data, 0600)
+}
+
+// unregister removes the registration and the temporary kubeconfig of the proxy
+func unregister(stateDir string, proxy Proxy) {
+	_ = kubeswitchio.Remove(filepath.Join(stateDir, ProxiesDirectory, fmt.Sprintf("%s.json", proxy.ID)))
+	if kubeconfigutil.IsTemporaryKubeconfigFile(proxy.KubeconfigPath) {
+		_ = kubeswitchio.Remove(proxy.KubeconfigPath)
+	}
+}
+
+func load(path string) (*Proxy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("proxy with id %q not found", filepath.Base(path[:len(path)-len(".json")]))
+		}
+		return nil, err
+	}
+
+	proxy := &Proxy{}
+	if err := json.Unmarshal(data, proxy); err != nil {
+		return nil, fmt.Errorf("failed to parse proxy registration %q: %w", path, err)
+	}
+	return proxy, nil
+}
+
+// getFreePort asks the kernel for a free local port
+func getFreePort() (int, error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contextcopy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/index"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var logger = logrus.New()
+
+// Options are the optional modifications of the copied context
+type Options struct {
+	// Namespace overrides the namespace of the copied context
+	Namespace string
+	// User overrides the user of the copied context. The user must exist in the kubeconfig of the source context.
+	User string
+	// Overwrite allows to overwrite an existing context with the destination name
+	Overwrite bool
+}
+
+// CopyContext duplicates the source context under the destination name.
+// The copied context is written as a standalone kubeconfig to the default output directory of the first filesystem store
+// that has a kubeconfig directory configured, and is added to the search index of this store.
+func CopyContext(source, destination string, opts Options, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	if source == destination {
+		return fmt.Errorf("the destination context name must differ from the source context name")
+	}
+
+	discoveredContext, err := pkg.FindContext(source, stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	filesystemStore, err := getOutputStore(stores)
+	if err != nil {
+		return err
+	}
+
+	path, err := filesystemStore.GetKubeconfigPathForContext(destination)
+	if err != nil {
+		return err
+	}
+
+	if !opts.Overwrite {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("kubeconfig %q of context %q already exists. Use --overwrite to replace it", path, destination)
+		}
+		if _, err := pkg.FindContext(destination, stores, config, stateDir, noIndex); err == nil {
+			return fmt.Errorf("context %q already exists. Use --overwrite to replace it", destination)
+		}
+	}
+
+	kubeconfigStore := *discoveredContext.Store
+	kubeconfigData, err := kubeconfigStore.GetKubeconfigForPath(context.Background(), discoveredContext.Path, discoveredContext.Tags)
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return fmt.Errorf("failed to parse kubeconfig of context %q: %v", source, err)
+	}
+
+	contextName := pkg.KubeconfigContextName(kubeconfigStore, *discoveredContext)
+
+	copied, err := copyContext(kubeconfig, contextName, destination, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := kubeswitchio.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := clientcmd.Write(*copied)
+	if err != nil {
+		return err
+	}
+	if err := kubeswitchio.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig to %q: %w", path, err)
+	}
+
+	addToIndex(filesystemStore, stateDir, destination, path)
+
+	fmt.Printf("Copied context %q to %q (%s)\n", source, destination, path)
+	return nil
+}
+
+// copyContext returns a kubeconfig only containing the copy of the context with its cluster and user
+func copyContext(kubeconfig *clientcmdapi.Config, contextName, destination string, opts Options) (*clientcmdapi.Config, error) {
+	context, ok := kubeconfig.Contexts[contextName]
+	if !ok {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", contextName)
+	}
+
+	copiedContext := context.DeepCopy()
+	if len(opts.Namespace) > 0 {
+		copiedContext.Namespace = opts.Namespace
+	}
+	if len(opts.User) > 0 {
+		if _, ok := kubeconfig.AuthInfos[opts.User]; !ok {
+			return nil, fmt.Errorf("user %q not found in the kubeconfig of context %q", opts.User, contextName)
+		}
+		copiedContext.AuthInfo = opts.User
+	}
+
+	kubeconfig.Contexts = map[string]*clientcmdapi.Context{destination: copiedContext}
+	kubeconfig.CurrentContext = destination
+
+	// only keep the cluster and user of the copied context
+	if err := clientcmdapi.MinifyConfig(kubeconfig); err != nil {
+		return nil, fmt.Errorf("failed to extract context %q from kubeconfig: %w", contextName, err)
+	}
+	return kubeconfig, nil
+}
+
+// getOutputStore returns the first writable filesystem store with a kubeconfig directory
+func getOutputStore(stores []store.KubeconfigStore) (*store.FilesystemStore, error) {
+	var lastErr error
+	for _, s := range stores {
+		filesystemStore, ok := store.Unwrap(s).(*store.FilesystemStore)
+		if !ok {
+			continue
+		}
+
+		if err := store.CheckWritable(s); err != nil {
+			lastErr = err
+			continue
+		}
+
+		if _, err := filesystemStore.GetDefaultOutputDirectory(); err != nil {
+			lastErr = err
+			continue
+		}
+		return filesystemStore, nil
+	}
+
+	if lastErr != nil {
+		return nil, fmt.Errorf("no filesystem store to write the copied context to: %w", lastErr)
+	}
+	return nil, fmt.Errorf("no filesystem store to write the copied context to. Please configure a filesystem store with a kubeconfig directory")
+}
+
+// addToIndex adds the copied context to the search index of the filesystem store, if the store uses an index.
+// Failing to do so is not fatal, the context is found once the index is refreshed.
+func addToIndex(filesystemStore *store.FilesystemStore, stateDir, destination, path string) {
+	searchIndex, err := index.New(filesystemStore.GetLogger(), filesystemStore.GetKind(), stateDir, filesystemStore.GetID())
+	if err != nil {
+		logger.Warnf("failed to load the index of store %q: %v", filesystemStore.GetID(), err)
+		return
+	}
+
+	if !searchIndex.HasKind(filesystemStore.GetKind()) {
+		return
+	}
+
+	contextName := destination
+	if prefix := filesystemStore.GetContextPrefix(path); len(prefix) > 0 {
+		contextName = fmt.Sprintf("%s/%s", prefix, destination)
+	}
+
+	contextToPath, contextToTags := searchIndex.GetContent()
+	if contextToPath == nil {
+		contextToPath = map[string]string{}
+	}
+	contextToPath[contextName] = path
+
+	if err := searchIndex.Write(types.Index{
+		Kind:                 filesystemStore.GetKind(),
+		ContextToPathMapping: contextToPath,
+		ContextToTags:        contextToTags,
+	}); err != nil {
+		logger.Warnf("failed to add context %q to the index of store %q: %v", contextName, filesystemStore.GetID(), err)
+	}
+}
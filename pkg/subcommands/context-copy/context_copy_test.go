@@ -0,0 +1,240 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contextcopy_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/index"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	contextcopy "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/context-copy"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+    namespace: default
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+users:
+- name: admin
+  user:
+    token: admin-token
+- name: viewer
+  user:
+    token: viewer-token
+`
+
+var _ = Describe("CopyContext", func() {
+	var (
+		tempDir        string
+		kubeconfigsDir string
+		stores         []store.KubeconfigStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-context-copy")
+		Expect(err).ToNot(HaveOccurred())
+		annotations.SetPath(filepath.Join(tempDir, "annotations.yaml"))
+
+		kubeconfigsDir = filepath.Join(tempDir, "kubeconfigs")
+		Expect(os.MkdirAll(filepath.Join(kubeconfigsDir, "team"), 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(kubeconfigsDir, "team", "config"), []byte(kubeconfig), 0600)).To(Succeed())
+
+		filesystemStore, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{kubeconfigsDir},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		stores = []store.KubeconfigStore{filesystemStore}
+	})
+
+	AfterEach(func() {
+		annotations.SetPath(annotations.DefaultPath)
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	copyContext := func(source, destination string, opts contextcopy.Options) error {
+		return contextcopy.CopyContext(source, destination, opts, stores, &types.Config{}, tempDir, true)
+	}
+
+	loadCopy := func(destination string) string {
+		data, err := os.ReadFile(filepath.Join(kubeconfigsDir, destination, "config"))
+		Expect(err).ToNot(HaveOccurred())
+		copied, err := clientcmd.Load(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(copied.Contexts).To(HaveLen(1))
+		Expect(copied.Contexts).To(HaveKey(destination))
+		return copied.Contexts[destination].Namespace
+	}
+
+	It("should write the copy to its own directory in the kubeconfig directory of the filesystem store", func() {
+		Expect(copyContext("team/dev", "dev-copy", contextcopy.Options{})).To(Succeed())
+		Expect(loadCopy("dev-copy")).To(Equal("default"))
+	})
+
+	It("should write the copy to the path the filesystem store writes kubeconfigs to", func() {
+		path, err := stores[0].(*store.FilesystemStore).GetKubeconfigPathForContext("team/copy")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(kubeconfigsDir, "team-copy", "config")))
+
+		Expect(copyContext("dev", "team/copy", contextcopy.Options{})).To(Succeed())
+		Expect(path).To(BeAnExistingFile())
+	})
+
+	It("should refuse to overwrite an existing copy without --overwrite", func() {
+		Expect(copyContext("dev", "dev-copy", contextcopy.Options{})).To(Succeed())
+
+		err := copyContext("prod", "dev-copy", contextcopy.Options{Namespace: "changed"})
+		Expect(err).To(MatchError(ContainSubstring("already exists. Use --overwrite to replace it")))
+		Expect(loadCopy("dev-copy")).To(Equal("default"))
+	})
+
+	It("should refuse to copy to the name of an existing context without --overwrite", func() {
+		Expect(copyContext("dev", "prod", contextcopy.Options{})).To(MatchError(`context "prod" already exists. Use --overwrite to replace it`))
+		Expect(filepath.Join(kubeconfigsDir, "prod")).ToNot(BeADirectory())
+	})
+
+	It("should overwrite an existing copy with --overwrite", func() {
+		Expect(copyContext("dev", "dev-copy", contextcopy.Options{})).To(Succeed())
+		Expect(copyContext("dev", "dev-copy", contextcopy.Options{Namespace: "changed", Overwrite: true})).To(Succeed())
+		Expect(loadCopy("dev-copy")).To(Equal("changed"))
+	})
+
+	Describe("options", func() {
+		loadConfig := func(destination string) *clientcmdapi.Config {
+			data, err := os.ReadFile(filepath.Join(kubeconfigsDir, destination, "config"))
+			Expect(err).ToNot(HaveOccurred())
+			copied, err := clientcmd.Load(data)
+			Expect(err).ToNot(HaveOccurred())
+			return copied
+		}
+
+		It("should set the namespace of the copy", func() {
+			Expect(copyContext("team/prod", "prod-copy", contextcopy.Options{Namespace: "monitoring"})).To(Succeed())
+			Expect(loadCopy("prod-copy")).To(Equal("monitoring"))
+		})
+
+		It("should keep the user of the source context if no user is given", func() {
+			Expect(copyContext("team/dev", "dev-copy", contextcopy.Options{})).To(Succeed())
+
+			copied := loadConfig("dev-copy")
+			Expect(copied.CurrentContext).To(Equal("dev-copy"))
+			Expect(copied.Contexts["dev-copy"].AuthInfo).To(Equal("admin"))
+			Expect(copied.Contexts["dev-copy"].Cluster).To(Equal("dev"))
+			Expect(copied.AuthInfos).To(HaveLen(1))
+			Expect(copied.AuthInfos).To(HaveKey("admin"))
+			Expect(copied.Clusters).To(HaveLen(1))
+			Expect(copied.Clusters).To(HaveKey("dev"))
+		})
+
+		It("should use the given user and only keep this user in the copy", func() {
+			Expect(copyContext("team/dev", "dev-viewer", contextcopy.Options{User: "viewer"})).To(Succeed())
+
+			copied := loadConfig("dev-viewer")
+			Expect(copied.Contexts["dev-viewer"].AuthInfo).To(Equal("viewer"))
+			Expect(copied.Contexts["dev-viewer"].Namespace).To(Equal("default"))
+			Expect(copied.AuthInfos).To(HaveLen(1))
+			Expect(copied.AuthInfos["viewer"].Token).To(Equal("viewer-token"))
+		})
+
+		It("should set the namespace and user of the copy", func() {
+			Expect(copyContext("team/prod", "prod-viewer", contextcopy.Options{Namespace: "monitoring", User: "viewer"})).To(Succeed())
+
+			copied := loadConfig("prod-viewer")
+			Expect(copied.Contexts["prod-viewer"].Namespace).To(Equal("monitoring"))
+			Expect(copied.Contexts["prod-viewer"].AuthInfo).To(Equal("viewer"))
+			Expect(copied.Contexts["prod-viewer"].Cluster).To(Equal("prod"))
+		})
+
+		It("should reject a user that does not exist in the kubeconfig of the source context", func() {
+			Expect(copyContext("team/dev", "dev-copy", contextcopy.Options{User: "unknown"})).To(MatchError(`user "unknown" not found in the kubeconfig of context "dev"`))
+			Expect(filepath.Join(kubeconfigsDir, "dev-copy")).ToNot(BeADirectory())
+		})
+
+		It("should not modify the source context", func() {
+			Expect(copyContext("team/dev", "dev-copy", contextcopy.Options{Namespace: "monitoring", User: "viewer"})).To(Succeed())
+
+			source := loadConfig("team")
+			Expect(source.Contexts["dev"].Namespace).To(Equal("default"))
+			Expect(source.Contexts["dev"].AuthInfo).To(Equal("admin"))
+		})
+	})
+
+	Describe("index", func() {
+		var searchIndex func() *index.SearchIndex
+
+		BeforeEach(func() {
+			searchIndex = func() *index.SearchIndex {
+				i, err := index.New(testutil.NewTestLogger(), types.StoreKindFilesystem, tempDir, stores[0].GetID())
+				Expect(err).ToNot(HaveOccurred())
+				return i
+			}
+			Expect(searchIndex().Write(types.Index{
+				Kind:                 types.StoreKindFilesystem,
+				ContextToPathMapping: map[string]string{"team/dev": filepath.Join(kubeconfigsDir, "team", "config")},
+			})).To(Succeed())
+		})
+
+		It("should add the copy to the index of the filesystem store", func() {
+			Expect(copyContext("team/dev", "dev-copy", contextcopy.Options{})).To(Succeed())
+
+			contextToPath, _ := searchIndex().GetContent()
+			Expect(contextToPath).To(HaveKeyWithValue("team/dev", filepath.Join(kubeconfigsDir, "team", "config")))
+			Expect(contextToPath).To(HaveKeyWithValue("dev-copy/dev-copy", filepath.Join(kubeconfigsDir, "dev-copy", "config")))
+		})
+
+		It("should neither write the copy nor update the index of a read-only store", func() {
+			readOnlyStore, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+				Kind:     types.StoreKindFilesystem,
+				Paths:    []string{kubeconfigsDir},
+				ReadOnly: true,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			stores = []store.KubeconfigStore{readOnlyStore}
+
+			Expect(copyContext("team/dev", "dev-copy", contextcopy.Options{})).To(MatchError(&storeerrors.ErrReadOnly{}))
+			Expect(filepath.Join(kubeconfigsDir, "dev-copy")).ToNot(BeADirectory())
+
+			contextToPath, _ := searchIndex().GetContent()
+			Expect(contextToPath).ToNot(HaveKey("dev-copy/dev-copy"))
+		})
+	})
+})
@@ -0,0 +1,217 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contextinfo
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const (
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputTable = "table"
+)
+
+// ContextInfo contains the metadata of a context
+type ContextInfo struct {
+	ContextName          string            `json:"contextName" yaml:"contextName"`
+	StoreID              string            `json:"storeID" yaml:"storeID"`
+	Server               string            `json:"server" yaml:"server"`
+	Namespace            string            `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	CertificateAuthority *CertificateInfo  `json:"certificateAuthority,omitempty" yaml:"certificateAuthority,omitempty"`
+	ClientCertificate    *CertificateInfo  `json:"clientCertificate,omitempty" yaml:"clientCertificate,omitempty"`
+	ExecPlugin           string            `json:"execPlugin,omitempty" yaml:"execPlugin,omitempty"`
+	Tags                 map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Notes                []string          `json:"notes,omitempty" yaml:"notes,omitempty"`
+}
+
+// CertificateInfo contains the common name and expiry of a certificate
+type CertificateInfo struct {
+	CommonName string    `json:"commonName" yaml:"commonName"`
+	NotAfter   time.Time `json:"notAfter" yaml:"notAfter"`
+}
+
+// ShowContextInfo prints metadata about the given context without switching to it
+func ShowContextInfo(desiredContext, output string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	info, err := GetContextInfo(desiredContext, stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case OutputJSON:
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case OutputYAML:
+		data, err := yaml.Marshal(info)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+	case OutputTable:
+		printTable(info)
+	default:
+		return fmt.Errorf("unknown output format %q. Valid formats are %q, %q and %q", output, OutputJSON, OutputYAML, OutputTable)
+	}
+	return nil
+}
+
+// GetContextInfo fetches the kubeconfig for the given context from its store and extracts the metadata
+func GetContextInfo(desiredContext string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) (*ContextInfo, error) {
+	discoveredContext, err := pkg.FindContext(desiredContext, stores, config, stateDir, noIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfigStore := *discoveredContext.Store
+	kubeconfigData, err := kubeconfigStore.GetKubeconfigForPath(context.Background(), discoveredContext.Path, discoveredContext.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfig, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %v", err)
+	}
+
+	contextName := pkg.KubeconfigContextName(kubeconfigStore, *discoveredContext)
+
+	context, ok := kubeconfig.Contexts[contextName]
+	if !ok {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", contextName)
+	}
+
+	info := &ContextInfo{
+		ContextName: desiredContext,
+		StoreID:     kubeconfigStore.GetID(),
+		Namespace:   context.Namespace,
+		Tags:        discoveredContext.Tags,
+	}
+
+	if cluster, ok := kubeconfig.Clusters[context.Cluster]; ok {
+		info.Server = cluster.Server
+		info.CertificateAuthority, err = getCertificateInfo(cluster.CertificateAuthorityData, cluster.CertificateAuthority)
+		if err != nil {
+			info.Notes = append(info.Notes, fmt.Sprintf("failed to parse certificate authority: %v", err))
+		}
+	}
+
+	if authInfo, ok := kubeconfig.AuthInfos[context.AuthInfo]; ok {
+		info.ClientCertificate, err = getCertificateInfo(authInfo.ClientCertificateData, authInfo.ClientCertificate)
+		if err != nil {
+			info.Notes = append(info.Notes, fmt.Sprintf("failed to parse client certificate: %v", err))
+		}
+
+		// the exec plugin is not invoked
+		if authInfo.Exec != nil {
+			info.ExecPlugin = strings.TrimSpace(fmt.Sprintf("%s %s", authInfo.Exec.Command, strings.Join(authInfo.Exec.Args, " ")))
+			info.Notes = append(info.Notes, "exec plugin required for token")
+		}
+	}
+
+	return info, nil
+}
+
+// getCertificateInfo parses the first PEM encoded certificate either from the given data or the file
+// returns nil if neither is set
+func getCertificateInfo(data []byte, file string) (*CertificateInfo, error) {
+	if len(data) == 0 && len(file) > 0 {
+		var err error
+		if data, err = os.ReadFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateInfo{
+		CommonName: certificate.Subject.CommonName,
+		NotAfter:   certificate.NotAfter,
+	}, nil
+}
+
+func printTable(info *ContextInfo) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendRows([]table.Row{
+		{"Context", info.ContextName},
+		{"Store", info.StoreID},
+		{"Server", info.Server},
+		{"Namespace", info.Namespace},
+	})
+
+	if info.CertificateAuthority != nil {
+		t.AppendRows([]table.Row{
+			{"CA Common Name", info.CertificateAuthority.CommonName},
+			{"CA Expiry", info.CertificateAuthority.NotAfter.Format(time.RFC3339)},
+		})
+	}
+
+	if info.ClientCertificate != nil {
+		t.AppendRows([]table.Row{
+			{"Client Certificate Common Name", info.ClientCertificate.CommonName},
+			{"Client Certificate Expiry", info.ClientCertificate.NotAfter.Format(time.RFC3339)},
+		})
+	}
+
+	if len(info.ExecPlugin) > 0 {
+		t.AppendRow(table.Row{"Exec Plugin", info.ExecPlugin})
+	}
+
+	tagKeys := make([]string, 0, len(info.Tags))
+	for key := range info.Tags {
+		tagKeys = append(tagKeys, key)
+	}
+	sort.Strings(tagKeys)
+	for _, key := range tagKeys {
+		t.AppendRow(table.Row{fmt.Sprintf("Tag %s", key), info.Tags[key]})
+	}
+
+	for _, note := range info.Notes {
+		t.AppendRow(table.Row{"Note", note})
+	}
+	t.Render()
+}
@@ -0,0 +1,191 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contextmove
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/index"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// MoveContext moves the context to the store with the given ID.
+// The kubeconfig of the context is written to the target store, read back to verify it, and then removed from the source store.
+// If the target store cannot be written to, the kubeconfig is written to the first writable filesystem store other than the source store instead.
+// If the source store cannot delete contexts or deleteSource is false, the original kubeconfig is kept in the source store.
+// In dry-run mode, only the planned move is printed.
+func MoveContext(contextName, targetStoreID string, deleteSource bool, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	targetStore, err := getStore(targetStoreID, stores)
+	if err != nil {
+		return err
+	}
+
+	discoveredContext, err := pkg.FindContext(contextName, stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	sourceStore := *discoveredContext.Store
+	if sourceStore.GetID() == targetStore.GetID() {
+		return fmt.Errorf("context %q is already in store %q", contextName, targetStore.GetID())
+	}
+
+	ctx := context.Background()
+	kubeconfigData, err := sourceStore.GetKubeconfigForPath(ctx, discoveredContext.Path, discoveredContext.Tags)
+	if err != nil {
+		return err
+	}
+
+	name := pkg.KubeconfigContextName(sourceStore, *discoveredContext)
+
+	kubeconfig, err := extractContext(kubeconfigData, name)
+	if err != nil {
+		return err
+	}
+
+	writer, writeStore, err := getWriter(targetStore, sourceStore, stores)
+	if err != nil {
+		return err
+	}
+
+	if kubeswitchio.IsDryRun() {
+		// the written kubeconfig cannot be read back to verify it
+		fmt.Printf("Would write context %q to store %q\n", contextName, writeStore.GetID())
+		if deleteSource {
+			fmt.Printf("Would remove context %q from store %q\n", contextName, sourceStore.GetID())
+		}
+		return nil
+	}
+
+	path, err := writer.WriteKubeconfig(ctx, name, kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to write context %q to store %q: %w", contextName, writeStore.GetID(), err)
+	}
+	index.Invalidate(writeStore.GetLogger(), writeStore.GetKind(), stateDir, writeStore.GetID())
+
+	if err := verify(ctx, writeStore, path, name); err != nil {
+		return fmt.Errorf("failed to verify context %q in store %q, the source is left untouched: %w", contextName, writeStore.GetID(), err)
+	}
+	fmt.Printf("Wrote context %q to store %q (%s)\n", contextName, writeStore.GetID(), path)
+
+	if !deleteSource {
+		return nil
+	}
+
+	deleter, ok := store.Unwrap(sourceStore).(store.ContextDeleter)
+	if !ok {
+		fmt.Printf("Note: the original context is still in store %q, as the store does not support deleting contexts\n", sourceStore.GetID())
+		return nil
+	}
+	if err := store.CheckWritable(sourceStore); err != nil {
+		fmt.Printf("Note: the original context is still in store %q: %v\n", sourceStore.GetID(), err)
+		return nil
+	}
+
+	if err := deleter.DeleteContext(ctx, discoveredContext.Path, name); err != nil {
+		return fmt.Errorf("failed to remove context %q from store %q: %w", contextName, sourceStore.GetID(), err)
+	}
+	index.Invalidate(sourceStore.GetLogger(), sourceStore.GetKind(), stateDir, sourceStore.GetID())
+
+	fmt.Printf("Removed context %q from store %q\n", contextName, sourceStore.GetID())
+	return nil
+}
+
+// getStore returns the store with the given ID
+func getStore(storeID string, stores []store.KubeconfigStore) (store.KubeconfigStore, error) {
+	ids := make([]string, 0, len(stores))
+	for _, s := range stores {
+		if s.GetID() == storeID {
+			return s, nil
+		}
+		ids = append(ids, s.GetID())
+	}
+	return nil, &storeerrors.ErrStoreNotFound{StoreID: storeID, Err: fmt.Errorf("configured stores: %s", strings.Join(ids, ", "))}
+}
+
+// getWriter returns the writer of the target store.
+// Falls back to the first writable filesystem store other than the source store if the target store cannot be written to,
+// e.g. because it is backed by a cloud API.
+func getWriter(targetStore, sourceStore store.KubeconfigStore, stores []store.KubeconfigStore) (store.Writer, store.KubeconfigStore, error) {
+	if err := store.CheckWritable(targetStore); err != nil {
+		return nil, nil, err
+	}
+
+	if writer, ok := store.Unwrap(targetStore).(store.Writer); ok {
+		return writer, targetStore, nil
+	}
+
+	for _, s := range stores {
+		// writing to the source store would remove the moved context afterwards
+		if s.GetID() == sourceStore.GetID() {
+			continue
+		}
+		filesystemStore, ok := store.Unwrap(s).(*store.FilesystemStore)
+		if !ok || store.CheckWritable(s) != nil {
+			continue
+		}
+		if _, err := filesystemStore.GetDefaultOutputDirectory(); err != nil {
+			continue
+		}
+
+		fmt.Printf("Note: store %q does not support writing kubeconfigs, writing to filesystem store %q instead\n", targetStore.GetID(), s.GetID())
+		return filesystemStore, s, nil
+	}
+	return nil, nil, fmt.Errorf("store %q does not support writing kubeconfigs and there is no filesystem store with a kubeconfig directory other than the source store %q to write to instead", targetStore.GetID(), sourceStore.GetID())
+}
+
+// extractContext returns the serialized kubeconfig only containing the given context with its cluster and user
+func extractContext(kubeconfigData []byte, name string) ([]byte, error) {
+	kubeconfig, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig of context %q: %w", name, err)
+	}
+
+	if _, ok := kubeconfig.Contexts[name]; !ok {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", name)
+	}
+	kubeconfig.CurrentContext = name
+
+	if err := clientcmdapi.MinifyConfig(kubeconfig); err != nil {
+		return nil, fmt.Errorf("failed to extract context %q from kubeconfig: %w", name, err)
+	}
+	return clientcmd.Write(*kubeconfig)
+}
+
+// verify checks that the written kubeconfig can be retrieved from the store and contains the context
+func verify(ctx context.Context, s store.KubeconfigStore, path, name string) error {
+	data, err := s.GetKubeconfigForPath(ctx, path, nil)
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, err := clientcmd.Load(data)
+	if err != nil {
+		return err
+	}
+	if _, ok := kubeconfig.Contexts[name]; !ok {
+		return fmt.Errorf("context %q not found in kubeconfig %q", name, path)
+	}
+	return nil
+}
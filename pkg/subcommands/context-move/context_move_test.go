@@ -0,0 +1,280 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contextmove_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/ptr"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/index"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	contextmove "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/context-move"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+users:
+- name: admin
+  user:
+    token: admin-token
+`
+
+// apiStore is a store that does not support writing kubeconfigs, e.g. because it is backed by a cloud API
+type apiStore struct{}
+
+func (apiStore) GetID() string                                        { return "eks.api" }
+func (apiStore) GetKind() types.StoreKind                             { return types.StoreKindEKS }
+func (apiStore) GetContextPrefix(string) string                       { return "" }
+func (apiStore) VerifyKubeconfigPaths(context.Context) error          { return nil }
+func (apiStore) Probe(context.Context) error                          { return nil }
+func (apiStore) StartSearch(context.Context, chan store.SearchResult) {}
+func (apiStore) GetLogger() *logrus.Entry                             { return testutil.NewTestLogger() }
+func (apiStore) Stop(context.Context) error                           { return nil }
+func (apiStore) GetStoreConfig() types.KubeconfigStore {
+	return types.KubeconfigStore{Kind: types.StoreKindEKS}
+}
+func (apiStore) GetKubeconfigForPath(context.Context, string, map[string]string) ([]byte, error) {
+	return nil, nil
+}
+
+var _ = Describe("MoveContext", func() {
+	var (
+		tempDir    string
+		sourceDir  string
+		targetDir  string
+		sourcePath string
+	)
+
+	newStore := func(id, dir string, readOnly bool) store.KubeconfigStore {
+		s, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			ID:       ptr.To(id),
+			Kind:     types.StoreKindFilesystem,
+			Paths:    []string{dir},
+			ReadOnly: readOnly,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		return s
+	}
+
+	searchIndex := func(s store.KubeconfigStore) *index.SearchIndex {
+		i, err := index.New(testutil.NewTestLogger(), types.StoreKindFilesystem, tempDir, s.GetID())
+		Expect(err).ToNot(HaveOccurred())
+		return i
+	}
+
+	writeIndex := func(s store.KubeconfigStore, contextToPath map[string]string) {
+		i := searchIndex(s)
+		Expect(i.Write(types.Index{Kind: types.StoreKindFilesystem, ContextToPathMapping: contextToPath})).To(Succeed())
+		Expect(i.WriteState(types.IndexState{Kind: types.StoreKindFilesystem, LastUpdateTime: time.Now().UTC()})).To(Succeed())
+	}
+
+	moveContext := func(contextName string, source, target store.KubeconfigStore) error {
+		return contextmove.MoveContext(contextName, target.GetID(), true, []store.KubeconfigStore{source, target}, &types.Config{}, tempDir, true)
+	}
+
+	contextsIn := func(path string) []string {
+		kubeconfig, err := clientcmd.LoadFromFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		var names []string
+		for name := range kubeconfig.Contexts {
+			names = append(names, name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-context-move")
+		Expect(err).ToNot(HaveOccurred())
+
+		sourceDir = filepath.Join(tempDir, "source")
+		targetDir = filepath.Join(tempDir, "target")
+		Expect(os.MkdirAll(filepath.Join(sourceDir, "team"), 0700)).To(Succeed())
+		Expect(os.MkdirAll(targetDir, 0700)).To(Succeed())
+
+		sourcePath = filepath.Join(sourceDir, "team", "config")
+		Expect(os.WriteFile(sourcePath, []byte(kubeconfig), 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should write the context to the target store, remove it from the source store and invalidate both indexes", func() {
+		source := newStore("source", sourceDir, false)
+		target := newStore("target", targetDir, false)
+		writeIndex(source, map[string]string{"team/dev": sourcePath, "team/prod": sourcePath})
+		writeIndex(target, map[string]string{})
+
+		Expect(moveContext("team/dev", source, target)).To(Succeed())
+
+		Expect(contextsIn(sourcePath)).To(ConsistOf("prod"))
+		movedPath, err := target.(*store.FilesystemStore).GetKubeconfigPathForContext("dev")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contextsIn(movedPath)).To(ConsistOf("dev"))
+
+		Expect(searchIndex(source).HasContent()).To(BeFalse())
+		Expect(searchIndex(target).HasContent()).To(BeFalse())
+	})
+
+	It("should neither write to nor update the index of a read-only target store", func() {
+		source := newStore("source", sourceDir, false)
+		target := newStore("target", targetDir, true)
+		writeIndex(source, map[string]string{"team/dev": sourcePath, "team/prod": sourcePath})
+		writeIndex(target, map[string]string{"other/context": filepath.Join(targetDir, "other", "config")})
+
+		Expect(moveContext("team/dev", source, target)).To(MatchError(&storeerrors.ErrReadOnly{}))
+
+		Expect(contextsIn(sourcePath)).To(ConsistOf("dev", "prod"))
+		entries, err := os.ReadDir(targetDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+
+		sourceIndex, _ := searchIndex(source).GetContent()
+		Expect(sourceIndex).To(HaveKey("team/dev"))
+		targetIndex, _ := searchIndex(target).GetContent()
+		Expect(targetIndex).To(Equal(map[string]string{"other/context": filepath.Join(targetDir, "other", "config")}))
+	})
+
+	It("should keep the context and the index of a read-only source store", func() {
+		source := newStore("source", sourceDir, true)
+		target := newStore("target", targetDir, false)
+		writeIndex(source, map[string]string{"team/dev": sourcePath, "team/prod": sourcePath})
+		writeIndex(target, map[string]string{})
+
+		Expect(moveContext("team/dev", source, target)).To(Succeed())
+
+		Expect(contextsIn(sourcePath)).To(ConsistOf("dev", "prod"))
+		sourceIndex, _ := searchIndex(source).GetContent()
+		Expect(sourceIndex).To(HaveKeyWithValue("team/dev", sourcePath))
+
+		Expect(searchIndex(target).HasContent()).To(BeFalse())
+	})
+
+	It("should keep the context in the source store if deleting the source is not requested", func() {
+		source := newStore("source", sourceDir, false)
+		target := newStore("target", targetDir, false)
+
+		Expect(contextmove.MoveContext("team/dev", target.GetID(), false, []store.KubeconfigStore{source, target}, &types.Config{}, tempDir, true)).To(Succeed())
+
+		Expect(contextsIn(sourcePath)).To(ConsistOf("dev", "prod"))
+		movedPath, err := target.(*store.FilesystemStore).GetKubeconfigPathForContext("dev")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contextsIn(movedPath)).To(ConsistOf("dev"))
+	})
+
+	It("should fail for an unknown target store", func() {
+		source := newStore("source", sourceDir, false)
+
+		err := contextmove.MoveContext("team/dev", "unknown", true, []store.KubeconfigStore{source}, &types.Config{}, tempDir, true)
+		Expect(err).To(MatchError(&storeerrors.ErrStoreNotFound{}))
+		Expect(contextsIn(sourcePath)).To(ConsistOf("dev", "prod"))
+	})
+
+	It("should refuse to move a context to its own store", func() {
+		source := newStore("source", sourceDir, false)
+
+		err := contextmove.MoveContext("team/dev", source.GetID(), true, []store.KubeconfigStore{source}, &types.Config{}, tempDir, true)
+		Expect(err).To(MatchError(`context "team/dev" is already in store "filesystem.source"`))
+		Expect(contextsIn(sourcePath)).To(ConsistOf("dev", "prod"))
+	})
+
+	It("should only print the planned move in dry-run mode", func() {
+		kubeswitchio.SetWriter(kubeswitchio.NewDryRunWriter(false, ""))
+		defer kubeswitchio.SetWriter(kubeswitchio.FileWriter{})
+
+		source := newStore("source", sourceDir, false)
+		target := newStore("target", targetDir, false)
+
+		Expect(moveContext("team/dev", source, target)).To(Succeed())
+
+		Expect(contextsIn(sourcePath)).To(ConsistOf("dev", "prod"))
+		entries, err := os.ReadDir(targetDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	Describe("target store without writer", func() {
+		It("should write to another filesystem store instead", func() {
+			source := newStore("source", sourceDir, false)
+			fallback := newStore("fallback", targetDir, false)
+
+			// the source store comes first, but must not be used as the fallback
+			stores := []store.KubeconfigStore{source, apiStore{}, fallback}
+			Expect(contextmove.MoveContext("team/dev", apiStore{}.GetID(), true, stores, &types.Config{}, tempDir, true)).To(Succeed())
+
+			Expect(contextsIn(sourcePath)).To(ConsistOf("prod"))
+			movedPath, err := fallback.(*store.FilesystemStore).GetKubeconfigPathForContext("dev")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contextsIn(movedPath)).To(ConsistOf("dev"))
+		})
+
+		It("should not write to the source store", func() {
+			source := newStore("source", sourceDir, false)
+
+			stores := []store.KubeconfigStore{source, apiStore{}}
+			err := contextmove.MoveContext("team/dev", apiStore{}.GetID(), true, stores, &types.Config{}, tempDir, true)
+			Expect(err).To(MatchError(ContainSubstring(`there is no filesystem store with a kubeconfig directory other than the source store "filesystem.source"`)))
+
+			Expect(contextsIn(sourcePath)).To(ConsistOf("dev", "prod"))
+			entries, err := os.ReadDir(sourceDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+
+		It("should not write to a read-only filesystem store", func() {
+			source := newStore("source", sourceDir, false)
+			readOnly := newStore("read-only", targetDir, true)
+
+			stores := []store.KubeconfigStore{source, apiStore{}, readOnly}
+			err := contextmove.MoveContext("team/dev", apiStore{}.GetID(), true, stores, &types.Config{}, tempDir, true)
+			Expect(err).To(MatchError(ContainSubstring("does not support writing kubeconfigs")))
+
+			Expect(contextsIn(sourcePath)).To(ConsistOf("dev", "prod"))
+			entries, err := os.ReadDir(targetDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+	})
+})
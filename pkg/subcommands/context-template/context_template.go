@@ -0,0 +1,165 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contexttemplate
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+)
+
+// ExecPlugin is the credential plugin pre-populated in the kubeconfig template
+type ExecPlugin string
+
+const (
+	// ExecPluginPlaceholder is a placeholder exec plugin which has to be replaced with the actual credential plugin
+	ExecPluginPlaceholder ExecPlugin = ""
+	// ExecPluginEKS uses the AWS CLI to issue tokens for EKS clusters
+	ExecPluginEKS ExecPlugin = "eks"
+	// ExecPluginGKE uses the gke-gcloud-auth-plugin to issue tokens for GKE clusters
+	ExecPluginGKE ExecPlugin = "gke"
+	// ExecPluginAzure uses kubelogin to issue tokens for AKS clusters with Microsoft Entra ID integration
+	ExecPluginAzure ExecPlugin = "azure"
+)
+
+// ValidExecPlugins are the exec plugins which can be pre-populated in the kubeconfig template
+var ValidExecPlugins = []ExecPlugin{ExecPluginEKS, ExecPluginGKE, ExecPluginAzure}
+
+// placeholderCommand is the command of the placeholder exec plugin
+const placeholderCommand = "REPLACE-WITH-CREDENTIAL-PLUGIN"
+
+// execAPIVersion is the API version of the ExecCredential used by the exec plugins
+const execAPIVersion = "client.authentication.k8s.io/v1beta1"
+
+// azureServerID is the application ID of the Microsoft Entra ID server application shared by all AKS clusters
+const azureServerID = "6dae42f8-4368-4678-94ff-3960e28e3630"
+
+// Options are the fields of the kubeconfig template
+type Options struct {
+	// ClusterName is used as name of the context, the cluster and the user
+	ClusterName string
+	// Server is the URL of the API server
+	Server string
+	// CAFile is the optional path to the PEM encoded certificate authority of the API server. The certificate is inlined.
+	CAFile string
+	// ExecPlugin is the credential plugin pre-populated in the template
+	ExecPlugin ExecPlugin
+}
+
+// WriteTemplate writes a minimal kubeconfig for a new cluster to the output file.
+// Writes to STDOUT if the output is empty or "-".
+func WriteTemplate(opts Options, output string) error {
+	kubeconfig, err := Template(opts)
+	if err != nil {
+		return err
+	}
+
+	data, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return fmt.Errorf("failed to serialize kubeconfig: %w", err)
+	}
+
+	if len(output) == 0 || output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if err := kubeswitchio.WriteFile(output, data, 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig to %q: %w", output, err)
+	}
+	return nil
+}
+
+// Template returns a minimal kubeconfig for a new cluster with a single context, cluster and user.
+// The kubeconfig is validated before it is returned.
+func Template(opts Options) (*clientcmdapi.Config, error) {
+	if len(opts.ClusterName) == 0 {
+		return nil, fmt.Errorf("the cluster name must not be empty")
+	}
+	// the credentials issued by the exec plugin must not be sent unencrypted
+	if server, err := url.Parse(opts.Server); err != nil || server.Scheme != "https" || len(server.Host) == 0 {
+		return nil, fmt.Errorf("the server %q must be a URL starting with https://", opts.Server)
+	}
+
+	cluster := clientcmdapi.NewCluster()
+	cluster.Server = opts.Server
+	if len(opts.CAFile) > 0 {
+		ca, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read certificate authority: %w", err)
+		}
+		if block, _ := pem.Decode(ca); block == nil {
+			return nil, fmt.Errorf("certificate authority %q is not PEM encoded", opts.CAFile)
+		}
+		cluster.CertificateAuthorityData = ca
+	}
+
+	execConfig, err := getExecConfig(opts.ExecPlugin, opts.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+	authInfo := clientcmdapi.NewAuthInfo()
+	authInfo.Exec = execConfig
+
+	context := clientcmdapi.NewContext()
+	context.Cluster = opts.ClusterName
+	context.AuthInfo = opts.ClusterName
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[opts.ClusterName] = cluster
+	kubeconfig.AuthInfos[opts.ClusterName] = authInfo
+	kubeconfig.Contexts[opts.ClusterName] = context
+	kubeconfig.CurrentContext = opts.ClusterName
+
+	if err := clientcmd.Validate(*kubeconfig); err != nil {
+		return nil, fmt.Errorf("generated kubeconfig is invalid: %w", err)
+	}
+	return kubeconfig, nil
+}
+
+// getExecConfig returns the exec plugin stanza of the given credential plugin
+func getExecConfig(plugin ExecPlugin, clusterName string) (*clientcmdapi.ExecConfig, error) {
+	execConfig := &clientcmdapi.ExecConfig{
+		APIVersion:      execAPIVersion,
+		InteractiveMode: clientcmdapi.IfAvailableExecInteractiveMode,
+	}
+
+	switch plugin {
+	case ExecPluginPlaceholder:
+		execConfig.Command = placeholderCommand
+		execConfig.InstallHint = "Replace the command with the credential plugin issuing tokens for the cluster"
+	case ExecPluginEKS:
+		execConfig.Command = "aws"
+		execConfig.Args = []string{"eks", "get-token", "--cluster-name", clusterName, "--output", "json"}
+		execConfig.InstallHint = "Install the AWS CLI by following\nhttps://docs.aws.amazon.com/cli/latest/userguide/getting-started-install.html"
+	case ExecPluginGKE:
+		execConfig.Command = "gke-gcloud-auth-plugin"
+		execConfig.ProvideClusterInfo = true
+		execConfig.InstallHint = "Install gke-gcloud-auth-plugin for use with kubectl by following\nhttps://cloud.google.com/blog/products/containers-kubernetes/kubectl-auth-changes-in-gke"
+	case ExecPluginAzure:
+		execConfig.Command = "kubelogin"
+		execConfig.Args = []string{"get-token", "--login", "azurecli", "--server-id", azureServerID}
+		execConfig.InstallHint = "Install kubelogin by following\nhttps://azure.github.io/kubelogin/install.html"
+	default:
+		return nil, fmt.Errorf("unsupported exec plugin %q, supported exec plugins are %v", plugin, ValidExecPlugins)
+	}
+	return execConfig, nil
+}
@@ -0,0 +1,166 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package contexttemplate
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const caPEM = `-----BEGIN CERTIFICATE-----
+MIIBdzCCAR2gAwIBAgIBADAKBggqhkjOPQQDAjAAMB4XDTI0MDEwMTAwMDAwMFoX
+-----END CERTIFICATE-----
+`
+
+var _ = Describe("Template", func() {
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-context-template")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	It("should return a kubeconfig with a single context, cluster and user named after the cluster", func() {
+		kubeconfig, err := Template(Options{ClusterName: "demo", Server: "https://api.demo.example.com"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(kubeconfig.CurrentContext).To(Equal("demo"))
+		Expect(kubeconfig.Contexts).To(HaveLen(1))
+		Expect(kubeconfig.Contexts["demo"].Cluster).To(Equal("demo"))
+		Expect(kubeconfig.Contexts["demo"].AuthInfo).To(Equal("demo"))
+		Expect(kubeconfig.Clusters).To(HaveLen(1))
+		Expect(kubeconfig.Clusters["demo"].Server).To(Equal("https://api.demo.example.com"))
+		Expect(kubeconfig.Clusters["demo"].CertificateAuthorityData).To(BeEmpty())
+		Expect(kubeconfig.AuthInfos).To(HaveLen(1))
+		Expect(kubeconfig.AuthInfos["demo"].Exec.Command).To(Equal(placeholderCommand))
+	})
+
+	It("should inline the certificate authority", func() {
+		caFile := filepath.Join(tempDir, "ca.pem")
+		Expect(os.WriteFile(caFile, []byte(caPEM), 0600)).To(Succeed())
+
+		kubeconfig, err := Template(Options{ClusterName: "demo", Server: "https://api.demo.example.com", CAFile: caFile})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(kubeconfig.Clusters["demo"].CertificateAuthorityData)).To(Equal(caPEM))
+	})
+
+	It("should reject a certificate authority that is not PEM encoded", func() {
+		caFile := filepath.Join(tempDir, "ca.der")
+		Expect(os.WriteFile(caFile, []byte("not a certificate"), 0600)).To(Succeed())
+
+		_, err := Template(Options{ClusterName: "demo", Server: "https://api.demo.example.com", CAFile: caFile})
+		Expect(err).To(MatchError(ContainSubstring("is not PEM encoded")))
+	})
+
+	It("should fail if the certificate authority cannot be read", func() {
+		_, err := Template(Options{ClusterName: "demo", Server: "https://api.demo.example.com", CAFile: filepath.Join(tempDir, "missing.pem")})
+		Expect(err).To(MatchError(ContainSubstring("failed to read certificate authority")))
+	})
+
+	It("should require a cluster name", func() {
+		_, err := Template(Options{Server: "https://api.demo.example.com"})
+		Expect(err).To(MatchError("the cluster name must not be empty"))
+	})
+
+	DescribeTable("should reject servers that are not HTTPS URLs",
+		func(server string) {
+			_, err := Template(Options{ClusterName: "demo", Server: server})
+			Expect(err).To(MatchError(ContainSubstring("must be a URL starting with https://")))
+		},
+		Entry("http", "http://api.demo.example.com"),
+		Entry("upper case http", "HTTP://api.demo.example.com"),
+		Entry("other scheme", "tcp://api.demo.example.com:6443"),
+		Entry("without scheme", "api.demo.example.com"),
+		Entry("without host", "https://"),
+		Entry("empty", ""),
+	)
+
+	It("should reject an unsupported exec plugin", func() {
+		_, err := Template(Options{ClusterName: "demo", Server: "https://api.demo.example.com", ExecPlugin: "oidc"})
+		Expect(err).To(MatchError(ContainSubstring(`unsupported exec plugin "oidc"`)))
+	})
+
+	It("should write a valid kubeconfig to the output file", func() {
+		output := filepath.Join(tempDir, "config")
+		Expect(WriteTemplate(Options{ClusterName: "demo", Server: "https://api.demo.example.com:6443", ExecPlugin: ExecPluginEKS}, output)).To(Succeed())
+
+		kubeconfig, err := clientcmd.LoadFromFile(output)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(clientcmd.Validate(*kubeconfig)).To(Succeed())
+		Expect(kubeconfig.Clusters["demo"].Server).To(Equal("https://api.demo.example.com:6443"))
+		Expect(kubeconfig.AuthInfos["demo"].Exec.Command).To(Equal("aws"))
+
+		info, err := os.Stat(output)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+})
+
+var _ = Describe("getExecConfig", func() {
+	It("should return a placeholder command without exec plugin", func() {
+		execConfig, err := getExecConfig(ExecPluginPlaceholder, "demo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(execConfig.Command).To(Equal(placeholderCommand))
+		Expect(execConfig.Args).To(BeEmpty())
+		Expect(execConfig.APIVersion).To(Equal(execAPIVersion))
+		Expect(execConfig.InteractiveMode).To(Equal(clientcmdapi.IfAvailableExecInteractiveMode))
+	})
+
+	It("should pass the cluster name to the AWS CLI", func() {
+		execConfig, err := getExecConfig(ExecPluginEKS, "demo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(execConfig.Command).To(Equal("aws"))
+		Expect(execConfig.Args).To(Equal([]string{"eks", "get-token", "--cluster-name", "demo", "--output", "json"}))
+	})
+
+	It("should provide the cluster info to the GKE plugin", func() {
+		execConfig, err := getExecConfig(ExecPluginGKE, "demo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(execConfig.Command).To(Equal("gke-gcloud-auth-plugin"))
+		Expect(execConfig.Args).To(BeEmpty())
+		Expect(execConfig.ProvideClusterInfo).To(BeTrue())
+	})
+
+	It("should use the AKS server application with kubelogin", func() {
+		execConfig, err := getExecConfig(ExecPluginAzure, "demo")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(execConfig.Command).To(Equal("kubelogin"))
+		Expect(execConfig.Args).To(Equal([]string{"get-token", "--login", "azurecli", "--server-id", azureServerID}))
+	})
+
+	It("should set an install hint for every exec plugin", func() {
+		for _, plugin := range append(ValidExecPlugins, ExecPluginPlaceholder) {
+			execConfig, err := getExecConfig(plugin, "demo")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(execConfig.InstallHint).ToNot(BeEmpty(), "exec plugin %q", plugin)
+		}
+	})
+
+	It("should reject an unsupported exec plugin", func() {
+		_, err := getExecConfig("oidc", "demo")
+		Expect(err).To(MatchError(ContainSubstring("supported exec plugins are [eks gke azure]")))
+	})
+})
@@ -0,0 +1,53 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package convert
+
+import (
+	"fmt"
+	"os"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+)
+
+// Convert converts the YAML or JSON kubeconfig file to the given format and writes it to the output file.
+// Writes to STDOUT if the output is empty or "-".
+func Convert(input, output, to string) error {
+	format, err := kubeconfigutil.ParseFormat(to)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		return fmt.Errorf("failed to read kubeconfig %q: %w", input, err)
+	}
+
+	converted, err := util.ConvertKubeconfig(data, format)
+	if err != nil {
+		return fmt.Errorf("failed to convert kubeconfig %q: %w", input, err)
+	}
+
+	if len(output) == 0 || output == "-" {
+		_, err = os.Stdout.Write(converted)
+		return err
+	}
+
+	if err := kubeswitchio.WriteFile(output, converted, 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig to %q: %w", output, err)
+	}
+	return nil
+}
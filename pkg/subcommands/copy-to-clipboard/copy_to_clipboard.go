@@ -0,0 +1,80 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package copytoclipboard
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/atotto/clipboard"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	generatekubeconfig "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/generate-kubeconfig"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const (
+	FormatName       = "name"
+	FormatKubeconfig = "kubeconfig"
+)
+
+// CopyContext copies the name or the minified kubeconfig of the given context to the system clipboard.
+// Prints the content to STDOUT instead if no clipboard is available, e.g. on headless systems.
+func CopyContext(desiredContext, format string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	var content string
+	switch format {
+	case FormatName:
+		discoveredContext, err := pkg.FindContext(desiredContext, stores, config, stateDir, noIndex)
+		if err != nil {
+			return err
+		}
+		content = discoveredContext.Name
+	case FormatKubeconfig:
+		kubeconfig, err := generatekubeconfig.GetMinifiedKubeconfig(desiredContext, stores, config, stateDir, noIndex)
+		if err != nil {
+			return err
+		}
+		data, err := util.EncodeKubeconfig(kubeconfig, kubeconfigutil.FormatYAML)
+		if err != nil {
+			return fmt.Errorf("failed to serialize kubeconfig: %w", err)
+		}
+		content = string(data)
+	default:
+		return fmt.Errorf("unknown format %q. Valid formats are %q and %q", format, FormatName, FormatKubeconfig)
+	}
+
+	if clipboard.Unsupported {
+		return printToStdout(content)
+	}
+	if err := clipboard.WriteAll(content); err != nil {
+		return printToStdout(content)
+	}
+
+	if format == FormatKubeconfig {
+		fmt.Fprintf(os.Stderr, "Copied kubeconfig of context %q to the clipboard. It may contain credentials.\n", desiredContext)
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Copied context name %q to the clipboard.\n", content)
+	return nil
+}
+
+func printToStdout(content string) error {
+	fmt.Fprintln(os.Stderr, "clipboard unavailable, printing to stdout")
+	_, err := fmt.Fprintln(os.Stdout, content)
+	return err
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentialplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientauthenticationv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	gettoken "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/get-token"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// contextEnv is set while the credential plugin obtains a token.
+// Exec plugins started by the credential plugin inherit it, which detects the credential plugin invoking itself.
+const contextEnv = "KUBESWITCH_CREDENTIAL_PLUGIN_CONTEXT"
+
+// PrintExecCredential prints the token of the given context as client.authentication.k8s.io/v1 ExecCredential.
+// The token is obtained like for "switch get-token", i.e., the exec plugin of the user is invoked
+// and the token is cached in the credential cache until it expires.
+func PrintExecCredential(desiredContext string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool, credentialCache *credentials.CredentialCache) error {
+	if parentContext, ok := os.LookupEnv(contextEnv); ok {
+		return fmt.Errorf("the credential plugin for context %q invoked itself for context %q. The kubeconfig of the store must not use the credential plugin", parentContext, desiredContext)
+	}
+	if err := os.Setenv(contextEnv, desiredContext); err != nil {
+		return err
+	}
+
+	token, err := gettoken.GetToken(desiredContext, stores, config, stateDir, noIndex, credentialCache)
+	if err != nil {
+		return err
+	}
+
+	credential := clientauthenticationv1.ExecCredential{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clientauthenticationv1.SchemeGroupVersion.String(),
+			Kind:       "ExecCredential",
+		},
+		Status: &clientauthenticationv1.ExecCredentialStatus{
+			Token: token.Token,
+		},
+	}
+	if !token.Expiry.IsZero() {
+		credential.Status.ExpirationTimestamp = &metav1.Time{Time: token.Expiry}
+	}
+
+	data, err := json.Marshal(credential)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credentialplugin_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+	clientauthenticationv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	credentialplugin "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/credential-plugin"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const contextEnv = "KUBESWITCH_CREDENTIAL_PLUGIN_CONTEXT"
+
+// kubeconfig returns a kubeconfig with a "static" context using a static token and an "exec" context using the given exec plugin
+func kubeconfig(pluginPath string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: static
+  context:
+    cluster: dev
+    user: static
+- name: exec
+  context:
+    cluster: dev
+    user: exec
+users:
+- name: static
+  user:
+    token: static-token
+- name: exec
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1
+      command: %s
+      interactiveMode: Never
+`, pluginPath)
+}
+
+// jwt returns an unsigned JWT expiring at the given time
+func jwt(expiry time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, expiry.Unix())))
+	return header + "." + payload + ".signature"
+}
+
+// captureStdout returns what the given function prints to stdout
+func captureStdout(f func() error) (string, error) {
+	reader, writer, err := os.Pipe()
+	Expect(err).ToNot(HaveOccurred())
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	err = f()
+	os.Stdout = stdout
+	Expect(writer.Close()).To(Succeed())
+
+	output, readErr := io.ReadAll(reader)
+	Expect(readErr).ToNot(HaveOccurred())
+	return string(output), err
+}
+
+var _ = Describe("PrintExecCredential", func() {
+	var (
+		tempDir         string
+		expiry          time.Time
+		stores          []store.KubeconfigStore
+		credentialCache *credentials.CredentialCache
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-credential-plugin")
+		Expect(err).ToNot(HaveOccurred())
+		annotations.SetPath(filepath.Join(tempDir, "annotations.yaml"))
+
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		block, err := ssh.MarshalPrivateKey(privateKey, "")
+		Expect(err).ToNot(HaveOccurred())
+		sshKeyPath := filepath.Join(tempDir, "id_ed25519")
+		Expect(os.WriteFile(sshKeyPath, pem.EncodeToMemory(block), 0600)).To(Succeed())
+
+		credentialCache, err = credentials.NewCredentialCache(filepath.Join(tempDir, "credentials"), time.Hour, sshKeyPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		expiry = time.Now().Add(30 * time.Minute).Truncate(time.Second)
+		pluginPath := filepath.Join(tempDir, "plugin")
+		plugin := fmt.Sprintf(`#!/bin/sh
+echo '{"apiVersion": "client.authentication.k8s.io/v1", "kind": "ExecCredential", "status": {"token": "%s"}}'
+`, jwt(expiry))
+		Expect(os.WriteFile(pluginPath, []byte(plugin), 0700)).To(Succeed())
+
+		kubeconfigsDir := filepath.Join(tempDir, "kubeconfigs")
+		Expect(os.MkdirAll(filepath.Join(kubeconfigsDir, "team"), 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(kubeconfigsDir, "team", "config"), []byte(kubeconfig(pluginPath)), 0600)).To(Succeed())
+
+		filesystemStore, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{kubeconfigsDir},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		stores = []store.KubeconfigStore{filesystemStore}
+	})
+
+	AfterEach(func() {
+		Expect(os.Unsetenv(contextEnv)).To(Succeed())
+		annotations.SetPath(annotations.DefaultPath)
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	printExecCredential := func(contextName string) (*clientauthenticationv1.ExecCredential, error) {
+		output, err := captureStdout(func() error {
+			return credentialplugin.PrintExecCredential(contextName, stores, &types.Config{}, tempDir, true, credentialCache)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		credential := &clientauthenticationv1.ExecCredential{}
+		Expect(json.Unmarshal([]byte(output), credential)).To(Succeed())
+		return credential, nil
+	}
+
+	It("should print the static token as ExecCredential without an expiration timestamp", func() {
+		credential, err := printExecCredential("team/static")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credential.APIVersion).To(Equal("client.authentication.k8s.io/v1"))
+		Expect(credential.Kind).To(Equal("ExecCredential"))
+		Expect(credential.Status.Token).To(Equal("static-token"))
+		Expect(credential.Status.ExpirationTimestamp).To(BeNil())
+	})
+
+	It("should print the token of the exec plugin with its expiration timestamp", func() {
+		credential, err := printExecCredential("team/exec")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credential.Status.Token).To(Equal(jwt(expiry)))
+		Expect(credential.Status.ExpirationTimestamp).ToNot(BeNil())
+		Expect(credential.Status.ExpirationTimestamp.Time.Equal(expiry)).To(BeTrue())
+	})
+
+	It("should set the name of the context for the exec plugins it invokes", func() {
+		_, err := printExecCredential("team/static")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.Getenv(contextEnv)).To(Equal("team/static"))
+	})
+
+	It("should fail if it is invoked by itself", func() {
+		Expect(os.Setenv(contextEnv, "team/exec")).To(Succeed())
+
+		_, err := printExecCredential("team/static")
+		Expect(err).To(MatchError(ContainSubstring(`the credential plugin for context "team/exec" invoked itself for context "team/static"`)))
+	})
+})
@@ -0,0 +1,120 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+)
+
+// DefaultCaptureDuration is the default duration of capturing the API calls of a store
+const DefaultCaptureDuration = 30 * time.Second
+
+// apiCallCapture logs the redacted API calls to stderr until it is stopped
+type apiCallCapture struct {
+	mutex   sync.Mutex
+	stopped bool
+	calls   int
+}
+
+// capture is set by EnableAPICallLogs
+var capture *apiCallCapture
+
+// WrapTransport returns a transport logging the requests and responses of the given transport
+func (c *apiCallCapture) WrapTransport(next http.RoundTripper) http.RoundTripper {
+	return &loggingTransport{next: next, logf: c.Logf}
+}
+
+// Logf logs the redacted API call with a timestamp, unless the capture has been stopped
+func (c *apiCallCapture) Logf(format string, v ...interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.stopped {
+		return
+	}
+
+	message := fmt.Sprintf(format, v...)
+	if strings.HasPrefix(message, "--> ") || strings.HasPrefix(message, "Request\n") {
+		c.calls++
+	}
+	fmt.Fprintf(logOutput, "[%s] %s\n", time.Now().Format("15:04:05.000"), strings.TrimRight(string(redactAPICall([]byte(message))), "\r\n"))
+}
+
+// stop stops logging and returns the number of captured API calls
+func (c *apiCallCapture) stop() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.stopped = true
+	return c.calls
+}
+
+// EnableAPICallLogs enables the capture of the raw API calls of the stores.
+// Must be called before the stores are created.
+// Covers stores with HTTP, Vault, Rancher, WebDAV, client-go (Gardener, CAPI) and AWS SDK (EKS) clients,
+// as well as all API clients using the default HTTP transport.
+func EnableAPICallLogs() {
+	capture = &apiCallCapture{}
+	store.SetAPICallLogger(capture)
+	http.DefaultTransport = capture.WrapTransport(http.DefaultTransport)
+}
+
+// CaptureAPICalls searches the store with the given ID and fetches the kubeconfig of the first search result,
+// while the API calls of the store are streamed to stderr.
+// The capture stops once finished or after the given duration.
+func CaptureAPICalls(storeID string, duration time.Duration, stores []store.KubeconfigStore) error {
+	if capture == nil {
+		return fmt.Errorf("the capture of the API calls has not been enabled")
+	}
+
+	var kubeconfigStore store.KubeconfigStore
+	ids := make([]string, 0, len(stores))
+	for _, s := range stores {
+		if s.GetID() == storeID {
+			kubeconfigStore = s
+			break
+		}
+		ids = append(ids, s.GetID())
+	}
+	if kubeconfigStore == nil {
+		return &storeerrors.ErrStoreNotFound{StoreID: storeID, Err: fmt.Errorf("configured stores: %s", strings.Join(ids, ", "))}
+	}
+
+	fmt.Fprintf(os.Stderr, "Capturing the API calls of store %s for up to %s\n", kubeconfigStore.GetID(), duration)
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	start := time.Now()
+	results, searchErr := search(ctx, kubeconfigStore, duration)
+	if len(results) > 0 && ctx.Err() == nil {
+		if _, err := kubeconfigStore.GetKubeconfigForPath(ctx, results[0].KubeconfigPath, results[0].Tags); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to get the kubeconfig for path %q: %v\n", results[0].KubeconfigPath, err)
+		}
+	}
+	calls := capture.stop()
+
+	fmt.Fprintf(os.Stderr, "Captured %d API calls in %s, the search returned %d results\n", calls, time.Since(start).Round(time.Millisecond), len(results))
+	return searchErr
+}
@@ -0,0 +1,244 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+)
+
+// SearchTimeout is the maximum duration of the search of the debugged store
+const SearchTimeout = 10 * time.Second
+
+// DebugStore runs diagnostics against the store with the given ID and prints the result of each step.
+// Returns an error if any step failed.
+func DebugStore(storeID string, stores []store.KubeconfigStore) error {
+	var kubeconfigStore store.KubeconfigStore
+	for _, s := range stores {
+		if s.GetID() == storeID {
+			kubeconfigStore = s
+			break
+		}
+	}
+	if kubeconfigStore == nil {
+		ids := make([]string, 0, len(stores))
+		for _, s := range stores {
+			ids = append(ids, s.GetID())
+		}
+		return &storeerrors.ErrStoreNotFound{StoreID: storeID, Err: fmt.Errorf("configured stores: %s", strings.Join(ids, ", "))}
+	}
+
+	fmt.Printf("Store %s (kind %s)\n\n", kubeconfigStore.GetID(), kubeconfigStore.GetKind())
+
+	storeConfig, err := redactedStoreConfig(kubeconfigStore)
+	if err != nil {
+		return fmt.Errorf("failed to print the store configuration: %w", err)
+	}
+	fmt.Printf("Configuration:\n%s\n", storeConfig)
+
+	ctx := context.Background()
+	failed := 0
+
+	if !runStep("VerifyKubeconfigPaths", func() (string, error) {
+		return "", kubeconfigStore.VerifyKubeconfigPaths(ctx)
+	}) {
+		failed++
+	}
+
+	if !runStep("Probe", func() (string, error) {
+		return "", kubeconfigStore.Probe(ctx)
+	}) {
+		failed++
+	}
+
+	var results []store.SearchResult
+	if !runStep("StartSearch", func() (string, error) {
+		var err error
+		results, err = search(ctx, kubeconfigStore, SearchTimeout)
+		return fmt.Sprintf("%d results", len(results)), err
+	}) {
+		failed++
+	}
+
+	if len(results) == 0 {
+		fmt.Println("[SKIP] GetKubeconfigForPath: no search results")
+	} else if !runStep("GetKubeconfigForPath", func() (string, error) {
+		return validateKubeconfig(ctx, kubeconfigStore, results[0])
+	}) {
+		failed++
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of the checks for store %q failed", failed, storeID)
+	}
+	return nil
+}
+
+// runStep runs the given diagnostic step and prints PASS or FAIL together with the elapsed time
+// returns false if the step failed
+func runStep(name string, step func() (string, error)) bool {
+	start := time.Now()
+	details, err := step()
+	elapsed := time.Since(start).Round(time.Microsecond)
+
+	if len(details) > 0 {
+		name = fmt.Sprintf("%s: %s", name, details)
+	}
+
+	if err != nil {
+		fmt.Printf("[FAIL] %s (%s)\n       %v\n", name, elapsed, err)
+		return false
+	}
+	fmt.Printf("[PASS] %s (%s)\n", name, elapsed)
+	return true
+}
+
+// search runs the search of the store until it is finished or the timeout is exceeded
+// returns the first error returned during the search
+func search(ctx context.Context, kubeconfigStore store.KubeconfigStore, timeout time.Duration) ([]store.SearchResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	channel := make(chan store.SearchResult)
+	go func() {
+		defer close(channel)
+		kubeconfigStore.StartSearch(ctx, channel)
+	}()
+
+	var (
+		results  []store.SearchResult
+		firstErr error
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			// keep reading, so that the search of the store does not block
+			go func() {
+				for range channel {
+				}
+			}()
+			return results, &storeerrors.ErrStoreTimeout{StoreID: kubeconfigStore.GetID(), Err: fmt.Errorf("search did not finish within %s", timeout)}
+		case result, ok := <-channel:
+			if !ok {
+				return results, firstErr
+			}
+			if result.Error != nil {
+				if firstErr == nil {
+					firstErr = result.Error
+				}
+				continue
+			}
+			results = append(results, result)
+		}
+	}
+}
+
+// validateKubeconfig fetches the kubeconfig of the given search result and validates it
+func validateKubeconfig(ctx context.Context, kubeconfigStore store.KubeconfigStore, result store.SearchResult) (string, error) {
+	kubeconfigData, err := kubeconfigStore.GetKubeconfigForPath(ctx, result.KubeconfigPath, result.Tags)
+	if err != nil {
+		return result.KubeconfigPath, err
+	}
+
+	kubeconfig, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return result.KubeconfigPath, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	if err := clientcmd.Validate(*kubeconfig); err != nil {
+		return result.KubeconfigPath, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+
+	if len(kubeconfig.Contexts) == 0 {
+		return result.KubeconfigPath, errors.New("kubeconfig does not contain any contexts")
+	}
+	return fmt.Sprintf("%s (%d contexts)", result.KubeconfigPath, len(kubeconfig.Contexts)), nil
+}
+
+// redactedStoreConfig returns the YAML representation of the store configuration with the values of secret fields redacted
+func redactedStoreConfig(kubeconfigStore store.KubeconfigStore) (string, error) {
+	data, err := yaml.Marshal(kubeconfigStore.GetStoreConfig())
+	if err != nil {
+		return "", err
+	}
+
+	var storeConfig map[string]interface{}
+	if err := yaml.Unmarshal(data, &storeConfig); err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(redact(storeConfig)); err != nil {
+		return "", err
+	}
+	return "  " + strings.ReplaceAll(strings.TrimSuffix(buffer.String(), "\n"), "\n", "\n  ") + "\n", nil
+}
+
+// redact replaces the values of secret fields of the given YAML value
+// unset fields are removed
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, fieldValue := range v {
+			if isEmpty(fieldValue) {
+				delete(v, key)
+				continue
+			}
+			if switchconfig.IsSecretKey(key) {
+				v[key] = switchconfig.RedactedValue
+				continue
+			}
+			if values, ok := fieldValue.(map[string]interface{}); ok && switchconfig.IsSecretMapKey(key) {
+				for name := range values {
+					values[name] = switchconfig.RedactedValue
+				}
+				continue
+			}
+			v[key] = redact(fieldValue)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return value
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// configStore is a store with the given configuration
+type configStore struct {
+	config types.KubeconfigStore
+}
+
+func (s *configStore) GetID() string                                        { return "http.default" }
+func (s *configStore) GetKind() types.StoreKind                             { return types.StoreKindHTTP }
+func (s *configStore) GetContextPrefix(string) string                       { return "" }
+func (s *configStore) VerifyKubeconfigPaths(context.Context) error          { return nil }
+func (s *configStore) Probe(context.Context) error                          { return nil }
+func (s *configStore) StartSearch(context.Context, chan store.SearchResult) {}
+func (s *configStore) GetLogger() *logrus.Entry                             { return testutil.NewTestLogger() }
+func (s *configStore) GetStoreConfig() types.KubeconfigStore                { return s.config }
+func (s *configStore) Stop(context.Context) error                           { return nil }
+func (s *configStore) GetKubeconfigForPath(context.Context, string, map[string]string) ([]byte, error) {
+	return nil, nil
+}
+
+var _ = Describe("redactedStoreConfig", func() {
+	It("should redact the secret fields and the values of the headers", func() {
+		storeConfig, err := redactedStoreConfig(&configStore{config: types.KubeconfigStore{
+			Kind: types.StoreKindHTTP,
+			Config: types.StoreConfigHTTP{
+				Endpoints: []types.HTTPEndpoint{{
+					URL:             "https://kubeconfigs.example.com",
+					BearerTokenFile: "/var/run/secrets/token",
+					Headers:         map[string]string{"Authorization": "Bearer abcdef", "X-Team": "platform"},
+				}},
+				Headers: map[string]string{"X-Api-Key": "key"},
+			},
+		}})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(storeConfig).To(ContainSubstring("url: https://kubeconfigs.example.com\n"))
+		Expect(storeConfig).To(ContainSubstring("bearerTokenFile: /var/run/secrets/token\n"))
+		Expect(storeConfig).To(ContainSubstring("Authorization: <REDACTED>\n"))
+		Expect(storeConfig).To(ContainSubstring("X-Team: <REDACTED>\n"))
+		Expect(storeConfig).To(ContainSubstring("X-Api-Key: <REDACTED>\n"))
+		Expect(storeConfig).ToNot(ContainSubstring("abcdef"))
+		Expect(storeConfig).ToNot(ContainSubstring("platform"))
+	})
+})
@@ -0,0 +1,119 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/klog/v2"
+
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+)
+
+// clientGoVerbosity is the klog verbosity at which client-go logs the HTTP requests and responses
+const clientGoVerbosity = "9"
+
+var (
+	// authorizationHeader matches the headers containing credentials in the dumped requests and responses.
+	// The value excludes the carriage return, so the header lines keep their CRLF line ending.
+	authorizationHeader = regexp.MustCompile(`(?im)^((?:proxy-)?authorization|x-vault-token|x-auth-token|x-amz-security-token|x-ovh-consumer|x-ovh-signature|cookie|set-cookie): [^\r\n]*`)
+	// secretJSONField matches JSON string fields containing credentials, certificate data or kubeconfigs, e.g. "client_token": "..."
+	secretJSONField = regexp.MustCompile(`(?i)("(?:[^"]*(?:token|password|secret|apikey|api_key|privatekey|private_key|accesskey|access_key|certificate|credentials|kubeconfig|data)[^"]*|config)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// secretJSONObject matches the data objects of Kubernetes secrets and Vault secrets containing the kubeconfigs
+	secretJSONObject = regexp.MustCompile(`("data"\s*:\s*)\{[^{}]*\}`)
+	// secretYAMLField matches YAML fields containing credentials or certificate data, e.g. in kubeconfigs
+	secretYAMLField = regexp.MustCompile(`(?im)^(\s*(?:- )?[\w-]*(?:token|password|secret|key-data|certificate-data|certificate-authority-data)[\w-]*:[ \t]*)\S.*$`)
+	// secretFormField matches form-encoded fields containing credentials, e.g. in OAuth2 token requests
+	secretFormField = regexp.MustCompile(`(?i)((?:^|&)[\w.-]*(?:token|password|secret)[\w.-]*=)[^&\s]*`)
+)
+
+// loggingTransport logs the raw HTTP requests and responses
+type loggingTransport struct {
+	next http.RoundTripper
+	// logf logs the redacted request or response
+	logf func(format string, v ...interface{})
+}
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if dump, err := httputil.DumpRequestOut(req, true); err == nil {
+		t.logf("--> %s\n", redactAPICall(dump))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		t.logf("<-- %s %s: %v\n", req.Method, req.URL, err)
+		return nil, err
+	}
+
+	if dump, err := httputil.DumpResponse(resp, true); err == nil {
+		t.logf("<-- %s\n", redactAPICall(dump))
+	}
+	return resp, nil
+}
+
+// redactAPICall redacts the credentials and certificate data in the headers and body of a dumped request or response
+func redactAPICall(dump []byte) []byte {
+	dump = authorizationHeader.ReplaceAll(dump, []byte("$1: "+switchconfig.RedactedValue))
+	dump = secretJSONObject.ReplaceAll(dump, []byte(`$1"`+switchconfig.RedactedValue+`"`))
+	dump = secretJSONField.ReplaceAll(dump, []byte(`$1"`+switchconfig.RedactedValue+`"`))
+	dump = secretYAMLField.ReplaceAll(dump, []byte("$1"+switchconfig.RedactedValue))
+	return secretFormField.ReplaceAll(dump, []byte("$1"+switchconfig.RedactedValue))
+}
+
+// logOutput is the output of the logged API calls
+var logOutput io.Writer = os.Stderr
+
+func logToStderr(format string, v ...interface{}) {
+	fmt.Fprintf(logOutput, format, v...)
+}
+
+// verboseLogger logs the redacted API calls of the stores to stderr
+type verboseLogger struct{}
+
+// WrapTransport returns a transport logging the requests and responses of the given transport
+func (verboseLogger) WrapTransport(next http.RoundTripper) http.RoundTripper {
+	return &loggingTransport{next: next, logf: logToStderr}
+}
+
+// Logf logs the redacted request or response dumped by the AWS SDK
+func (verboseLogger) Logf(format string, v ...interface{}) {
+	logToStderr("%s\n", redactAPICall([]byte(fmt.Sprintf(format, v...))))
+}
+
+// EnableHTTPDebugLogs enables the logs of the raw HTTP requests and responses of the API clients of the stores.
+// Must be called before the stores are created.
+// The transports of the API clients instrumented by the store package are wrapped,
+// the default HTTP transport shared with all other HTTP clients of the process is not modified.
+// Covers the HTTP, Vault, Rancher, WebDAV, client-go (Gardener, CAPI) and AWS SDK (EKS) clients.
+// Other API clients only log at trace level, if supported.
+func EnableHTTPDebugLogs() {
+	logrus.SetLevel(logrus.TraceLevel)
+
+	store.SetAPICallLogger(verboseLogger{})
+
+	flags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	if err := flags.Set("v", clientGoVerbosity); err != nil {
+		logrus.Warnf("failed to set the client-go log verbosity: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package debug
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+)
+
+var _ = Describe("EnableHTTPDebugLogs", func() {
+	var (
+		server *httptest.Server
+		output *bytes.Buffer
+		level  logrus.Level
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"client_token": "s.secret"}`))
+		}))
+		output = &bytes.Buffer{}
+		logOutput = output
+		level = logrus.GetLevel()
+	})
+
+	AfterEach(func() {
+		server.Close()
+		logOutput = os.Stderr
+		logrus.SetLevel(level)
+		store.SetAPICallLogger(nil)
+	})
+
+	It("should not modify the default HTTP transport", func() {
+		defaultTransport := http.DefaultTransport
+
+		EnableHTTPDebugLogs()
+		Expect(http.DefaultTransport).To(BeIdenticalTo(defaultTransport))
+	})
+
+	It("should log the redacted API calls of the HTTP clients of the stores", func() {
+		EnableHTTPDebugLogs()
+
+		request, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		request.Header.Set("Authorization", "Bearer my-token")
+
+		response, err := store.NewHTTPClient(time.Second).Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Body.Close()).To(Succeed())
+
+		Expect(output.String()).To(ContainSubstring("--> GET / HTTP/1.1"))
+		Expect(output.String()).To(ContainSubstring("Authorization: <REDACTED>"))
+		Expect(output.String()).To(ContainSubstring(`<-- HTTP/1.1 200 OK`))
+		Expect(output.String()).To(ContainSubstring(`"client_token": "<REDACTED>"`))
+		Expect(output.String()).ToNot(ContainSubstring("my-token"))
+		Expect(output.String()).ToNot(ContainSubstring("s.secret"))
+	})
+
+	It("should not log the API calls of other HTTP clients", func() {
+		EnableHTTPDebugLogs()
+
+		response, err := http.Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Body.Close()).To(Succeed())
+
+		Expect(output.String()).To(BeEmpty())
+	})
+
+	It("should redact the API calls logged by the AWS SDK", func() {
+		verboseLogger{}.Logf("Request\n%s", "X-Amz-Security-Token: session")
+
+		Expect(output.String()).To(Equal("Request\nX-Amz-Security-Token: <REDACTED>\n"))
+	})
+})
+
+var _ = Describe("redactAPICall", func() {
+	DescribeTable("should redact the credentials",
+		func(dump, expected string) {
+			Expect(string(redactAPICall([]byte(dump)))).To(Equal(expected))
+		},
+		// authorizationHeader
+		Entry("authorization header", "GET / HTTP/1.1\r\nAuthorization: Bearer my-token\r\nAccept: */*", "GET / HTTP/1.1\r\nAuthorization: <REDACTED>\r\nAccept: */*"),
+		Entry("proxy authorization header", "Proxy-Authorization: Basic dXNlcjpwYXNz", "Proxy-Authorization: <REDACTED>"),
+		Entry("lower case vault token header", "x-vault-token: s.token", "x-vault-token: <REDACTED>"),
+		Entry("AWS session token header", "X-Amz-Security-Token: session", "X-Amz-Security-Token: <REDACTED>"),
+		Entry("OVH signature header", "X-Ovh-Signature: $1$abc", "X-Ovh-Signature: <REDACTED>"),
+		Entry("cookie headers", "Cookie: session=abc\nSet-Cookie: session=def; HttpOnly", "Cookie: <REDACTED>\nSet-Cookie: <REDACTED>"),
+		Entry("other headers", "Content-Type: application/json\nX-Request-Id: token", "Content-Type: application/json\nX-Request-Id: token"),
+
+		// secretJSONField
+		Entry("JSON token field", `{"client_token": "s.token", "lease_duration": 0}`, `{"client_token": "<REDACTED>", "lease_duration": 0}`),
+		Entry("JSON field with escaped quotes", `{"password":"p\"w"}`, `{"password":"<REDACTED>"}`),
+		Entry("JSON fields in any case", `{"SecretAccessKey": "secret", "AccessKeyId": "id", "Region": "eu-west-1"}`, `{"SecretAccessKey": "<REDACTED>", "AccessKeyId": "<REDACTED>", "Region": "eu-west-1"}`),
+		Entry("JSON kubeconfig fields", `{"kubeconfig": "apiVersion: v1", "config": "apiVersion: v1"}`, `{"kubeconfig": "<REDACTED>", "config": "<REDACTED>"}`),
+		Entry("JSON certificate fields", `{"masterAuth": {"clusterCaCertificate": "LS0t"}}`, `{"masterAuth": {"clusterCaCertificate": "<REDACTED>"}}`),
+		Entry("other JSON fields", `{"name": "dev", "token_type": 1}`, `{"name": "dev", "token_type": 1}`),
+
+		// secretJSONObject
+		Entry("JSON data objects", `{"data": {"config": "abc", "other": "def"}, "kind": "Secret"}`, `{"data": "<REDACTED>", "kind": "Secret"}`),
+		Entry("other JSON objects", `{"metadata": {"name": "dev"}}`, `{"metadata": {"name": "dev"}}`),
+
+		// secretYAMLField
+		Entry("YAML token field", "users:\n- name: admin\n  user:\n    token: my-token\n", "users:\n- name: admin\n  user:\n    token: <REDACTED>\n"),
+		Entry("YAML key and certificate data", "    client-certificate-data: LS0t\n    client-key-data: LS0t", "    client-certificate-data: <REDACTED>\n    client-key-data: <REDACTED>"),
+		Entry("YAML certificate authority data", "    certificate-authority-data: LS0t", "    certificate-authority-data: <REDACTED>"),
+		Entry("YAML list item", "- password: secret", "- password: <REDACTED>"),
+		Entry("empty YAML fields", "    token:\n    name: dev", "    token:\n    name: dev"),
+
+		// secretFormField
+		Entry("form fields", "grant_type=refresh_token&refresh_token=abc&client_id=kubeswitch", "grant_type=refresh_token&refresh_token=<REDACTED>&client_id=kubeswitch"),
+		Entry("first form field", "client_secret=abc&scope=openid", "client_secret=<REDACTED>&scope=openid"),
+		Entry("password form field", "username=admin&password=abc", "username=admin&password=<REDACTED>"),
+	)
+
+	It("should redact every secret of a dumped request", func() {
+		dump := "POST /v1/auth/token HTTP/1.1\r\n" +
+			"Host: vault.example.com\r\n" +
+			"Authorization: Bearer my-token\r\n" +
+			"Content-Type: application/json\r\n" +
+			"\r\n" +
+			`{"role": "dev", "jwt_token": "eyJ", "data": {"kubeconfig": "apiVersion: v1"}}`
+
+		redacted := string(redactAPICall([]byte(dump)))
+		Expect(redacted).To(ContainSubstring("Host: vault.example.com"))
+		Expect(redacted).To(ContainSubstring(`"role": "dev"`))
+		Expect(redacted).ToNot(ContainSubstring("my-token"))
+		Expect(redacted).ToNot(ContainSubstring("eyJ"))
+		Expect(redacted).ToNot(ContainSubstring("apiVersion"))
+	})
+})
@@ -22,6 +22,7 @@ import (
 	"github.com/danielfoehrkn/kubeswitch/pkg/store"
 	gardenerstore "github.com/danielfoehrkn/kubeswitch/pkg/store/gardener"
 	historyutil "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/history/util"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
 	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
 	"github.com/danielfoehrkn/kubeswitch/types"
 	"github.com/sirupsen/logrus"
@@ -72,7 +73,10 @@ func SwitchToControlplane(stores []store.KubeconfigStore, kubeconfigPathFromFlag
 	}
 
 	foundCorrectStore := false
-	var targetStore *store.GardenerStore
+	var (
+		targetStore     *store.GardenerStore
+		targetLandscape *store.GardenerLandscape
+	)
 	for _, kubeconfigStore := range stores {
 		if kubeconfigStore.GetKind() == types.StoreKindGardener {
 			gardenerStore, ok := kubeconfigStore.(*store.GardenerStore)
@@ -80,18 +84,19 @@ func SwitchToControlplane(stores []store.KubeconfigStore, kubeconfigPathFromFlag
 				return nil, fmt.Errorf("internal error")
 			}
 
-			if !gardenerStore.IsInitialized() {
-				if err := gardenerStore.InitializeGardenerStore(); err != nil {
-					if gardenerStore.GetStoreConfig().Required != nil && !*gardenerStore.GetStoreConfig().Required {
-						continue
-					}
-					return nil, fmt.Errorf("failed to initialize Gardener store with ID %q: %v", kubeconfigStore.GetID(), err)
+			// only the landscapes required to find the landscape are initialized
+			landscape, err := gardenerStore.FindLandscape(landscapeIdentity)
+			if err != nil {
+				if gardenerStore.GetStoreConfig().Required != nil && !*gardenerStore.GetStoreConfig().Required {
+					continue
 				}
+				return nil, fmt.Errorf("failed to initialize Gardener store with ID %q: %v", kubeconfigStore.GetID(), err)
 			}
 
-			if landscapeIdentity == gardenerStore.LandscapeIdentity {
+			if landscape != nil {
 				foundCorrectStore = true
 				targetStore = gardenerStore
+				targetLandscape = landscape
 				break
 			}
 		}
@@ -101,7 +106,7 @@ func SwitchToControlplane(stores []store.KubeconfigStore, kubeconfigPathFromFlag
 		return nil, fmt.Errorf("unable to find Seed for Shoot. Landscape identity %q not found", landscapeIdentity)
 	}
 
-	kubeconfigBytes, seedName, err := targetStore.GetControlplaneKubeconfigForShoot(clusterName, project)
+	kubeconfigBytes, seedName, err := targetStore.GetControlplaneKubeconfigForShoot(targetLandscape, clusterName, project)
 	if err != nil {
 		return nil, fmt.Errorf("gardener store returned an error obtaining the kubeconfig for the Shoot's Seed cluster: %v", err)
 	}
@@ -123,7 +128,7 @@ func SwitchToControlplane(stores []store.KubeconfigStore, kubeconfigPathFromFlag
 		return nil, fmt.Errorf("failed to get namespace of current context: %v", err)
 	}
 
-	seedPath := gardenerstore.GetSeedIdentifier(targetStore.LandscapeName, *seedName)
+	seedPath := gardenerstore.GetSeedIdentifier(targetLandscape.Prefix(), *seedName)
 	context := targetStore.GetContextPrefix(seedPath)
 	context = fmt.Sprintf("%s/%s", context, kubeconfig.GetCurrentContext())
 
@@ -146,7 +151,7 @@ func getKubeconfig(kubeconfigPathFromFlag string) (*kubeconfigutil.Kubeconfig, e
 			return nil, fmt.Errorf("providing multiple kubeconfig files via environment variable KUBECONFIG is not supported")
 		}
 
-		kubeconfigPath = os.ExpandEnv(kubeconfigPathFromEnv)
+		kubeconfigPath = util.ResolveKubeconfigPath(kubeconfigPathFromEnv)
 	}
 
 	if _, err := os.Stat(kubeconfigPath); err != nil {
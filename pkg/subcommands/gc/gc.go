@@ -0,0 +1,297 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/checkpoint"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/connect"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/monitor"
+)
+
+const (
+	// indexFilePrefix and the suffixes are the naming scheme of the index files of the stores
+	// in the state directory: "switch.<store ID>.index" and "switch.<store ID>.index.state"
+	indexFilePrefix      = "switch."
+	indexFileSuffix      = ".index"
+	indexStateFileSuffix = ".index.state"
+)
+
+// GarbageCollector removes stale files from the state directory
+type GarbageCollector struct {
+	// StateDir is the state directory to clean up
+	StateDir string
+	// StoreIDs are the IDs of the configured stores. The index files of all other stores are removed.
+	StoreIDs sets.Set[string]
+	// CredentialCacheDirectory is the directory of the credential cache
+	CredentialCacheDirectory string
+	// CredentialMaxAge is the maximum duration a credential is cached. Older credential files are removed.
+	CredentialMaxAge time.Duration
+	// MaxBackups is the number of checkpoints to keep. The oldest checkpoints beyond this number are removed.
+	// If nil, all checkpoints are kept.
+	MaxBackups *int
+}
+
+// StaleFile is a file found by the garbage collector
+type StaleFile struct {
+	Path   string
+	Reason string
+	Size   int64
+}
+
+// Run removes all stale files and prints a summary of the reclaimed bytes.
+// With --dry-run, the files that would be removed are listed by the dry-run summary.
+func (c *GarbageCollector) Run() error {
+	staleFiles, err := c.Find(time.Now())
+	if err != nil {
+		return err
+	}
+
+	if len(staleFiles) == 0 {
+		fmt.Printf("No stale files found in %s\n", c.StateDir)
+		return nil
+	}
+
+	var (
+		reclaimed int64
+		removed   int
+	)
+	for _, staleFile := range staleFiles {
+		if err := kubeswitchio.Remove(staleFile.Path); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to remove %s: %w", staleFile.Path, err)
+		}
+		// the dry-run summary already lists the files
+		if !kubeswitchio.IsDryRun() {
+			fmt.Printf("  - %s (%s)\n", staleFile.Path, staleFile.Reason)
+		}
+		reclaimed += staleFile.Size
+		removed++
+	}
+
+	verb := "Reclaimed"
+	if kubeswitchio.IsDryRun() {
+		verb = "Would reclaim"
+	}
+	fmt.Printf("%s %s by removing %d stale file(s)\n", verb, humanize.IBytes(uint64(reclaimed)), removed)
+	return nil
+}
+
+// Find returns the stale files of the state directory at the given time
+func (c *GarbageCollector) Find(now time.Time) ([]StaleFile, error) {
+	var staleFiles []StaleFile
+	for _, find := range []func(time.Time) ([]StaleFile, error){
+		c.findStoppedProcesses,
+		c.findExpiredCredentials,
+		c.findOrphanedIndexFiles,
+		c.findExcessBackups,
+	} {
+		found, err := find(now)
+		if err != nil {
+			return nil, err
+		}
+		staleFiles = append(staleFiles, found...)
+	}
+	return staleFiles, nil
+}
+
+// findStoppedProcesses returns the files of proxies and monitors whose process is no longer running.
+// On Windows, the files are kept as it cannot be checked whether the process is running.
+func (c *GarbageCollector) findStoppedProcesses(time.Time) ([]StaleFile, error) {
+	if runtime.GOOS == "windows" {
+		return nil, nil
+	}
+
+	files, err := readDir(filepath.Join(c.StateDir, connect.ProxiesDirectory))
+	if err != nil {
+		return nil, err
+	}
+	if monitorFile, err := stat(filepath.Join(c.StateDir, monitor.RegistrationFile)); err != nil {
+		return nil, err
+	} else if monitorFile != nil {
+		files = append(files, *monitorFile)
+	}
+
+	var staleFiles []StaleFile
+	for _, f := range files {
+		if filepath.Ext(f.path) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(f.path)
+		if err != nil {
+			return nil, err
+		}
+
+		process := struct {
+			PID int `json:"pid"`
+		}{}
+		if err := json.Unmarshal(data, &process); err != nil || process.PID <= 0 {
+			// not a PID file
+			continue
+		}
+
+		if !connect.IsRunning(process.PID) {
+			staleFiles = append(staleFiles, f.stale(fmt.Sprintf("process %d is not running", process.PID)))
+		}
+	}
+	return staleFiles, nil
+}
+
+// findExpiredCredentials returns the cached credentials older than the maximum age of the credential cache
+func (c *GarbageCollector) findExpiredCredentials(now time.Time) ([]StaleFile, error) {
+	if len(c.CredentialCacheDirectory) == 0 {
+		return nil, nil
+	}
+
+	maxAge := c.CredentialMaxAge
+	if maxAge <= 0 {
+		maxAge = credentials.DefaultMaxAge
+	}
+
+	files, err := readDir(c.CredentialCacheDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	var staleFiles []StaleFile
+	for _, f := range files {
+		if strings.HasSuffix(f.path, credentials.FileSuffix) && now.Sub(f.modTime) > maxAge {
+			staleFiles = append(staleFiles, f.stale(fmt.Sprintf("credential cached for more than %s", maxAge)))
+		}
+	}
+	return staleFiles, nil
+}
+
+// findOrphanedIndexFiles returns the index files of stores that are no longer configured
+func (c *GarbageCollector) findOrphanedIndexFiles(time.Time) ([]StaleFile, error) {
+	files, err := readDir(c.StateDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var staleFiles []StaleFile
+	for _, f := range files {
+		name := filepath.Base(f.path)
+		if !strings.HasPrefix(name, indexFilePrefix) {
+			continue
+		}
+
+		var storeID string
+		switch {
+		case strings.HasSuffix(name, indexStateFileSuffix):
+			storeID = strings.TrimSuffix(strings.TrimPrefix(name, indexFilePrefix), indexStateFileSuffix)
+		case strings.HasSuffix(name, indexFileSuffix):
+			storeID = strings.TrimSuffix(strings.TrimPrefix(name, indexFilePrefix), indexFileSuffix)
+		default:
+			continue
+		}
+
+		if !c.StoreIDs.Has(storeID) {
+			staleFiles = append(staleFiles, f.stale(fmt.Sprintf("store %q is not configured", storeID)))
+		}
+	}
+	return staleFiles, nil
+}
+
+// findExcessBackups returns the oldest checkpoints beyond the maximum number of backups
+func (c *GarbageCollector) findExcessBackups(time.Time) ([]StaleFile, error) {
+	if c.MaxBackups == nil {
+		return nil, nil
+	}
+
+	files, err := readDir(filepath.Join(c.StateDir, checkpoint.Directory))
+	if err != nil {
+		return nil, err
+	}
+
+	// newest first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	var staleFiles []StaleFile
+	for i, f := range files {
+		if i >= *c.MaxBackups {
+			staleFiles = append(staleFiles, f.stale(fmt.Sprintf("more than %d backups", *c.MaxBackups)))
+		}
+	}
+	return staleFiles, nil
+}
+
+// file is a regular file in the state directory
+type file struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (f file) stale(reason string) StaleFile {
+	return StaleFile{Path: f.path, Reason: reason, Size: f.size}
+}
+
+// readDir returns the regular files in the directory. Returns no files if the directory does not exist.
+func readDir(directory string) ([]file, error) {
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []file
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file{path: filepath.Join(directory, entry.Name()), size: info.Size(), modTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+// stat returns the regular file at the path or nil if it does not exist
+func stat(path string) (*file, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+	return &file{path: path, size: info.Size(), modTime: info.ModTime()}, nil
+}
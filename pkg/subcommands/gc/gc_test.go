@@ -0,0 +1,203 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gc_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/checkpoint"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/connect"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/gc"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/monitor"
+)
+
+// captureStdout returns everything written to the stdout by the given function
+func captureStdout(f func() error) (string, error) {
+	reader, writer, err := os.Pipe()
+	Expect(err).ToNot(HaveOccurred())
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+
+	err = f()
+	Expect(writer.Close()).To(Succeed())
+	return <-output, err
+}
+
+// stoppedPID returns the PID of a process that is no longer running
+func stoppedPID() int {
+	command := exec.Command("true")
+	Expect(command.Run()).To(Succeed())
+	return command.Process.Pid
+}
+
+var _ = Describe("GarbageCollector", func() {
+	var (
+		stateDir  string
+		collector *gc.GarbageCollector
+		now       time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		stateDir, err = os.MkdirTemp("", "kubeswitch-gc")
+		Expect(err).ToNot(HaveOccurred())
+
+		now = time.Now()
+		collector = &gc.GarbageCollector{
+			StateDir:                 stateDir,
+			StoreIDs:                 sets.New[string]("vault.default"),
+			CredentialCacheDirectory: filepath.Join(stateDir, "credentials"),
+			CredentialMaxAge:         time.Hour,
+		}
+	})
+
+	AfterEach(func() {
+		kubeswitchio.SetWriter(kubeswitchio.FileWriter{})
+		Expect(os.RemoveAll(stateDir)).To(Succeed())
+	})
+
+	// writeFile writes the file relative to the state directory, last modified at the given time
+	writeFile := func(name, content string, modTime time.Time) string {
+		path := filepath.Join(stateDir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		Expect(os.Chtimes(path, modTime, modTime)).To(Succeed())
+		return path
+	}
+
+	paths := func(staleFiles []gc.StaleFile) []string {
+		var result []string
+		for _, staleFile := range staleFiles {
+			result = append(result, staleFile.Path)
+		}
+		return result
+	}
+
+	It("should find the files of proxies and monitors whose process is no longer running", func() {
+		pid := stoppedPID()
+		stopped := writeFile(filepath.Join(connect.ProxiesDirectory, "dev.json"), fmt.Sprintf(`{"pid": %d}`, pid), now)
+		writeFile(filepath.Join(connect.ProxiesDirectory, "prod.json"), fmt.Sprintf(`{"pid": %d}`, os.Getpid()), now)
+		writeFile(filepath.Join(connect.ProxiesDirectory, "other.json"), `{"name": "no pid"}`, now)
+		writeFile(filepath.Join(connect.ProxiesDirectory, "dev.log"), "", now)
+		monitorFile := writeFile(monitor.RegistrationFile, fmt.Sprintf(`{"pid": %d}`, pid), now)
+
+		staleFiles, err := collector.Find(now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(staleFiles).To(ConsistOf(
+			gc.StaleFile{Path: stopped, Reason: fmt.Sprintf("process %d is not running", pid), Size: int64(len(fmt.Sprintf(`{"pid": %d}`, pid)))},
+			gc.StaleFile{Path: monitorFile, Reason: fmt.Sprintf("process %d is not running", pid), Size: int64(len(fmt.Sprintf(`{"pid": %d}`, pid)))},
+		))
+	})
+
+	It("should find the credentials cached for more than the maximum age", func() {
+		expired := writeFile(filepath.Join("credentials", "vault.default.token"+credentials.FileSuffix), "expired", now.Add(-2*time.Hour))
+		writeFile(filepath.Join("credentials", "vault.default.other"+credentials.FileSuffix), "valid", now.Add(-30*time.Minute))
+		writeFile(filepath.Join("credentials", "unrelated"), "unrelated", now.Add(-2*time.Hour))
+
+		staleFiles, err := collector.Find(now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths(staleFiles)).To(ConsistOf(expired))
+	})
+
+	It("should find the index files of stores that are no longer configured", func() {
+		index := writeFile("switch.vault.old.index", "", now)
+		state := writeFile("switch.vault.old.index.state", "", now)
+		writeFile("switch.vault.default.index", "", now)
+		writeFile("switch.vault.default.index.state", "", now)
+		writeFile("switch.config", "", now)
+
+		staleFiles, err := collector.Find(now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths(staleFiles)).To(ConsistOf(index, state))
+		Expect(staleFiles[0].Reason).To(Equal(`store "vault.old" is not configured`))
+	})
+
+	It("should find the oldest checkpoints beyond the maximum number of backups", func() {
+		oldest := writeFile(filepath.Join(checkpoint.Directory, "1"), "", now.Add(-3*time.Hour))
+		writeFile(filepath.Join(checkpoint.Directory, "2"), "", now.Add(-2*time.Hour))
+		writeFile(filepath.Join(checkpoint.Directory, "3"), "", now.Add(-time.Hour))
+
+		staleFiles, err := collector.Find(now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(staleFiles).To(BeEmpty())
+
+		collector.MaxBackups = ptr.To(2)
+		staleFiles, err = collector.Find(now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths(staleFiles)).To(ConsistOf(oldest))
+	})
+
+	It("should not remove lock files", func() {
+		writeFile("switch.vault.default.index.lock", "", now.Add(-24*time.Hour))
+
+		staleFiles, err := collector.Find(now)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(staleFiles).To(BeEmpty())
+	})
+
+	It("should find no files in a missing state directory", func() {
+		collector.StateDir = filepath.Join(stateDir, "missing")
+		collector.CredentialCacheDirectory = filepath.Join(stateDir, "missing", "credentials")
+
+		output, err := captureStdout(collector.Run)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output).To(Equal(fmt.Sprintf("No stale files found in %s\n", collector.StateDir)))
+	})
+
+	It("should remove the stale files and print the reclaimed bytes", func() {
+		index := writeFile("switch.vault.old.index", "0123456789", now)
+		writeFile("switch.vault.default.index", "", now)
+
+		output, err := captureStdout(collector.Run)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output).To(Equal(fmt.Sprintf("  - %s (store \"vault.old\" is not configured)\nReclaimed 10 B by removing 1 stale file(s)\n", index)))
+		Expect(index).ToNot(BeAnExistingFile())
+		Expect(filepath.Join(stateDir, "switch.vault.default.index")).To(BeAnExistingFile())
+	})
+
+	It("should only list each stale file once with --dry-run", func() {
+		index := writeFile("switch.vault.old.index", "0123456789", now)
+		dryRunWriter := kubeswitchio.NewDryRunWriter(false, "")
+		kubeswitchio.SetWriter(dryRunWriter)
+
+		output, err := captureStdout(collector.Run)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output).To(Equal("Would reclaim 10 B by removing 1 stale file(s)\n"))
+		Expect(dryRunWriter.Summary()).To(Equal([]string{fmt.Sprintf("Would remove %s", index)}))
+		Expect(index).To(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,197 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generatekubeconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientauthenticationv1 "k8s.io/client-go/pkg/apis/clientauthentication/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	setcontext "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/set-context"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var logger = logrus.New()
+
+// OriginalContextExtensionName is the name of the kubeconfig extension containing the original context name
+// if the context was renamed with outputContextName
+const OriginalContextExtensionName = "kubeswitch.io/original-context"
+
+// GenerateKubeconfig writes a standalone kubeconfig only containing the given context with its cluster and user to the output file.
+// Writes to STDOUT if the output is empty or "-". The kubeconfig is serialized in the given format.
+// If inlineExecPlugin is set, exec plugins are invoked and replaced by the returned credentials.
+// The ttl is the minimum validity of the inlined credentials. Only a warning is logged if the provider issues credentials with a shorter validity.
+// If outputContextName is set, the context, cluster and user are renamed and the original context name is kept in an extension.
+func GenerateKubeconfig(desiredContext, output, outputContextName string, format kubeconfigutil.Format, inlineExecPlugin bool, ttl time.Duration, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	kubeconfig, err := GetMinifiedKubeconfig(desiredContext, stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	if inlineExecPlugin {
+		for name, authInfo := range kubeconfig.AuthInfos {
+			if authInfo.Exec == nil {
+				continue
+			}
+			if err := inlineExecCredential(authInfo, ttl); err != nil {
+				return fmt.Errorf("failed to inline credentials of exec plugin for user %q: %w", name, err)
+			}
+		}
+	} else if ttl > 0 {
+		logger.Warnf("--ttl is only used when inlining the credentials of exec plugins")
+	}
+
+	if len(outputContextName) > 0 {
+		if err := renameContext(kubeconfig, outputContextName); err != nil {
+			return err
+		}
+	}
+
+	data, err := util.EncodeKubeconfig(kubeconfig, format)
+	if err != nil {
+		return fmt.Errorf("failed to serialize kubeconfig: %w", err)
+	}
+
+	if len(output) == 0 || output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if err := kubeswitchio.WriteFile(output, data, 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig to %q: %w", output, err)
+	}
+	return nil
+}
+
+// GetMinifiedKubeconfig returns a standalone kubeconfig only containing the given context with its cluster and user
+func GetMinifiedKubeconfig(desiredContext string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) (*clientcmdapi.Config, error) {
+	tmpKubeconfigFile, _, err := setcontext.SetContext(desiredContext, stores, config, stateDir, noIndex, false)
+	if err != nil {
+		return nil, err
+	}
+	if kubeconfigutil.IsTemporaryKubeconfigFile(*tmpKubeconfigFile) {
+		defer os.Remove(*tmpKubeconfigFile)
+	}
+
+	kubeconfig, err := clientcmd.LoadFromFile(*tmpKubeconfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig for context %q: %w", desiredContext, err)
+	}
+
+	// only keep the current context with its cluster and user
+	if err := clientcmdapi.MinifyConfig(kubeconfig); err != nil {
+		return nil, fmt.Errorf("failed to extract context %q from kubeconfig: %w", desiredContext, err)
+	}
+	return kubeconfig, nil
+}
+
+// renameContext renames the current context of the minified kubeconfig to the given name.
+// The cluster and user are renamed to "<name>-cluster" and "<name>-user" respectively.
+func renameContext(kubeconfig *clientcmdapi.Config, name string) error {
+	originalName := kubeconfig.CurrentContext
+	context, ok := kubeconfig.Contexts[originalName]
+	if !ok {
+		return fmt.Errorf("context %q not found in kubeconfig", originalName)
+	}
+
+	clusterName := fmt.Sprintf("%s-cluster", name)
+	if cluster, ok := kubeconfig.Clusters[context.Cluster]; ok {
+		kubeconfig.Clusters = map[string]*clientcmdapi.Cluster{clusterName: cluster}
+	}
+	context.Cluster = clusterName
+
+	userName := fmt.Sprintf("%s-user", name)
+	if authInfo, ok := kubeconfig.AuthInfos[context.AuthInfo]; ok {
+		kubeconfig.AuthInfos = map[string]*clientcmdapi.AuthInfo{userName: authInfo}
+	}
+	context.AuthInfo = userName
+
+	kubeconfig.Contexts = map[string]*clientcmdapi.Context{name: context}
+	kubeconfig.CurrentContext = name
+
+	raw, err := json.Marshal(originalName)
+	if err != nil {
+		return err
+	}
+	if kubeconfig.Extensions == nil {
+		kubeconfig.Extensions = make(map[string]runtime.Object)
+	}
+	kubeconfig.Extensions[OriginalContextExtensionName] = &runtime.Unknown{Raw: raw, ContentType: runtime.ContentTypeJSON}
+	return nil
+}
+
+// inlineExecCredential invokes the exec plugin of the user and replaces it with the returned token or client certificate
+func inlineExecCredential(authInfo *clientcmdapi.AuthInfo, ttl time.Duration) error {
+	execConfig := authInfo.Exec
+
+	cmd := osexec.Command(execConfig.Command, execConfig.Args...)
+	cmd.Env = os.Environ()
+	for _, env := range execConfig.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", env.Name, env.Value))
+	}
+
+	execInfo, err := json.Marshal(map[string]interface{}{
+		"apiVersion": execConfig.APIVersion,
+		"kind":       "ExecCredential",
+		"spec":       map[string]interface{}{"interactive": false},
+	})
+	if err != nil {
+		return err
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("KUBERNETES_EXEC_INFO=%s", execInfo))
+
+	stderr := bytes.Buffer{}
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("exec plugin %q failed: %w: %s", execConfig.Command, err, stderr.String())
+	}
+
+	// the status of the ExecCredential is identical for v1 and v1beta1
+	credential := clientauthenticationv1.ExecCredential{}
+	if err := json.Unmarshal(out, &credential); err != nil {
+		return fmt.Errorf("failed to parse ExecCredential returned by exec plugin %q: %w", execConfig.Command, err)
+	}
+
+	status := credential.Status
+	if status == nil || (len(status.Token) == 0 && len(status.ClientCertificateData) == 0) {
+		return fmt.Errorf("exec plugin %q did not return credentials", execConfig.Command)
+	}
+
+	if ttl > 0 && status.ExpirationTimestamp != nil && status.ExpirationTimestamp.Time.Before(time.Now().Add(ttl)) {
+		logger.Warnf("credentials returned by exec plugin %q expire at %s which is before the requested ttl of %s", execConfig.Command, status.ExpirationTimestamp.Time.Format(time.RFC3339), ttl.String())
+	}
+
+	authInfo.Exec = nil
+	authInfo.Token = status.Token
+	if len(status.ClientCertificateData) > 0 {
+		authInfo.ClientCertificateData = []byte(status.ClientCertificateData)
+		authInfo.ClientKeyData = []byte(status.ClientKeyData)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generatekubeconfig_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	generatekubeconfig "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/generate-kubeconfig"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+    namespace: monitoring
+- name: prod
+  context:
+    cluster: prod
+    user: viewer
+users:
+- name: admin
+  user:
+    token: admin-token
+- name: viewer
+  user:
+    token: viewer-token
+`
+
+var _ = Describe("GenerateKubeconfig", func() {
+	var (
+		tempDir string
+		home    string
+		output  string
+		stores  []store.KubeconfigStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-generate-kubeconfig")
+		Expect(err).ToNot(HaveOccurred())
+		annotations.SetPath(filepath.Join(tempDir, "annotations.yaml"))
+
+		// the selected context is written to a temporary kubeconfig in the home directory
+		home = os.Getenv("HOME")
+		Expect(os.Setenv("HOME", tempDir)).To(Succeed())
+
+		kubeconfigsDir := filepath.Join(tempDir, "kubeconfigs")
+		Expect(os.MkdirAll(filepath.Join(kubeconfigsDir, "team"), 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(kubeconfigsDir, "team", "config"), []byte(kubeconfig), 0600)).To(Succeed())
+
+		filesystemStore, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{kubeconfigsDir},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		stores = []store.KubeconfigStore{filesystemStore}
+		output = filepath.Join(tempDir, "generated")
+	})
+
+	AfterEach(func() {
+		Expect(os.Setenv("HOME", home)).To(Succeed())
+		annotations.SetPath(annotations.DefaultPath)
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	generate := func(desiredContext, outputContextName string) *clientcmdapi.Config {
+		Expect(generatekubeconfig.GenerateKubeconfig(desiredContext, output, outputContextName, kubeconfigutil.FormatYAML, false, 0, stores, &types.Config{}, tempDir, true)).To(Succeed())
+		generated, err := clientcmd.LoadFromFile(output)
+		Expect(err).ToNot(HaveOccurred())
+		return generated
+	}
+
+	It("should only contain the context with its cluster and user", func() {
+		generated := generate("team/prod", "")
+
+		Expect(generated.Contexts).To(HaveLen(1))
+		context := generated.Contexts[generated.CurrentContext]
+		Expect(context).ToNot(BeNil())
+		Expect(generated.Clusters).To(HaveLen(1))
+		Expect(generated.Clusters[context.Cluster].Server).To(Equal("https://prod.example.com"))
+		Expect(generated.AuthInfos).To(HaveLen(1))
+		Expect(generated.AuthInfos[context.AuthInfo].Token).To(Equal("viewer-token"))
+		Expect(generated.Extensions).ToNot(HaveKey(generatekubeconfig.OriginalContextExtensionName))
+	})
+
+	Describe("output context name", func() {
+		It("should rename the context, the cluster and the user", func() {
+			generated := generate("team/dev", "ci")
+
+			Expect(generated.CurrentContext).To(Equal("ci"))
+			Expect(generated.Contexts).To(HaveLen(1))
+			Expect(generated.Contexts).To(HaveKey("ci"))
+			Expect(generated.Contexts["ci"].Cluster).To(Equal("ci-cluster"))
+			Expect(generated.Contexts["ci"].AuthInfo).To(Equal("ci-user"))
+			Expect(generated.Contexts["ci"].Namespace).To(Equal("monitoring"))
+
+			Expect(generated.Clusters).To(HaveLen(1))
+			Expect(generated.Clusters["ci-cluster"].Server).To(Equal("https://dev.example.com"))
+			Expect(generated.AuthInfos).To(HaveLen(1))
+			Expect(generated.AuthInfos["ci-user"].Token).To(Equal("admin-token"))
+		})
+
+		It("should keep the original context name in an extension", func() {
+			generated := generate("team/dev", "ci")
+
+			Expect(generated.Extensions).To(HaveKey(generatekubeconfig.OriginalContextExtensionName))
+			extension, ok := generated.Extensions[generatekubeconfig.OriginalContextExtensionName].(*runtime.Unknown)
+			Expect(ok).To(BeTrue())
+			// the name of the context in the kubeconfig of the store
+			Expect(string(extension.Raw)).To(Equal(`"dev"`))
+		})
+
+		It("should rename a context to its own name", func() {
+			generated := generate("team/dev", "team/dev")
+
+			Expect(generated.CurrentContext).To(Equal("team/dev"))
+			Expect(generated.Contexts["team/dev"].Cluster).To(Equal("team/dev-cluster"))
+			Expect(generated.Clusters).To(HaveKey("team/dev-cluster"))
+		})
+	})
+})
@@ -0,0 +1,265 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gettoken
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/pkg/apis/clientauthentication"
+	execplugin "k8s.io/client-go/plugin/pkg/client/auth/exec"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/transport"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const (
+	FormatJSON = "json"
+	FormatRaw  = "raw"
+)
+
+// Token is the bearer token of a context
+type Token struct {
+	Token  string    `json:"token"`
+	Expiry time.Time `json:"-"`
+}
+
+// MarshalJSON formats the expiry as RFC3339 and omits it if the token does not expire
+func (t Token) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Token  string `json:"token"`
+		Expiry string `json:"expiry,omitempty"`
+	}{Token: t.Token}
+	if !t.Expiry.IsZero() {
+		out.Expiry = t.Expiry.UTC().Format(time.RFC3339)
+	}
+	return json.Marshal(out)
+}
+
+// cachedToken is the representation of a token in the credential cache
+type cachedToken struct {
+	Token  string    `json:"token"`
+	Expiry time.Time `json:"expiry"`
+}
+
+// PrintToken prints the bearer token of the given context in the given format
+func PrintToken(desiredContext, format string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool, credentialCache *credentials.CredentialCache) error {
+	if format != FormatJSON && format != FormatRaw {
+		return fmt.Errorf("unknown format %q. Valid formats are %q and %q", format, FormatJSON, FormatRaw)
+	}
+
+	token, err := GetToken(desiredContext, stores, config, stateDir, noIndex, credentialCache)
+	if err != nil {
+		return err
+	}
+
+	if format == FormatRaw {
+		fmt.Println(token.Token)
+		return nil
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// User is the user of a context in the kubeconfig fetched from the store of the context
+type User struct {
+	// ContextName is the name of the context including the store prefix
+	ContextName string
+	// StoreID is the ID of the store of the context
+	StoreID string
+	// Kubeconfig is the kubeconfig of the context
+	Kubeconfig *clientcmdapi.Config
+	// Context is the context in the kubeconfig
+	Context *clientcmdapi.Context
+	// AuthInfo is the user of the context in the kubeconfig
+	AuthInfo *clientcmdapi.AuthInfo
+}
+
+// GetToken fetches the kubeconfig of the given context from its store and returns the bearer token of the user.
+// The exec plugin of the user is invoked if the kubeconfig does not contain a static token.
+// Tokens obtained from exec plugins are cached in the credential cache until they expire.
+// Tokens with an unknown expiry are not cached.
+func GetToken(desiredContext string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool, credentialCache *credentials.CredentialCache) (*Token, error) {
+	user, err := FindUser(desiredContext, stores, config, stateDir, noIndex)
+	if err != nil {
+		return nil, err
+	}
+	return GetTokenForUser(user, credentialCache)
+}
+
+// FindUser fetches the kubeconfig of the given context from its store and returns the user of the context
+func FindUser(desiredContext string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) (*User, error) {
+	discoveredContext, err := pkg.FindContext(desiredContext, stores, config, stateDir, noIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfigStore := *discoveredContext.Store
+	kubeconfigData, err := kubeconfigStore.GetKubeconfigForPath(context.Background(), discoveredContext.Path, discoveredContext.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfig, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %v", err)
+	}
+
+	contextName := pkg.KubeconfigContextName(kubeconfigStore, *discoveredContext)
+
+	kubeContext, ok := kubeconfig.Contexts[contextName]
+	if !ok {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", contextName)
+	}
+
+	authInfo, ok := kubeconfig.AuthInfos[kubeContext.AuthInfo]
+	if !ok {
+		return nil, fmt.Errorf("user %q of context %q not found in kubeconfig", kubeContext.AuthInfo, contextName)
+	}
+
+	return &User{
+		ContextName: discoveredContext.Name,
+		StoreID:     kubeconfigStore.GetID(),
+		Kubeconfig:  kubeconfig,
+		Context:     kubeContext,
+		AuthInfo:    authInfo,
+	}, nil
+}
+
+// GetTokenForUser returns the bearer token of the user.
+// The exec plugin of the user is invoked if the kubeconfig does not contain a static token.
+func GetTokenForUser(user *User, credentialCache *credentials.CredentialCache) (*Token, error) {
+	authInfo := user.AuthInfo
+	switch {
+	case len(authInfo.Token) > 0:
+		return &Token{Token: authInfo.Token}, nil
+	case len(authInfo.TokenFile) > 0:
+		token, err := os.ReadFile(authInfo.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token file %q: %w", authInfo.TokenFile, err)
+		}
+		return &Token{Token: strings.TrimSpace(string(token))}, nil
+	case authInfo.Exec != nil:
+		var cluster *clientcmdapi.Cluster
+		if authInfo.Exec.ProvideClusterInfo {
+			cluster = user.Kubeconfig.Clusters[user.Context.Cluster]
+		}
+		return getExecToken(user.ContextName, user.StoreID, authInfo.Exec, cluster, credentialCache)
+	default:
+		return nil, fmt.Errorf("the user of context %q neither has a token nor an exec plugin", user.ContextName)
+	}
+}
+
+// getExecToken returns the token of the exec plugin from the credential cache or invokes the exec plugin.
+// The token is cached by the name of the context, so that an alias of the context shares the cached token.
+func getExecToken(contextName, storeID string, execConfig *clientcmdapi.ExecConfig, cluster *clientcmdapi.Cluster, credentialCache *credentials.CredentialCache) (*Token, error) {
+	cacheKey := fmt.Sprintf("token-%s", contextName)
+
+	var cached cachedToken
+	found, err := credentialCache.Get(storeID, cacheKey, &cached)
+	if err != nil {
+		logrus.Debugf("failed to read cached token of context %q: %v", contextName, err)
+	}
+	if found {
+		return &Token{Token: cached.Token, Expiry: cached.Expiry}, nil
+	}
+
+	token, err := runExecPlugin(execConfig, cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Expiry.IsZero() {
+		return token, nil
+	}
+
+	if err := credentialCache.Put(storeID, cacheKey, cachedToken{Token: token.Token, Expiry: token.Expiry}, token.Expiry); err != nil {
+		logrus.Debugf("failed to cache token of context %q: %v", contextName, err)
+	}
+	return token, nil
+}
+
+// runExecPlugin obtains the token of the exec plugin with the exec authenticator of client-go,
+// which passes the stdin to interactive exec plugins.
+// The authenticator does not expose the expiry of the ExecCredential, hence the expiry is read from the "exp" claim of JWTs.
+func runExecPlugin(execConfig *clientcmdapi.ExecConfig, cluster *clientcmdapi.Cluster) (*Token, error) {
+	var execCluster *clientauthentication.Cluster
+	if cluster != nil {
+		execCluster = &clientauthentication.Cluster{
+			Server:                   cluster.Server,
+			TLSServerName:            cluster.TLSServerName,
+			InsecureSkipTLSVerify:    cluster.InsecureSkipTLSVerify,
+			CertificateAuthorityData: cluster.CertificateAuthorityData,
+			ProxyURL:                 cluster.ProxyURL,
+			DisableCompression:       cluster.DisableCompression,
+		}
+	}
+
+	authenticator, err := execplugin.GetAuthenticator(execConfig, execCluster)
+	if err != nil {
+		return nil, err
+	}
+
+	transportConfig := &transport.Config{}
+	if err := authenticator.UpdateTransportConfig(transportConfig); err != nil {
+		return nil, err
+	}
+
+	// the request is never sent. The authenticator sets the bearer token on the request.
+	var authorization string
+	capture := roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		authorization = request.Header.Get("Authorization")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: request}, nil
+	})
+	request, err := http.NewRequest(http.MethodGet, "https://kubeswitch.invalid", nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := transportConfig.WrapTransport(capture).RoundTrip(request); err != nil {
+		return nil, fmt.Errorf("failed to run exec plugin %q: %w", execConfig.Command, err)
+	}
+
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || len(token) == 0 {
+		return nil, fmt.Errorf("exec plugin %q did not return a token", execConfig.Command)
+	}
+
+	// tokens which are not JWTs have an unknown expiry
+	expiry, _ := util.JWTExpiry(token)
+	return &Token{Token: token, Expiry: expiry}, nil
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
@@ -0,0 +1,243 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gettoken_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	aliasstate "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/alias/state"
+	gettoken "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/get-token"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+users:
+- name: admin
+  user:
+    token: admin-token
+`
+
+// jwt returns an unsigned JWT expiring at the given time
+func jwt(expiry time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, expiry.Unix())))
+	return header + "." + payload + ".signature"
+}
+
+var _ = Describe("GetToken", func() {
+	var (
+		tempDir         string
+		credentialCache *credentials.CredentialCache
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-get-token")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		block, err := ssh.MarshalPrivateKey(privateKey, "")
+		Expect(err).ToNot(HaveOccurred())
+		sshKeyPath := filepath.Join(tempDir, "id_ed25519")
+		Expect(os.WriteFile(sshKeyPath, pem.EncodeToMemory(block), 0600)).To(Succeed())
+
+		credentialCache, err = credentials.NewCredentialCache(filepath.Join(tempDir, "credentials"), time.Hour, sshKeyPath)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	// writePlugin writes an exec plugin returning the given token which records its KUBERNETES_EXEC_INFO
+	writePlugin := func(token string) string {
+		path := filepath.Join(tempDir, "plugin")
+		script := fmt.Sprintf(`#!/bin/sh
+printf '%%s' "$KUBERNETES_EXEC_INFO" > %q
+echo '{"apiVersion": "client.authentication.k8s.io/v1", "kind": "ExecCredential", "status": {"token": "%s"}}'
+`, filepath.Join(tempDir, "exec-info"), token)
+		Expect(os.WriteFile(path, []byte(script), 0700)).To(Succeed())
+		return path
+	}
+
+	execUser := func(execConfig *clientcmdapi.ExecConfig) *gettoken.User {
+		if len(execConfig.APIVersion) == 0 {
+			execConfig.APIVersion = "client.authentication.k8s.io/v1"
+		}
+		if len(execConfig.InteractiveMode) == 0 {
+			execConfig.InteractiveMode = clientcmdapi.IfAvailableExecInteractiveMode
+		}
+		return &gettoken.User{
+			ContextName: "team/dev",
+			StoreID:     "filesystem.default",
+			Kubeconfig: &clientcmdapi.Config{
+				Clusters: map[string]*clientcmdapi.Cluster{"dev": {Server: "https://dev.example.com"}},
+			},
+			Context:  &clientcmdapi.Context{Cluster: "dev", AuthInfo: "admin"},
+			AuthInfo: &clientcmdapi.AuthInfo{Exec: execConfig},
+		}
+	}
+
+	Describe("FindUser", func() {
+		var stores []store.KubeconfigStore
+
+		BeforeEach(func() {
+			annotations.SetPath(filepath.Join(tempDir, "annotations.yaml"))
+
+			kubeconfigsDir := filepath.Join(tempDir, "kubeconfigs")
+			Expect(os.MkdirAll(filepath.Join(kubeconfigsDir, "team"), 0700)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(kubeconfigsDir, "team", "config"), []byte(kubeconfig), 0600)).To(Succeed())
+
+			filesystemStore, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+				Kind:  types.StoreKindFilesystem,
+				Paths: []string{kubeconfigsDir},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			stores = []store.KubeconfigStore{filesystemStore}
+		})
+
+		AfterEach(func() {
+			annotations.SetPath(annotations.DefaultPath)
+		})
+
+		It("should return the user of the context", func() {
+			user, err := gettoken.FindUser("team/dev", stores, &types.Config{}, tempDir, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user.ContextName).To(Equal("team/dev"))
+			Expect(user.StoreID).To(Equal(stores[0].GetID()))
+			Expect(user.AuthInfo.Token).To(Equal("admin-token"))
+		})
+
+		It("should resolve an alias to the name of the context", func() {
+			alias, err := aliasstate.GetDefaultAlias(tempDir)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = alias.WriteAlias("short", "team/dev")
+			Expect(err).ToNot(HaveOccurred())
+
+			user, err := gettoken.FindUser("short", stores, &types.Config{}, tempDir, true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(user.ContextName).To(Equal("team/dev"))
+		})
+	})
+
+	Describe("GetTokenForUser", func() {
+		It("should return the static token", func() {
+			token, err := gettoken.GetTokenForUser(&gettoken.User{ContextName: "dev", AuthInfo: &clientcmdapi.AuthInfo{Token: "static"}}, credentialCache)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token).To(Equal(&gettoken.Token{Token: "static"}))
+		})
+
+		It("should read the token file", func() {
+			tokenFile := filepath.Join(tempDir, "token")
+			Expect(os.WriteFile(tokenFile, []byte("from-file\n"), 0600)).To(Succeed())
+
+			token, err := gettoken.GetTokenForUser(&gettoken.User{ContextName: "dev", AuthInfo: &clientcmdapi.AuthInfo{TokenFile: tokenFile}}, credentialCache)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token.Token).To(Equal("from-file"))
+		})
+
+		It("should fail if the user has neither a token nor an exec plugin", func() {
+			_, err := gettoken.GetTokenForUser(&gettoken.User{ContextName: "dev", AuthInfo: &clientcmdapi.AuthInfo{}}, credentialCache)
+			Expect(err).To(MatchError(`the user of context "dev" neither has a token nor an exec plugin`))
+		})
+
+		It("should return the token of the exec plugin and cache it by the name of the context until the JWT expires", func() {
+			expiry := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+			user := execUser(&clientcmdapi.ExecConfig{Command: writePlugin(jwt(expiry))})
+
+			token, err := gettoken.GetTokenForUser(user, credentialCache)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token.Token).To(Equal(jwt(expiry)))
+			Expect(token.Expiry.Equal(expiry)).To(BeTrue())
+
+			var cached struct {
+				Token string `json:"token"`
+			}
+			found, err := credentialCache.Get("filesystem.default", "token-team/dev", &cached)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(cached.Token).To(Equal(jwt(expiry)))
+		})
+
+		It("should return the cached token without invoking the exec plugin", func() {
+			expiry := time.Now().Add(30 * time.Minute)
+			Expect(credentialCache.Put("filesystem.default", "token-team/dev", gettoken.Token{Token: "cached"}, expiry)).To(Succeed())
+
+			token, err := gettoken.GetTokenForUser(execUser(&clientcmdapi.ExecConfig{Command: filepath.Join(tempDir, "missing")}), credentialCache)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token.Token).To(Equal("cached"))
+		})
+
+		It("should not cache tokens with an unknown expiry", func() {
+			token, err := gettoken.GetTokenForUser(execUser(&clientcmdapi.ExecConfig{Command: writePlugin("opaque")}), credentialCache)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(token).To(Equal(&gettoken.Token{Token: "opaque"}))
+
+			found, err := credentialCache.Get("filesystem.default", "token-team/dev", &gettoken.Token{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("should pass the cluster to the exec plugin if requested", func() {
+			_, err := gettoken.GetTokenForUser(execUser(&clientcmdapi.ExecConfig{Command: writePlugin("opaque"), ProvideClusterInfo: true}), credentialCache)
+			Expect(err).ToNot(HaveOccurred())
+
+			execInfo, err := os.ReadFile(filepath.Join(tempDir, "exec-info"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(execInfo)).To(ContainSubstring(`"server":"https://dev.example.com"`))
+			Expect(string(execInfo)).To(ContainSubstring(`"interactive":false`))
+		})
+
+		It("should fail for exec plugins requiring an interactive terminal if the stdin is no terminal", func() {
+			user := execUser(&clientcmdapi.ExecConfig{Command: writePlugin("opaque"), InteractiveMode: clientcmdapi.AlwaysExecInteractiveMode})
+
+			_, err := gettoken.GetTokenForUser(user, credentialCache)
+			Expect(err).To(MatchError(ContainSubstring("standard input is not a terminal")))
+		})
+
+		It("should print the install hint if the exec plugin is not installed", func() {
+			user := execUser(&clientcmdapi.ExecConfig{Command: "kubeswitch-missing-plugin", InstallHint: "install the plugin"})
+
+			_, err := gettoken.GetTokenForUser(user, credentialCache)
+			Expect(err).To(MatchError(ContainSubstring("install the plugin")))
+		})
+	})
+})
@@ -20,6 +20,8 @@ import (
 	"io"
 	"os"
 	"strings"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
 )
 
 // historyFilePath is a constant for the filename storing the history of namespaces
@@ -49,30 +51,21 @@ func ReadHistory() ([]string, error) {
 // AppendToHistory appends the given context: namespace to the history file
 func AppendToHistory(context, namespace string) error {
 	filepath := os.ExpandEnv(historyFilePath)
-	f, err := os.OpenFile(filepath,
-		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
 	historyEntry := fmt.Sprintf("%s:: %s\n", context, namespace)
 
-	lastHistoryEntry, err := getLastLineWithSeek(filepath)
-	if err != nil {
-		return err
-	}
-
-	// do not entry history entry if previous entry is identical
-	if historyEntry == lastHistoryEntry {
-		return nil
-	}
+	if _, err := os.Stat(filepath); err == nil {
+		lastHistoryEntry, err := getLastLineWithSeek(filepath)
+		if err != nil {
+			return err
+		}
 
-	if _, err := f.WriteString(historyEntry); err != nil {
-		return err
+		// do not entry history entry if previous entry is identical
+		if historyEntry == lastHistoryEntry {
+			return nil
+		}
 	}
 
-	return nil
+	return kubeswitchio.AppendFile(filepath, []byte(historyEntry), 0644)
 }
 
 // ParseHistoryEntry takes a history entry as argument and returns the context as first, and the namespace as seconds
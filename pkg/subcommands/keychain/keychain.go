@@ -0,0 +1,93 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keychain
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	keychainutil "github.com/danielfoehrkn/kubeswitch/pkg/keychain"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// AddToken stores the token of the user of the given context in the macOS Keychain
+// and replaces the token in the kubeconfig file with a reference to the Keychain item.
+// The context must be discovered by a filesystem store.
+func AddToken(desiredContext, token string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	if len(token) == 0 {
+		return fmt.Errorf("the token must not be empty")
+	}
+
+	discoveredContext, err := pkg.FindContext(desiredContext, stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	kubeconfigStore := *discoveredContext.Store
+	if _, ok := store.Unwrap(kubeconfigStore).(*store.FilesystemStore); !ok {
+		return fmt.Errorf("context %q is not stored in a kubeconfig file of a filesystem store", desiredContext)
+	}
+
+	if err := store.CheckWritable(kubeconfigStore); err != nil {
+		return err
+	}
+
+	// read the file directly, the store would replace existing Keychain references
+	kubeconfig, err := clientcmd.LoadFromFile(discoveredContext.Path)
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig %q: %w", discoveredContext.Path, err)
+	}
+
+	contextName := pkg.KubeconfigContextName(kubeconfigStore, *discoveredContext)
+
+	context, ok := kubeconfig.Contexts[contextName]
+	if !ok {
+		return fmt.Errorf("context %q not found in kubeconfig %q", contextName, discoveredContext.Path)
+	}
+
+	authInfo, ok := kubeconfig.AuthInfos[context.AuthInfo]
+	if !ok {
+		return fmt.Errorf("user %q of context %q not found in kubeconfig %q", context.AuthInfo, contextName, discoveredContext.Path)
+	}
+
+	// the Keychain item is specific to the user, as multiple contexts can share a user
+	account := fmt.Sprintf("%s/%s", contextName, context.AuthInfo)
+	if err := keychainutil.AddToken(keychainutil.DefaultService, account, token); err != nil {
+		return err
+	}
+
+	authInfo.Token = keychainutil.Reference(keychainutil.DefaultService, account)
+	authInfo.TokenFile = ""
+
+	if err := clientcmd.WriteToFile(*kubeconfig, discoveredContext.Path); err != nil {
+		return fmt.Errorf("failed to write kubeconfig %q: %w", discoveredContext.Path, err)
+	}
+
+	if !isKeychainBackendEnabled(kubeconfigStore) {
+		fmt.Fprintf(os.Stderr, "Warning: the Keychain backend is not enabled for store %q. Please set \"keychainBackend: true\" in the store configuration.\n", kubeconfigStore.GetID())
+	}
+
+	fmt.Printf("Stored the token of user %q of context %q in the Keychain\n", context.AuthInfo, desiredContext)
+	return nil
+}
+
+func isKeychainBackendEnabled(kubeconfigStore store.KubeconfigStore) bool {
+	filesystemStore, ok := store.Unwrap(kubeconfigStore).(*store.FilesystemStore)
+	return ok && filesystemStore.KeychainBackend
+}
@@ -27,14 +27,37 @@ import (
 
 var logger = logrus.New()
 
+// Context is a context found by the search
+type Context struct {
+	// Name is the name of the context including the store prefix, or its alias
+	Name string
+	// StoreKind is the kind of the store the context was found in
+	StoreKind types.StoreKind
+}
+
 func ListContexts(pattern string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) ([]string, error) {
+	contexts, err := ListContextsWithStoreKind(pattern, stores, config, stateDir, noIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(contexts))
+	for _, context := range contexts {
+		names = append(names, context.Name)
+	}
+	return names, nil
+}
+
+// ListContextsWithStoreKind returns the contexts matching the wildcard pattern sorted by name
+// together with the kind of the store they were found in
+func ListContextsWithStoreKind(pattern string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) ([]Context, error) {
 	c, err := pkg.DoSearch(stores, config, stateDir, noIndex)
 	if err != nil {
 		return nil, fmt.Errorf("cannot list contexts: %v", err)
 	}
 
 	m := wildmatch.NewWildMatch(pattern)
-	var contexts []string
+	var contexts []Context
 	for discoveredKubeconfig := range *c {
 		if discoveredKubeconfig.Error != nil {
 			logger.Warnf("cannot list contexts. Error returned from search: %v", discoveredKubeconfig.Error)
@@ -47,11 +70,17 @@ func ListContexts(pattern string, stores []store.KubeconfigStore, config *types.
 		}
 		result := m.IsMatch(name)
 		if result {
-			contexts = append(contexts, name)
+			context := Context{Name: name}
+			if discoveredKubeconfig.Store != nil {
+				context.StoreKind = (*discoveredKubeconfig.Store).GetKind()
+			}
+			contexts = append(contexts, context)
 		}
 	}
 	// Sort alphabetically
-	sort.Strings(contexts)
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].Name < contexts[j].Name
+	})
 
 	return contexts, nil
 }
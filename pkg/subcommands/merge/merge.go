@@ -0,0 +1,138 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ConflictStrategy defines how entries with the same name in multiple kubeconfig files are handled
+type ConflictStrategy string
+
+const (
+	// ConflictStrict fails if entries with the same name differ
+	ConflictStrict ConflictStrategy = "strict"
+	// ConflictOverwrite uses the entry of the last file
+	ConflictOverwrite ConflictStrategy = "overwrite"
+	// ConflictSkip uses the entry of the first file
+	ConflictSkip ConflictStrategy = "skip"
+)
+
+// ValidConflictStrategies contains all valid conflict strategies
+var ValidConflictStrategies = sets.NewString(string(ConflictStrict), string(ConflictOverwrite), string(ConflictSkip))
+
+// Merge merges the given kubeconfig files into a single kubeconfig and writes it to the output file.
+// Writes to STDOUT if the output is empty or "-".
+// Clusters, contexts and users are deduplicated by name. Identical entries never conflict.
+// If currentContext is set, it is used as the current-context of the merged kubeconfig.
+// Otherwise, the current-context of the first file that sets one is used.
+func Merge(paths []string, output string, conflict ConflictStrategy, currentContext string) error {
+	if !ValidConflictStrategies.Has(string(conflict)) {
+		return fmt.Errorf("invalid conflict strategy %q. Valid strategies are: %v", conflict, ValidConflictStrategies.List())
+	}
+
+	merged := clientcmdapi.NewConfig()
+	for _, path := range paths {
+		kubeconfig, err := clientcmd.LoadFromFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to load kubeconfig %q: %w", path, err)
+		}
+		clearLocationOfOrigin(kubeconfig)
+
+		if err := mergeEntries(merged.Clusters, kubeconfig.Clusters, conflict, "cluster", path); err != nil {
+			return err
+		}
+		if err := mergeEntries(merged.AuthInfos, kubeconfig.AuthInfos, conflict, "user", path); err != nil {
+			return err
+		}
+		if err := mergeEntries(merged.Contexts, kubeconfig.Contexts, conflict, "context", path); err != nil {
+			return err
+		}
+		if err := mergeEntries(merged.Extensions, kubeconfig.Extensions, conflict, "extension", path); err != nil {
+			return err
+		}
+
+		if len(merged.CurrentContext) == 0 {
+			merged.CurrentContext = kubeconfig.CurrentContext
+		}
+	}
+
+	if len(currentContext) > 0 {
+		if _, ok := merged.Contexts[currentContext]; !ok {
+			return fmt.Errorf("context %q does not exist in the merged kubeconfig", currentContext)
+		}
+		merged.CurrentContext = currentContext
+	}
+
+	data, err := clientcmd.Write(*merged)
+	if err != nil {
+		return fmt.Errorf("failed to serialize kubeconfig: %w", err)
+	}
+
+	if len(output) == 0 || output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if err := kubeswitchio.WriteFile(output, data, 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig to %q: %w", output, err)
+	}
+	return nil
+}
+
+// mergeEntries adds the entries of src to dst according to the conflict strategy
+func mergeEntries[T any](dst, src map[string]T, conflict ConflictStrategy, kind, path string) error {
+	for name, entry := range src {
+		existing, ok := dst[name]
+		if !ok {
+			dst[name] = entry
+			continue
+		}
+
+		if reflect.DeepEqual(existing, entry) {
+			continue
+		}
+
+		switch conflict {
+		case ConflictStrict:
+			return fmt.Errorf("%s %q of kubeconfig %q conflicts with an existing %s of the same name", kind, name, path, kind)
+		case ConflictOverwrite:
+			dst[name] = entry
+		case ConflictSkip:
+		}
+	}
+	return nil
+}
+
+// clearLocationOfOrigin resets the file the entries have been loaded from,
+// so that identical entries of different files are considered equal
+func clearLocationOfOrigin(kubeconfig *clientcmdapi.Config) {
+	for _, cluster := range kubeconfig.Clusters {
+		cluster.LocationOfOrigin = ""
+	}
+	for _, authInfo := range kubeconfig.AuthInfos {
+		authInfo.LocationOfOrigin = ""
+	}
+	for _, context := range kubeconfig.Contexts {
+		context.LocationOfOrigin = ""
+	}
+}
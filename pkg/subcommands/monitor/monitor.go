@@ -0,0 +1,390 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	switchconfig "github.com/danielfoehrkn/kubeswitch/pkg/config"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/monitor"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const (
+	// RegistrationFile is the file in the state directory containing the registration of the running monitor
+	RegistrationFile = "monitor.json"
+	// statusFile contains the status of the targets after the last check
+	statusFile = "monitor-status.json"
+	// logFile contains the output of the monitor started in the background
+	logFile = "monitor.log"
+
+	// connectivityTimeout is the timeout for checking the connectivity to the API server of a watched context
+	connectivityTimeout = 10 * time.Second
+)
+
+var logger = logrus.New()
+
+// Registration is the monitor registered in the state directory
+type Registration struct {
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// Start starts the monitor in the background by running the given command.
+// The command is expected to run the monitor in the foreground.
+func Start(command string, args []string, stateDir string) error {
+	if registration, err := load(stateDir); err == nil && isRunning(registration.PID) {
+		return fmt.Errorf("the monitor is already running with PID %d", registration.PID)
+	}
+
+	logPath := filepath.Join(stateDir, logFile)
+	if kubeswitchio.IsDryRun() {
+		fmt.Printf("Would start the monitor. Logs would be written to %s\n", logPath)
+		return nil
+	}
+
+	if err := kubeswitchio.MkdirAll(stateDir, 0700); err != nil {
+		return err
+	}
+
+	log, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open monitor log %q: %w", logPath, err)
+	}
+	defer log.Close()
+
+	cmd := exec.Command(command, args...)
+	cmd.Stdout = log
+	cmd.Stderr = log
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start the monitor: %w", err)
+	}
+
+	fmt.Printf("Started the monitor with PID %d. Logs are written to %s\n", cmd.Process.Pid, logPath)
+	return cmd.Process.Release()
+}
+
+// Reload configures reloading the stores of the running monitor when the switch config file changes
+type Reload struct {
+	// ConfigPath is the path of the switch config file to watch
+	ConfigPath string
+	// Prepare is called with every reloaded config before the stores are reloaded, e.g. to validate it.
+	// If it returns an error, the reloaded config is ignored.
+	Prepare func(config *types.Config) error
+	// NewStore creates the kubeconfig store for a store configuration
+	NewStore func(kubeconfigStore types.KubeconfigStore) (store.KubeconfigStore, error)
+}
+
+// Run runs the monitor in the foreground until it is interrupted or stopped with Stop.
+// If reload is set, added, changed and removed stores of the config file are applied while running.
+func Run(stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool, reload *Reload) error {
+	if config.Monitor == nil {
+		return fmt.Errorf("the monitor is not configured. Please configure \"monitor.alertmanagerURL\" in the switch configuration")
+	}
+
+	alerter, err := monitor.NewAlertmanagerAlerter(config.Monitor.AlertmanagerURL)
+	if err != nil {
+		return err
+	}
+
+	targets, err := getTargets(stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	checkInterval := monitor.DefaultCheckInterval
+	if config.Monitor.CheckInterval != nil {
+		checkInterval = *config.Monitor.CheckInterval
+	}
+
+	failureThreshold := monitor.DefaultFailureThreshold
+	if config.Monitor.FailureThreshold != nil {
+		failureThreshold = *config.Monitor.FailureThreshold
+	}
+
+	m := monitor.New(targets, alerter, checkInterval, failureThreshold)
+	m.Logger.Logger.SetLevel(logrus.GetLevel())
+	m.OnCheck = func(status []monitor.Status) {
+		if err := writeStatus(stateDir, status); err != nil {
+			logger.Warnf("failed to write the monitor status: %v", err)
+		}
+	}
+
+	registration := Registration{PID: os.Getpid(), StartedAt: time.Now().UTC()}
+	if err := register(stateDir, registration); err != nil {
+		return fmt.Errorf("failed to register the monitor: %w", err)
+	}
+	defer unregister(stateDir, registration)
+
+	if reload != nil {
+		var lock sync.Mutex
+		watcher, err := switchconfig.WatchConfig(reload.ConfigPath, func(newConfig types.Config) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			if err := reload.Prepare(&newConfig); err != nil {
+				logger.Errorf("ignoring changed config: %v", err)
+				return
+			}
+
+			changes := switchconfig.DiffKubeconfigStores(config.KubeconfigStores, newConfig.KubeconfigStores)
+			if changes.IsEmpty() && reflect.DeepEqual(config.Monitor, newConfig.Monitor) {
+				return
+			}
+
+			// the alerting settings cannot be changed while running
+			if newConfig.Monitor == nil {
+				newConfig.Monitor = config.Monitor
+			}
+
+			newStores := reloadStores(stores, changes, reload.NewStore)
+			newTargets, err := getTargets(newStores, &newConfig, stateDir, noIndex)
+			if err != nil {
+				logger.Errorf("failed to reload the targets: %v", err)
+				return
+			}
+
+			stores, config = newStores, &newConfig
+			m.SetTargets(newTargets)
+			logger.Infof("reloaded config: monitoring %d target(s)", len(newTargets))
+		})
+		if err != nil {
+			return err
+		}
+		defer watcher.Close()
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logger.Infof("monitoring %d target(s) every %s", len(targets), checkInterval)
+	return m.Run(ctx)
+}
+
+// reloadStores stops and removes the removed stores and creates the added stores.
+// Unchanged stores are kept as they are. Stores that cannot be created are skipped.
+func reloadStores(stores []store.KubeconfigStore, changes switchconfig.StoreChanges, newStore func(types.KubeconfigStore) (store.KubeconfigStore, error)) []store.KubeconfigStore {
+	removed := sets.New(changes.Removed...)
+
+	var result []store.KubeconfigStore
+	for _, s := range stores {
+		if !removed.Has(s.GetID()) {
+			result = append(result, s)
+			continue
+		}
+
+		logger.Infof("removing store %q", s.GetID())
+		if err := s.Stop(context.Background()); err != nil {
+			logger.Warnf("failed to stop store %q: %v", s.GetID(), err)
+		}
+	}
+
+	for _, kubeconfigStore := range changes.Added {
+		s, err := newStore(kubeconfigStore)
+		if err != nil {
+			logger.Errorf("failed to initialize store %q: %v", store.IDForConfig(kubeconfigStore), err)
+			continue
+		}
+		logger.Infof("adding store %q", s.GetID())
+		result = append(result, s)
+	}
+	return result
+}
+
+// Stop stops the running monitor
+func Stop(stateDir string) error {
+	registration, err := load(stateDir)
+	if err != nil {
+		return err
+	}
+
+	if !isRunning(registration.PID) {
+		unregister(stateDir, *registration)
+		return fmt.Errorf("the monitor is not running")
+	}
+
+	process, err := os.FindProcess(registration.PID)
+	if err != nil {
+		return err
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		return fmt.Errorf("failed to stop the monitor with PID %d: %w", registration.PID, err)
+	}
+
+	fmt.Printf("Stopped the monitor with PID %d\n", registration.PID)
+	return nil
+}
+
+// Status prints if the monitor is running and the status of the targets after the last check
+func Status(stateDir string) error {
+	registration, err := load(stateDir)
+	if err != nil || !isRunning(registration.PID) {
+		fmt.Println("The monitor is not running")
+	} else {
+		fmt.Printf("The monitor is running with PID %d since %s\n", registration.PID, registration.StartedAt.Local().Format(time.RFC1123))
+	}
+
+	data, err := os.ReadFile(filepath.Join(stateDir, statusFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var status []monitor.Status
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("failed to parse the monitor status: %w", err)
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Target", "Reachable", "Failures", "Alerting", "Last check", "Last error"})
+	for _, s := range status {
+		t.AppendRow(table.Row{s.Name, s.Reachable, s.ConsecutiveFailures, s.Alerting, s.LastCheck.Local().Format(time.RFC1123), s.LastError})
+	}
+	t.Render()
+	return nil
+}
+
+// getTargets returns a target for each store and each watched context
+func getTargets(stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) ([]monitor.Target, error) {
+	var targets []monitor.Target
+	for _, s := range stores {
+		targets = append(targets, monitor.Target{
+			Name: fmt.Sprintf("store/%s", s.GetID()),
+			Labels: map[string]string{
+				"alertname": monitor.AlertNameStoreUnreachable,
+				"store":     s.GetID(),
+			},
+			Check: s.Probe,
+		})
+	}
+
+	for _, contextName := range config.Monitor.WatchContexts {
+		discoveredContext, err := pkg.FindContext(contextName, stores, config, stateDir, noIndex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find watched context %q: %w", contextName, err)
+		}
+
+		kubeconfigStore := *discoveredContext.Store
+		targets = append(targets, monitor.Target{
+			Name: fmt.Sprintf("context/%s", contextName),
+			Labels: map[string]string{
+				"alertname": monitor.AlertNameContextUnreachable,
+				"context":   contextName,
+				"store":     kubeconfigStore.GetID(),
+			},
+			Check: func(ctx context.Context) error {
+				return checkContext(ctx, kubeconfigStore, discoveredContext)
+			},
+		})
+	}
+	return targets, nil
+}
+
+// checkContext checks the connectivity to the API server of the discovered context.
+// The kubeconfig is retrieved on every check, so that expired credentials are refreshed by the store.
+func checkContext(ctx context.Context, kubeconfigStore store.KubeconfigStore, discoveredContext *pkg.DiscoveredContext) error {
+	kubeconfigData, err := kubeconfigStore.GetKubeconfigForPath(ctx, discoveredContext.Path, discoveredContext.Tags)
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	contextName := pkg.KubeconfigContextName(kubeconfigStore, *discoveredContext)
+	kubeconfig.CurrentContext = contextName
+
+	kubeconfigData, err = clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return err
+	}
+	return util.CheckAPIServerConnectivity(kubeconfigData, connectivityTimeout)
+}
+
+func isRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+func register(stateDir string, registration Registration) error {
+	if err := kubeswitchio.MkdirAll(stateDir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(registration, "", "  ")
+	if err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(filepath.Join(stateDir, RegistrationFile), data, 0600)
+}
+
+// unregister removes the registration, unless another monitor has been registered in the meantime
+func unregister(stateDir string, registration Registration) {
+	if current, err := load(stateDir); err == nil && current.PID != registration.PID {
+		return
+	}
+	_ = kubeswitchio.Remove(filepath.Join(stateDir, RegistrationFile))
+}
+
+func load(stateDir string) (*Registration, error) {
+	data, err := os.ReadFile(filepath.Join(stateDir, RegistrationFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("the monitor is not running")
+		}
+		return nil, err
+	}
+
+	registration := &Registration{}
+	if err := json.Unmarshal(data, registration); err != nil {
+		return nil, fmt.Errorf("failed to parse the monitor registration: %w", err)
+	}
+	return registration, nil
+}
+
+func writeStatus(stateDir string, status []monitor.Status) error {
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(filepath.Join(stateDir, statusFile), data, 0600)
+}
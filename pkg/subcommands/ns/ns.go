@@ -22,7 +22,9 @@ import (
 	"sync"
 	"time"
 
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
 	historyutil "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/history/util"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
 	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
 	"github.com/ktr0731/go-fuzzyfinder"
 	"github.com/sirupsen/logrus"
@@ -41,6 +43,8 @@ import (
 const (
 	defaultKubeconfigPath       = "$HOME/.kube/config"
 	linuxEnvKubeconfigSeperator = ":"
+	// createNamespaceTimeout is the timeout of the API calls creating a missing namespace
+	createNamespaceTimeout = 5 * time.Second
 )
 
 var (
@@ -286,6 +290,97 @@ func ListNamespaces(kubeconfigPathFromFlag, stateDir string, noIndex bool) ([]st
 	return allNamespaces, nil
 }
 
+// ListClusterNamespaces lists the namespaces of the current cluster via API call with the given timeout.
+// Does not use the namespace cache.
+func ListClusterNamespaces(kubeconfigPathFromFlag string, timeout time.Duration) ([]string, error) {
+	kubeconfigPath, err := getKubeconfigPath(kubeconfigPathFromFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	config.Timeout = timeout
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	list, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces := make([]string, 0, len(list.Items))
+	for _, namespace := range list.Items {
+		namespaces = append(namespaces, namespace.Name)
+	}
+	return namespaces, nil
+}
+
+// SetNamespaceForContext sets the namespace on the current context of the given kubeconfig
+// and creates the namespace in the cluster if create is set.
+// The namespace is set without checking its existence. Only a warning is logged if the namespace cannot be created.
+func SetNamespaceForContext(namespace, kubeconfigPath string, create bool) error {
+	if err := SwitchToNamespace(namespace, kubeconfigPath, false); err != nil {
+		return err
+	}
+
+	if create {
+		// the switch succeeded, so do not fail if the namespace cannot be created
+		if err := CreateNamespace(namespace, kubeconfigPath); err != nil {
+			logger.Warnf("could not create namespace: %v", err)
+		}
+	}
+	return nil
+}
+
+// CreateNamespace creates the namespace in the cluster of the given kubeconfig if it does not exist yet.
+// In dry-run mode, only prints the namespace that would be created without calling the API server.
+func CreateNamespace(namespace, kubeconfigPath string) error {
+	if kubeswitchio.IsDryRun() {
+		fmt.Printf("Would create namespace %q if it does not exist\n", namespace)
+		return nil
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	config.Timeout = createNamespaceTimeout
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), createNamespaceTimeout)
+	defer cancel()
+
+	_, err = clientset.CoreV1().Namespaces().Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}, metav1.CreateOptions{})
+	switch {
+	case err == nil:
+		logger.Infof("Created namespace %q", namespace)
+		return nil
+	case apierrors.IsAlreadyExists(err):
+		return nil
+	case apierrors.IsForbidden(err):
+		// users without permission to create namespaces are denied regardless of whether the namespace exists
+		if _, getErr := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); getErr == nil {
+			return nil
+		}
+		return err
+	default:
+		return err
+	}
+}
+
 func getKubeconfigPath(kubeconfigPathFromFlag string) (string, error) {
 	kubeconfigPath := kubeconfigPathFromFlag
 
@@ -295,7 +390,7 @@ func getKubeconfigPath(kubeconfigPathFromFlag string) (string, error) {
 			return "", fmt.Errorf("providing multiple kubeconfig files via environment variable KUBECONFIG is not supported for namespace switching")
 		}
 
-		kubeconfigPath = os.ExpandEnv(kubeconfigPathFromEnv)
+		kubeconfigPath = util.ResolveKubeconfigPath(kubeconfigPathFromEnv)
 	}
 
 	if _, err := os.Stat(kubeconfigPath); err != nil {
@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
 )
 
 const (
@@ -36,7 +38,7 @@ type NamespaceCache struct {
 func NewNamespaceCache(stateDirectory string, contextName string) (*NamespaceCache, error) {
 	namespaceStateDirectory := fmt.Sprintf("%s/%s", stateDirectory, namespaceSubdirectory)
 	if _, err := os.Stat(namespaceStateDirectory); os.IsNotExist(err) {
-		if err := os.Mkdir(namespaceStateDirectory, 0755); err != nil {
+		if err := kubeswitchio.MkdirAll(namespaceStateDirectory, 0755); err != nil {
 			return nil, err
 		}
 	}
@@ -91,20 +93,12 @@ func (i *NamespaceCache) loadFromFile() ([]string, error) {
 }
 
 func (i *NamespaceCache) Write(toWrite []string) error {
-	// creates or truncate/clean the existing file
-	file, err := os.Create(i.cacheFilepath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	// print values to f, one per line
+	// one value per line
+	output := strings.Builder{}
 	for _, value := range toWrite {
-		_, err = fmt.Fprintln(file, value)
-		if err != nil {
-			return err
-		}
+		fmt.Fprintln(&output, value)
 	}
 
-	return nil
+	// creates or truncate/clean the existing file
+	return kubeswitchio.WriteFile(i.cacheFilepath, []byte(output.String()), 0644)
 }
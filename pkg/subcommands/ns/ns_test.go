@@ -0,0 +1,222 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ns_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/clientcmd"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/ns"
+)
+
+// apiServer serves the namespaces API of a cluster with the given existing namespaces
+type apiServer struct {
+	*httptest.Server
+
+	lock       sync.Mutex
+	namespaces map[string]bool
+	// requests are the received requests in the form "<method> <path>"
+	requests []string
+	// createStatus overrides the status code of creating a namespace if set
+	createStatus int
+}
+
+func newAPIServer(namespaces ...string) *apiServer {
+	s := &apiServer{namespaces: map[string]bool{}}
+	for _, namespace := range namespaces {
+		s.namespaces[namespace] = true
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.requests = append(s.requests, fmt.Sprintf("%s %s", r.Method, r.URL.Path))
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/namespaces":
+			namespace := corev1.Namespace{}
+			if err := json.NewDecoder(r.Body).Decode(&namespace); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			name := namespace.Name
+
+			switch {
+			case s.createStatus == http.StatusForbidden:
+				writeStatus(w, http.StatusForbidden, "Forbidden", name)
+			case s.createStatus != 0:
+				writeStatus(w, s.createStatus, "InternalError", name)
+			case s.namespaces[name]:
+				writeStatus(w, http.StatusConflict, "AlreadyExists", name)
+			default:
+				s.namespaces[name] = true
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": %q}}`, name)
+			}
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/namespaces/"):
+			name := strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/")
+			if !s.namespaces[name] {
+				writeStatus(w, http.StatusNotFound, "NotFound", name)
+				return
+			}
+			fmt.Fprintf(w, `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": %q}}`, name)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return s
+}
+
+func writeStatus(w http.ResponseWriter, code int, reason, name string) {
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `{"apiVersion": "v1", "kind": "Status", "status": "Failure", "reason": %q, "code": %d, "details": {"name": %q, "kind": "namespaces"}}`, reason, code, name)
+}
+
+func (s *apiServer) hasNamespace(name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.namespaces[name]
+}
+
+func (s *apiServer) getRequests() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+var _ = Describe("Namespace", func() {
+	var (
+		tempDir        string
+		home           string
+		kubeconfigPath string
+		server         *apiServer
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-ns")
+		Expect(err).ToNot(HaveOccurred())
+
+		// the namespace history is written to the home directory
+		home = os.Getenv("HOME")
+		Expect(os.Setenv("HOME", tempDir)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(tempDir, ".kube"), 0700)).To(Succeed())
+
+		server = newAPIServer("default", "monitoring")
+		kubeconfigPath = filepath.Join(tempDir, "config")
+		Expect(os.WriteFile(kubeconfigPath, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: %s
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+users:
+- name: admin
+  user:
+    token: admin-token
+`, server.URL)), 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.Setenv("HOME", home)).To(Succeed())
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	namespaceOfContext := func() string {
+		kubeconfig, err := clientcmd.LoadFromFile(kubeconfigPath)
+		Expect(err).ToNot(HaveOccurred())
+		return kubeconfig.Contexts[kubeconfig.CurrentContext].Namespace
+	}
+
+	Describe("CreateNamespace", func() {
+		It("should create a missing namespace", func() {
+			Expect(ns.CreateNamespace("team-a", kubeconfigPath)).To(Succeed())
+			Expect(server.getRequests()).To(Equal([]string{"POST /api/v1/namespaces"}))
+			Expect(server.hasNamespace("team-a")).To(BeTrue())
+		})
+
+		It("should succeed if the namespace already exists without reading it first", func() {
+			Expect(ns.CreateNamespace("monitoring", kubeconfigPath)).To(Succeed())
+			Expect(server.getRequests()).To(Equal([]string{"POST /api/v1/namespaces"}))
+		})
+
+		It("should succeed if creating is forbidden, but the namespace exists", func() {
+			server.createStatus = http.StatusForbidden
+			Expect(ns.CreateNamespace("monitoring", kubeconfigPath)).To(Succeed())
+			Expect(server.getRequests()).To(Equal([]string{"POST /api/v1/namespaces", "GET /api/v1/namespaces/monitoring"}))
+		})
+
+		It("should fail if creating is forbidden and the namespace does not exist", func() {
+			server.createStatus = http.StatusForbidden
+			err := ns.CreateNamespace("team-a", kubeconfigPath)
+			Expect(apierrors.IsForbidden(err)).To(BeTrue())
+		})
+
+		It("should fail if the namespace cannot be created", func() {
+			server.createStatus = http.StatusInternalServerError
+			Expect(ns.CreateNamespace("team-a", kubeconfigPath)).ToNot(Succeed())
+		})
+
+		It("should not call the API server in dry-run mode", func() {
+			kubeswitchio.SetWriter(kubeswitchio.NewDryRunWriter(false, ""))
+			defer kubeswitchio.SetWriter(kubeswitchio.FileWriter{})
+
+			Expect(ns.CreateNamespace("team-a", kubeconfigPath)).To(Succeed())
+			Expect(server.getRequests()).To(BeEmpty())
+		})
+	})
+
+	Describe("SetNamespaceForContext", func() {
+		It("should set the namespace without checking its existence", func() {
+			Expect(ns.SetNamespaceForContext("team-a", kubeconfigPath, false)).To(Succeed())
+			Expect(namespaceOfContext()).To(Equal("team-a"))
+			Expect(server.getRequests()).To(BeEmpty())
+		})
+
+		It("should set and create the namespace", func() {
+			Expect(ns.SetNamespaceForContext("team-a", kubeconfigPath, true)).To(Succeed())
+			Expect(namespaceOfContext()).To(Equal("team-a"))
+			Expect(server.hasNamespace("team-a")).To(BeTrue())
+		})
+
+		It("should set the namespace even if it cannot be created", func() {
+			server.createStatus = http.StatusInternalServerError
+			Expect(ns.SetNamespaceForContext("team-a", kubeconfigPath, true)).To(Succeed())
+			Expect(namespaceOfContext()).To(Equal("team-a"))
+			Expect(server.hasNamespace("team-a")).To(BeFalse())
+		})
+	})
+})
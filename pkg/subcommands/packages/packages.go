@@ -0,0 +1,84 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packages
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var logger = logrus.New()
+
+// ListPackages returns the context names of all stores grouped by package.
+// Contexts that do not belong to any package are omitted.
+func ListPackages(stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) (map[string][]string, error) {
+	c, err := pkg.DoSearch(stores, config, stateDir, noIndex)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list packages: %v", err)
+	}
+
+	packages := make(map[string][]string)
+	for discoveredContext := range *c {
+		if discoveredContext.Error != nil {
+			logger.Warnf("cannot list packages. Error returned from search: %v", discoveredContext.Error)
+			continue
+		}
+
+		if len(discoveredContext.PackageName) == 0 {
+			continue
+		}
+
+		name := discoveredContext.Name
+		if len(discoveredContext.Alias) > 0 {
+			name = discoveredContext.Alias
+		}
+		packages[discoveredContext.PackageName] = append(packages[discoveredContext.PackageName], name)
+	}
+
+	for _, contexts := range packages {
+		slices.Sort(contexts)
+	}
+	return packages, nil
+}
+
+// PrintPackages prints the packages in alphabetical order followed by their contexts
+func PrintPackages(packages map[string][]string) {
+	if len(packages) == 0 {
+		fmt.Println("No context belongs to a package. Configure the packages of a store with \"packages\" in the SwitchConfig.")
+		return
+	}
+
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s (%d)\n", name, len(packages[name]))
+		for _, contextName := range packages[name] {
+			fmt.Fprintf(&b, "  - %s\n", contextName)
+		}
+	}
+	fmt.Print(b.String())
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/plugin"
+)
+
+// Install installs the plugin with the given name in the registry or from the given GitHub repository URL
+func Install(source string, manager *plugin.Manager) error {
+	installed, err := manager.Install(source)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Installed plugin %q %s to %s\n", installed.Name, installed.Version, manager.Directory)
+	return nil
+}
+
+// Update updates the installed plugin with the given name to the latest version
+func Update(name string, manager *plugin.Manager) error {
+	installed, updated, err := manager.Update(name)
+	if err != nil {
+		return err
+	}
+
+	if !updated {
+		fmt.Printf("Plugin %q is already up to date (%s)\n", name, installed.Version)
+		return nil
+	}
+	fmt.Printf("Updated plugin %q to %s\n", name, installed.Version)
+	return nil
+}
+
+// Remove uninstalls the plugin with the given name
+func Remove(name string, manager *plugin.Manager) error {
+	if err := manager.Remove(name); err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed plugin %q\n", name)
+	return nil
+}
+
+// List prints the installed plugins
+func List(manager *plugin.Manager) error {
+	installed, err := manager.List()
+	if err != nil {
+		return err
+	}
+
+	if len(installed) == 0 {
+		fmt.Println("No plugins installed")
+		return nil
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Name", "Version", "Source", "Installed"})
+	for _, p := range installed {
+		t.AppendRow(table.Row{p.Name, p.Version, p.Source, p.InstalledAt.Local().Format(time.RFC1123)})
+	}
+	t.Render()
+	return nil
+}
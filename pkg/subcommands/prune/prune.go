@@ -0,0 +1,239 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/state"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// ContextPruner removes the contexts from a kubeconfig file, e.g. the merged kubeconfig ~/.kube/config,
+// that are no longer found in any kubeconfig store.
+type ContextPruner struct {
+	// KubeconfigPath is the path of the kubeconfig file to remove the contexts from
+	KubeconfigPath string
+	// StateDir is the directory recording when each context was last found in a store
+	StateDir string
+	// MinAge is the duration a context must not have been found in any store before it is removed.
+	// If zero, contexts are removed as soon as they are not found in any store.
+	MinAge time.Duration
+}
+
+// NewContextPruner returns a pruner for the kubeconfig file with the given path
+func NewContextPruner(kubeconfigPath, stateDir string, minAge time.Duration) *ContextPruner {
+	return &ContextPruner{
+		KubeconfigPath: kubeconfigPath,
+		StateDir:       stateDir,
+		MinAge:         minAge,
+	}
+}
+
+// Prune searches all stores and removes the contexts of the kubeconfig file that are not found in any store.
+// Nothing is removed unless every store has been searched successfully,
+// so that a temporarily unreachable store does not cause its contexts to be removed.
+// Paths that do not contain a kubeconfig, e.g. other secrets listed by a Vault store, do not fail the search.
+// The current context of the kubeconfig is never removed.
+func (p *ContextPruner) Prune(stores []store.KubeconfigStore, config *types.Config) error {
+	kubeconfig, err := kubeconfigutil.NewKubeconfigForPath(p.KubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	contextNames, err := kubeconfig.GetContextNames()
+	if err != nil {
+		return err
+	}
+
+	if len(contextNames) == 0 {
+		fmt.Printf("No contexts found in %s\n", p.KubeconfigPath)
+		return nil
+	}
+
+	discoveredNames, err := p.discoverContextNames(stores, config)
+	if err != nil {
+		return err
+	}
+
+	lastSeen, err := state.ReadLastSeen(p.StateDir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	currentContext := kubeconfig.GetCurrentContext()
+	// only the contexts remaining in the kubeconfig are tracked
+	updatedLastSeen := make(map[string]time.Time, len(contextNames))
+
+	var pruned []string
+	var kept []string
+	for _, name := range contextNames {
+		if discoveredNames.Has(name) {
+			updatedLastSeen[name] = now
+			continue
+		}
+
+		seen, tracked := lastSeen[name]
+		if !tracked {
+			// the age of a context is unknown the first time it is not found in any store
+			seen = now
+		}
+
+		if name == currentContext || (p.MinAge > 0 && now.Sub(seen) < p.MinAge) {
+			updatedLastSeen[name] = seen
+			kept = append(kept, name)
+			continue
+		}
+
+		pruned = append(pruned, name)
+	}
+
+	for _, name := range pruned {
+		if err := kubeconfig.RemoveContext(name); err != nil {
+			return fmt.Errorf("failed to remove context %q: %v", name, err)
+		}
+	}
+
+	if len(pruned) > 0 {
+		if _, err := kubeconfig.WriteKubeconfigFile(); err != nil {
+			return fmt.Errorf("failed to write kubeconfig file: %v", err)
+		}
+	}
+
+	if err := state.WriteLastSeen(p.StateDir, updatedLastSeen); err != nil {
+		return err
+	}
+
+	p.printSummary(pruned, kept, currentContext, updatedLastSeen, now)
+	return nil
+}
+
+// discoverContextNames searches all stores and returns the names of all discovered contexts,
+// with and without the store prefix.
+// Returns an error if any store could not be searched.
+func (p *ContextPruner) discoverContextNames(stores []store.KubeconfigStore, config *types.Config) (sets.Set[string], error) {
+	// store errors must not be ignored, otherwise the contexts of a failing store would be removed
+	searchConfig := *config
+	searchConfig.IgnoreStoreErrors = ptr.To(false)
+	searchConfig.ExcludeContexts = nil
+
+	requiredStores := make([]store.KubeconfigStore, 0, len(stores))
+	for _, kubeconfigStore := range stores {
+		requiredStores = append(requiredStores, &unfilteredStore{KubeconfigStore: kubeconfigStore})
+	}
+
+	// always search the stores, the index might still contain deleted contexts.
+	// A kubeconfig that cannot be retrieved fails the search, otherwise its contexts would be removed.
+	c, err := pkg.DoSearch(requiredStores, &searchConfig, p.StateDir, true, pkg.WithKubeconfigErrors())
+	if err != nil {
+		return nil, fmt.Errorf("not pruning any contexts, failed to search the kubeconfig stores: %w", err)
+	}
+
+	kubeconfigPath, _ := filepath.Abs(p.KubeconfigPath)
+
+	var mError *multierror.Error
+	names := sets.New[string]()
+	for discoveredContext := range *c {
+		if discoveredContext.Error != nil {
+			mError = multierror.Append(mError, discoveredContext.Error)
+			continue
+		}
+
+		if discoveredContext.Store == nil {
+			continue
+		}
+
+		kubeconfigStore := *discoveredContext.Store
+		// the pruned kubeconfig itself might be searched by a filesystem store
+		if kubeconfigStore.GetKind() == types.StoreKindFilesystem {
+			if path, _ := filepath.Abs(discoveredContext.Path); path == kubeconfigPath {
+				continue
+			}
+		}
+
+		names.Insert(discoveredContext.Name)
+		if prefix := kubeconfigStore.GetContextPrefix(discoveredContext.Path); len(prefix) > 0 {
+			names.Insert(strings.TrimPrefix(discoveredContext.Name, fmt.Sprintf("%s/", prefix)))
+		}
+		if len(discoveredContext.Alias) > 0 {
+			names.Insert(discoveredContext.Alias)
+		}
+	}
+
+	if mError != nil {
+		return nil, fmt.Errorf("not pruning any contexts, not all kubeconfig stores could be searched: %w", mError.ErrorOrNil())
+	}
+	return names, nil
+}
+
+func (p *ContextPruner) printSummary(pruned, kept []string, currentContext string, lastSeen map[string]time.Time, now time.Time) {
+	if len(pruned) == 0 {
+		fmt.Printf("No contexts to prune in %s\n", p.KubeconfigPath)
+	} else {
+		verb := "Removed"
+		if kubeswitchio.IsDryRun() {
+			verb = "Would remove"
+		}
+
+		sort.Strings(pruned)
+		fmt.Printf("%s %d context(s) not found in any store from %s:\n", verb, len(pruned), p.KubeconfigPath)
+		for _, name := range pruned {
+			fmt.Printf("  - %s\n", name)
+		}
+	}
+
+	if len(kept) == 0 {
+		return
+	}
+
+	sort.Strings(kept)
+	fmt.Printf("Kept %d context(s) not found in any store:\n", len(kept))
+	for _, name := range kept {
+		if name == currentContext {
+			fmt.Printf("  - %s (current context)\n", name)
+			continue
+		}
+		fmt.Printf("  - %s (not seen for %s, pruned after %s)\n", name, now.Sub(lastSeen[name]).Round(time.Second), p.MinAge)
+	}
+}
+
+// unfilteredStore returns all contexts of the wrapped store and treats the store as required,
+// so that errors of the store are reported during the search.
+// Contexts excluded in the configuration still exist in the store and must not be removed.
+type unfilteredStore struct {
+	store.KubeconfigStore
+}
+
+func (s *unfilteredStore) GetStoreConfig() types.KubeconfigStore {
+	storeConfig := s.KubeconfigStore.GetStoreConfig()
+	storeConfig.Required = ptr.To(true)
+	storeConfig.ExcludeContexts = nil
+	storeConfig.RequiredTags = nil
+	storeConfig.ForbiddenTags = nil
+	return storeConfig
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prune_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/state"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store/testutil"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/prune"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// kubeconfig returns a kubeconfig with the given contexts, the first context is the current context
+func kubeconfig(contexts ...string) string {
+	data := fmt.Sprintf("apiVersion: v1\nkind: Config\ncurrent-context: %s\nclusters:\n- name: cluster\n  cluster:\n    server: https://cluster.example.com\nusers:\n- name: user\n  user:\n    token: token\ncontexts:\n", contexts[0])
+	for _, name := range contexts {
+		data += fmt.Sprintf("- name: %s\n  context:\n    cluster: cluster\n    user: user\n", name)
+	}
+	return data
+}
+
+// vaultStore finds the kubeconfigs at the configured paths.
+// Paths with an error fail to be retrieved.
+type vaultStore struct {
+	kubeconfigs map[string]string
+	errs        map[string]error
+}
+
+func (s *vaultStore) GetID() string                               { return "vault.default" }
+func (s *vaultStore) GetKind() types.StoreKind                    { return types.StoreKindVault }
+func (s *vaultStore) GetContextPrefix(string) string              { return "" }
+func (s *vaultStore) VerifyKubeconfigPaths(context.Context) error { return nil }
+func (s *vaultStore) Probe(context.Context) error                 { return nil }
+func (s *vaultStore) GetLogger() *logrus.Entry                    { return testutil.NewTestLogger() }
+func (s *vaultStore) GetStoreConfig() types.KubeconfigStore {
+	return types.KubeconfigStore{Kind: types.StoreKindVault}
+}
+func (s *vaultStore) Stop(context.Context) error { return nil }
+
+func (s *vaultStore) StartSearch(_ context.Context, channel chan store.SearchResult) {
+	var paths []string
+	for path := range s.kubeconfigs {
+		paths = append(paths, path)
+	}
+	for path := range s.errs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		channel <- testutil.FakeSearchResult(path)
+	}
+}
+
+func (s *vaultStore) GetKubeconfigForPath(_ context.Context, path string, _ map[string]string) ([]byte, error) {
+	if err, ok := s.errs[path]; ok {
+		return nil, err
+	}
+	return []byte(s.kubeconfigs[path]), nil
+}
+
+var _ = Describe("ContextPruner", func() {
+	var (
+		tempDir        string
+		kubeconfigPath string
+		kubeconfigs    *vaultStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-prune")
+		Expect(err).ToNot(HaveOccurred())
+		annotations.SetPath(filepath.Join(tempDir, "annotations.yaml"))
+
+		kubeconfigPath = filepath.Join(tempDir, "config")
+		Expect(os.WriteFile(kubeconfigPath, []byte(kubeconfig("current", "dev", "deleted")), 0600)).To(Succeed())
+
+		kubeconfigs = &vaultStore{
+			kubeconfigs: map[string]string{"secret/dev": kubeconfig("dev")},
+			errs:        map[string]error{},
+		}
+	})
+
+	AfterEach(func() {
+		kubeswitchio.SetWriter(kubeswitchio.FileWriter{})
+		annotations.SetPath(annotations.DefaultPath)
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	pruneContexts := func(minAge time.Duration) error {
+		return prune.NewContextPruner(kubeconfigPath, tempDir, minAge).Prune([]store.KubeconfigStore{kubeconfigs}, &types.Config{})
+	}
+
+	contexts := func() []string {
+		config, err := clientcmd.LoadFromFile(kubeconfigPath)
+		Expect(err).ToNot(HaveOccurred())
+		var names []string
+		for name := range config.Contexts {
+			names = append(names, name)
+		}
+		return names
+	}
+
+	It("should remove the contexts not found in any store except the current context", func() {
+		Expect(pruneContexts(0)).To(Succeed())
+		Expect(contexts()).To(ConsistOf("current", "dev"))
+	})
+
+	It("should not remove any context if a kubeconfig of a store cannot be retrieved", func() {
+		kubeconfigs.errs["secret/deleted"] = errors.New("permission denied")
+
+		err := pruneContexts(0)
+		Expect(err).To(MatchError(ContainSubstring("not all kubeconfig stores could be searched")))
+		Expect(err).To(MatchError(ContainSubstring(`store "vault.default" failed to get the kubeconfig with path "secret/deleted": permission denied`)))
+		Expect(contexts()).To(ConsistOf("current", "dev", "deleted"))
+	})
+
+	It("should remove the contexts if a store lists paths without a kubeconfig", func() {
+		kubeconfigs.errs["secret/other"] = &storeerrors.ErrKubeconfigNotFound{StoreID: "vault.default", Err: errors.New("key \"config\" not found")}
+
+		Expect(pruneContexts(0)).To(Succeed())
+		Expect(contexts()).To(ConsistOf("current", "dev"))
+	})
+
+	It("should only remove contexts not found for the minimum age", func() {
+		Expect(pruneContexts(time.Hour)).To(Succeed())
+		Expect(contexts()).To(ConsistOf("current", "dev", "deleted"))
+
+		lastSeen, err := state.ReadLastSeen(tempDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lastSeen).To(HaveKey("deleted"))
+		Expect(lastSeen).To(HaveKey("dev"))
+
+		lastSeen["deleted"] = time.Now().Add(-2 * time.Hour)
+		Expect(state.WriteLastSeen(tempDir, lastSeen)).To(Succeed())
+
+		Expect(pruneContexts(time.Hour)).To(Succeed())
+		Expect(contexts()).To(ConsistOf("current", "dev"))
+
+		lastSeen, err = state.ReadLastSeen(tempDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lastSeen).ToNot(HaveKey("deleted"))
+	})
+
+	It("should not write the kubeconfig in dry-run mode", func() {
+		writer := kubeswitchio.NewDryRunWriter(false, "")
+		kubeswitchio.SetWriter(writer)
+
+		Expect(pruneContexts(0)).To(Succeed())
+		Expect(contexts()).To(ConsistOf("current", "dev", "deleted"))
+		Expect(writer.Summary()).To(ContainElement(HavePrefix("Would write")))
+	})
+})
@@ -15,10 +15,12 @@
 package setcontext
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
 	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/audit"
 	"github.com/danielfoehrkn/kubeswitch/pkg/store"
 	historyutil "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/history/util"
 	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
@@ -58,7 +60,7 @@ func SetContext(desiredContext string, stores []store.KubeconfigStore, config *t
 
 		matchesContextWithoutPrefix := desiredContext == contextWithoutPrefix
 		if desiredContext == discoveredContext.Name || matchesContextWithoutPrefix || desiredContext == discoveredContext.Alias {
-			kubeconfigData, err := kubeconfigStore.GetKubeconfigForPath(discoveredContext.Path, discoveredContext.Tags)
+			kubeconfigData, err := kubeconfigStore.GetKubeconfigForPath(context.Background(), discoveredContext.Path, discoveredContext.Tags)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -81,6 +83,10 @@ func SetContext(desiredContext string, stores []store.KubeconfigStore, config *t
 				return nil, nil, err
 			}
 
+			if err := kubeconfig.UseCredentialPlugin(desiredContext); err != nil {
+				return nil, nil, err
+			}
+
 			tempKubeconfigPath, err := kubeconfig.WriteKubeconfigFile()
 			if err != nil {
 				return nil, nil, fmt.Errorf("failed to write temporary kubeconfig file: %v", err)
@@ -96,6 +102,10 @@ func SetContext(desiredContext string, stores []store.KubeconfigStore, config *t
 				if err := historyutil.AppendToHistory(desiredContext, ns); err != nil {
 					logger.Warnf("failed to append context to history file: %v", err)
 				}
+
+				if err := audit.Record(desiredContext, kubeconfigStore.GetID(), kubeconfigStore.GetKind()); err != nil {
+					logger.Warnf("failed to append context to audit log: %v", err)
+				}
 			}
 			return &tempKubeconfigPath, &desiredContext, nil
 		}
@@ -0,0 +1,86 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package share
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/share"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	setcontext "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/set-context"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// Share prints a signed URL referencing the given context that expires after the given duration.
+// The URL only contains the ID of the store and the name of the context, but no credentials.
+func Share(desiredContext string, ttl time.Duration, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	if ttl <= 0 {
+		return fmt.Errorf("the ttl must be positive")
+	}
+
+	secret, err := getSecret(config)
+	if err != nil {
+		return err
+	}
+
+	discoveredContext, err := pkg.FindContext(desiredContext, stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	url, err := share.NewURL(share.Reference{
+		StoreID: (*discoveredContext.Store).GetID(),
+		Context: discoveredContext.Name,
+		Expiry:  time.Now().Add(ttl).UTC().Truncate(time.Second),
+	}, secret)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(url)
+	return nil
+}
+
+// Open verifies the shared URL and switches to the referenced context.
+// The context is only resolved from the store referenced in the URL.
+func Open(url string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) (*string, *string, error) {
+	secret, err := getSecret(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reference, err := share.ParseURL(url, secret, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, s := range stores {
+		if s.GetID() == reference.StoreID {
+			return setcontext.SetContext(reference.Context, []store.KubeconfigStore{s}, config, stateDir, noIndex, true)
+		}
+	}
+	return nil, nil, &storeerrors.ErrStoreNotFound{StoreID: reference.StoreID, Err: fmt.Errorf("the store of the shared context %q is not configured. Please add a store with the ID %q to the switch configuration", reference.Context, reference.StoreID)}
+}
+
+func getSecret(config *types.Config) ([]byte, error) {
+	if config.ShareSecret == nil || len(os.ExpandEnv(*config.ShareSecret)) == 0 {
+		return nil, fmt.Errorf("no secret to sign shared URLs configured. Please set \"shareSecret\" in the switch configuration")
+	}
+	return []byte(os.ExpandEnv(*config.ShareSecret)), nil
+}
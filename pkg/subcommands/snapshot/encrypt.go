@@ -0,0 +1,155 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// encryptedPrefix marks an encrypted string field of a kubeconfig
+const encryptedPrefix = "age:"
+
+// sensitiveAuthProviderKeys are the keys of the auth provider configuration containing credentials
+var sensitiveAuthProviderKeys = []string{"id-token", "refresh-token", "client-secret", "access-token"}
+
+// ParseRecipient parses an age recipient ("age1...") or an SSH public key
+func ParseRecipient(recipient string) (age.Recipient, error) {
+	if strings.HasPrefix(recipient, "age1") {
+		return age.ParseX25519Recipient(recipient)
+	}
+	return agessh.ParseRecipient(recipient)
+}
+
+// ParseIdentityFile parses an age identity file or an unencrypted SSH private key
+func ParseIdentityFile(path string) ([]age.Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if identities, err := age.ParseIdentities(bytes.NewReader(data)); err == nil {
+		return identities, nil
+	}
+
+	identity, err := agessh.ParseIdentity(data)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither an age identity file nor an unencrypted SSH private key: %w", path, err)
+	}
+	return []age.Identity{identity}, nil
+}
+
+// encryptCredentials encrypts the credentials (tokens, passwords, client certificates and keys) of all users of the kubeconfig
+func encryptCredentials(kubeconfig *clientcmdapi.Config, recipient age.Recipient) error {
+	return transformCredentials(kubeconfig, func(data []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		writer, err := age.Encrypt(&buf, recipient)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}, false)
+}
+
+// decryptCredentials decrypts the credentials encrypted by encryptCredentials
+func decryptCredentials(kubeconfig *clientcmdapi.Config, identities []age.Identity) error {
+	return transformCredentials(kubeconfig, func(data []byte) ([]byte, error) {
+		reader, err := age.Decrypt(bytes.NewReader(data), identities...)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(reader)
+	}, true)
+}
+
+// transformCredentials applies the given encryption or decryption to every credential of the kubeconfig.
+// Encrypted string fields are base64 encoded and marked with the encryptedPrefix.
+func transformCredentials(kubeconfig *clientcmdapi.Config, transform func([]byte) ([]byte, error), decrypt bool) error {
+	transformBytes := func(data []byte) ([]byte, error) {
+		if len(data) == 0 {
+			return data, nil
+		}
+		return transform(data)
+	}
+
+	transformString := func(value string) (string, error) {
+		if len(value) == 0 {
+			return value, nil
+		}
+
+		if decrypt {
+			encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+			if !ok {
+				return "", fmt.Errorf("value is not encrypted")
+			}
+			data, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return "", err
+			}
+			transformed, err := transform(data)
+			return string(transformed), err
+		}
+
+		transformed, err := transform([]byte(value))
+		if err != nil {
+			return "", err
+		}
+		return encryptedPrefix + base64.StdEncoding.EncodeToString(transformed), nil
+	}
+
+	for name, authInfo := range kubeconfig.AuthInfos {
+		var err error
+		if authInfo.Token, err = transformString(authInfo.Token); err != nil {
+			return fmt.Errorf("token of user %q: %w", name, err)
+		}
+		if authInfo.Password, err = transformString(authInfo.Password); err != nil {
+			return fmt.Errorf("password of user %q: %w", name, err)
+		}
+		if authInfo.ClientCertificateData, err = transformBytes(authInfo.ClientCertificateData); err != nil {
+			return fmt.Errorf("client certificate of user %q: %w", name, err)
+		}
+		if authInfo.ClientKeyData, err = transformBytes(authInfo.ClientKeyData); err != nil {
+			return fmt.Errorf("client key of user %q: %w", name, err)
+		}
+
+		if authInfo.AuthProvider == nil {
+			continue
+		}
+		for _, key := range sensitiveAuthProviderKeys {
+			value, ok := authInfo.AuthProvider.Config[key]
+			if !ok {
+				continue
+			}
+			if authInfo.AuthProvider.Config[key], err = transformString(value); err != nil {
+				return fmt.Errorf("%s of the auth provider of user %q: %w", key, name, err)
+			}
+		}
+	}
+	return nil
+}
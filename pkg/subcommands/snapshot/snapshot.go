@@ -0,0 +1,380 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const (
+	// manifestFileName is the name of the manifest in the snapshot archive
+	manifestFileName = "manifest.json"
+	// kubeconfigDirectory is the directory of the kubeconfigs in the snapshot archive
+	kubeconfigDirectory = "kubeconfigs"
+)
+
+var logger = logrus.New()
+
+// Manifest describes the content of a snapshot
+type Manifest struct {
+	// CreatedAt is the time the snapshot has been created
+	CreatedAt time.Time `json:"createdAt"`
+	// Encrypted is true if the credentials in the kubeconfigs are encrypted with age
+	Encrypted bool `json:"encrypted"`
+	// Kubeconfigs contains one entry per kubeconfig in the snapshot
+	Kubeconfigs []ManifestEntry `json:"kubeconfigs"`
+}
+
+// ManifestEntry describes a kubeconfig in the snapshot
+type ManifestEntry struct {
+	// File is the path of the kubeconfig in the snapshot archive
+	File string `json:"file"`
+	// StoreID is the ID of the store the kubeconfig has been read from
+	StoreID string `json:"storeID"`
+	// Path is the path of the kubeconfig in the store
+	Path string `json:"path"`
+	// Contexts are the names of the contexts of the kubeconfig as shown by kubeswitch
+	Contexts []string `json:"contexts"`
+	// Tags are the tags of the kubeconfig attached by the store
+	Tags map[string]string `json:"tags,omitempty"`
+	// FetchedAt is the time the kubeconfig has been read from the store
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// CreateSnapshot reads the kubeconfigs of all contexts of all stores and writes them to a tar.gz archive together with a manifest.
+// If a recipient is given, the credentials in the kubeconfigs are encrypted with age.
+func CreateSnapshot(output string, recipient age.Recipient, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	c, err := pkg.DoSearch(stores, config, stateDir, noIndex)
+	if err != nil {
+		return fmt.Errorf("cannot search contexts: %v", err)
+	}
+
+	// multiple contexts can share the same kubeconfig
+	type kubeconfigKey struct{ storeID, path string }
+	var (
+		keys    []kubeconfigKey
+		entries = map[kubeconfigKey]*ManifestEntry{}
+		sources = map[kubeconfigKey]store.KubeconfigStore{}
+	)
+	for discoveredContext := range *c {
+		if discoveredContext.Error != nil {
+			logger.Warnf("error returned from search: %v", discoveredContext.Error)
+			continue
+		}
+
+		kubeconfigStore := *discoveredContext.Store
+		key := kubeconfigKey{storeID: kubeconfigStore.GetID(), path: discoveredContext.Path}
+		if entry, ok := entries[key]; ok {
+			entry.Contexts = append(entry.Contexts, discoveredContext.Name)
+			continue
+		}
+
+		keys = append(keys, key)
+		sources[key] = kubeconfigStore
+		entries[key] = &ManifestEntry{
+			StoreID:  key.storeID,
+			Path:     key.path,
+			Contexts: []string{discoveredContext.Name},
+			Tags:     discoveredContext.Tags,
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].storeID != keys[j].storeID {
+			return keys[i].storeID < keys[j].storeID
+		}
+		return keys[i].path < keys[j].path
+	})
+
+	archive := bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	manifest := Manifest{
+		CreatedAt: time.Now().UTC(),
+		Encrypted: recipient != nil,
+	}
+
+	for i, key := range keys {
+		entry := entries[key]
+		sort.Strings(entry.Contexts)
+
+		data, err := sources[key].GetKubeconfigForPath(context.Background(), key.path, entry.Tags)
+		if err != nil {
+			logger.Warnf("skipping kubeconfig %q of store %q: %v", key.path, key.storeID, err)
+			continue
+		}
+		entry.FetchedAt = time.Now().UTC()
+
+		if recipient != nil {
+			kubeconfig, err := clientcmd.Load(data)
+			if err != nil {
+				return fmt.Errorf("failed to parse kubeconfig %q of store %q: %w", key.path, key.storeID, err)
+			}
+			if err := encryptCredentials(kubeconfig, recipient); err != nil {
+				return fmt.Errorf("failed to encrypt kubeconfig %q of store %q: %w", key.path, key.storeID, err)
+			}
+			if data, err = clientcmd.Write(*kubeconfig); err != nil {
+				return err
+			}
+		}
+
+		entry.File = path.Join(kubeconfigDirectory, fmt.Sprintf("%04d-%s.yaml", i, util.SanitizeContextName(key.storeID)))
+		if err := writeFile(tarWriter, entry.File, data, entry.FetchedAt); err != nil {
+			return err
+		}
+		manifest.Kubeconfigs = append(manifest.Kubeconfigs, *entry)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(tarWriter, manifestFileName, manifestData, manifest.CreatedAt); err != nil {
+		return err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+
+	if err := kubeswitchio.WriteFile(output, archive.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to create snapshot %q: %w", output, err)
+	}
+
+	fmt.Printf("Wrote %d kubeconfig(s) to snapshot %q\n", len(manifest.Kubeconfigs), output)
+	return nil
+}
+
+// writeFile adds a file to the tar archive
+func writeFile(tarWriter *tar.Writer, name string, data []byte, modTime time.Time) error {
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}); err != nil {
+		return fmt.Errorf("failed to write %q to snapshot: %w", name, err)
+	}
+	if _, err := tarWriter.Write(data); err != nil {
+		return fmt.Errorf("failed to write %q to snapshot: %w", name, err)
+	}
+	return nil
+}
+
+// RestoreSnapshot writes the kubeconfigs of the snapshot to the default output directory of the first filesystem store
+// that has a kubeconfig directory configured. Every kubeconfig is written to its own directory.
+// The identities are required to decrypt the credentials of an encrypted snapshot.
+func RestoreSnapshot(from string, identities []age.Identity, stores []store.KubeconfigStore) error {
+	manifest, files, err := readSnapshot(from)
+	if err != nil {
+		return err
+	}
+
+	if manifest.Encrypted && len(identities) == 0 {
+		return fmt.Errorf("the credentials of snapshot %q are encrypted. Please provide the identity to decrypt them", from)
+	}
+
+	filesystemStore, outputDirectory, err := getOutputDirectory(stores)
+	if err != nil {
+		return err
+	}
+
+	if strings.ContainsAny(filesystemStore.KubeconfigName, "*?[") {
+		return fmt.Errorf("cannot derive the kubeconfig file name from the pattern %q of the filesystem store %q", filesystemStore.KubeconfigName, filesystemStore.GetID())
+	}
+
+	// all kubeconfigs are validated before the first one is written, so that a broken snapshot is not restored partially
+	kubeconfigs := make([]restoredKubeconfig, 0, len(manifest.Kubeconfigs))
+	paths := map[string]struct{}{}
+	for _, entry := range manifest.Kubeconfigs {
+		data, ok := files[entry.File]
+		if !ok {
+			return fmt.Errorf("kubeconfig %q listed in the manifest is missing in snapshot %q", entry.File, from)
+		}
+
+		if manifest.Encrypted {
+			kubeconfig, err := clientcmd.Load(data)
+			if err != nil {
+				return fmt.Errorf("failed to parse kubeconfig %q: %w", entry.File, err)
+			}
+			if err := decryptCredentials(kubeconfig, identities); err != nil {
+				return fmt.Errorf("failed to decrypt kubeconfig %q: %w", entry.File, err)
+			}
+			if data, err = clientcmd.Write(*kubeconfig); err != nil {
+				return err
+			}
+		}
+
+		// the filesystem store only finds kubeconfig files with the configured name, hence every kubeconfig is written to its own directory
+		kubeconfigPath := filepath.Join(outputDirectory, strings.TrimSuffix(path.Base(entry.File), ".yaml"), filesystemStore.KubeconfigName)
+		if _, err := os.Stat(kubeconfigPath); err == nil {
+			return fmt.Errorf("kubeconfig %q already exists", kubeconfigPath)
+		}
+		if _, ok := paths[kubeconfigPath]; ok {
+			return fmt.Errorf("kubeconfig %q is listed more than once in the manifest of snapshot %q", entry.File, from)
+		}
+		paths[kubeconfigPath] = struct{}{}
+
+		kubeconfigs = append(kubeconfigs, restoredKubeconfig{entry: entry, path: kubeconfigPath, data: data})
+	}
+
+	if err := writeKubeconfigs(kubeconfigs); err != nil {
+		return err
+	}
+
+	for _, kubeconfig := range kubeconfigs {
+		fmt.Printf("Restored %s from store %q to %q\n", strings.Join(kubeconfig.entry.Contexts, ", "), kubeconfig.entry.StoreID, kubeconfig.path)
+	}
+	return nil
+}
+
+// restoredKubeconfig is a kubeconfig of the snapshot together with the path it is restored to
+type restoredKubeconfig struct {
+	entry ManifestEntry
+	path  string
+	data  []byte
+}
+
+// writeKubeconfigs writes the kubeconfigs. If a kubeconfig cannot be written, the already written kubeconfigs
+// and the directories created for them are removed again.
+func writeKubeconfigs(kubeconfigs []restoredKubeconfig) (err error) {
+	var created []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(created) - 1; i >= 0; i-- {
+			if removeErr := kubeswitchio.Remove(created[i]); removeErr != nil {
+				logger.Warnf("failed to remove %q of the partially restored snapshot: %v", created[i], removeErr)
+			}
+		}
+	}()
+
+	for _, kubeconfig := range kubeconfigs {
+		directory := filepath.Dir(kubeconfig.path)
+		if _, statErr := os.Stat(directory); os.IsNotExist(statErr) {
+			if err := kubeswitchio.MkdirAll(directory, 0700); err != nil {
+				return err
+			}
+			created = append(created, directory)
+		}
+
+		if err := kubeswitchio.WriteFile(kubeconfig.path, kubeconfig.data, 0600); err != nil {
+			return fmt.Errorf("failed to write kubeconfig to %q: %w", kubeconfig.path, err)
+		}
+		created = append(created, kubeconfig.path)
+	}
+	return nil
+}
+
+// readSnapshot reads the manifest and all files of the snapshot archive
+func readSnapshot(from string) (*Manifest, map[string][]byte, error) {
+	file, err := os.Open(from)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open snapshot %q: %w", from, err)
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read snapshot %q: %w", from, err)
+	}
+
+	files := map[string][]byte{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read snapshot %q: %w", from, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %q from snapshot %q: %w", header.Name, from, err)
+		}
+		files[header.Name] = data
+	}
+
+	manifestData, ok := files[manifestFileName]
+	if !ok {
+		return nil, nil, fmt.Errorf("snapshot %q does not contain a %s", from, manifestFileName)
+	}
+
+	manifest := &Manifest{}
+	if err := json.Unmarshal(manifestData, manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the manifest of snapshot %q: %w", from, err)
+	}
+	return manifest, files, nil
+}
+
+// getOutputDirectory returns the first filesystem store with a kubeconfig directory together with the directory
+func getOutputDirectory(stores []store.KubeconfigStore) (*store.FilesystemStore, string, error) {
+	var lastErr error
+	for _, s := range stores {
+		filesystemStore, ok := store.Unwrap(s).(*store.FilesystemStore)
+		if !ok {
+			continue
+		}
+
+		if err := store.CheckWritable(s); err != nil {
+			lastErr = err
+			continue
+		}
+
+		directory, err := filesystemStore.GetDefaultOutputDirectory()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return filesystemStore, directory, nil
+	}
+
+	if lastErr != nil {
+		return nil, "", fmt.Errorf("no filesystem store to restore the snapshot to: %w", lastErr)
+	}
+	return nil, "", fmt.Errorf("no filesystem store to restore the snapshot to. Please configure a filesystem store with a kubeconfig directory")
+}
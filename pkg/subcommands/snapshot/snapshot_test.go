@@ -0,0 +1,179 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snapshot_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/subcommands/snapshot"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: dev
+users:
+- name: dev
+  user:
+    token: dev-token
+`
+
+var _ = Describe("RestoreSnapshot", func() {
+	var (
+		tempDir        string
+		kubeconfigsDir string
+		snapshotPath   string
+		stores         []store.KubeconfigStore
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-snapshot")
+		Expect(err).ToNot(HaveOccurred())
+
+		kubeconfigsDir = filepath.Join(tempDir, "kubeconfigs")
+		Expect(os.MkdirAll(kubeconfigsDir, 0700)).To(Succeed())
+		snapshotPath = filepath.Join(tempDir, "snapshot.tar.gz")
+
+		filesystemStore, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{kubeconfigsDir},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		stores = []store.KubeconfigStore{filesystemStore}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	// writeSnapshot writes a snapshot with the given manifest and files
+	writeSnapshot := func(manifest snapshot.Manifest, files map[string]string) {
+		manifestData, err := json.Marshal(manifest)
+		Expect(err).ToNot(HaveOccurred())
+
+		file, err := os.Create(snapshotPath)
+		Expect(err).ToNot(HaveOccurred())
+		defer file.Close()
+		gzipWriter := gzip.NewWriter(file)
+		tarWriter := tar.NewWriter(gzipWriter)
+
+		files["manifest.json"] = string(manifestData)
+		for name, data := range files {
+			Expect(tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data))})).To(Succeed())
+			_, err := tarWriter.Write([]byte(data))
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(tarWriter.Close()).To(Succeed())
+		Expect(gzipWriter.Close()).To(Succeed())
+	}
+
+	entries := func(files ...string) snapshot.Manifest {
+		manifest := snapshot.Manifest{}
+		for _, file := range files {
+			manifest.Kubeconfigs = append(manifest.Kubeconfigs, snapshot.ManifestEntry{File: file, StoreID: "vault.default", Contexts: []string{"dev"}})
+		}
+		return manifest
+	}
+
+	restoredDirectories := func() []string {
+		dirEntries, err := os.ReadDir(kubeconfigsDir)
+		Expect(err).ToNot(HaveOccurred())
+		var names []string
+		for _, entry := range dirEntries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	It("should write every kubeconfig to its own directory", func() {
+		writeSnapshot(entries("kubeconfigs/0000-a.yaml", "kubeconfigs/0001-b.yaml"), map[string]string{
+			"kubeconfigs/0000-a.yaml": kubeconfig,
+			"kubeconfigs/0001-b.yaml": kubeconfig,
+		})
+
+		Expect(snapshot.RestoreSnapshot(snapshotPath, nil, stores)).To(Succeed())
+		Expect(filepath.Join(kubeconfigsDir, "0000-a", "config")).To(BeAnExistingFile())
+		Expect(filepath.Join(kubeconfigsDir, "0001-b", "config")).To(BeAnExistingFile())
+	})
+
+	It("should not write any kubeconfig if a kubeconfig is missing in the snapshot", func() {
+		writeSnapshot(entries("kubeconfigs/0000-a.yaml", "kubeconfigs/0001-b.yaml"), map[string]string{
+			"kubeconfigs/0000-a.yaml": kubeconfig,
+		})
+
+		Expect(snapshot.RestoreSnapshot(snapshotPath, nil, stores)).To(MatchError(ContainSubstring(`kubeconfig "kubeconfigs/0001-b.yaml" listed in the manifest is missing`)))
+		Expect(restoredDirectories()).To(BeEmpty())
+	})
+
+	It("should not write any kubeconfig if a kubeconfig already exists", func() {
+		Expect(os.MkdirAll(filepath.Join(kubeconfigsDir, "0001-b"), 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(kubeconfigsDir, "0001-b", "config"), []byte("existing"), 0600)).To(Succeed())
+		writeSnapshot(entries("kubeconfigs/0000-a.yaml", "kubeconfigs/0001-b.yaml"), map[string]string{
+			"kubeconfigs/0000-a.yaml": kubeconfig,
+			"kubeconfigs/0001-b.yaml": kubeconfig,
+		})
+
+		Expect(snapshot.RestoreSnapshot(snapshotPath, nil, stores)).To(MatchError(ContainSubstring("already exists")))
+		Expect(restoredDirectories()).To(ConsistOf("0001-b"))
+		Expect(os.ReadFile(filepath.Join(kubeconfigsDir, "0001-b", "config"))).To(BeEquivalentTo("existing"))
+	})
+
+	It("should not write any kubeconfig if a kubeconfig is listed twice", func() {
+		writeSnapshot(entries("kubeconfigs/0000-a.yaml", "kubeconfigs/0000-a.yaml"), map[string]string{
+			"kubeconfigs/0000-a.yaml": kubeconfig,
+		})
+
+		Expect(snapshot.RestoreSnapshot(snapshotPath, nil, stores)).To(MatchError(ContainSubstring("is listed more than once")))
+		Expect(restoredDirectories()).To(BeEmpty())
+	})
+
+	It("should remove the restored kubeconfigs if a kubeconfig cannot be written", func() {
+		// the directory of the second kubeconfig cannot be created
+		Expect(os.WriteFile(filepath.Join(kubeconfigsDir, "0001-b"), []byte("file"), 0600)).To(Succeed())
+		writeSnapshot(entries("kubeconfigs/0000-a.yaml", "kubeconfigs/0001-b.yaml"), map[string]string{
+			"kubeconfigs/0000-a.yaml": kubeconfig,
+			"kubeconfigs/0001-b.yaml": kubeconfig,
+		})
+
+		Expect(snapshot.RestoreSnapshot(snapshotPath, nil, stores)).ToNot(Succeed())
+		Expect(restoredDirectories()).To(ConsistOf("0001-b"))
+	})
+
+	It("should require an identity to restore an encrypted snapshot", func() {
+		manifest := entries("kubeconfigs/0000-a.yaml")
+		manifest.Encrypted = true
+		writeSnapshot(manifest, map[string]string{"kubeconfigs/0000-a.yaml": kubeconfig})
+
+		Expect(snapshot.RestoreSnapshot(snapshotPath, nil, stores)).To(MatchError(ContainSubstring("Please provide the identity to decrypt them")))
+		Expect(restoredDirectories()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,384 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/index"
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	storeerrors "github.com/danielfoehrkn/kubeswitch/pkg/store/errors"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// fileExtension is the extension of the synchronized kubeconfig files
+const fileExtension = ".yaml"
+
+var logger = logrus.New()
+
+// Options configures the synchronization
+type Options struct {
+	// FromStore is the ID of the store the contexts are synchronized from
+	FromStore string
+	// ToStore is the ID of the filesystem store the contexts are synchronized to
+	ToStore string
+	// Concurrency is the maximum number of kubeconfigs fetched in parallel
+	Concurrency int
+	// DeleteStale removes the files of contexts which no longer exist in the source store
+	DeleteStale bool
+}
+
+// fetch is the kubeconfig of the source store fetched for one or more contexts
+type fetch struct {
+	discoveredContext pkg.DiscoveredContext
+	// contexts maps the full context names to the context names in the kubeconfig
+	contexts   map[string]string
+	kubeconfig []byte
+	err        error
+}
+
+// Sync writes every context of the source store as standalone kubeconfig file <sanitized-context-name>.yaml
+// to the directory of the source store in the first kubeconfig directory of the target filesystem store.
+// Files are only written if their content changed, so the synchronization can be repeated safely.
+// If DeleteStale is set, files of contexts no longer found in the source store are removed.
+// Contexts whose sanitized names collide are not written and reported as failed.
+func Sync(options Options, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	if options.Concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	sourceStore, err := getStore(options.FromStore, stores)
+	if err != nil {
+		return err
+	}
+	targetStore, err := getStore(options.ToStore, stores)
+	if err != nil {
+		return err
+	}
+	if sourceStore.GetID() == targetStore.GetID() {
+		return fmt.Errorf("the source and target store must differ")
+	}
+
+	filesystemStore, ok := store.Unwrap(targetStore).(*store.FilesystemStore)
+	if !ok {
+		return fmt.Errorf("the target store %q must be a filesystem store", targetStore.GetID())
+	}
+	if err := store.CheckWritable(targetStore); err != nil {
+		return err
+	}
+	if matched, _ := filepath.Match(filesystemStore.KubeconfigName, "context"+fileExtension); !matched {
+		logger.Warnf("the kubeconfig name %q of store %q does not match the synchronized %q files. Use e.g. \"*%s\" to find the synchronized contexts", filesystemStore.KubeconfigName, targetStore.GetID(), fileExtension, fileExtension)
+	}
+
+	outputDirectory, err := filesystemStore.GetDefaultOutputDirectory()
+	if err != nil {
+		return err
+	}
+	// the directory is named after the source store, which is also the prefix of the synchronized contexts
+	directory := filepath.Join(outputDirectory, util.SanitizeContextName(sourceStore.GetID()))
+
+	fetches, searchErr, err := resolveContexts(sourceStore, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Synchronizing the kubeconfigs of %d context(s) from store %q to %q\n", countContexts(fetches), sourceStore.GetID(), directory)
+	fetchKubeconfigs(fetches, options.Concurrency)
+
+	if err := kubeswitchio.MkdirAll(directory, 0700); err != nil {
+		return fmt.Errorf("failed to create directory %q: %w", directory, err)
+	}
+
+	var (
+		failures                  []string
+		added, updated, unchanged int
+		synchronizedFiles         = map[string]struct{}{}
+		collisions                = findCollisions(fetches)
+	)
+	for _, f := range fetches {
+		for _, fullName := range sortedKeys(f.contexts) {
+			fileName := util.SanitizeContextName(fullName)
+			path := filepath.Join(directory, fileName+fileExtension)
+			synchronizedFiles[path] = struct{}{}
+
+			if names, ok := collisions[fileName]; ok {
+				// writing either context would overwrite the other one
+				fmt.Printf("✗ %s: the file name %q is shared by the contexts %s\n", fullName, fileName+fileExtension, strings.Join(names, ", "))
+				failures = append(failures, fullName)
+				continue
+			}
+
+			result, err := writeContext(f, f.contexts[fullName], path)
+			if err != nil {
+				fmt.Printf("✗ %s: %v\n", fullName, err)
+				failures = append(failures, fullName)
+				continue
+			}
+
+			switch result {
+			case resultAdded:
+				added++
+				fmt.Printf("+ %s\n", fullName)
+			case resultUpdated:
+				updated++
+				fmt.Printf("~ %s\n", fullName)
+			default:
+				unchanged++
+			}
+		}
+	}
+
+	removed := 0
+	if options.DeleteStale {
+		// a failed search could report existing contexts as removed
+		if searchErr != nil || len(failures) > 0 {
+			logger.Warnf("not removing stale contexts as not all contexts of store %q could be synchronized", sourceStore.GetID())
+		} else {
+			if removed, err = deleteStaleFiles(directory, synchronizedFiles); err != nil {
+				return err
+			}
+		}
+	}
+
+	if added > 0 || updated > 0 || removed > 0 {
+		index.Invalidate(targetStore.GetLogger(), targetStore.GetKind(), stateDir, targetStore.GetID())
+	}
+
+	fmt.Printf("%d added, %d updated, %d unchanged, %d removed, %d failed\n", added, updated, unchanged, removed, len(failures))
+
+	if searchErr != nil {
+		return fmt.Errorf("failed to search store %q: %w", sourceStore.GetID(), searchErr)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to synchronize %d context(s): %s", len(failures), strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// getStore returns the store with the given ID
+func getStore(storeID string, stores []store.KubeconfigStore) (store.KubeconfigStore, error) {
+	ids := make([]string, 0, len(stores))
+	for _, s := range stores {
+		if s.GetID() == storeID {
+			return s, nil
+		}
+		ids = append(ids, s.GetID())
+	}
+	return nil, &storeerrors.ErrStoreNotFound{StoreID: storeID, Err: fmt.Errorf("configured stores: %s", strings.Join(ids, ", "))}
+}
+
+// resolveContexts searches the source store for its contexts. Contexts sharing a kubeconfig are fetched once.
+// Returns the errors of the search together with the contexts that were found.
+func resolveContexts(sourceStore store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) ([]*fetch, error, error) {
+	c, err := pkg.DoSearch([]store.KubeconfigStore{sourceStore}, config, stateDir, noIndex)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		mError    *multierror.Error
+		fetches   []*fetch
+		fetchByID = map[string]*fetch{}
+	)
+	for discoveredContext := range *c {
+		if discoveredContext.Error != nil {
+			mError = multierror.Append(mError, discoveredContext.Error)
+			continue
+		}
+		if discoveredContext.Store == nil {
+			continue
+		}
+
+		f, ok := fetchByID[discoveredContext.Path]
+		if !ok {
+			f = &fetch{discoveredContext: discoveredContext, contexts: map[string]string{}}
+			fetchByID[discoveredContext.Path] = f
+			fetches = append(fetches, f)
+		}
+
+		contextName := pkg.KubeconfigContextName(sourceStore, discoveredContext)
+		f.contexts[discoveredContext.Name] = contextName
+	}
+	return fetches, mError.ErrorOrNil(), nil
+}
+
+// fetchKubeconfigs retrieves the kubeconfigs in parallel from the source store and shows the progress
+func fetchKubeconfigs(fetches []*fetch, concurrency int) {
+	var (
+		wg        sync.WaitGroup
+		mutex     sync.Mutex
+		completed int
+		semaphore = make(chan struct{}, concurrency)
+	)
+
+	printProgress(0, len(fetches))
+	for _, f := range fetches {
+		wg.Add(1)
+		go func(f *fetch) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			kubeconfigStore := *f.discoveredContext.Store
+			logger.Debugf("Fetching kubeconfig %q of store %q", f.discoveredContext.Path, kubeconfigStore.GetID())
+			f.kubeconfig, f.err = kubeconfigStore.GetKubeconfigForPath(context.Background(), f.discoveredContext.Path, f.discoveredContext.Tags)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			completed++
+			printProgress(completed, len(fetches))
+		}(f)
+	}
+	wg.Wait()
+	fmt.Fprintln(os.Stderr)
+}
+
+// printProgress prints the number of fetched kubeconfigs to stderr, overwriting the previous count
+func printProgress(completed, total int) {
+	fmt.Fprintf(os.Stderr, "\rFetched %d/%d kubeconfig(s)", completed, total)
+}
+
+// writeResult is the outcome of writing the kubeconfig of a single context
+type writeResult int
+
+const (
+	resultUnchanged writeResult = iota
+	resultAdded
+	resultUpdated
+)
+
+// writeContext writes the standalone kubeconfig of the context to the path, if its content changed
+func writeContext(f *fetch, contextName, path string) (writeResult, error) {
+	if f.err != nil {
+		return resultUnchanged, f.err
+	}
+
+	kubeconfig, err := clientcmd.Load(f.kubeconfig)
+	if err != nil {
+		return resultUnchanged, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	if _, ok := kubeconfig.Contexts[contextName]; !ok {
+		return resultUnchanged, fmt.Errorf("context %q not found in kubeconfig", contextName)
+	}
+
+	// only keep the context with its cluster and user
+	kubeconfig.CurrentContext = contextName
+	if err := clientcmdapi.MinifyConfig(kubeconfig); err != nil {
+		return resultUnchanged, fmt.Errorf("failed to extract context from kubeconfig: %w", err)
+	}
+
+	data, err := clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return resultUnchanged, err
+	}
+
+	result := resultAdded
+	existing, err := os.ReadFile(path)
+	if err == nil {
+		if bytes.Equal(existing, data) {
+			return resultUnchanged, nil
+		}
+		result = resultUpdated
+	}
+
+	if err := kubeswitchio.WriteFile(path, data, 0600); err != nil {
+		return resultUnchanged, fmt.Errorf("failed to write kubeconfig to %q: %w", path, err)
+	}
+	return result, nil
+}
+
+// deleteStaleFiles removes the kubeconfig files in the directory which have not been synchronized
+func deleteStaleFiles(directory string, synchronizedFiles map[string]struct{}) (int, error) {
+	entries, err := os.ReadDir(directory)
+	if os.IsNotExist(err) {
+		// the directory has not been created in dry-run mode
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read directory %q: %w", directory, err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != fileExtension {
+			continue
+		}
+
+		path := filepath.Join(directory, entry.Name())
+		if _, ok := synchronizedFiles[path]; ok {
+			continue
+		}
+
+		if err := kubeswitchio.Remove(path); err != nil {
+			return removed, fmt.Errorf("failed to remove stale kubeconfig %q: %w", path, err)
+		}
+		fmt.Printf("- %s\n", strings.TrimSuffix(entry.Name(), fileExtension))
+		removed++
+	}
+	return removed, nil
+}
+
+// findCollisions returns the sanitized names shared by more than one context, mapped to the sorted names of these contexts
+func findCollisions(fetches []*fetch) map[string][]string {
+	contextsByName := map[string][]string{}
+	for _, f := range fetches {
+		for fullName := range f.contexts {
+			fileName := util.SanitizeContextName(fullName)
+			contextsByName[fileName] = append(contextsByName[fileName], fullName)
+		}
+	}
+
+	collisions := map[string][]string{}
+	for fileName, names := range contextsByName {
+		if len(names) > 1 {
+			sort.Strings(names)
+			collisions[fileName] = names
+		}
+	}
+	return collisions
+}
+
+// countContexts returns the number of contexts of all fetches
+func countContexts(fetches []*fetch) int {
+	count := 0
+	for _, f := range fetches {
+		count += len(f.contexts)
+	}
+	return count
+}
+
+// sortedKeys returns the keys of the map in lexical order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
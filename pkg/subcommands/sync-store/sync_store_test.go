@@ -0,0 +1,225 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncstore_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/ptr"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	syncstore "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/sync-store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+- name: prod:eu
+  context:
+    cluster: prod
+    user: admin
+users:
+- name: admin
+  user:
+    token: admin-token
+`
+
+var _ = Describe("Sync", func() {
+	var (
+		tempDir         string
+		sourceDir       string
+		targetDir       string
+		stores          []store.KubeconfigStore
+		options         syncstore.Options
+		outputDirectory string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-sync-store")
+		Expect(err).ToNot(HaveOccurred())
+
+		sourceDir = filepath.Join(tempDir, "source")
+		targetDir = filepath.Join(tempDir, "target")
+		Expect(os.MkdirAll(filepath.Join(sourceDir, "team"), 0700)).To(Succeed())
+		Expect(os.MkdirAll(targetDir, 0700)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(sourceDir, "team", "config"), []byte(kubeconfig), 0600)).To(Succeed())
+
+		sourceStore, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{sourceDir},
+			ID:    ptr.To("source"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		targetStore, err := store.NewFilesystemStore("*.yaml", types.KubeconfigStore{
+			Kind:  types.StoreKindFilesystem,
+			Paths: []string{targetDir},
+			ID:    ptr.To("target"),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		stores = []store.KubeconfigStore{sourceStore, targetStore}
+
+		options = syncstore.Options{FromStore: sourceStore.GetID(), ToStore: targetStore.GetID(), Concurrency: 2}
+		outputDirectory = filepath.Join(targetDir, sourceStore.GetID())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	sync := func() error {
+		return syncstore.Sync(options, stores, &types.Config{}, tempDir, true)
+	}
+
+	synchronizedFiles := func() []string {
+		entries, err := os.ReadDir(outputDirectory)
+		Expect(err).ToNot(HaveOccurred())
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	It("should write every context to a file named after the sanitized context name", func() {
+		Expect(sync()).To(Succeed())
+		Expect(synchronizedFiles()).To(ConsistOf("team-dev.yaml", "team-prod-eu.yaml"))
+
+		data, err := os.ReadFile(filepath.Join(outputDirectory, "team-prod-eu.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		synchronized, err := clientcmd.Load(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(synchronized.CurrentContext).To(Equal("prod:eu"))
+		Expect(synchronized.Contexts).To(HaveLen(1))
+		Expect(synchronized.Clusters).To(HaveLen(1))
+		Expect(synchronized.Clusters).To(HaveKey("prod"))
+	})
+
+	It("should not rewrite unchanged files when synchronizing again", func() {
+		Expect(sync()).To(Succeed())
+
+		path := filepath.Join(outputDirectory, "team-dev.yaml")
+		past := time.Now().Add(-time.Hour).Truncate(time.Second)
+		Expect(os.Chtimes(path, past, past)).To(Succeed())
+
+		Expect(sync()).To(Succeed())
+		Expect(synchronizedFiles()).To(ConsistOf("team-dev.yaml", "team-prod-eu.yaml"))
+		info, err := os.Stat(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.ModTime()).To(BeTemporally("==", past))
+	})
+
+	It("should update the file of a changed context", func() {
+		Expect(sync()).To(Succeed())
+
+		changed, err := clientcmd.Load([]byte(kubeconfig))
+		Expect(err).ToNot(HaveOccurred())
+		changed.Contexts["dev"].Namespace = "monitoring"
+		Expect(clientcmd.WriteToFile(*changed, filepath.Join(sourceDir, "team", "config"))).To(Succeed())
+
+		Expect(sync()).To(Succeed())
+		data, err := os.ReadFile(filepath.Join(outputDirectory, "team-dev.yaml"))
+		Expect(err).ToNot(HaveOccurred())
+		synchronized, err := clientcmd.Load(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(synchronized.Contexts["dev"].Namespace).To(Equal("monitoring"))
+	})
+
+	Describe("stale contexts", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(outputDirectory, 0700)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(outputDirectory, "team-removed.yaml"), []byte(kubeconfig), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(outputDirectory, "notes.txt"), []byte("notes"), 0600)).To(Succeed())
+		})
+
+		It("should keep the files of stale contexts by default", func() {
+			Expect(sync()).To(Succeed())
+			Expect(synchronizedFiles()).To(ConsistOf("team-dev.yaml", "team-prod-eu.yaml", "team-removed.yaml", "notes.txt"))
+		})
+
+		It("should only remove the synchronized files of stale contexts", func() {
+			options.DeleteStale = true
+			Expect(sync()).To(Succeed())
+			Expect(synchronizedFiles()).To(ConsistOf("team-dev.yaml", "team-prod-eu.yaml", "notes.txt"))
+		})
+
+		It("should not remove stale contexts if a context could not be synchronized", func() {
+			Expect(os.MkdirAll(filepath.Join(sourceDir, "broken"), 0700)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "broken", "config"), []byte(`apiVersion: v1
+kind: Config
+contexts:
+- name: dev
+  context:
+    cluster: missing
+`), 0600)).To(Succeed())
+
+			options.DeleteStale = true
+			Expect(sync()).To(MatchError(ContainSubstring("failed to synchronize 1 context(s): broken/dev")))
+			Expect(synchronizedFiles()).To(ContainElement("team-removed.yaml"))
+		})
+	})
+
+	Describe("name collisions", func() {
+		BeforeEach(func() {
+			Expect(os.MkdirAll(filepath.Join(sourceDir, "team-prod"), 0700)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(sourceDir, "team-prod", "config"), []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://other.example.com
+contexts:
+- name: eu
+  context:
+    cluster: prod
+`), 0600)).To(Succeed())
+		})
+
+		It("should not write contexts whose sanitized names collide", func() {
+			err := sync()
+			Expect(err).To(MatchError(ContainSubstring("failed to synchronize 2 context(s)")))
+			Expect(err).To(MatchError(ContainSubstring("team-prod/eu")))
+			Expect(err).To(MatchError(ContainSubstring("team/prod:eu")))
+			Expect(synchronizedFiles()).To(ConsistOf("team-dev.yaml"))
+		})
+
+		It("should not remove the previously synchronized file of a colliding context", func() {
+			Expect(os.MkdirAll(outputDirectory, 0700)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(outputDirectory, "team-prod-eu.yaml"), []byte(kubeconfig), 0600)).To(Succeed())
+
+			options.DeleteStale = true
+			Expect(sync()).ToNot(Succeed())
+			Expect(synchronizedFiles()).To(ConsistOf("team-dev.yaml", "team-prod-eu.yaml"))
+		})
+	})
+})
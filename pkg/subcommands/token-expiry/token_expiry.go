@@ -0,0 +1,261 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenexpiry
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	gettoken "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/get-token"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+
+	// TypeJWT is the type of JSON Web Tokens
+	TypeJWT = "JWT"
+	// TypeToken is the type of opaque bearer tokens
+	TypeToken = "token"
+	// TypeCertificate is the type of client certificates
+	TypeCertificate = "cert"
+)
+
+// Credential is a credential of the user of a context
+type Credential struct {
+	// Type is the type of the credential: JWT, token or cert
+	Type string `json:"type"`
+	// Source describes where the credential is configured, e.g. "exec plugin aws"
+	Source string `json:"source"`
+	// Expiry is the time the credential expires. Nil if the expiry is unknown or the credential does not expire.
+	Expiry *time.Time `json:"expiry,omitempty"`
+	// ExpiresInSeconds is the number of seconds until the credential expires. Negative if the credential is expired.
+	ExpiresInSeconds *int64 `json:"expiresInSeconds,omitempty"`
+}
+
+// Report contains the credentials of the user of a context and their expiry
+type Report struct {
+	Context     string       `json:"context"`
+	Credentials []Credential `json:"credentials"`
+}
+
+// ErrExpiresSoon is returned if a credential expires within the alert duration
+type ErrExpiresSoon struct {
+	// Context is the name of the context
+	Context string
+	// AlertBefore is the alert duration
+	AlertBefore time.Duration
+}
+
+func (e *ErrExpiresSoon) Error() string {
+	return fmt.Sprintf("the credentials of context %q expire within %s", e.Context, e.AlertBefore)
+}
+
+// ShowTokenExpiry prints the type and expiry of the credentials of the given context.
+// The exec plugin of the user is invoked to get the current token.
+// If alertBefore is positive, returns an *ErrExpiresSoon if any credential expires within this duration.
+func ShowTokenExpiry(desiredContext, output string, alertBefore time.Duration, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool, credentialCache *credentials.CredentialCache) error {
+	if output != OutputText && output != OutputJSON {
+		return fmt.Errorf("unknown output format %q. Valid formats are %q and %q", output, OutputText, OutputJSON)
+	}
+
+	user, err := gettoken.FindUser(desiredContext, stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	report, err := GetReport(user, credentialCache, time.Now())
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case OutputJSON:
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	default:
+		printText(report)
+	}
+
+	if alertBefore > 0 && report.ExpiresWithin(alertBefore) {
+		return &ErrExpiresSoon{Context: desiredContext, AlertBefore: alertBefore}
+	}
+	return nil
+}
+
+// GetReport returns the credentials of the user and their expiry relative to the given time
+func GetReport(user *gettoken.User, credentialCache *credentials.CredentialCache, now time.Time) (*Report, error) {
+	report := &Report{Context: user.ContextName}
+	authInfo := user.AuthInfo
+
+	certificate, err := getCertificateExpiry(authInfo.ClientCertificateData, authInfo.ClientCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the client certificate of context %q: %w", user.ContextName, err)
+	}
+	if certificate != nil {
+		report.add(TypeCertificate, "client certificate", certificate, now)
+	}
+
+	if authInfo.AuthProvider != nil {
+		if idToken := authInfo.AuthProvider.Config["id-token"]; len(idToken) > 0 {
+			report.addToken(fmt.Sprintf("auth provider %s", authInfo.AuthProvider.Name), idToken, nil, now)
+		}
+	}
+
+	if len(authInfo.Token) > 0 || len(authInfo.TokenFile) > 0 || authInfo.Exec != nil {
+		token, err := gettoken.GetTokenForUser(user, credentialCache)
+		if err != nil {
+			return nil, err
+		}
+
+		source := "token"
+		var expiry *time.Time
+		switch {
+		case authInfo.Exec != nil:
+			source = fmt.Sprintf("exec plugin %s", authInfo.Exec.Command)
+			if !token.Expiry.IsZero() {
+				expiry = &token.Expiry
+			}
+		case len(authInfo.TokenFile) > 0:
+			source = fmt.Sprintf("token file %s", authInfo.TokenFile)
+		}
+		report.addToken(source, token.Token, expiry, now)
+	}
+
+	if len(report.Credentials) == 0 {
+		return nil, fmt.Errorf("the user of context %q has neither a token, an exec plugin nor a client certificate", user.ContextName)
+	}
+	return report, nil
+}
+
+// addToken adds the bearer token. The expiry of JWTs is read from the "exp" claim unless given.
+func (r *Report) addToken(source, token string, expiry *time.Time, now time.Time) {
+	tokenType := TypeToken
+	if jwtExpiry, err := util.JWTExpiry(token); err == nil {
+		tokenType = TypeJWT
+		if expiry == nil && !jwtExpiry.IsZero() {
+			expiry = &jwtExpiry
+		}
+	}
+	r.add(tokenType, source, expiry, now)
+}
+
+func (r *Report) add(credentialType, source string, expiry *time.Time, now time.Time) {
+	credential := Credential{
+		Type:   credentialType,
+		Source: source,
+	}
+	if expiry != nil {
+		utc := expiry.UTC()
+		expiresIn := int64(math.Floor(utc.Sub(now).Seconds()))
+		credential.Expiry = &utc
+		credential.ExpiresInSeconds = &expiresIn
+	}
+	r.Credentials = append(r.Credentials, credential)
+}
+
+// ExpiresWithin returns true if any credential expires within the given duration or is already expired
+func (r *Report) ExpiresWithin(duration time.Duration) bool {
+	for _, credential := range r.Credentials {
+		if credential.ExpiresInSeconds != nil && time.Duration(*credential.ExpiresInSeconds)*time.Second < duration {
+			return true
+		}
+	}
+	return false
+}
+
+// getCertificateExpiry returns the expiry of the PEM encoded certificate either from the given data or the file.
+// Returns nil if neither is set.
+func getCertificateExpiry(data []byte, file string) (*time.Time, error) {
+	if len(data) == 0 && len(file) > 0 {
+		var err error
+		if data, err = os.ReadFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded certificate found")
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return &certificate.NotAfter, nil
+}
+
+func printText(report *Report) {
+	credentials := append([]Credential(nil), report.Credentials...)
+	sort.SliceStable(credentials, func(i, j int) bool {
+		return credentials[i].Expiry != nil && (credentials[j].Expiry == nil || credentials[i].Expiry.Before(*credentials[j].Expiry))
+	})
+
+	fmt.Printf("Context %s\n", report.Context)
+	for _, credential := range credentials {
+		if credential.Expiry == nil {
+			fmt.Printf("  %s (%s): no expiry\n", credential.Type, credential.Source)
+			continue
+		}
+		fmt.Printf("  %s (%s): %s at %s\n", credential.Type, credential.Source, FormatExpiresIn(time.Duration(*credential.ExpiresInSeconds)*time.Second), credential.Expiry.Format(time.RFC3339))
+	}
+}
+
+// FormatExpiresIn returns a human readable description of the duration until the expiry,
+// e.g. "expires in 47 minutes" or "expired 2 hours ago"
+func FormatExpiresIn(d time.Duration) string {
+	if d < 0 {
+		return fmt.Sprintf("expired %s ago", formatDuration(-d))
+	}
+	return fmt.Sprintf("expires in %s", formatDuration(d))
+}
+
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return plural(int64(d/(24*time.Hour)), "day")
+	case d >= 2*time.Hour:
+		return plural(int64(d/time.Hour), "hour")
+	case d >= 2*time.Minute:
+		return plural(int64(d/time.Minute), "minute")
+	default:
+		return plural(int64(d/time.Second), "second")
+	}
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
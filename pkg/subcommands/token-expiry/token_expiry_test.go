@@ -0,0 +1,362 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenexpiry_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/utils/ptr"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/annotations"
+	"github.com/danielfoehrkn/kubeswitch/pkg/credentials"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	gettoken "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/get-token"
+	tokenexpiry "github.com/danielfoehrkn/kubeswitch/pkg/subcommands/token-expiry"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// jwt returns an unsigned JWT expiring at the given time
+func jwt(expiry time.Time) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, expiry.Unix())))
+	return header + "." + payload + ".signature"
+}
+
+// certificate returns a PEM encoded self-signed certificate expiring at the given time
+func certificate(notAfter time.Time) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "admin"},
+		NotBefore:    notAfter.Add(-24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// captureStdout returns everything written to the stdout by the given function
+func captureStdout(f func() error) (string, error) {
+	reader, writer, err := os.Pipe()
+	Expect(err).ToNot(HaveOccurred())
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+
+	err = f()
+	Expect(writer.Close()).To(Succeed())
+	return <-output, err
+}
+
+var _ = Describe("TokenExpiry", func() {
+	var (
+		tempDir         string
+		credentialCache *credentials.CredentialCache
+		now             time.Time
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-token-expiry")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		block, err := ssh.MarshalPrivateKey(privateKey, "")
+		Expect(err).ToNot(HaveOccurred())
+		sshKeyPath := filepath.Join(tempDir, "id_ed25519")
+		Expect(os.WriteFile(sshKeyPath, pem.EncodeToMemory(block), 0600)).To(Succeed())
+
+		credentialCache, err = credentials.NewCredentialCache(filepath.Join(tempDir, "credentials"), time.Hour, sshKeyPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		now = time.Now().Truncate(time.Second)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	// writePlugin writes an exec plugin returning the given token and counting its invocations
+	writePlugin := func(token string) string {
+		path := filepath.Join(tempDir, "plugin")
+		script := fmt.Sprintf(`#!/bin/sh
+echo invoked >> %q
+echo '{"apiVersion": "client.authentication.k8s.io/v1", "kind": "ExecCredential", "status": {"token": "%s"}}'
+`, filepath.Join(tempDir, "invocations"), token)
+		Expect(os.WriteFile(path, []byte(script), 0700)).To(Succeed())
+		return path
+	}
+
+	invocations := func() int {
+		data, err := os.ReadFile(filepath.Join(tempDir, "invocations"))
+		if os.IsNotExist(err) {
+			return 0
+		}
+		Expect(err).ToNot(HaveOccurred())
+		return len(data) / len("invoked\n")
+	}
+
+	newUser := func(authInfo *clientcmdapi.AuthInfo) *gettoken.User {
+		if authInfo.Exec != nil {
+			authInfo.Exec.APIVersion = "client.authentication.k8s.io/v1"
+			authInfo.Exec.InteractiveMode = clientcmdapi.NeverExecInteractiveMode
+		}
+		return &gettoken.User{
+			ContextName: "team/dev",
+			StoreID:     "filesystem.default",
+			Kubeconfig:  &clientcmdapi.Config{},
+			Context:     &clientcmdapi.Context{},
+			AuthInfo:    authInfo,
+		}
+	}
+
+	Describe("GetReport", func() {
+		It("should report the expiry of the client certificate", func() {
+			notAfter := now.Add(72 * time.Hour)
+
+			report, err := tokenexpiry.GetReport(newUser(&clientcmdapi.AuthInfo{ClientCertificateData: certificate(notAfter)}), credentialCache, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Credentials).To(Equal([]tokenexpiry.Credential{{
+				Type:             tokenexpiry.TypeCertificate,
+				Source:           "client certificate",
+				Expiry:           ptr.To(notAfter.UTC()),
+				ExpiresInSeconds: ptr.To(int64(72 * 60 * 60)),
+			}}))
+		})
+
+		It("should read the client certificate file", func() {
+			certificateFile := filepath.Join(tempDir, "client.crt")
+			Expect(os.WriteFile(certificateFile, certificate(now.Add(-time.Hour)), 0600)).To(Succeed())
+
+			report, err := tokenexpiry.GetReport(newUser(&clientcmdapi.AuthInfo{ClientCertificate: certificateFile}), credentialCache, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Credentials).To(HaveLen(1))
+			Expect(*report.Credentials[0].ExpiresInSeconds).To(Equal(int64(-60 * 60)))
+		})
+
+		It("should fail for an invalid client certificate", func() {
+			_, err := tokenexpiry.GetReport(newUser(&clientcmdapi.AuthInfo{ClientCertificateData: []byte("invalid")}), credentialCache, now)
+			Expect(err).To(MatchError(`failed to parse the client certificate of context "team/dev": no PEM encoded certificate found`))
+		})
+
+		It("should read the expiry of a static JWT and report opaque tokens without expiry", func() {
+			report, err := tokenexpiry.GetReport(newUser(&clientcmdapi.AuthInfo{Token: jwt(now.Add(time.Hour))}), credentialCache, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Credentials).To(HaveLen(1))
+			Expect(report.Credentials[0].Type).To(Equal(tokenexpiry.TypeJWT))
+			Expect(report.Credentials[0].Source).To(Equal("token"))
+			Expect(*report.Credentials[0].ExpiresInSeconds).To(Equal(int64(60 * 60)))
+
+			report, err = tokenexpiry.GetReport(newUser(&clientcmdapi.AuthInfo{Token: "opaque"}), credentialCache, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Credentials).To(Equal([]tokenexpiry.Credential{{Type: tokenexpiry.TypeToken, Source: "token"}}))
+		})
+
+		It("should report the id token of the auth provider", func() {
+			report, err := tokenexpiry.GetReport(newUser(&clientcmdapi.AuthInfo{AuthProvider: &clientcmdapi.AuthProviderConfig{
+				Name:   "oidc",
+				Config: map[string]string{"id-token": jwt(now.Add(-time.Minute))},
+			}}), credentialCache, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(report.Credentials).To(HaveLen(1))
+			Expect(report.Credentials[0].Source).To(Equal("auth provider oidc"))
+			Expect(*report.Credentials[0].ExpiresInSeconds).To(Equal(int64(-60)))
+		})
+
+		It("should invoke the exec plugin to get the current token", func() {
+			plugin := writePlugin(jwt(now.Add(30 * time.Minute)))
+
+			report, err := tokenexpiry.GetReport(newUser(&clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: plugin}}), credentialCache, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(invocations()).To(Equal(1))
+			Expect(report.Credentials).To(HaveLen(1))
+			Expect(report.Credentials[0].Type).To(Equal(tokenexpiry.TypeJWT))
+			Expect(report.Credentials[0].Source).To(Equal(fmt.Sprintf("exec plugin %s", plugin)))
+			Expect(*report.Credentials[0].ExpiresInSeconds).To(Equal(int64(30 * 60)))
+		})
+
+		It("should report the cached token of the exec plugin until it expires and refresh it afterwards", func() {
+			plugin := writePlugin(jwt(now.Add(time.Hour)))
+			user := newUser(&clientcmdapi.AuthInfo{Exec: &clientcmdapi.ExecConfig{Command: plugin}})
+
+			cachedExpiry := now.Add(10 * time.Minute)
+			Expect(credentialCache.Put("filesystem.default", "token-team/dev", gettoken.Token{Token: jwt(cachedExpiry), Expiry: cachedExpiry}, cachedExpiry)).To(Succeed())
+
+			report, err := tokenexpiry.GetReport(user, credentialCache, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(invocations()).To(Equal(0))
+			Expect(*report.Credentials[0].ExpiresInSeconds).To(Equal(int64(10 * 60)))
+
+			expired := now.Add(-time.Minute)
+			Expect(credentialCache.Put("filesystem.default", "token-team/dev", gettoken.Token{Token: jwt(expired), Expiry: expired}, expired)).To(Succeed())
+
+			report, err = tokenexpiry.GetReport(user, credentialCache, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(invocations()).To(Equal(1))
+			Expect(*report.Credentials[0].ExpiresInSeconds).To(Equal(int64(60 * 60)))
+		})
+
+		It("should fail if the user has no credentials", func() {
+			_, err := tokenexpiry.GetReport(newUser(&clientcmdapi.AuthInfo{}), credentialCache, now)
+			Expect(err).To(MatchError(`the user of context "team/dev" has neither a token, an exec plugin nor a client certificate`))
+		})
+	})
+
+	Describe("ExpiresWithin", func() {
+		It("should only report credentials expiring within the duration", func() {
+			report := &tokenexpiry.Report{Credentials: []tokenexpiry.Credential{
+				{Type: tokenexpiry.TypeToken},
+				{Type: tokenexpiry.TypeJWT, ExpiresInSeconds: ptr.To(int64(30 * 60))},
+			}}
+			Expect(report.ExpiresWithin(time.Hour)).To(BeTrue())
+			Expect(report.ExpiresWithin(30 * time.Minute)).To(BeFalse())
+
+			report.Credentials[1].ExpiresInSeconds = ptr.To(int64(-1))
+			Expect(report.ExpiresWithin(time.Second)).To(BeTrue())
+		})
+	})
+
+	Describe("FormatExpiresIn", func() {
+		It("should describe the duration in the largest sensible unit", func() {
+			for d, expected := range map[time.Duration]string{
+				time.Second:             "expires in 1 second",
+				90 * time.Second:        "expires in 90 seconds",
+				47 * time.Minute:        "expires in 47 minutes",
+				3 * time.Hour:           "expires in 3 hours",
+				47 * time.Hour:          "expires in 47 hours",
+				72 * time.Hour:          "expires in 3 days",
+				-2 * time.Hour:          "expired 2 hours ago",
+				-150 * time.Second:      "expired 2 minutes ago",
+				-(48*time.Hour + 1):     "expired 2 days ago",
+				-(time.Second + 100000): "expired 1 second ago",
+			} {
+				Expect(tokenexpiry.FormatExpiresIn(d)).To(Equal(expected), "duration %s", d)
+			}
+		})
+	})
+
+	Describe("ShowTokenExpiry", func() {
+		var stores []store.KubeconfigStore
+
+		BeforeEach(func() {
+			annotations.SetPath(filepath.Join(tempDir, "annotations.yaml"))
+
+			kubeconfigsDir := filepath.Join(tempDir, "kubeconfigs")
+			Expect(os.MkdirAll(filepath.Join(kubeconfigsDir, "team"), 0700)).To(Succeed())
+			kubeconfig := fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+users:
+- name: admin
+  user:
+    token: %s
+    client-certificate-data: %s
+`, jwt(now.Add(30*time.Minute)), base64.StdEncoding.EncodeToString(certificate(now.Add(72*time.Hour))))
+			Expect(os.WriteFile(filepath.Join(kubeconfigsDir, "team", "config"), []byte(kubeconfig), 0600)).To(Succeed())
+
+			filesystemStore, err := store.NewFilesystemStore("config", types.KubeconfigStore{
+				Kind:  types.StoreKindFilesystem,
+				Paths: []string{kubeconfigsDir},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			stores = []store.KubeconfigStore{filesystemStore}
+		})
+
+		AfterEach(func() {
+			annotations.SetPath(annotations.DefaultPath)
+		})
+
+		It("should print the credentials ordered by their expiry", func() {
+			output, err := captureStdout(func() error {
+				return tokenexpiry.ShowTokenExpiry("team/dev", tokenexpiry.OutputText, 0, stores, &types.Config{}, tempDir, true, credentialCache)
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(output).To(MatchRegexp(`^Context team/dev
+  JWT \(token\): expires in (29|30) minutes at \S+
+  cert \(client certificate\): expires in (2|3) days at \S+
+$`))
+		})
+
+		It("should print the report as JSON", func() {
+			output, err := captureStdout(func() error {
+				return tokenexpiry.ShowTokenExpiry("team/dev", tokenexpiry.OutputJSON, 0, stores, &types.Config{}, tempDir, true, credentialCache)
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			report := &tokenexpiry.Report{}
+			Expect(json.Unmarshal([]byte(output), report)).To(Succeed())
+			Expect(report.Context).To(Equal("team/dev"))
+			Expect(report.Credentials).To(HaveLen(2))
+			Expect(report.Credentials[0].Type).To(Equal(tokenexpiry.TypeCertificate))
+			Expect(report.Credentials[1].Type).To(Equal(tokenexpiry.TypeJWT))
+		})
+
+		It("should return an error if a credential expires within the alert duration", func() {
+			_, err := captureStdout(func() error {
+				return tokenexpiry.ShowTokenExpiry("team/dev", tokenexpiry.OutputText, time.Hour, stores, &types.Config{}, tempDir, true, credentialCache)
+			})
+			Expect(err).To(MatchError(&tokenexpiry.ErrExpiresSoon{Context: "team/dev", AlertBefore: time.Hour}))
+
+			_, err = captureStdout(func() error {
+				return tokenexpiry.ShowTokenExpiry("team/dev", tokenexpiry.OutputText, 10*time.Minute, stores, &types.Config{}, tempDir, true, credentialCache)
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should reject unknown output formats", func() {
+			err := tokenexpiry.ShowTokenExpiry("team/dev", "yaml", 0, stores, &types.Config{}, tempDir, true, credentialCache)
+			Expect(err).To(MatchError(`unknown output format "yaml". Valid formats are "text" and "json"`))
+		})
+	})
+})
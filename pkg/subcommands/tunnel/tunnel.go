@@ -0,0 +1,47 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tunnel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// StartCloudflareTunnel routes the API server of the cluster of the given context
+// through the Cloudflare Tunnel configured for its CAPI store.
+// Afterwards, the kubeconfig of the context points to the public hostname of the cluster.
+func StartCloudflareTunnel(contextName string, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	discoveredContext, err := pkg.FindContext(contextName, stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	capiStore, ok := store.Unwrap(*discoveredContext.Store).(*store.CapiStore)
+	if !ok {
+		return fmt.Errorf("context %q is not from a CAPI store. Cloudflare Tunnels are only supported for CAPI stores", contextName)
+	}
+
+	hostname, err := capiStore.ProvisionCloudflareTunnel(context.Background(), discoveredContext.Path, discoveredContext.Tags)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("The API server of context %q is routed through Cloudflare Tunnel %q at https://%s\n", contextName, capiStore.Config.CloudflareTunnel.TunnelID, hostname)
+	return nil
+}
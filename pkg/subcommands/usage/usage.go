@@ -0,0 +1,136 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package usage
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/audit"
+)
+
+const (
+	OutputJSON  = "json"
+	OutputCSV   = "csv"
+	OutputTable = "table"
+)
+
+// Options configure which statistics are shown
+type Options struct {
+	// Since only considers switches within the given duration. Zero considers all switches.
+	Since time.Duration
+	// Top limits the statistics to the given number of contexts. Zero shows all contexts.
+	Top int
+	// Output is the output format of the statistics
+	Output string
+	// ByUser groups the statistics by OS username
+	ByUser bool
+	// Heatmap shows a calendar of the daily switches instead of the statistics
+	Heatmap bool
+}
+
+// ShowUsage prints the statistics of the switches per context recorded in the audit log
+func ShowUsage(auditLogPath string, options Options) error {
+	switch options.Output {
+	case OutputJSON, OutputCSV, OutputTable:
+	default:
+		return fmt.Errorf("unknown output format %q. Valid formats are %q, %q and %q", options.Output, OutputJSON, OutputCSV, OutputTable)
+	}
+
+	entries, err := audit.Read(auditLogPath)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if options.Since > 0 {
+		entries = audit.Filter(entries, now.Add(-options.Since))
+	}
+
+	if options.Heatmap {
+		fmt.Print(audit.Heatmap(entries, now))
+		return nil
+	}
+
+	stats := audit.ComputeStats(entries, options.ByUser)
+	if options.Top > 0 && len(stats) > options.Top {
+		stats = stats[:options.Top]
+	}
+
+	switch options.Output {
+	case OutputJSON:
+		data, err := json.MarshalIndent(stats, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case OutputCSV:
+		return writeCSV(stats, options.ByUser)
+	default:
+		printTable(stats, options.ByUser)
+	}
+	return nil
+}
+
+func writeCSV(stats []audit.Stats, byUser bool) error {
+	w := csv.NewWriter(os.Stdout)
+
+	header := []string{"context", "switches", "lastAccessed", "storeKind"}
+	if byUser {
+		header = append([]string{"user"}, header...)
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, s := range stats {
+		record := []string{s.Context, strconv.Itoa(s.Switches), s.LastAccessed.Format(time.RFC3339), string(s.StoreKind)}
+		if byUser {
+			record = append([]string{s.User}, record...)
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func printTable(stats []audit.Stats, byUser bool) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+
+	header := table.Row{"Context", "Switches", "Last Accessed", "Store Kind"}
+	if byUser {
+		header = append(table.Row{"User"}, header...)
+	}
+	t.AppendHeader(header)
+
+	for _, s := range stats {
+		row := table.Row{s.Context, s.Switches, s.LastAccessed.Local().Format(time.RFC3339), s.StoreKind}
+		if byUser {
+			row = append(table.Row{s.User}, row...)
+		}
+		t.AppendRow(row)
+	}
+	t.Render()
+}
@@ -0,0 +1,122 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg"
+	"github.com/danielfoehrkn/kubeswitch/pkg/store"
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// DefaultTimeout is the default duration to wait for the cluster to become ready
+	DefaultTimeout = 10 * time.Minute
+	// DefaultReadyThreshold is the default number of consecutive successful health checks
+	// required to declare the cluster ready
+	DefaultReadyThreshold = 3
+	// pollInterval is the interval between two health checks
+	pollInterval = 5 * time.Second
+	// checkTimeout is the timeout of a single health check
+	checkTimeout = 5 * time.Second
+)
+
+var logger = logrus.New()
+
+// WaitForReady waits until the API server of the context responded to readyThreshold consecutive
+// health checks (GET /api) or the timeout expires.
+// Prints a dot to stderr for each health check.
+func WaitForReady(desiredContext string, timeout time.Duration, readyThreshold int, stores []store.KubeconfigStore, config *types.Config, stateDir string, noIndex bool) error {
+	if readyThreshold < 1 {
+		return fmt.Errorf("the ready threshold must be at least 1")
+	}
+
+	discoveredContext, err := pkg.FindContext(desiredContext, stores, config, stateDir, noIndex)
+	if err != nil {
+		return err
+	}
+
+	return WaitForContextReady(discoveredContext, timeout, readyThreshold)
+}
+
+// WaitForContextReady waits until the API server of the already discovered context responded to
+// readyThreshold consecutive health checks or the timeout expires.
+func WaitForContextReady(discoveredContext *pkg.DiscoveredContext, timeout time.Duration, readyThreshold int) error {
+	if readyThreshold < 1 {
+		return fmt.Errorf("the ready threshold must be at least 1")
+	}
+
+	name := discoveredContext.Name
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	successes := 0
+	for {
+		if err := checkReady(ctx, discoveredContext); err != nil {
+			logger.Debugf("health check of context %q failed: %v", name, err)
+			successes = 0
+		} else {
+			successes++
+		}
+		fmt.Fprint(os.Stderr, ".")
+
+		if successes >= readyThreshold {
+			fmt.Fprintln(os.Stderr)
+			fmt.Printf("Context %q is ready\n", name)
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stderr)
+			return fmt.Errorf("context %q did not become ready within %s", name, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkReady checks if the API server of the discovered context responds.
+// The kubeconfig is retrieved on every check, so that credentials of newly provisioned clusters are picked up.
+func checkReady(ctx context.Context, discoveredContext *pkg.DiscoveredContext) error {
+	kubeconfigStore := *discoveredContext.Store
+	kubeconfigData, err := kubeconfigStore.GetKubeconfigForPath(ctx, discoveredContext.Path, discoveredContext.Tags)
+	if err != nil {
+		return err
+	}
+
+	kubeconfig, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	contextName := pkg.KubeconfigContextName(kubeconfigStore, *discoveredContext)
+	kubeconfig.CurrentContext = contextName
+
+	kubeconfigData, err = clientcmd.Write(*kubeconfig)
+	if err != nil {
+		return err
+	}
+	return util.CheckAPIServerConnectivity(kubeconfigData, checkTimeout)
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watchcontext
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// debounce is the time to wait for further writes to the kubeconfig before reading the current context
+const debounce = 200 * time.Millisecond
+
+var logger = logrus.New()
+
+// WatchContext watches the given kubeconfig file and prints a notification whenever the current-context changes.
+// If bell is set, the terminal bell is rung on each change.
+// If command is set, the command is executed on each change with the environment variables
+// KUBESWITCH_OLD_CONTEXT and KUBESWITCH_NEW_CONTEXT set.
+// Returns when receiving SIGINT or SIGTERM.
+func WatchContext(kubeconfigPath string, bell bool, command string) error {
+	kubeconfigPath, err := filepath.Abs(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// watch the parent directory as the kubeconfig file is frequently replaced instead of written to
+	if err := watcher.Add(filepath.Dir(kubeconfigPath)); err != nil {
+		return fmt.Errorf("failed to watch kubeconfig %q: %w", kubeconfigPath, err)
+	}
+
+	var (
+		lock           sync.Mutex
+		currentContext = getCurrentContext(kubeconfigPath)
+	)
+
+	onChange := func() {
+		lock.Lock()
+		defer lock.Unlock()
+
+		newContext := getCurrentContext(kubeconfigPath)
+		if newContext == currentContext {
+			return
+		}
+		oldContext := currentContext
+		currentContext = newContext
+
+		fmt.Printf("%s context changed: %q -> %q\n", time.Now().Format(time.TimeOnly), oldContext, newContext)
+		if bell {
+			fmt.Print("\a")
+		}
+		if len(command) > 0 {
+			if err := runCommand(command, oldContext, newContext); err != nil {
+				logger.Warnf("failed to run command %q: %v", command, err)
+			}
+		}
+	}
+
+	fmt.Printf("Watching %q for context changes. Current context: %q\n", kubeconfigPath, currentContext)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	var timer *time.Timer
+	for {
+		select {
+		case <-signals:
+			if timer != nil {
+				timer.Stop()
+			}
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) != kubeconfigPath {
+				continue
+			}
+			// debounce rapid writes to the kubeconfig
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(debounce, onChange)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Warnf("error watching kubeconfig: %v", err)
+		}
+	}
+}
+
+// getCurrentContext returns the current-context of the kubeconfig or an empty string if it cannot be read
+func getCurrentContext(kubeconfigPath string) string {
+	config, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		logger.Debugf("failed to read kubeconfig %q: %v", kubeconfigPath, err)
+		return ""
+	}
+	return config.CurrentContext
+}
+
+func runCommand(command, oldContext, newContext string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("KUBESWITCH_OLD_CONTEXT=%s", oldContext),
+		fmt.Sprintf("KUBESWITCH_NEW_CONTEXT=%s", newContext),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
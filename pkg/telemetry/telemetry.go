@@ -0,0 +1,191 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry sends anonymous usage statistics if the user opted in with "switch telemetry enable".
+//
+// The complete data sent is the Event type of this package. It contains no personal data:
+// no context names, store IDs, paths, hostnames, usernames or IP addresses of clusters.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+// DefaultSettingsPath is the path of the file storing if telemetry is enabled
+const DefaultSettingsPath = "$HOME/.kube/switch-telemetry.yaml"
+
+// sendTimeout is the maximum duration of sending an event
+const sendTimeout = 2 * time.Second
+
+// httpClient sends the events. The timeout also applies to reading the response body.
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+// Settings are the telemetry settings of the user
+type Settings struct {
+	// Enabled is true if the user opted in to send usage statistics
+	Enabled bool `yaml:"enabled"`
+}
+
+// Event is the usage statistics sent after a switch
+type Event struct {
+	// Version is the version of kubeswitch
+	Version string `json:"version"`
+	// OS is the operating system, e.g. "linux"
+	OS string `json:"os"`
+	// Arch is the CPU architecture, e.g. "amd64"
+	Arch string `json:"arch"`
+	// StoreKinds are the sorted kinds of the configured stores, e.g. ["eks", "filesystem"]
+	StoreKinds []string `json:"storeKinds"`
+	// ContextCount is the range of the number of contexts found by the search, e.g. "11-100"
+	ContextCount string `json:"contextCount"`
+	// SearchLatency contains the percentiles of the search durations of the stores
+	SearchLatency Latency `json:"searchLatency"`
+}
+
+// Latency contains percentiles of durations in milliseconds
+type Latency struct {
+	P50 int64 `json:"p50"`
+	P90 int64 `json:"p90"`
+	P99 int64 `json:"p99"`
+}
+
+// LoadSettings reads the telemetry settings from the given path.
+// Telemetry is disabled if the file does not exist.
+func LoadSettings(path string) (*Settings, error) {
+	settings := &Settings{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return settings, nil
+		}
+		return nil, fmt.Errorf("failed to read telemetry settings: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, settings); err != nil {
+		return nil, fmt.Errorf("failed to parse telemetry settings %q: %w", path, err)
+	}
+	return settings, nil
+}
+
+// SaveSettings writes the telemetry settings to the given path
+func SaveSettings(path string, settings Settings) error {
+	data, err := yaml.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	if err := kubeswitchio.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return kubeswitchio.WriteFile(path, data, 0644)
+}
+
+// NewEvent returns the event for the given store kinds, number of contexts and search durations
+func NewEvent(version string, storeKinds []types.StoreKind, contextCount int, searchDurations []time.Duration) Event {
+	kinds := make([]string, 0, len(storeKinds))
+	for _, kind := range storeKinds {
+		if !slices.Contains(kinds, string(kind)) {
+			kinds = append(kinds, string(kind))
+		}
+	}
+	slices.Sort(kinds)
+
+	return Event{
+		Version:       version,
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		StoreKinds:    kinds,
+		ContextCount:  countRange(contextCount),
+		SearchLatency: percentiles(searchDurations),
+	}
+}
+
+// Send posts the event to the telemetry endpoint.
+// Returns once the event is sent or the send timeout is exceeded.
+func Send(endpoint string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("failed to send telemetry: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("failed to send telemetry: %s", response.Status)
+	}
+	return nil
+}
+
+// countRange returns the range of the number of contexts instead of the exact number
+func countRange(count int) string {
+	switch {
+	case count == 0:
+		return "0"
+	case count <= 10:
+		return "1-10"
+	case count <= 100:
+		return "11-100"
+	case count <= 1000:
+		return "101-1000"
+	default:
+		return ">1000"
+	}
+}
+
+// percentiles returns the nearest-rank percentiles of the durations
+func percentiles(durations []time.Duration) Latency {
+	if len(durations) == 0 {
+		return Latency{}
+	}
+
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	percentile := func(p int) int64 {
+		rank := (p*len(sorted) + 99) / 100
+		return sorted[rank-1].Milliseconds()
+	}
+
+	return Latency{
+		P50: percentile(50),
+		P90: percentile(90),
+		P99: percentile(99),
+	}
+}
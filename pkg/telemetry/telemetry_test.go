@@ -0,0 +1,131 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/telemetry"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("Telemetry", func() {
+	Describe("Settings", func() {
+		var directory string
+
+		BeforeEach(func() {
+			var err error
+			directory, err = os.MkdirTemp("", "telemetry")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(directory)).To(Succeed())
+		})
+
+		It("should be disabled without settings file", func() {
+			settings, err := telemetry.LoadSettings(filepath.Join(directory, "switch-telemetry.yaml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(settings.Enabled).To(BeFalse())
+		})
+
+		It("should save and load the settings", func() {
+			path := filepath.Join(directory, "kube", "switch-telemetry.yaml")
+			Expect(telemetry.SaveSettings(path, telemetry.Settings{Enabled: true})).To(Succeed())
+
+			settings, err := telemetry.LoadSettings(path)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(settings.Enabled).To(BeTrue())
+		})
+	})
+
+	Describe("NewEvent", func() {
+		It("should only contain the anonymous usage statistics", func() {
+			durations := []time.Duration{
+				40 * time.Millisecond, 10 * time.Millisecond, 30 * time.Millisecond, 20 * time.Millisecond, 1000 * time.Millisecond,
+			}
+			event := telemetry.NewEvent("v1.0.0", []types.StoreKind{types.StoreKindEKS, types.StoreKindFilesystem, types.StoreKindEKS}, 42, durations)
+			Expect(event).To(Equal(telemetry.Event{
+				Version:       "v1.0.0",
+				OS:            runtime.GOOS,
+				Arch:          runtime.GOARCH,
+				StoreKinds:    []string{"eks", "filesystem"},
+				ContextCount:  "11-100",
+				SearchLatency: telemetry.Latency{P50: 30, P90: 1000, P99: 1000},
+			}))
+		})
+
+		It("should only contain the range of the number of contexts", func() {
+			for count, expected := range map[int]string{0: "0", 1: "1-10", 10: "1-10", 100: "11-100", 1000: "101-1000", 1001: ">1000"} {
+				Expect(telemetry.NewEvent("", nil, count, nil).ContextCount).To(Equal(expected))
+			}
+		})
+	})
+
+	Describe("Send", func() {
+		It("should post the event to the endpoint", func() {
+			received := make(chan telemetry.Event, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.Method).To(Equal(http.MethodPost))
+				Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+
+				var event telemetry.Event
+				Expect(json.NewDecoder(r.Body).Decode(&event)).To(Succeed())
+				received <- event
+			}))
+			defer server.Close()
+
+			event := telemetry.NewEvent("v1.0.0", []types.StoreKind{types.StoreKindGKE}, 3, []time.Duration{time.Second})
+			Expect(telemetry.Send(server.URL, event)).To(Succeed())
+			Expect(received).To(Receive(Equal(event)))
+		})
+
+		It("should return an error if the endpoint rejects the event", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			Expect(telemetry.Send(server.URL, telemetry.Event{})).To(MatchError(ContainSubstring("500 Internal Server Error")))
+		})
+
+		It("should return an error if the endpoint is not reachable", func() {
+			Expect(telemetry.Send("http://127.0.0.1:0", telemetry.Event{})).ToNot(Succeed())
+		})
+
+		It("should give up once the send timeout is exceeded", func() {
+			unblock := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				<-unblock
+			}))
+			defer server.Close()
+			defer close(unblock)
+
+			start := time.Now()
+			Expect(telemetry.Send(server.URL, telemetry.Event{})).ToNot(Succeed())
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		})
+	})
+})
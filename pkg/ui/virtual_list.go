@@ -0,0 +1,112 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"sync"
+)
+
+// DefaultWindowSize is the default number of entries a VirtualList reads per page
+const DefaultWindowSize = 200
+
+// VirtualList lazily reads the entries of a channel page by page.
+// Only the next page is read from the channel once the user scrolls close to the end of the already shown entries.
+// As the sender blocks until the next page is requested, at most one page of entries is buffered in the list.
+// The read pages are handed to the caller, which decides which entries to keep.
+type VirtualList[T any] struct {
+	source     <-chan T
+	windowSize int
+	// onPage is called with the entries of every page read from the source
+	onPage func(page []T)
+
+	lock      sync.Mutex
+	loading   bool
+	exhausted bool
+}
+
+// NewVirtualList creates a virtual list reading pages of the given window size from the source channel.
+// The given function is called for every page read from the source.
+func NewVirtualList[T any](source <-chan T, windowSize int, onPage func(page []T)) *VirtualList[T] {
+	if windowSize <= 0 {
+		windowSize = DefaultWindowSize
+	}
+	return &VirtualList[T]{
+		source:     source,
+		windowSize: windowSize,
+		onPage:     onPage,
+	}
+}
+
+// Scroll is called when the user moved the cursor.
+// Remaining is the number of entries shown below the cursor.
+// Reads the next page asynchronously if less than half a page of entries remains.
+func (l *VirtualList[T]) Scroll(remaining int) {
+	if remaining >= l.windowSize/2 {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.loading || l.exhausted {
+		return
+	}
+	l.loading = true
+
+	go func() {
+		page := l.NextPage()
+
+		l.lock.Lock()
+		l.loading = false
+		l.lock.Unlock()
+
+		if len(page) > 0 {
+			l.onPage(page)
+		}
+	}()
+}
+
+// NextPage reads the next page from the source.
+// Blocks until the page is full or the source is closed.
+// Returns an empty page once the source is exhausted.
+func (l *VirtualList[T]) NextPage() []T {
+	page := make([]T, 0, l.windowSize)
+	for len(page) < l.windowSize {
+		entry, ok := <-l.source
+		if !ok {
+			l.lock.Lock()
+			l.exhausted = true
+			l.lock.Unlock()
+			break
+		}
+		page = append(page, entry)
+	}
+	return page
+}
+
+// LoadAll reads all remaining pages from the source
+func (l *VirtualList[T]) LoadAll() {
+	for !l.Exhausted() {
+		if page := l.NextPage(); len(page) > 0 {
+			l.onPage(page)
+		}
+	}
+}
+
+// Exhausted returns true once all entries have been read from the source
+func (l *VirtualList[T]) Exhausted() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.exhausted
+}
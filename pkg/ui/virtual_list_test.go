@@ -0,0 +1,81 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui_test
+
+import (
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/ui"
+)
+
+var _ = Describe("VirtualList", func() {
+	var (
+		lock   sync.Mutex
+		loaded []int
+		list   *ui.VirtualList[int]
+	)
+
+	getLoaded := func() []int {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]int{}, loaded...)
+	}
+
+	BeforeEach(func() {
+		source := make(chan int)
+		loaded = nil
+		list = ui.NewVirtualList(source, 4, func(page []int) {
+			lock.Lock()
+			defer lock.Unlock()
+			loaded = append(loaded, page...)
+		})
+
+		go func() {
+			defer close(source)
+			for i := 0; i < 10; i++ {
+				source <- i
+			}
+		}()
+	})
+
+	It("should read the entries page by page", func() {
+		Expect(list.NextPage()).To(Equal([]int{0, 1, 2, 3}))
+		Expect(list.NextPage()).To(Equal([]int{4, 5, 6, 7}))
+		Expect(list.NextPage()).To(Equal([]int{8, 9}))
+		Expect(list.Exhausted()).To(BeTrue())
+		Expect(list.NextPage()).To(BeEmpty())
+	})
+
+	It("should only read the next page when scrolling close to the last entry", func() {
+		list.Scroll(0)
+		Eventually(getLoaded).Should(Equal([]int{0, 1, 2, 3}))
+
+		// more than half a page remains below the cursor
+		list.Scroll(3)
+		Consistently(getLoaded, "100ms").Should(HaveLen(4))
+
+		list.Scroll(1)
+		Eventually(getLoaded).Should(HaveLen(8))
+	})
+
+	It("should read all pages", func() {
+		list.LoadAll()
+		Expect(getLoaded()).To(Equal([]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}))
+		Expect(list.Exhausted()).To(BeTrue())
+	})
+})
@@ -0,0 +1,54 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// CheckAPIServerConnectivity sends a GET request to the /api endpoint of the API server
+// of the current context in the given kubeconfig using the credentials of the kubeconfig.
+// Returns an error if the API server cannot be reached within the given timeout.
+func CheckAPIServerConnectivity(kubeconfigBytes []byte, timeout time.Duration) error {
+	restConfig, err := clientcmd.RESTConfigFromKubeConfig(kubeconfigBytes)
+	if err != nil {
+		return fmt.Errorf("failed to create rest config from kubeconfig: %w", err)
+	}
+	restConfig.Timeout = timeout
+
+	httpClient, err := rest.HTTPClientFor(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create http client: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api", strings.TrimSuffix(restConfig.Host, "/"))
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to reach API server %q: %w", restConfig.Host, err)
+	}
+	defer resp.Body.Close()
+
+	// the API server is reachable, even if the credentials are not authorized
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("API server %q responded with status code %d", restConfig.Host, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"strings"
+
+	"github.com/becheran/wildmatch-go"
+)
+
+// IsContextExcluded checks if the given context name is excluded by the given list of exclusion patterns.
+// Patterns are exact context names or wildcard patterns ('*' and '?').
+// A pattern prefixed with "!" re-includes a context excluded by a previous pattern.
+// As the patterns are evaluated in order, the last matching pattern wins.
+func IsContextExcluded(contextName string, patterns []string) bool {
+	excluded := false
+	for _, pattern := range patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		if negate {
+			pattern = strings.TrimPrefix(pattern, "!")
+		}
+
+		if wildmatch.NewWildMatch(pattern).IsMatch(contextName) {
+			excluded = !negate
+		}
+	}
+	return excluded
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var _ = Describe("IsContextExcluded", func() {
+	It("should not exclude any context without patterns", func() {
+		Expect(util.IsContextExcluded("minikube", nil)).To(BeFalse())
+	})
+
+	It("should exclude a context by its exact name", func() {
+		patterns := []string{"docker-desktop", "minikube"}
+		Expect(util.IsContextExcluded("minikube", patterns)).To(BeTrue())
+		Expect(util.IsContextExcluded("docker-desktop", patterns)).To(BeTrue())
+		Expect(util.IsContextExcluded("minikube-2", patterns)).To(BeFalse())
+	})
+
+	It("should exclude contexts matching a glob pattern", func() {
+		patterns := []string{"kind-*", "dev-?"}
+		Expect(util.IsContextExcluded("kind-test", patterns)).To(BeTrue())
+		Expect(util.IsContextExcluded("dev-1", patterns)).To(BeTrue())
+		Expect(util.IsContextExcluded("dev-10", patterns)).To(BeFalse())
+		Expect(util.IsContextExcluded("prod", patterns)).To(BeFalse())
+	})
+
+	It("should re-include a context excluded by a glob pattern with the ! override", func() {
+		patterns := []string{"kind-*", "!kind-important"}
+		Expect(util.IsContextExcluded("kind-test", patterns)).To(BeTrue())
+		Expect(util.IsContextExcluded("kind-important", patterns)).To(BeFalse())
+	})
+
+	It("should let the last matching pattern win", func() {
+		patterns := []string{"!kind-important", "kind-*"}
+		Expect(util.IsContextExcluded("kind-important", patterns)).To(BeTrue())
+	})
+})
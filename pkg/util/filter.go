@@ -0,0 +1,82 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/ktr0731/go-fuzzyfinder/matching"
+)
+
+// FilterMode defines how the pattern of a ContextFilter is matched against the context names
+type FilterMode string
+
+const (
+	// FilterModeFuzzy matches like the interactive search of the selection dialog
+	FilterModeFuzzy FilterMode = "fuzzy"
+	// FilterModeRegex matches the context names against a regular expression
+	FilterModeRegex FilterMode = "regex"
+)
+
+// ContextFilter selects the context names matching a pattern
+type ContextFilter struct {
+	// Pattern is the pattern as given by the user
+	Pattern string
+	Mode    FilterMode
+	regex   *regexp.Regexp
+}
+
+// NewContextFilter returns a filter for the given pattern.
+// Fails if the mode is unknown or the pattern is not a valid regular expression in regex mode.
+func NewContextFilter(pattern string, mode FilterMode) (*ContextFilter, error) {
+	filter := &ContextFilter{Pattern: pattern, Mode: mode}
+	switch mode {
+	case FilterModeFuzzy:
+	case FilterModeRegex:
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+		}
+		filter.regex = regex
+	default:
+		return nil, fmt.Errorf("unknown filter mode %q. Valid modes are %q and %q", mode, FilterModeFuzzy, FilterModeRegex)
+	}
+	return filter, nil
+}
+
+// Filter returns the context names matching the pattern in their original order
+func (f *ContextFilter) Filter(contextNames []string) []string {
+	var result []string
+	if f.regex != nil {
+		for _, contextName := range contextNames {
+			if f.regex.MatchString(contextName) {
+				result = append(result, contextName)
+			}
+		}
+		return result
+	}
+
+	matched := matching.FindAll(f.Pattern, contextNames)
+	// the matches are sorted by score, keep the order of the context names instead
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Idx < matched[j].Idx
+	})
+	for _, m := range matched {
+		result = append(result, contextNames[m.Idx])
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var _ = Describe("ContextFilter", func() {
+	contextNames := []string{"dev-eu", "prod-eu", "prod-us", "Prod-Asia"}
+
+	It("should match fuzzy like the selection dialog", func() {
+		filter, err := util.NewContextFilter("preu", util.FilterModeFuzzy)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filter.Filter(contextNames)).To(Equal([]string{"prod-eu"}))
+
+		filter, err = util.NewContextFilter("prod", util.FilterModeFuzzy)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filter.Filter(contextNames)).To(Equal([]string{"prod-eu", "prod-us", "Prod-Asia"}))
+
+		// upper case characters switch to case-sensitive matching
+		filter, err = util.NewContextFilter("Prod", util.FilterModeFuzzy)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filter.Filter(contextNames)).To(Equal([]string{"Prod-Asia"}))
+	})
+
+	It("should match regular expressions", func() {
+		filter, err := util.NewContextFilter("^prod-(eu|us)$", util.FilterModeRegex)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filter.Filter(contextNames)).To(Equal([]string{"prod-eu", "prod-us"}))
+
+		filter, err = util.NewContextFilter("staging", util.FilterModeRegex)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(filter.Filter(contextNames)).To(BeEmpty())
+	})
+
+	It("should reject invalid patterns and modes", func() {
+		_, err := util.NewContextFilter("prod-(", util.FilterModeRegex)
+		Expect(err).To(HaveOccurred())
+
+		_, err = util.NewContextFilter("prod", "glob")
+		Expect(err).To(HaveOccurred())
+	})
+})
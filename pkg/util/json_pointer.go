@@ -0,0 +1,82 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONPointerGet returns the value referenced by the given RFC 6901 JSON Pointer in data.
+// data is expected to be a decoded JSON document (maps, slices and scalar values).
+// String values are returned as is, any other value is returned in its JSON encoding.
+func JSONPointerGet(data interface{}, pointer string) (string, error) {
+	if pointer != "" && !strings.HasPrefix(pointer, "/") {
+		return "", fmt.Errorf("invalid JSON pointer %q: must be empty or start with '/'", pointer)
+	}
+
+	current := data
+	if pointer != "" {
+		for _, token := range strings.Split(pointer[1:], "/") {
+			// "~1" has to be replaced before "~0", see RFC 6901 section 4
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+			switch value := current.(type) {
+			case map[string]interface{}:
+				next, ok := value[token]
+				if !ok {
+					return "", fmt.Errorf("JSON pointer %q: key %q not found", pointer, token)
+				}
+				current = next
+			case []interface{}:
+				index, err := parseArrayIndex(token)
+				if err != nil {
+					return "", fmt.Errorf("JSON pointer %q: %v", pointer, err)
+				}
+				if index >= len(value) {
+					return "", fmt.Errorf("JSON pointer %q: index %d out of range", pointer, index)
+				}
+				current = value[index]
+			default:
+				return "", fmt.Errorf("JSON pointer %q: cannot traverse into value of type %T with token %q", pointer, current, token)
+			}
+		}
+	}
+
+	if s, ok := current.(string); ok {
+		return s, nil
+	}
+
+	bytes, err := json.Marshal(current)
+	if err != nil {
+		return "", fmt.Errorf("JSON pointer %q: failed to encode value: %v", pointer, err)
+	}
+	return string(bytes), nil
+}
+
+// parseArrayIndex parses an array index reference token.
+// Leading zeros and the "-" (past the end) token are not valid for reading.
+func parseArrayIndex(token string) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	return index, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var _ = Describe("JSONPointerGet", func() {
+	var document interface{}
+
+	BeforeEach(func() {
+		Expect(json.Unmarshal([]byte(`{
+			"kubeconfig": "top-level",
+			"clusters": [
+				{"admin_kubeconfig": "first"},
+				{"admin_kubeconfig": "second"}
+			],
+			"a/b": "slash",
+			"m~n": "tilde",
+			"nested": {"port": 6443}
+		}`), &document)).To(Succeed())
+	})
+
+	It("should return the value of a top-level key", func() {
+		value, err := util.JSONPointerGet(document, "/kubeconfig")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(value).To(Equal("top-level"))
+	})
+
+	It("should traverse arrays", func() {
+		value, err := util.JSONPointerGet(document, "/clusters/1/admin_kubeconfig")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(value).To(Equal("second"))
+	})
+
+	It("should unescape reference tokens", func() {
+		value, err := util.JSONPointerGet(document, "/a~1b")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(value).To(Equal("slash"))
+
+		value, err = util.JSONPointerGet(document, "/m~0n")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(value).To(Equal("tilde"))
+	})
+
+	It("should return non-string values JSON encoded", func() {
+		value, err := util.JSONPointerGet(document, "/nested")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(value).To(Equal(`{"port":6443}`))
+	})
+
+	It("should fail for invalid pointers", func() {
+		for _, pointer := range []string{"kubeconfig", "/missing", "/clusters/2", "/clusters/01", "/clusters/-", "/kubeconfig/x"} {
+			_, err := util.JSONPointerGet(document, pointer)
+			Expect(err).To(HaveOccurred(), pointer)
+		}
+	})
+})
@@ -0,0 +1,52 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	"encoding/base64"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+// newJWT returns an unsigned JWT with the given claims
+func newJWT(claims string) string {
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".signature"
+}
+
+var _ = Describe("JWTExpiry", func() {
+	It("should return the time of the exp claim", func() {
+		expiry, err := util.JWTExpiry(newJWT(`{"sub":"user","exp":1760000000}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(expiry).To(BeTemporally("==", time.Unix(1760000000, 0)))
+	})
+
+	It("should return the zero time if the JWT does not expire", func() {
+		expiry, err := util.JWTExpiry(newJWT(`{"sub":"user"}`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(expiry.IsZero()).To(BeTrue())
+	})
+
+	It("should fail for tokens that are not JWTs", func() {
+		_, err := util.JWTExpiry("opaque-token")
+		Expect(err).To(MatchError("token is not a JWT"))
+
+		_, err = util.JWTExpiry("a.!!!.c")
+		Expect(err).To(HaveOccurred())
+	})
+})
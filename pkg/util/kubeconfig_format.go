@@ -0,0 +1,58 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+)
+
+// EncodeKubeconfig serializes the kubeconfig in the given format
+func EncodeKubeconfig(config *clientcmdapi.Config, format kubeconfigutil.Format) ([]byte, error) {
+	if format != kubeconfigutil.FormatJSON {
+		return clientcmd.Write(*config)
+	}
+
+	// the internal representation uses maps instead of named lists, so it has to be converted
+	// to the versioned kubeconfig first to be readable by other tools
+	versioned := clientcmdapiv1.Config{}
+	if err := clientcmdlatest.Scheme.Convert(config, &versioned, nil); err != nil {
+		return nil, fmt.Errorf("failed to convert kubeconfig: %w", err)
+	}
+	versioned.APIVersion = clientcmdlatest.Version
+	versioned.Kind = "Config"
+
+	data, err := json.MarshalIndent(versioned, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ConvertKubeconfig converts the given YAML or JSON kubeconfig to the given format
+func ConvertKubeconfig(data []byte, format kubeconfigutil.Format) ([]byte, error) {
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	return EncodeKubeconfig(config, format)
+}
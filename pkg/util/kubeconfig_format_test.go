@@ -0,0 +1,88 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+)
+
+var _ = Describe("ConvertKubeconfig", func() {
+	const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://127.0.0.1:6443
+    certificate-authority-data: Y2EtZGF0YQ==
+users:
+- name: dev-user
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1
+      command: kubelogin
+      args:
+      - get-token
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+    namespace: kube-system
+`
+
+	It("should convert a YAML kubeconfig to JSON", func() {
+		data, err := util.ConvertKubeconfig([]byte(kubeconfig), kubeconfigutil.FormatJSON)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Valid(data)).To(BeTrue())
+
+		var parsed map[string]interface{}
+		Expect(json.Unmarshal(data, &parsed)).To(Succeed())
+		Expect(parsed).To(HaveKeyWithValue("apiVersion", "v1"))
+		Expect(parsed).To(HaveKeyWithValue("kind", "Config"))
+		Expect(parsed).To(HaveKeyWithValue("current-context", "dev"))
+		Expect(parsed["clusters"]).To(HaveLen(1))
+	})
+
+	It("should be round-trippable", func() {
+		original, err := clientcmd.Load([]byte(kubeconfig))
+		Expect(err).ToNot(HaveOccurred())
+
+		asJSON, err := util.ConvertKubeconfig([]byte(kubeconfig), kubeconfigutil.FormatJSON)
+		Expect(err).ToNot(HaveOccurred())
+		asYAML, err := util.ConvertKubeconfig(asJSON, kubeconfigutil.FormatYAML)
+		Expect(err).ToNot(HaveOccurred())
+
+		roundTripped, err := clientcmd.Load(asYAML)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(roundTripped).To(Equal(original))
+	})
+
+	It("should reject unknown formats", func() {
+		_, err := kubeconfigutil.ParseFormat("toml")
+		Expect(err).To(HaveOccurred())
+
+		format, err := kubeconfigutil.ParseFormat("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(format).To(Equal(kubeconfigutil.FormatYAML))
+	})
+})
@@ -179,6 +179,34 @@ func (k *Kubeconfig) ModifyContextName(old, new string) error {
 	return nil
 }
 
+// CopyContext adds a copy of the context with the given name under the new name
+func (k *Kubeconfig) CopyContext(name, newName string) error {
+	contexts, err := k.contextsNode()
+	if err != nil {
+		return err
+	}
+
+	contextNode, err := k.contextNode(name)
+	if err != nil {
+		return err
+	}
+
+	newContextNode := copyNode(contextNode)
+	valueOf(newContextNode, "name").Value = newName
+	contexts.Content = append(contexts.Content, newContextNode)
+	return nil
+}
+
+// copyNode returns a deep copy of the given node
+func copyNode(node *yaml.Node) *yaml.Node {
+	c := *node
+	c.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		c.Content[i] = copyNode(child)
+	}
+	return &c
+}
+
 func (k *Kubeconfig) RemoveContext(name string) error {
 	contexts := valueOf(k.rootNode, "contexts")
 	if contexts == nil {
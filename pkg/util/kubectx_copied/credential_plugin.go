@@ -0,0 +1,99 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeconfigutil
+
+import (
+	"fmt"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// credentialPluginCommand and credentialPluginArgs are the exec plugin of the users of switched contexts
+var (
+	credentialPluginCommand string
+	credentialPluginArgs    []string
+)
+
+// credentialPluginExecConfig is the exec plugin configuration written for the credential plugin
+type credentialPluginExecConfig struct {
+	APIVersion      string   `yaml:"apiVersion"`
+	Command         string   `yaml:"command"`
+	Args            []string `yaml:"args"`
+	InteractiveMode string   `yaml:"interactiveMode"`
+}
+
+// SetCredentialPlugin configures the command that replaces the exec plugin of the users of switched contexts.
+// The name of the context is appended to the given arguments.
+// If the command is empty, the exec plugins are not replaced.
+func SetCredentialPlugin(command string, args []string) {
+	credentialPluginCommand = command
+	credentialPluginArgs = args
+}
+
+// UseCredentialPlugin replaces the exec plugin of the user of the current context with the configured credential plugin.
+// Users without an exec plugin are not modified, as their credentials do not have to be refreshed.
+func (k *Kubeconfig) UseCredentialPlugin(contextName string) error {
+	if len(credentialPluginCommand) == 0 {
+		return nil
+	}
+
+	contextNode, err := k.contextNode(k.GetCurrentContext())
+	if err != nil {
+		return err
+	}
+
+	userName := valueOf(contextNode, "context")
+	if userName != nil {
+		userName = valueOf(userName, "user")
+	}
+	if userName == nil {
+		return nil
+	}
+
+	users := valueOf(k.rootNode, "users")
+	if users == nil || users.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	for _, userNode := range users.Content {
+		if nameNode := valueOf(userNode, "name"); nameNode == nil || nameNode.Value != userName.Value {
+			continue
+		}
+
+		user := valueOf(userNode, "user")
+		if user == nil {
+			return nil
+		}
+
+		execNode := valueOf(user, "exec")
+		if execNode == nil || execNode.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		execConfig := yaml.Node{}
+		if err := execConfig.Encode(credentialPluginExecConfig{
+			APIVersion:      "client.authentication.k8s.io/v1",
+			Command:         credentialPluginCommand,
+			Args:            append(slices.Clone(credentialPluginArgs), contextName),
+			InteractiveMode: "Never",
+		}); err != nil {
+			return fmt.Errorf("failed to set credential plugin for user %q: %w", userName.Value, err)
+		}
+		*execNode = execConfig
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeconfigutil_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+)
+
+var _ = Describe("UseCredentialPlugin", func() {
+	const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: exec
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+contexts:
+- name: exec
+  context:
+    cluster: dev
+    user: exec
+- name: static
+  context:
+    cluster: dev
+    user: static
+users:
+- name: static
+  user:
+    token: static-token
+- name: exec
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: kubelogin
+      args:
+      - get-token
+      env:
+      - name: SECRET
+        value: value
+`
+
+	AfterEach(func() {
+		kubeconfigutil.SetCredentialPlugin("", nil)
+	})
+
+	// useCredentialPlugin replaces the exec plugin of the user of the given current context and returns the resulting kubeconfig
+	useCredentialPlugin := func(currentContext string) *clientcmdapi.Config {
+		k, err := kubeconfigutil.New([]byte(kubeconfig), "", false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(k.ModifyCurrentContext(currentContext)).To(Succeed())
+
+		Expect(k.UseCredentialPlugin("team/" + currentContext)).To(Succeed())
+
+		data, err := k.GetBytes()
+		Expect(err).ToNot(HaveOccurred())
+		config, err := clientcmd.Load(data)
+		Expect(err).ToNot(HaveOccurred())
+		return config
+	}
+
+	It("should not modify the exec plugin if no credential plugin is configured", func() {
+		config := useCredentialPlugin("exec")
+		Expect(config.AuthInfos["exec"].Exec.Command).To(Equal("kubelogin"))
+		Expect(config.AuthInfos["exec"].Exec.Args).To(Equal([]string{"get-token"}))
+	})
+
+	It("should replace the exec plugin of the user of the current context with the credential plugin", func() {
+		kubeconfigutil.SetCredentialPlugin("/usr/local/bin/switcher", []string{"credential-plugin", "--kubeconfig-path", "/kubeconfigs"})
+
+		config := useCredentialPlugin("exec")
+		Expect(config.AuthInfos["exec"].Exec).To(Equal(&clientcmdapi.ExecConfig{
+			APIVersion:      "client.authentication.k8s.io/v1",
+			Command:         "/usr/local/bin/switcher",
+			Args:            []string{"credential-plugin", "--kubeconfig-path", "/kubeconfigs", "team/exec"},
+			InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+		}))
+		Expect(config.AuthInfos["static"].Token).To(Equal("static-token"))
+	})
+
+	It("should not modify the configured arguments of the credential plugin", func() {
+		args := make([]string, 1, 2)
+		args[0] = "credential-plugin"
+		kubeconfigutil.SetCredentialPlugin("switcher", args)
+
+		useCredentialPlugin("exec")
+		config := useCredentialPlugin("exec")
+		Expect(config.AuthInfos["exec"].Exec.Args).To(Equal([]string{"credential-plugin", "team/exec"}))
+		Expect(args[:cap(args)][1]).To(BeEmpty())
+	})
+
+	It("should not modify users without an exec plugin", func() {
+		kubeconfigutil.SetCredentialPlugin("switcher", []string{"credential-plugin"})
+
+		config := useCredentialPlugin("static")
+		Expect(config.AuthInfos["static"].Exec).To(BeNil())
+		Expect(config.AuthInfos["static"].Token).To(Equal("static-token"))
+		Expect(config.AuthInfos["exec"].Exec.Command).To(Equal("kubelogin"))
+	})
+
+	It("should fail if the current context does not exist", func() {
+		kubeconfigutil.SetCredentialPlugin("switcher", []string{"credential-plugin"})
+
+		k, err := kubeconfigutil.New([]byte(kubeconfig), "", false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(k.ModifyCurrentContext("missing")).To(Succeed())
+		Expect(k.UseCredentialPlugin("missing")).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,40 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeconfigutil
+
+import "fmt"
+
+// Format is the serialization format of a kubeconfig
+type Format string
+
+const (
+	// FormatYAML serializes kubeconfigs as YAML (default)
+	FormatYAML Format = "yaml"
+	// FormatJSON serializes kubeconfigs as JSON
+	FormatJSON Format = "json"
+)
+
+// ParseFormat returns the kubeconfig format with the given name.
+// An empty name defaults to YAML.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", FormatYAML:
+		return FormatYAML, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported kubeconfig format %q: must be one of %q, %q", name, FormatYAML, FormatJSON)
+	}
+}
@@ -15,14 +15,16 @@
 package kubeconfigutil
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
-
 	"gopkg.in/yaml.v3"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
 )
 
 const (
@@ -150,39 +152,24 @@ func (k *Kubeconfig) SetNamespaceForCurrentContext(namespace string) error {
 }
 
 // WriteKubeconfigFile writes kubeconfig bytes to the local filesystem
-// and returns the kubeconfig path
+// and returns the kubeconfig path.
+// Kubeconfigs created with NewKubeconfig are written by the configured Writer (see SetOutputPath).
 func (k *Kubeconfig) WriteKubeconfigFile() (string, error) {
-	var (
-		file *os.File
-		err  error
-	)
-	// if we do not use a tmp file, then k.path is the path to a directory to create the tmp file in
 	if k.useTmpFile {
-		err = os.Mkdir(k.path, 0700)
-		if err != nil && !os.IsExist(err) {
-			return "", err
-		}
-
-		// write temporary kubeconfig file
-		file, err = os.CreateTemp(k.path, "config.*.tmp")
-		if err != nil {
-			return "", err
-		}
-	} else {
-		file, err = os.OpenFile(k.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-		if err != nil {
-			return "", fmt.Errorf("failed to open existing kubeconfig file: %v", err)
-		}
+		return writer.Write(k)
 	}
 
-	enc := yaml.NewEncoder(file)
-	enc.SetIndent(0)
-
-	if err := enc.Encode(k.rootNode); err != nil {
+	// existing kubeconfigs keep their format
+	buf := bytes.Buffer{}
+	if err := encode(&buf, k, k.format()); err != nil {
 		return "", err
 	}
 
-	return file.Name(), nil
+	if err := kubeswitchio.WriteFile(k.path, buf.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write existing kubeconfig file: %v", err)
+	}
+
+	return k.path, nil
 }
 
 func (k *Kubeconfig) GetBytes() ([]byte, error) {
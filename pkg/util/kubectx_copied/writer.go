@@ -0,0 +1,182 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeconfigutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	clientcmdapiv1 "k8s.io/client-go/tools/clientcmd/api/v1"
+
+	kubeswitchio "github.com/danielfoehrkn/kubeswitch/pkg/io"
+)
+
+// Writer writes the kubeconfig of a switched context and returns the path of the written file
+type Writer interface {
+	Write(k *Kubeconfig) (string, error)
+}
+
+// writer is used for all kubeconfigs created with NewKubeconfig
+var writer Writer = temporaryFileWriter{}
+
+// outputFormat is the format of kubeconfigs written by the writer
+var outputFormat = FormatYAML
+
+// SetOutputFormat configures the format ("yaml" or "json") the kubeconfigs of switched contexts are written in
+func SetOutputFormat(format string) error {
+	f, err := ParseFormat(format)
+	if err != nil {
+		return err
+	}
+	outputFormat = f
+	return nil
+}
+
+// SetOutputPath configures where the kubeconfigs of switched contexts are written to.
+// The path is a Go template, the name of the context is available as {{ .ContextName }}.
+// If the path does not depend on the context, the same file is overwritten on every switch.
+// If the path is empty, a new temporary kubeconfig file is written for every switch.
+func SetOutputPath(path string) error {
+	if len(path) == 0 {
+		writer = temporaryFileWriter{}
+		return nil
+	}
+
+	pathTemplate, err := template.New("kubeconfigOutputPath").Option("missingkey=error").Parse(path)
+	if err != nil {
+		return fmt.Errorf("failed to parse kubeconfig output path %q: %w", path, err)
+	}
+	writer = outputPathWriter{pathTemplate: pathTemplate}
+	return nil
+}
+
+// IsTemporaryKubeconfigFile returns true if the path is a temporary kubeconfig file created by kubeswitch
+// that can safely be removed
+func IsTemporaryKubeconfigFile(path string) bool {
+	return filepath.Dir(path) == filepath.Clean(os.ExpandEnv(TemporaryKubeconfigDir))
+}
+
+// temporaryFileWriter writes every kubeconfig to a new file in the temporary kubeconfig directory
+type temporaryFileWriter struct{}
+
+func (temporaryFileWriter) Write(k *Kubeconfig) (string, error) {
+	// the path of kubeconfigs using a tmp file is the directory to create the tmp file in
+	directory := k.path
+	if err := kubeswitchio.MkdirAll(directory, 0700); err != nil {
+		return "", err
+	}
+
+	buf := bytes.Buffer{}
+	if err := encode(&buf, k, outputFormat); err != nil {
+		return "", err
+	}
+	return kubeswitchio.CreateTemp(directory, "config.*.tmp", buf.Bytes())
+}
+
+// outputPathWriter writes the kubeconfig to a path templated with the context name
+type outputPathWriter struct {
+	pathTemplate *template.Template
+}
+
+func (w outputPathWriter) Write(k *Kubeconfig) (string, error) {
+	contextName := k.GetKubeswitchContext()
+	if len(contextName) == 0 {
+		contextName = k.GetCurrentContext()
+	}
+
+	// context names of stores with a prefix contain a "/" which must not result in nested directories
+	contextName = strings.NewReplacer("/", "_", "\\", "_").Replace(contextName)
+
+	buf := bytes.Buffer{}
+	if err := w.pathTemplate.Execute(&buf, struct{ ContextName string }{ContextName: contextName}); err != nil {
+		return "", fmt.Errorf("failed to render kubeconfig output path: %w", err)
+	}
+	path := buf.String()
+
+	if err := kubeswitchio.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+
+	buf.Reset()
+	if err := encode(&buf, k, outputFormat); err != nil {
+		return "", err
+	}
+	if err := kubeswitchio.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return "", fmt.Errorf("failed to write kubeconfig output file: %v", err)
+	}
+	return path, nil
+}
+
+// jsonKubeconfig is the versioned kubeconfig including the top-level fields added by kubeswitch,
+// which are required for subsequent runs
+type jsonKubeconfig struct {
+	clientcmdapiv1.Config
+	KubeswitchContext         string `json:"kubeswitch-context,omitempty"`
+	GardenerLandscapeIdentity string `json:"gardener-landscape-identity,omitempty"`
+	GardenerProject           string `json:"gardener-project,omitempty"`
+	GardenerClusterName       string `json:"gardener-cluster-name,omitempty"`
+	GardenerClusterType       string `json:"gardener-cluster-type,omitempty"`
+}
+
+func encode(w io.Writer, k *Kubeconfig, format Format) error {
+	if format == FormatJSON {
+		data, err := yaml.Marshal(k.rootNode)
+		if err != nil {
+			return err
+		}
+		config, err := clientcmd.Load(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse kubeconfig: %w", err)
+		}
+
+		document := jsonKubeconfig{
+			KubeswitchContext:         k.GetKubeswitchContext(),
+			GardenerLandscapeIdentity: k.GetGardenerLandscapeIdentity(),
+			GardenerProject:           k.GetGardenerProject(),
+			GardenerClusterName:       k.GetGardenerClusterName(),
+			GardenerClusterType:       k.GetGardenerClusterType(),
+		}
+		if err := clientcmdlatest.Scheme.Convert(config, &document.Config, nil); err != nil {
+			return fmt.Errorf("failed to convert kubeconfig: %w", err)
+		}
+		document.APIVersion = clientcmdlatest.Version
+		document.Kind = "Config"
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(document)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(0)
+	return enc.Encode(k.rootNode)
+}
+
+// format returns the format the kubeconfig was read in. JSON documents are parsed as YAML flow mappings.
+func (k *Kubeconfig) format() Format {
+	if k.rootNode.Style&yaml.FlowStyle != 0 {
+		return FormatJSON
+	}
+	return FormatYAML
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeconfigutil_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/tools/clientcmd"
+
+	kubeconfigutil "github.com/danielfoehrkn/kubeswitch/pkg/util/kubectx_copied"
+)
+
+var _ = Describe("Writer", func() {
+	const kubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com
+    certificate-authority-data: Y2EtZGF0YQ==
+    insecure-skip-verify: false
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+    namespace: "1234"
+users:
+- name: admin
+  user:
+    token: admin-token
+`
+
+	var tempDir string
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = os.MkdirTemp("", "kubeswitch-writer")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(kubeconfigutil.SetOutputFormat("")).To(Succeed())
+		Expect(kubeconfigutil.SetOutputPath("")).To(Succeed())
+		Expect(os.RemoveAll(tempDir)).To(Succeed())
+	})
+
+	// writeKubeconfig writes the kubeconfig of the switched context "team/dev" and returns the written data
+	writeKubeconfig := func(format string) []byte {
+		Expect(kubeconfigutil.SetOutputFormat(format)).To(Succeed())
+		Expect(kubeconfigutil.SetOutputPath(filepath.Join(tempDir, "{{ .ContextName }}.config"))).To(Succeed())
+
+		k, err := kubeconfigutil.NewKubeconfig([]byte(kubeconfig))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(k.SetKubeswitchContext("team/dev")).To(Succeed())
+		Expect(k.SetGardenerStoreMetaInformation("landscape", "Shoot", "project", "shoot")).To(Succeed())
+
+		path, err := k.WriteKubeconfigFile()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal(filepath.Join(tempDir, "team_dev.config")))
+
+		data, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		return data
+	}
+
+	It("should write YAML by default", func() {
+		data := writeKubeconfig("")
+		Expect(json.Valid(data)).To(BeFalse())
+		Expect(string(data)).To(HavePrefix("apiVersion: v1\n"))
+		Expect(string(data)).To(ContainSubstring("kubeswitch-context: team/dev"))
+	})
+
+	It("should write the versioned kubeconfig as JSON", func() {
+		data := writeKubeconfig("json")
+		Expect(json.Valid(data)).To(BeTrue())
+
+		config, err := clientcmd.Load(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.CurrentContext).To(Equal("dev"))
+		Expect(config.Clusters["dev"].Server).To(Equal("https://dev.example.com"))
+		Expect(config.Clusters["dev"].CertificateAuthorityData).To(Equal([]byte("ca-data")))
+		Expect(config.AuthInfos["admin"].Token).To(Equal("admin-token"))
+	})
+
+	It("should keep the types of the kubeconfig fields", func() {
+		var document struct {
+			Contexts []struct {
+				Context map[string]interface{} `json:"context"`
+			} `json:"contexts"`
+		}
+		Expect(json.Unmarshal(writeKubeconfig("json"), &document)).To(Succeed())
+		Expect(document.Contexts).To(HaveLen(1))
+		Expect(document.Contexts[0].Context).To(HaveKeyWithValue("namespace", "1234"))
+	})
+
+	It("should write the fields in the order of the versioned kubeconfig followed by the fields added by kubeswitch", func() {
+		data := string(writeKubeconfig("json"))
+
+		var offsets []int
+		for _, key := range []string{"kind", "apiVersion", "clusters", "users", "contexts", "current-context", "kubeswitch-context", "gardener-landscape-identity"} {
+			offset := strings.Index(data, "\n  \""+key+"\":")
+			Expect(offset).To(BeNumerically(">=", 0), "key %q not found", key)
+			offsets = append(offsets, offset)
+		}
+		Expect(sort.IntsAreSorted(offsets)).To(BeTrue())
+	})
+
+	It("should keep the fields added by kubeswitch so that the written JSON kubeconfig can be read again", func() {
+		k, err := kubeconfigutil.New(writeKubeconfig("json"), "", false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(k.GetKubeswitchContext()).To(Equal("team/dev"))
+		Expect(k.GetGardenerLandscapeIdentity()).To(Equal("landscape"))
+		Expect(k.GetGardenerClusterType()).To(Equal("Shoot"))
+		Expect(k.GetGardenerProject()).To(Equal("project"))
+		Expect(k.GetGardenerClusterName()).To(Equal("shoot"))
+	})
+
+	It("should keep the JSON format of an existing kubeconfig", func() {
+		path := filepath.Join(tempDir, "existing.json")
+		Expect(kubeconfigutil.SetOutputFormat("json")).To(Succeed())
+		Expect(kubeconfigutil.SetOutputPath(path)).To(Succeed())
+		k, err := kubeconfigutil.NewKubeconfig([]byte(kubeconfig))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = k.WriteKubeconfigFile()
+		Expect(err).ToNot(HaveOccurred())
+
+		existing, err := kubeconfigutil.NewKubeconfigForPath(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(existing.SetNamespaceForCurrentContext("changed")).To(Succeed())
+		_, err = existing.WriteKubeconfigFile()
+		Expect(err).ToNot(HaveOccurred())
+
+		data, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Valid(data)).To(BeTrue())
+		config, err := clientcmd.Load(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Contexts["dev"].Namespace).To(Equal("changed"))
+	})
+})
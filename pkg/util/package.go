@@ -0,0 +1,30 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import "github.com/danielfoehrkn/kubeswitch/types"
+
+// GetPackageName returns the name of the first package containing the given context name
+// or an empty string if the context does not belong to any package.
+// The context patterns of a package are evaluated like exclusion patterns, so a pattern prefixed with "!"
+// removes contexts matched by a previous pattern from the package.
+func GetPackageName(contextName string, packages []types.PackageConfig) string {
+	for _, p := range packages {
+		if IsContextExcluded(contextName, p.Contexts) {
+			return p.Name
+		}
+	}
+	return ""
+}
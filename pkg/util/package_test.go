@@ -0,0 +1,46 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+	"github.com/danielfoehrkn/kubeswitch/types"
+)
+
+var _ = Describe("GetPackageName", func() {
+	packages := []types.PackageConfig{
+		{Name: "ring-0", Contexts: []string{"mgmt-*"}},
+		{Name: "ring-1", Contexts: []string{"infra-*", "!infra-sandbox"}},
+		{Name: "ring-2", Contexts: []string{"*-apps-*", "infra-*"}},
+	}
+
+	It("should return the first package containing the context", func() {
+		Expect(util.GetPackageName("mgmt-eu", packages)).To(Equal("ring-0"))
+		Expect(util.GetPackageName("infra-eu", packages)).To(Equal("ring-1"))
+		Expect(util.GetPackageName("eu-apps-1", packages)).To(Equal("ring-2"))
+	})
+
+	It("should skip packages excluding the context", func() {
+		Expect(util.GetPackageName("infra-sandbox", packages)).To(Equal("ring-2"))
+	})
+
+	It("should return an empty package name if no package contains the context", func() {
+		Expect(util.GetPackageName("dev", packages)).To(BeEmpty())
+		Expect(util.GetPackageName("mgmt-eu", nil)).To(BeEmpty())
+	})
+})
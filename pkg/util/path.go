@@ -0,0 +1,27 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+// ResolveKubeconfigPath expands environment variables and a leading "~" in the given path
+// and converts it into a path of the current platform.
+//   - On Windows, "~" is expanded to %USERPROFILE% and forward slashes are converted to backslashes.
+//   - In WSL, Windows paths such as "C:\Users\me\.kube\config" are translated to the mounted Linux path.
+//   - On other POSIX systems, only environment variables and "~" are expanded.
+func ResolveKubeconfigPath(rawPath string) string {
+	if len(rawPath) == 0 {
+		return rawPath
+	}
+	return resolvePath(rawPath)
+}
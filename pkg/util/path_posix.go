@@ -0,0 +1,71 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package util
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// procVersionPath is read to detect if kubeswitch runs in WSL
+	procVersionPath = "/proc/version"
+	// wslpathCommand translates Windows paths to WSL paths
+	wslpathCommand = "wslpath"
+	// windowsPathRegex matches absolute Windows paths such as C:\Users or C:/Users
+	windowsPathRegex = regexp.MustCompile(`^[a-zA-Z]:[\\/]`)
+)
+
+func resolvePath(rawPath string) string {
+	path := rawPath
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = "$HOME" + path[1:]
+	}
+	path = os.ExpandEnv(path)
+
+	if windowsPathRegex.MatchString(path) && isWSL() {
+		return translateWindowsPath(path)
+	}
+	return path
+}
+
+// isWSL returns true if kubeswitch runs in the Windows Subsystem for Linux
+func isWSL() bool {
+	version, err := os.ReadFile(procVersionPath)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(version)), "microsoft")
+}
+
+// translateWindowsPath translates the Windows path into the path of the mounted Windows drive in WSL
+// using wslpath. Falls back to the default mount point /mnt/<drive> if wslpath is not available.
+func translateWindowsPath(path string) string {
+	out, err := exec.Command(wslpathCommand, "-u", path).Output()
+	if err == nil {
+		return strings.TrimSpace(string(out))
+	}
+	logrus.Debugf("failed to translate Windows path %q using wslpath: %v", path, err)
+
+	drive := strings.ToLower(path[:1])
+	return filepath.Join("/mnt", drive, strings.ReplaceAll(path[3:], "\\", "/"))
+}
@@ -0,0 +1,59 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+
+package util_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var _ = Describe("ResolveKubeconfigPath (POSIX)", func() {
+	var home string
+
+	BeforeEach(func() {
+		home = os.Getenv("HOME")
+		os.Setenv("HOME", "/home/user")
+		os.Setenv("KUBESWITCH_TEST_DIR", "/tmp/kubeconfigs")
+	})
+
+	AfterEach(func() {
+		os.Setenv("HOME", home)
+		os.Unsetenv("KUBESWITCH_TEST_DIR")
+	})
+
+	It("should expand a leading ~", func() {
+		Expect(util.ResolveKubeconfigPath("~")).To(Equal("/home/user"))
+		Expect(util.ResolveKubeconfigPath("~/.kube/config")).To(Equal("/home/user/.kube/config"))
+	})
+
+	It("should not expand a ~ within the path", func() {
+		Expect(util.ResolveKubeconfigPath("/data/~/config")).To(Equal("/data/~/config"))
+	})
+
+	It("should expand environment variables", func() {
+		Expect(util.ResolveKubeconfigPath("$KUBESWITCH_TEST_DIR/config")).To(Equal("/tmp/kubeconfigs/config"))
+		Expect(util.ResolveKubeconfigPath("$HOME/.kube/config")).To(Equal("/home/user/.kube/config"))
+	})
+
+	It("should return an empty path", func() {
+		Expect(util.ResolveKubeconfigPath("")).To(BeEmpty())
+	})
+})
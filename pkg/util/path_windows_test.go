@@ -0,0 +1,52 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+
+package util_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var _ = Describe("ResolveKubeconfigPath (Windows)", func() {
+	var userProfile string
+
+	BeforeEach(func() {
+		userProfile = os.Getenv("USERPROFILE")
+		os.Setenv("USERPROFILE", `C:\Users\me`)
+	})
+
+	AfterEach(func() {
+		os.Setenv("USERPROFILE", userProfile)
+	})
+
+	It("should expand a leading ~ to the user profile", func() {
+		Expect(util.ResolveKubeconfigPath("~/.kube/config")).To(Equal(`C:\Users\me\.kube\config`))
+		Expect(util.ResolveKubeconfigPath(`~\.kube\config`)).To(Equal(`C:\Users\me\.kube\config`))
+	})
+
+	It("should convert forward slashes", func() {
+		Expect(util.ResolveKubeconfigPath("C:/kubeconfigs/config")).To(Equal(`C:\kubeconfigs\config`))
+	})
+
+	It("should not modify short names containing a ~", func() {
+		Expect(util.ResolveKubeconfigPath(`C:\PROGRA~1\config`)).To(Equal(`C:\PROGRA~1\config`))
+	})
+})
@@ -0,0 +1,71 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResolveKubeconfigPath (WSL)", func() {
+	var (
+		tmpDir                 string
+		originalProcVersion    string
+		originalWslpathCommand string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "kubeswitch-wsl")
+		Expect(err).ToNot(HaveOccurred())
+		originalProcVersion, originalWslpathCommand = procVersionPath, wslpathCommand
+
+		procVersionPath = filepath.Join(tmpDir, "version")
+		Expect(os.WriteFile(procVersionPath, []byte("Linux version 5.15.153.1-microsoft-standard-WSL2"), 0600)).To(Succeed())
+
+		// fake wslpath printing a fixed path
+		wslpathCommand = filepath.Join(tmpDir, "wslpath")
+		Expect(os.WriteFile(wslpathCommand, []byte("#!/bin/sh\necho /mnt/wslpath/config\n"), 0700)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		procVersionPath, wslpathCommand = originalProcVersion, originalWslpathCommand
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	It("should translate Windows paths using wslpath", func() {
+		Expect(ResolveKubeconfigPath(`C:\Users\me\.kube\config`)).To(Equal("/mnt/wslpath/config"))
+	})
+
+	It("should translate Windows paths to the default mount point if wslpath is not available", func() {
+		wslpathCommand = filepath.Join(tmpDir, "does-not-exist")
+		Expect(ResolveKubeconfigPath(`C:\Users\me\.kube\config`)).To(Equal("/mnt/c/Users/me/.kube/config"))
+		Expect(ResolveKubeconfigPath("D:/kubeconfigs/config")).To(Equal("/mnt/d/kubeconfigs/config"))
+	})
+
+	It("should not translate Linux paths", func() {
+		Expect(ResolveKubeconfigPath("/home/me/.kube/config")).To(Equal("/home/me/.kube/config"))
+	})
+
+	It("should not translate Windows paths outside of WSL", func() {
+		Expect(os.WriteFile(procVersionPath, []byte("Linux version 6.8.0-generic"), 0600)).To(Succeed())
+		Expect(ResolveKubeconfigPath(`C:\Users\me\.kube\config`)).To(Equal(`C:\Users\me\.kube\config`))
+	})
+})
@@ -0,0 +1,28 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"regexp"
+)
+
+// illegalContextNameCharacters matches all characters that are not allowed in sanitized context names
+var illegalContextNameCharacters = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// SanitizeContextName replaces every sequence of characters that are not alphanumeric, "-", "_" or "." with a single "-".
+// The sanitization is idempotent.
+func SanitizeContextName(name string) string {
+	return illegalContextNameCharacters.ReplaceAllString(name, "-")
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var _ = Describe("SanitizeContextName", func() {
+	It("should not modify a valid context name", func() {
+		Expect(util.SanitizeContextName("my-cluster_01.dev")).To(Equal("my-cluster_01.dev"))
+	})
+
+	It("should replace spaces", func() {
+		Expect(util.SanitizeContextName("my cluster")).To(Equal("my-cluster"))
+	})
+
+	It("should replace slashes and colons", func() {
+		Expect(util.SanitizeContextName("arn:aws:eks:eu-west-1:123456789:cluster/prod")).To(Equal("arn-aws-eks-eu-west-1-123456789-cluster-prod"))
+	})
+
+	It("should replace angle brackets", func() {
+		Expect(util.SanitizeContextName("<admin>@cluster")).To(Equal("-admin-cluster"))
+	})
+
+	It("should replace unicode characters", func() {
+		Expect(util.SanitizeContextName("clüster-äöü")).To(Equal("cl-ster--"))
+		Expect(util.SanitizeContextName("集群")).To(Equal("-"))
+	})
+
+	It("should replace consecutive illegal characters with a single dash", func() {
+		Expect(util.SanitizeContextName("a :/ b")).To(Equal("a-b"))
+	})
+
+	It("should be idempotent", func() {
+		for _, name := range []string{"my cluster", "arn:aws:eks/prod", "clüster", "<admin>@cluster", "valid-name"} {
+			sanitized := util.SanitizeContextName(name)
+			Expect(util.SanitizeContextName(sanitized)).To(Equal(sanitized))
+		}
+	})
+})
@@ -0,0 +1,38 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"github.com/becheran/wildmatch-go"
+)
+
+// MatchesTags checks if the given tags contain all required tags and none of the forbidden tags.
+// The values of the required and forbidden tags are wildcard patterns ('*' and '?').
+func MatchesTags(tags, requiredTags, forbiddenTags map[string]string) bool {
+	for key, pattern := range requiredTags {
+		value, ok := tags[key]
+		if !ok || !wildmatch.NewWildMatch(pattern).IsMatch(value) {
+			return false
+		}
+	}
+
+	for key, pattern := range forbiddenTags {
+		value, ok := tags[key]
+		if ok && wildmatch.NewWildMatch(pattern).IsMatch(value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The Kubeswitch authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/danielfoehrkn/kubeswitch/pkg/util"
+)
+
+var _ = Describe("MatchesTags", func() {
+	tags := map[string]string{
+		"env":  "production",
+		"team": "platform",
+	}
+
+	It("should match any tags without required or forbidden tags", func() {
+		Expect(util.MatchesTags(tags, nil, nil)).To(BeTrue())
+		Expect(util.MatchesTags(nil, nil, nil)).To(BeTrue())
+	})
+
+	It("should only match tags containing all required tags", func() {
+		Expect(util.MatchesTags(tags, map[string]string{"env": "production", "team": "platform"}, nil)).To(BeTrue())
+		Expect(util.MatchesTags(tags, map[string]string{"env": "production", "team": "network"}, nil)).To(BeFalse())
+		Expect(util.MatchesTags(tags, map[string]string{"region": "eu"}, nil)).To(BeFalse())
+		Expect(util.MatchesTags(nil, map[string]string{"env": "*"}, nil)).To(BeFalse())
+	})
+
+	It("should not match tags containing any forbidden tag", func() {
+		Expect(util.MatchesTags(tags, nil, map[string]string{"env": "staging"})).To(BeTrue())
+		Expect(util.MatchesTags(tags, nil, map[string]string{"env": "staging", "team": "platform"})).To(BeFalse())
+		Expect(util.MatchesTags(tags, nil, map[string]string{"region": "*"})).To(BeTrue())
+	})
+
+	It("should match glob values", func() {
+		for _, env := range []string{"prod", "prod-eu", "production"} {
+			Expect(util.MatchesTags(map[string]string{"env": env}, map[string]string{"env": "prod*"}, nil)).To(BeTrue())
+			Expect(util.MatchesTags(map[string]string{"env": env}, nil, map[string]string{"env": "prod*"})).To(BeFalse())
+		}
+		Expect(util.MatchesTags(map[string]string{"env": "preprod"}, map[string]string{"env": "prod*"}, nil)).To(BeFalse())
+		Expect(util.MatchesTags(map[string]string{"env": "dev-1"}, map[string]string{"env": "dev-?"}, nil)).To(BeTrue())
+	})
+})
@@ -24,7 +24,16 @@ import (
 type StoreKind string
 
 // ValidStoreKinds contains all valid store kinds
-var ValidStoreKinds = sets.NewString(string(StoreKindVault), string(StoreKindFilesystem), string(StoreKindGardener), string(StoreKindGKE), string(StoreKindAzure), string(StoreKindEKS), string(StoreKindRancher), string(StoreKindOVH), string(StoreKindScaleway), string(StoreKindDigitalOcean), string(StoreKindAkamai), string(StoreKindCapi))
+var ValidStoreKinds = sets.NewString(string(StoreKindVault), string(StoreKindFilesystem), string(StoreKindGardener), string(StoreKindGKE), string(StoreKindAzure), string(StoreKindEKS), string(StoreKindRancher), string(StoreKindOVH), string(StoreKindScaleway), string(StoreKindDigitalOcean), string(StoreKindAkamai), string(StoreKindCapi), string(StoreKindComposite), string(StoreKindFallback), string(StoreKindWebDAV), string(StoreKindConsul), string(StoreKindEtcd), string(StoreKindFirestore), string(StoreKindHTTP), string(StoreKindRoundRobin), string(StoreKindFailover), string(StoreKindScript))
+
+// ValidStoreLogLevels contains all valid log levels of kubeconfig stores
+var ValidStoreLogLevels = sets.NewString("trace", "debug", "info", "warn", "error")
+
+// ValidEnricherTypes contains all valid enricher types
+var ValidEnricherTypes = sets.NewString(string(EnricherTypeHTTP), string(EnricherTypeCommand))
+
+// ValidTransformerTypes contains all valid kubeconfig transformer types
+var ValidTransformerTypes = sets.NewString(string(TransformerTypeServerRewrite), string(TransformerTypeInjectProxyURL), string(TransformerTypeStripToken), string(TransformerTypeSetNamespace), string(TransformerTypeAddLabel))
 
 // ValidConfigVersions contains all valid config versions
 var ValidConfigVersions = sets.NewString("v1alpha1")
@@ -54,6 +63,62 @@ const (
 	StoreKindAkamai StoreKind = "akamai"
 	// StoreKindCapi is an identifier for the CAPI store
 	StoreKindCapi StoreKind = "capi"
+	// StoreKindComposite is an identifier for the composite store aggregating multiple stores
+	StoreKindComposite StoreKind = "composite"
+	// StoreKindFallback is an identifier for the fallback store wrapping a primary and a fallback store
+	StoreKindFallback StoreKind = "fallback"
+	// StoreKindRoundRobin is an identifier for the store distributing the requests across identical replicas of a store
+	StoreKindRoundRobin StoreKind = "roundrobin"
+	// StoreKindFailover is an identifier for the store trying identical replicas of a store in order
+	StoreKindFailover StoreKind = "failover"
+	// StoreKindWebDAV is an identifier for the WebDAV store
+	StoreKindWebDAV StoreKind = "webdav"
+	// StoreKindConsul is an identifier for the Consul KV store
+	StoreKindConsul StoreKind = "consul"
+	// StoreKindEtcd is an identifier for the etcd store
+	StoreKindEtcd StoreKind = "etcd"
+	// StoreKindFirestore is an identifier for the Google Cloud Firestore store
+	StoreKindFirestore StoreKind = "firestore"
+	// StoreKindHTTP is an identifier for the store downloading kubeconfigs from HTTP(S) endpoints
+	StoreKindHTTP StoreKind = "http"
+	// StoreKindScript is an identifier for the store generating the kubeconfigs with a Lua script or CEL expression
+	StoreKindScript StoreKind = "script"
+)
+
+// SortOrder defines how the search results are ordered in the selection dialog
+type SortOrder string
+
+// ValidSortOrders contains all valid sort orders
+var ValidSortOrders = sets.NewString(string(SortOrderNone), string(SortOrderAlphabetical), string(SortOrderStore), string(SortOrderFrecency), string(SortOrderPriority))
+
+const (
+	// SortOrderNone keeps the order in which the stores return the results
+	SortOrderNone SortOrder = "none"
+	// SortOrderAlphabetical sorts the results by context name
+	SortOrderAlphabetical SortOrder = "alphabetical"
+	// SortOrderStore groups the results by store and sorts alphabetically within each store
+	SortOrderStore SortOrder = "store"
+	// SortOrderFrecency sorts the results by how frequently and recently a context has been used
+	SortOrderFrecency SortOrder = "frecency"
+	// SortOrderPriority sorts the results by the priority score of their store (highest first)
+	// and alphabetically within stores of the same priority score
+	SortOrderPriority SortOrder = "priority"
+)
+
+// GroupSortOrder defines how the groups of search results are ordered in the selection dialog
+type GroupSortOrder string
+
+// ValidGroupSortOrders contains all valid group sort orders
+var ValidGroupSortOrders = sets.NewString(string(GroupSortOrderAsc), string(GroupSortOrderDesc), string(GroupSortOrderCountDesc))
+
+const (
+	// GroupSortOrderAsc sorts the groups alphabetically by tag value
+	GroupSortOrderAsc GroupSortOrder = "asc"
+	// GroupSortOrderDesc sorts the groups reverse alphabetically by tag value
+	GroupSortOrderDesc GroupSortOrder = "desc"
+	// GroupSortOrderCountDesc sorts the groups by their number of search results (largest first)
+	// and alphabetically by tag value for groups of the same size
+	GroupSortOrderCountDesc GroupSortOrder = "count-desc"
 )
 
 type Config struct {
@@ -82,12 +147,226 @@ type Config struct {
 	// Can be overridden in the individual kubeconfig store configuration
 	// + optional
 	RefreshIndexAfter *time.Duration `yaml:"refreshIndexAfter"`
+	// SortOrder defines how the search results are ordered in the selection dialog.
+	// Possible values: "none", "alphabetical", "store", "frecency", "priority"
+	// default: priority if a store has a priority score configured, otherwise none (order in which the stores return the results)
+	// + optional
+	SortOrder *SortOrder `yaml:"sortOrder"`
+	// SortDeadline is the maximum time to wait for all stores to finish the search
+	// before the results are sorted.
+	// Results discovered afterwards are appended in arrival order.
+	// default: 5s
+	// + optional
+	SortDeadline *time.Duration `yaml:"sortDeadline"`
+	// PriorityDeadline is the maximum time to wait for all stores to finish the search
+	// before the results are sorted by the priority score of their store.
+	// Overrides the SortDeadline for the sort order "priority".
+	// default: SortDeadline
+	// + optional
+	PriorityDeadline *time.Duration `yaml:"priorityDeadline"`
+	// CompletionTimeout is the maximum duration of the search for the context names offered by the shell completion.
+	// No context names are offered if the search does not finish in time.
+	// default: 3s
+	// + optional
+	CompletionTimeout *time.Duration `yaml:"completionTimeout"`
+	// IgnoreStoreErrors configures if errors of kubeconfig stores during the search (such as search timeouts)
+	// are suppressed and not shown in the selection dialog.
+	// Can be overridden via command line flag --ignore-store-errors
+	// default: false
+	// + optional
+	IgnoreStoreErrors *bool `yaml:"ignoreStoreErrors"`
+	// PreflightConnectivityCheck configures if the API server of the selected context is checked for
+	// connectivity before switching to it. If the API server is unreachable, a confirmation is requested.
+	// Can be disabled via command line flag --no-connectivity-check
+	// default: false
+	// + optional
+	PreflightConnectivityCheck *bool `yaml:"preflightConnectivityCheck"`
+	// SanitizeContextNames configures if characters in context names that are not alphanumeric, "-", "_" or "."
+	// are replaced with "-" in the search results.
+	// default: false
+	// + optional
+	SanitizeContextNames *bool `yaml:"sanitizeContextNames"`
+	// ExcludeContexts contains context names or wildcard patterns of contexts that shall not be shown in the search results.
+	// Patterns prefixed with "!" re-include contexts excluded by a previous pattern.
+	// + optional
+	ExcludeContexts []string `yaml:"excludeContexts"`
+	// KubeconfigOutputPath is the path the kubeconfig of the selected context is written to
+	// instead of a new temporary file in ~/.kube/.switch_tmp. The KUBECONFIG environment variable points to this path after the switch.
+	// The path is a Go template, the context name is available as {{ .ContextName }}, e.g. "~/.kube/contexts/{{ .ContextName }}.yaml"
+	// to write one file per context.
+	// Can be overridden via command line flag --kubeconfig-output
+	// + optional
+	KubeconfigOutputPath *string `yaml:"kubeconfigOutputPath"`
+	// AuditLogPath is the path of the audit log recording every switch to a context together with the OS username.
+	// The audit log can be shared with other users, e.g. via a shared filesystem, and is evaluated by "switch usage".
+	// Switches are only recorded if the path is set, e.g. to ~/.kube/.switch_audit.log.
+	// + optional
+	AuditLogPath *string `yaml:"auditLogPath"`
+	// CredentialCache configures the cache for the credentials of cloud provider stores (EKS, GKE, Azure).
+	// The credentials are persisted in files in the state directory encrypted with the SSH key of the user.
+	// + optional
+	CredentialCache *CredentialCacheConfig `yaml:"credentialCache"`
+	// CredentialPlugin configures kubeswitch as the exec credential plugin of switched contexts.
+	// The exec plugin of the user of the selected context is replaced with "switch credential-plugin <context>",
+	// so that kubectl obtains a fresh token via kubeswitch whenever the token expired.
+	// Users with static credentials are not modified.
+	// default: false
+	// + optional
+	CredentialPlugin *bool `yaml:"credentialPlugin"`
+	// ShareSecret is the secret used to sign the URLs created with "switch share" and verified by "switch open".
+	// Everyone opening a shared URL needs the same secret and a store with the same ID.
+	// Environment variables are expanded, e.g. ${KUBESWITCH_SHARE_SECRET}.
+	// + optional
+	ShareSecret *string `yaml:"shareSecret"`
+	// Enrichers add metadata tags to the search results after all stores finished the search.
+	// The tags are shown in the selection dialog and in the preview.
+	// + optional
+	Enrichers []Enricher `yaml:"enrichers"`
+	// Monitor configures the monitor started with "switch monitor start".
+	// The monitor periodically probes the stores and watched contexts and alerts via Alertmanager when they become unreachable.
+	// + optional
+	Monitor *MonitorConfig `yaml:"monitor"`
+	// CompressCache gzip compresses the kubeconfigs cached by stores with a disk cache.
+	// Reduces the disk usage for large kubeconfigs, e.g. with embedded certificates.
+	// default: false
+	// + optional
+	CompressCache bool `yaml:"compressCache"`
+	// AutoGC runs "switch gc" in the background after each successful switch to remove stale files from the state directory.
+	// default: false
+	// + optional
+	AutoGC bool `yaml:"autoGC"`
+	// MaxBackups is the number of checkpoints kept by "switch gc". The oldest checkpoints beyond this number are removed.
+	// If not set, all checkpoints are kept.
+	// + optional
+	MaxBackups *int `yaml:"maxBackups"`
+	// TelemetryEndpoint is the URL the anonymous usage statistics are sent to if enabled with "switch telemetry enable".
+	// There is no default endpoint, so no usage statistics are sent unless an endpoint is configured.
+	// + optional
+	TelemetryEndpoint string `yaml:"telemetryEndpoint"`
+	// DirectoryStoreMapping restricts the search to a single store depending on the current working directory.
+	// If the working directory is within multiple mapped directories, the longest directory wins.
+	// The mapping only applies to the selection dialog of the root command and is ignored with --ignore-dir-mapping.
+	// + optional
+	DirectoryStoreMapping []DirMapping `yaml:"directoryStoreMapping"`
 	// Hooks defines configurations for commands that shall be executed prior to the search
 	Hooks []Hook `yaml:"hooks"`
 	// KubeconfigStores contains the configuration for kubeconfig stores
 	KubeconfigStores []KubeconfigStore `yaml:"kubeconfigStores"`
 }
 
+// DirMapping maps a directory to the store searched when kubeswitch is invoked within the directory
+type DirMapping struct {
+	// Directory is the directory including its subdirectories, e.g. "~/work/project-a".
+	// Environment variables and a leading "~" are expanded.
+	Directory string `yaml:"directory"`
+	// Store is the ID of the store (e.g. "dev") or the store ID including the kind (e.g. "eks.dev")
+	Store string `yaml:"store"`
+}
+
+type CredentialCacheConfig struct {
+	// Enabled determines if cloud provider credentials are cached.
+	// Defaults to false. Credentials are only cached if the configured SSH key is not protected by a passphrase.
+	// + optional
+	Enabled *bool `yaml:"enabled"`
+	// MaxAge is the maximum duration a credential is cached before it is obtained again from the cloud provider.
+	// Credentials are always obtained again once they expire.
+	// Defaults to 1h.
+	// + optional
+	MaxAge *time.Duration `yaml:"maxAge"`
+	// SSHKeyPath is the path to the SSH private key (ed25519 or RSA) used to encrypt the cached credentials.
+	// Required if the credential cache is enabled. No SSH key of the user is read unless configured here.
+	// + optional
+	SSHKeyPath *string `yaml:"sshKeyPath"`
+}
+
+// EnricherType defines how an enricher obtains the metadata of a search result
+type EnricherType string
+
+const (
+	// EnricherTypeHTTP fetches the metadata as JSON object from a URL
+	EnricherTypeHTTP EnricherType = "http"
+	// EnricherTypeCommand runs a command printing the metadata as key=value lines
+	EnricherTypeCommand EnricherType = "command"
+)
+
+type Enricher struct {
+	// Type is the type of the enricher. One of http or command.
+	Type EnricherType `yaml:"type"`
+	// URL is the URL the metadata is fetched from. Only used for the http enricher.
+	// The URL is a Go template, the context name is available as {{ .ContextName }}
+	// + optional
+	URL string `yaml:"url"`
+	// Headers are additional HTTP headers, e.g. for authentication. Only used for the http enricher.
+	// + optional
+	Headers map[string]string `yaml:"headers"`
+	// Command is the command to run. Only used for the command enricher.
+	// The context name is passed as last argument.
+	// + optional
+	Command string `yaml:"command"`
+	// Args are the arguments of the command. Only used for the command enricher.
+	// + optional
+	Args []string `yaml:"args"`
+	// Timeout is the maximum duration to enrich a single search result.
+	// default: 5s
+	// + optional
+	Timeout *time.Duration `yaml:"timeout"`
+}
+
+type MonitorConfig struct {
+	// AlertmanagerURL is the URL of the Alertmanager the alerts are sent to, e.g. http://localhost:9093
+	AlertmanagerURL string `yaml:"alertmanagerURL"`
+	// CheckInterval is the duration between two checks of the stores and watched contexts.
+	// default: 1m
+	// + optional
+	CheckInterval *time.Duration `yaml:"checkInterval"`
+	// FailureThreshold is the number of consecutive failed checks after which an alert is sent.
+	// default: 3
+	// + optional
+	FailureThreshold *int `yaml:"failureThreshold"`
+	// WatchContexts are the names of the contexts whose API server is checked for connectivity.
+	// + optional
+	WatchContexts []string `yaml:"watchContexts"`
+}
+
+// TransformerType defines how a transformer modifies the kubeconfigs returned by a store
+type TransformerType string
+
+const (
+	// TransformerTypeServerRewrite replaces the hostname of the API servers
+	TransformerTypeServerRewrite TransformerType = "serverRewrite"
+	// TransformerTypeInjectProxyURL sets the proxy URL of all clusters
+	TransformerTypeInjectProxyURL TransformerType = "injectProxyURL"
+	// TransformerTypeStripToken removes the static bearer tokens of all users
+	TransformerTypeStripToken TransformerType = "stripToken"
+	// TransformerTypeSetNamespace sets the namespace of all contexts
+	TransformerTypeSetNamespace TransformerType = "setNamespace"
+	// TransformerTypeAddLabel adds a label to the kubeswitch extension of the kubeconfig
+	TransformerTypeAddLabel TransformerType = "addLabel"
+)
+
+type Transformer struct {
+	// Type is the type of the transformer. One of serverRewrite, injectProxyURL, stripToken, setNamespace or addLabel.
+	Type TransformerType `yaml:"type"`
+	// From is the hostname of the API server to replace. Only used for the serverRewrite transformer.
+	// + optional
+	From string `yaml:"from"`
+	// To is the hostname replacing the hostname of the API server. Only used for the serverRewrite transformer.
+	// + optional
+	To string `yaml:"to"`
+	// URL is the proxy URL. Only used for the injectProxyURL transformer.
+	// + optional
+	URL string `yaml:"url"`
+	// Namespace is the namespace of the contexts. Only used for the setNamespace transformer.
+	// + optional
+	Namespace string `yaml:"namespace"`
+	// Key is the key of the label. Only used for the addLabel transformer.
+	// + optional
+	Key string `yaml:"key"`
+	// Value is the value of the label. Only used for the addLabel transformer.
+	// + optional
+	Value string `yaml:"value"`
+}
+
 type KubeconfigStore struct {
 	// ID is the ID of the kubeconfig store.
 	// Used to write distinct index files for each store
@@ -108,6 +387,16 @@ type KubeconfigStore struct {
 	// Not setting this field will cause kubeswitch to not use an index
 	// + optional
 	RefreshIndexAfter *time.Duration `yaml:"refreshIndexAfter"`
+	// IncrementalIndexRefresh configures if a refresh of the index only fetches the kubeconfigs of added kubeconfig paths.
+	// The context names of kubeconfig paths already contained in the index are taken from the index.
+	// Changes to the contexts of a kubeconfig whose path did not change are not detected until the index is rebuilt, e.g. using --no-index.
+	// default: false
+	// + optional
+	IncrementalIndexRefresh bool `yaml:"incrementalIndexRefresh"`
+	// Transformers modify the kubeconfigs returned by this store in the given order,
+	// e.g. to rewrite the API server hostnames or to inject a proxy URL.
+	// + optional
+	Transformers []Transformer `yaml:"transformers"`
 	// Required defines if errors when initializing this store should be logged
 	// defaults to true
 	// useful when configuring a kubeconfig store that is not always available
@@ -115,6 +404,109 @@ type KubeconfigStore struct {
 	// it will throw an errors nonetheless
 	// + optional
 	Required *bool `yaml:"required"`
+	// ReadOnly prevents kubeswitch from writing to this store, e.g., when copying contexts or restoring snapshots.
+	// Reading kubeconfigs is unaffected, even if the backing store records the access (e.g., Vault leases or audit logs).
+	// + optional
+	ReadOnly bool `yaml:"readOnly"`
+	// PriorityScore ranks the results of this store in the selection dialog.
+	// Results of stores with a higher priority score are shown first.
+	// Setting a priority score on any store sorts the results by priority, unless another sort order is configured.
+	// default: 0
+	// + optional
+	PriorityScore int `yaml:"priorityScore"`
+	// ExcludeContexts contains context names or wildcard patterns of contexts of this store that shall not be shown in the search results.
+	// Evaluated after the global ExcludeContexts.
+	// + optional
+	ExcludeContexts []string `yaml:"excludeContexts"`
+	// RequiredTags only shows the contexts of this store whose tags contain all of the given tags.
+	// The values are wildcard patterns, e.g. "env: prod*" matches "prod", "prod-eu" and "production".
+	// + optional
+	RequiredTags map[string]string `yaml:"requiredTags"`
+	// ForbiddenTags hides the contexts of this store whose tags contain any of the given tags.
+	// The values are wildcard patterns.
+	// + optional
+	ForbiddenTags map[string]string `yaml:"forbiddenTags"`
+	// Packages group the contexts of this store into logical cluster groups, e.g. the rings of a multi-cluster deployment.
+	// A context belongs to the first package with a matching context name pattern, unless the store already assigned a package.
+	// + optional
+	Packages []PackageConfig `yaml:"packages"`
+	// GroupByTag groups the search results of this store in the selection dialog by the value of the given tag, e.g. "env".
+	// Each group is introduced by a header. Search results without the tag are shown in the group "(untagged)" at the bottom.
+	// + optional
+	GroupByTag string `yaml:"groupByTag"`
+	// GroupSortOrder defines the order of the groups if GroupByTag is set.
+	// Defaults to "asc"
+	// + optional
+	GroupSortOrder GroupSortOrder `yaml:"groupSortOrder"`
+	// SearchTimeout is the maximum duration of the search for this kubeconfig store.
+	// When the timeout is exceeded, the results discovered so far are used and the search is marked as partial.
+	// Not setting this field will cause kubeswitch to wait until the search of the store is finished
+	// + optional
+	SearchTimeout *time.Duration `yaml:"searchTimeout"`
+	// LogLevel is the log level of this kubeconfig store and overrides the global log level.
+	// Valid values are trace, debug, info, warn and error.
+	// Not setting this field will cause the store to use the global log level
+	// + optional
+	LogLevel string `yaml:"logLevel"`
+	// OIDCRefresh configures if expired id tokens of the OIDC auth provider in the kubeconfigs of this store are refreshed
+	// using the refresh token before switching to a context.
+	// default: false
+	// + optional
+	OIDCRefresh bool `yaml:"oidcRefresh"`
+	// OIDC overrides the OIDC auth provider configuration of the kubeconfigs used to refresh expired id tokens
+	// + optional
+	OIDC *OIDCConfig `yaml:"oidc"`
+	// RBACPreview configures if the preview of a context shows the permissions of the user in the cluster.
+	// The permissions are checked using SelfSubjectAccessReviews when the context is previewed.
+	// default: false
+	// + optional
+	RBACPreview bool `yaml:"rbacPreview"`
+	// RBACCheckResources are the permissions checked for the RBAC preview
+	// in the form "<verb> <resource>[.<group>][/<subresource>]", e.g. "create deployments.apps".
+	// default: get pods, list secrets, create pods/exec, create deployments.apps, delete namespaces, * *
+	// + optional
+	RBACCheckResources []string `yaml:"rbacCheckResources"`
+	// Paginated configures if the search results of this store are read page by page while scrolling through the selection dialog,
+	// instead of reading all search results before they are shown. Useful for stores with many thousands of contexts.
+	// The next page is read once the cursor approaches the last shown search result in the preview,
+	// or once the query leaves few matching search results.
+	// Without the preview, all pages are read one after another.
+	// The search pauses until the next page is read. The time waiting for the next page to be read does not count towards the search timeout.
+	// The store sends at most one page of search results ahead of the shown search results. Of the read search results,
+	// only the context names, paths and tags are kept, the kubeconfigs are only kept for the kubeconfig paths of the last window.
+	// With a filter (e.g. "switch <pattern>"), all pages are read one after another, only keeping the matching search results.
+	// default: false
+	// + optional
+	Paginated bool `yaml:"paginated"`
+	// WindowSize is the number of search results per page of a paginated store.
+	// default: 200
+	// + optional
+	WindowSize *int `yaml:"windowSize"`
+	// LazyInit defers the creation of this store (loading credentials, constructing API clients) until the store is first used.
+	// The store is initialized when the search verifies its kubeconfig paths, so that configuration errors are still reported by the search.
+	// default: false
+	// + optional
+	LazyInit bool `yaml:"lazyInit"`
+	// ExpandNamespaces adds a context named "<context>/<namespace>" for each namespace of the cluster of a context,
+	// so that a context per namespace can be selected. The namespaces are listed with the credentials of the context.
+	// If the namespaces cannot be listed, only the original context is shown.
+	// default: false
+	// + optional
+	ExpandNamespaces bool `yaml:"expandNamespaces"`
+	// NamespaceFilter is a wildcard pattern ('*' and '?') limiting the namespaces for which contexts are added
+	// when ExpandNamespaces is enabled.
+	// default: all namespaces
+	// + optional
+	NamespaceFilter string `yaml:"namespaceFilter"`
+	// NamespaceListTimeout is the timeout for listing the namespaces of a cluster when ExpandNamespaces is enabled.
+	// default: 3s
+	// + optional
+	NamespaceListTimeout *time.Duration `yaml:"namespaceListTimeout"`
+	// MaxKubeconfigSize is the maximum size in bytes of a kubeconfig returned by this store.
+	// Larger kubeconfigs are rejected before they are parsed, e.g. if a misconfigured or compromised backend returns arbitrary data.
+	// default: 1048576 (1 MiB)
+	// + optional
+	MaxKubeconfigSize int `yaml:"maxKubeconfigSize"`
 	// ShowPrefix configures if the search result should include store specific prefix (e.g for the filesystem store the parent directory name)
 	// default: true
 	ShowPrefix *bool `yaml:"showPrefix"`
@@ -127,6 +519,28 @@ type KubeconfigStore struct {
 	Cache *Cache `yaml:"cache"`
 }
 
+// PackageConfig assigns contexts to a package (logical cluster group)
+type PackageConfig struct {
+	// Name is the name of the package, e.g. "ring-0"
+	Name string `yaml:"name"`
+	// Contexts are wildcard patterns matched against the context names with and without the store prefix
+	Contexts []string `yaml:"contexts"`
+}
+
+// OIDCConfig contains the configuration to refresh OIDC id tokens.
+// Empty fields default to the configuration of the OIDC auth provider in the kubeconfig.
+type OIDCConfig struct {
+	// Issuer is the URL of the OpenID provider
+	// + optional
+	Issuer string `yaml:"issuer"`
+	// ClientID is the OAuth client ID
+	// + optional
+	ClientID string `yaml:"clientID"`
+	// ClientSecret is the OAuth client secret
+	// + optional
+	ClientSecret string `yaml:"clientSecret"`
+}
+
 // CacheConfig contains the configuration for the cache
 type Cache struct {
 	Kind string `yaml:"kind"`
@@ -135,11 +549,43 @@ type Cache struct {
 	Config interface{} `yaml:"config"`
 }
 
+type StoreConfigFilesystem struct {
+	// ExpandKubeconfigEnv defines if environment variables in the configured paths are expanded
+	// and if the resulting path lists (e.g. a path set to "$KUBECONFIG") are split into the individual files,
+	// similar to how kubectl handles the KUBECONFIG environment variable.
+	// Defaults to true
+	// + optional
+	ExpandKubeconfigEnv *bool `yaml:"expandKubeconfigEnv"`
+	// KeychainBackend defines if user tokens referencing a macOS Keychain item in the form "keychain://<service>/<account>"
+	// are replaced with the password of the Keychain item when reading a kubeconfig.
+	// Only supported on macOS.
+	// Defaults to false
+	// + optional
+	KeychainBackend bool `yaml:"keychainBackend"`
+}
+
 type StoreConfigVault struct {
 	// VaultAPIAddress is the URL of the Vault API
 	VaultAPIAddress    string `yaml:"vaultAPIAddress"`
 	VaultEngineVersion string `yaml:"vaultEngineVersion"`
 	VaultKeyKubeconfig string `yaml:"vaultKeyKubeconfig"`
+	// KubeconfigJSONPointer is an optional RFC 6901 JSON Pointer (e.g. "/clusters/0/admin_kubeconfig")
+	// selecting the kubeconfig within the secret data.
+	// If set, it takes precedence over VaultKeyKubeconfig
+	KubeconfigJSONPointer string `yaml:"kubeconfigJSONPointer"`
+	// PathPrefix is prepended to the configured paths of the store.
+	// It is a Go template evaluated on startup with the fields
+	//   - .Env.<VAR_NAME> containing the environment variables
+	//   - .GitBranch containing the git branch of the current working directory
+	//   - .Hostname containing the hostname
+	// e.g. "secret/data/k8s/{{.Env.TEAM}}/{{.Env.ENVIRONMENT}}"
+	// + optional
+	PathPrefix string `yaml:"pathPrefix"`
+	// ConnectionPooling keeps idle connections to the Vault API to reuse them across requests.
+	// If disabled, a new connection is established for every request.
+	// Defaults to true
+	// + optional
+	ConnectionPooling *bool `yaml:"connectionPooling"`
 }
 
 type StoreConfigGardener struct {
@@ -151,6 +597,29 @@ type StoreConfigGardener struct {
 	// also used as the store ID if the kubeconfig store ID is not specified
 	// + optional
 	LandscapeName *string `yaml:"landscapeName"`
+	// Landscapes configures multiple Gardener landscapes searched by the store in parallel.
+	// If set, the fields GardenerAPIKubeconfigPath and LandscapeName are ignored.
+	// + optional
+	Landscapes []LandscapeConfig `yaml:"landscapes"`
+}
+
+// LandscapeConfig configures a Gardener landscape of a Gardener store
+type LandscapeConfig struct {
+	// Name is a custom name for the Gardener landscape used as prefix of the kubeconfig paths
+	// instead of the landscape identity
+	// + optional
+	Name string `yaml:"name"`
+	// Identity is the cluster identity of the Gardener landscape.
+	// If not set, it is read from the ConfigMap "kube-system/cluster-identity" of the Gardener API server.
+	// + optional
+	Identity string `yaml:"identity"`
+	// GardenletKubeconfigPath is the path on the local filesystem pointing to the kubeconfig
+	// for the Gardener API server of the landscape
+	GardenletKubeconfigPath string `yaml:"gardenletKubeconfigPath"`
+	// Namespace restricts the search to the given namespace of the landscape, e.g. "garden-my-project".
+	// If not set, the paths of the store are searched.
+	// + optional
+	Namespace string `yaml:"namespace"`
 }
 
 type StoreConfigGKE struct {
@@ -166,6 +635,11 @@ type StoreConfigGKE struct {
 	// ProjectID contains an optional list of projects that will be considered in the search for existing GKE clusters.
 	// If no projects are given, will discover clusters from every found project.
 	ProjectIDs []string `yaml:"projectIDs"`
+	// ClusterNameTemplate is a Go template formatting the kubeconfig paths of the discovered clusters.
+	// Available variables: {{.ClusterName}}, {{.Region}} (location of the cluster), {{.ProjectName}} and {{.ProjectID}}.
+	// default: gke_{{.ProjectName}}--{{.Region}}--{{.ClusterName}}
+	// + optional
+	ClusterNameTemplate string `yaml:"clusterNameTemplate"`
 }
 
 type StoreConfigAzure struct {
@@ -184,6 +658,11 @@ type StoreConfigAzure struct {
 	// ResourceGroups limits the search to clusters within the given resource groups
 	// + optional
 	ResourceGroups []string `yaml:"resourceGroups"`
+	// ClusterNameTemplate is a Go template formatting the kubeconfig paths of the discovered clusters.
+	// Available variables: {{.ClusterName}}, {{.Region}} (location of the cluster), {{.ResourceGroup}} and {{.SubscriptionID}}.
+	// default: az_{{.ResourceGroup}}--{{.ClusterName}}
+	// + optional
+	ClusterNameTemplate string `yaml:"clusterNameTemplate"`
 }
 
 type StoreConfigEKS struct {
@@ -191,6 +670,24 @@ type StoreConfigEKS struct {
 	Region *string `yaml:"region"`
 	// Profile is the named profile to authenticate with https://docs.aws.amazon.com/cli/latest/userguide/cli-configure-profiles.html
 	Profile string `yaml:"profile"`
+	// UseIMDSv2 configures the EC2 instance metadata client to only use IMDSv2 (session tokens)
+	// and to never fall back to IMDSv1 when fetching credentials from the instance metadata service.
+	// Defaults to true.
+	UseIMDSv2 *bool `yaml:"useIMDSv2"`
+	// ClusterNameTemplate is a Go template formatting the kubeconfig paths of the discovered clusters.
+	// Available variables: {{.ClusterName}}, {{.Region}}, {{.AccountID}} and {{.ProfileName}}.
+	// The AWS account ID is only looked up if the template uses it.
+	// default: eks_{{.ProfileName}}--{{.Region}}--{{.ClusterName}}
+	// + optional
+	ClusterNameTemplate string `yaml:"clusterNameTemplate"`
+	// EnrichPreview connects to the cluster of the previewed context to show its Karpenter NodePools in the preview.
+	// Falls back to the basic cluster metadata if the cluster is unreachable or Karpenter is not installed.
+	// + optional
+	EnrichPreview bool `yaml:"enrichPreview"`
+	// UseEKSPodIdentity generates kubeconfigs retrieving tokens with "aws eks get-token --cluster-id" instead of "--cluster-name".
+	// Defaults to true for clusters using the EKS access entry API, i.e., the authentication mode "API" or "API_AND_CONFIG_MAP".
+	// + optional
+	UseEKSPodIdentity *bool `yaml:"useEKSPodIdentity"`
 }
 
 // GCPAuthenticationType
@@ -239,6 +736,11 @@ type StoreConfigRancher struct {
 	RancherAPIAddress string `yaml:"rancherAPIAddress"`
 	// RancherToken is the token used to authenticate against the Rancher API, format: token-12abc:bmjlzslas......x4hv5ptc29wt4sfk
 	RancherToken string `yaml:"rancherToken"`
+	// ConnectionPooling keeps idle connections to the Rancher API to reuse them across requests.
+	// If disabled, a new connection is established for every request.
+	// Defaults to true
+	// + optional
+	ConnectionPooling *bool `yaml:"connectionPooling"`
 }
 
 type StoreConfigOVH struct {
@@ -263,4 +765,277 @@ type StoreConfigCapi struct {
 	// KubeconfigPath is the path on the local filesystem pointing to the kubeconfig
 	// for the management cluster
 	KubeconfigPath string `yaml:"kubeconfigPath"`
+	// Provider is the CAPI infrastructure provider of the clusters.
+	// Determines the name and namespace of the kubeconfig secrets of the clusters.
+	// One of "generic", "aws", "azure", "gcp", "openstack", "vsphere" or "docker".
+	// Defaults to "generic"
+	// + optional
+	Provider string `yaml:"provider"`
+	// CloudflareTunnel routes the API servers of the clusters through a Cloudflare Tunnel.
+	// Use this for clusters with private API servers.
+	// + optional
+	CloudflareTunnel *CloudflareTunnelConfig `yaml:"cloudflareTunnel"`
+}
+
+type CloudflareTunnelConfig struct {
+	// TunnelID is the ID of the Cloudflare Tunnel running in the private network of the clusters
+	TunnelID string `yaml:"tunnelID"`
+	// AccountID is the ID of the Cloudflare account owning the tunnel
+	AccountID string `yaml:"accountID"`
+	// APIToken is the token for the Cloudflare API. Requires the permission to edit the tunnels of the account.
+	// Defaults to the environment variable CLOUDFLARE_API_TOKEN
+	// + optional
+	APIToken string `yaml:"apiToken"`
+}
+
+type StoreConfigComposite struct {
+	// Stores contains the configuration of the kubeconfig stores aggregated by the composite store.
+	// The configuration of the composite store (e.g., searchTimeout, excludeContexts) applies to all of them
+	Stores []KubeconfigStore `yaml:"stores"`
+}
+
+type StoreConfigFallback struct {
+	// Primary is the configuration of the kubeconfig store that is tried first
+	Primary KubeconfigStore `yaml:"primary"`
+	// Fallback is the configuration of the kubeconfig store that is used
+	// if the primary store does not know the cluster or does not respond in time
+	Fallback KubeconfigStore `yaml:"fallback"`
+	// FallbackDelay is the maximum duration to wait for the primary store to return a kubeconfig
+	// before giving up and using the fallback store.
+	// Defaults to waiting until the primary store responds.
+	// +optional
+	FallbackDelay *time.Duration `yaml:"fallbackDelay"`
+}
+
+type StoreConfigReplicas struct {
+	// Stores contains the configuration of the identical replicas of a kubeconfig store,
+	// e.g. the Vault clusters of a Vault HA setup.
+	// The replicas must return the same kubeconfig paths.
+	Stores []KubeconfigStore `yaml:"stores"`
+	// RetryAfter is the duration a replica is skipped after it failed to return a kubeconfig.
+	// Skipped replicas are only used once all other replicas failed as well.
+	// default: 30s
+	// +optional
+	RetryAfter *time.Duration `yaml:"retryAfter"`
+}
+
+// WebDAVAuthType is the authentication method used for the WebDAV server
+type WebDAVAuthType string
+
+const (
+	// WebDAVAuthTypeBasic uses basic authentication with username and password
+	WebDAVAuthTypeBasic WebDAVAuthType = "basic"
+	// WebDAVAuthTypeDigest uses digest authentication with username and password
+	WebDAVAuthTypeDigest WebDAVAuthType = "digest"
+	// WebDAVAuthTypeBearer uses the password as bearer token
+	WebDAVAuthTypeBearer WebDAVAuthType = "bearer"
+)
+
+type StoreConfigWebDAV struct {
+	// URL is the URL of the WebDAV server
+	URL string `yaml:"url"`
+	// AuthType is the authentication method. One of basic, digest or bearer.
+	// Defaults to basic
+	// + optional
+	AuthType WebDAVAuthType `yaml:"authType"`
+	// Username is the username for basic and digest authentication
+	// + optional
+	Username string `yaml:"username"`
+	// Password is the password for basic and digest authentication or the token for bearer authentication
+	// + optional
+	Password string `yaml:"password"`
+	// TLSCACertFile is the path to a CA certificate file used to verify the certificate of the WebDAV server
+	// + optional
+	TLSCACertFile string `yaml:"tlsCACertFile"`
+	// SearchPaths are the directories on the WebDAV server to search for kubeconfig files.
+	// Defaults to the paths of the kubeconfig store
+	// + optional
+	SearchPaths []string `yaml:"searchPaths"`
+	// RecursiveDepth is the maximum depth of directories that are searched below each search path.
+	// Defaults to 3
+	// + optional
+	RecursiveDepth *int `yaml:"recursiveDepth"`
+}
+
+// ConsulDiscovery defines how kubeconfigs are discovered in the Consul KV store
+type ConsulDiscovery string
+
+const (
+	// ConsulDiscoveryStructured treats "/"-separated keys as directory hierarchy
+	// and only discovers keys whose name matches the kubeconfig name
+	ConsulDiscoveryStructured ConsulDiscovery = "structured"
+	// ConsulDiscoveryFlat treats every key below the configured prefixes as kubeconfig
+	ConsulDiscoveryFlat ConsulDiscovery = "flat"
+)
+
+type StoreConfigConsul struct {
+	// Address is the address of the Consul server.
+	// Defaults to the CONSUL_HTTP_ADDR environment variable or 127.0.0.1:8500
+	// + optional
+	Address string `yaml:"address"`
+	// Token is the ACL token used for requests to Consul.
+	// Defaults to the CONSUL_HTTP_TOKEN environment variable
+	// + optional
+	Token string `yaml:"token"`
+	// TLSCACertFile is the path to a CA certificate file used to verify the certificate of the Consul server
+	// + optional
+	TLSCACertFile string `yaml:"tlsCACertFile"`
+	// DataCenter is the Consul datacenter. Defaults to the datacenter of the agent
+	// + optional
+	DataCenter string `yaml:"dataCenter"`
+	// Namespace is the namespace of the keys (Consul Enterprise only)
+	// + optional
+	Namespace string `yaml:"namespace"`
+	// Discovery defines how kubeconfigs are discovered. One of structured or flat.
+	// Defaults to structured
+	// + optional
+	Discovery ConsulDiscovery `yaml:"discovery"`
+}
+
+type StoreConfigEtcd struct {
+	// Endpoints are the URLs of the etcd members
+	Endpoints []string `yaml:"endpoints"`
+	// TLSCACertFile is the path to a CA certificate file used to verify the certificates of the etcd members
+	// + optional
+	TLSCACertFile string `yaml:"tlsCACertFile"`
+	// TLSCertFile is the path to the client certificate file
+	// + optional
+	TLSCertFile string `yaml:"tlsCertFile"`
+	// TLSKeyFile is the path to the key file of the client certificate
+	// + optional
+	TLSKeyFile string `yaml:"tlsKeyFile"`
+	// Username is the username for authentication
+	// + optional
+	Username string `yaml:"username"`
+	// Password is the password for authentication
+	// + optional
+	Password string `yaml:"password"`
+}
+
+type StoreConfigFirestore struct {
+	// ProjectID is the Google Cloud project of the Firestore database
+	ProjectID string `yaml:"projectID"`
+	// Collection is the path of the collection containing one document per cluster, e.g. "clusters"
+	// or "registry/production/clusters" for a subcollection.
+	// Each document must contain the fields "contextName" and "kubeconfigBase64".
+	Collection string `yaml:"collection"`
+	// FilterField is the name of a document field to filter the documents by on the server side
+	// + optional
+	FilterField string `yaml:"filterField"`
+	// FilterValue is the value the FilterField of the documents must be equal to
+	// + optional
+	FilterValue string `yaml:"filterValue"`
+	// TagFields are the document fields added as tags to the search results.
+	// Defaults to "env", "region" and "team"
+	// + optional
+	TagFields []string `yaml:"tagFields"`
+	// ServiceAccountFile is the path to the key file of a service account used for authentication.
+	// Defaults to the Google Application Default Credentials
+	// + optional
+	ServiceAccountFile string `yaml:"serviceAccountFile"`
+}
+
+type StoreConfigHTTP struct {
+	// Endpoints are the URLs to download kubeconfigs from. Each endpoint returns a single kubeconfig
+	// + optional
+	Endpoints []HTTPEndpoint `yaml:"endpoints"`
+	// ListEndpoint is the URL of an endpoint returning a JSON array of the kubeconfigs to discover,
+	// e.g. [{"path": "https://api.example.com/kubeconfigs/prod", "name": "prod", "tags": {"env": "prod"}}].
+	// Relative paths are resolved against the ListEndpoint.
+	// + optional
+	ListEndpoint string `yaml:"listEndpoint"`
+	// Headers are sent with the requests to the ListEndpoint and to the kubeconfigs discovered via the ListEndpoint
+	// + optional
+	Headers map[string]string `yaml:"headers"`
+	// BearerTokenFile is the path to a file containing the bearer token for the requests to the ListEndpoint
+	// and to the kubeconfigs discovered via the ListEndpoint.
+	// The file is read on every request, so that rotated tokens are picked up.
+	// + optional
+	BearerTokenFile string `yaml:"bearerTokenFile"`
+	// TLSCACertFile is the path to a CA certificate file used to verify the certificate of the ListEndpoint
+	// and of the kubeconfigs discovered via the ListEndpoint
+	// + optional
+	TLSCACertFile string `yaml:"tlsCACertFile"`
+	// Timeout is the maximum duration of a single request.
+	// Defaults to 30s
+	// + optional
+	Timeout *time.Duration `yaml:"timeout"`
+	// ProxyURL is the URL of the proxy used for all requests.
+	// Defaults to the proxy configured via the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables
+	// + optional
+	ProxyURL string `yaml:"proxyURL"`
+	// SigV4 signs the requests to the ListEndpoint and to the kubeconfigs discovered via the ListEndpoint
+	// with AWS Signature Version 4
+	// + optional
+	SigV4 *SigV4Config `yaml:"sigV4"`
+	// ConnectionPooling keeps idle connections to the endpoints to reuse them across requests.
+	// If disabled, a new connection is established for every request.
+	// Defaults to true
+	// + optional
+	ConnectionPooling *bool `yaml:"connectionPooling"`
+}
+
+type HTTPEndpoint struct {
+	// URL is the URL returning the kubeconfig
+	URL string `yaml:"url"`
+	// Method is the HTTP method of the request.
+	// Defaults to GET
+	// + optional
+	Method string `yaml:"method"`
+	// Headers are sent with the request
+	// + optional
+	Headers map[string]string `yaml:"headers"`
+	// BearerTokenFile is the path to a file containing the bearer token sent in the Authorization header.
+	// The file is read on every request, so that rotated tokens are picked up.
+	// + optional
+	BearerTokenFile string `yaml:"bearerTokenFile"`
+	// TLSCACertFile is the path to a CA certificate file used to verify the certificate of the endpoint
+	// + optional
+	TLSCACertFile string `yaml:"tlsCACertFile"`
+	// SigV4 signs the request with AWS Signature Version 4, e.g. for endpoints behind an AWS API Gateway
+	// + optional
+	SigV4 *SigV4Config `yaml:"sigV4"`
+}
+
+// SigV4Config configures the signing of requests with AWS Signature Version 4
+type SigV4Config struct {
+	// Region is the AWS region of the endpoint, e.g. eu-central-1
+	Region string `yaml:"region"`
+	// Service is the signing name of the AWS service, e.g. execute-api for the API Gateway
+	Service string `yaml:"service"`
+	// AccessKeyID is the access key used to sign the requests.
+	// If not set, the credentials are loaded from the default AWS credential chain.
+	// Environment variables are expanded, e.g. ${PLATFORM_AWS_ACCESS_KEY_ID}.
+	// + optional
+	AccessKeyID string `yaml:"accessKeyID"`
+	// SecretAccessKey is the secret key belonging to the AccessKeyID.
+	// Environment variables are expanded, e.g. ${PLATFORM_AWS_SECRET_ACCESS_KEY}.
+	// + optional
+	SecretAccessKey string `yaml:"secretAccessKey"`
+	// SessionToken is the session token of temporary credentials.
+	// Environment variables are expanded.
+	// + optional
+	SessionToken string `yaml:"sessionToken"`
+	// Profile is the profile in the shared AWS configuration used to load the credentials if no AccessKeyID is set.
+	// Defaults to the AWS_PROFILE environment variable or the default profile
+	// + optional
+	Profile string `yaml:"profile"`
+}
+
+const (
+	// ScriptLanguageLua denotes a Lua 5.2 script
+	ScriptLanguageLua = "lua"
+	// ScriptLanguageCEL denotes a CEL expression
+	ScriptLanguageCEL = "cel"
+)
+
+type StoreConfigScript struct {
+	// ScriptFile is the path to the Lua script or CEL expression generating the kubeconfigs.
+	// A Lua script defines the functions listKubeconfigs() and getKubeconfig(path).
+	// A CEL expression returns the kubeconfigs if the variable "path" is empty, otherwise the kubeconfig for the path.
+	ScriptFile string `yaml:"scriptFile"`
+	// ScriptLanguage is the language of the ScriptFile. One of lua or cel.
+	// Defaults to cel for files with the extension ".cel", otherwise to lua
+	// + optional
+	ScriptLanguage string `yaml:"scriptLanguage"`
 }